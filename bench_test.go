@@ -0,0 +1,119 @@
+package openflow
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+// repeatConn is an in-memory peer, that endlessly returns the same
+// message on each read.
+type repeatConn struct {
+	dummyConn
+	data []byte
+	rd   bytes.Reader
+}
+
+func (c *repeatConn) Read(b []byte) (int, error) {
+	if c.rd.Len() == 0 {
+		c.rd.Reset(c.data)
+	}
+
+	return c.rd.Read(b)
+}
+
+func (c *repeatConn) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func benchmarkReceive(b *testing.B, body io.WriterTo) {
+	var buf bytes.Buffer
+	if _, err := NewRequest(TypePacketIn, body).WriteTo(&buf); err != nil {
+		b.Fatalf("Failed to encode request: %s", err)
+	}
+
+	conn := NewConn(&repeatConn{data: buf.Bytes()})
+
+	b.SetBytes(int64(buf.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := conn.Receive(); err != nil {
+			b.Fatalf("Failed to receive request: %s", err)
+		}
+	}
+}
+
+func BenchmarkConnReceive(b *testing.B) {
+	b.Run("Empty", func(b *testing.B) {
+		benchmarkReceive(b, nil)
+	})
+
+	b.Run("128", func(b *testing.B) {
+		benchmarkReceive(b, bytes.NewReader(make([]byte, 128)))
+	})
+
+	b.Run("1500", func(b *testing.B) {
+		benchmarkReceive(b, bytes.NewReader(make([]byte, 1500)))
+	})
+}
+
+func BenchmarkConnSend(b *testing.B) {
+	conn := NewConn(&repeatConn{})
+	data := make([]byte, 128)
+
+	b.SetBytes(int64(headerlen + len(data)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		req := NewRequest(TypePacketOut, bytes.NewReader(data))
+		if err := conn.Send(req); err != nil {
+			b.Fatalf("Failed to send request: %s", err)
+		}
+	}
+}
+
+func BenchmarkConnPipe(b *testing.B) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	sender, receiver := NewConn(c1), NewConn(c2)
+	data := make([]byte, 128)
+
+	go func() {
+		for i := 0; i < b.N; i++ {
+			req := NewRequest(TypePacketIn, bytes.NewReader(data))
+			if err := Send(sender, req); err != nil {
+				return
+			}
+		}
+	}()
+
+	b.SetBytes(int64(headerlen + len(data)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := receiver.Receive(); err != nil {
+			b.Fatalf("Failed to receive request: %s", err)
+		}
+	}
+}
+
+// TestConnReceiveAllocs ensures the number of allocations of the
+// connection read loop does not grow unnoticed.
+func TestConnReceiveAllocs(t *testing.T) {
+	var buf bytes.Buffer
+	NewRequest(TypeEchoRequest, bytes.NewReader(make([]byte, 64))).WriteTo(&buf)
+
+	conn := NewConn(&repeatConn{data: buf.Bytes()})
+	allocs := testing.AllocsPerRun(100, func() {
+		conn.Receive()
+	})
+
+	if allocs > 3 {
+		t.Errorf("Receive takes %.0f allocations, limit 3", allocs)
+	}
+}
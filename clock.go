@@ -0,0 +1,68 @@
+package openflow
+
+import (
+	"time"
+)
+
+// Clock is a source of the current time and timers. The subsystems
+// depending on timeouts accept the clock, so the tests could advance
+// the time deterministically instead of sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a new ticker delivering the ticks with
+	// the period specified by the duration.
+	NewTicker(d time.Duration) Ticker
+
+	// AfterFunc waits for the duration to elapse and then calls
+	// the function in its own goroutine.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Ticker delivers the ticks of the clock at intervals.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker.
+	Stop()
+}
+
+// Timer is a single event scheduled by the clock.
+type Timer interface {
+	// Stop prevents the timer from firing. It returns false, when
+	// the timer has already expired or been stopped.
+	Stop() bool
+}
+
+// SystemClock is a clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+// systemClock implements Clock interface using the system time.
+type systemClock struct{}
+
+// Now implements Clock interface.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker implements Clock interface.
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// AfterFunc implements Clock interface.
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// systemTicker implements Ticker interface for the time.Ticker.
+type systemTicker struct {
+	*time.Ticker
+}
+
+// C implements Ticker interface.
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// client is a connection to the switch.
+type client struct {
+	conn    of.Conn
+	timeout time.Duration
+}
+
+// dial connects to the switch listening on the given address and
+// performs the version negotiation. The address could be prefixed
+// with the network name, like "tcp:127.0.0.1:6653" or "unix:/path".
+func dial(addr string, timeout time.Duration) (*client, error) {
+	network := "tcp"
+	if kv := strings.SplitN(addr, ":", 2); len(kv) == 2 {
+		switch kv[0] {
+		case "tcp", "tcp4", "tcp6", "unix":
+			network, addr = kv[0], kv[1]
+		}
+	}
+
+	// Zero timeout disables the timeout, so the context is not
+	// limited in that case.
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	d := &of.Dialer{NetDialContext: (&net.Dialer{}).DialContext}
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{conn: conn, timeout: timeout}
+	if err = c.handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// handshake exchanges the hello messages with the switch.
+func (c *client) handshake() error {
+	if err := of.Send(c.conn, of.NewRequest(of.TypeHello, nil)); err != nil {
+		return err
+	}
+
+	_, err := c.receive(func(r *of.Request) bool {
+		return r.Header.Type == of.TypeHello
+	})
+
+	return err
+}
+
+// receive reads the messages from the switch until the message matching
+// the given function is received. Echo requests are replied along the
+// way, errors are returned as ofp.ErrorMessage.
+func (c *client) receive(match func(*of.Request) bool) (*of.Request, error) {
+	for {
+		if c.timeout != 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+		}
+
+		r, err := c.conn.Receive()
+		if err != nil {
+			return nil, err
+		}
+
+		switch r.Header.Type {
+		case of.TypeEchoRequest:
+			// The echo reply must carry the data of the request.
+			data, err := r.RawBody()
+			if err != nil {
+				return nil, err
+			}
+
+			reply := r.NewReply(of.TypeEchoReply, bytes.NewReader(data))
+			if err = of.Send(c.conn, reply); err != nil {
+				return nil, err
+			}
+			continue
+		case of.TypeError:
+			e, err := ofp.ReadError(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			return nil, e
+		}
+
+		if match(r) {
+			return r, nil
+		}
+	}
+}
+
+// send sends the given request to the switch and waits for the barrier
+// reply, so the errors caused by the request are returned.
+func (c *client) send(req *of.Request) error {
+	barrier := of.NewRequest(of.TypeBarrierRequest, nil)
+	matcher := of.TransactionMatcher(&barrier.Header)
+
+	if err := of.Send(c.conn, req, barrier); err != nil {
+		return err
+	}
+
+	_, err := c.receive(matcher.Match)
+	return err
+}
+
+// multipart sends the multipart request and calls the given function
+// for the body of each multipart reply.
+func (c *client) multipart(t ofp.MultipartType, body io.WriterTo,
+	fn func(io.Reader) error) error {
+
+	req := of.NewRequest(of.TypeMultipartRequest,
+		ofp.NewMultipartRequest(t, body))
+	matcher := of.TransactionMatcher(&req.Header)
+
+	if err := of.Send(c.conn, req); err != nil {
+		return err
+	}
+
+	for {
+		r, err := c.receive(matcher.Match)
+		if err != nil {
+			return err
+		}
+
+		var reply ofp.MultipartReply
+		if _, err = reply.ReadFrom(r.Body); err != nil {
+			return err
+		}
+
+		if reply.Type != t {
+			return fmt.Errorf("ofctl: unexpected reply %s", reply.Type)
+		}
+
+		if err = fn(r.Body); err != nil {
+			return err
+		}
+
+		if reply.Flags&ofp.MultipartReplyMode == 0 {
+			return nil
+		}
+	}
+}
+
+// Close closes the connection to the switch.
+func (c *client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+// flowSpec is a flow description parsed from the command line.
+type flowSpec struct {
+	Table       ofp.Table
+	Priority    uint16
+	IdleTimeout uint16
+	HardTimeout uint16
+	Cookie      uint64
+	OutPort     ofp.PortNo
+
+	Match        ofp.Match
+	Instructions ofp.Instructions
+}
+
+// portNames maps names of reserved ports to their numbers.
+var portNames = map[string]ofp.PortNo{
+	"in_port":    ofp.PortIn,
+	"table":      ofp.PortTable,
+	"normal":     ofp.PortNormal,
+	"flood":      ofp.PortFlood,
+	"all":        ofp.PortAll,
+	"controller": ofp.PortController,
+	"local":      ofp.PortLocal,
+	"any":        ofp.PortAny,
+}
+
+// parsePort parses the port number or the name of the reserved port.
+func parsePort(s string) (ofp.PortNo, error) {
+	if port, ok := portNames[strings.ToLower(s)]; ok {
+		return port, nil
+	}
+
+	port, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("ofctl: invalid port %q", s)
+	}
+
+	return ofp.PortNo(port), nil
+}
+
+// formatPort returns the name of the reserved port or the port number.
+func formatPort(port ofp.PortNo) string {
+	for name, p := range portNames {
+		if p == port {
+			return name
+		}
+	}
+
+	return strconv.FormatUint(uint64(port), 10)
+}
+
+// parseFlow parses the flow description in the format similar to the
+// one used by ovs-ofctl, for example:
+//
+//	table=0,priority=10,in_port=1,eth_type=0x0800,actions=output:2
+//
+// The actions must be the last element of the description. When the
+// table is not specified, ofp.TableAll is used.
+func parseFlow(s string) (*flowSpec, error) {
+	spec := &flowSpec{
+		Table:   ofp.TableAll,
+		OutPort: ofp.PortAny,
+		Match:   ofp.Match{Type: ofp.MatchTypeXM},
+	}
+
+	var actions string
+	if i := strings.Index(s, "actions="); i >= 0 {
+		s, actions = strings.TrimSuffix(s[:i], ","), s[i+len("actions="):]
+	}
+
+	for _, elem := range strings.Split(s, ",") {
+		elem = strings.TrimSpace(elem)
+		if elem == "" {
+			continue
+		}
+
+		kv := strings.SplitN(elem, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("ofctl: invalid flow element %q", elem)
+		}
+
+		if err := spec.set(kv[0], kv[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	instructions, err := parseActions(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	spec.Instructions = instructions
+	return spec, nil
+}
+
+// set assigns the value of the flow attribute or the match field.
+func (spec *flowSpec) set(key, value string) error {
+	var err error
+	parse := func(bits int) uint64 {
+		var v uint64
+		if v, err = strconv.ParseUint(value, 0, bits); err != nil {
+			err = fmt.Errorf("ofctl: invalid value of %s: %q", key, value)
+		}
+		return v
+	}
+
+	switch key {
+	case "table":
+		spec.Table = ofp.Table(parse(8))
+	case "priority":
+		spec.Priority = uint16(parse(16))
+	case "idle_timeout":
+		spec.IdleTimeout = uint16(parse(16))
+	case "hard_timeout":
+		spec.HardTimeout = uint16(parse(16))
+	case "cookie":
+		spec.Cookie = parse(64)
+	case "out_port":
+		port, err := parsePort(value)
+		if err != nil {
+			return err
+		}
+		spec.OutPort = port
+	default:
+		xm, err := parseXM(key, value)
+		if err != nil {
+			return err
+		}
+		spec.Match.Fields = append(spec.Match.Fields, xm)
+	}
+
+	return err
+}
+
+// parseXM parses the match field with the optional mask separated
+// with slash, for example "ipv4_dst=10.0.0.0/255.255.255.0".
+func parseXM(name, s string) (ofp.XM, error) {
+	field, ok := ofp.LookupXMFieldName(name)
+	if !ok {
+		return ofp.XM{}, fmt.Errorf("ofctl: unknown match field %q", name)
+	}
+
+	xm := ofp.XM{Class: field.Class, Type: field.Type}
+	parts := strings.SplitN(s, "/", 2)
+
+	var err error
+	if field.Type == ofp.XMTypeInPort && field.Class == ofp.XMClassOpenflowBasic {
+		var port ofp.PortNo
+		if port, err = parsePort(parts[0]); err == nil {
+			xm.Value = ofp.XMValue{byte(port >> 24), byte(port >> 16),
+				byte(port >> 8), byte(port)}
+		}
+	} else {
+		xm.Value, err = parseXMValue(parts[0], field.Len)
+	}
+
+	if err != nil {
+		return xm, fmt.Errorf("ofctl: invalid value of %s: %s", name, err)
+	}
+
+	if len(parts) == 2 {
+		if xm.Mask, err = parseXMValue(parts[1], field.Len); err != nil {
+			return xm, fmt.Errorf("ofctl: invalid mask of %s: %s", name, err)
+		}
+	}
+
+	return xm, nil
+}
+
+// parseXMValue parses the value of the match field of the given length.
+// The value could be a hardware address, an IP address, a hexadecimal
+// number prefixed with "0x" or a decimal number.
+func parseXMValue(s string, length int) (ofp.XMValue, error) {
+	if strings.HasPrefix(s, "0x") {
+		digits := strings.TrimPrefix(s, "0x")
+		if len(digits)%2 == 1 {
+			digits = "0" + digits
+		}
+
+		b, err := hex.DecodeString(digits)
+		if err != nil {
+			return nil, err
+		}
+
+		return padXMValue(b, length)
+	}
+
+	if hwaddr, err := net.ParseMAC(s); err == nil {
+		return padXMValue(hwaddr, length)
+	}
+
+	if ip := net.ParseIP(s); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil && length == net.IPv4len {
+			return ofp.XMValue(ip4), nil
+		}
+
+		return padXMValue(ip.To16(), length)
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("unsupported value %q", s)
+	}
+
+	return padXMValue(n.Bytes(), length)
+}
+
+// padXMValue left-pads the value with zeros to the given length.
+func padXMValue(b []byte, length int) (ofp.XMValue, error) {
+	if length == 0 {
+		return ofp.XMValue(b), nil
+	}
+
+	if len(b) > length {
+		return nil, errors.New("value is too long")
+	}
+
+	v := make(ofp.XMValue, length)
+	copy(v[length-len(b):], b)
+	return v, nil
+}
+
+// parseActions parses the comma-separated list of actions. The
+// following actions are supported: output:PORT, group:GROUP, drop and
+// goto_table:TABLE.
+func parseActions(s string) (ofp.Instructions, error) {
+	var (
+		actions      ofp.Actions
+		instructions ofp.Instructions
+	)
+
+	for _, elem := range strings.Split(s, ",") {
+		elem = strings.TrimSpace(elem)
+		if elem == "" || elem == "drop" {
+			continue
+		}
+
+		kv := strings.SplitN(elem, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("ofctl: invalid action %q", elem)
+		}
+
+		switch kv[0] {
+		case "output":
+			port, err := parsePort(kv[1])
+			if err != nil {
+				return nil, err
+			}
+
+			actions = append(actions, &ofp.ActionOutput{
+				Port: port, MaxLen: ofp.ContentLenNoBuffer,
+			})
+		case "group":
+			group, err := strconv.ParseUint(kv[1], 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("ofctl: invalid group %q", kv[1])
+			}
+
+			actions = append(actions, &ofp.ActionGroup{
+				Group: ofp.Group(group),
+			})
+		case "goto_table":
+			table, err := strconv.ParseUint(kv[1], 0, 8)
+			if err != nil {
+				return nil, fmt.Errorf("ofctl: invalid table %q", kv[1])
+			}
+
+			instructions = append(instructions, &ofp.InstructionGotoTable{
+				Table: ofp.Table(table),
+			})
+		default:
+			return nil, fmt.Errorf("ofctl: unsupported action %q", kv[0])
+		}
+	}
+
+	if len(actions) != 0 {
+		apply := &ofp.InstructionApplyActions{Actions: actions}
+		instructions = append(ofp.Instructions{apply}, instructions...)
+	}
+
+	return instructions, nil
+}
+
+// formatInstructions returns the representation of the instructions
+// in the same format as accepted by the parseActions function.
+func formatInstructions(instructions ofp.Instructions) string {
+	var elems []string
+
+	for _, inst := range instructions {
+		switch inst := inst.(type) {
+		case *ofp.InstructionApplyActions:
+			elems = append(elems, formatActions(inst.Actions)...)
+		case *ofp.InstructionWriteActions:
+			elems = append(elems, "write_actions("+
+				strings.Join(formatActions(inst.Actions), ",")+")")
+		case *ofp.InstructionGotoTable:
+			elems = append(elems, fmt.Sprintf("goto_table:%d", inst.Table))
+		default:
+			elems = append(elems, inst.Type().String())
+		}
+	}
+
+	if len(elems) == 0 {
+		return "drop"
+	}
+
+	return strings.Join(elems, ",")
+}
+
+// formatActions returns the list of string representations of actions.
+func formatActions(actions ofp.Actions) []string {
+	var elems []string
+
+	for _, action := range actions {
+		switch action := action.(type) {
+		case *ofp.ActionOutput:
+			elems = append(elems, "output:"+formatPort(action.Port))
+		case *ofp.ActionGroup:
+			elems = append(elems, fmt.Sprintf("group:%d", action.Group))
+		case *ofp.ActionSetField:
+			elems = append(elems, "set_field:"+action.Field.String())
+		default:
+			elems = append(elems, action.Type().String())
+		}
+	}
+
+	return elems
+}
+
+// formatMatch returns the comma-separated list of match fields.
+func formatMatch(m ofp.Match) string {
+	elems := make([]string, len(m.Fields))
+	for i, xm := range m.Fields {
+		elems[i] = xm.String()
+	}
+
+	return strings.Join(elems, ",")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestParseFlow(t *testing.T) {
+	spec, err := parseFlow("table=1,priority=10,in_port=controller," +
+		"eth_type=0x800,ipv4_dst=10.0.0.0/255.255.255.0," +
+		"actions=output:2,group:3,goto_table:2")
+
+	if err != nil {
+		t.Fatalf("Failed to parse the flow: %s", err)
+	}
+
+	if spec.Table != 1 || spec.Priority != 10 {
+		t.Errorf("Invalid flow attributes: %v", spec)
+	}
+
+	match := formatMatch(spec.Match)
+	expected := "in_port=4294967293,eth_type=0x0800," +
+		"ipv4_dst=10.0.0.0/255.255.255.0"
+
+	if match != expected {
+		t.Errorf("Invalid match, expected:\n%s got:\n%s", expected, match)
+	}
+
+	actions := formatInstructions(spec.Instructions)
+	if actions != "output:2,group:3,goto_table:2" {
+		t.Errorf("Invalid actions: %s", actions)
+	}
+}
+
+func TestParseFlowErrors(t *testing.T) {
+	tests := []string{
+		"priority=high",
+		"unknown_field=1",
+		"in_port=port",
+		"vlan_pcp=0x0102",
+		"actions=resubmit:1",
+		"actions=output",
+	}
+
+	for _, test := range tests {
+		if _, err := parseFlow(test); err == nil {
+			t.Errorf("Flow %q must be rejected", test)
+		}
+	}
+}
+
+func TestParseXMValue(t *testing.T) {
+	tests := []struct {
+		Text  string
+		Len   int
+		Value ofp.XMValue
+	}{
+		{"0x1", 2, ofp.XMValue{0x00, 0x01}},
+		{"42", 4, ofp.XMValue{0x00, 0x00, 0x00, 0x2a}},
+		{"01:02:03:04:05:06", 6, ofp.XMValue{1, 2, 3, 4, 5, 6}},
+		{"10.0.0.1", 4, ofp.XMValue{10, 0, 0, 1}},
+		{"::1", 16, ofp.XMValue{15: 1}},
+	}
+
+	for _, test := range tests {
+		v, err := parseXMValue(test.Text, test.Len)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", test.Text, err)
+		}
+
+		if !bytes.Equal(v, test.Value) {
+			t.Errorf("Invalid value of %q: %x", test.Text, v)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Command ofctl is a tool for monitoring and administering OpenFlow
+// switches. It connects to the switch listening for the controller
+// connections and executes the given command.
+//
+// Usage:
+//
+//	ofctl [flags] command switch [args...]
+//
+// The commands are:
+//
+//	dump-flows SWITCH [FLOW]   print flows matching the FLOW
+//	add-flow SWITCH FLOW       add the FLOW to the switch
+//	del-flows SWITCH [FLOW]    delete flows matching the FLOW
+//	dump-ports SWITCH [PORT]   print statistics of the PORT
+//	monitor SWITCH             print asynchronous messages
+//
+// The flow is described in the format similar to the one used by the
+// ovs-ofctl tool:
+//
+//	table=0,priority=10,in_port=1,eth_type=0x0800,actions=output:2
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofputil"
+)
+
+// command is a handler of the command line command.
+type command struct {
+	// args is a usage of the command arguments.
+	args string
+
+	// min and max are the minimum and maximum numbers of the
+	// arguments of the command excluding the switch address.
+	min, max int
+
+	run func(c *client, w io.Writer, args []string) error
+}
+
+var commands = map[string]command{
+	"dump-flows": {"SWITCH [FLOW]", 0, 1, dumpFlows},
+	"add-flow":   {"SWITCH FLOW", 1, 1, addFlow},
+	"del-flows":  {"SWITCH [FLOW]", 0, 1, delFlows},
+	"dump-ports": {"SWITCH [PORT]", 0, 1, dumpPorts},
+	"monitor":    {"SWITCH", 0, 0, monitor},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] command switch [args...]\n\n",
+		os.Args[0])
+
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range []string{
+		"dump-flows", "add-flow", "del-flows", "dump-ports", "monitor",
+	} {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, commands[name].args)
+	}
+
+	fmt.Fprintln(os.Stderr, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	os.Exit(run())
+}
+
+// run executes the command and returns the exit code of the tool, so
+// the deferred calls run before the exit.
+func run() int {
+	timeout := flag.Duration("timeout", 5*time.Second,
+		"timeout of the switch operations, zero disables the timeout")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 {
+		usage()
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok || len(args)-2 < cmd.min || len(args)-2 > cmd.max {
+		usage()
+		return 2
+	}
+
+	// Monitor waits for the messages infinitely long.
+	if args[0] == "monitor" {
+		*timeout = 0
+	}
+
+	c, err := dial(args[1], *timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ofctl: %s: %s\n", args[1], err)
+		return 1
+	}
+
+	defer c.Close()
+
+	if err = cmd.run(c, os.Stdout, args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "ofctl: %s: %s\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+// optionalFlow parses the optional flow argument.
+func optionalFlow(args []string) (*flowSpec, error) {
+	if len(args) == 0 {
+		return parseFlow("")
+	}
+
+	return parseFlow(args[0])
+}
+
+func dumpFlows(c *client, w io.Writer, args []string) error {
+	spec, err := optionalFlow(args)
+	if err != nil {
+		return err
+	}
+
+	req := &ofp.FlowStatsRequest{
+		Table:    spec.Table,
+		OutPort:  spec.OutPort,
+		OutGroup: ofp.GroupAny,
+		Match:    spec.Match,
+	}
+
+	return c.multipart(ofp.MultipartTypeFlow, req, func(r io.Reader) error {
+		for {
+			var stats ofp.FlowStats
+			_, err := stats.ReadFrom(r)
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "cookie=0x%x, duration=%d.%03ds, table=%d, "+
+				"n_packets=%d, n_bytes=%d, priority=%d",
+				stats.Cookie, stats.DurationSec, stats.DurationNSec/1e6,
+				stats.Table, stats.PacketCount, stats.ByteCount,
+				stats.Priority)
+
+			if match := formatMatch(stats.Match); match != "" {
+				fmt.Fprintf(w, ",%s", match)
+			}
+
+			fmt.Fprintf(w, " actions=%s\n",
+				formatInstructions(stats.Instructions))
+		}
+	})
+}
+
+func addFlow(c *client, w io.Writer, args []string) error {
+	spec, err := parseFlow(args[0])
+	if err != nil {
+		return err
+	}
+
+	// The flow is added to the first table, unless specified.
+	if spec.Table == ofp.TableAll {
+		spec.Table = 0
+	}
+
+	return c.send(of.NewRequest(of.TypeFlowMod, &ofp.FlowMod{
+		Cookie:       spec.Cookie,
+		Table:        spec.Table,
+		Command:      ofp.FlowAdd,
+		IdleTimeout:  spec.IdleTimeout,
+		HardTimeout:  spec.HardTimeout,
+		Priority:     spec.Priority,
+		Buffer:       ofp.NoBuffer,
+		OutPort:      ofp.PortAny,
+		OutGroup:     ofp.GroupAny,
+		Match:        spec.Match,
+		Instructions: spec.Instructions,
+	}))
+}
+
+func delFlows(c *client, w io.Writer, args []string) error {
+	spec, err := optionalFlow(args)
+	if err != nil {
+		return err
+	}
+
+	return c.send(of.NewRequest(of.TypeFlowMod, &ofp.FlowMod{
+		Table:    spec.Table,
+		Command:  ofp.FlowDelete,
+		Buffer:   ofp.NoBuffer,
+		OutPort:  spec.OutPort,
+		OutGroup: ofp.GroupAny,
+		Match:    spec.Match,
+	}))
+}
+
+func dumpPorts(c *client, w io.Writer, args []string) error {
+	port := ofp.PortAny
+	if len(args) != 0 {
+		var err error
+		if port, err = parsePort(args[0]); err != nil {
+			return err
+		}
+	}
+
+	req := &ofp.PortStatsRequest{PortNo: port}
+	return c.multipart(ofp.MultipartTypePortStats, req, func(r io.Reader) error {
+		for {
+			var stats ofp.PortStats
+			_, err := stats.ReadFrom(r)
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "port %s: rx pkts=%d, bytes=%d, drop=%d, "+
+				"errs=%d\n", formatPort(stats.PortNo), stats.RxPackets,
+				stats.RxBytes, stats.RxDropped, stats.RxErrors)
+			fmt.Fprintf(w, "%*s  tx pkts=%d, bytes=%d, drop=%d, "+
+				"errs=%d\n", len(formatPort(stats.PortNo))+5, "",
+				stats.TxPackets, stats.TxBytes, stats.TxDropped,
+				stats.TxErrors)
+		}
+	})
+}
+
+func monitor(c *client, w io.Writer, args []string) error {
+	return ofputil.Monitor(c.conn, w)
+}
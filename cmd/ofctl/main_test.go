@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+	"github.com/netrack/openflow/ofputil"
+)
+
+// newSwitch creates a server emulating the switch with a single flow.
+func newSwitch(flows chan<- *ofp.FlowMod) *ofptest.Server {
+	mux := of.NewTypeMux()
+	mux.Handle(of.TypeHello, ofputil.HelloHandler(4, nil))
+
+	mux.HandleFunc(of.TypeBarrierRequest, func(rw of.ResponseWriter, r *of.Request) {
+		header := r.Header.Copy()
+		header.Type = of.TypeBarrierReply
+		rw.Write(header, nil)
+	})
+
+	mux.HandleFunc(of.TypeFlowMod, func(rw of.ResponseWriter, r *of.Request) {
+		var fmod ofp.FlowMod
+		fmod.ReadFrom(r.Body)
+		flows <- &fmod
+	})
+
+	mux.HandleFunc(of.TypeMultipartRequest, func(rw of.ResponseWriter, r *of.Request) {
+		mw := ofputil.NewMultipartWriter(rw, r, ofp.MultipartTypeFlow)
+		mw.Write(&ofp.FlowStats{
+			Priority:    10,
+			PacketCount: 3,
+			Match: ofputil.ExtendedMatch(
+				ofputil.MatchInPort(1),
+			),
+			Instructions: ofputil.ActionsApply(
+				&ofp.ActionOutput{Port: 2},
+			),
+		})
+		mw.Close()
+	})
+
+	return ofptest.NewServer(mux)
+}
+
+func TestCommands(t *testing.T) {
+	flows := make(chan *ofp.FlowMod, 1)
+
+	ts := newSwitch(flows)
+	defer ts.Close()
+
+	c, err := dial("tcp:"+ts.Listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect to the switch: %s", err)
+	}
+
+	defer c.Close()
+
+	var buf bytes.Buffer
+	if err = dumpFlows(c, &buf, nil); err != nil {
+		t.Fatalf("Failed to dump flows: %s", err)
+	}
+
+	expected := "priority=10,in_port=1 actions=output:2"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Invalid flows dumped: %s", buf.String())
+	}
+
+	err = addFlow(c, &buf, []string{"priority=5,tcp_dst=80,actions=drop"})
+	if err != nil {
+		t.Fatalf("Failed to add flow: %s", err)
+	}
+
+	fmod := <-flows
+	if fmod.Command != ofp.FlowAdd || fmod.Priority != 5 {
+		t.Errorf("Invalid flow modification: %v", fmod)
+	}
+
+	if match := formatMatch(fmod.Match); match != "tcp_dst=80" {
+		t.Errorf("Invalid match of the flow: %s", match)
+	}
+}
+
+func TestDialWithoutTimeout(t *testing.T) {
+	ts := newSwitch(make(chan *ofp.FlowMod, 1))
+	defer ts.Close()
+
+	// The zero timeout is used by the monitor command and disables
+	// the timeout instead of expiring immediately.
+	c, err := dial("tcp:"+ts.Listener.Addr().String(), 0)
+	if err != nil {
+		t.Fatalf("Failed to connect to the switch without timeout: %s", err)
+	}
+
+	c.Close()
+}
+
+func TestClientEcho(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+
+	c := &client{conn: of.NewConn(c1), timeout: time.Second}
+	defer c.Close()
+
+	sw := of.NewConn(c2)
+	data := make(chan []byte, 1)
+
+	go func() {
+		// Wait for the hello message of the client.
+		sw.Receive()
+
+		echo := &ofp.EchoRequest{Data: []byte("ping")}
+		of.Send(sw, of.NewRequest(of.TypeEchoRequest, echo))
+
+		var reply ofp.EchoReply
+		if r, err := sw.Receive(); err == nil {
+			reply.ReadFrom(r.Body)
+		}
+
+		data <- reply.Data
+		of.Send(sw, of.NewRequest(of.TypeHello, nil))
+	}()
+
+	if err := c.handshake(); err != nil {
+		t.Fatalf("Failed to complete handshake: %s", err)
+	}
+
+	if b := <-data; string(b) != "ping" {
+		t.Errorf("Echo reply must carry the request data: %q", b)
+	}
+}
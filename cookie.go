@@ -2,7 +2,7 @@ package openflow
 
 import (
 	"bytes"
-	"fmt"
+	"errors"
 	"io"
 	"io/ioutil"
 	"math/rand"
@@ -111,7 +111,7 @@ func CookieReaderOf(j CookieJar) CookieReader {
 		// interface, so it could be unmarshaled.
 		if !ok {
 			message := "openflow: not a valid cookie reader"
-			return nil, fmt.Errorf(message)
+			return nil, errors.New(message)
 		}
 
 		// Unmarshal the cookie jar and return it.
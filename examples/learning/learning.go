@@ -0,0 +1,303 @@
+// Package learning is a reference module of the reactive learning switch
+// built as a multi-table pipeline. Unlike the single-table learning
+// switch, the stages of the packet processing are programmed into the
+// separate tables and pass the state to each other with the metadata:
+//
+//   - The classification table assigns the ingress ports to the segments
+//     and writes the segment into the metadata. The frames received on
+//     the unassigned ports and the LLDP frames are dropped.
+//   - The MAC table recognizes the known source addresses of the segment.
+//     The frames of the unknown source addresses are copied to the
+//     controller, which learns the address, and continue to the output
+//     table.
+//   - The output table forwards the frames to the learned destination
+//     addresses of the segment, the rest of the frames are flooded to
+//     all ports of the segment.
+//
+// The module is attached to the server along with the handshake:
+//
+//	sw, err := learning.New(ofputil.NewMetadataAllocator(0))
+//	if err != nil {
+//		// ...
+//	}
+//
+//	sw.Segments = map[ofp.PortNo]uint64{1: 10, 2: 10, 3: 20, 4: 20}
+//	if err = sw.Install(conn); err != nil {
+//		// ...
+//	}
+//
+//	mux.Handle(of.TypePacketIn, ofputil.NewPacketInServer(sw))
+package learning
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofpconst"
+	"github.com/netrack/openflow/ofputil"
+)
+
+// Tables of the pipeline.
+const (
+	// TableClassify is a table assigning the ingress ports to the
+	// segments.
+	TableClassify ofp.Table = iota
+
+	// TableMAC is a table of the learned source addresses.
+	TableMAC
+
+	// TableOutput is a table forwarding the frames to the learned
+	// destination addresses.
+	TableOutput
+)
+
+// Priorities of the flow entries.
+const (
+	priorityMiss    uint16 = 0
+	prioritySegment uint16 = 10
+	priorityLearned uint16 = 100
+	priorityDrop    uint16 = 1000
+)
+
+const (
+	// SegmentWidth is a number of metadata bits of the segment.
+	SegmentWidth = 12
+
+	// DefaultIdleTimeout is a default idle timeout of the learned
+	// flow entries in seconds.
+	DefaultIdleTimeout uint16 = 300
+
+	// missSendLen is a number of bytes of the frame sent to the
+	// controller, it covers the Ethernet header.
+	missSendLen uint16 = 64
+)
+
+// Switch is a learning switch module. The ports must be assigned to the
+// segments before the pipeline is installed.
+type Switch struct {
+	// Segments maps the ports to the segments, the addresses are
+	// learned and the frames are flooded within the segment only.
+	Segments map[ofp.PortNo]uint64
+
+	// IdleTimeout is an idle timeout of the learned flow entries in
+	// seconds. DefaultIdleTimeout is used, when it is zero.
+	IdleTimeout uint16
+
+	segment ofputil.MetadataField
+}
+
+// New creates a new learning switch, the metadata bits of the segment are
+// allocated from the given allocator.
+func New(allocator *ofputil.MetadataAllocator) (*Switch, error) {
+	segment, err := allocator.Allocate("learning.segment", SegmentWidth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Switch{segment: segment}, nil
+}
+
+// idleTimeout returns the idle timeout of the learned flow entries.
+func (s *Switch) idleTimeout() uint16 {
+	if s.IdleTimeout == 0 {
+		return DefaultIdleTimeout
+	}
+	return s.IdleTimeout
+}
+
+// newFlow returns a new flow entry added to the table.
+func newFlow(table ofp.Table, priority uint16, match ofp.Match,
+	instructions ...ofp.Instruction) *ofp.FlowMod {
+
+	fmod := ofp.NewFlowMod(ofp.FlowAdd, nil)
+	fmod.Flags = 0
+	fmod.Table = table
+	fmod.Priority = priority
+	fmod.Match = match
+	fmod.Instructions = instructions
+	return fmod
+}
+
+// ports returns the ports of the segments ordered by the port number,
+// so the flow entries are generated in the stable order.
+func (s *Switch) ports() map[uint64][]ofp.PortNo {
+	segments := make(map[uint64][]ofp.PortNo)
+	for port, segment := range s.Segments {
+		segments[segment] = append(segments[segment], port)
+	}
+
+	for _, ports := range segments {
+		sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	}
+
+	return segments
+}
+
+// Flows returns the static flow entries of the pipeline: the
+// classification of the ports, the table-miss entries and the flooding
+// within the segments.
+func (s *Switch) Flows() ([]*ofp.FlowMod, error) {
+	var flows []*ofp.FlowMod
+
+	// The frames of the discovery protocol are never forwarded.
+	lldp := ofputil.ExtendedMatch(ofputil.MatchEthType(ofpconst.EtherTypeLLDP))
+	flows = append(flows, newFlow(TableClassify, priorityDrop, lldp))
+
+	segments := s.ports()
+	ids := make([]uint64, 0, len(segments))
+	for segment := range segments {
+		ids = append(ids, segment)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, segment := range ids {
+		bits, err := s.segment.Bits(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		var flood ofp.Actions
+		for _, port := range segments[segment] {
+			match := ofputil.ExtendedMatch(ofputil.MatchInPort(port))
+			flows = append(flows, newFlow(TableClassify, prioritySegment, match,
+				bits.Instruction(), &ofp.InstructionGotoTable{Table: TableMAC}))
+
+			// The switch does not send the frame back to the
+			// ingress port, so the frames are flooded to the
+			// rest of the ports of the segment.
+			flood = append(flood, &ofp.ActionOutput{Port: port})
+		}
+
+		match := ofputil.ExtendedMatch(bits.Match())
+		flows = append(flows, newFlow(TableOutput, prioritySegment, match,
+			ofputil.ActionsApply(flood...)...))
+	}
+
+	// The unknown source addresses are sent to the controller, while
+	// the frame continues to the output table.
+	miss := ofputil.ActionsApply(&ofp.ActionOutput{
+		Port:   ofp.PortController,
+		MaxLen: missSendLen,
+	})
+
+	miss = append(miss, &ofp.InstructionGotoTable{Table: TableOutput})
+	flows = append(flows, newFlow(TableMAC, priorityMiss,
+		ofputil.ExtendedMatch(), miss...))
+
+	if err := ofputil.CheckGotoTables(flows...); err != nil {
+		return nil, err
+	}
+
+	return flows, nil
+}
+
+// Install replaces the flow entries of the pipeline tables with the
+// static flow entries of the pipeline. The frames missing the
+// classification and output tables are dropped.
+func (s *Switch) Install(conn of.Conn) error {
+	flows, err := s.Flows()
+	if err != nil {
+		return err
+	}
+
+	var reqs []*of.Request
+	for _, table := range []ofp.Table{TableClassify, TableMAC, TableOutput} {
+		reqs = append(reqs, ofputil.TableFlush(table))
+	}
+
+	reqs = append(reqs,
+		ofputil.FlowDrop(TableClassify),
+		ofputil.FlowDrop(TableOutput),
+	)
+
+	for _, flow := range flows {
+		reqs = append(reqs, of.NewRequest(of.TypeFlowMod, flow))
+	}
+
+	return of.Send(conn, reqs...)
+}
+
+// Learn returns the flow entries learning the source address of the
+// frame of the packet-in message sent by the MAC table. Nil is returned
+// for the multicast source addresses.
+func (s *Switch) Learn(p *ofp.PacketIn) ([]*ofp.FlowMod, error) {
+	if p.Table != TableMAC {
+		return nil, fmt.Errorf("learning: packet-in from table %d", p.Table)
+	}
+
+	port, ok := p.InPort()
+	if !ok {
+		return nil, fmt.Errorf("learning: packet-in without in_port")
+	}
+
+	if len(p.Data) < 14 {
+		return nil, fmt.Errorf("learning: frame of %d bytes is too short",
+			len(p.Data))
+	}
+
+	src := net.HardwareAddr(p.Data[6:12])
+	if src[0]&0x01 != 0 {
+		return nil, nil
+	}
+
+	// The metadata is omitted from the match of the packet-in message,
+	// when it is zero.
+	var metadata uint64
+	if xm := p.Match.Field(ofp.XMTypeMetadata); xm != nil && len(xm.Value) == 8 {
+		metadata = binary.BigEndian.Uint64(xm.Value)
+	}
+
+	bits, err := s.segment.Bits(s.segment.Value(metadata))
+	if err != nil {
+		return nil, err
+	}
+
+	matchSrc, err := ofputil.MatchEthSrc(src)
+	if err != nil {
+		return nil, err
+	}
+
+	matchDst, err := ofputil.MatchEthDst(src)
+	if err != nil {
+		return nil, err
+	}
+
+	known := newFlow(TableMAC, priorityLearned,
+		ofputil.ExtendedMatch(bits.Match(), ofputil.MatchInPort(port), matchSrc),
+		&ofp.InstructionGotoTable{Table: TableOutput})
+
+	forward := newFlow(TableOutput, priorityLearned,
+		ofputil.ExtendedMatch(bits.Match(), matchDst),
+		ofputil.ActionsApply(&ofp.ActionOutput{Port: port})...)
+
+	for _, flow := range []*ofp.FlowMod{known, forward} {
+		flow.IdleTimeout = s.idleTimeout()
+	}
+
+	return []*ofp.FlowMod{known, forward}, nil
+}
+
+// ServePacketIn implements ofputil.PacketInHandler interface. It installs
+// the flow entries learning the source address of the frame.
+func (s *Switch) ServePacketIn(rw of.ResponseWriter, r *of.Request, p *ofputil.PacketIn) {
+	flows, err := s.Learn(&p.PacketIn)
+	if err != nil {
+		ofputil.Logf(r, "%v", err)
+		return
+	}
+
+	for _, flow := range flows {
+		header := r.Header.Copy()
+		header.Type = of.TypeFlowMod
+
+		if err = rw.Write(header, flow); err != nil {
+			ofputil.Logf(r, "learning: failed to install flow: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package learning
+
+import (
+	"encoding/binary"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+	"github.com/netrack/openflow/ofputil"
+)
+
+// newSwitch returns the switch with two segments of two ports each.
+func newSwitch(t *testing.T) *Switch {
+	// The lowest bits are reserved, so the segment is shifted.
+	sw, err := New(ofputil.NewMetadataAllocator(0xff))
+	if err != nil {
+		t.Fatalf("Failed to create learning switch: %s", err)
+	}
+
+	sw.Segments = map[ofp.PortNo]uint64{1: 10, 2: 10, 3: 20, 4: 20}
+	return sw
+}
+
+func TestSwitchInstall(t *testing.T) {
+	sw := newSwitch(t)
+	conn := ofptest.NewConnRecorder()
+
+	if err := sw.Install(conn); err != nil {
+		t.Fatalf("Failed to install pipeline: %s", err)
+	}
+
+	// Three tables are flushed, two table-miss drop entries, the
+	// LLDP drop entry, four ports, two segments and the MAC table-miss.
+	if conn.Len() != 3+2+1+4+2+1 || conn.Flushed() != 1 {
+		t.Fatalf("Invalid number of sent flow mods: %d", conn.Len())
+	}
+
+	var classify ofp.FlowMod
+	if err := conn.Decode(6, &classify); err != nil {
+		t.Fatalf("Failed to decode classification flow: %s", err)
+	}
+
+	meta, ok := classify.Instructions[0].(*ofp.InstructionWriteMetadata)
+	if !ok || meta.Metadata != 10<<8 || meta.MetadataMask != 0xfff00 {
+		t.Errorf("Invalid metadata of the segment: %v", classify.Instructions)
+	}
+
+	var miss ofp.FlowMod
+	if err := conn.Decode(conn.Len()-1, &miss); err != nil {
+		t.Fatalf("Failed to decode table-miss flow: %s", err)
+	}
+
+	if miss.Table != TableMAC || len(miss.Instructions) != 2 {
+		t.Errorf("Invalid MAC table-miss flow: %v", miss)
+	}
+}
+
+func TestSwitchLearn(t *testing.T) {
+	sw := newSwitch(t)
+
+	frame := make([]byte, 60)
+	copy(frame[6:], []byte{0x02, 0, 0, 0, 0, 0x03})
+
+	packet := &ofp.PacketIn{
+		Buffer: ofp.NoBuffer,
+		Length: uint16(len(frame)),
+		Reason: ofp.PacketInReasonAction,
+		Table:  TableMAC,
+		Match: ofputil.ExtendedMatch(
+			ofputil.MatchInPort(3),
+			ofputil.MatchMetadata(20<<8),
+		),
+		Data: frame,
+	}
+
+	rw := ofptest.NewRecorder()
+	server := ofputil.NewPacketInServer(sw)
+	server.Serve(rw, of.NewRequest(of.TypePacketIn, packet))
+
+	if err := rw.ExpectTypes(of.TypeFlowMod, of.TypeFlowMod); err != nil {
+		t.Fatal(err)
+	}
+
+	var known, forward ofp.FlowMod
+	if err := rw.Decode(0, &known); err != nil {
+		t.Fatalf("Failed to decode MAC flow: %s", err)
+	}
+
+	if err := rw.Decode(1, &forward); err != nil {
+		t.Fatalf("Failed to decode output flow: %s", err)
+	}
+
+	if known.Table != TableMAC || len(known.Match.Fields) != 3 {
+		t.Errorf("Invalid MAC flow: %v", known)
+	}
+
+	if forward.Table != TableOutput || forward.IdleTimeout != DefaultIdleTimeout {
+		t.Errorf("Invalid output flow: %v", forward)
+	}
+
+	xm := forward.Match.Field(ofp.XMTypeMetadata)
+	if xm == nil || binary.BigEndian.Uint64(xm.Value) != 20<<8 {
+		t.Errorf("Output flow must match the segment: %v", forward.Match)
+	}
+
+	apply := forward.Instructions[0].(*ofp.InstructionApplyActions)
+	if output := apply.Actions[0].(*ofp.ActionOutput); output.Port != 3 {
+		t.Errorf("Invalid output port of the learned address: %d", output.Port)
+	}
+
+	// The multicast source addresses are not learned.
+	frame[6] = 0x01
+	flows, err := sw.Learn(packet)
+	if err != nil || flows != nil {
+		t.Errorf("Multicast address must not be learned: %v, %v", flows, err)
+	}
+}
@@ -0,0 +1,180 @@
+package openflow
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrDeadlineUnsupported is returned when the deadline is set on the
+// framed connection, which framer does not support deadlines.
+var ErrDeadlineUnsupported = errors.New(
+	"openflow: Deadlines are not supported by the framer")
+
+// A Framer reads and writes the OpenFlow messages framed by the
+// underlying transport.
+//
+// The default connection relies on the length of the message specified
+// in the header to delimit messages in the byte stream. The transports,
+// that carry the messages as discrete frames, like websockets or gRPC
+// streams, or apply the compression to the stream, could implement this
+// interface to reuse the message codecs.
+//
+// The framer could optionally implement SetReadDeadline and
+// SetWriteDeadline methods of the net.Conn interface to support
+// deadlines of the connection.
+type Framer interface {
+	// ReadFrame reads the next message in the wire format including
+	// the header. The returned slice is owned by the caller.
+	ReadFrame() ([]byte, error)
+
+	// WriteFrame writes the message in the wire format including the
+	// header. The framer must not retain the slice.
+	WriteFrame([]byte) error
+
+	// Close closes the transport. Any blocked ReadFrame or WriteFrame
+	// calls will be unblocked and return errors.
+	Close() error
+}
+
+// framedConn is an OpenFlow connection over the framed transport.
+type framedConn struct {
+	framer Framer
+	laddr  net.Addr
+	raddr  net.Addr
+
+	// frames is a list of the serialized messages, written to the
+	// framer on the Flush call.
+	frames [][]byte
+	mu     sync.Mutex
+
+	// identified is set, when the features reply is received.
+	identified int32
+}
+
+// NewFramedConn creates a new OpenFlow protocol connection, that reads
+// and writes messages using the given framer. The addresses are returned
+// from LocalAddr and RemoteAddr methods of the connection.
+//
+// The connection could be used both to dial the switch and to serve the
+// switch, in the latter case the connections are returned by the
+// Listener passed to the ServeListener method of the Server.
+func NewFramedConn(f Framer, laddr, raddr net.Addr) Conn {
+	countConn()
+	return &framedConn{framer: f, laddr: laddr, raddr: raddr}
+}
+
+// Receive reads the next frame and decodes it into the request.
+func (c *framedConn) Receive() (*Request, error) {
+	b, err := c.framer.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < headerlen {
+		countDecodeError()
+		return nil, ErrCorruptedHeader
+	}
+
+	r := &Request{Addr: c.raddr, conn: c}
+	r.Header = parseHeader(b)
+	r.setProto()
+
+	// Frame must contain exactly one message.
+	if r.Header.Len() != len(b) {
+		countDecodeError()
+		return nil, ErrCorruptedHeader
+	}
+
+	body := b[headerlen:]
+	r.Body = bytes.NewBuffer(body)
+	r.ContentLength = int64(len(body))
+	r.raw = body
+
+	countReceived(r.Header.Type)
+	countFeatures(&c.identified, r)
+	return r, nil
+}
+
+// Send serializes the request into the output buffer.
+func (c *framedConn) Send(r *Request) error {
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.frames = append(c.frames, buf.Bytes())
+	countSent(r.Header.Type)
+	return nil
+}
+
+// Flush writes the buffered messages to the framer, each message is
+// written as a separate frame.
+func (c *framedConn) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.frames) > 0 {
+		if err := c.framer.WriteFrame(c.frames[0]); err != nil {
+			return err
+		}
+
+		c.frames[0] = nil
+		c.frames = c.frames[1:]
+	}
+
+	c.frames = nil
+	return nil
+}
+
+// Close closes the framer.
+func (c *framedConn) Close() error {
+	return c.framer.Close()
+}
+
+// LocalAddr returns the local network address.
+func (c *framedConn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+// RemoteAddr returns the remote network address.
+func (c *framedConn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+// SetDeadline sets the read and write deadlines associated with the
+// connection.
+func (c *framedConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for the future Receive calls, when
+// it is supported by the framer.
+func (c *framedConn) SetReadDeadline(t time.Time) error {
+	f, ok := c.framer.(interface{ SetReadDeadline(time.Time) error })
+	if !ok {
+		return ErrDeadlineUnsupported
+	}
+
+	return f.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for the future Flush calls, when
+// it is supported by the framer.
+func (c *framedConn) SetWriteDeadline(t time.Time) error {
+	f, ok := c.framer.(interface{ SetWriteDeadline(time.Time) error })
+	if !ok {
+		return ErrDeadlineUnsupported
+	}
+
+	return f.SetWriteDeadline(t)
+}
@@ -0,0 +1,140 @@
+package openflow
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// chanFramer is a framer, that passes frames through the channels.
+type chanFramer struct {
+	r <-chan []byte
+	w chan<- []byte
+}
+
+func (f *chanFramer) ReadFrame() ([]byte, error) {
+	b, ok := <-f.r
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return b, nil
+}
+
+func (f *chanFramer) WriteFrame(b []byte) error {
+	f.w <- append([]byte(nil), b...)
+	return nil
+}
+
+func (f *chanFramer) Close() error {
+	return nil
+}
+
+func TestFramedConn(t *testing.T) {
+	ch := make(chan []byte, 2)
+	addr := dummyAddr("framer")
+	c := NewFramedConn(&chanFramer{r: ch, w: ch}, addr, addr)
+
+	req := NewRequest(TypeEchoRequest, bytes.NewReader([]byte{1, 2, 3}))
+	req.Header.Transaction = 42
+
+	err := Send(c, req, NewRequest(TypeBarrierRequest, nil))
+	if err != nil {
+		t.Fatalf("Failed to send requests: %s", err)
+	}
+
+	if len(ch) != 2 {
+		t.Fatalf("Each message must be sent in own frame: %d", len(ch))
+	}
+
+	r, err := c.Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive request: %s", err)
+	}
+
+	if r.Header.Type != TypeEchoRequest || r.Header.Transaction != 42 {
+		t.Errorf("Invalid header of the request: %v", r.Header)
+	}
+
+	if raw, _ := r.RawBody(); !bytes.Equal(raw, []byte{1, 2, 3}) {
+		t.Errorf("Invalid body of the request: %x", raw)
+	}
+
+	if r.Conn() != c || r.Addr != addr {
+		t.Errorf("Request must reference the connection")
+	}
+
+	r, err = c.Receive()
+	if err != nil || r.Header.Type != TypeBarrierRequest {
+		t.Fatalf("Failed to receive barrier request: %v", err)
+	}
+
+	// Frame containing a truncated message must be rejected.
+	ch <- []byte{4, byte(TypeHello), 0, 16, 0, 0, 0, 0}
+	if _, err = c.Receive(); err != ErrCorruptedHeader {
+		t.Errorf("Corrupted header error expected: %v", err)
+	}
+
+	if err = c.SetDeadline(time.Now()); err != ErrDeadlineUnsupported {
+		t.Errorf("Deadlines must not be supported: %v", err)
+	}
+}
+
+// chanListener accepts the connections passed through the channel.
+type chanListener struct {
+	conns chan Conn
+}
+
+func (l *chanListener) Accept() (Conn, error) {
+	c, ok := <-l.conns
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return c, nil
+}
+
+func (l *chanListener) Close() error {
+	return nil
+}
+
+func (l *chanListener) Addr() net.Addr {
+	return dummyAddr("framer")
+}
+
+func TestServerFramedConn(t *testing.T) {
+	c2s, s2c := make(chan []byte, 1), make(chan []byte, 1)
+	addr := dummyAddr("framer")
+
+	ln := &chanListener{conns: make(chan Conn, 1)}
+	ln.conns <- NewFramedConn(&chanFramer{r: c2s, w: s2c}, addr, addr)
+	close(ln.conns)
+
+	srv := &Server{Handler: HandlerFunc(func(rw ResponseWriter, r *Request) {
+		raw, _ := r.RawBody()
+		r.Reply(rw, bytes.NewReader(raw), TypeEchoReply)
+	})}
+
+	if err := srv.ServeListener(ln); err != io.EOF {
+		t.Fatalf("Listener error expected: %v", err)
+	}
+
+	c := NewFramedConn(&chanFramer{r: s2c, w: c2s}, addr, addr)
+	defer close(c2s)
+
+	req := NewRequest(TypeEchoRequest, bytes.NewReader([]byte{1, 2, 3}))
+	if err := Send(c, req); err != nil {
+		t.Fatalf("Failed to send echo request: %s", err)
+	}
+
+	r, err := c.Receive()
+	if err != nil || r.Header.Type != TypeEchoReply {
+		t.Fatalf("Failed to receive echo reply: %v", err)
+	}
+
+	if raw, _ := r.RawBody(); !bytes.Equal(raw, []byte{1, 2, 3}) {
+		t.Errorf("Invalid body of the echo reply: %x", raw)
+	}
+}
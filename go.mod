@@ -1 +1,3 @@
 module github.com/netrack/openflow
+
+go 1.18
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"sort"
 
 	"github.com/netrack/openflow/internal/encoding"
+	"github.com/netrack/openflow/ofp"
 )
 
 const (
@@ -201,6 +203,71 @@ var typeText = map[Type]string{
 	TypeMeterMod:              "TypeMeterMod",
 }
 
+// TypeInfo describes the message type. The list of message types could
+// be used to build the tools, like command line interfaces, fuzzers and
+// documentation generators, without copying the tables.
+type TypeInfo struct {
+	// Name is a string representation of the type.
+	Name string
+
+	// Type is a message type.
+	Type Type
+
+	// Version is a wire version of the protocol, in which the type
+	// was introduced with the current value.
+	Version uint8
+}
+
+// typeVersion maps the message types to the versions, in which the
+// types were introduced with the current value. The types starting from
+// the group modification were renumbered in version 1.1 of the protocol.
+var typeVersion = map[Type]uint8{
+	TypeHello:                 ofp.Version10,
+	TypeError:                 ofp.Version10,
+	TypeEchoRequest:           ofp.Version10,
+	TypeEchoReply:             ofp.Version10,
+	TypeExperiment:            ofp.Version10,
+	TypeFeaturesRequest:       ofp.Version10,
+	TypeFeaturesReply:         ofp.Version10,
+	TypeGetConfigRequest:      ofp.Version10,
+	TypeGetConfigReply:        ofp.Version10,
+	TypeSetConfig:             ofp.Version10,
+	TypePacketIn:              ofp.Version10,
+	TypeFlowRemoved:           ofp.Version10,
+	TypePortStatus:            ofp.Version10,
+	TypePacketOut:             ofp.Version10,
+	TypeFlowMod:               ofp.Version10,
+	TypeGroupMod:              ofp.Version11,
+	TypePortMod:               ofp.Version11,
+	TypeTableMod:              ofp.Version11,
+	TypeMultipartRequest:      ofp.Version11,
+	TypeMultipartReply:        ofp.Version11,
+	TypeBarrierRequest:        ofp.Version11,
+	TypeBarrierReply:          ofp.Version11,
+	TypeQueueGetConfigRequest: ofp.Version11,
+	TypeQueueGetConfigReply:   ofp.Version11,
+	TypeRoleRequest:           ofp.Version12,
+	TypeRoleReply:             ofp.Version12,
+	TypeGetAsyncRequest:       ofp.Version13,
+	TypeGetAsyncReply:         ofp.Version13,
+	TypeSetAsync:              ofp.Version13,
+	TypeMeterMod:              ofp.Version13,
+}
+
+// Types returns the metadata of the message types sorted by value.
+func Types() []TypeInfo {
+	types := make([]TypeInfo, 0, len(typeText))
+	for t, name := range typeText {
+		types = append(types, TypeInfo{name, t, typeVersion[t]})
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Type < types[j].Type
+	})
+
+	return types
+}
+
 // The Header is a response header. It contains the negotiated
 // version of the OpenFlow, a type and length of the message.
 type Header struct {
@@ -239,6 +306,26 @@ func (h *Header) ReadFrom(r io.Reader) (int64, error) {
 	return encoding.ReadFrom(r, &h.Version, &h.Type, &h.Length, &h.Transaction)
 }
 
+// PeekHeader parses the message header from the given wire bytes
+// without consuming them. It could be used to inspect the messages
+// embedded into other protocols or memory-mapped captures.
+//
+// The io.ErrUnexpectedEOF is returned when the slice is shorter than
+// the header, and ErrCorruptedHeader when the length of the message
+// is less than the length of the header.
+func PeekHeader(b []byte) (Header, error) {
+	if len(b) < headerlen {
+		return Header{}, io.ErrUnexpectedEOF
+	}
+
+	h := parseHeader(b)
+	if h.Len() < headerlen {
+		return h, ErrCorruptedHeader
+	}
+
+	return h, nil
+}
+
 // TransactionMatcher creates a new matcher that matches the request
 // by the transaction identifier.
 //
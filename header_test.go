@@ -2,6 +2,7 @@ package openflow
 
 import (
 	"bytes"
+	"io"
 	"testing"
 )
 
@@ -35,3 +36,57 @@ func TestTransactionMatcher(t *testing.T) {
 		t.Errorf("Matched request of different transaction")
 	}
 }
+
+func TestTypes(t *testing.T) {
+	types := Types()
+	if len(types) != len(typeText) {
+		t.Fatalf("Invalid number of types: %d", len(types))
+	}
+
+	for i, info := range types {
+		if info.Type != Type(i) || info.Name != info.Type.String() {
+			t.Errorf("Invalid metadata of type %d: %v", i, info)
+		}
+	}
+
+	versions := map[Type]uint8{
+		TypeHello:            1,
+		TypeExperiment:       1,
+		TypeFeaturesRequest:  1,
+		TypePacketIn:         1,
+		TypeFlowMod:          1,
+		TypeGroupMod:         2,
+		TypePortMod:          2,
+		TypeMultipartRequest: 2,
+		TypeRoleReply:        3,
+		TypeMeterMod:         4,
+	}
+
+	for typ, version := range versions {
+		if v := types[typ].Version; v != version {
+			t.Errorf("Invalid version of %s: %d", typ, v)
+		}
+	}
+}
+
+func TestPeekHeader(t *testing.T) {
+	b := []byte{4, byte(TypeFlowMod), 0, 16, 0x01, 0x02, 0x03, 0x04, 0xff}
+
+	h, err := PeekHeader(b)
+	if err != nil {
+		t.Fatalf("Failed to peek header: %s", err)
+	}
+
+	want := Header{4, TypeFlowMod, 16, 0x01020304}
+	if h != want {
+		t.Errorf("Invalid header returned: %v != %v", h, want)
+	}
+
+	if _, err = PeekHeader(b[:7]); err != io.ErrUnexpectedEOF {
+		t.Errorf("Unexpected EOF error expected: %v", err)
+	}
+
+	if _, err = PeekHeader([]byte{4, 0, 0, 4, 0, 0, 0, 0}); err != ErrCorruptedHeader {
+		t.Errorf("Corrupted header error expected: %v", err)
+	}
+}
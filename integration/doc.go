@@ -0,0 +1,14 @@
+// Package integration contains the tests of the library against the
+// Open vSwitch instance running in the Docker container.
+//
+// The tests are excluded from the regular builds with the integration
+// build tag, since they require the Docker daemon and access to the
+// host network. To run the tests:
+//
+//	go test -tags integration ./integration
+//
+// The image of the switch could be overridden with the OFP_OVS_IMAGE
+// environment variable, the image must start the OVS daemons and provide
+// the ovs-vsctl tool. The bridge uses the userspace datapath, so the
+// kernel module of Open vSwitch is not required on the host.
+package integration
@@ -0,0 +1,295 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofputil"
+)
+
+// defaultImage is an image of the Open vSwitch used when OFP_OVS_IMAGE
+// environment variable is not set.
+const defaultImage = "globocom/openvswitch"
+
+// timeout is a maximum duration of waiting for the switch reply.
+const timeout = 10 * time.Second
+
+// ovsSwitch is an Open vSwitch instance running in the container.
+type ovsSwitch struct {
+	t         *testing.T
+	container string
+	bridge    string
+}
+
+// startSwitch starts the container with Open vSwitch and creates the
+// bridge connected to the controller listening on the given address.
+func startSwitch(t *testing.T, controller net.Addr) *ovsSwitch {
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skipf("Docker is not available: %s", err)
+	}
+
+	image := os.Getenv("OFP_OVS_IMAGE")
+	if image == "" {
+		image = defaultImage
+	}
+
+	out, err := exec.Command("docker", "run", "--rm", "--detach",
+		"--privileged", "--network", "host", image).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to start the switch container: %s: %s", err, out)
+	}
+
+	sw := &ovsSwitch{
+		t:         t,
+		container: strings.TrimSpace(string(out)),
+		bridge:    fmt.Sprintf("ofp%d", os.Getpid()%10000),
+	}
+
+	t.Cleanup(sw.stop)
+
+	// Wait until the OVSDB server accepts the connections.
+	deadline := time.Now().Add(timeout)
+	for sw.vsctl("show") != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("Failed to wait for the switch to start")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	err = sw.vsctl("add-br", sw.bridge, "--",
+		"set", "bridge", sw.bridge,
+		"datapath_type=netdev",
+		"fail_mode=secure",
+		"protocols=OpenFlow13")
+	if err != nil {
+		t.Fatalf("Failed to create the bridge: %s", err)
+	}
+
+	err = sw.vsctl("set-controller", sw.bridge, "tcp:"+controller.String())
+	if err != nil {
+		t.Fatalf("Failed to set the controller: %s", err)
+	}
+
+	return sw
+}
+
+// vsctl executes the ovs-vsctl command in the container.
+func (sw *ovsSwitch) vsctl(args ...string) error {
+	args = append([]string{"exec", sw.container, "ovs-vsctl"}, args...)
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// stop removes the container of the switch.
+func (sw *ovsSwitch) stop() {
+	out, err := exec.Command("docker", "kill", sw.container).CombinedOutput()
+	if err != nil {
+		sw.t.Logf("Failed to stop the switch container: %s: %s", err, out)
+	}
+}
+
+// connect starts the switch and accepts the connection from it.
+func connect(t *testing.T) of.Conn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+
+	defer ln.Close()
+	startSwitch(t, ln.Addr())
+
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(timeout))
+	rwc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept the switch connection: %s", err)
+	}
+
+	conn := of.NewConn(rwc)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// receive reads the messages from the connection until the message of
+// the given type is received. The echo requests are replied.
+func receive(t *testing.T, conn of.Conn, typ of.Type) *of.Request {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	for {
+		r, err := conn.Receive()
+		if err != nil {
+			t.Fatalf("Failed to receive %s message: %s", typ, err)
+		}
+
+		switch r.Header.Type {
+		case typ:
+			return r
+		case of.TypeEchoRequest:
+			data, _ := r.RawBody()
+			err = of.Send(conn, r.NewReply(of.TypeEchoReply, bytes.NewReader(data)))
+			if err != nil {
+				t.Fatalf("Failed to send echo reply: %s", err)
+			}
+		case of.TypeError:
+			e, err := ofp.ReadError(r.Body)
+			if err != nil {
+				t.Fatalf("Failed to read error message: %s", err)
+			}
+			t.Fatalf("Switch replied with error: %s", e)
+		}
+	}
+}
+
+// multipart sends the multipart request and returns the body of the
+// reply without the multipart header.
+func multipart(t *testing.T, conn of.Conn, mtype ofp.MultipartType,
+	body io.WriterTo) io.Reader {
+
+	req := of.NewRequest(of.TypeMultipartRequest,
+		ofp.NewMultipartRequest(mtype, body))
+
+	if err := of.Send(conn, req); err != nil {
+		t.Fatalf("Failed to send %s request: %s", mtype, err)
+	}
+
+	r := receive(t, conn, of.TypeMultipartReply)
+
+	var reply ofp.MultipartReply
+	if _, err := reply.ReadFrom(r.Body); err != nil {
+		t.Fatalf("Failed to read multipart reply: %s", err)
+	}
+
+	if reply.Type != mtype {
+		t.Fatalf("Invalid multipart reply type: %s", reply.Type)
+	}
+
+	return r.Body
+}
+
+func TestOVS(t *testing.T) {
+	conn := connect(t)
+
+	h := &ofputil.Handshake{
+		HelloTimeout:    timeout,
+		FeaturesTimeout: timeout,
+	}
+
+	t.Run("Handshake", func(t *testing.T) {
+		features, err := h.Run(conn)
+		if err != nil {
+			t.Fatalf("Failed to perform handshake: %s", err)
+		}
+
+		if features.DatapathID == 0 {
+			t.Errorf("Datapath identifier must be assigned")
+		}
+	})
+
+	const cookie = 0x0f0e0d0c
+
+	t.Run("FlowInstall", func(t *testing.T) {
+		dst, err := ofputil.MatchIPv4Dst(net.IPv4(10, 0, 0, 1))
+		if err != nil {
+			t.Fatalf("Failed to create the match: %s", err)
+		}
+
+		mod := &ofp.FlowMod{
+			Cookie:   cookie,
+			Command:  ofp.FlowAdd,
+			Priority: 100,
+			Buffer:   ofp.NoBuffer,
+			OutPort:  ofp.PortAny,
+			OutGroup: ofp.GroupAny,
+			Match: ofputil.ExtendedMatch(
+				ofputil.MatchEthType(0x0800), dst),
+			Instructions: ofp.Instructions{
+				&ofp.InstructionApplyActions{Actions: ofp.Actions{
+					&ofp.ActionOutput{Port: ofp.PortController, MaxLen: ofp.ContentLenNoBuffer},
+				}},
+			},
+		}
+
+		err = of.Send(conn,
+			of.NewRequest(of.TypeFlowMod, mod),
+			of.NewRequest(of.TypeBarrierRequest, nil))
+		if err != nil {
+			t.Fatalf("Failed to install the flow: %s", err)
+		}
+
+		receive(t, conn, of.TypeBarrierReply)
+
+		body := multipart(t, conn, ofp.MultipartTypeFlow, &ofp.FlowStatsRequest{
+			Table:      ofp.TableAll,
+			OutPort:    ofp.PortAny,
+			OutGroup:   ofp.GroupAny,
+			Cookie:     cookie,
+			CookieMask: ^uint64(0),
+			Match:      ofp.Match{Type: ofp.MatchTypeXM},
+		})
+
+		var flow ofp.FlowStats
+		if _, err = flow.ReadFrom(body); err != nil {
+			t.Fatalf("Failed to read the installed flow: %s", err)
+		}
+
+		if flow.Priority != mod.Priority {
+			t.Errorf("Invalid priority of the flow: %d", flow.Priority)
+		}
+	})
+
+	t.Run("PacketOut", func(t *testing.T) {
+		// The frame is sent back to the controller in the
+		// packet-in message with an action reason.
+		data := make([]byte, 64)
+		copy(data, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+		out := &ofp.PacketOut{
+			Buffer: ofp.NoBuffer,
+			InPort: ofp.PortController,
+			Actions: ofp.Actions{
+				&ofp.ActionOutput{Port: ofp.PortController, MaxLen: ofp.ContentLenNoBuffer},
+			},
+			Data: data,
+		}
+
+		if err := of.Send(conn, of.NewRequest(of.TypePacketOut, out)); err != nil {
+			t.Fatalf("Failed to send packet-out: %s", err)
+		}
+
+		r := receive(t, conn, of.TypePacketIn)
+
+		var in ofp.PacketIn
+		if _, err := in.ReadFrom(r.Body); err != nil {
+			t.Fatalf("Failed to read packet-in: %s", err)
+		}
+
+		if !bytes.Equal(in.Data, data) {
+			t.Errorf("Invalid packet-in data: %x", in.Data)
+		}
+	})
+
+	t.Run("PortStats", func(t *testing.T) {
+		body := multipart(t, conn, ofp.MultipartTypePortStats,
+			&ofp.PortStatsRequest{PortNo: ofp.PortAny})
+
+		// The bridge has at least the local port.
+		var stats ofp.PortStats
+		if _, err := stats.ReadFrom(body); err != nil {
+			t.Fatalf("Failed to read port statistics: %s", err)
+		}
+	})
+}
@@ -3,9 +3,11 @@ package encoding
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"io"
-	"reflect"
+	"sync"
+	"sync/atomic"
 )
 
 // reader type used to calculate the count of bytes retrieved from the
@@ -22,6 +24,50 @@ func (r *reader) Read(b []byte) (int, error) {
 	return n, err
 }
 
+// contextReader is a reader, that carries the context of decoding, so
+// the decoders of the nested elements could look up the options of the
+// decoding, like the check mode of the message.
+type contextReader struct {
+	io.Reader
+	ctx context.Context
+}
+
+// Context returns the context of decoding.
+func (r *contextReader) Context() context.Context {
+	return r.ctx
+}
+
+// NewContextReader returns a reader, that reads from r and carries the
+// given context of decoding.
+func NewContextReader(r io.Reader, ctx context.Context) io.Reader {
+	return &contextReader{r, ctx}
+}
+
+// ReaderContext returns the context of decoding carried by the reader,
+// or nil when the reader has no context.
+func ReaderContext(r io.Reader) context.Context {
+	if rd, ok := r.(*contextReader); ok {
+		return rd.ctx
+	}
+	return nil
+}
+
+// InheritReader returns a reader, that reads from r and carries the
+// context of decoding of the parent reader, if any. It is used when
+// the nested elements are decoded from an intermediate reader.
+func InheritReader(parent, r io.Reader) io.Reader {
+	if ctx := ReaderContext(parent); ctx != nil {
+		return &contextReader{r, ctx}
+	}
+	return r
+}
+
+// LimitReader is like io.LimitReader, but the returned reader carries
+// the context of decoding of r.
+func LimitReader(r io.Reader, n int64) io.Reader {
+	return InheritReader(r, io.LimitReader(r, n))
+}
+
 // ReadWriter describes typed that are capable both, to write their
 // representation into the writer and read it from the reader.
 type ReadWriter interface {
@@ -112,26 +158,27 @@ func ReadFrom(r io.Reader, v ...interface{}) (int64, error) {
 	return rd.read, nil
 }
 
-// WriteSliceTo writes the slice of the types, that implement io.WriterTo
-// interface into the given writer.
-//
-// The function will panic if the given variable is not a slice or elements
-// of the slice does not implement interface io.WriterTo.
-func WriteSliceTo(w io.Writer, slice interface{}) (int64, error) {
-	var n int64
+// readerFrom is a constraint of the pointer to the type T, that
+// implements io.ReaderFrom interface.
+type readerFrom[T any] interface {
+	*T
+	io.ReaderFrom
+}
 
-	// Retrieve the lengt of the provided slice.
-	sliceValue := reflect.ValueOf(slice)
-	sliceLen := sliceValue.Len()
+// writerTo is a constraint of the pointer to the type T, that
+// implements io.WriterTo interface.
+type writerTo[T any] interface {
+	*T
+	io.WriterTo
+}
 
-	for index := 0; index < sliceLen; index++ {
-		// It is expected, that elements of the slice would not implement
-		// the io.WriterTo interface, but their pointers will.
-		addr := sliceValue.Index(index).Addr()
-		writer := addr.Interface().(io.WriterTo)
+// WriteSliceTo writes the slice of the types, which pointers implement
+// io.WriterTo interface, into the given writer.
+func WriteSliceTo[T any, PT writerTo[T]](w io.Writer, slice []T) (int64, error) {
+	var n int64
 
-		// Write the data to the provided writer instance.
-		nn, err := writer.WriteTo(w)
+	for index := range slice {
+		nn, err := PT(&slice[index]).WriteTo(w)
 		n += nn
 
 		if err != nil {
@@ -142,38 +189,33 @@ func WriteSliceTo(w io.Writer, slice interface{}) (int64, error) {
 	return n, nil
 }
 
-// ReadSliceFrom appends elements decoded using reader from reader maker
-// into slice of arbitrary type. Elements of the slice should be the
-// same type as produced by reader maker.
-func ReadSliceFrom(r io.Reader, rm ReaderMaker, slice interface{}) (int64, error) {
-	sliceValue := reflect.ValueOf(slice)
-	return ReadFunc(r, rm, func(reader io.ReaderFrom) {
-		elem := reflect.ValueOf(reader).Elem()
-		reflect.Append(sliceValue, elem)
+// ReadSliceFrom decodes the elements from the reader until the end of
+// file and appends them into the slice. The pointer to the type of the
+// slice elements must implement io.ReaderFrom interface.
+func ReadSliceFrom[T any, PT readerFrom[T]](r io.Reader, slice *[]T) (int64, error) {
+	return ReadFunc(r, func(elem PT) {
+		*slice = append(*slice, *elem)
 	})
 }
 
-// ReadFunc decodes elements using reader created by reader maker and
-// calls a callback function for each of them.
+// ReadFunc decodes the elements from the reader until the end of file
+// and calls a callback function for each of them.
 //
 // Returns a number of extracted bytes and error instance.
-func ReadFunc(r io.Reader, rm ReaderMaker, fn func(r io.ReaderFrom)) (int64, error) {
+func ReadFunc[T any, PT readerFrom[T]](r io.Reader, fn func(PT)) (int64, error) {
 	var n int64
 
 	for {
-		reader, err := rm.MakeReader()
-		if err != nil {
-			return n, err
-		}
+		elem := PT(new(T))
 
-		nn, err := reader.ReadFrom(r)
+		nn, err := elem.ReadFrom(r)
 		n += nn
 
 		if err != nil {
 			return n, SkipEOF(err)
 		}
 
-		fn(reader)
+		fn(elem)
 	}
 }
 
@@ -183,13 +225,11 @@ type ReaderMaker interface {
 	MakeReader() (io.ReaderFrom, error)
 }
 
-// ReaderMakerOf creates a new ReaderMaker based on the specified
-// type. Pointer to the type must implement io.ReaderFrom interface.
-func ReaderMakerOf(v interface{}) ReaderMaker {
-	valueType := reflect.TypeOf(v)
+// ReaderMakerOf creates a new ReaderMaker of the specified type.
+// Pointer to the type must implement io.ReaderFrom interface.
+func ReaderMakerOf[T any, PT readerFrom[T]]() ReaderMaker {
 	return ReaderMakerFunc(func() (io.ReaderFrom, error) {
-		value := reflect.New(valueType)
-		return value.Interface().(io.ReaderFrom), nil
+		return PT(new(T)), nil
 	})
 }
 
@@ -201,42 +241,138 @@ func (fn ReaderMakerFunc) MakeReader() (io.ReaderFrom, error) {
 	return fn()
 }
 
-func ScanFrom(r io.Reader, v interface{}, rm ReaderMaker) (int64, error) {
-	// Retrieve the size of the instruction type, that precedes
-	// every instruction body.
-	valType := reflect.TypeOf(v)
-	typeLen := int(valType.Elem().Size())
+// readerMakerDenseLen is a length of the dense part of the reader maker
+// table, the standard types of the protocol fit into this range.
+const readerMakerDenseLen = 256
+
+// readerMakerState is an immutable state of the reader maker table.
+type readerMakerState[T ~uint8 | ~uint16 | ~uint32] struct {
+	dense  []ReaderMaker
+	sparse map[T]ReaderMaker
+}
+
+// with returns a copy of the state with the reader maker of the given
+// type set, the receiver is left unchanged.
+func (s *readerMakerState[T]) with(typ T, rm ReaderMaker) *readerMakerState[T] {
+	next := &readerMakerState[T]{
+		dense:  s.dense,
+		sparse: make(map[T]ReaderMaker, len(s.sparse)),
+	}
+
+	for t, rm := range s.sparse {
+		next.sparse[t] = rm
+	}
+
+	if uint64(typ) >= readerMakerDenseLen {
+		next.sparse[typ] = rm
+		return next
+	}
+
+	size := len(s.dense)
+	if int(typ) >= size {
+		size = int(typ) + 1
+	}
+
+	next.dense = make([]ReaderMaker, size)
+	copy(next.dense, s.dense)
+	next.dense[typ] = rm
+	return next
+}
+
+// ReaderMakerTable is a lookup table of the reader makers. The types in
+// the contiguous range of the standard types are looked up in the slice,
+// the rest (usually the experimenter types) are looked up in the map.
+//
+// The table is safe for concurrent use: the lookups are lock-free, the
+// registration of the new reader makers copies the table, so the
+// readers never observe the partially updated state.
+type ReaderMakerTable[T ~uint8 | ~uint16 | ~uint32] struct {
+	mu    sync.Mutex
+	state atomic.Value
+}
+
+// NewReaderMakerTable creates a new lookup table from the given map.
+func NewReaderMakerTable[T ~uint8 | ~uint16 | ~uint32](m map[T]ReaderMaker) *ReaderMakerTable[T] {
+	state := &readerMakerState[T]{sparse: make(map[T]ReaderMaker)}
+	for typ, rm := range m {
+		state = state.with(typ, rm)
+	}
+
+	t := &ReaderMakerTable[T]{}
+	t.state.Store(state)
+	return t
+}
 
-	var n, num int64
-	// To keep the implementation of the instruction unmarshaling
-	// consistent with marshaling, we have to put the instruction
-	// type back to the reader during unmarshaling of the list of
-	// instructions.
+// load returns the current state of the table.
+func (t *ReaderMakerTable[T]) load() *readerMakerState[T] {
+	return t.state.Load().(*readerMakerState[T])
+}
+
+// Lookup returns the reader maker of the given type.
+func (t *ReaderMakerTable[T]) Lookup(typ T) (ReaderMaker, bool) {
+	s := t.load()
+	if uint64(typ) < uint64(len(s.dense)) {
+		rm := s.dense[typ]
+		return rm, rm != nil
+	}
+
+	rm, ok := s.sparse[typ]
+	return rm, ok
+}
+
+// Register sets the reader maker of the given type, the existing reader
+// maker of the same type is replaced. Registration with nil reader maker
+// removes the type from the table.
+func (t *ReaderMakerTable[T]) Register(typ T, rm ReaderMaker) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next := t.load().with(typ, rm)
+	if rm == nil {
+		delete(next.sparse, typ)
+	}
+
+	t.state.Store(next)
+}
+
+// ScanFrom decodes the list of type-length-value elements from the
+// reader until the end of file. The header of type H preceding each
+// element is peeked from the reader and passed to the given function,
+// that returns the reader of the element. The element reader decodes
+// the complete element including the header.
+func ScanFrom[H any](r io.Reader, fn func(H) (io.ReaderFrom, error)) (int64, error) {
+	var header H
+	headerLen := binary.Size(header)
+
+	var n int64
+	// To keep the implementation of the element unmarshaling
+	// consistent with marshaling, we have to put the header back
+	// to the reader during unmarshaling of the list of elements.
 	rdbuf := bufio.NewReader(r)
+	elemrd := InheritReader(r, rdbuf)
 
 	for {
-		typeBuf, err := rdbuf.Peek(typeLen)
+		headerBuf, err := rdbuf.Peek(headerLen)
 		if err != nil {
 			return n, SkipEOF(err)
 		}
 
-		// Unmarshal the instruction type from the peeked bytes.
-		_, err = ReadFrom(bytes.NewReader(typeBuf), v)
+		// Unmarshal the header from the peeked bytes.
+		_, err = ReadFrom(bytes.NewReader(headerBuf), &header)
 		if err != nil {
 			return n, err
 		}
 
-		// It is defined a corresponding io.ReaderFrom factory for
-		// each value type, so we could parse the raw bytes using
-		// the correct implementation of the reader.
-		rdfrom, err := rm.MakeReader()
+		// Use the header to create the reader of the element, so
+		// we could parse the raw bytes using the correct type.
+		rdfrom, err := fn(header)
 		if err != nil {
 			return n, err
 		}
 
 		// Read the corresponding value from the binary representation.
-		num, err = rdfrom.ReadFrom(rdbuf)
-		n += num
+		nn, err := rdfrom.ReadFrom(elemrd)
+		n += nn
 
 		if err != nil {
 			return n, SkipEOF(err)
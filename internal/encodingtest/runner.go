@@ -108,3 +108,54 @@ func RunMU(t *testing.T, tests []MU) {
 		RunU(t, []U{{test.ReadWriter, test.Bytes}})
 	}
 }
+
+// R defines the round trip testing type.
+type R struct {
+	ReadWriter interface {
+		io.ReaderFrom
+		io.WriterTo
+	}
+
+	// Bytes is a captured wire representation of the value.
+	Bytes []byte
+
+	// Normalized is an expected wire representation after the round
+	// trip, when it differs from the captured one, e.g. the non-zero
+	// paddings are written as zeros. When nil, Bytes are expected.
+	Normalized []byte
+}
+
+// RunR validates, that each captured sequence of bytes is decoded and
+// then encoded back into the identical sequence of bytes.
+func RunR(t *testing.T, tests []R) {
+	for _, test := range tests {
+		nn, err := test.ReadWriter.ReadFrom(bytes.NewReader(test.Bytes))
+		if err != nil {
+			t.Fatalf("Failed to unmarshal the captured packet: "+
+				"`%x`, got error: %s", test.Bytes, err)
+		}
+
+		if nn != int64(len(test.Bytes)) {
+			t.Fatalf("Invalid length returned on attempt to "+
+				"unmarshal:`%x`: %d, expected: %d",
+				test.Bytes, nn, len(test.Bytes))
+		}
+
+		var buf bytes.Buffer
+		if _, err = test.ReadWriter.WriteTo(&buf); err != nil {
+			t.Fatalf("Failed to marshal the decoded packet: "+
+				"`%x`, got error: %s", test.Bytes, err)
+		}
+
+		expected := test.Normalized
+		if expected == nil {
+			expected = test.Bytes
+		}
+
+		if !bytes.Equal(expected, buf.Bytes()) {
+			t.Fatalf("The round trip result is not equal to "+
+				"the\nexpected:\n`%x`,\ngot instead:\n`%x`",
+				expected, buf.Bytes())
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package openflow
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"log"
 	"sync"
+
+	"github.com/netrack/openflow/internal/encoding"
 )
 
 // A Matcher interface is used by multiplexer to find the handler for
@@ -30,6 +35,28 @@ func (t TypeMatcher) Match(r *Request) bool {
 	return r.Header.Type == Type(t)
 }
 
+// VersionMatcher used to match requests by the protocol version.
+type VersionMatcher uint8
+
+// Match implements Matcher interface and matches the request by the
+// version of the protocol.
+func (v VersionMatcher) Match(r *Request) bool {
+	return r.Header.Version == uint8(v)
+}
+
+// typeVersionMatcher used to match requests by their types and
+// version of the protocol.
+type typeVersionMatcher struct {
+	Type    Type
+	Version uint8
+}
+
+// Match implements Matcher interface and matches the request by the
+// type and version.
+func (m typeVersionMatcher) Match(r *Request) bool {
+	return r.Header.Type == m.Type && r.Header.Version == m.Version
+}
+
 // MultiMatcher creates a new Matcher instance that matches the request
 // by all specified criteria.
 func MultiMatcher(m ...Matcher) Matcher {
@@ -112,6 +139,17 @@ func (mux *ServeMux) HandleFunc(m Matcher, h HandlerFunc) {
 
 // Handler returns a handler of the specified request.
 func (mux *ServeMux) Handler(r *Request) Handler {
+	// Use the DefaultHandler when there are no matching entries in the list.
+	if h, ok := mux.handler(r); ok {
+		return h
+	}
+
+	return DefaultHandler
+}
+
+// handler returns a handler of the specified request and reports
+// whether the request matched any of the registered entries.
+func (mux *ServeMux) handler(r *Request) (Handler, bool) {
 	var matcher Matcher
 	var entry *muxEntry
 
@@ -130,15 +168,14 @@ func (mux *ServeMux) Handler(r *Request) Handler {
 
 	mux.mu.RUnlock()
 
-	// Use the DefaultHandler when there are no matching entries in the list.
 	if !matched {
-		return DefaultHandler
+		return nil, false
 	}
 
 	// If the retrieved entry is not disposable one, we will
 	// return it as is without any processing.
 	if !entry.once {
-		return entry.handler
+		return entry.handler, true
 	}
 
 	// But when the entry is disposable, we need to remove it
@@ -153,12 +190,12 @@ func (mux *ServeMux) Handler(r *Request) Handler {
 	// If the concurrent message have already started the message
 	// processing, it will be no longer presented in the list.
 	if _, ok := mux.handlers[matcher]; !ok {
-		return DiscardHandler
+		return DiscardHandler, true
 	}
 
 	// Remove the entry from the list if it is marked as disposable.
 	delete(mux.handlers, matcher)
-	return entry.handler
+	return entry.handler, true
 }
 
 // Serve implements Handler internface. It processing the request and
@@ -168,16 +205,104 @@ func (mux *ServeMux) Serve(rw ResponseWriter, r *Request) {
 	h.Serve(rw, r)
 }
 
+// UnhandledPolicy defines how the TypeMux processes requests that
+// don't have a registered handler.
+type UnhandledPolicy int
+
+const (
+	// UnhandledIgnore discards unhandled requests with DefaultHandler.
+	// This is the default policy.
+	UnhandledIgnore UnhandledPolicy = iota
+
+	// UnhandledLog logs and discards unhandled requests.
+	UnhandledLog
+
+	// UnhandledErrorReply replies to unhandled requests with an error
+	// message of bad request type and bad type code.
+	UnhandledErrorReply
+)
+
+func (p UnhandledPolicy) String() string {
+	text, ok := unhandledPolicyText[p]
+	if !ok {
+		return fmt.Sprintf("UnhandledPolicy(%d)", p)
+	}
+	return text
+}
+
+var unhandledPolicyText = map[UnhandledPolicy]string{
+	UnhandledIgnore:     "UnhandledIgnore",
+	UnhandledLog:        "UnhandledLog",
+	UnhandledErrorReply: "UnhandledErrorReply",
+}
+
+// UnhandledLogHandler is a Handler that logs and discards requests.
+var UnhandledLogHandler = HandlerFunc(func(rw ResponseWriter, r *Request) {
+	log.Printf("openflow: unhandled message %s of version %d",
+		r.Header.Type, r.Header.Version)
+})
+
+// badRequestLen is a maximum length of the failed request included
+// into the error message.
+const badRequestLen = 64
+
+// badTypeError is a body of the error message returned in reply to
+// the request of unsupported type.
+type badTypeError struct {
+	data []byte
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the error
+// message of bad request type and bad type code into the wire format.
+func (e *badTypeError) WriteTo(w io.Writer) (int64, error) {
+	const (
+		errTypeBadRequest     uint16 = 1
+		errCodeBadRequestType uint16 = 1
+	)
+
+	return encoding.WriteTo(w, errTypeBadRequest, errCodeBadRequestType, e.data)
+}
+
+// UnhandledErrorHandler is a Handler that replies to requests with an
+// error message of bad request type and bad type code. The error
+// contains at most 64 bytes of the failed request. Error messages are
+// never replied to prevent the infinite exchange of errors.
+var UnhandledErrorHandler = HandlerFunc(func(rw ResponseWriter, r *Request) {
+	if r.Header.Type == TypeError {
+		return
+	}
+
+	var buf bytes.Buffer
+	r.Header.WriteTo(&buf)
+	io.CopyN(&buf, r.Body, int64(badRequestLen-buf.Len()))
+
+	r.Reply(rw, &badTypeError{buf.Bytes()}, TypeError)
+})
+
 // TypeMux is an OpenFlow request multiplexer. It matches the type
 // of the OpenFlow message against a list of registered handlers and calls
 // the marching handler.
+//
+// Handlers could be registered for the specific version of the protocol,
+// these handlers take precedence over the handlers registered for all
+// versions. Requests without a matching handler are served with the
+// Fallback handler or processed according to the Unhandled policy.
 type TypeMux struct {
-	mux *ServeMux
+	// Fallback is a handler used for requests that don't have a
+	// registered handler. When nil, the Unhandled policy is used.
+	Fallback Handler
+
+	// Unhandled defines how the requests without registered handler
+	// are processed, when the Fallback handler is not specified.
+	Unhandled UnhandledPolicy
+
+	mux      *ServeMux
+	versions *ServeMux
 }
 
 // NewTypeMux creates and returns a new TypeMux.
 func NewTypeMux() *TypeMux {
-	return &TypeMux{NewServeMux()}
+	return &TypeMux{mux: NewServeMux(), versions: NewServeMux()}
 }
 
 // Handle registers the handler for the given message type.
@@ -195,15 +320,53 @@ func (mux *TypeMux) HandleFunc(t Type, f HandlerFunc) {
 	mux.Handle(t, f)
 }
 
+// HandleVersion registers the handler for the given message type of
+// the specified protocol version.
+func (mux *TypeMux) HandleVersion(t Type, version uint8, h Handler) {
+	mux.versions.Handle(typeVersionMatcher{t, version}, h)
+}
+
+// HandleVersionOnce registers a disposable handler for the given message
+// type of the specified protocol version.
+func (mux *TypeMux) HandleVersionOnce(t Type, version uint8, h Handler) {
+	mux.versions.HandleOnce(typeVersionMatcher{t, version}, h)
+}
+
+// HandleVersionFunc registers handler function for the given message
+// type of the specified protocol version.
+func (mux *TypeMux) HandleVersionFunc(t Type, version uint8, f HandlerFunc) {
+	mux.HandleVersion(t, version, f)
+}
+
 // Handler returns a Handler instance for the given OpenFlow request.
 func (mux *TypeMux) Handler(r *Request) Handler {
-	return mux.mux.Handler(r)
+	if h, ok := mux.versions.handler(r); ok {
+		return h
+	}
+
+	if h, ok := mux.mux.handler(r); ok {
+		return h
+	}
+
+	if mux.Fallback != nil {
+		return mux.Fallback
+	}
+
+	switch mux.Unhandled {
+	case UnhandledLog:
+		return UnhandledLogHandler
+	case UnhandledErrorReply:
+		return UnhandledErrorHandler
+	}
+
+	return DefaultHandler
 }
 
 // Serve implements Handler internface. It processing the request and
 // writes back the response.
 func (mux *TypeMux) Serve(rw ResponseWriter, r *Request) {
-	mux.mux.Serve(rw, r)
+	h := mux.Handler(r)
+	h.Serve(rw, r)
 }
 
 // DefaultMux is an instance of the TypeMux used as
@@ -82,3 +82,93 @@ func TestTypeMux(t *testing.T) {
 		t.Errorf("Invalid data returned: %v", returned)
 	}
 }
+
+// dummyResponse is a ResponseWriter that records the written messages.
+type dummyResponse struct {
+	headers []*Header
+	bodies  []io.WriterTo
+}
+
+func (rw *dummyResponse) Write(h *Header, w io.WriterTo) error {
+	rw.headers = append(rw.headers, h)
+	rw.bodies = append(rw.bodies, w)
+	return nil
+}
+
+func TestTypeMuxVersion(t *testing.T) {
+	var served string
+
+	handler := func(name string) HandlerFunc {
+		return func(rw ResponseWriter, r *Request) { served = name }
+	}
+
+	mux := NewTypeMux()
+	mux.HandleFunc(TypeEchoRequest, handler("any"))
+	mux.HandleVersionFunc(TypeEchoRequest, 1, handler("1.0"))
+
+	tests := []struct {
+		Type    Type
+		Version uint8
+		Served  string
+	}{
+		{TypeEchoRequest, 1, "1.0"},
+		{TypeEchoRequest, 4, "any"},
+		{TypeEchoReply, 4, ""},
+	}
+
+	for _, test := range tests {
+		served = ""
+
+		r := NewRequest(test.Type, nil)
+		r.Header.Version = test.Version
+
+		mux.Serve(&dummyResponse{}, r)
+		if served != test.Served {
+			t.Errorf("Invalid handler called for %s version %d: %q",
+				test.Type, test.Version, served)
+		}
+	}
+
+	mux.Fallback = handler("fallback")
+	mux.Serve(&dummyResponse{}, NewRequest(TypeEchoReply, nil))
+
+	if served != "fallback" {
+		t.Errorf("Fallback handler is expected to be called")
+	}
+}
+
+func TestTypeMuxUnhandled(t *testing.T) {
+	mux := NewTypeMux()
+	mux.Unhandled = UnhandledErrorReply
+
+	r := NewRequest(TypeFlowMod, bytes.NewBuffer([]byte{0x01, 0x02}))
+	r.Header.Transaction = 42
+	r.Header.Length = 10
+
+	var rw dummyResponse
+	mux.Serve(&rw, r)
+
+	if len(rw.headers) != 1 {
+		t.Fatalf("Expected one error reply, got %d", len(rw.headers))
+	}
+
+	if rw.headers[0].Type != TypeError || rw.headers[0].Transaction != 42 {
+		t.Errorf("Invalid header of the error reply: %v", rw.headers[0])
+	}
+
+	var buf bytes.Buffer
+	rw.bodies[0].WriteTo(&buf)
+
+	body := fmt.Sprintf("%x", buf.Bytes())
+	if body != "00010001040e000a0000002a0102" {
+		t.Errorf("Invalid error reply body: %s", body)
+	}
+
+	// Error messages must not be replied with errors.
+	rw = dummyResponse{}
+	mux.Serve(&rw, NewRequest(TypeError, nil))
+
+	if len(rw.headers) != 0 {
+		t.Errorf("Error message must not be replied")
+	}
+}
@@ -2,8 +2,12 @@ package openflow
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 	"time"
@@ -106,6 +110,18 @@ type conn struct {
 	buf *bufio.ReadWriter
 	mu  sync.Mutex
 
+	// The header of the received message is read into the fixed
+	// array, while the body is carved from the slab, so the reading
+	// of the message takes no allocations beside the request itself
+	// until the slab is exhausted.
+	hdr  [headerlen]byte
+	slab []byte
+	rmu  sync.Mutex
+
+	// identified is set, when the features reply is received, so
+	// the reconnects are counted only once per connection.
+	identified int32
+
 	// Maximum duration before timing out the read of the request.
 	ReadTimeout time.Duration
 	// Maximum duration before timing out the write of the response.
@@ -124,6 +140,8 @@ func newConn(c net.Conn) *conn {
 	bw := bufio.NewWriter(c)
 
 	brw := bufio.NewReadWriter(br, bw)
+	countConn()
+
 	return &conn{rwc: c, buf: brw}
 }
 
@@ -138,15 +156,115 @@ func (c *conn) Receive() (*Request, error) {
 		c.SetReadDeadline(time.Now().Add(d))
 	}
 
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
 	r := &Request{Addr: c.rwc.RemoteAddr(), conn: c}
-	_, err := r.ReadFrom(c)
-	if err != nil {
+	if err := c.readRequest(r); err != nil {
+		if err == ErrCorruptedHeader || err == io.ErrUnexpectedEOF {
+			countDecodeError()
+		}
 		return nil, err
 	}
 
+	countReceived(r.Header.Type)
+	countFeatures(&c.identified, r)
 	return r, nil
 }
 
+// readSlabLen defines the length of the slab used to read the bodies
+// of the messages. Bodies exceeding the quarter of the slab are read
+// into the dedicated buffers, so the small messages retained by the
+// handlers do not pin large slabs.
+const readSlabLen = 16 << 10
+
+// alloc returns the slice of the given length used to read the body
+// of the message. The returned slice is never reused by the connection,
+// thus it could be safely passed to the handlers without copying.
+func (c *conn) alloc(n int) []byte {
+	if n > readSlabLen/4 {
+		return make([]byte, n)
+	}
+
+	if len(c.slab) < n {
+		c.slab = make([]byte, readSlabLen)
+	}
+
+	b := c.slab[:n:n]
+	c.slab = c.slab[n:]
+	return b
+}
+
+// partialError is returned when the read of the message fails after a
+// part of the message was consumed. It matches ErrPartialMessage and
+// unwraps to the original read error, so the timeouts could be still
+// recognized by the callers.
+type partialError struct {
+	err error
+}
+
+func (e *partialError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPartialMessage, e.err)
+}
+
+// Is reports whether the target error is ErrPartialMessage.
+func (e *partialError) Is(target error) bool {
+	return target == ErrPartialMessage
+}
+
+// Unwrap returns the original read error.
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+// readRequest reads the header of the message into the fixed buffer
+// and the body into the slab.
+func (c *conn) readRequest(r *Request) error {
+	n, err := io.ReadFull(c.buf, c.hdr[:])
+	if err != nil {
+		if n != 0 && err != io.ErrUnexpectedEOF {
+			err = &partialError{err}
+		}
+		return err
+	}
+
+	r.Header = parseHeader(c.hdr[:])
+	r.setProto()
+
+	contentlen := r.Header.Len() - headerlen
+	if contentlen < 0 {
+		return ErrCorruptedHeader
+	}
+
+	body := c.alloc(contentlen)
+	if _, err = io.ReadFull(c.buf, body); err != nil {
+		switch err {
+		case io.EOF:
+			err = io.ErrUnexpectedEOF
+		case io.ErrUnexpectedEOF:
+		default:
+			err = &partialError{err}
+		}
+		return err
+	}
+
+	r.Body = bytes.NewBuffer(body)
+	r.ContentLength = int64(contentlen)
+	r.raw = body
+	return nil
+}
+
+// parseHeader decodes the message header from the wire format, the
+// slice must be at least headerlen bytes long.
+func parseHeader(b []byte) Header {
+	return Header{
+		Version:     b[0],
+		Type:        Type(b[1]),
+		Length:      binary.BigEndian.Uint16(b[2:4]),
+		Transaction: binary.BigEndian.Uint32(b[4:8]),
+	}
+}
+
 // Write writes data to the connection. Write can be made to time out.
 func (c *conn) Write(b []byte) (int, error) {
 	c.mu.Lock()
@@ -183,6 +301,10 @@ func (c *conn) Send(r *Request) error {
 	}
 
 	_, err := r.WriteTo(c)
+	if err == nil {
+		countSent(r.Header.Type)
+	}
+
 	return err
 }
 
@@ -262,6 +384,95 @@ func DialTLS(network, addr string, config *tls.Config) (Conn, error) {
 	return NewConn(conn), nil
 }
 
+// DialContext establishes the remote connection to the address on the
+// given network using the provided context.
+//
+// The provided context must be non-nil. If the context expires before
+// the connection is complete, an error is returned. Once successfully
+// connected, any expiration of the context will not affect the
+// connection.
+func DialContext(ctx context.Context, network, addr string) (Conn, error) {
+	var d Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+// A Dialer contains options for establishing the OpenFlow connection.
+//
+// The custom dial function could be used to configure the socket options,
+// bind the connection to the specific interface, establish the connection
+// through the proxy or use an externally supplied transport. For example,
+// to enable TCP keep-alive probes, the following dialer could be used:
+//
+//	d := &of.Dialer{NetDialContext: (&net.Dialer{
+//		KeepAlive: 30 * time.Second,
+//	}).DialContext}
+//
+//	conn, err := d.Dial("tcp", "localhost:6633")
+//	// ...
+type Dialer struct {
+	// NetDialContext specifies the dial function for creating
+	// unencrypted network connections. If NetDialContext is nil, then
+	// the zero value of net.Dialer is used.
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// TLSConfig specifies the TLS configuration to use with the dialed
+	// connection. When not nil, the TLS handshake is initiated right
+	// after the network connection is established.
+	TLSConfig *tls.Config
+}
+
+// Dial establishes the remote connection to the address on the given
+// network.
+func (d *Dialer) Dial(network, addr string) (Conn, error) {
+	return d.dialContext(context.Background(), network, addr)
+}
+
+// DialContext establishes the remote connection to the address on the
+// given network using the provided context.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (Conn, error) {
+	return d.dialContext(ctx, network, addr)
+}
+
+// dialContext establishes the network connection and wraps it with the
+// TLS client connection, when the TLS configuration is specified.
+func (d *Dialer) dialContext(ctx context.Context, network, addr string) (Conn, error) {
+	dial := d.NetDialContext
+	if dial == nil {
+		var nd net.Dialer
+		dial = nd.DialContext
+	}
+
+	c, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.TLSConfig == nil {
+		return NewConn(c), nil
+	}
+
+	config := d.TLSConfig
+	if config.ServerName == "" {
+		// Use the host name of the address to verify the server
+		// certificate in the same way as tls.Dial does.
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	tc := tls.Client(c, config)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return NewConn(tc), nil
+}
+
 // Listener is an OpenFlow network listener. Clients should typically
 // use variables of type net.Listener instead of assuming OFP.
 type Listener interface {
@@ -2,6 +2,7 @@ package openflow
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net"
@@ -237,3 +238,121 @@ func TestDial(t *testing.T) {
 		t.Fatal("Wrong content length returned:", r.ContentLength)
 	}
 }
+
+func TestDialerNetDialContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	var dialed string
+	d := &Dialer{NetDialContext: func(ctx context.Context,
+		network, addr string) (net.Conn, error) {
+		dialed = network + "://" + addr
+		return client, nil
+	}}
+
+	rwc, err := d.DialContext(context.Background(), "tcp", "switch:6633")
+	if err != nil {
+		t.Fatal("Failed to dial with custom dialer:", err)
+	}
+
+	defer rwc.Close()
+
+	if dialed != "tcp://switch:6633" {
+		t.Fatal("Custom dial function was not used:", dialed)
+	}
+
+	go Send(rwc, NewRequest(TypeHello, nil))
+
+	r, err := NewConn(server).Receive()
+	if err != nil {
+		t.Fatal("Failed to receive request:", err)
+	}
+
+	if r.Header.Type != TypeHello {
+		t.Fatal("Hello message expected:", r.Header.Type)
+	}
+}
+
+func TestDialContextCanceled(t *testing.T) {
+	ln, err := Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal("Failed to create listener:", err)
+	}
+
+	defer ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = DialContext(ctx, "tcp", ln.Addr().String())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("Canceled context must abort dialing:", err)
+	}
+}
+
+func TestConnReceiveSlab(t *testing.T) {
+	dc := &dummyConn{}
+	for i := 0; i < 3; i++ {
+		body := bytes.Repeat([]byte{byte(i)}, 3000+i)
+		NewRequest(TypeEchoRequest, bytes.NewReader(body)).WriteTo(&dc.r)
+	}
+
+	// Write truncated message at the end of the stream.
+	dc.r.Write([]byte{4, byte(TypeEchoRequest), 0, 16, 0, 0, 0, 0, 1})
+
+	c := NewConn(dc)
+	var bodies [][]byte
+
+	for i := 0; i < 3; i++ {
+		req, err := c.Receive()
+		if err != nil {
+			t.Fatalf("Failed to receive request: %s", err)
+		}
+
+		body := req.Body.(*bytes.Buffer).Bytes()
+		bodies = append(bodies, body)
+	}
+
+	// The bodies of the previously received messages must not be
+	// overwritten by the following messages.
+	for i, body := range bodies {
+		want := bytes.Repeat([]byte{byte(i)}, 3000+i)
+		if !bytes.Equal(body, want) {
+			t.Errorf("Body of the message %d was corrupted", i)
+		}
+	}
+
+	if _, err := c.Receive(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Unexpected end of file expected: %v", err)
+	}
+}
+
+func TestConnReceivePartial(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	c := NewConn(client)
+	defer c.Close()
+
+	// Nothing is consumed from the stream on timeout, so the
+	// error is not reported as a partial message.
+	c.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err := c.Receive(); err == nil || errors.Is(err, ErrPartialMessage) {
+		t.Fatalf("Timeout error expected: %v", err)
+	}
+
+	// Write only the part of the message header.
+	go server.Write([]byte{4, byte(TypeHello)})
+
+	c.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err := c.Receive()
+	if !errors.Is(err, ErrPartialMessage) {
+		t.Fatalf("Partial message error expected: %v", err)
+	}
+
+	// The cause of the partial read must be preserved.
+	var nerr net.Error
+	if !errors.As(err, &nerr) || !nerr.Timeout() {
+		t.Fatalf("Timeout error must be unwrapped: %v", err)
+	}
+}
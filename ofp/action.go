@@ -108,23 +108,42 @@ var actionText = map[ActionType]string{
 }
 
 var actionMap = map[ActionType]encoding.ReaderMaker{
-	ActionTypeOutput:       encoding.ReaderMakerOf(ActionOutput{}),
-	ActionTypeCopyTTLOut:   encoding.ReaderMakerOf(ActionCopyTTLOut{}),
-	ActionTypeCopyTTLIn:    encoding.ReaderMakerOf(ActionCopyTTLIn{}),
-	ActionTypeSetMPLSTTL:   encoding.ReaderMakerOf(ActionSetMPLSTTL{}),
-	ActionTypeDecMPLSTTL:   encoding.ReaderMakerOf(ActionDecMPLSTTL{}),
-	ActionTypePushVLAN:     encoding.ReaderMakerOf(ActionPushVLAN{}),
-	ActionTypePopVLAN:      encoding.ReaderMakerOf(ActionPopVLAN{}),
-	ActionTypePushMPLS:     encoding.ReaderMakerOf(ActionPushMPLS{}),
-	ActionTypePopMPLS:      encoding.ReaderMakerOf(ActionPopMPLS{}),
-	ActionTypeSetQueue:     encoding.ReaderMakerOf(ActionSetQueue{}),
-	ActionTypeGroup:        encoding.ReaderMakerOf(ActionGroup{}),
-	ActionTypeSetNwTTL:     encoding.ReaderMakerOf(ActionSetNetworkTTL{}),
-	ActionTypeDecNwTTL:     encoding.ReaderMakerOf(ActionDecNetworkTTL{}),
-	ActionTypeSetField:     encoding.ReaderMakerOf(ActionSetField{}),
-	ActionTypePushPBB:      encoding.ReaderMakerOf(ActionPushPBB{}),
-	ActionTypePopPBB:       encoding.ReaderMakerOf(ActionPopPBB{}),
-	ActionTypeExperimenter: encoding.ReaderMakerOf(ActionExperimenter{}),
+	ActionTypeOutput:       encoding.ReaderMakerOf[ActionOutput](),
+	ActionTypeCopyTTLOut:   encoding.ReaderMakerOf[ActionCopyTTLOut](),
+	ActionTypeCopyTTLIn:    encoding.ReaderMakerOf[ActionCopyTTLIn](),
+	ActionTypeSetMPLSTTL:   encoding.ReaderMakerOf[ActionSetMPLSTTL](),
+	ActionTypeDecMPLSTTL:   encoding.ReaderMakerOf[ActionDecMPLSTTL](),
+	ActionTypePushVLAN:     encoding.ReaderMakerOf[ActionPushVLAN](),
+	ActionTypePopVLAN:      encoding.ReaderMakerOf[ActionPopVLAN](),
+	ActionTypePushMPLS:     encoding.ReaderMakerOf[ActionPushMPLS](),
+	ActionTypePopMPLS:      encoding.ReaderMakerOf[ActionPopMPLS](),
+	ActionTypeSetQueue:     encoding.ReaderMakerOf[ActionSetQueue](),
+	ActionTypeGroup:        encoding.ReaderMakerOf[ActionGroup](),
+	ActionTypeSetNwTTL:     encoding.ReaderMakerOf[ActionSetNetworkTTL](),
+	ActionTypeDecNwTTL:     encoding.ReaderMakerOf[ActionDecNetworkTTL](),
+	ActionTypeSetField:     encoding.ReaderMakerOf[ActionSetField](),
+	ActionTypePushPBB:      encoding.ReaderMakerOf[ActionPushPBB](),
+	ActionTypePopPBB:       encoding.ReaderMakerOf[ActionPopPBB](),
+	ActionTypeExperimenter: encoding.ReaderMakerOf[ActionExperimenter](),
+}
+
+// actionTable is a dense lookup table of the action decoders, that
+// avoids map lookups when decoding the standard actions.
+var actionTable = encoding.NewReaderMakerTable(actionMap)
+
+// RegisterAction registers the constructor of the action of the given
+// type, used to decode the actions from the wire format. The decoder of
+// the action of the same type is replaced.
+//
+// The actions could be registered at any time, concurrently with the
+// decoding of the messages. For example, to decode the vendor action:
+//
+//	ofp.RegisterAction(ofp.ActionTypeExperimenter, func() ofp.Action {
+//		return new(VendorAction)
+//	})
+func RegisterAction(t ActionType, fn func() Action) {
+	actionTable.Register(t, encoding.ReaderMakerFunc(
+		func() (io.ReaderFrom, error) { return fn(), nil }))
 }
 
 const (
@@ -173,10 +192,14 @@ func (a *Actions) bytes() ([]byte, error) {
 	var buf bytes.Buffer
 
 	for _, action := range *a {
-		_, err := action.WriteTo(&buf)
+		n, err := action.WriteTo(&buf)
 		if err != nil {
 			return nil, err
 		}
+
+		if err = checkAligned(CurrentCheckMode(), action.Type(), n); err != nil {
+			return nil, err
+		}
 	}
 
 	return buf.Bytes(), nil
@@ -195,22 +218,40 @@ func (a *Actions) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom decodes the list of actions from the wire format into
 // the list of types that implement Action interface.
 func (a *Actions) ReadFrom(r io.Reader) (int64, error) {
-	var actionType ActionType
 	*a = nil
 
-	rm := func() (io.ReaderFrom, error) {
-		if rm, ok := actionMap[actionType]; ok {
+	rm := func(actionType ActionType) (io.ReaderFrom, error) {
+		if rm, ok := actionTable.Lookup(actionType); ok {
 			rd, err := rm.MakeReader()
 			*a = append(*a, rd.(Action))
 			return rd, err
 		}
 
-		format := "ofp: unknown action type: '%x'"
-		return nil, fmt.Errorf(format, actionType)
+		format := "ofp: unknown action type: '%x': %w"
+		return nil, fmt.Errorf(format, actionType,
+			Error{Type: ErrTypeBadAction, Code: ErrCodeBadActionType})
 	}
 
-	return encoding.ScanFrom(r, &actionType,
-		encoding.ReaderMakerFunc(rm))
+	return encoding.ScanFrom(r, rm)
+}
+
+// Clone returns a deep copy of the list of actions.
+func (a Actions) Clone() Actions {
+	if a == nil {
+		return nil
+	}
+
+	actions := make(Actions, len(a))
+	for i, action := range a {
+		switch action := action.(type) {
+		case *ActionSetField:
+			actions[i] = &ActionSetField{Field: action.Field.Clone()}
+		default:
+			actions[i] = cloneValue(action).(Action)
+		}
+	}
+
+	return actions
 }
 
 // ActionOutput is an action used to output the packets to the switch port.
@@ -264,7 +305,7 @@ func (a *ActionCopyTTLOut) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "copy TTL out" action from a wire format.
 func (a *ActionCopyTTLOut) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad8)
+	return encoding.ReadFrom(r, &action{}, &defaultPad4)
 }
 
 // ActionCopyTTLIn is an action used to copy TTL from outermost to
@@ -285,7 +326,7 @@ func (a *ActionCopyTTLIn) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "copy TTL in" action from a wire format.
 func (a *ActionCopyTTLIn) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad8)
+	return encoding.ReadFrom(r, &action{}, &defaultPad4)
 }
 
 // ActionSetMPLSTTL is an action used to replace the MPLS TTL
@@ -330,7 +371,7 @@ func (a *ActionDecMPLSTTL) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "decrement MPLS TTL" action from a wire format.
 func (a *ActionDecMPLSTTL) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad8)
+	return encoding.ReadFrom(r, &action{}, &defaultPad4)
 }
 
 // ActionPushVLAN is an action used to push the VLAN tag onto the
@@ -358,7 +399,7 @@ func (a *ActionPushVLAN) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "push VLAN" action from a wire format.
 func (a *ActionPushVLAN) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad4, &a.EtherType, &defaultPad2)
+	return encoding.ReadFrom(r, &action{}, &a.EtherType, &defaultPad2)
 }
 
 // ActionPopVLAN is an action used to pop the VLAN tag from the
@@ -379,7 +420,7 @@ func (a *ActionPopVLAN) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "pop VLAN" action from a wire format.
 func (a *ActionPopVLAN) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad8)
+	return encoding.ReadFrom(r, &action{}, &defaultPad4)
 }
 
 // ActionPushMPLS is an action used to push the MPLS tag onto the
@@ -403,7 +444,7 @@ func (a *ActionPushMPLS) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "push VLAN" action from a wire format.
 func (a *ActionPushMPLS) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad4, &a.EtherType, &defaultPad2)
+	return encoding.ReadFrom(r, &action{}, &a.EtherType, &defaultPad2)
 }
 
 // ActionPopMPLS is an action used to extract the outer-most MPLS tag
@@ -428,7 +469,7 @@ func (a *ActionPopMPLS) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "pop MPLS" action from a wire format.
 func (a *ActionPopMPLS) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad4, &a.EtherType, &defaultPad2)
+	return encoding.ReadFrom(r, &action{}, &a.EtherType, &defaultPad2)
 }
 
 // ActionSetQueue sets the queue ID that will be used to map a flow entry
@@ -457,7 +498,7 @@ func (a *ActionSetQueue) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "set queue" action from a wire format.
 func (a *ActionSetQueue) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad4, &a.QueueID)
+	return encoding.ReadFrom(r, &action{}, &a.QueueID)
 }
 
 // ActionGroup is an action that specifies the group used to process
@@ -482,7 +523,7 @@ func (a *ActionGroup) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "group" action from a wire format.
 func (a *ActionGroup) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad4, &a.Group)
+	return encoding.ReadFrom(r, &action{}, &a.Group)
 }
 
 // ActionSetNetworkTTL is an action used to replace the network
@@ -506,7 +547,7 @@ func (a *ActionSetNetworkTTL) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "set network TTL" action from a wire format.
 func (a *ActionSetNetworkTTL) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad4, &a.TTL, &defaultPad3)
+	return encoding.ReadFrom(r, &action{}, &a.TTL, &defaultPad3)
 }
 
 // ActionDecNetworkTTL is an actions used to decrement time to live value
@@ -527,7 +568,7 @@ func (a *ActionDecNetworkTTL) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "decrement network TTL" action from a wire format.
 func (a *ActionDecNetworkTTL) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad8)
+	return encoding.ReadFrom(r, &action{}, &defaultPad4)
 }
 
 // ActionSetField is an action used to set the value of the packet field.
@@ -577,7 +618,7 @@ func (a *ActionSetField) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	limrd := io.LimitReader(r, int64(header.Len-4))
+	limrd := encoding.LimitReader(r, int64(header.Len-4))
 	num, err = a.Field.ReadFrom(limrd)
 	n += num
 
@@ -590,6 +631,42 @@ func (a *ActionSetField) ReadFrom(r io.Reader) (int64, error) {
 	return n, err
 }
 
+// Validate checks that the set-field action could be applied to the
+// packets matched by the given match. It ensures that the field of
+// OpenFlow basic class is settable, the length of the value is valid,
+// mask is not specified and prerequisites of the field are satisfied
+// by the match.
+//
+// On failure an Error of ErrTypeBadAction type is returned.
+func (a *ActionSetField) Validate(m Match) error {
+	newError := func(code ErrCode) error {
+		return Error{Type: ErrTypeBadAction, Code: code}
+	}
+
+	field, ok := LookupXMField(a.Field.Class, a.Field.Type)
+	if a.Field.Class != XMClassOpenflowBasic || !ok {
+		return newError(ErrCodeBadActionSetType)
+	}
+
+	if xmUnsettable[a.Field.Type] {
+		return newError(ErrCodeBadActionSetType)
+	}
+
+	if len(a.Field.Value) != field.Len {
+		return newError(ErrCodeBadActionSetLen)
+	}
+
+	if a.Field.Mask != nil {
+		return newError(ErrCodeBadActionSetArgument)
+	}
+
+	if !m.prereqSatisfied(a.Field.Type) {
+		return newError(ErrCodeBadActionMatchInconsistent)
+	}
+
+	return nil
+}
+
 // ActionPushPBB is an action used to push a new PBB service tag
 // (I-TAG) onto the processing packet.
 type ActionPushPBB struct {
@@ -611,13 +688,30 @@ func (a *ActionPushPBB) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "push PBB" action from a wire format.
 func (a *ActionPushPBB) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad4, &a.EtherType, &defaultPad2)
+	return encoding.ReadFrom(r, &action{}, &a.EtherType, &defaultPad2)
 }
 
 // ActionPopPBB is an action used to pop the outer PBB service tag
 // (I-TAG) from the processing packet.
 type ActionPopPBB struct{}
 
+// Type returns type of the action.
+func (a *ActionPopPBB) Type() ActionType {
+	return ActionTypePopPBB
+}
+
+// WriteTo implement the io.WriterTo interface. It serializes
+// the "pop PBB" action with a necessary padding.
+func (a *ActionPopPBB) WriteTo(w io.Writer) (int64, error) {
+	return encoding.WriteTo(w, action{a.Type(), actionLen}, pad4{})
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes
+// the "pop PBB" action from a wire format.
+func (a *ActionPopPBB) ReadFrom(r io.Reader) (int64, error) {
+	return encoding.ReadFrom(r, &action{}, &defaultPad4)
+}
+
 // ActionExperimenter is an experimenter action.
 type ActionExperimenter struct {
 	// The Experimenter identifies the experimental feature.
@@ -638,5 +732,5 @@ func (a *ActionExperimenter) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the "experimenter" action from a wire format.
 func (a *ActionExperimenter) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad4, &a.Experimenter)
+	return encoding.ReadFrom(r, &action{}, &a.Experimenter)
 }
@@ -1,8 +1,12 @@
 package ofp
 
 import (
+	"bytes"
+	"io"
+	"sync"
 	"testing"
 
+	"github.com/netrack/openflow/internal/encoding"
 	"github.com/netrack/openflow/internal/encodingtest"
 )
 
@@ -182,6 +186,62 @@ func TestActionSetField(t *testing.T) {
 	encodingtest.RunMU(t, tests)
 }
 
+func TestActionSetFieldValidate(t *testing.T) {
+	basic := func(t XMType, value XMValue) XM {
+		return XM{Class: XMClassOpenflowBasic, Type: t, Value: value}
+	}
+
+	ipv4 := basic(XMTypeEthType, XMValue{0x08, 0x00})
+	tcp := basic(XMTypeIPProto, XMValue{0x06})
+	ipv4Dst := basic(XMTypeIPv4Dst, XMValue{0x0a, 0x00, 0x00, 0x01})
+	tcpDst := basic(XMTypeTCPDst, XMValue{0x00, 0x50})
+
+	masked := ipv4Dst
+	masked.Mask = XMValue{0xff, 0xff, 0xff, 0x00}
+
+	tests := []struct {
+		Field XM
+		Match Match
+		Code  ErrCode
+		Valid bool
+	}{
+		{Field: ipv4Dst, Match: Match{MatchTypeXM, []XM{ipv4}}, Valid: true},
+		{Field: tcpDst, Match: Match{MatchTypeXM, []XM{ipv4, tcp}}, Valid: true},
+		{Field: basic(XMTypeEthDst, make(XMValue, 6)), Valid: true},
+
+		// Prerequisites of the prerequisite field are not satisfied.
+		{Field: tcpDst, Match: Match{MatchTypeXM, []XM{tcp}},
+			Code: ErrCodeBadActionMatchInconsistent},
+		{Field: ipv4Dst, Match: Match{MatchTypeXM, nil},
+			Code: ErrCodeBadActionMatchInconsistent},
+		{Field: basic(XMTypeInPort, make(XMValue, 4)),
+			Code: ErrCodeBadActionSetType},
+		{Field: basic(XMTypeIPv4Src, make(XMValue, 2)),
+			Code: ErrCodeBadActionSetLen},
+		{Field: masked, Match: Match{MatchTypeXM, []XM{ipv4}},
+			Code: ErrCodeBadActionSetArgument},
+	}
+
+	for _, test := range tests {
+		a := ActionSetField{Field: test.Field}
+		err := a.Validate(test.Match)
+
+		if test.Valid {
+			if err != nil {
+				t.Errorf("Set of %s expected to be valid: %s",
+					test.Field.Type, err)
+			}
+			continue
+		}
+
+		e, ok := err.(Error)
+		if !ok || e.Type != ErrTypeBadAction || e.Code != test.Code {
+			t.Errorf("Set of %s expected to fail with code %d, got: %v",
+				test.Field.Type, test.Code, err)
+		}
+	}
+}
+
 func TestActionExperimenter(t *testing.T) {
 	tests := []encodingtest.MU{
 		{ReadWriter: &ActionExperimenter{41}, Bytes: []byte{
@@ -198,3 +258,68 @@ func TestActionExperimenter(t *testing.T) {
 
 	encodingtest.RunMU(t, tests)
 }
+
+// actionTest is an action of the unassigned type used to test the
+// registration of the action decoders.
+type actionTest struct {
+	Value uint32
+}
+
+const actionTypeTest ActionType = 0x7f
+
+func (a *actionTest) Type() ActionType {
+	return actionTypeTest
+}
+
+func (a *actionTest) WriteTo(w io.Writer) (int64, error) {
+	return encoding.WriteTo(w, actionTypeTest, uint16(8), a.Value)
+}
+
+func (a *actionTest) ReadFrom(r io.Reader) (int64, error) {
+	var header action
+	return encoding.ReadFrom(r, &header, &a.Value)
+}
+
+func TestRegisterAction(t *testing.T) {
+	defer actionTable.Register(actionTypeTest, nil)
+
+	data := []byte{
+		0x00, 0x7f, 0x00, 0x08, // Action header.
+		0x00, 0x00, 0x00, 0x2a, // Value.
+	}
+
+	var actions Actions
+	if _, err := actions.ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Fatalf("Unknown action type must not be decoded")
+	}
+
+	// Decode the actions concurrently with the registration.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var actions Actions
+			actions.ReadFrom(bytes.NewReader(data))
+		}()
+	}
+
+	RegisterAction(actionTypeTest, func() Action { return new(actionTest) })
+	wg.Wait()
+
+	if _, err := actions.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Failed to decode registered action: %s", err)
+	}
+
+	if len(actions) != 1 || actions[0].(*actionTest).Value != 42 {
+		t.Errorf("Invalid decoded actions: %v", actions)
+	}
+
+	// Standard actions must be decoded after the registration.
+	if _, err := actions.ReadFrom(bytes.NewReader([]byte{
+		0x00, 0x0c, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00,
+	})); err != nil {
+		t.Errorf("Failed to decode standard action: %s", err)
+	}
+}
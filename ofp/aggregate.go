@@ -78,6 +78,13 @@ func (a *AggregateStatsRequest) ReadFrom(r io.Reader) (int64, error) {
 		&a.CookieMask, &a.Match)
 }
 
+// Clone returns a deep copy of the aggregate statistics request.
+func (a *AggregateStatsRequest) Clone() *AggregateStatsRequest {
+	clone := *a
+	clone.Match = a.Match.Clone()
+	return &clone
+}
+
 // AggregateStats is a response on the aggregated statistics request.
 // This message is used as a body of multipart reply.
 type AggregateStats struct {
@@ -0,0 +1,205 @@
+package ofp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+// benchMatch is a match of a typical reactive flow entry.
+var benchMatch = Match{MatchTypeXM, []XM{
+	{Class: XMClassOpenflowBasic, Type: XMTypeInPort,
+		Value: XMValue{0x00, 0x00, 0x00, 0x03}},
+	{Class: XMClassOpenflowBasic, Type: XMTypeEthType,
+		Value: XMValue{0x08, 0x00}},
+	{Class: XMClassOpenflowBasic, Type: XMTypeIPv4Dst,
+		Value: XMValue{0x0a, 0x00, 0x00, 0x01},
+		Mask:  XMValue{0xff, 0xff, 0xff, 0x00}},
+}}
+
+// benchInstructions is a set of instructions of a typical flow entry.
+var benchInstructions = Instructions{
+	&InstructionApplyActions{Actions{
+		&ActionSetField{Field: XM{
+			Class: XMClassOpenflowBasic,
+			Type:  XMTypeVlanID,
+			Value: XMValue{0x10, 0x02},
+		}},
+		&ActionOutput{Port: 2, MaxLen: 0xffff},
+	}},
+	&InstructionGotoTable{Table: 1},
+}
+
+// benchMessage describes the message used in the benchmarks and the
+// allocation tests.
+type benchMessage struct {
+	Name string
+
+	// Message is used to encode, New returns an empty message used
+	// to decode the serialized representation of the Message.
+	Message io.WriterTo
+	New     func() io.ReaderFrom
+
+	// MaxAllocs is a maximum allowed number of allocations used to
+	// decode the message.
+	MaxAllocs float64
+}
+
+// benchMessages are the ten most common messages of the control
+// channel.
+var benchMessages = []benchMessage{
+	{"Hello", &Hello{HelloElems{&HelloElemVersionBitmap{[]uint32{0x12}}}},
+		func() io.ReaderFrom { return new(Hello) }, 24},
+	{"EchoRequest", &EchoRequest{Data: make([]byte, 16)},
+		func() io.ReaderFrom { return new(EchoRequest) }, 4},
+	{"SwitchFeatures", &SwitchFeatures{DatapathID: 1, NumBuffers: 256,
+		NumTables: 254, Capabilities: CapabilityFlowStats},
+		func() io.ReaderFrom { return new(SwitchFeatures) }, 12},
+	{"PacketIn", &PacketIn{Buffer: NoBuffer, Length: 128,
+		Reason: PacketInReasonNoMatch, Match: benchMatch,
+		Data: make([]byte, 128)},
+		func() io.ReaderFrom { return new(PacketIn) }, 16},
+	{"PacketOut", &PacketOut{Buffer: NoBuffer, InPort: PortController,
+		Actions: Actions{&ActionOutput{Port: PortFlood}},
+		Data:    make([]byte, 128)},
+		func() io.ReaderFrom { return new(PacketOut) }, 30},
+	{"FlowMod", &FlowMod{Command: FlowAdd, Priority: 100,
+		Buffer: NoBuffer, OutPort: PortAny, OutGroup: GroupAny,
+		Match: benchMatch, Instructions: benchInstructions},
+		func() io.ReaderFrom { return new(FlowMod) }, 95},
+	{"FlowRemoved", &FlowRemoved{Cookie: 1, Priority: 100,
+		Reason: FlowReasonIdleTimeout, Match: benchMatch},
+		func() io.ReaderFrom { return new(FlowRemoved) }, 20},
+	{"PortStatus", &PortStatus{Reason: PortReasonModify, Port: Port{
+		PortNo: 1, HWAddr: net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		Name: "eth0"}},
+		func() io.ReaderFrom { return new(PortStatus) }, 26},
+	{"FlowStatsRequest", &FlowStatsRequest{Table: TableAll,
+		OutPort: PortAny, OutGroup: GroupAny, Match: benchMatch},
+		func() io.ReaderFrom { return new(FlowStatsRequest) }, 16},
+	{"FlowStats", &FlowStats{Priority: 100, PacketCount: 42,
+		Match: benchMatch, Instructions: benchInstructions},
+		func() io.ReaderFrom { return new(FlowStats) }, 100},
+}
+
+// marshal returns the wire representation of the message.
+func (m *benchMessage) marshal(tb testing.TB) []byte {
+	var buf bytes.Buffer
+	if _, err := m.Message.WriteTo(&buf); err != nil {
+		tb.Fatalf("Failed to encode %s: %s", m.Name, err)
+	}
+
+	return buf.Bytes()
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, m := range benchMessages {
+		m := m
+		b.Run(m.Name, func(b *testing.B) {
+			b.SetBytes(int64(len(m.marshal(b))))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				m.Message.WriteTo(ioutil.Discard)
+			}
+		})
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for _, m := range benchMessages {
+		m := m
+		b.Run(m.Name, func(b *testing.B) {
+			data := m.marshal(b)
+			rd := bytes.NewReader(data)
+
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				rd.Reset(data)
+				if _, err := m.New().ReadFrom(rd); err != nil {
+					b.Fatalf("Failed to decode %s: %s", m.Name, err)
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeAllocs ensures the number of allocations used to decode the
+// most common messages does not grow unnoticed. Update the limits along
+// with the published baselines when the encoding layer is changed.
+func TestDecodeAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping allocation test in short mode")
+	}
+
+	for _, m := range benchMessages {
+		data := m.marshal(t)
+		rd := bytes.NewReader(data)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			rd.Reset(data)
+			m.New().ReadFrom(rd)
+		})
+
+		if allocs > m.MaxAllocs {
+			t.Errorf("Decoding of %s takes %.0f allocations, limit %.0f",
+				m.Name, allocs, m.MaxAllocs)
+		}
+	}
+}
+
+// BenchmarkDecodeMatch measures decoding of the match, the values and
+// masks of the fields are sliced from a single buffer.
+func BenchmarkDecodeMatch(b *testing.B) {
+	var buf bytes.Buffer
+	benchMatch.WriteTo(&buf)
+
+	data := buf.Bytes()
+	rd := bytes.NewReader(data)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		rd.Reset(data)
+
+		var m Match
+		if _, err := m.ReadFrom(rd); err != nil {
+			b.Fatalf("Failed to decode match: %s", err)
+		}
+	}
+}
+
+// BenchmarkDecodeFlowDump measures decoding of the large flow dump, it
+// is dominated by the dispatch of the actions and instructions.
+func BenchmarkDecodeFlowDump(b *testing.B) {
+	var buf bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		stats := FlowStats{Priority: uint16(i), Match: benchMatch,
+			Instructions: benchInstructions}
+		stats.WriteTo(&buf)
+	}
+
+	data := buf.Bytes()
+	rd := bytes.NewReader(data)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		rd.Reset(data)
+
+		for {
+			var stats FlowStats
+			if _, err := stats.ReadFrom(rd); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatalf("Failed to decode flow dump: %s", err)
+			}
+		}
+	}
+}
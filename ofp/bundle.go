@@ -0,0 +1,214 @@
+package ofp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/netrack/openflow/internal/encoding"
+)
+
+// ExperimenterONF is an experimenter identifier of the Open Networking
+// Foundation extensions.
+const ExperimenterONF uint32 = 0x4f4e4600
+
+const (
+	// ONFExpTypeBundleControl is an experimenter type of the bundle
+	// control message of the ONF extension 230.
+	ONFExpTypeBundleControl uint32 = 2300
+
+	// ONFExpTypeBundleAdd is an experimenter type of the bundle add
+	// message of the ONF extension 230.
+	ONFExpTypeBundleAdd uint32 = 2301
+)
+
+// BundleControlType defines the type of the bundle control message.
+type BundleControlType uint16
+
+const (
+	// BundleOpenRequest is used to create a new bundle.
+	BundleOpenRequest BundleControlType = iota
+
+	// BundleOpenReply is sent by the switch in reply to the open
+	// request.
+	BundleOpenReply
+
+	// BundleCloseRequest is used to prevent the further additions of
+	// the messages to the bundle.
+	BundleCloseRequest
+
+	// BundleCloseReply is sent by the switch in reply to the close
+	// request.
+	BundleCloseReply
+
+	// BundleCommitRequest is used to apply all messages of the bundle.
+	BundleCommitRequest
+
+	// BundleCommitReply is sent by the switch in reply to the commit
+	// request.
+	BundleCommitReply
+
+	// BundleDiscardRequest is used to discard the bundle without
+	// applying its messages.
+	BundleDiscardRequest
+
+	// BundleDiscardReply is sent by the switch in reply to the discard
+	// request.
+	BundleDiscardReply
+)
+
+func (t BundleControlType) String() string {
+	text, ok := bundleControlTypeText[t]
+	if !ok {
+		return fmt.Sprintf("BundleControlType(%d)", t)
+	}
+	return text
+}
+
+var bundleControlTypeText = map[BundleControlType]string{
+	BundleOpenRequest:    "BundleOpenRequest",
+	BundleOpenReply:      "BundleOpenReply",
+	BundleCloseRequest:   "BundleCloseRequest",
+	BundleCloseReply:     "BundleCloseReply",
+	BundleCommitRequest:  "BundleCommitRequest",
+	BundleCommitReply:    "BundleCommitReply",
+	BundleDiscardRequest: "BundleDiscardRequest",
+	BundleDiscardReply:   "BundleDiscardReply",
+}
+
+// BundleFlag defines the flags of the bundle.
+type BundleFlag uint16
+
+const (
+	// BundleAtomic is set to apply the messages of the bundle
+	// atomically: either all of them or none.
+	BundleAtomic BundleFlag = 1 << iota
+
+	// BundleOrdered is set to apply the messages of the bundle in the
+	// order they were added.
+	BundleOrdered
+)
+
+// BundleControl is an experimenter message of the ONF extension 230,
+// used to manage the bundles on the OpenFlow 1.3 switches. The message
+// is sent with of.TypeExperiment type.
+//
+// For example, to open a new atomic bundle:
+//
+//	req := of.NewRequest(of.TypeExperiment, &ofp.BundleControl{
+//		Bundle: 1,
+//		Type:   ofp.BundleOpenRequest,
+//		Flags:  ofp.BundleAtomic,
+//	})
+type BundleControl struct {
+	// Bundle is an identifier of the bundle.
+	Bundle uint32
+
+	// Type is a type of the control message.
+	Type BundleControlType
+
+	// Flags is a bitmap of the bundle flags.
+	Flags BundleFlag
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the bundle
+// control message into the wire format.
+func (b *BundleControl) WriteTo(w io.Writer) (int64, error) {
+	header := Experimenter{ExperimenterONF, ONFExpTypeBundleControl}
+	return encoding.WriteTo(w, &header, b.Bundle, b.Type, b.Flags)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// bundle control message from the wire format. The properties of the
+// message are skipped.
+func (b *BundleControl) ReadFrom(r io.Reader) (int64, error) {
+	var header Experimenter
+
+	n, err := encoding.ReadFrom(r, &header, &b.Bundle, &b.Type, &b.Flags)
+	if err != nil {
+		return n, err
+	}
+
+	if err = checkBundleHeader(&header, ONFExpTypeBundleControl); err != nil {
+		return n, err
+	}
+
+	nn, err := io.Copy(ioutil.Discard, r)
+	return n + nn, err
+}
+
+// checkBundleHeader returns an error, when the experimenter header does
+// not belong to the bundle message of the given type.
+func checkBundleHeader(header *Experimenter, expType uint32) error {
+	if header.Experimenter != ExperimenterONF || header.ExpType != expType {
+		return fmt.Errorf("ofp: experimenter 0x%x type %d is not a "+
+			"bundle message", header.Experimenter, header.ExpType)
+	}
+
+	return nil
+}
+
+// bundleMessageHeaderLen is a length of the header of the message
+// added to the bundle.
+const bundleMessageHeaderLen = 8
+
+// BundleAdd is an experimenter message of the ONF extension 230, used
+// to add the message to the open bundle. The message is sent with
+// of.TypeExperiment type.
+type BundleAdd struct {
+	// Bundle is an identifier of the bundle.
+	Bundle uint32
+
+	// Flags is a bitmap of the bundle flags, it must be the same as
+	// the flags used to open the bundle.
+	Flags BundleFlag
+
+	// Message is the added OpenFlow message including the header in
+	// the wire format. The transaction identifier of the message must
+	// be the same as the identifier of the bundle add message.
+	Message []byte
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the bundle
+// add message into the wire format.
+func (b *BundleAdd) WriteTo(w io.Writer) (int64, error) {
+	header := Experimenter{ExperimenterONF, ONFExpTypeBundleAdd}
+	return encoding.WriteTo(w, &header, b.Bundle, pad2{}, b.Flags, b.Message)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// bundle add message from the wire format. The length of the added
+// message is taken from its header, the properties following the
+// message are skipped.
+func (b *BundleAdd) ReadFrom(r io.Reader) (int64, error) {
+	var header Experimenter
+	var msgHeader [bundleMessageHeaderLen]byte
+
+	n, err := encoding.ReadFrom(r, &header, &b.Bundle,
+		&defaultPad2, &b.Flags, &msgHeader)
+	if err != nil {
+		return n, err
+	}
+
+	if err = checkBundleHeader(&header, ONFExpTypeBundleAdd); err != nil {
+		return n, err
+	}
+
+	length := int(binary.BigEndian.Uint16(msgHeader[2:4]))
+	if length < bundleMessageHeaderLen {
+		return n, fmt.Errorf("ofp: invalid length of the bundled "+
+			"message: %d", length)
+	}
+
+	b.Message = make([]byte, length)
+	copy(b.Message, msgHeader[:])
+
+	nn, err := io.ReadFull(r, b.Message[bundleMessageHeaderLen:])
+	if n += int64(nn); err != nil {
+		return n, err
+	}
+
+	discarded, err := io.Copy(ioutil.Discard, r)
+	return n + discarded, err
+}
@@ -0,0 +1,72 @@
+package ofp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/netrack/openflow/internal/encodingtest"
+)
+
+func TestBundleControl(t *testing.T) {
+	tests := []encodingtest.MU{
+		{ReadWriter: &BundleControl{
+			Bundle: 0x2a,
+			Type:   BundleCommitRequest,
+			Flags:  BundleAtomic | BundleOrdered,
+		}, Bytes: []byte{
+			0x4f, 0x4e, 0x46, 0x00, // Experimenter.
+			0x00, 0x00, 0x08, 0xfc, // Experimenter type.
+			0x00, 0x00, 0x00, 0x2a, // Bundle identifier.
+			0x00, 0x04, // Control type.
+			0x00, 0x03, // Flags.
+		}},
+	}
+
+	encodingtest.RunMU(t, tests)
+}
+
+func TestBundleAdd(t *testing.T) {
+	tests := []encodingtest.MU{
+		{ReadWriter: &BundleAdd{
+			Bundle: 0x2a,
+			Flags:  BundleAtomic,
+			Message: []byte{
+				0x04, 0x12, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x01,
+				0xab, 0xcd,
+			},
+		}, Bytes: []byte{
+			0x4f, 0x4e, 0x46, 0x00, // Experimenter.
+			0x00, 0x00, 0x08, 0xfd, // Experimenter type.
+			0x00, 0x00, 0x00, 0x2a, // Bundle identifier.
+			0x00, 0x00, // 2-byte padding.
+			0x00, 0x01, // Flags.
+			0x04, 0x12, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x01, // Header.
+			0xab, 0xcd, // Body of the message.
+		}},
+	}
+
+	encodingtest.RunMU(t, tests)
+}
+
+func TestBundleInvalid(t *testing.T) {
+	var control BundleControl
+	_, err := control.ReadFrom(bytes.NewReader([]byte{
+		0x00, 0x00, 0x23, 0x20, 0x00, 0x00, 0x08, 0xfc,
+		0x00, 0x00, 0x00, 0x2a, 0x00, 0x04, 0x00, 0x03,
+	}))
+
+	if err == nil {
+		t.Errorf("Error expected for non-ONF experimenter")
+	}
+
+	var add BundleAdd
+	_, err = add.ReadFrom(bytes.NewReader([]byte{
+		0x4f, 0x4e, 0x46, 0x00, 0x00, 0x00, 0x08, 0xfd,
+		0x00, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x01,
+		0x04, 0x12, 0x00, 0x10, 0x00, 0x00, 0x00, 0x01,
+	}))
+
+	if err == nil {
+		t.Errorf("Error expected for truncated message")
+	}
+}
@@ -0,0 +1,149 @@
+package ofp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/netrack/openflow/internal/encoding"
+)
+
+var (
+	// ErrPaddingNotZero is returned in strict mode, when the padding
+	// of the decoded message contains non-zero bytes.
+	ErrPaddingNotZero = errors.New("ofp: padding is not zero")
+
+	// ErrReservedValue is returned in strict mode, when the reserved
+	// field of the message is set or the value of the enumeration is
+	// out of the range defined by the specification.
+	ErrReservedValue = errors.New("ofp: reserved value")
+
+	// ErrMisaligned is returned in strict mode, when the serialized
+	// action, instruction, property or match does not end on the 8-byte
+	// boundary.
+	ErrMisaligned = errors.New("ofp: element is not 64-bit aligned")
+)
+
+// CheckMode defines how the reserved fields of the messages are
+// validated during encoding and decoding.
+type CheckMode uint32
+
+const (
+	// CheckLenient ignores the content of the paddings and accepts
+	// values of enumerations that are not defined by specification.
+	// The table and queue properties of unknown types are decoded
+	// into the raw properties. This is the default mode.
+	CheckLenient CheckMode = iota
+
+	// CheckStrict rejects messages with non-zero paddings, non-zero
+	// reserved fields, undefined values of enumerations and unknown
+	// types of the table and queue properties. This mode is useful to
+	// certify the switch implementations during the interoperability
+	// testing.
+	CheckStrict
+)
+
+func (m CheckMode) String() string {
+	text, ok := checkModeText[m]
+	if !ok {
+		return fmt.Sprintf("CheckMode(%d)", m)
+	}
+	return text
+}
+
+var checkModeText = map[CheckMode]string{
+	CheckLenient: "CheckLenient",
+	CheckStrict:  "CheckStrict",
+}
+
+// checkMode stores the default check mode, it is accessed atomically,
+// so the mode could be changed while messages are decoded.
+var checkMode uint32
+
+// SetCheckMode sets the default mode of the reserved fields validation.
+// The default mode is used by all encoders and by the decoders, which
+// are not given a mode of their own with NewCheckReader or the context
+// of the request created with NewCheckModeContext.
+func SetCheckMode(m CheckMode) {
+	atomic.StoreUint32(&checkMode, uint32(m))
+}
+
+// CurrentCheckMode returns the default mode of the reserved fields
+// validation.
+func CurrentCheckMode() CheckMode {
+	return CheckMode(atomic.LoadUint32(&checkMode))
+}
+
+// IsStrict reports whether the default check mode is strict.
+func IsStrict() bool {
+	return CurrentCheckMode() == CheckStrict
+}
+
+// checkModeKey is the key of the check mode in the context.
+type checkModeKey struct{}
+
+// NewCheckModeContext returns a new context carrying the check mode.
+// The body of the of.Request with such context is decoded in the given
+// mode instead of the default one, so the mode could be selected per
+// connection, for example:
+//
+//	ctx := ofp.NewCheckModeContext(r.Context(), ofp.CheckStrict)
+//	if err := r.WithContext(ctx).Decode(&features); err != nil {
+//		// ...
+//	}
+func NewCheckModeContext(ctx context.Context, m CheckMode) context.Context {
+	return context.WithValue(ctx, checkModeKey{}, m)
+}
+
+// CheckModeFromContext returns the check mode carried by the context.
+func CheckModeFromContext(ctx context.Context) (CheckMode, bool) {
+	m, ok := ctx.Value(checkModeKey{}).(CheckMode)
+	return m, ok
+}
+
+// NewCheckReader returns a reader, that reads from r and makes the
+// decoders validate the messages in the given mode instead of the
+// default one.
+func NewCheckReader(r io.Reader, m CheckMode) io.Reader {
+	ctx := encoding.ReaderContext(r)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return encoding.NewContextReader(r, NewCheckModeContext(ctx, m))
+}
+
+// readerMode returns the check mode of the decoder reading from r. The
+// default mode is returned, when the reader does not carry the mode.
+func readerMode(r io.Reader) CheckMode {
+	if ctx := encoding.ReaderContext(r); ctx != nil {
+		if m, ok := CheckModeFromContext(ctx); ok {
+			return m
+		}
+	}
+
+	return CurrentCheckMode()
+}
+
+// checkReserved returns ErrReservedValue wrapped with the name of the
+// field, when the mode is strict and the value is not valid.
+func checkReserved(m CheckMode, name string, valid bool, v interface{}) error {
+	if valid || m != CheckStrict {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s: %v", ErrReservedValue, name, v)
+}
+
+// checkAligned returns ErrMisaligned wrapped with the type of the element,
+// when the mode is strict and the length of the serialized element is not
+// a multiple of 8 bytes.
+func checkAligned(m CheckMode, t interface{}, length int64) error {
+	if length%8 == 0 || m != CheckStrict {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v: %d bytes", ErrMisaligned, t, length)
+}
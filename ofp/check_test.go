@@ -0,0 +1,224 @@
+package ofp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/netrack/openflow/internal/encoding"
+)
+
+func TestCheckModePadding(t *testing.T) {
+	defer SetCheckMode(CheckLenient)
+
+	b := []byte{
+		0xfe,             // Table identifier.
+		0x00, 0x01, 0x00, // 3-byte padding.
+		0x00, 0x00, 0x00, 0x00, // Configuration.
+	}
+
+	var tm TableMod
+	_, err := tm.ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Lenient mode must ignore padding: %s", err)
+	}
+
+	SetCheckMode(CheckStrict)
+	_, err = tm.ReadFrom(bytes.NewReader(b))
+	if !errors.Is(err, ErrPaddingNotZero) {
+		t.Fatalf("Strict mode must reject non-zero padding: %v", err)
+	}
+}
+
+func TestCheckModeReserved(t *testing.T) {
+	defer SetCheckMode(CheckLenient)
+
+	fm := FlowMod{Command: FlowDeleteStrict + 1}
+
+	var buf bytes.Buffer
+	_, err := fm.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Lenient mode must ignore reserved values: %s", err)
+	}
+
+	var rfm FlowMod
+	_, err = rfm.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Lenient mode must ignore reserved values: %s", err)
+	}
+
+	SetCheckMode(CheckStrict)
+	_, err = fm.WriteTo(&bytes.Buffer{})
+	if !errors.Is(err, ErrReservedValue) {
+		t.Fatalf("Strict mode must reject reserved values: %v", err)
+	}
+
+	_, err = rfm.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if !errors.Is(err, ErrReservedValue) {
+		t.Fatalf("Strict mode must reject reserved values: %v", err)
+	}
+}
+
+func TestCheckModeStrict(t *testing.T) {
+	defer SetCheckMode(CheckLenient)
+	SetCheckMode(CheckStrict)
+
+	var buf bytes.Buffer
+	fm := FlowMod{Command: FlowAdd, Match: Match{Type: MatchTypeXM}}
+
+	_, err := fm.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Error writing flow modification: %s", err)
+	}
+
+	var rfm FlowMod
+	_, err = rfm.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("Valid message must be accepted: %s", err)
+	}
+}
+
+func TestCheckModeAlignment(t *testing.T) {
+	defer SetCheckMode(CheckLenient)
+	SetCheckMode(CheckStrict)
+
+	var writers []io.WriterTo
+
+	// Collect zero values of all known actions, instructions and
+	// properties using the decoder maps.
+	var makers []encoding.ReaderMaker
+	for _, rm := range actionMap {
+		makers = append(makers, rm)
+	}
+	for _, rm := range instructionMap {
+		makers = append(makers, rm)
+	}
+	for _, rm := range tablePropMap {
+		makers = append(makers, rm)
+	}
+	for _, rm := range queuePropTypeMap {
+		makers = append(makers, rm)
+	}
+
+	for _, rm := range makers {
+		rd, err := rm.MakeReader()
+		if err != nil {
+			t.Fatalf("Failed to create a reader: %s", err)
+		}
+
+		writers = append(writers, rd.(io.WriterTo))
+	}
+
+	// Set-field actions and matches with values of different length.
+	for _, length := range []int{1, 2, 3, 4, 6, 8, 16} {
+		xm := XM{
+			Class: XMClassOpenflowBasic,
+			Type:  XMTypeMetadata,
+			Value: make(XMValue, length),
+		}
+
+		writers = append(writers,
+			&ActionSetField{Field: xm},
+			&Match{MatchTypeXM, []XM{xm}},
+			&Match{MatchTypeXM, []XM{xm, xm}},
+		)
+	}
+
+	for _, w := range writers {
+		var buf bytes.Buffer
+		n, err := w.WriteTo(&buf)
+		if err != nil {
+			t.Errorf("Failed to write %T: %s", w, err)
+			continue
+		}
+
+		if n%8 != 0 {
+			t.Errorf("Serialized %T is not aligned: %d bytes", w, n)
+		}
+	}
+}
+
+func TestCheckAligned(t *testing.T) {
+	if err := checkAligned(CheckLenient, ActionTypeOutput, 12); err != nil {
+		t.Fatalf("Lenient mode must ignore alignment: %s", err)
+	}
+
+	err := checkAligned(CheckStrict, ActionTypeOutput, 12)
+	if !errors.Is(err, ErrMisaligned) {
+		t.Fatalf("Strict mode must reject misaligned elements: %v", err)
+	}
+}
+
+func TestCheckReader(t *testing.T) {
+	defer SetCheckMode(CheckLenient)
+
+	b := []byte{
+		0x00, 0x01, // Match type.
+		0x00, 0x04, // Match length.
+		0x00, 0x00, 0x01, 0x00, // 4-byte padding.
+	}
+
+	var m Match
+	_, err := m.ReadFrom(NewCheckReader(bytes.NewReader(b), CheckStrict))
+	if !errors.Is(err, ErrPaddingNotZero) {
+		t.Fatalf("Strict reader must reject non-zero padding: %v", err)
+	}
+
+	SetCheckMode(CheckStrict)
+	_, err = m.ReadFrom(NewCheckReader(bytes.NewReader(b), CheckLenient))
+	if err != nil {
+		t.Fatalf("Lenient reader must ignore padding: %s", err)
+	}
+}
+
+func TestCheckReaderNested(t *testing.T) {
+	fm := FlowMod{
+		Match: Match{Type: MatchTypeXM},
+		Instructions: Instructions{
+			&InstructionApplyActions{Actions: Actions{
+				&ActionOutput{Port: 1},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := fm.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write flow mod: %s", err)
+	}
+
+	// Corrupt the padding of the output action, which is the last
+	// field of the message.
+	b := buf.Bytes()
+	b[len(b)-1] = 0x01
+
+	var rfm FlowMod
+	_, err := rfm.ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Default mode must ignore padding: %s", err)
+	}
+
+	_, err = rfm.ReadFrom(NewCheckReader(bytes.NewReader(b), CheckStrict))
+	if !errors.Is(err, ErrPaddingNotZero) {
+		t.Fatalf("Strict mode must apply to nested elements: %v", err)
+	}
+}
+
+func TestCheckModeContext(t *testing.T) {
+	ctx := NewCheckModeContext(context.Background(), CheckStrict)
+
+	mode, ok := CheckModeFromContext(ctx)
+	if !ok || mode != CheckStrict {
+		t.Fatalf("Invalid check mode: %s", mode)
+	}
+
+	r := encoding.NewContextReader(bytes.NewReader(nil), ctx)
+	if mode = readerMode(r); mode != CheckStrict {
+		t.Errorf("Invalid reader mode: %s", mode)
+	}
+
+	if mode = readerMode(bytes.NewReader(nil)); mode != CheckLenient {
+		t.Errorf("Invalid default mode: %s", mode)
+	}
+}
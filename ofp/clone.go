@@ -0,0 +1,29 @@
+package ofp
+
+import (
+	"reflect"
+)
+
+// cloneBytes returns a copy of the given slice of bytes, so the result
+// does not share the backing array with the original slice.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(b)), b...)
+}
+
+// cloneValue returns a pointer to the copy of the value referenced by
+// the given pointer. It is used to copy actions and instructions that
+// do not contain slices.
+func cloneValue(v interface{}) interface{} {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return v
+	}
+
+	clone := reflect.New(value.Elem().Type())
+	clone.Elem().Set(value.Elem())
+	return clone.Interface()
+}
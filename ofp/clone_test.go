@@ -0,0 +1,76 @@
+package ofp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFlowModClone(t *testing.T) {
+	fmod := &FlowMod{
+		Command: FlowAdd,
+		Match: Match{MatchTypeXM, []XM{{
+			Class: XMClassOpenflowBasic,
+			Type:  XMTypeInPort,
+			Value: XMValue{0x00, 0x00, 0x00, 0x03},
+		}}},
+		Instructions: Instructions{
+			&InstructionApplyActions{Actions{
+				&ActionSetField{Field: XM{
+					Class: XMClassOpenflowBasic,
+					Type:  XMTypeVlanID,
+					Value: XMValue{0x00, 0x02},
+				}},
+				&ActionOutput{Port: 2},
+			}},
+			&InstructionGotoTable{Table: 1},
+		},
+	}
+
+	var before, after bytes.Buffer
+	clone := fmod.Clone()
+
+	fmod.WriteTo(&before)
+	clone.WriteTo(&after)
+
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Fatalf("Clone is not wire compatible:\n%x\n%x",
+			before.Bytes(), after.Bytes())
+	}
+
+	// Modify the clone, it must not affect the original message.
+	clone.Match.Fields[0].Value[3] = 0x04
+
+	apply := clone.Instructions[0].(*InstructionApplyActions)
+	apply.Actions[0].(*ActionSetField).Field.Value[1] = 0x03
+	apply.Actions[1].(*ActionOutput).Port = 3
+	clone.Instructions[1].(*InstructionGotoTable).Table = 2
+
+	after.Reset()
+	fmod.WriteTo(&after)
+
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Fatalf("Original message was modified through the clone:\n%x\n%x",
+			before.Bytes(), after.Bytes())
+	}
+}
+
+func TestPacketOutClone(t *testing.T) {
+	pout := &PacketOut{
+		Buffer:  NoBuffer,
+		InPort:  PortController,
+		Actions: Actions{&ActionOutput{Port: PortFlood}},
+		Data:    []byte{0x01, 0x02, 0x03},
+	}
+
+	clone := pout.Clone()
+	clone.Data[0] = 0xff
+	clone.Actions[0].(*ActionOutput).Port = 1
+
+	if pout.Data[0] != 0x01 {
+		t.Errorf("Data of the original message was modified")
+	}
+
+	if pout.Actions[0].(*ActionOutput).Port != PortFlood {
+		t.Errorf("Actions of the original message were modified")
+	}
+}
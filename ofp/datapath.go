@@ -0,0 +1,98 @@
+package ofp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DatapathID uniquely identifies the datapath. The lower 48 bits are
+// for a MAC address, while the upper 16 bits are implementer-defined.
+type DatapathID uint64
+
+// datapathIDLen is a length of the datapath identifier in bytes.
+const datapathIDLen = 8
+
+// NewDatapathID creates a new datapath identifier from the given
+// implementer-defined bits and the hardware address. Only the first
+// 6 bytes of the hardware address are used.
+func NewDatapathID(impl uint16, hwaddr net.HardwareAddr) DatapathID {
+	id := DatapathID(impl) << 48
+	for i := 0; i < 6 && i < len(hwaddr); i++ {
+		id |= DatapathID(hwaddr[i]) << uint(40-8*i)
+	}
+
+	return id
+}
+
+// ParseDatapathID parses the datapath identifier in the canonical
+// colon-separated format, e.g. "00:00:f2:e0:b3:b6:a3:4c", or as
+// 16 hexadecimal digits, optionally prefixed with "0x".
+func ParseDatapathID(s string) (DatapathID, error) {
+	text := s
+	if strings.Contains(text, ":") {
+		octets := strings.Split(text, ":")
+		if len(octets) != datapathIDLen {
+			return 0, fmt.Errorf("ofp: invalid datapath ID: %q", s)
+		}
+
+		for _, octet := range octets {
+			if len(octet) != 2 {
+				return 0, fmt.Errorf("ofp: invalid datapath ID: %q", s)
+			}
+		}
+
+		text = strings.Join(octets, "")
+	} else {
+		text = strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X")
+	}
+
+	if len(text) != datapathIDLen*2 {
+		return 0, fmt.Errorf("ofp: invalid datapath ID: %q", s)
+	}
+
+	b, err := hex.DecodeString(text)
+	if err != nil {
+		return 0, fmt.Errorf("ofp: invalid datapath ID: %q", s)
+	}
+
+	var id DatapathID
+	for _, octet := range b {
+		id = id<<8 | DatapathID(octet)
+	}
+
+	return id, nil
+}
+
+// HardwareAddr returns the MAC address stored in the lower 48 bits of
+// the datapath identifier.
+func (id DatapathID) HardwareAddr() net.HardwareAddr {
+	hwaddr := make(net.HardwareAddr, 6)
+	for i := range hwaddr {
+		hwaddr[i] = byte(id >> uint(40-8*i))
+	}
+
+	return hwaddr
+}
+
+// Implementer returns the implementer-defined upper 16 bits of the
+// datapath identifier.
+func (id DatapathID) Implementer() uint16 {
+	return uint16(id >> 48)
+}
+
+// String returns the datapath identifier in the canonical
+// colon-separated format.
+func (id DatapathID) String() string {
+	var b strings.Builder
+	for i := 0; i < datapathIDLen; i++ {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+
+		fmt.Fprintf(&b, "%02x", byte(id>>uint(56-8*i)))
+	}
+
+	return b.String()
+}
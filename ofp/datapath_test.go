@@ -0,0 +1,68 @@
+package ofp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestParseDatapathID(t *testing.T) {
+	tests := []struct {
+		Text string
+		ID   DatapathID
+		Err  bool
+	}{
+		{Text: "00:00:f2:e0:b3:b6:a3:4c", ID: 0x0000f2e0b3b6a34c},
+		{Text: "0000F2E0B3B6A34C", ID: 0x0000f2e0b3b6a34c},
+		{Text: "0x0001f2e0b3b6a34c", ID: 0x0001f2e0b3b6a34c},
+		{Text: "00:00:f2:e0:b3:b6:a3", Err: true},
+		{Text: "00:00:f2:e0:b3:b6:a3:4", Err: true},
+		{Text: "0:00:f2:e0:b3:b6:a3:4cc", Err: true},
+		{Text: "zz00f2e0b3b6a34c", Err: true},
+		{Text: "", Err: true},
+	}
+
+	for _, test := range tests {
+		id, err := ParseDatapathID(test.Text)
+		if test.Err {
+			if err == nil {
+				t.Errorf("Error expected for %q", test.Text)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", test.Text, err)
+		}
+
+		if id != test.ID {
+			t.Errorf("Invalid datapath ID parsed from %q: %x", test.Text, id)
+		}
+	}
+}
+
+func TestDatapathID(t *testing.T) {
+	hwaddr := net.HardwareAddr{0xf2, 0xe0, 0xb3, 0xb6, 0xa3, 0x4c}
+	id := NewDatapathID(0x2a, hwaddr)
+
+	if id != 0x002af2e0b3b6a34c {
+		t.Fatalf("Invalid datapath ID: %x", uint64(id))
+	}
+
+	if text := id.String(); text != "00:2a:f2:e0:b3:b6:a3:4c" {
+		t.Errorf("Invalid datapath ID representation: %s", text)
+	}
+
+	if !bytes.Equal(id.HardwareAddr(), hwaddr) {
+		t.Errorf("Invalid hardware address: %s", id.HardwareAddr())
+	}
+
+	if id.Implementer() != 0x2a {
+		t.Errorf("Invalid implementer bits: %x", id.Implementer())
+	}
+
+	parsed, err := ParseDatapathID(id.String())
+	if err != nil || parsed != id {
+		t.Errorf("Datapath ID must be parsed from its representation")
+	}
+}
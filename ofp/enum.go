@@ -0,0 +1,175 @@
+package ofp
+
+import (
+	"sort"
+)
+
+// Wire versions of the protocol, used in the header of the messages and
+// to describe the version, in which the enumeration values were
+// introduced.
+const (
+	Version10 uint8 = 1 + iota
+	Version11
+	Version12
+	Version13
+	Version14
+)
+
+// EnumValue describes a value of the protocol enumeration. The list of
+// values could be used to build the tools, like command line interfaces,
+// fuzzers and documentation generators, without copying the tables.
+type EnumValue struct {
+	// Name is a string representation of the value.
+	Name string
+
+	// Value is a numeric value of the enumeration.
+	Value uint32
+
+	// Version is a wire version of the protocol, in which the value
+	// was introduced with the current meaning.
+	Version uint8
+}
+
+// enumValues returns the list of enumeration values sorted by value.
+func enumValues[T ~uint8 | ~uint16](text map[T]string, version func(T) uint8) []EnumValue {
+	values := make([]EnumValue, 0, len(text))
+	for v, name := range text {
+		values = append(values, EnumValue{name, uint32(v), version(v)})
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Value < values[j].Value
+	})
+
+	return values
+}
+
+// ActionTypes returns the metadata of the action types.
+func ActionTypes() []EnumValue {
+	return enumValues(actionText, func(t ActionType) uint8 {
+		switch {
+		case t == ActionTypeOutput || t == ActionTypeExperimenter:
+			return Version10
+		case t < ActionTypeSetField:
+			return Version11
+		case t == ActionTypeSetField:
+			return Version12
+		}
+		return Version13
+	})
+}
+
+// InstructionTypes returns the metadata of the instruction types.
+func InstructionTypes() []EnumValue {
+	return enumValues(instructionTypeText, func(t InstructionType) uint8 {
+		if t == InstructionTypeMeter {
+			return Version13
+		}
+		return Version11
+	})
+}
+
+// XMTypes returns the metadata of the OpenFlow basic match field types.
+func XMTypes() []EnumValue {
+	return enumValues(xmTypeText, func(t XMType) uint8 {
+		if t > XMTypeMPLSTC {
+			return Version13
+		}
+		return Version12
+	})
+}
+
+// errTypeVersion returns the version, in which the error type was
+// introduced.
+func errTypeVersion(t ErrType) uint8 {
+	switch {
+	case t <= ErrTypeBadAction:
+		return Version10
+	case t <= ErrTypeSwitchConfigFailed:
+		return Version11
+	case t == ErrTypeRoleRequestFailed || t == ErrTypeExperimenter:
+		return Version12
+	}
+	return Version13
+}
+
+// ErrTypes returns the metadata of the error types.
+func ErrTypes() []EnumValue {
+	return enumValues(errTypeText, errTypeVersion)
+}
+
+// errCodeVersion is the first error code introduced in the version.
+type errCodeVersion struct {
+	code    ErrCode
+	version uint8
+}
+
+// errCodeVersions lists the first error codes of the error types added
+// in the later versions of the protocol, ordered by the code. The codes
+// below the first listed one were introduced along with the error type.
+var errCodeVersions = map[ErrType][]errCodeVersion{
+	ErrTypeBadRequest: {
+		{ErrCodeBadRequestBadTableID, Version11},
+		{ErrCodeBadRequestIsSlave, Version12},
+		{ErrCodeBadRequestMultipartBufferOverflow, Version13},
+	},
+	ErrTypeBadAction: {
+		{ErrCodeBadActionOutGroup, Version11},
+		{ErrCodeBadActionSetType, Version12},
+	},
+	ErrTypeBadInstruction: {
+		{ErrCodeBadInstructionExperimenter, Version12},
+	},
+	ErrTypeBadMatch: {
+		{ErrCodeBadMatchBadMask, Version12},
+	},
+	ErrTypeFlowModFailed: {
+		{ErrCodeFlowModFailedBadFlags, Version12},
+	},
+	ErrTypeGroupModFailed: {
+		{ErrCodeGroupModFailedChainedGroup, Version12},
+	},
+	ErrTypePortModFailed: {
+		{ErrCodePortModFailedPerm, Version12},
+	},
+	ErrTypeTableModFailed: {
+		{ErrCodeTableModFailedPerm, Version12},
+	},
+	ErrTypeQueueOpFailed: {
+		{ErrCodeQueueOpFailedPerm, Version12},
+	},
+	ErrTypeSwitchConfigFailed: {
+		{ErrCodeSwitchConfigFailedPerm, Version12},
+	},
+}
+
+// ErrCodes returns the metadata of the error codes of the given error
+// type.
+func ErrCodes(t ErrType) []EnumValue {
+	return enumValues(errTypeCodeText[t], func(code ErrCode) uint8 {
+		version := errTypeVersion(t)
+		for _, v := range errCodeVersions[t] {
+			if code >= v.code {
+				version = v.version
+			}
+		}
+		return version
+	})
+}
+
+// MultipartTypes returns the metadata of the multipart message types.
+func MultipartTypes() []EnumValue {
+	return enumValues(multipartTypeText, func(t MultipartType) uint8 {
+		switch {
+		case t <= MultipartTypeQueue || t == MultipartTypeExperimenter:
+			return Version10
+		case t <= MultipartTypeGroupDescription:
+			return Version11
+		case t == MultipartTypeGroupFeatures:
+			return Version12
+		case t <= MultipartTypePortDescription:
+			return Version13
+		}
+		return Version14
+	})
+}
@@ -0,0 +1,59 @@
+package ofp
+
+import (
+	"testing"
+)
+
+func TestEnumValues(t *testing.T) {
+	tests := []struct {
+		Values []EnumValue
+		Len    int
+		Value  EnumValue
+	}{
+		{ActionTypes(), len(actionText),
+			EnumValue{"ActionSetField", 25, Version12}},
+		{InstructionTypes(), len(instructionTypeText),
+			EnumValue{"InstructionMeter", 6, Version13}},
+		{XMTypes(), len(xmTypeText),
+			EnumValue{"XMTypeTunnelID", 38, Version13}},
+		{ErrTypes(), len(errTypeText),
+			EnumValue{"ErrTypeBadMatch", 4, Version11}},
+		{ErrCodes(ErrTypeBadRequest), len(errTypeCodeText[ErrTypeBadRequest]),
+			EnumValue{"ErrCodeBadRequestBadType", 1, Version10}},
+		{ErrCodes(ErrTypeBadRequest), len(errTypeCodeText[ErrTypeBadRequest]),
+			EnumValue{"ErrCodeBadRequestBadTableID", 9, Version11}},
+		{ErrCodes(ErrTypeBadRequest), len(errTypeCodeText[ErrTypeBadRequest]),
+			EnumValue{"ErrCodeBadRequestBadPacket", 12, Version12}},
+		{ErrCodes(ErrTypeBadRequest), len(errTypeCodeText[ErrTypeBadRequest]),
+			EnumValue{"ErrCodeBadRequestMultipartBufferOverflow", 13, Version13}},
+		{ErrCodes(ErrTypeBadAction), len(errTypeCodeText[ErrTypeBadAction]),
+			EnumValue{"ErrCodeBadActionQueue", 8, Version10}},
+		{ErrCodes(ErrTypeBadAction), len(errTypeCodeText[ErrTypeBadAction]),
+			EnumValue{"ErrCodeBadActionSetArgument", 15, Version12}},
+		{ErrCodes(ErrTypeFlowModFailed), len(errTypeCodeText[ErrTypeFlowModFailed]),
+			EnumValue{"ErrCodeFlowModFailedBadCommand", 6, Version11}},
+		{ErrCodes(ErrTypeMeterModFailed), len(errTypeCodeText[ErrTypeMeterModFailed]),
+			EnumValue{"ErrCodeMeterModFailedUnknown", 0, Version13}},
+		{MultipartTypes(), len(multipartTypeText),
+			EnumValue{"MultipartTypeFlowMonitor", 16, Version14}},
+	}
+
+	for _, test := range tests {
+		if len(test.Values) != test.Len {
+			t.Errorf("Invalid number of values: %d", len(test.Values))
+		}
+
+		var found bool
+		for i, v := range test.Values {
+			if i > 0 && test.Values[i-1].Value >= v.Value {
+				t.Errorf("Values must be sorted: %v", test.Values)
+			}
+
+			found = found || v == test.Value
+		}
+
+		if !found {
+			t.Errorf("Value %v is not found", test.Value)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package ofp
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -744,14 +746,23 @@ func (e *Error) WriteTo(w io.Writer) (int64, error) {
 	return encoding.WriteTo(w, e.Type, e.Code, e.Data)
 }
 
+// ErrExperimenterError is returned when the experimenter error message
+// is decoded as Error. Use ReadError to decode such messages.
+var ErrExperimenterError = errors.New("ofp: experimenter error, use ReadError")
+
 // ReadFrom implements io.ReadFrom interface. It deserializes the
-// error message from the wire format.
+// error message from the wire format. The experimenter error messages
+// are rejected with ErrExperimenterError.
 func (e *Error) ReadFrom(r io.Reader) (n int64, err error) {
 	n, err = encoding.ReadFrom(r, &e.Type, &e.Code)
 	if err != nil {
 		return
 	}
 
+	if e.Type == ErrTypeExperimenter {
+		return n, ErrExperimenterError
+	}
+
 	e.Data, err = ioutil.ReadAll(r)
 	if err != nil {
 		return
@@ -760,6 +771,47 @@ func (e *Error) ReadFrom(r io.Reader) (n int64, err error) {
 	return n + int64(len(e.Data)), nil
 }
 
+// ErrorDataLen is a maximum number of bytes of the failed request
+// included into the data of the error message.
+const ErrorDataLen = 64
+
+// ErrorData returns the data of the error message replying to the
+// given failed request in the wire format including the header. The
+// request is truncated to the first ErrorDataLen bytes and copied.
+func ErrorData(request []byte) []byte {
+	if len(request) > ErrorDataLen {
+		request = request[:ErrorDataLen]
+	}
+
+	return cloneBytes(request)
+}
+
+// AsError finds the first Error in the chain of the given error, so
+// the failures of the validation wrapping the Error could be converted
+// into the error message. Both Error values and pointers are recognized.
+//
+// For example, to reply to the invalid flow modification:
+//
+//	if err := fmod.Match.Canonicalize(); err != nil {
+//		if e, ok := ofp.AsError(err); ok {
+//			e.Data = ofp.ErrorData(request)
+//			// ...
+//		}
+//	}
+func AsError(err error) (Error, bool) {
+	var e Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+
+	var pe *Error
+	if errors.As(err, &pe) && pe != nil {
+		return *pe, true
+	}
+
+	return Error{}, false
+}
+
 // ErrorExperimenter defines an experimental error message.
 type ErrorExperimenter struct {
 	// ExpType is experimenter type defined kind of error.
@@ -772,6 +824,15 @@ type ErrorExperimenter struct {
 	Data []byte
 }
 
+func (e ErrorExperimenter) Error() string {
+	return e.String()
+}
+
+func (e ErrorExperimenter) String() string {
+	return fmt.Sprintf("ErrTypeExperimenter(0x%08x)ExpType(%d)",
+		e.Experimenter, e.ExpType)
+}
+
 // WriteTo implements io.WriterTo interface. It serializes experimenter
 // error message into the wire format.
 func (e *ErrorExperimenter) WriteTo(w io.Writer) (int64, error) {
@@ -795,3 +856,50 @@ func (e *ErrorExperimenter) ReadFrom(r io.Reader) (n int64, err error) {
 
 	return n + int64(len(e.Data)), nil
 }
+
+// ErrorMessage is an error message decoded from the wire format. It is
+// either *Error or *ErrorExperimenter.
+type ErrorMessage interface {
+	error
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// ReadError deserializes the error message from the wire format. When
+// the type of the error is ErrTypeExperimenter, the *ErrorExperimenter
+// is returned, otherwise the *Error is returned.
+//
+// For example, to inspect the experimenter errors returned by the
+// switch, the following code could be used:
+//
+//	e, err := ofp.ReadError(r.Body)
+//	if err != nil {
+//		return
+//	}
+//
+//	if exp, ok := e.(*ofp.ErrorExperimenter); ok {
+//		log.Printf("experimenter error: %d", exp.ExpType)
+//	}
+func ReadError(r io.Reader) (ErrorMessage, error) {
+	var etype ErrType
+	if _, err := encoding.ReadFrom(r, &etype); err != nil {
+		return nil, err
+	}
+
+	// Put the type back to the reader, so the error message
+	// could be decoded with a regular ReadFrom call.
+	var buf bytes.Buffer
+	encoding.WriteTo(&buf, etype)
+	r = encoding.InheritReader(r, io.MultiReader(&buf, r))
+
+	var e ErrorMessage = new(Error)
+	if etype == ErrTypeExperimenter {
+		e = new(ErrorExperimenter)
+	}
+
+	if _, err := e.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
@@ -1,6 +1,8 @@
 package ofp
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/netrack/openflow/internal/encodingtest"
@@ -38,3 +40,68 @@ func TestErrorExperimenterMsg(t *testing.T) {
 
 	encodingtest.RunMU(t, tests)
 }
+
+func TestErrorExperimenterString(t *testing.T) {
+	e := ErrorExperimenter{ExpType: 4, Experimenter: 0x2320}
+
+	text := "ErrTypeExperimenter(0x00002320)ExpType(4)"
+	if e.String() != text {
+		t.Fatalf("Invalid experimenter error text: %s", e)
+	}
+}
+
+func TestErrorReadExperimenter(t *testing.T) {
+	data := []byte{
+		0xff, 0xff, // Error type.
+		0x00, 0x04, // Experimenter type.
+		0x00, 0x00, 0x23, 0x20, // Experimenter.
+		0x01,
+	}
+
+	var e Error
+	_, err := e.ReadFrom(bytes.NewReader(data))
+	if err != ErrExperimenterError {
+		t.Fatalf("Experimenter error must be rejected: %v", err)
+	}
+
+	msg, err := ReadError(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to read experimenter error: %s", err)
+	}
+
+	exp, ok := msg.(*ErrorExperimenter)
+	if !ok || exp.ExpType != 4 || exp.Experimenter != 0x2320 {
+		t.Fatalf("Invalid experimenter error decoded: %#v", msg)
+	}
+}
+
+func TestAsError(t *testing.T) {
+	want := Error{Type: ErrTypeBadAction, Code: ErrCodeBadActionType}
+
+	tests := []error{
+		want,
+		&want,
+		fmt.Errorf("wrapped: %w", want),
+	}
+
+	// Unknown action type must be reported with the bad action error.
+	var actions Actions
+	_, err := actions.ReadFrom(bytes.NewReader([]byte{0x7f, 0x7e, 0x00, 0x08, 0, 0, 0, 0}))
+	tests = append(tests, err)
+
+	for _, err := range tests {
+		e, ok := AsError(err)
+		if !ok || e.Type != want.Type || e.Code != want.Code {
+			t.Errorf("Failed to convert error %v: %v", err, e)
+		}
+	}
+
+	if _, ok := AsError(ErrPaddingNotZero); ok {
+		t.Errorf("Error without wire error must not be converted")
+	}
+
+	data := ErrorData(make([]byte, 100))
+	if len(data) != ErrorDataLen {
+		t.Errorf("Data must be truncated to 64 bytes: %d", len(data))
+	}
+}
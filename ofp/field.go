@@ -0,0 +1,172 @@
+package ofp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+)
+
+// XMFormatter returns a human-readable representation of the value or
+// mask of the extensible match field.
+type XMFormatter func(v XMValue) string
+
+// FormatXMUint formats the value as a decimal big-endian unsigned integer.
+func FormatXMUint(v XMValue) string {
+	return new(big.Int).SetBytes(v).String()
+}
+
+// FormatXMHex formats the value as a hexadecimal number.
+func FormatXMHex(v XMValue) string {
+	return fmt.Sprintf("0x%x", []byte(v))
+}
+
+// FormatXMHardwareAddr formats the value as a hardware address.
+func FormatXMHardwareAddr(v XMValue) string {
+	return net.HardwareAddr(v).String()
+}
+
+// FormatXMIP formats the value as an IPv4 or IPv6 address.
+func FormatXMIP(v XMValue) string {
+	return net.IP(v).String()
+}
+
+// FormatXMIPv6ExtHeader formats the value as a bitmap of the IPv6
+// extension headers.
+func FormatXMIPv6ExtHeader(v XMValue) string {
+	if len(v) != 2 {
+		return FormatXMHex(v)
+	}
+
+	return IPv6ExtensionHeader(binary.BigEndian.Uint16(v)).String()
+}
+
+// XMField describes the extensible match field of the specific class
+// and type. The descriptions of the fields are used to format them and
+// to validate the length of decoded fields in strict check mode.
+//
+// For example, to register the Nicira register field, the following
+// description can be used:
+//
+//	ofp.RegisterXMField(ofp.XMField{
+//		Class:  ofp.XMClassNicira1,
+//		Type:   0,
+//		Name:   "reg0",
+//		Len:    4,
+//		Format: ofp.FormatXMHex,
+//	})
+type XMField struct {
+	// Class is a class of the match field.
+	Class XMClass
+
+	// Type is a class-specific type of the match field.
+	Type XMType
+
+	// Name is a human-readable name of the match field.
+	Name string
+
+	// Len is a length of the field value in bytes, it does not include
+	// the length of the mask. Zero means the length is not validated.
+	Len int
+
+	// Format is used to format the value and the mask of the field,
+	// when nil, the hexadecimal representation is used.
+	Format XMFormatter
+}
+
+// xmFieldKey uniquely identifies the match field.
+type xmFieldKey struct {
+	Class XMClass
+	Type  XMType
+}
+
+var (
+	xmFields   = make(map[xmFieldKey]XMField)
+	xmFieldsMu sync.RWMutex
+)
+
+// RegisterXMField registers the description of the extensible match
+// field. The existing description of the field with the same class and
+// type is replaced.
+func RegisterXMField(f XMField) {
+	xmFieldsMu.Lock()
+	defer xmFieldsMu.Unlock()
+
+	xmFields[xmFieldKey{f.Class, f.Type}] = f
+}
+
+// LookupXMField returns the description of the extensible match field
+// of the given class and type.
+func LookupXMField(class XMClass, t XMType) (XMField, bool) {
+	xmFieldsMu.RLock()
+	defer xmFieldsMu.RUnlock()
+
+	f, ok := xmFields[xmFieldKey{class, t}]
+	return f, ok
+}
+
+// LookupXMFieldName returns the description of the extensible match
+// field with the given name.
+func LookupXMFieldName(name string) (XMField, bool) {
+	xmFieldsMu.RLock()
+	defer xmFieldsMu.RUnlock()
+
+	for _, f := range xmFields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return XMField{}, false
+}
+
+func init() {
+	basic := []XMField{
+		{Type: XMTypeInPort, Name: "in_port", Len: 4, Format: FormatXMUint},
+		{Type: XMTypeInPhyPort, Name: "in_phy_port", Len: 4, Format: FormatXMUint},
+		{Type: XMTypeMetadata, Name: "metadata", Len: 8},
+		{Type: XMTypeEthDst, Name: "eth_dst", Len: 6, Format: FormatXMHardwareAddr},
+		{Type: XMTypeEthSrc, Name: "eth_src", Len: 6, Format: FormatXMHardwareAddr},
+		{Type: XMTypeEthType, Name: "eth_type", Len: 2},
+		{Type: XMTypeVlanID, Name: "vlan_vid", Len: 2},
+		{Type: XMTypeVlanPCP, Name: "vlan_pcp", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeIPDSCP, Name: "ip_dscp", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeIPECN, Name: "ip_ecn", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeIPProto, Name: "ip_proto", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeIPv4Src, Name: "ipv4_src", Len: 4, Format: FormatXMIP},
+		{Type: XMTypeIPv4Dst, Name: "ipv4_dst", Len: 4, Format: FormatXMIP},
+		{Type: XMTypeTCPSrc, Name: "tcp_src", Len: 2, Format: FormatXMUint},
+		{Type: XMTypeTCPDst, Name: "tcp_dst", Len: 2, Format: FormatXMUint},
+		{Type: XMTypeUDPSrc, Name: "udp_src", Len: 2, Format: FormatXMUint},
+		{Type: XMTypeUDPDst, Name: "udp_dst", Len: 2, Format: FormatXMUint},
+		{Type: XMTypeSCTPSrc, Name: "sctp_src", Len: 2, Format: FormatXMUint},
+		{Type: XMTypeSCTPDst, Name: "sctp_dst", Len: 2, Format: FormatXMUint},
+		{Type: XMTypeICMPv4Type, Name: "icmpv4_type", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeICMPv4Code, Name: "icmpv4_code", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeARPOpcode, Name: "arp_op", Len: 2, Format: FormatXMUint},
+		{Type: XMTypeARPSPA, Name: "arp_spa", Len: 4, Format: FormatXMIP},
+		{Type: XMTypeARPTPA, Name: "arp_tpa", Len: 4, Format: FormatXMIP},
+		{Type: XMTypeARPSHA, Name: "arp_sha", Len: 6, Format: FormatXMHardwareAddr},
+		{Type: XMTypeARPTHA, Name: "arp_tha", Len: 6, Format: FormatXMHardwareAddr},
+		{Type: XMTypeIPv6Src, Name: "ipv6_src", Len: 16, Format: FormatXMIP},
+		{Type: XMTypeIPv6Dst, Name: "ipv6_dst", Len: 16, Format: FormatXMIP},
+		{Type: XMTypeIPv6FLabel, Name: "ipv6_flabel", Len: 4},
+		{Type: XMTypeICMPv6Type, Name: "icmpv6_type", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeICMPv6Code, Name: "icmpv6_code", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeIPv6NDTarget, Name: "ipv6_nd_target", Len: 16, Format: FormatXMIP},
+		{Type: XMTypeIPv6NDSLL, Name: "ipv6_nd_sll", Len: 6, Format: FormatXMHardwareAddr},
+		{Type: XMTypeIPv6NDTLL, Name: "ipv6_nd_tll", Len: 6, Format: FormatXMHardwareAddr},
+		{Type: XMTypeMPLSLabel, Name: "mpls_label", Len: 4, Format: FormatXMUint},
+		{Type: XMTypeMPLSTC, Name: "mpls_tc", Len: 1, Format: FormatXMUint},
+		{Type: XMTypeMPLSBOS, Name: "mpls_bos", Len: 1, Format: FormatXMUint},
+		{Type: XMTypePBBISID, Name: "pbb_isid", Len: 3, Format: FormatXMUint},
+		{Type: XMTypeTunnelID, Name: "tunnel_id", Len: 8},
+		{Type: XMTypeIPv6ExtHeader, Name: "ipv6_exthdr", Len: 2, Format: FormatXMIPv6ExtHeader},
+	}
+
+	for _, f := range basic {
+		f.Class = XMClassOpenflowBasic
+		RegisterXMField(f)
+	}
+}
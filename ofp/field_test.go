@@ -0,0 +1,80 @@
+package ofp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestXMString(t *testing.T) {
+	RegisterXMField(XMField{
+		Class: XMClassNicira1,
+		Type:  0,
+		Name:  "reg0",
+		Len:   4,
+	})
+
+	tests := []struct {
+		XM   XM
+		Text string
+	}{
+		{XM{Class: XMClassOpenflowBasic, Type: XMTypeInPort,
+			Value: XMValue{0x00, 0x00, 0x00, 0x03}}, "in_port=3"},
+		{XM{Class: XMClassOpenflowBasic, Type: XMTypeIPv4Dst,
+			Value: XMValue{0x0a, 0x00, 0x00, 0x01},
+			Mask:  XMValue{0xff, 0xff, 0xff, 0x00}},
+			"ipv4_dst=10.0.0.1/255.255.255.0"},
+		{XM{Class: XMClassOpenflowBasic, Type: XMTypeEthSrc,
+			Value: XMValue{0x01, 0x23, 0x45, 0x67, 0x89, 0xab}},
+			"eth_src=01:23:45:67:89:ab"},
+		{XM{Class: XMClassOpenflowBasic, Type: XMTypeIPv6ExtHeader,
+			Value: XMValue{0x00, 0x50}, Mask: XMValue{0x00, 0x50}},
+			"ipv6_exthdr=frag|hop/frag|hop"},
+		{XM{Class: XMClassNicira1, Type: 0,
+			Value: XMValue{0x00, 0x00, 0x00, 0x2a}}, "reg0=0x0000002a"},
+		{XM{Class: XMClassNicira1, Type: 1,
+			Value: XMValue{0x01}}, "XMClassNicira1:1=0x01"},
+	}
+
+	for _, test := range tests {
+		if text := test.XM.String(); text != test.Text {
+			t.Errorf("Invalid match field representation, expected:\n"+
+				"`%s` got:\n`%s`", test.Text, text)
+		}
+	}
+}
+
+func TestXMLength(t *testing.T) {
+	defer SetCheckMode(CheckLenient)
+
+	b := []byte{
+		0x80, 0x00, // OpenFlow basic class.
+		0x00,       // Match field + Mask flag.
+		0x02,       // Payload length.
+		0x00, 0x03, // Payload.
+	}
+
+	var xm XM
+	if _, err := xm.ReadFrom(bytes.NewReader(b)); err != nil {
+		t.Fatalf("Lenient mode must not validate length: %s", err)
+	}
+
+	SetCheckMode(CheckStrict)
+	if _, err := xm.ReadFrom(bytes.NewReader(b)); err == nil {
+		t.Fatalf("Strict mode must reject invalid length of the field")
+	}
+}
+
+func TestLookupXMFieldName(t *testing.T) {
+	f, ok := LookupXMFieldName("ipv6_dst")
+	if !ok {
+		t.Fatalf("Field ipv6_dst must be registered")
+	}
+
+	if f.Class != XMClassOpenflowBasic || f.Type != XMTypeIPv6Dst {
+		t.Errorf("Invalid field returned: %v", f)
+	}
+
+	if _, ok = LookupXMFieldName("unknown"); ok {
+		t.Errorf("Unknown field must not be found")
+	}
+}
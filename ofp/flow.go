@@ -2,6 +2,8 @@ package ofp
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
 	"io"
 
 	"github.com/netrack/openflow/internal/encoding"
@@ -223,6 +225,10 @@ func (f *FlowMod) SetCookies(cookies uint64) {
 // WriteTo implements io.WriterTo interface. It serializes the flow
 // modification command into the wire format with a necessary padding.
 func (f *FlowMod) WriteTo(w io.Writer) (int64, error) {
+	if err := f.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
 	return encoding.WriteTo(w, f.Cookie, f.CookieMask, f.Table,
 		f.Command, f.IdleTimeout, f.HardTimeout, f.Priority,
 		f.Buffer, f.OutPort, f.OutGroup, f.Flags, pad2{},
@@ -237,11 +243,31 @@ func (f *FlowMod) ReadFrom(r io.Reader) (int64, error) {
 	// to reuse the same exemplar for multiple deserializations.
 	f.Instructions = nil
 
-	return encoding.ReadFrom(r, &f.Cookie, &f.CookieMask, &f.Table,
+	n, err := encoding.ReadFrom(r, &f.Cookie, &f.CookieMask, &f.Table,
 		&f.Command, &f.IdleTimeout, &f.HardTimeout, &f.Priority,
 		&f.Buffer, &f.OutPort, &f.OutGroup, &f.Flags, &defaultPad2,
 		&f.Match, &f.Instructions,
 	)
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, f.checkReserved(readerMode(r))
+}
+
+// Clone returns a deep copy of the flow modification message.
+func (f *FlowMod) Clone() *FlowMod {
+	clone := *f
+	clone.Match = f.Match.Clone()
+	clone.Instructions = f.Instructions.Clone()
+	return &clone
+}
+
+// checkReserved validates the flow modification command in strict mode.
+func (f *FlowMod) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "flow mod command",
+		f.Command <= FlowDeleteStrict, f.Command)
 }
 
 // FlowRemovedReason specifies the reason of the flow entry removal.
@@ -263,6 +289,21 @@ const (
 	FlowReasonGroupDelete
 )
 
+func (r FlowRemovedReason) String() string {
+	text, ok := flowRemovedReasonText[r]
+	if !ok {
+		return fmt.Sprintf("FlowRemovedReason(%d)", r)
+	}
+	return text
+}
+
+var flowRemovedReasonText = map[FlowRemovedReason]string{
+	FlowReasonIdleTimeout: "FlowReasonIdleTimeout",
+	FlowReasonHardTimeout: "FlowReasonHardTimeout",
+	FlowReasonDelete:      "FlowReasonDelete",
+	FlowReasonGroupDelete: "FlowReasonGroupDelete",
+}
+
 // FlowRemoved represents an OpenFlow message that is send if the
 // controller has requested to be notified when flow entries are timed
 // out or are deleted from tables.
@@ -319,6 +360,10 @@ func (f *FlowRemoved) SetCookies(cookies uint64) {
 // WriteTo implements io.WriterTo interface. It serializes the flow
 // removed message into the wire format with necessary padding.
 func (f *FlowRemoved) WriteTo(w io.Writer) (int64, error) {
+	if err := f.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
 	return encoding.WriteTo(w, f.Cookie, f.Priority, f.Reason,
 		f.Table, f.DurationSec, f.DurationNSec, f.IdleTimeout,
 		f.HardTimeout, f.PacketCount, f.ByteCount, &f.Match,
@@ -328,10 +373,29 @@ func (f *FlowRemoved) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements ReaderFrom interface. It serializes the flow
 // removed message from the wire format.
 func (f *FlowRemoved) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &f.Cookie, &f.Priority, &f.Reason,
+	n, err := encoding.ReadFrom(r, &f.Cookie, &f.Priority, &f.Reason,
 		&f.Table, &f.DurationSec, &f.DurationNSec, &f.IdleTimeout,
 		&f.HardTimeout, &f.PacketCount, &f.ByteCount, &f.Match,
 	)
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, f.checkReserved(readerMode(r))
+}
+
+// Clone returns a deep copy of the flow removed message.
+func (f *FlowRemoved) Clone() *FlowRemoved {
+	clone := *f
+	clone.Match = f.Match.Clone()
+	return &clone
+}
+
+// checkReserved validates the flow removal reason in strict mode.
+func (f *FlowRemoved) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "flow removed reason",
+		f.Reason <= FlowReasonGroupDelete, f.Reason)
 }
 
 // FlowStatsRequest is a multipart request used to retrieve information
@@ -404,6 +468,13 @@ func (f *FlowStatsRequest) ReadFrom(r io.Reader) (int64, error) {
 	)
 }
 
+// Clone returns a deep copy of the flow statistics request.
+func (f *FlowStatsRequest) Clone() *FlowStatsRequest {
+	clone := *f
+	clone.Match = f.Match.Clone()
+	return &clone
+}
+
 // FlowStats is a body returned within the multipart flow statistics
 // reply.
 type FlowStats struct {
@@ -489,9 +560,95 @@ func (f *FlowStats) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	limrd := io.LimitReader(r, int64(len)-n)
+	// The declared length must cover at least the fixed header and
+	// the match, the rest of the entry is a list of instructions.
+	if int64(len) < n {
+		return n, fmt.Errorf("ofp: flow stats length %d is less "+
+			"than length of header and match %d", len, n)
+	}
+
+	body := make([]byte, int64(len)-n)
+	nn, err := io.ReadFull(r, body)
+	if n += int64(nn); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return n, err
+	}
+
+	// Some switches pad the entries with the trailing zero bytes,
+	// so the instructions are decoded only up to the padding.
+	instLen := flowStatsInstructionsLen(body)
+	if err = checkPad(readerMode(r), body[instLen:]); err != nil {
+		return n, err
+	}
+
 	f.Instructions = nil
+	instrd := encoding.InheritReader(r, bytes.NewReader(body[:instLen]))
+	_, err = f.Instructions.ReadFrom(instrd)
+	return n, err
+}
+
+// flowStatsInstructionsLen returns the length of the instructions of
+// the flow statistics entry without the trailing padding. The padding
+// starts at the instruction header of zero type and length, or at the
+// remainder shorter than the instruction header.
+func flowStatsInstructionsLen(b []byte) int {
+	var offset int
+	for offset+4 <= len(b) {
+		itype := binary.BigEndian.Uint16(b[offset:])
+		ilen := int(binary.BigEndian.Uint16(b[offset+2:]))
+
+		if itype == 0 && ilen == 0 {
+			break
+		}
+
+		// Let the instructions decoder report the invalid length.
+		if ilen < 4 || offset+ilen > len(b) {
+			return len(b)
+		}
+
+		offset += ilen
+	}
+
+	return offset
+}
+
+// Clone returns a deep copy of the flow statistics.
+func (f *FlowStats) Clone() *FlowStats {
+	clone := *f
+	clone.Match = f.Match.Clone()
+	clone.Instructions = f.Instructions.Clone()
+	return &clone
+}
 
-	nn, err := f.Instructions.ReadFrom(limrd)
-	return n + nn, err
+// FlowMod returns a flow modification message, that adds the flow entry
+// equivalent to the one described by the flow statistics. The match and
+// instructions are copied, so the message could be modified without
+// changing the statistics.
+//
+// For example, to copy all flow entries from one switch to another, the
+// flow statistics could be converted to the flow modifications:
+//
+//	for _, stats := range flows {
+//		req := of.NewRequest(of.TypeFlowMod, stats.FlowMod())
+//		// ...
+//	}
+func (f *FlowStats) FlowMod() *FlowMod {
+	clone := f.Clone()
+
+	return &FlowMod{
+		Cookie:       clone.Cookie,
+		Table:        clone.Table,
+		Command:      FlowAdd,
+		IdleTimeout:  clone.IdleTimeout,
+		HardTimeout:  clone.HardTimeout,
+		Priority:     clone.Priority,
+		Buffer:       NoBuffer,
+		OutPort:      PortAny,
+		OutGroup:     GroupAny,
+		Flags:        clone.Flags,
+		Match:        clone.Match,
+		Instructions: clone.Instructions,
+	}
 }
@@ -1,7 +1,11 @@
 package ofp
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/gob"
+	"errors"
+	"io"
 	"reflect"
 	"testing"
 
@@ -212,6 +216,135 @@ func TestFlowStats(t *testing.T) {
 	encodingtest.RunMU(t, tests)
 }
 
+func TestFlowStatsLength(t *testing.T) {
+	apply := &InstructionApplyActions{Actions: Actions{
+		&ActionOutput{Port: PortController, MaxLen: ContentLenNoBuffer},
+	}}
+
+	inPort := XM{
+		Class: XMClassOpenflowBasic,
+		Type:  XMTypeInPort,
+		Value: XMValue{0x00, 0x00, 0x00, 0x03},
+	}
+
+	tests := []struct {
+		stats  FlowStats
+		length int
+	}{
+		// The size of the ofp_flow_stats structure with the empty
+		// match is 56 bytes according to the specification.
+		{FlowStats{Match: Match{Type: MatchTypeXM}}, 56},
+		{FlowStats{Match: Match{MatchTypeXM, []XM{inPort}}}, 64},
+		{FlowStats{Match: Match{Type: MatchTypeXM},
+			Instructions: Instructions{&InstructionGotoTable{Table: 1}}}, 64},
+		{FlowStats{Match: Match{MatchTypeXM, []XM{inPort}},
+			Instructions: Instructions{apply}}, 88},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if _, err := tt.stats.WriteTo(&buf); err != nil {
+			t.Fatalf("Failed to marshal flow stats: %s", err)
+		}
+
+		length := int(binary.BigEndian.Uint16(buf.Bytes()))
+		if length != tt.length || buf.Len() != tt.length {
+			t.Errorf("Invalid length of flow stats %v: %d, %d bytes "+
+				"written, %d expected", tt.stats, length, buf.Len(), tt.length)
+		}
+	}
+}
+
+func TestFlowStatsPadding(t *testing.T) {
+	stats := &FlowStats{
+		Table:    1,
+		Priority: 10,
+		Match: Match{MatchTypeXM, []XM{{
+			Class: XMClassOpenflowBasic,
+			Type:  XMTypeInPort,
+			Value: XMValue{0x00, 0x00, 0x00, 0x03},
+		}}},
+		Instructions: Instructions{&InstructionGotoTable{Table: 2}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := stats.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to marshal flow stats: %s", err)
+	}
+
+	// withPadding returns the entry padded with the given bytes, the
+	// length of the entry is adjusted to include the padding.
+	withPadding := func(padding ...byte) []byte {
+		b := append(append([]byte(nil), buf.Bytes()...), padding...)
+		binary.BigEndian.PutUint16(b, uint16(len(b)))
+		return b
+	}
+
+	tests := []struct {
+		padding []byte
+		strict  bool
+		err     error
+	}{
+		{padding: nil},
+		{padding: make([]byte, 8)},
+		{padding: make([]byte, 4)},
+		{padding: make([]byte, 3)},
+		{padding: make([]byte, 12)},
+		{padding: []byte{0, 1}, strict: true, err: ErrPaddingNotZero},
+	}
+
+	for _, tt := range tests {
+		if tt.strict {
+			SetCheckMode(CheckStrict)
+		}
+
+		// Decode two entries of the reply, to ensure the padding
+		// of the first one is consumed completely.
+		entry := withPadding(tt.padding...)
+		rd := bytes.NewReader(append(entry, buf.Bytes()...))
+
+		var first, second FlowStats
+		_, err := first.ReadFrom(rd)
+		SetCheckMode(CheckLenient)
+
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("Invalid error for padding %v: %v", tt.padding, err)
+		}
+
+		if tt.err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(&first, stats) {
+			t.Fatalf("Invalid flow stats with padding %v: %v", tt.padding, first)
+		}
+
+		if _, err = second.ReadFrom(rd); err != nil {
+			t.Fatalf("Failed to decode next entry after padding %v: %s",
+				tt.padding, err)
+		}
+
+		if !reflect.DeepEqual(&second, stats) {
+			t.Fatalf("Invalid flow stats after padding %v: %v", tt.padding, second)
+		}
+	}
+
+	// The length does not cover the header and the match.
+	short := withPadding()
+	binary.BigEndian.PutUint16(short, 48)
+
+	var fs FlowStats
+	if _, err := fs.ReadFrom(bytes.NewReader(short)); err == nil {
+		t.Errorf("Error expected for length shorter than header")
+	}
+
+	// The length exceeds the actual data.
+	long := withPadding(make([]byte, 8)...)
+	if _, err := fs.ReadFrom(bytes.NewReader(long[:len(long)-4])); err != io.ErrUnexpectedEOF {
+		t.Errorf("Unexpected EOF error expected: %v", err)
+	}
+}
+
 func TestNewFlowMod(t *testing.T) {
 	match := Match{MatchTypeXM, []XM{{
 		Class: XMClassOpenflowBasic,
@@ -237,3 +370,48 @@ func TestNewFlowMod(t *testing.T) {
 		t.Errorf("Flow match is not the same as in packet-in")
 	}
 }
+
+func TestFlowStatsFlowMod(t *testing.T) {
+	stats := &FlowStats{
+		Table:       2,
+		DurationSec: 10,
+		Priority:    100,
+		IdleTimeout: 30,
+		HardTimeout: 60,
+		Flags:       FlowFlagSendFlowRem,
+		Cookie:      0xabcd,
+		PacketCount: 5,
+		Match: Match{MatchTypeXM, []XM{{
+			Class: XMClassOpenflowBasic,
+			Type:  XMTypeInPort,
+			Value: XMValue{0x00, 0x00, 0x00, 0x03},
+		}}},
+		Instructions: Instructions{&InstructionGotoTable{Table: 3}},
+	}
+
+	fmod := stats.FlowMod()
+	want := &FlowMod{
+		Cookie:       stats.Cookie,
+		Table:        stats.Table,
+		Command:      FlowAdd,
+		IdleTimeout:  stats.IdleTimeout,
+		HardTimeout:  stats.HardTimeout,
+		Priority:     stats.Priority,
+		Buffer:       NoBuffer,
+		OutPort:      PortAny,
+		OutGroup:     GroupAny,
+		Flags:        stats.Flags,
+		Match:        stats.Match,
+		Instructions: stats.Instructions,
+	}
+
+	if !reflect.DeepEqual(fmod, want) {
+		t.Fatalf("Invalid flow mod: %v, expected %v", fmod, want)
+	}
+
+	// The flow mod must not share the match with the statistics.
+	fmod.Match.Fields[0].Value[3] = 0x04
+	if stats.Match.Fields[0].Value[3] != 0x03 {
+		t.Errorf("Statistics were modified through the flow mod")
+	}
+}
@@ -0,0 +1,65 @@
+package ofp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// goString returns the Go-syntax representation of the list of values
+// of the interface type. Unlike the default formatting, that prints the
+// addresses of the pointers, the elements are printed as composite
+// literals, so the output could be pasted back into the tests.
+func goString[T any](name string, elems []T) string {
+	if elems == nil {
+		return name + "(nil)"
+	}
+
+	texts := make([]string, len(elems))
+	for i, elem := range elems {
+		texts[i] = fmt.Sprintf("%#v", elem)
+	}
+
+	return name + "{" + strings.Join(texts, ", ") + "}"
+}
+
+// GoString implements fmt.GoStringer interface. It returns the list
+// of actions as a Go literal.
+func (a Actions) GoString() string {
+	return goString("ofp.Actions", a)
+}
+
+// GoString implements fmt.GoStringer interface. It returns the list
+// of instructions as a Go literal.
+func (i Instructions) GoString() string {
+	return goString("ofp.Instructions", i)
+}
+
+// GoString implements fmt.GoStringer interface. It returns the list
+// of hello elements as a Go literal.
+func (h HelloElems) GoString() string {
+	return goString("ofp.HelloElems", h)
+}
+
+// GoString implements fmt.GoStringer interface. It returns the list
+// of meter bands as a Go literal.
+func (m MeterBands) GoString() string {
+	return goString("ofp.MeterBands", m)
+}
+
+// GoString implements fmt.GoStringer interface. It returns the list
+// of flow updates as a Go literal.
+func (f FlowUpdates) GoString() string {
+	return goString("ofp.FlowUpdates", f)
+}
+
+// GoString implements fmt.GoStringer interface. It returns the list
+// of queue properties as a Go literal.
+func (q QueueProps) GoString() string {
+	return goString("ofp.QueueProps", q)
+}
+
+// GoString implements fmt.GoStringer interface. It returns the list
+// of queue description properties as a Go literal.
+func (q QueueDescProps) GoString() string {
+	return goString("ofp.QueueDescProps", q)
+}
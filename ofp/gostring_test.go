@@ -0,0 +1,37 @@
+package ofp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGoString(t *testing.T) {
+	tests := []struct {
+		Value interface{}
+		Text  string
+	}{
+		{Actions(nil), "ofp.Actions(nil)"},
+		{Actions{&ActionOutput{Port: 2, MaxLen: 0xffff}, &ActionPopVLAN{}},
+			"ofp.Actions{&ofp.ActionOutput{Port:0x2, MaxLen:0xffff}, " +
+				"&ofp.ActionPopVLAN{}}"},
+		{Instructions{
+			&InstructionApplyActions{Actions{&ActionGroup{Group: 1}}},
+			&InstructionGotoTable{Table: 1}},
+			"ofp.Instructions{&ofp.InstructionApplyActions{" +
+				"Actions:ofp.Actions{&ofp.ActionGroup{Group:0x1}}}, " +
+				"&ofp.InstructionGotoTable{Table:0x1}}"},
+	}
+
+	for _, test := range tests {
+		text := fmt.Sprintf("%#v", test.Value)
+		if text != test.Text {
+			t.Errorf("Invalid Go representation:\n%s\n%s", text, test.Text)
+		}
+	}
+
+	text := fmt.Sprintf("%#v", &FlowMod{Instructions: Instructions{}})
+	if !strings.Contains(text, "Instructions:ofp.Instructions{}") {
+		t.Errorf("Nested list is not formatted: %s", text)
+	}
+}
@@ -94,6 +94,10 @@ type GroupMod struct {
 // WriteTo implements io.WriterTo interface. It serializes the group
 // modification message into the wire format.
 func (g *GroupMod) WriteTo(w io.Writer) (int64, error) {
+	if err := g.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
 	n, err := encoding.WriteTo(w, g.Command, g.Type, pad1{}, g.Group)
 	if err != nil {
 		return n, err
@@ -112,9 +116,35 @@ func (g *GroupMod) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	bucketMaker := encoding.ReaderMakerOf(Bucket{})
-	nn, err := encoding.ReadSliceFrom(r, bucketMaker, g.Buckets)
-	return n + nn, err
+	g.Buckets = nil
+
+	nn, err := encoding.ReadSliceFrom(r, &g.Buckets)
+	if err != nil {
+		return n + nn, err
+	}
+
+	return n + nn, g.checkReserved(readerMode(r))
+}
+
+// Clone returns a deep copy of the group modification message.
+func (g *GroupMod) Clone() *GroupMod {
+	clone := *g
+	clone.Buckets = cloneBuckets(g.Buckets)
+	return &clone
+}
+
+// checkReserved validates the group modification command and the
+// type of the group in strict mode.
+func (g *GroupMod) checkReserved(mode CheckMode) error {
+	err := checkReserved(mode, "group mod command",
+		g.Command <= GroupDelete, g.Command)
+	if err != nil {
+		return err
+	}
+
+	// Values in range [128, 255] are reserved for experimental use.
+	return checkReserved(mode, "group type",
+		g.Type <= GroupTypeFastFailover || g.Type >= 128, g.Type)
 }
 
 // bucketLen is a length of the bucket header, it does not
@@ -173,13 +203,33 @@ func (b *Bucket) ReadFrom(r io.Reader) (int64, error) {
 
 	// Created a limited reader to not read more bytes
 	// that it is allocated for the list of actions.
-	limrd := io.LimitReader(r, int64(length-bucketLen))
+	limrd := encoding.LimitReader(r, int64(length-bucketLen))
 	b.Actions = nil
 
 	nn, err := b.Actions.ReadFrom(limrd)
 	return n + nn, err
 }
 
+// Clone returns a deep copy of the bucket.
+func (b Bucket) Clone() Bucket {
+	b.Actions = b.Actions.Clone()
+	return b
+}
+
+// cloneBuckets returns a deep copy of the list of buckets.
+func cloneBuckets(buckets []Bucket) []Bucket {
+	if buckets == nil {
+		return nil
+	}
+
+	clone := make([]Bucket, len(buckets))
+	for i, bucket := range buckets {
+		clone[i] = bucket.Clone()
+	}
+
+	return clone
+}
+
 // GroupStatsRequest is a multipart request used to collect statistics
 // for one or more groups.
 //
@@ -266,10 +316,10 @@ func (g *GroupStats) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	limrd := io.LimitReader(r, int64(length-groupStatsLen))
-	counterMaker := encoding.ReaderMakerOf(BucketCounter{})
+	limrd := encoding.LimitReader(r, int64(length-groupStatsLen))
+	g.BucketStats = nil
 
-	nn, err := encoding.ReadSliceFrom(limrd, counterMaker, g.BucketStats)
+	nn, err := encoding.ReadSliceFrom(limrd, &g.BucketStats)
 	return n + nn, err
 }
 
@@ -342,13 +392,24 @@ func (g *GroupDescStats) ReadFrom(r io.Reader) (int64, error) {
 	n, err := encoding.ReadFrom(r, &length, &g.Type,
 		&defaultPad1, &g.Group)
 
-	limrd := io.LimitReader(r, int64(length-groupDescStatsLen))
-	bucketMaker := encoding.ReaderMakerOf(Bucket{})
+	if err != nil {
+		return n, err
+	}
 
-	nn, err := encoding.ReadSliceFrom(limrd, bucketMaker, g.Buckets)
+	limrd := encoding.LimitReader(r, int64(length-groupDescStatsLen))
+	g.Buckets = nil
+
+	nn, err := encoding.ReadSliceFrom(limrd, &g.Buckets)
 	return n + nn, err
 }
 
+// Clone returns a deep copy of the group description.
+func (g *GroupDescStats) Clone() *GroupDescStats {
+	clone := *g
+	clone.Buckets = cloneBuckets(g.Buckets)
+	return &clone
+}
+
 // GroupCapability defines the group configuration flags.
 type GroupCapability uint32
 
@@ -27,8 +27,7 @@ type HelloElem interface {
 
 // Mapping of the hello elements to the implementations.
 var helloElemMap = map[HelloElemType]encoding.ReaderMaker{
-	HelloElemTypeVersionBitmap: encoding.ReaderMakerOf(
-		HelloElemVersionBitmap{}),
+	HelloElemTypeVersionBitmap: encoding.ReaderMakerOf[HelloElemVersionBitmap](),
 }
 
 // helloElem defines a common header for all hello elements.
@@ -59,13 +58,12 @@ func (h HelloElems) WriteTo(w io.Writer) (int64, error) {
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // list of hello elements from the wire format.
-func (h HelloElems) ReadFrom(r io.Reader) (int64, error) {
-	var helloElemType HelloElemType
+func (h *HelloElems) ReadFrom(r io.Reader) (int64, error) {
 
-	rm := func() (io.ReaderFrom, error) {
+	rm := func(helloElemType HelloElemType) (io.ReaderFrom, error) {
 		if rm, ok := helloElemMap[helloElemType]; ok {
 			rd, err := rm.MakeReader()
-			h = append(h, rd.(HelloElem))
+			*h = append(*h, rd.(HelloElem))
 			return rd, err
 		}
 
@@ -73,8 +71,7 @@ func (h HelloElems) ReadFrom(r io.Reader) (int64, error) {
 		return nil, fmt.Errorf(format, helloElemType)
 	}
 
-	return encoding.ScanFrom(r, &helloElemType,
-		encoding.ReaderMakerFunc(rm))
+	return encoding.ScanFrom(r, rm)
 }
 
 // Hello is a message used to perform an initial handshake right after
@@ -105,9 +102,15 @@ func (h *Hello) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the message from the wire format.
 func (h *Hello) ReadFrom(r io.Reader) (int64, error) {
+	h.Elements = nil
 	return encoding.ReadFrom(r, &h.Elements)
 }
 
+// HelloBitmapsMax is a maximum number of the version bitmaps of the
+// hello element. The bitmaps cover all versions representable by the
+// single byte of the message header, so longer lists are rejected.
+const HelloBitmapsMax = 8
+
 // HelloElemVersionBitmap is a bitmap of the supported versions.
 //
 // The bitmaps field indicates the set of versions of the OpenFlow
@@ -129,19 +132,20 @@ func (h *HelloElemVersionBitmap) Type() HelloElemType {
 // WriteTo implements io.WriterTo interface. It serializes the version
 // bitmap into the wire format.
 func (h *HelloElemVersionBitmap) WriteTo(w io.Writer) (int64, error) {
-	// Calculate the padding used to align the list of bitmaps.
-	maplen := len(h.Bitmaps) * 4
-	padding := make([]byte, (helloElemLen+maplen)%8)
+	if len(h.Bitmaps) > HelloBitmapsMax {
+		return 0, fmt.Errorf("ofp: %d version bitmaps exceed %d",
+			len(h.Bitmaps), HelloBitmapsMax)
+	}
 
-	// Total length of the element includes the length of the
-	// optional header, length of the bitmaps and the padding
-	// used to align the message to 64-bits border.
-	totalLen := helloElemLen + maplen + len(padding)
+	// Length of the element includes the length of the header
+	// and the list of bitmaps, but not the padding used to align
+	// the element to the 64-bits border.
+	length := helloElemLen + len(h.Bitmaps)*4
 
 	// Compose the header of the element and marshal it
 	// altogether with a version bitmaps and required padding.
-	header := helloElem{h.Type(), uint16(totalLen)}
-	return encoding.WriteTo(w, header, h.Bitmaps, padding)
+	header := helloElem{h.Type(), uint16(length)}
+	return encoding.WriteTo(w, header, h.Bitmaps, makePad(length))
 }
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
@@ -155,15 +159,37 @@ func (h *HelloElemVersionBitmap) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
+	// The length is controlled by the remote side, therefore it
+	// must be validated before the memory for bitmaps is allocated.
+	if header.Len < helloElemLen || (header.Len-helloElemLen)%4 != 0 {
+		return n, fmt.Errorf("ofp: invalid hello element length: %d",
+			header.Len)
+	}
+
 	// Calculate the length of the list of version bitmaps.
 	bodyLen := header.Len - helloElemLen
-	limrd := io.LimitReader(r, int64(bodyLen))
+	limrd := encoding.LimitReader(r, int64(bodyLen))
+
+	if bodyLen/4 > HelloBitmapsMax {
+		return n, fmt.Errorf("ofp: %d version bitmaps exceed %d",
+			bodyLen/4, HelloBitmapsMax)
+	}
 
 	// Allocate required amount of memory used to fit all
 	// element version bitmaps (assuming that uint32 takes
-	// 4 bytes of the memory).
+	// 4 bytes of the memory). The bitmaps truncated by the
+	// end of message are reported as unexpected EOF.
 	h.Bitmaps = make([]uint32, bodyLen/4)
 	nn, err := encoding.ReadFrom(limrd, &h.Bitmaps)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if n += nn; err != nil {
+		return n, err
+	}
+
+	nn, err = encoding.ReadFrom(r, makePad(int(header.Len)))
 	return n + nn, err
 }
 
@@ -240,24 +266,43 @@ type RoleRequest struct {
 // WriteTo implements io.WriterTo interface. It serializes the role
 // request into the wire format.
 func (rr *RoleRequest) WriteTo(w io.Writer) (int64, error) {
+	if err := rr.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
 	return encoding.WriteTo(w, rr.Role, pad4{}, rr.GenerationID)
 }
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the role
 // request from the wire format.
 func (rr *RoleRequest) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &rr.Role, &defaultPad4, &rr.GenerationID)
+	n, err := encoding.ReadFrom(r, &rr.Role, &defaultPad4, &rr.GenerationID)
+	if err != nil {
+		return n, err
+	}
+
+	return n, rr.checkReserved(readerMode(r))
+}
+
+// checkReserved validates the controller role in strict mode.
+func (rr *RoleRequest) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "controller role",
+		rr.Role <= ControllerRoleSlave, rr.Role)
 }
 
 // AsyncConfig is a message used to configure the switch to receive
-// specific types of asynchronous messages.
+// specific types of asynchronous messages. The same message is sent by
+// the switch in reply to the TypeGetAsyncRequest message.
+//
+// The first element of each mask is used when the controller has the
+// master or equal role, the second one is used for the slave role.
 //
 // For example, to configure the switch to send notifications on
 // addition of the new port only to the controllers playing the master
 // role, while the modification of the port configuration to the slave
 // controllers, the following request could be constructed:
 //
-//	req := of.NewRequest(of.TypeAsyncConfig, &ofp.AsyncConfig{
+//	req := of.NewRequest(of.TypeSetAsync, &ofp.AsyncConfig{
 //		PortStatusMask: ofputil.Bitmap64(
 //			// Master will receive PortStats message when
 //			// a new port will be added.
@@ -292,3 +337,31 @@ func (a *AsyncConfig) ReadFrom(r io.Reader) (int64, error) {
 	return encoding.ReadFrom(r, &a.PacketInMask,
 		&a.PortStatusMask, &a.FlowRemovedMask)
 }
+
+// asyncMask returns the element of the asynchronous configuration mask
+// used for the given controller role.
+func asyncMask(mask [2]uint32, role ControllerRole) uint32 {
+	if role == ControllerRoleSlave {
+		return mask[1]
+	}
+
+	return mask[0]
+}
+
+// IsPacketInEnabled reports whether the packet-in messages with the
+// given reason are sent to the controller with the specified role.
+func (a *AsyncConfig) IsPacketInEnabled(role ControllerRole, reason PacketInReason) bool {
+	return asyncMask(a.PacketInMask, role)&(1<<reason) != 0
+}
+
+// IsPortStatusEnabled reports whether the port status messages with
+// the given reason are sent to the controller with the specified role.
+func (a *AsyncConfig) IsPortStatusEnabled(role ControllerRole, reason PortReason) bool {
+	return asyncMask(a.PortStatusMask, role)&(1<<reason) != 0
+}
+
+// IsFlowRemovedEnabled reports whether the flow removed messages with
+// the given reason are sent to the controller with the specified role.
+func (a *AsyncConfig) IsFlowRemovedEnabled(role ControllerRole, reason FlowRemovedReason) bool {
+	return asyncMask(a.FlowRemovedMask, role)&(1<<reason) != 0
+}
@@ -1,7 +1,9 @@
 package ofp
 
 import (
+	"bytes"
 	"encoding/gob"
+	"io"
 	"testing"
 
 	"github.com/netrack/openflow/internal/encodingtest"
@@ -16,7 +18,7 @@ func TestHello(t *testing.T) {
 		{ReadWriter: &Hello{}, Bytes: []byte{}},
 		{ReadWriter: &Hello{elems}, Bytes: []byte{
 			0x00, 0x01, // Hello element type.
-			0x00, 0x18, // Hello element length.
+			0x00, 0x14, // Hello element length.
 			0x00, 0x00, 0x00, 0x10, // OpenFlow versions.
 			0x00, 0x00, 0x00, 0x13,
 			0x00, 0x00, 0x00, 0x14,
@@ -29,6 +31,63 @@ func TestHello(t *testing.T) {
 	encodingtest.RunMU(t, tests)
 }
 
+func TestHelloElemVersionBitmapPadding(t *testing.T) {
+	// Some implementations include the padding into the length of
+	// the element, the padding is decoded as the empty bitmap.
+	b := []byte{
+		0x00, 0x01, // Hello element type.
+		0x00, 0x10, // Hello element length.
+		0x00, 0x00, 0x00, 0x12, // OpenFlow versions.
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+	}
+
+	var hello Hello
+	if _, err := hello.ReadFrom(bytes.NewReader(b)); err != nil {
+		t.Fatalf("Failed to decode hello: %s", err)
+	}
+
+	bitmap, ok := hello.Elements[0].(*HelloElemVersionBitmap)
+	if !ok || len(hello.Elements) != 1 || bitmap.Bitmaps[0] != 0x12 {
+		t.Errorf("Invalid hello elements: %v", hello.Elements)
+	}
+}
+
+func TestHelloElemVersionBitmapLimits(t *testing.T) {
+	tests := []struct {
+		Bytes []byte
+		Err   error
+	}{
+		// Length shorter than the header of the element.
+		{Bytes: []byte{0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00}},
+		// Length not aligned to the size of the bitmap.
+		{Bytes: []byte{0x00, 0x01, 0x00, 0x06, 0x00, 0x10, 0x00, 0x00}},
+		// Number of bitmaps exceeds the maximum.
+		{Bytes: []byte{0x00, 0x01, 0xff, 0xfc}},
+		// Bitmaps truncated by the end of the message.
+		{Bytes: []byte{0x00, 0x01, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x10},
+			Err: io.ErrUnexpectedEOF},
+		{Bytes: []byte{0x00, 0x01, 0x00, 0x08}, Err: io.ErrUnexpectedEOF},
+	}
+
+	for _, test := range tests {
+		var hello Hello
+		_, err := hello.ReadFrom(bytes.NewReader(test.Bytes))
+		if err == nil {
+			t.Errorf("Error expected for % x", test.Bytes)
+		}
+
+		if test.Err != nil && err != test.Err {
+			t.Errorf("Invalid error for % x: %v", test.Bytes, err)
+		}
+	}
+
+	elem := &HelloElemVersionBitmap{make([]uint32, HelloBitmapsMax+1)}
+	if _, err := elem.WriteTo(io.Discard); err == nil {
+		t.Errorf("Error expected for %d bitmaps", len(elem.Bitmaps))
+	}
+}
+
 func TestExperimenter(t *testing.T) {
 	tests := []encodingtest.MU{
 		{ReadWriter: &Experimenter{
@@ -73,3 +132,32 @@ func TestAsyncConfig(t *testing.T) {
 
 	encodingtest.RunMU(t, tests)
 }
+
+func TestAsyncConfigEnabled(t *testing.T) {
+	config := AsyncConfig{
+		[2]uint32{1 << PacketInReasonAction, 0},
+		[2]uint32{0, 1 << PortReasonModify},
+		[2]uint32{1 << FlowReasonDelete, 1 << FlowReasonGroupDelete},
+	}
+
+	tests := []struct {
+		Enabled bool
+		Want    bool
+	}{
+		{config.IsPacketInEnabled(ControllerRoleMaster, PacketInReasonAction), true},
+		{config.IsPacketInEnabled(ControllerRoleEqual, PacketInReasonAction), true},
+		{config.IsPacketInEnabled(ControllerRoleSlave, PacketInReasonAction), false},
+		{config.IsPacketInEnabled(ControllerRoleMaster, PacketInReasonNoMatch), false},
+		{config.IsPortStatusEnabled(ControllerRoleSlave, PortReasonModify), true},
+		{config.IsPortStatusEnabled(ControllerRoleMaster, PortReasonModify), false},
+		{config.IsFlowRemovedEnabled(ControllerRoleMaster, FlowReasonDelete), true},
+		{config.IsFlowRemovedEnabled(ControllerRoleSlave, FlowReasonDelete), false},
+		{config.IsFlowRemovedEnabled(ControllerRoleSlave, FlowReasonGroupDelete), true},
+	}
+
+	for i, test := range tests {
+		if test.Enabled != test.Want {
+			t.Errorf("Invalid result of the test %d: %v", i, test.Enabled)
+		}
+	}
+}
@@ -59,12 +59,25 @@ var instructionTypeText = map[InstructionType]string{
 }
 
 var instructionMap = map[InstructionType]encoding.ReaderMaker{
-	InstructionTypeGotoTable:     encoding.ReaderMakerOf(InstructionGotoTable{}),
-	InstructionTypeWriteMetadata: encoding.ReaderMakerOf(InstructionWriteMetadata{}),
-	InstructionTypeApplyActions:  encoding.ReaderMakerOf(InstructionApplyActions{}),
-	InstructionTypeWriteActions:  encoding.ReaderMakerOf(InstructionWriteActions{}),
-	InstructionTypeClearActions:  encoding.ReaderMakerOf(InstructionClearActions{}),
-	InstructionTypeMeter:         encoding.ReaderMakerOf(InstructionMeter{}),
+	InstructionTypeGotoTable:     encoding.ReaderMakerOf[InstructionGotoTable](),
+	InstructionTypeWriteMetadata: encoding.ReaderMakerOf[InstructionWriteMetadata](),
+	InstructionTypeApplyActions:  encoding.ReaderMakerOf[InstructionApplyActions](),
+	InstructionTypeWriteActions:  encoding.ReaderMakerOf[InstructionWriteActions](),
+	InstructionTypeClearActions:  encoding.ReaderMakerOf[InstructionClearActions](),
+	InstructionTypeMeter:         encoding.ReaderMakerOf[InstructionMeter](),
+}
+
+// instructionTable is a dense lookup table of the instruction decoders, that
+// avoids map lookups when decoding the standard instructions.
+var instructionTable = encoding.NewReaderMakerTable(instructionMap)
+
+// RegisterInstruction registers the constructor of the instruction of
+// the given type, used to decode the instructions from the wire format.
+// The decoder of the instruction of the same type is replaced. It is
+// safe to register the instructions concurrently with the decoding.
+func RegisterInstruction(t InstructionType, fn func() Instruction) {
+	instructionTable.Register(t, encoding.ReaderMakerFunc(
+		func() (io.ReaderFrom, error) { return fn(), nil }))
 }
 
 // Instruction header that is common to all instructions. The length
@@ -109,10 +122,15 @@ func (i *Instructions) WriteTo(w io.Writer) (n int64, err error) {
 	var buf bytes.Buffer
 
 	for _, inst := range *i {
-		_, err = inst.WriteTo(&buf)
+		var nn int64
+		nn, err = inst.WriteTo(&buf)
 		if err != nil {
 			return
 		}
+
+		if err = checkAligned(CurrentCheckMode(), inst.Type(), nn); err != nil {
+			return
+		}
 	}
 
 	return encoding.WriteTo(w, buf.Bytes())
@@ -121,20 +139,45 @@ func (i *Instructions) WriteTo(w io.Writer) (n int64, err error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes the set
 // of actions from the wire format.
 func (i *Instructions) ReadFrom(r io.Reader) (n int64, err error) {
-	var instType InstructionType
 
-	rm := func() (io.ReaderFrom, error) {
-		if rm, ok := instructionMap[instType]; ok {
+	rm := func(instType InstructionType) (io.ReaderFrom, error) {
+		if rm, ok := instructionTable.Lookup(instType); ok {
 			rd, err := rm.MakeReader()
 			*i = append(*i, rd.(Instruction))
 			return rd, err
 		}
 
-		return nil, fmt.Errorf("ofp: unknown instruction type: %s", instType)
+		return nil, fmt.Errorf("ofp: unknown instruction type: %s: %w",
+			instType, Error{
+				Type: ErrTypeBadInstruction,
+				Code: ErrCodeBadInstructionUnknown,
+			})
+	}
+
+	return encoding.ScanFrom(r, rm)
+}
+
+// Clone returns a deep copy of the list of instructions.
+func (i Instructions) Clone() Instructions {
+	if i == nil {
+		return nil
+	}
+
+	instructions := make(Instructions, len(i))
+	for pos, inst := range i {
+		switch inst := inst.(type) {
+		case *InstructionApplyActions:
+			instructions[pos] = &InstructionApplyActions{
+				Actions: inst.Actions.Clone()}
+		case *InstructionWriteActions:
+			instructions[pos] = &InstructionWriteActions{
+				Actions: inst.Actions.Clone()}
+		default:
+			instructions[pos] = cloneValue(inst).(Instruction)
+		}
 	}
 
-	return encoding.ScanFrom(r, &instType,
-		encoding.ReaderMakerFunc(rm))
+	return instructions
 }
 
 // InstructionGotoTable represents a packet processing pipeline
@@ -236,7 +279,7 @@ func readInstructionActions(r io.Reader, actions *Actions) (int64, error) {
 
 	// Limit the reader to the size of actions, so we could know
 	// where is the a border of the message.
-	limrd := io.LimitReader(r, int64(header.Len-8))
+	limrd := encoding.LimitReader(r, int64(header.Len-8))
 	num, err = actions.ReadFrom(limrd)
 	read += num
 
@@ -312,7 +355,7 @@ func (i *InstructionClearActions) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReadFrom interface. It deserializes the
 // instruction used to clear actions from the wire format.
 func (i *InstructionClearActions) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad8)
+	return encoding.ReadFrom(r, &instruction{}, &defaultPad4)
 }
 
 // InstructionMeter is an instruction used to apply meter (rate
@@ -2,9 +2,13 @@ package ofp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"io/ioutil"
+	"sort"
+	"strings"
 
 	"github.com/netrack/openflow/internal/encoding"
 )
@@ -185,6 +189,122 @@ var xmTypeText = map[XMType]string{
 	XMTypeIPv6ExtHeader: "XMTypeIPv6ExtHeader",
 }
 
+// xmUnsettable lists the OpenFlow basic match fields that cannot be
+// modified using the set-field action.
+var xmUnsettable = map[XMType]bool{
+	XMTypeInPort:        true,
+	XMTypeInPhyPort:     true,
+	XMTypeMetadata:      true,
+	XMTypeIPv6ExtHeader: true,
+}
+
+// xmPrereq defines a prerequisite of the match field: the match must
+// contain a field of the given type with one of the listed values.
+// When the mask is specified, only masked bits are compared. Empty list
+// of values means the field has to be presented with any value.
+type xmPrereq struct {
+	Type   XMType
+	Values []XMValue
+	Mask   XMValue
+}
+
+var (
+	prereqIPv4 = xmPrereq{Type: XMTypeEthType,
+		Values: []XMValue{{0x08, 0x00}}}
+	prereqIPv6 = xmPrereq{Type: XMTypeEthType,
+		Values: []XMValue{{0x86, 0xdd}}}
+	prereqIP = xmPrereq{Type: XMTypeEthType,
+		Values: []XMValue{{0x08, 0x00}, {0x86, 0xdd}}}
+	prereqARP = xmPrereq{Type: XMTypeEthType,
+		Values: []XMValue{{0x08, 0x06}}}
+	prereqMPLS = xmPrereq{Type: XMTypeEthType,
+		Values: []XMValue{{0x88, 0x47}, {0x88, 0x48}}}
+	prereqPBB = xmPrereq{Type: XMTypeEthType,
+		Values: []XMValue{{0x88, 0xe7}}}
+	prereqTCP = xmPrereq{Type: XMTypeIPProto,
+		Values: []XMValue{{6}}}
+	prereqUDP = xmPrereq{Type: XMTypeIPProto,
+		Values: []XMValue{{17}}}
+	prereqSCTP = xmPrereq{Type: XMTypeIPProto,
+		Values: []XMValue{{132}}}
+	prereqICMPv4 = xmPrereq{Type: XMTypeIPProto,
+		Values: []XMValue{{1}}}
+	prereqICMPv6 = xmPrereq{Type: XMTypeIPProto,
+		Values: []XMValue{{58}}}
+)
+
+// xmPrereqs defines the prerequisites of the OpenFlow basic match
+// fields. Prerequisites of the prerequisite fields must be satisfied
+// as well.
+var xmPrereqs = map[XMType]xmPrereq{
+	XMTypeInPhyPort: {Type: XMTypeInPort},
+	XMTypeVlanPCP: {Type: XMTypeVlanID,
+		Values: []XMValue{{0x10, 0x00}}, Mask: XMValue{0x10, 0x00}},
+	XMTypeIPDSCP:        prereqIP,
+	XMTypeIPECN:         prereqIP,
+	XMTypeIPProto:       prereqIP,
+	XMTypeIPv4Src:       prereqIPv4,
+	XMTypeIPv4Dst:       prereqIPv4,
+	XMTypeTCPSrc:        prereqTCP,
+	XMTypeTCPDst:        prereqTCP,
+	XMTypeUDPSrc:        prereqUDP,
+	XMTypeUDPDst:        prereqUDP,
+	XMTypeSCTPSrc:       prereqSCTP,
+	XMTypeSCTPDst:       prereqSCTP,
+	XMTypeICMPv4Type:    prereqICMPv4,
+	XMTypeICMPv4Code:    prereqICMPv4,
+	XMTypeARPOpcode:     prereqARP,
+	XMTypeARPSPA:        prereqARP,
+	XMTypeARPTPA:        prereqARP,
+	XMTypeARPSHA:        prereqARP,
+	XMTypeARPTHA:        prereqARP,
+	XMTypeIPv6Src:       prereqIPv6,
+	XMTypeIPv6Dst:       prereqIPv6,
+	XMTypeIPv6FLabel:    prereqIPv6,
+	XMTypeICMPv6Type:    prereqICMPv6,
+	XMTypeICMPv6Code:    prereqICMPv6,
+	XMTypeIPv6NDTarget:  {Type: XMTypeICMPv6Type, Values: []XMValue{{135}, {136}}},
+	XMTypeIPv6NDSLL:     {Type: XMTypeICMPv6Type, Values: []XMValue{{135}}},
+	XMTypeIPv6NDTLL:     {Type: XMTypeICMPv6Type, Values: []XMValue{{136}}},
+	XMTypeMPLSLabel:     prereqMPLS,
+	XMTypeMPLSTC:        prereqMPLS,
+	XMTypeMPLSBOS:       prereqMPLS,
+	XMTypePBBISID:       prereqPBB,
+	XMTypeIPv6ExtHeader: prereqIPv6,
+}
+
+// satisfied reports whether the given field satisfies the prerequisite.
+func (p xmPrereq) satisfied(xm XM) bool {
+	if len(p.Values) == 0 {
+		return true
+	}
+
+	for _, value := range p.Values {
+		if len(value) != len(xm.Value) {
+			continue
+		}
+
+		matched := true
+		for i := range value {
+			mask := byte(0xff)
+			if p.Mask != nil {
+				mask = p.Mask[i]
+			}
+
+			if (value[i]^xm.Value[i])&mask != 0 {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
 // XMClass represents an OXM Class ID. The high order bit differentiate
 // reserved classes from member classes.
 //
@@ -281,6 +401,48 @@ const (
 	IPv6ExtensionHeaderUnseq
 )
 
+// IPv6ExtensionHeaderAll is a bitmask of all bits of the IPv6 extension
+// header pseudo-field.
+const IPv6ExtensionHeaderAll IPv6ExtensionHeader = 1<<9 - 1
+
+var ipv6ExtensionHeaderText = []struct {
+	mask IPv6ExtensionHeader
+	text string
+}{
+	{IPv6ExtensionHeaderNoNext, "nonext"},
+	{IPv6ExtensionHeaderESP, "esp"},
+	{IPv6ExtensionHeaderAuth, "auth"},
+	{IPv6ExtensionHeaderDest, "dest"},
+	{IPv6ExtensionHeaderFrag, "frag"},
+	{IPv6ExtensionHeaderRouter, "router"},
+	{IPv6ExtensionHeaderHop, "hop"},
+	{IPv6ExtensionHeaderUnrep, "unrep"},
+	{IPv6ExtensionHeaderUnseq, "unseq"},
+}
+
+// String returns a human-readable representation of the IPv6 extension
+// header bitmap. The bits outside of the pseudo-field are represented
+// with a hexadecimal number.
+func (h IPv6ExtensionHeader) String() string {
+	var headers []string
+
+	for _, header := range ipv6ExtensionHeaderText {
+		if header.mask&h != 0 {
+			headers = append(headers, header.text)
+		}
+	}
+
+	if rest := h &^ IPv6ExtensionHeaderAll; rest != 0 {
+		headers = append(headers, fmt.Sprintf("0x%x", uint16(rest)))
+	}
+
+	if len(headers) == 0 {
+		return "none"
+	}
+
+	return strings.Join(headers, "|")
+}
+
 const (
 	// xmlen defines the length of the extension match header, it does
 	// not include the value and mask.
@@ -327,10 +489,12 @@ func readAllXM(r io.Reader, xms *[]XM, hasPayload bool) (int64, error) {
 	}
 
 	rbuf := bytes.NewBuffer(buf)
+	rd := encoding.InheritReader(r, rbuf)
+
 	for rbuf.Len() >= xmlen {
 		var xm XM
 
-		_, err = xm.readFrom(rbuf, hasPayload)
+		_, err = xm.readFrom(rd, hasPayload)
 		if err != nil {
 			return n, err
 		}
@@ -341,12 +505,95 @@ func readAllXM(r io.Reader, xms *[]XM, hasPayload bool) (int64, error) {
 	return n, nil
 }
 
+// parseXM unmarshals the list of extensible matches from the buffer.
+// The values and masks of the matches share the memory of the buffer,
+// so decoding of the match does not allocate memory for each field.
+func parseXM(mode CheckMode, buf []byte, xms *[]XM) error {
+	// Count the matches to allocate the list at once.
+	var count int
+	for off := 0; len(buf)-off >= xmlen; count++ {
+		off += xmlen + int(buf[off+3])
+	}
+
+	if *xms == nil && count > 0 {
+		*xms = make([]XM, 0, count)
+	}
+
+	for len(buf) >= xmlen {
+		var xm XM
+
+		xm.Class = XMClass(binary.BigEndian.Uint16(buf))
+		xm.Type = XMType(buf[2] >> 1)
+		hasmask := buf[2]&1 == 1
+		length := buf[3]
+
+		if err := checkXMLen(mode, xm.Class, xm.Type, hasmask, length); err != nil {
+			return err
+		}
+
+		buf = buf[xmlen:]
+		if len(buf) < int(length) {
+			return io.ErrUnexpectedEOF
+		}
+
+		// Limit the capacity of the slices, so appending to the
+		// value does not overwrite the mask or the next match.
+		value := buf[:length:length]
+		buf = buf[length:]
+
+		if hasmask {
+			half := length / 2
+			xm.Value, xm.Mask = XMValue(value[:half:half]), XMValue(value[half:])
+		} else {
+			xm.Value = XMValue(value)
+		}
+
+		*xms = append(*xms, xm)
+	}
+
+	return nil
+}
+
+// checkXMLen validates the length of the registered match field in
+// strict mode. When the mask is presented, the length of the value is
+// doubled.
+func checkXMLen(mode CheckMode, class XMClass, t XMType, hasmask bool, length uint8) error {
+	if mode != CheckStrict {
+		return nil
+	}
+
+	f, ok := LookupXMField(class, t)
+	if !ok || f.Len <= 0 {
+		return nil
+	}
+
+	expected := f.Len
+	if hasmask {
+		expected *= 2
+	}
+
+	if int(length) != expected {
+		return fmt.Errorf("ofp: invalid length of the "+
+			"'%s' match field: %d: %w", f.Name, length,
+			Error{Type: ErrTypeBadMatch, Code: ErrCodeBadMatchBadLen})
+	}
+
+	return nil
+}
+
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the OpenFlow extensible match from the given reader.
 func (xm *XM) ReadFrom(r io.Reader) (n int64, err error) {
 	return xm.readFrom(r, true)
 }
 
+// Clone returns a deep copy of the extensible match.
+func (xm XM) Clone() XM {
+	xm.Value = XMValue(cloneBytes(xm.Value))
+	xm.Mask = XMValue(cloneBytes(xm.Mask))
+	return xm
+}
+
 // readFrom deserializes the OpenFlow extensible match from the
 // given reader.  If hasPayload is false, xm.Value and xm.Mask
 // will be filled with the zero value.
@@ -362,6 +609,10 @@ func (xm *XM) readFrom(r io.Reader, hasPayload bool) (n int64, err error) {
 	hasmask := (xm.Type & 1) == 1
 	xm.Type >>= 1
 
+	if err = checkXMLen(readerMode(r), xm.Class, xm.Type, hasmask, length); err != nil {
+		return n, err
+	}
+
 	xm.Value, xm.Mask = make(XMValue, length), nil
 
 	if hasPayload {
@@ -384,6 +635,27 @@ func (xm *XM) readFrom(r io.Reader, hasPayload bool) (n int64, err error) {
 	return
 }
 
+// String returns a human-readable representation of the extensible
+// match using the registered field description.
+func (xm XM) String() string {
+	f, ok := LookupXMField(xm.Class, xm.Type)
+	if !ok {
+		f.Name = fmt.Sprintf("%s:%d", xm.Class, xm.Type)
+	}
+
+	format := f.Format
+	if format == nil {
+		format = FormatXMHex
+	}
+
+	if xm.Mask == nil {
+		return fmt.Sprintf("%s=%s", f.Name, format(xm.Value))
+	}
+
+	return fmt.Sprintf("%s=%s/%s", f.Name,
+		format(xm.Value), format(xm.Mask))
+}
+
 // WriteTo implements io.WriterTo interface. It serializes the OpenFlow
 // extensible match into given writer.
 func (xm *XM) WriteTo(w io.Writer) (int64, error) {
@@ -400,6 +672,21 @@ func (xm *XM) WriteTo(w io.Writer) (int64, error) {
 		xm.Value, xm.Mask)
 }
 
+// writeHeader serializes only the header of the extensible match. The
+// length of the header is the length of the value and the mask, so the
+// headers decoded without payload are written back unchanged.
+func (xm *XM) writeHeader(w io.Writer) (int64, error) {
+	var hasmask XMType
+	if len(xm.Mask) > 0 {
+		hasmask = 1
+	}
+
+	field := (xm.Type << 1) | hasmask
+
+	return encoding.WriteTo(w, xm.Class, field,
+		uint8(len(xm.Mask)+len(xm.Value)))
+}
+
 // XMValue is a value of the extensible match.
 type XMValue []byte
 
@@ -459,37 +746,182 @@ func (m *Match) Field(mt XMType) *XM {
 	return nil
 }
 
+// prereqSatisfied reports whether the match satisfies prerequisites of
+// the field of the given type, including the prerequisites of the
+// prerequisite fields.
+func (m *Match) prereqSatisfied(mt XMType) bool {
+	for {
+		prereq, ok := xmPrereqs[mt]
+		if !ok {
+			return true
+		}
+
+		xm := m.Field(prereq.Type)
+		if xm == nil || !prereq.satisfied(*xm) {
+			return false
+		}
+
+		mt = prereq.Type
+	}
+}
+
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
-// match from the wire format.
-func (m *Match) ReadFrom(r io.Reader) (n int64, err error) {
-	var nn int64
-	var length uint16
+// match from the wire format. The values and masks of the decoded
+// fields share a single buffer, use Clone to retain a single field
+// without the rest of the match.
+func (m *Match) ReadFrom(r io.Reader) (int64, error) {
+	var header [4]byte
 
 	// Initialize the structure attributes with default
 	// values, so we could read multiple times into the
 	// same variable.
 	m.Type, m.Fields = 0, nil
 
-	n, err = encoding.ReadFrom(r, &m.Type, &length)
+	n, err := io.ReadFull(r, header[:])
 	if err != nil {
-		return
+		return int64(n), err
 	}
 
-	matchlen := int(length)
+	m.Type = MatchType(binary.BigEndian.Uint16(header[:]))
+	matchlen := int(binary.BigEndian.Uint16(header[2:]))
+
+	// Subtract the length of the already-read Type & Length fields.
+	rdlen := matchlen - len(header)
+	if rdlen < 0 {
+		rdlen = 0
+	}
 
-	// subtract the length of the already-read Type & Length fields
-	rdlen := matchlen - 4
+	// Read the extensible matches and the padding after them into
+	// the buffer of the exact size, the values of the fields are
+	// sliced from it.
+	buf := make([]byte, rdlen+padLen(matchlen))
 
-	// Limit the reader to the length of the extensible matches.
-	limrd := io.LimitReader(r, int64(rdlen))
-	nn, err = readAllXM(limrd, &m.Fields, true)
-	if n += nn; err != nil {
-		return
+	nn, err := io.ReadFull(r, buf)
+	if n += nn; err == io.EOF {
+		return int64(n), io.ErrUnexpectedEOF
+	} else if err != nil {
+		return int64(n), err
+	}
+
+	mode := readerMode(r)
+	if err = checkPad(mode, buf[rdlen:]); err != nil {
+		return int64(n), err
+	}
+
+	return int64(n), parseXM(mode, buf[:rdlen], &m.Fields)
+}
+
+// Clone returns a deep copy of the match.
+func (m Match) Clone() Match {
+	if m.Fields == nil {
+		return m
+	}
+
+	fields := make([]XM, len(m.Fields))
+	for i, xm := range m.Fields {
+		fields[i] = xm.Clone()
 	}
 
-	// Read the padding after the list of extensible matches.
-	nn, err = encoding.ReadFrom(r, makePad(matchlen))
-	return n + nn, err
+	m.Fields = fields
+	return m
+}
+
+// xmLess reports whether the field a precedes the field b in the
+// canonical order of the match fields. The fields of the OpenFlow basic
+// class precede the others and are ordered by type, which places the
+// prerequisites before the fields depending on them.
+func xmLess(a, b XM) bool {
+	basicA := a.Class == XMClassOpenflowBasic
+	basicB := b.Class == XMClassOpenflowBasic
+
+	switch {
+	case basicA != basicB:
+		return basicA
+	case a.Class != b.Class:
+		return a.Class < b.Class
+	}
+
+	return a.Type < b.Type
+}
+
+// Canonicalize converts the match into the canonical form, so the
+// matches selecting the same packets have the same representation.
+//
+// The fields are sorted in the prerequisite order, the all-ones masks
+// are removed and the bits of the value not covered by the mask are
+// cleared. Duplicate fields and all-zero masks are rejected with the
+// Error of ErrTypeBadMatch type.
+func (m *Match) Canonicalize() error {
+	fields := make([]XM, len(m.Fields))
+	copy(fields, m.Fields)
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return xmLess(fields[i], fields[j])
+	})
+
+	for i := range fields {
+		xm := &fields[i]
+
+		if i > 0 && xm.Class == fields[i-1].Class && xm.Type == fields[i-1].Type {
+			return Error{Type: ErrTypeBadMatch, Code: ErrCodeBadMatchDupField}
+		}
+
+		if len(xm.Mask) == 0 {
+			xm.Mask = nil
+			continue
+		}
+
+		if len(xm.Mask) != len(xm.Value) {
+			return Error{Type: ErrTypeBadMatch, Code: ErrCodeBadMatchBadMask}
+		}
+
+		ones, zeros := true, true
+		for _, b := range xm.Mask {
+			ones = ones && b == 0xff
+			zeros = zeros && b == 0
+		}
+
+		switch {
+		case zeros:
+			return Error{Type: ErrTypeBadMatch, Code: ErrCodeBadMatchBadMask}
+		case ones:
+			xm.Mask = nil
+			continue
+		}
+
+		// Clear the bits of the value that are not matched, so
+		// the equal matches have the same values.
+		value := make(XMValue, len(xm.Value))
+		for j := range value {
+			value[j] = xm.Value[j] & xm.Mask[j]
+		}
+
+		xm.Value = value
+	}
+
+	m.Fields = fields
+	return nil
+}
+
+// Key returns a string uniquely identifying the match, so it could be
+// used as a map key. The matches must be canonicalized with Canonicalize
+// method in order to return equal keys for equivalent matches.
+func (m Match) Key() string {
+	var buf bytes.Buffer
+	encoding.WriteTo(&buf, m.Type)
+
+	for _, xm := range m.Fields {
+		xm.WriteTo(&buf)
+	}
+
+	return buf.String()
+}
+
+// Hash returns a 64-bit FNV-1a hash of the match key.
+func (m Match) Hash() uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, m.Key())
+	return h.Sum64()
 }
 
 // WriteTo implements io.WriterTo interface. It serializes the match
@@ -508,6 +940,11 @@ func (m *Match) WriteTo(w io.Writer) (n int64, err error) {
 	length := buf.Len() + 4
 	padding := makePad(length)
 
+	err = checkAligned(CurrentCheckMode(), m.Type, int64(length+len(padding)))
+	if err != nil {
+		return
+	}
+
 	return encoding.WriteTo(
 		w, m.Type, uint16(length), buf.Bytes(), padding)
 }
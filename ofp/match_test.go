@@ -1,6 +1,9 @@
 package ofp
 
 import (
+	"bytes"
+	"io"
+	"reflect"
 	"testing"
 
 	"github.com/netrack/openflow/internal/encodingtest"
@@ -43,6 +46,41 @@ func TestMatch(t *testing.T) {
 	encodingtest.RunMU(t, tests)
 }
 
+func TestMatchSharedBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	benchMatch.WriteTo(&buf)
+
+	var m Match
+	if _, err := m.ReadFrom(&buf); err != nil {
+		t.Fatalf("Failed to decode match: %s", err)
+	}
+
+	if !reflect.DeepEqual(m, benchMatch) {
+		t.Fatalf("Invalid match decoded: %v", m)
+	}
+
+	// The fields share the buffer, appending to the value must not
+	// overwrite the mask or the next field.
+	_ = append(m.Fields[0].Value, 0xff)
+	_ = append(m.Fields[2].Value, 0x00)
+
+	if !reflect.DeepEqual(m, benchMatch) {
+		t.Fatalf("Match fields overwritten: %v", m)
+	}
+
+	// The length of the field exceeds the length of the match.
+	truncated := []byte{
+		0x00, 0x01, 0x00, 0x0c,
+		0x80, 0x00, 0x00, 0x08,
+		0x00, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x00,
+	}
+
+	if _, err := m.ReadFrom(bytes.NewReader(truncated)); err != io.ErrUnexpectedEOF {
+		t.Errorf("Unexpected end of file error expected: %v", err)
+	}
+}
+
 func TestXMValue(t *testing.T) {
 	value := XMValue{0xef}
 	if value.UInt8() != 0xef {
@@ -59,3 +97,90 @@ func TestXMValue(t *testing.T) {
 		t.Fatal("Failed to return right uin32 value:", value.UInt32())
 	}
 }
+
+func TestMatchCanonicalize(t *testing.T) {
+	m1 := Match{MatchTypeXM, []XM{
+		{Class: XMClassOpenflowBasic, Type: XMTypeIPv4Dst,
+			Value: XMValue{10, 0, 0, 1}, Mask: XMValue{255, 255, 255, 0}},
+		{Class: XMClassNicira1, Type: 0, Value: XMValue{0, 0, 0, 1}},
+		{Class: XMClassOpenflowBasic, Type: XMTypeEthType,
+			Value: XMValue{0x08, 0x00}, Mask: XMValue{0xff, 0xff}},
+	}}
+
+	m2 := Match{MatchTypeXM, []XM{
+		{Class: XMClassOpenflowBasic, Type: XMTypeEthType,
+			Value: XMValue{0x08, 0x00}},
+		{Class: XMClassNicira1, Type: 0, Value: XMValue{0, 0, 0, 1}},
+		{Class: XMClassOpenflowBasic, Type: XMTypeIPv4Dst,
+			Value: XMValue{10, 0, 0, 0}, Mask: XMValue{255, 255, 255, 0}},
+	}}
+
+	if m1.Key() == m2.Key() {
+		t.Fatalf("Keys of different representations must differ")
+	}
+
+	for _, m := range []*Match{&m1, &m2} {
+		if err := m.Canonicalize(); err != nil {
+			t.Fatalf("Failed to canonicalize match: %s", err)
+		}
+	}
+
+	if m1.Key() != m2.Key() || m1.Hash() != m2.Hash() {
+		t.Fatalf("Keys of equivalent matches must be equal")
+	}
+
+	types := []XMType{XMTypeEthType, XMTypeIPv4Dst, 0}
+	for i, xm := range m1.Fields {
+		if xm.Type != types[i] {
+			t.Errorf("Invalid order of fields: %v", m1.Fields)
+		}
+	}
+
+	if m1.Fields[0].Mask != nil {
+		t.Errorf("All-ones mask must be removed: %v", m1.Fields[0])
+	}
+}
+
+func TestMatchCanonicalizeErrors(t *testing.T) {
+	tests := []struct {
+		Match Match
+		Code  ErrCode
+	}{
+		{Match{MatchTypeXM, []XM{
+			{Class: XMClassOpenflowBasic, Type: XMTypeEthType,
+				Value: XMValue{0x08, 0x00}, Mask: XMValue{0x00, 0x00}},
+		}}, ErrCodeBadMatchBadMask},
+		{Match{MatchTypeXM, []XM{
+			{Class: XMClassOpenflowBasic, Type: XMTypeInPort,
+				Value: XMValue{0, 0, 0, 1}},
+			{Class: XMClassOpenflowBasic, Type: XMTypeInPort,
+				Value: XMValue{0, 0, 0, 2}},
+		}}, ErrCodeBadMatchDupField},
+	}
+
+	for _, test := range tests {
+		err, ok := test.Match.Canonicalize().(Error)
+		if !ok || err.Type != ErrTypeBadMatch || err.Code != test.Code {
+			t.Errorf("Expected error code %d, got %v", test.Code, err)
+		}
+	}
+}
+
+func TestIPv6ExtensionHeaderString(t *testing.T) {
+	tests := []struct {
+		Header IPv6ExtensionHeader
+		Text   string
+	}{
+		{0, "none"},
+		{IPv6ExtensionHeaderNoNext, "nonext"},
+		{IPv6ExtensionHeaderESP | IPv6ExtensionHeaderUnseq, "esp|unseq"},
+		{IPv6ExtensionHeaderAuth | 0x8000, "auth|0x8000"},
+	}
+
+	for _, test := range tests {
+		if text := test.Header.String(); text != test.Text {
+			t.Errorf("Invalid extension header representation, "+
+				"expected %s got %s", test.Text, text)
+		}
+	}
+}
@@ -74,9 +74,9 @@ const (
 )
 
 var meterBandMap = map[MeterBandType]encoding.ReaderMaker{
-	MeterBandTypeDrop:         encoding.ReaderMakerOf(MeterBandDrop{}),
-	MeterBandTypeDSCPRemark:   encoding.ReaderMakerOf(MeterBandDSCPRemark{}),
-	MeterBandTypeExperimenter: encoding.ReaderMakerOf(MeterBandExperimenter{}),
+	MeterBandTypeDrop:         encoding.ReaderMakerOf[MeterBandDrop](),
+	MeterBandTypeDSCPRemark:   encoding.ReaderMakerOf[MeterBandDSCPRemark](),
+	MeterBandTypeExperimenter: encoding.ReaderMakerOf[MeterBandExperimenter](),
 }
 
 // meterBand is a header of meter band. It holds the type of the meter
@@ -119,13 +119,12 @@ func (m MeterBands) WriteTo(w io.Writer) (int64, error) {
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes
 // the list of meter bands from the wire format.
-func (m MeterBands) ReadFrom(r io.Reader) (int64, error) {
-	var meterBandType MeterBandType
+func (m *MeterBands) ReadFrom(r io.Reader) (int64, error) {
 
-	rm := func() (io.ReaderFrom, error) {
+	rm := func(meterBandType MeterBandType) (io.ReaderFrom, error) {
 		if rm, ok := meterBandMap[meterBandType]; ok {
 			rd, err := rm.MakeReader()
-			m = append(m, rd.(MeterBand))
+			*m = append(*m, rd.(MeterBand))
 			return rd, err
 		}
 
@@ -133,8 +132,7 @@ func (m MeterBands) ReadFrom(r io.Reader) (int64, error) {
 		return nil, fmt.Errorf(format, meterBandType)
 	}
 
-	return encoding.ScanFrom(r, &meterBandType,
-		encoding.ReaderMakerFunc(rm))
+	return encoding.ScanFrom(r, rm)
 }
 
 // MeterBandDrop defines a simple rate limiter that drops packets that
@@ -275,13 +273,30 @@ type MeterMod struct {
 // WriteTo implements io.WriterTo interface. It serializes the meter
 // modification message into the wire format.
 func (m *MeterMod) WriteTo(w io.Writer) (int64, error) {
+	if err := m.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
 	return encoding.WriteTo(w, m.Command, m.Flags, m.Meter, m.Bands)
 }
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // meter modfiication message from the wire format.
 func (m *MeterMod) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &m.Command, &m.Flags, &m.Meter, m.Bands)
+	m.Bands = nil
+
+	n, err := encoding.ReadFrom(r, &m.Command, &m.Flags, &m.Meter, &m.Bands)
+	if err != nil {
+		return n, err
+	}
+
+	return n, m.checkReserved(readerMode(r))
+}
+
+// checkReserved validates the meter modification command in strict mode.
+func (m *MeterMod) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "meter mod command",
+		m.Command <= MeterDelete, m.Command)
 }
 
 // MeterConfigRequest is a multipart request used to retrieve
@@ -348,7 +363,9 @@ func (m *MeterConfig) ReadFrom(r io.Reader) (int64, error) {
 	}
 
 	// Use the rest of bytes to decode the bands.
-	limrd := io.LimitReader(r, int64(length-meterConfigLen))
+	limrd := encoding.LimitReader(r, int64(length-meterConfigLen))
+	m.Bands = nil
+
 	nn, err := m.Bands.ReadFrom(limrd)
 	return n + nn, err
 }
@@ -493,9 +510,13 @@ func (m *MeterStats) ReadFrom(r io.Reader) (int64, error) {
 		&defaultPad6, &m.FlowCount, &m.PacketInCount,
 		&m.ByteInCount, &m.DurationSec, &m.DurationNSec)
 
-	limrd := io.LimitReader(r, int64(length-meterStatsLen))
-	statsMaker := encoding.ReaderMakerOf(MeterBandStats{})
+	if err != nil {
+		return n, err
+	}
+
+	limrd := encoding.LimitReader(r, int64(length-meterStatsLen))
+	m.BandStats = nil
 
-	nn, err := encoding.ReadSliceFrom(limrd, statsMaker, m.BandStats)
+	nn, err := encoding.ReadSliceFrom(limrd, &m.BandStats)
 	return n + nn, err
 }
@@ -1,7 +1,9 @@
 package ofp
 
 import (
+	"bytes"
 	"encoding/gob"
+	"reflect"
 	"testing"
 
 	"github.com/netrack/openflow/internal/encodingtest"
@@ -149,4 +151,23 @@ func TestMeterStats(t *testing.T) {
 	}
 
 	encodingtest.RunMU(t, tests)
+
+	// Decode the statistics into the empty value, so the list
+	// of band statistics must be populated from the bytes.
+	for _, test := range tests {
+		var m MeterStats
+
+		// Decode the same bytes twice to ensure the band
+		// statistics are not accumulated on reuse.
+		for i := 0; i < 2; i++ {
+			_, err := m.ReadFrom(bytes.NewReader(test.Bytes))
+			if err != nil {
+				t.Fatalf("Failed to decode meter stats: %s", err)
+			}
+		}
+
+		if !reflect.DeepEqual(m.BandStats, stats) {
+			t.Fatalf("Invalid band statistics decoded: %v", m.BandStats)
+		}
+	}
 }
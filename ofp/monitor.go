@@ -0,0 +1,449 @@
+package ofp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/netrack/openflow/internal/encoding"
+)
+
+// FlowMonitorCommand represents a command of the flow monitor request.
+// Flow monitors are defined starting from OpenFlow 1.4.
+type FlowMonitorCommand uint8
+
+const (
+	// FlowMonitorAdd is a command used to add a new flow monitor.
+	FlowMonitorAdd FlowMonitorCommand = iota
+
+	// FlowMonitorModify is a command used to modify an existing flow
+	// monitor.
+	FlowMonitorModify
+
+	// FlowMonitorDelete is a command used to delete an existing flow
+	// monitor.
+	FlowMonitorDelete
+)
+
+func (c FlowMonitorCommand) String() string {
+	text, ok := flowMonitorCommandText[c]
+	if !ok {
+		return fmt.Sprintf("FlowMonitorCommand(%d)", c)
+	}
+	return text
+}
+
+var flowMonitorCommandText = map[FlowMonitorCommand]string{
+	FlowMonitorAdd:    "FlowMonitorAdd",
+	FlowMonitorModify: "FlowMonitorModify",
+	FlowMonitorDelete: "FlowMonitorDelete",
+}
+
+// FlowMonitorFlag defines the flags of the flow monitor request.
+type FlowMonitorFlag uint16
+
+const (
+	// FlowMonitorFlagInitial instructs the switch to send the initial
+	// flow table contents matching the monitor.
+	FlowMonitorFlagInitial FlowMonitorFlag = 1 << iota
+
+	// FlowMonitorFlagAdd instructs the switch to send the updates for
+	// the added flow entries.
+	FlowMonitorFlagAdd
+
+	// FlowMonitorFlagRemoved instructs the switch to send the updates
+	// for the removed flow entries.
+	FlowMonitorFlagRemoved
+
+	// FlowMonitorFlagModify instructs the switch to send the updates
+	// for the modified flow entries.
+	FlowMonitorFlagModify
+
+	// FlowMonitorFlagInstructions instructs the switch to include the
+	// instructions of the flow entries into the updates.
+	FlowMonitorFlagInstructions
+
+	// FlowMonitorFlagNoAbbrev instructs the switch to send the full
+	// updates for the changes made by the controller itself, instead
+	// of the abbreviated ones.
+	FlowMonitorFlagNoAbbrev
+
+	// FlowMonitorFlagOnlyOwn instructs the switch to send the updates
+	// only for the changes made by the controller itself.
+	FlowMonitorFlagOnlyOwn
+)
+
+// FlowMonitorRequest is a multipart request used to subscribe to the
+// changes of the flow tables, so the controller is notified about the
+// flow entries added, modified and removed by the other controllers or
+// by the switch itself.
+//
+// The switch replies with the initial contents of the flow tables (when
+// requested) and then sends the updates asynchronously in the multipart
+// replies of MultipartTypeFlowMonitor type, see FlowUpdates.
+//
+// For example, to monitor the changes of the first table, the following
+// request could be sent:
+//
+//	body := &ofp.FlowMonitorRequest{
+//		Monitor:  1,
+//		OutPort:  ofp.PortAny,
+//		OutGroup: ofp.GroupAny,
+//		Flags:    ofp.FlowMonitorFlagAdd | ofp.FlowMonitorFlagRemoved,
+//		Table:    0,
+//		Command:  ofp.FlowMonitorAdd,
+//	}
+//
+//	req := ofp.NewMultipartRequest(
+//		ofp.MultipartTypeFlowMonitor, body)
+type FlowMonitorRequest struct {
+	// Monitor is a controller-assigned identifier of the monitor.
+	Monitor uint32
+
+	// Require matching entries to include this as an output port.
+	// A value PortAny indicates no restrictions.
+	OutPort PortNo
+
+	// Require matching entries to include this as an output group.
+	// A value GroupAny indicates no restrictions.
+	OutGroup Group
+
+	// Flags of the flow monitor.
+	Flags FlowMonitorFlag
+
+	// Table is an identifier of the table to monitor or TableAll to
+	// monitor all tables of the datapath.
+	Table Table
+
+	// Command specifies the flow monitor command.
+	Command FlowMonitorCommand
+
+	// Fields to match.
+	Match Match
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the flow
+// monitor request into the wire format.
+func (f *FlowMonitorRequest) WriteTo(w io.Writer) (int64, error) {
+	if err := f.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
+	return encoding.WriteTo(w, f.Monitor, f.OutPort, f.OutGroup,
+		f.Flags, f.Table, f.Command, &f.Match)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the flow
+// monitor request from the wire format.
+func (f *FlowMonitorRequest) ReadFrom(r io.Reader) (int64, error) {
+	n, err := encoding.ReadFrom(r, &f.Monitor, &f.OutPort, &f.OutGroup,
+		&f.Flags, &f.Table, &f.Command, &f.Match)
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, f.checkReserved(readerMode(r))
+}
+
+// Clone returns a deep copy of the flow monitor request.
+func (f *FlowMonitorRequest) Clone() *FlowMonitorRequest {
+	clone := *f
+	clone.Match = f.Match.Clone()
+	return &clone
+}
+
+// checkReserved validates the flow monitor command in strict mode.
+func (f *FlowMonitorRequest) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "flow monitor command",
+		f.Command <= FlowMonitorDelete, f.Command)
+}
+
+// FlowUpdateEvent represents a type of the flow update sent by the
+// switch to the flow monitor.
+type FlowUpdateEvent uint16
+
+const (
+	// FlowUpdateEventInitial is set for the flow entries existing in
+	// the flow table at the moment of the monitor creation.
+	FlowUpdateEventInitial FlowUpdateEvent = iota
+
+	// FlowUpdateEventAdded is set when the new flow entry was added.
+	FlowUpdateEventAdded
+
+	// FlowUpdateEventRemoved is set when the flow entry was removed.
+	FlowUpdateEventRemoved
+
+	// FlowUpdateEventModified is set when the flow entry was modified.
+	FlowUpdateEventModified
+
+	// FlowUpdateEventAbbrev is set for the abbreviated update of the
+	// change made by the controller itself.
+	FlowUpdateEventAbbrev
+
+	// FlowUpdateEventPaused is set when the monitoring was paused,
+	// because the control connection is congested.
+	FlowUpdateEventPaused
+
+	// FlowUpdateEventResumed is set when the monitoring was resumed.
+	FlowUpdateEventResumed
+)
+
+func (e FlowUpdateEvent) String() string {
+	text, ok := flowUpdateEventText[e]
+	if !ok {
+		return fmt.Sprintf("FlowUpdateEvent(%d)", e)
+	}
+	return text
+}
+
+var flowUpdateEventText = map[FlowUpdateEvent]string{
+	FlowUpdateEventInitial:  "FlowUpdateEventInitial",
+	FlowUpdateEventAdded:    "FlowUpdateEventAdded",
+	FlowUpdateEventRemoved:  "FlowUpdateEventRemoved",
+	FlowUpdateEventModified: "FlowUpdateEventModified",
+	FlowUpdateEventAbbrev:   "FlowUpdateEventAbbrev",
+	FlowUpdateEventPaused:   "FlowUpdateEventPaused",
+	FlowUpdateEventResumed:  "FlowUpdateEventResumed",
+}
+
+var flowUpdateMap = map[FlowUpdateEvent]encoding.ReaderMaker{
+	FlowUpdateEventInitial:  encoding.ReaderMakerOf[FlowUpdateFull](),
+	FlowUpdateEventAdded:    encoding.ReaderMakerOf[FlowUpdateFull](),
+	FlowUpdateEventRemoved:  encoding.ReaderMakerOf[FlowUpdateFull](),
+	FlowUpdateEventModified: encoding.ReaderMakerOf[FlowUpdateFull](),
+	FlowUpdateEventAbbrev:   encoding.ReaderMakerOf[FlowUpdateAbbrev](),
+	FlowUpdateEventPaused:   encoding.ReaderMakerOf[FlowUpdatePaused](),
+	FlowUpdateEventResumed:  encoding.ReaderMakerOf[FlowUpdatePaused](),
+}
+
+// flowUpdateLen is a length of the abbreviated and paused flow updates.
+const flowUpdateLen = 8
+
+// flowUpdateHeader is a header preceding each flow update.
+type flowUpdateHeader struct {
+	Length uint16
+	Event  FlowUpdateEvent
+}
+
+// FlowUpdate is an interface representing a single flow update sent
+// within the flow monitor multipart reply.
+type FlowUpdate interface {
+	encoding.ReadWriter
+
+	// Event returns the type of the flow update.
+	Event() FlowUpdateEvent
+}
+
+// FlowUpdates is a list of flow updates sent within the multipart
+// reply of MultipartTypeFlowMonitor type.
+//
+// For example, to decode the updates from the multipart reply, the
+// following code could be used:
+//
+//	var reply ofp.MultipartReply
+//	reply.ReadFrom(r.Body)
+//
+//	var updates ofp.FlowUpdates
+//	updates.ReadFrom(r.Body)
+//
+//	for _, update := range updates {
+//		switch update := update.(type) {
+//		case *ofp.FlowUpdateFull:
+//			// ...
+//		case *ofp.FlowUpdatePaused:
+//			// ...
+//		}
+//	}
+type FlowUpdates []FlowUpdate
+
+// WriteTo implements io.WriterTo interface. It serializes the list of
+// flow updates into the wire format.
+func (f FlowUpdates) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+
+	for _, update := range f {
+		nn, err := update.WriteTo(w)
+		n += nn
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the list
+// of flow updates from the wire format.
+func (f *FlowUpdates) ReadFrom(r io.Reader) (int64, error) {
+
+	rm := func(header flowUpdateHeader) (io.ReaderFrom, error) {
+		if rm, ok := flowUpdateMap[header.Event]; ok {
+			rd, err := rm.MakeReader()
+			*f = append(*f, rd.(FlowUpdate))
+			return rd, err
+		}
+
+		return nil, fmt.Errorf("ofp: unknown flow update event: %s",
+			header.Event)
+	}
+
+	return encoding.ScanFrom(r, rm)
+}
+
+// FlowUpdateFull is a full description of the flow entry sent for the
+// initial, added, removed and modified flow updates.
+type FlowUpdateFull struct {
+	// EventType is one of the FlowUpdateEventInitial,
+	// FlowUpdateEventAdded, FlowUpdateEventRemoved and
+	// FlowUpdateEventModified.
+	EventType FlowUpdateEvent
+
+	// Table is an identifier of the table.
+	Table Table
+
+	// Reason of the flow entry removal, it is set only for the
+	// FlowUpdateEventRemoved updates.
+	Reason FlowRemovedReason
+
+	// IdleTimeout is a number of seconds idle before expiration.
+	IdleTimeout uint16
+
+	// HardTimeout is a number of seconds before expiration.
+	HardTimeout uint16
+
+	// Priority of the entry.
+	Priority uint16
+
+	// Opaque controller-issued identifier.
+	Cookie uint64
+
+	// Fields to match.
+	Match Match
+
+	// The set of instructions associated with a flow entry, it is
+	// set only when the monitor was created with the instructions
+	// flag.
+	Instructions Instructions
+}
+
+// Event implements FlowUpdate interface. It returns the type of the
+// flow update.
+func (f *FlowUpdateFull) Event() FlowUpdateEvent {
+	return f.EventType
+}
+
+// Cookies implements openflow.CookieJar interface. It returns the
+// cookie of the updated flow entry.
+func (f *FlowUpdateFull) Cookies() uint64 {
+	return f.Cookie
+}
+
+// SetCookies implements openflow.CookieJar interface. It sets the
+// cookie of the updated flow entry.
+func (f *FlowUpdateFull) SetCookies(cookies uint64) {
+	f.Cookie = cookies
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the full flow
+// update into the wire format.
+func (f *FlowUpdateFull) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	_, err := encoding.WriteTo(&buf, f.EventType, f.Table, f.Reason,
+		f.IdleTimeout, f.HardTimeout, f.Priority, pad4{}, f.Cookie,
+		&f.Match, &f.Instructions)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return encoding.WriteTo(w, uint16(buf.Len()+2), buf.Bytes())
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the full
+// flow update from the wire format.
+func (f *FlowUpdateFull) ReadFrom(r io.Reader) (int64, error) {
+	var length uint16
+
+	n, err := encoding.ReadFrom(r, &length, &f.EventType, &f.Table,
+		&f.Reason, &f.IdleTimeout, &f.HardTimeout, &f.Priority,
+		&defaultPad4, &f.Cookie, &f.Match)
+
+	if err != nil {
+		return n, err
+	}
+
+	limrd := encoding.LimitReader(r, int64(length)-n)
+	f.Instructions = nil
+
+	nn, err := f.Instructions.ReadFrom(limrd)
+	return n + nn, err
+}
+
+// Clone returns a deep copy of the full flow update.
+func (f *FlowUpdateFull) Clone() *FlowUpdateFull {
+	clone := *f
+	clone.Match = f.Match.Clone()
+	clone.Instructions = f.Instructions.Clone()
+	return &clone
+}
+
+// FlowUpdateAbbrev is an abbreviated flow update sent for the changes
+// made by the controller itself.
+type FlowUpdateAbbrev struct {
+	// Transaction is an identifier of the controller's request that
+	// caused the flow table change.
+	Transaction uint32
+}
+
+// Event implements FlowUpdate interface. It returns the type of the
+// flow update.
+func (f *FlowUpdateAbbrev) Event() FlowUpdateEvent {
+	return FlowUpdateEventAbbrev
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the
+// abbreviated flow update into the wire format.
+func (f *FlowUpdateAbbrev) WriteTo(w io.Writer) (int64, error) {
+	header := flowUpdateHeader{flowUpdateLen, f.Event()}
+	return encoding.WriteTo(w, header, f.Transaction)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// abbreviated flow update from the wire format.
+func (f *FlowUpdateAbbrev) ReadFrom(r io.Reader) (int64, error) {
+	return encoding.ReadFrom(r, &flowUpdateHeader{}, &f.Transaction)
+}
+
+// FlowUpdatePaused is a flow update sent when the monitoring is paused
+// or resumed.
+type FlowUpdatePaused struct {
+	// EventType is either FlowUpdateEventPaused or
+	// FlowUpdateEventResumed.
+	EventType FlowUpdateEvent
+}
+
+// Event implements FlowUpdate interface. It returns the type of the
+// flow update.
+func (f *FlowUpdatePaused) Event() FlowUpdateEvent {
+	return f.EventType
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the paused
+// flow update into the wire format.
+func (f *FlowUpdatePaused) WriteTo(w io.Writer) (int64, error) {
+	header := flowUpdateHeader{flowUpdateLen, f.EventType}
+	return encoding.WriteTo(w, header, pad4{})
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// paused flow update from the wire format.
+func (f *FlowUpdatePaused) ReadFrom(r io.Reader) (int64, error) {
+	var header flowUpdateHeader
+
+	n, err := encoding.ReadFrom(r, &header, &defaultPad4)
+	f.EventType = header.Event
+	return n, err
+}
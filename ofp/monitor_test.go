@@ -0,0 +1,124 @@
+package ofp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/netrack/openflow/internal/encodingtest"
+)
+
+func TestFlowMonitorRequest(t *testing.T) {
+	match := Match{MatchTypeXM, []XM{{
+		Class: XMClassOpenflowBasic,
+		Type:  XMTypeInPort,
+		Value: XMValue{0x00, 0x00, 0x00, 0x02},
+	}}}
+
+	tests := []encodingtest.MU{
+		{ReadWriter: &FlowMonitorRequest{
+			Monitor:  42,
+			OutPort:  PortAny,
+			OutGroup: GroupAny,
+			Flags:    FlowMonitorFlagInitial | FlowMonitorFlagRemoved,
+			Table:    Table(3),
+			Command:  FlowMonitorModify,
+			Match:    match,
+		}, Bytes: []byte{
+			0x00, 0x00, 0x00, 0x2a, // Monitor identifier.
+			0xff, 0xff, 0xff, 0xff, // Out port.
+			0xff, 0xff, 0xff, 0xff, // Out group.
+			0x00, 0x05, // Flags.
+			0x03, // Table identifier.
+			0x01, // Command.
+
+			// Match.
+			0x00, 0x01, // Match type.
+			0x00, 0x0c, // Match length.
+			0x80, 0x00, // OpenFlow basic.
+			0x00,                   // Match field + Mask flag.
+			0x04,                   // Payload length.
+			0x00, 0x00, 0x00, 0x02, // Payload.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+		}},
+	}
+
+	encodingtest.RunMU(t, tests)
+}
+
+func TestFlowUpdates(t *testing.T) {
+	match := Match{MatchTypeXM, []XM{{
+		Class: XMClassOpenflowBasic,
+		Type:  XMTypeInPort,
+		Value: XMValue{0x00, 0x00, 0x00, 0x03},
+	}}}
+
+	updates := FlowUpdates{
+		&FlowUpdateFull{
+			EventType:    FlowUpdateEventRemoved,
+			Table:        Table(1),
+			Reason:       FlowReasonHardTimeout,
+			IdleTimeout:  10,
+			HardTimeout:  20,
+			Priority:     30,
+			Cookie:       0xf22884334a8def04,
+			Match:        match,
+			Instructions: Instructions{&InstructionClearActions{}},
+		},
+		&FlowUpdateAbbrev{Transaction: 0x01020304},
+		&FlowUpdatePaused{EventType: FlowUpdateEventResumed},
+	}
+
+	data := []byte{
+		// Full flow update.
+		0x00, 0x30, // Length.
+		0x00, 0x02, // Event.
+		0x01,       // Table identifier.
+		0x01,       // Reason.
+		0x00, 0x0a, // IDLE timeout.
+		0x00, 0x14, // Hard timeout.
+		0x00, 0x1e, // Priority.
+		0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+		0xf2, 0x28, 0x84, 0x33, 0x4a, 0x8d, 0xef, 0x04, // Cookie.
+
+		// Match.
+		0x00, 0x01, // Match type.
+		0x00, 0x0c, // Match length.
+		0x80, 0x00, // OpenFlow basic.
+		0x00,                   // Match field + Mask flag.
+		0x04,                   // Payload length.
+		0x00, 0x00, 0x00, 0x03, // Payload.
+		0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+
+		// Instructions.
+		0x00, 0x05, // Instruction type.
+		0x00, 0x08, // Intruction length.
+		0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+
+		// Abbreviated flow update.
+		0x00, 0x08, // Length.
+		0x00, 0x04, // Event.
+		0x01, 0x02, 0x03, 0x04, // Transaction.
+
+		// Resumed flow update.
+		0x00, 0x08, // Length.
+		0x00, 0x06, // Event.
+		0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+	}
+
+	encodingtest.RunM(t, []encodingtest.M{{Writer: updates, Bytes: data}})
+
+	var decoded FlowUpdates
+	n, err := decoded.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to unmarshal flow updates: %s", err)
+	}
+
+	if n != int64(len(data)) {
+		t.Fatalf("Invalid length of unmarshaled flow updates: %d", n)
+	}
+
+	if !reflect.DeepEqual(decoded, updates) {
+		t.Fatalf("Invalid flow updates unmarshaled: %v", decoded)
+	}
+}
@@ -100,6 +100,20 @@ const (
 	// The request body is empty. The reply body is an array of struct Port.
 	MultipartTypePortDescription
 
+	// MultipartTypeQueueDescription is used to retrieve queue
+	// description (OpenFlow 1.4).
+	//
+	// The request body is struct QueueDescRequest. The reply body is
+	// an array of struct QueueDesc.
+	MultipartTypeQueueDescription MultipartType = 15
+
+	// MultipartTypeFlowMonitor is used to subscribe to the changes of
+	// the flow tables (OpenFlow 1.4).
+	//
+	// The request body is struct FlowMonitorRequest. The reply body is
+	// struct FlowUpdates.
+	MultipartTypeFlowMonitor MultipartType = 16
+
 	// MultipartTypeExperimenter is an experimenter extension.
 	//
 	// The request and reply bodies begin with struct
@@ -131,9 +145,15 @@ var multipartTypeText = map[MultipartType]string{
 	MultipartTypeMeterFeatures:    "MultipartTypeMeterFeatures",
 	MultipartTypeTableFeatures:    "MultipartTypeTableFeatures",
 	MultipartTypePortDescription:  "MultipartTypePortDescription",
+	MultipartTypeQueueDescription: "MultipartTypeQueueDescription",
+	MultipartTypeFlowMonitor:      "MultipartTypeFlowMonitor",
 	MultipartTypeExperimenter:     "MultipartTypeExperimenter",
 }
 
+// MultipartHeaderLen is a length of the multipart request and reply
+// headers preceding the body of the message, including the padding.
+const MultipartHeaderLen = 8
+
 // MultipartRequestFlag defines the multipart request flags.
 type MultipartRequestFlag uint16
 
@@ -219,16 +239,55 @@ type MultipartRequest struct {
 	Body io.Reader
 }
 
+// writers is a list of io.WriterTo instances serialized one after
+// another into the wire format.
+type writers []io.WriterTo
+
+// WriteTo implements io.WriterTo interface. It serializes the list
+// of writers back-to-back into the given writer.
+func (ws writers) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+
+	for _, wt := range ws {
+		nn, err := wt.WriteTo(w)
+		n += nn
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
 // NewMultipartRequest creates a new multipart request of the given
 // type. If the body is not equal to nil, it will be sent as part of
 // the multipart request message.
-func NewMultipartRequest(t MultipartType, body io.WriterTo) *MultipartRequest {
+//
+// Several multipart types take an array in the request body, in this
+// case the elements are serialized back-to-back, for example:
+//
+//	body := []io.WriterTo{&features1, &features2}
+//	req := ofp.NewMultipartRequest(ofp.MultipartTypeTableFeatures, body...)
+func NewMultipartRequest(t MultipartType, body ...io.WriterTo) *MultipartRequest {
+	var ws writers
+
+	// Skip the nil elements, so the request created without
+	// a body won't be wrapped with a reader type.
+	for _, wt := range body {
+		if wt != nil {
+			ws = append(ws, wt)
+		}
+	}
+
 	var rd io.Reader
 
-	// When the body is not defined then bypass wrapping
-	// it with a reader type.
-	if body != nil {
-		rd = &reader{WriterTo: body}
+	switch len(ws) {
+	case 0:
+	case 1:
+		rd = &reader{WriterTo: ws[0]}
+	default:
+		rd = &reader{WriterTo: ws}
 	}
 
 	return &MultipartRequest{t, 0, rd}
@@ -47,6 +47,32 @@ func TestMultipartRequest(t *testing.T) {
 	}
 }
 
+func TestNewMultipartRequest(t *testing.T) {
+	tests := []encodingtest.M{
+		{Writer: NewMultipartRequest(MultipartTypeTable, nil), Bytes: []byte{
+			0x00, 0x03, // Multipart type.
+			0x00, 0x00, // Multipart flags.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+		}},
+		{Writer: NewMultipartRequest(MultipartTypePortStats,
+			&PortStatsRequest{PortNo: 1},
+			&PortStatsRequest{PortNo: 2},
+		), Bytes: []byte{
+			0x00, 0x04, // Multipart type.
+			0x00, 0x00, // Multipart flags.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+
+			0x00, 0x00, 0x00, 0x01, // Port number.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+
+			0x00, 0x00, 0x00, 0x02, // Port number.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+		}},
+	}
+
+	encodingtest.RunM(t, tests)
+}
+
 func TestMultipartReply(t *testing.T) {
 	tests := []encodingtest.MU{
 		{ReadWriter: &MultipartReply{
@@ -2,11 +2,13 @@ package ofp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
 
 	"github.com/netrack/openflow/internal/encoding"
+	"github.com/netrack/openflow/ofpconst"
 )
 
 const (
@@ -70,7 +72,8 @@ type PacketIn struct {
 	// datapath, that holds the processing packet.
 	Buffer uint32
 
-	// Length is the total length of the frame.
+	// Length is the total length of the frame. It is greater than the
+	// length of the Data, when the frame was truncated by the switch.
 	Length uint16
 
 	// Reason is the reason why packet is being sent.
@@ -103,6 +106,16 @@ func (p *PacketIn) SetCookies(cookies uint64) {
 // WriteTo implements io.WriterTo interface. It serializes the packet-in
 // message into the wire format.
 func (p *PacketIn) WriteTo(w io.Writer) (int64, error) {
+	if err := p.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
+	if IsStrict() {
+		if err := p.Validate(); err != nil {
+			return 0, err
+		}
+	}
+
 	return encoding.WriteTo(w, p.Buffer, p.Length,
 		p.Reason, p.Table, p.Cookie, &p.Match, pad2{}, p.Data)
 }
@@ -119,11 +132,156 @@ func (p *PacketIn) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 	p.Data, err = ioutil.ReadAll(r)
-	if err != nil {
-		return n + int64(len(p.Data)), err
+	if n += int64(len(p.Data)); err != nil {
+		return n, err
+	}
+
+	if err = p.checkReserved(readerMode(r)); err != nil {
+		return n, err
+	}
+
+	if readerMode(r) == CheckStrict {
+		err = p.Validate()
+	}
+
+	return n, err
+}
+
+// Truncated reports whether the frame was truncated by the switch, so
+// the Data contains only the first bytes of the frame. The frames are
+// truncated to the max_len of the output action or to the miss_send_len
+// of the switch configuration, the rest of the frame could be retrieved
+// from the buffer, when it is not NoBuffer.
+func (p *PacketIn) Truncated() bool {
+	return int(p.Length) > len(p.Data)
+}
+
+// InPort returns the ingress port of the packet from the match of the
+// packet-in message. The second value is false when the match does not
+// contain the in_port field.
+func (p *PacketIn) InPort() (PortNo, bool) {
+	xm := p.Match.Field(XMTypeInPort)
+	if xm == nil || len(xm.Value) != 4 {
+		return 0, false
+	}
+
+	return PortNo(binary.BigEndian.Uint32(xm.Value)), true
+}
+
+// l4PortTypes maps the transport protocol to the source and destination
+// port match fields.
+var l4PortTypes = map[uint8][2]XMType{
+	ofpconst.IPProtoTCP:  {XMTypeTCPSrc, XMTypeTCPDst},
+	ofpconst.IPProtoUDP:  {XMTypeUDPSrc, XMTypeUDPDst},
+	ofpconst.IPProtoSCTP: {XMTypeSCTPSrc, XMTypeSCTPDst},
+}
+
+// ReverseMatch returns the match of the packets flowing in the reverse
+// direction to the packet of the packet-in message, so the reactive
+// application could install the flow entry for the reply traffic. The
+// source and destination Ethernet addresses, IPv4 and IPv6 addresses
+// and TCP, UDP and SCTP ports of the frame are swapped, the VLAN
+// identifier of the single 802.1Q tag is preserved.
+//
+// The headers truncated by the switch are left out of the match. An
+// error is returned when the frame is shorter than the Ethernet header.
+func (p *PacketIn) ReverseMatch() (Match, error) {
+	data := p.Data
+	if len(data) < 14 {
+		return Match{}, fmt.Errorf("ofp: packet-in frame of %d bytes "+
+			"is too short: %w", len(data), io.ErrUnexpectedEOF)
+	}
+
+	m := Match{Type: MatchTypeXM}
+	add := func(t XMType, value []byte) {
+		m.Fields = append(m.Fields, XM{
+			Class: XMClassOpenflowBasic,
+			Type:  t,
+			Value: XMValue(cloneBytes(value)),
+		})
+	}
+
+	ethType := binary.BigEndian.Uint16(data[12:])
+	add(XMTypeEthDst, data[6:12])
+	add(XMTypeEthSrc, data[0:6])
+	data = data[14:]
+
+	if ethType == ofpconst.EtherTypeVLAN && len(data) >= 4 {
+		var vid [2]byte
+		binary.BigEndian.PutUint16(vid[:],
+			uint16(VlanPresent)|binary.BigEndian.Uint16(data)&0x0fff)
+
+		add(XMTypeVlanID, vid[:])
+		ethType = binary.BigEndian.Uint16(data[2:])
+		data = data[4:]
+	}
+
+	var typ [2]byte
+	binary.BigEndian.PutUint16(typ[:], ethType)
+	add(XMTypeEthType, typ[:])
+
+	var proto uint8
+	switch {
+	case ethType == ofpconst.EtherTypeIPv4 && len(data) >= 20:
+		headerLen := int(data[0]&0x0f) * 4
+		fragmented := binary.BigEndian.Uint16(data[6:])&0x1fff != 0
+
+		proto = data[9]
+		add(XMTypeIPProto, []byte{proto})
+		add(XMTypeIPv4Src, data[16:20])
+		add(XMTypeIPv4Dst, data[12:16])
+
+		if fragmented || headerLen < 20 || len(data) < headerLen {
+			return m, nil
+		}
+
+		data = data[headerLen:]
+	case ethType == ofpconst.EtherTypeIPv6 && len(data) >= 40:
+		proto = data[6]
+		add(XMTypeIPProto, []byte{proto})
+		add(XMTypeIPv6Src, data[24:40])
+		add(XMTypeIPv6Dst, data[8:24])
+		data = data[40:]
+	default:
+		return m, nil
 	}
 
-	return n + int64(len(p.Data)), nil
+	if ports, ok := l4PortTypes[proto]; ok && len(data) >= 4 {
+		add(ports[0], data[2:4])
+		add(ports[1], data[0:2])
+	}
+
+	return m, nil
+}
+
+// Validate checks that the packet-in message conforms to the
+// specification: the data of the frame does not exceed the total
+// length of the frame.
+//
+// On failure an Error of ErrTypeBadRequest type wrapped with the
+// description of the failure is returned.
+func (p *PacketIn) Validate() error {
+	if len(p.Data) > int(p.Length) {
+		return fmt.Errorf("ofp: packet-in %d bytes of data exceed "+
+			"total length %d: %w", len(p.Data), p.Length,
+			Error{Type: ErrTypeBadRequest, Code: ErrCodeBadRequestLen})
+	}
+
+	return nil
+}
+
+// Clone returns a deep copy of the packet-in message.
+func (p *PacketIn) Clone() *PacketIn {
+	clone := *p
+	clone.Match = p.Match.Clone()
+	clone.Data = cloneBytes(p.Data)
+	return &clone
+}
+
+// checkReserved validates the packet-in reason in strict mode.
+func (p *PacketIn) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "packet in reason",
+		p.Reason <= PacketInReasonInvalidTTL, p.Reason)
 }
 
 // PacketOut used by the controller to send a packet out through the
@@ -153,6 +311,12 @@ type PacketOut struct {
 // WriteTo implements io.WriterTo interface. It serializes the message
 // into the wire format.
 func (p *PacketOut) WriteTo(w io.Writer) (n int64, err error) {
+	if CurrentCheckMode() == CheckStrict {
+		if err = p.Validate(); err != nil {
+			return
+		}
+	}
+
 	var buf bytes.Buffer
 
 	// Simply serialize the list of actions into the buffer,
@@ -178,7 +342,7 @@ func (p *PacketOut) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	limrd := io.LimitReader(r, int64(plen))
+	limrd := encoding.LimitReader(r, int64(plen))
 	p.Actions = nil
 
 	nn, err := p.Actions.ReadFrom(limrd)
@@ -186,6 +350,62 @@ func (p *PacketOut) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
+	// The length of the actions must match the consumed bytes,
+	// otherwise the remaining bytes will be treated as data.
+	if nn != int64(plen) {
+		return n, fmt.Errorf("ofp: packet-out actions length %d, "+
+			"decoded %d bytes: %w", plen, nn, Error{
+			Type: ErrTypeBadRequest, Code: ErrCodeBadRequestLen})
+	}
+
 	p.Data, err = ioutil.ReadAll(r)
-	return n + int64(len(p.Data)), err
+	if n += int64(len(p.Data)); err != nil {
+		return n, err
+	}
+
+	if readerMode(r) == CheckStrict {
+		err = p.Validate()
+	}
+
+	return n, err
+}
+
+// Validate checks that the packet-out message conforms to the
+// specification: the ingress port is either a standard switch port,
+// PortLocal, PortController or PortAny, and the data is empty when the
+// packet buffered at the switch is referenced.
+//
+// The message is validated on encoding in the default check mode, and
+// on decoding in the check mode of the reader.
+//
+// On failure an Error of ErrTypeBadRequest type wrapped with the
+// description of the failure is returned.
+func (p *PacketOut) Validate() error {
+	newError := func(code ErrCode, format string, v ...interface{}) error {
+		text := fmt.Sprintf(format, v...)
+		return fmt.Errorf("ofp: packet-out %s: %w", text,
+			Error{Type: ErrTypeBadRequest, Code: code})
+	}
+
+	switch {
+	case p.InPort == PortLocal, p.InPort == PortController, p.InPort == PortAny:
+	case p.InPort == 0 || p.InPort >= PortMax:
+		return newError(ErrCodeBadRequestBadPort,
+			"invalid ingress port %d", p.InPort)
+	}
+
+	if p.Buffer != NoBuffer && len(p.Data) != 0 {
+		return newError(ErrCodeBadRequestBadPacket,
+			"buffer 0x%x with %d bytes of data", p.Buffer, len(p.Data))
+	}
+
+	return nil
+}
+
+// Clone returns a deep copy of the packet-out message.
+func (p *PacketOut) Clone() *PacketOut {
+	clone := *p
+	clone.Actions = p.Actions.Clone()
+	clone.Data = cloneBytes(p.Data)
+	return &clone
 }
@@ -1,7 +1,12 @@
 package ofp
 
 import (
+	"bytes"
 	"encoding/gob"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
 	"testing"
 
 	"github.com/netrack/openflow/internal/encodingtest"
@@ -54,6 +59,53 @@ func TestPacketIn(t *testing.T) {
 	encodingtest.RunMU(t, tests)
 }
 
+func TestPacketInTruncated(t *testing.T) {
+	defer SetCheckMode(CheckLenient)
+
+	tests := []struct {
+		PacketIn  PacketIn
+		Truncated bool
+		Valid     bool
+	}{
+		{PacketIn{Length: 2, Data: []byte{1, 2}}, false, true},
+		{PacketIn{Length: 128, Data: []byte{1, 2}}, true, true},
+		{PacketIn{}, false, true},
+		{PacketIn{Length: 1, Data: []byte{1, 2}}, false, false},
+	}
+
+	for _, test := range tests {
+		if truncated := test.PacketIn.Truncated(); truncated != test.Truncated {
+			t.Errorf("Invalid truncation of %v: %v", test.PacketIn, truncated)
+		}
+
+		err := test.PacketIn.Validate()
+		if test.Valid {
+			if err != nil {
+				t.Errorf("Packet-in %v must be valid: %s", test.PacketIn, err)
+			}
+			continue
+		}
+
+		var e Error
+		if !errors.As(err, &e) || e.Code != ErrCodeBadRequestLen {
+			t.Errorf("Invalid error returned for %v: %v", test.PacketIn, err)
+		}
+
+		// Invalid messages are rejected only in strict mode.
+		if _, err = test.PacketIn.WriteTo(ioutil.Discard); err != nil {
+			t.Errorf("Packet-in %v must be serialized: %s", test.PacketIn, err)
+		}
+
+		SetCheckMode(CheckStrict)
+		_, err = test.PacketIn.WriteTo(ioutil.Discard)
+		SetCheckMode(CheckLenient)
+
+		if err == nil {
+			t.Errorf("Packet-in %v must not be serialized", test.PacketIn)
+		}
+	}
+}
+
 func TestPacketOut(t *testing.T) {
 	tests := []encodingtest.MU{
 		// Test Packet-Out without data (buffer_id required).
@@ -106,3 +158,153 @@ func TestPacketOut(t *testing.T) {
 	gob.Register(ActionOutput{})
 	encodingtest.RunMU(t, tests)
 }
+
+func TestPacketOutValidate(t *testing.T) {
+	defer SetCheckMode(CheckLenient)
+
+	tests := []struct {
+		PacketOut PacketOut
+		Code      ErrCode
+		Valid     bool
+	}{
+		{PacketOut{Buffer: NoBuffer, InPort: 1, Data: []byte{1}}, 0, true},
+		{PacketOut{Buffer: NoBuffer, InPort: PortController}, 0, true},
+		{PacketOut{Buffer: NoBuffer, InPort: PortLocal}, 0, true},
+		{PacketOut{Buffer: 1, InPort: PortAny}, 0, true},
+		{PacketOut{Buffer: NoBuffer}, ErrCodeBadRequestBadPort, false},
+		{PacketOut{Buffer: NoBuffer, InPort: PortFlood}, ErrCodeBadRequestBadPort, false},
+		{PacketOut{Buffer: 1, InPort: 1, Data: []byte{1}}, ErrCodeBadRequestBadPacket, false},
+	}
+
+	for _, test := range tests {
+		err := test.PacketOut.Validate()
+		if test.Valid {
+			if err != nil {
+				t.Errorf("Packet-out %v must be valid: %s", test.PacketOut, err)
+			}
+			continue
+		}
+
+		var e Error
+		if !errors.As(err, &e) || e.Type != ErrTypeBadRequest || e.Code != test.Code {
+			t.Errorf("Invalid error returned for %v: %v", test.PacketOut, err)
+		}
+
+		// Invalid messages are rejected only in strict mode.
+		if _, err = test.PacketOut.WriteTo(ioutil.Discard); err != nil {
+			t.Errorf("Packet-out %v must be serialized: %s", test.PacketOut, err)
+		}
+
+		SetCheckMode(CheckStrict)
+		_, err = test.PacketOut.WriteTo(ioutil.Discard)
+		SetCheckMode(CheckLenient)
+
+		if err == nil {
+			t.Errorf("Packet-out %v must not be serialized", test.PacketOut)
+		}
+
+		// The decoder validates the message in the mode of the reader.
+		var buf bytes.Buffer
+		test.PacketOut.WriteTo(&buf)
+
+		var pout PacketOut
+		rd := NewCheckReader(&buf, CheckStrict)
+		if _, err = pout.ReadFrom(rd); err == nil {
+			t.Errorf("Packet-out %v must not be decoded", test.PacketOut)
+		}
+	}
+}
+
+func TestPacketOutActionsLen(t *testing.T) {
+	data := []byte{
+		0xff, 0xff, 0xff, 0xff, // Buffer identifier.
+		0xff, 0xff, 0xff, 0xfd, // Port number.
+		0x00, 0x10, // Actions list length exceeding the message.
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 6-byte padding.
+
+		// Actions.
+		0x00, 0x16, // Action group.
+		0x00, 0x08,
+		0xff, 0xff, 0xff, 0xfc,
+	}
+
+	var p PacketOut
+	_, err := p.ReadFrom(bytes.NewReader(data))
+
+	var e Error
+	if !errors.As(err, &e) || e.Code != ErrCodeBadRequestLen {
+		t.Fatalf("Actions length mismatch must be reported: %v", err)
+	}
+}
+
+func TestPacketInInPort(t *testing.T) {
+	var packet PacketIn
+	if _, ok := packet.InPort(); ok {
+		t.Fatalf("In port must not be found in empty match")
+	}
+
+	packet.Match = Match{MatchTypeXM, []XM{{
+		Class: XMClassOpenflowBasic,
+		Type:  XMTypeInPort,
+		Value: XMValue{0x00, 0x00, 0x00, 0x03},
+	}}}
+
+	if port, ok := packet.InPort(); !ok || port != 3 {
+		t.Errorf("Invalid in port: %d", port)
+	}
+}
+
+func TestPacketInReverseMatch(t *testing.T) {
+	frame := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x02, // Destination address.
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // Source address.
+		0x81, 0x00, 0x00, 0x0a, // 802.1Q tag, VLAN 10.
+		0x08, 0x00, // IPv4.
+
+		0x45, 0x00, 0x00, 0x1c, 0x00, 0x00, 0x00, 0x00,
+		0x40, 0x11, 0x00, 0x00, // UDP.
+		0x0a, 0x00, 0x00, 0x01, // Source address.
+		0x0a, 0x00, 0x00, 0x02, // Destination address.
+
+		0x30, 0x39, 0x00, 0x35, // Source and destination ports.
+		0x00, 0x08, 0x00, 0x00,
+	}
+
+	packet := PacketIn{Length: uint16(len(frame)), Data: frame}
+
+	m, err := packet.ReverseMatch()
+	if err != nil {
+		t.Fatalf("Failed to create reverse match: %s", err)
+	}
+
+	basic := func(t XMType, v ...byte) XM {
+		return XM{Class: XMClassOpenflowBasic, Type: t, Value: v}
+	}
+
+	expected := Match{MatchTypeXM, []XM{
+		basic(XMTypeEthDst, 0, 0, 0, 0, 0, 1),
+		basic(XMTypeEthSrc, 0, 0, 0, 0, 0, 2),
+		basic(XMTypeVlanID, 0x10, 0x0a),
+		basic(XMTypeEthType, 0x08, 0x00),
+		basic(XMTypeIPProto, 17),
+		basic(XMTypeIPv4Src, 10, 0, 0, 2),
+		basic(XMTypeIPv4Dst, 10, 0, 0, 1),
+		basic(XMTypeUDPSrc, 0x00, 0x35),
+		basic(XMTypeUDPDst, 0x30, 0x39),
+	}}
+
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("Invalid reverse match: %v", m.Fields)
+	}
+
+	// The transport header is truncated by the switch.
+	packet.Data = frame[:38]
+	if m, _ = packet.ReverseMatch(); len(m.Fields) != 7 {
+		t.Errorf("Truncated headers must be left out: %v", m.Fields)
+	}
+
+	packet.Data = frame[:10]
+	if _, err = packet.ReverseMatch(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Unexpected end of file error expected: %v", err)
+	}
+}
@@ -1,5 +1,9 @@
 package ofp
 
+import (
+	"io"
+)
+
 type (
 	pad  []byte
 	pad1 [1]uint8
@@ -24,6 +28,89 @@ var (
 	defaultPad8 pad8
 )
 
+// readPad reads the len(b) bytes of padding from the given reader. In
+// strict mode it ensures that all bytes of the padding are zeroed.
+func readPad(r io.Reader, b []byte) (int64, error) {
+	n, err := io.ReadFull(r, b)
+	if err != nil {
+		return int64(n), err
+	}
+
+	return int64(n), checkPad(readerMode(r), b)
+}
+
+// checkPad validates the padding bytes are zero in strict mode.
+func checkPad(mode CheckMode, b []byte) error {
+	if mode != CheckStrict {
+		return nil
+	}
+
+	for _, octet := range b {
+		if octet != 0 {
+			return ErrPaddingNotZero
+		}
+	}
+
+	return nil
+}
+
+// ReadFrom implements io.ReaderFrom interface. It consumes the padding
+// of the variable length from the reader.
+func (p pad) ReadFrom(r io.Reader) (int64, error) {
+	return readPad(r, p)
+}
+
+// The padding of the fixed length is read into the local buffer, so the
+// default paddings could be shared between concurrent decoders.
+
+// ReadFrom implements io.ReaderFrom interface.
+func (*pad1) ReadFrom(r io.Reader) (int64, error) {
+	var b pad1
+	return readPad(r, b[:])
+}
+
+// ReadFrom implements io.ReaderFrom interface.
+func (*pad2) ReadFrom(r io.Reader) (int64, error) {
+	var b pad2
+	return readPad(r, b[:])
+}
+
+// ReadFrom implements io.ReaderFrom interface.
+func (*pad3) ReadFrom(r io.Reader) (int64, error) {
+	var b pad3
+	return readPad(r, b[:])
+}
+
+// ReadFrom implements io.ReaderFrom interface.
+func (*pad4) ReadFrom(r io.Reader) (int64, error) {
+	var b pad4
+	return readPad(r, b[:])
+}
+
+// ReadFrom implements io.ReaderFrom interface.
+func (*pad5) ReadFrom(r io.Reader) (int64, error) {
+	var b pad5
+	return readPad(r, b[:])
+}
+
+// ReadFrom implements io.ReaderFrom interface.
+func (*pad6) ReadFrom(r io.Reader) (int64, error) {
+	var b pad6
+	return readPad(r, b[:])
+}
+
+// ReadFrom implements io.ReaderFrom interface.
+func (*pad7) ReadFrom(r io.Reader) (int64, error) {
+	var b pad7
+	return readPad(r, b[:])
+}
+
+// ReadFrom implements io.ReaderFrom interface.
+func (*pad8) ReadFrom(r io.Reader) (int64, error) {
+	var b pad8
+	return readPad(r, b[:])
+}
+
 // padLen returns a size of the padding for the given length.
 func padLen(length int) int {
 	return (length+7)/8*8 - length
@@ -31,6 +118,6 @@ func padLen(length int) int {
 
 // makePad creates a new padding based on the given length according
 // to the formula: (length + 7) / 8 * 8 - length
-func makePad(length int) []byte {
-	return make([]byte, padLen(length))
+func makePad(length int) pad {
+	return make(pad, padLen(length))
 }
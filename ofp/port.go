@@ -1,6 +1,8 @@
 package ofp
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"strings"
@@ -250,6 +252,83 @@ const (
 	PortMax PortNo = 0xffffff00
 )
 
+// ErrPortNoRange is returned when the port number cannot be represented
+// in the 16-bit port number of the OpenFlow 1.0 protocol.
+var ErrPortNoRange = errors.New("ofp: port number is out of 1.0 range")
+
+// PortNo10 defines a switch port number of the OpenFlow 1.0 protocol.
+// Starting from OpenFlow 1.1 the port numbers were extended to 32 bits,
+// therefore the reserved ports have different values.
+type PortNo10 uint16
+
+const (
+	// Port10Max is a maximum number of physical switch ports.
+	Port10Max PortNo10 = 0xff00
+
+	// Port10In used to forward the packet out the input port.
+	Port10In PortNo10 = 0xfff8
+
+	// Port10Table used to submit the packet to the flow table.
+	Port10Table PortNo10 = 0xfff9
+
+	// Port10Normal used to process packets with normal L2/L3 switching.
+	Port10Normal PortNo10 = 0xfffa
+
+	// Port10Flood used to forward packets to all physical ports except
+	// input port and those disabled by STP.
+	Port10Flood PortNo10 = 0xfffb
+
+	// Port10All used to forward all physical ports except input port.
+	Port10All PortNo10 = 0xfffc
+
+	// Port10Controller used to send the received packet to controller.
+	Port10Controller PortNo10 = 0xfffd
+
+	// Port10Local is a local OpenFlow port.
+	Port10Local PortNo10 = 0xfffe
+
+	// Port10None is not associated with a physical port, it is an
+	// equivalent of the PortAny.
+	Port10None PortNo10 = 0xffff
+)
+
+// portNo10Offset is a difference between the reserved port numbers
+// of the OpenFlow 1.0 and the later versions of the protocol.
+const portNo10Offset = 0xffff0000
+
+// PortNo converts the OpenFlow 1.0 port number into the port number
+// of the later versions of the protocol. Reserved port numbers are
+// mapped to the corresponding 32-bit reserved port numbers.
+func (p PortNo10) PortNo() PortNo {
+	if p >= Port10Max {
+		return PortNo(p) + portNo10Offset
+	}
+
+	return PortNo(p)
+}
+
+// PortNo10 converts the port number into the port number of the
+// OpenFlow 1.0 protocol. When the port number cannot be represented
+// with 16 bits, the ErrPortNoRange error is returned.
+//
+// For example, to convert the output port of the action for the
+// switch that supports only the first version of the protocol:
+//
+//	port, err := action.Port.PortNo10()
+//	if err != nil {
+//		// The port is not available on this switch.
+//	}
+func (p PortNo) PortNo10() (PortNo10, error) {
+	switch {
+	case p < PortNo(Port10Max):
+		return PortNo10(p), nil
+	case p >= PortMax:
+		return PortNo10(p - portNo10Offset), nil
+	}
+
+	return 0, ErrPortNoRange
+}
+
 // portNameLen defines a length of the port name.
 const portNameLen = 16
 
@@ -408,6 +487,20 @@ const (
 	PortReasonModify
 )
 
+func (r PortReason) String() string {
+	text, ok := portReasonText[r]
+	if !ok {
+		return fmt.Sprintf("PortReason(%d)", r)
+	}
+	return text
+}
+
+var portReasonText = map[PortReason]string{
+	PortReasonAdd:    "PortReasonAdd",
+	PortReasonDelete: "PortReasonDelete",
+	PortReasonModify: "PortReasonModify",
+}
+
 // PortStatus is the message used by the switch to inform the controller
 // about the port being added, modified or removed.
 type PortStatus struct {
@@ -421,13 +514,28 @@ type PortStatus struct {
 // WriteTo implements io.WriterTo interface. It serializes the
 // port status into the wire format.
 func (p *PortStatus) WriteTo(w io.Writer) (int64, error) {
+	if err := p.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
 	return encoding.WriteTo(w, p.Reason, pad7{}, &p.Port)
 }
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // port status from the wire format.
 func (p *PortStatus) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &p.Reason, &defaultPad7, &p.Port)
+	n, err := encoding.ReadFrom(r, &p.Reason, &defaultPad7, &p.Port)
+	if err != nil {
+		return n, err
+	}
+
+	return n, p.checkReserved(readerMode(r))
+}
+
+// checkReserved validates the port status reason in strict mode.
+func (p *PortStatus) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "port status reason",
+		p.Reason <= PortReasonModify, p.Reason)
 }
 
 // PortStatsRequest is a multipart request message body used to retrieve
@@ -230,3 +230,43 @@ func TestPortStats(t *testing.T) {
 
 	encodingtest.RunMU(t, tests)
 }
+
+func TestPortNo10(t *testing.T) {
+	tests := []struct {
+		PortNo   PortNo
+		PortNo10 PortNo10
+	}{
+		{1, 1},
+		{0xfeff, 0xfeff},
+		{PortMax, Port10Max},
+		{PortIn, Port10In},
+		{PortTable, Port10Table},
+		{PortNormal, Port10Normal},
+		{PortFlood, Port10Flood},
+		{PortAll, Port10All},
+		{PortController, Port10Controller},
+		{PortLocal, Port10Local},
+		{PortAny, Port10None},
+	}
+
+	for _, test := range tests {
+		p, err := test.PortNo.PortNo10()
+		if err != nil {
+			t.Fatalf("Failed to convert port %d: %s", test.PortNo, err)
+		}
+
+		if p != test.PortNo10 {
+			t.Errorf("Invalid 1.0 port %x, expected %x", p, test.PortNo10)
+		}
+
+		if p.PortNo() != test.PortNo {
+			t.Errorf("Invalid port %x, expected %x", p.PortNo(), test.PortNo)
+		}
+	}
+
+	for _, port := range []PortNo{0xff00, 0x10000, PortMax - 1} {
+		if _, err := port.PortNo10(); err != ErrPortNoRange {
+			t.Errorf("Port %x must be out of range: %v", port, err)
+		}
+	}
+}
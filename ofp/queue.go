@@ -28,9 +28,9 @@ const (
 
 // queuePropTypeMap is a mapping used to decode the set of queue properties.
 var queuePropTypeMap = map[QueuePropType]encoding.ReaderMaker{
-	QueuePropTypeMinRate:      encoding.ReaderMakerOf(QueuePropMinRate{}),
-	QueuePropTypeMaxRate:      encoding.ReaderMakerOf(QueuePropMaxRate{}),
-	QueuePropTypeExperimenter: encoding.ReaderMakerOf(QueuePropExperimenter{}),
+	QueuePropTypeMinRate:      encoding.ReaderMakerOf[QueuePropMinRate](),
+	QueuePropTypeMaxRate:      encoding.ReaderMakerOf[QueuePropMaxRate](),
+	QueuePropTypeExperimenter: encoding.ReaderMakerOf[QueuePropExperimenter](),
 }
 
 // Queue defines a queue number configured at the specific port.
@@ -58,10 +58,14 @@ func (q QueueProps) WriteTo(w io.Writer) (int64, error) {
 	var buf bytes.Buffer
 
 	for _, prop := range q {
-		_, err := prop.WriteTo(&buf)
+		n, err := prop.WriteTo(&buf)
 		if err != nil {
 			return 0, err
 		}
+
+		if err = checkAligned(CurrentCheckMode(), prop.Type(), n); err != nil {
+			return 0, err
+		}
 	}
 
 	return encoding.WriteTo(w, buf.Bytes())
@@ -69,22 +73,28 @@ func (q QueueProps) WriteTo(w io.Writer) (int64, error) {
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // set of queue properties from the wire format.
-func (q QueueProps) ReadFrom(r io.Reader) (int64, error) {
-	var queueType QueuePropType
+func (q *QueueProps) ReadFrom(r io.Reader) (int64, error) {
 
-	rm := func() (io.ReaderFrom, error) {
+	rm := func(queueType QueuePropType) (io.ReaderFrom, error) {
 		if rm, ok := queuePropTypeMap[queueType]; ok {
 			rd, err := rm.MakeReader()
-			q = append(q, rd.(QueueProp))
+			*q = append(*q, rd.(QueueProp))
 			return rd, err
 		}
 
-		format := "ofp: unknown queue property type: '%x'"
-		return nil, fmt.Errorf(format, queueType)
+		if readerMode(r) == CheckStrict {
+			format := "ofp: unknown queue property type: '%x'"
+			return nil, fmt.Errorf(format, queueType)
+		}
+
+		// Preserve the unknown property, so the queue could be
+		// encoded back without loss.
+		rd := &QueuePropRaw{}
+		*q = append(*q, rd)
+		return rd, nil
 	}
 
-	return encoding.ScanFrom(r, &queueType,
-		encoding.ReaderMakerFunc(rm))
+	return encoding.ScanFrom(r, rm)
 }
 
 // packetQueueLen defines the length of the packet queue header.
@@ -132,20 +142,56 @@ func (q *PacketQueue) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	limrd := io.LimitReader(r, int64(length-packetQueueLen))
+	if length < packetQueueLen {
+		return n, fmt.Errorf("ofp: invalid packet queue length: %d",
+			length)
+	}
+
+	limrd := encoding.LimitReader(r, int64(length-packetQueueLen))
+	q.Properties = nil
+
 	nn, err := q.Properties.ReadFrom(limrd)
 	return n + nn, err
 }
 
-// queuePropLen defines the length of the queue property header length.
+// queuePropLen defines the length of the minimum-rate and maximum-rate
+// queue properties including the 8-byte property header.
 const queuePropLen = 16
 
+// queuePropExperimenterLen defines the length of the experimental queue
+// property without the experimenter-defined data: the 8-byte property
+// header followed by the experimenter identifier and 4-byte padding.
+const queuePropExperimenterLen = 16
+
+// queuePropHeaderLen defines the length of the type and length fields
+// of the queue property header.
+const queuePropHeaderLen = 4
+
 // queueProp is a common header of the queue properties.
 type queueProp struct {
 	Type QueuePropType
 	Len  uint16
 }
 
+// readQueuePropRate deserializes the minimum-rate or maximum-rate queue
+// property. The bytes exceeding the length of the property are skipped,
+// so the following properties are decoded from the correct offset.
+func readQueuePropRate(r io.Reader, rate *uint16) (int64, error) {
+	var header queueProp
+	n, err := encoding.ReadFrom(r, &header, &defaultPad4, rate, &defaultPad6)
+	if err != nil {
+		return n, err
+	}
+
+	if header.Len < queuePropLen {
+		return n, fmt.Errorf("ofp: invalid queue property length: %d",
+			header.Len)
+	}
+
+	nn, err := io.CopyN(ioutil.Discard, r, int64(header.Len-queuePropLen))
+	return n + nn, err
+}
+
 const (
 	// QueueMinRateUncfg indicates that minimum-rate queue property is not
 	// configured.
@@ -179,7 +225,7 @@ func (q *QueuePropMinRate) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes the queue
 // property from the wire format.
 func (q *QueuePropMinRate) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad8, &q.Rate, &defaultPad6)
+	return readQueuePropRate(r, &q.Rate)
 }
 
 // QueuePropMaxRate defines the maximum-rate queue property.
@@ -205,7 +251,7 @@ func (q *QueuePropMaxRate) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // maximum-rate queue property from the wire format.
 func (q *QueuePropMaxRate) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &defaultPad8, &q.Rate, &defaultPad6)
+	return readQueuePropRate(r, &q.Rate)
 }
 
 // QueuePropExperimenter defines an experimental queue property.
@@ -226,7 +272,8 @@ func (q *QueuePropExperimenter) Type() QueuePropType {
 // WriteTo implements io.WriterTo interface. It serializes the
 // experimental queue property into the wire format.
 func (q *QueuePropExperimenter) WriteTo(w io.Writer) (int64, error) {
-	header := queueProp{q.Type(), queuePropLen + uint16(len(q.Data))}
+	length := queuePropExperimenterLen + len(q.Data)
+	header := queueProp{q.Type(), uint16(length)}
 	return encoding.WriteTo(w, header, pad4{},
 		q.Experimenter, &pad4{}, q.Data)
 }
@@ -242,7 +289,67 @@ func (q *QueuePropExperimenter) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	limrd := io.LimitReader(r, int64(header.Len-queuePropLen))
+	// The experimenter identifier and padding are already read, so
+	// only the experimenter-defined data is left.
+	if header.Len < queuePropExperimenterLen {
+		return n, fmt.Errorf("ofp: invalid queue property length: %d",
+			header.Len)
+	}
+
+	limrd := encoding.LimitReader(r, int64(header.Len-queuePropExperimenterLen))
+	q.Data, err = ioutil.ReadAll(limrd)
+	if n += int64(len(q.Data)); err != nil {
+		return n, err
+	}
+
+	if len(q.Data) < int(header.Len-queuePropExperimenterLen) {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	return n, nil
+}
+
+// QueuePropRaw is a queue property of the type unknown to the library.
+// In the lenient check mode the unknown properties are decoded into the
+// raw property, so the queue could be encoded back without loss.
+type QueuePropRaw struct {
+	// PropType is a type of the queue property.
+	PropType QueuePropType
+
+	// Data is a body of the property following the type and length
+	// of the property, including the padding of the header.
+	Data []byte
+}
+
+// Type implements QueueProp interface. It returns the type of the
+// queue property.
+func (q *QueuePropRaw) Type() QueuePropType {
+	return q.PropType
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the raw
+// queue property into the wire format.
+func (q *QueuePropRaw) WriteTo(w io.Writer) (int64, error) {
+	header := queueProp{q.PropType, uint16(queuePropHeaderLen + len(q.Data))}
+	return encoding.WriteTo(w, header, q.Data)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// raw queue property from the wire format.
+func (q *QueuePropRaw) ReadFrom(r io.Reader) (int64, error) {
+	var header queueProp
+	n, err := encoding.ReadFrom(r, &header)
+	if err != nil {
+		return n, err
+	}
+
+	if header.Len < queuePropHeaderLen {
+		return n, fmt.Errorf("ofp: invalid queue property length: %d",
+			header.Len)
+	}
+
+	q.PropType = header.Type
+	limrd := encoding.LimitReader(r, int64(header.Len-queuePropHeaderLen))
 	q.Data, err = ioutil.ReadAll(limrd)
 	return n + int64(len(q.Data)), err
 }
@@ -374,7 +481,376 @@ func (q *QueueGetConfigReply) ReadFrom(r io.Reader) (int64, error) {
 	// OpenFlow message, thus return EOF error when the
 	// messages ends. Otherwise this implementation will
 	// read the packet queues indefinitely.
-	queueMaker := encoding.ReaderMakerOf(PacketQueue{})
-	nn, err := encoding.ReadSliceFrom(r, queueMaker, q.Queues)
+	q.Queues = nil
+
+	nn, err := encoding.ReadSliceFrom(r, &q.Queues)
+	return n + nn, err
+}
+
+// QueueDescPropType defines the type of the queue description property
+// (OpenFlow 1.4).
+type QueueDescPropType uint16
+
+const (
+	// QueueDescPropTypeMinRate indicates that queue guarantees minimum
+	// datarate.
+	QueueDescPropTypeMinRate QueueDescPropType = 1
+
+	// QueueDescPropTypeMaxRate indicates that queue guarantees maximum
+	// datarate.
+	QueueDescPropTypeMaxRate QueueDescPropType = 2
+
+	// QueueDescPropTypeExperimenter indicates an experimental queue
+	// description property.
+	QueueDescPropTypeExperimenter QueueDescPropType = 0xffff
+)
+
+// queueDescPropTypeMap is a mapping used to decode the set of queue
+// description properties.
+var queueDescPropTypeMap = map[QueueDescPropType]encoding.ReaderMaker{
+	QueueDescPropTypeMinRate:      encoding.ReaderMakerOf[QueueDescPropMinRate](),
+	QueueDescPropTypeMaxRate:      encoding.ReaderMakerOf[QueueDescPropMaxRate](),
+	QueueDescPropTypeExperimenter: encoding.ReaderMakerOf[QueueDescPropExperimenter](),
+}
+
+// QueueDescProp is an interface representing an OpenFlow queue
+// description property.
+type QueueDescProp interface {
+	encoding.ReadWriter
+
+	// Type returns the queue description property type.
+	Type() QueueDescPropType
+}
+
+// QueueDescProps used to consolidate a set of queue description
+// properties.
+type QueueDescProps []QueueDescProp
+
+// WriteTo implements io.WriterTo interface. It serializes the set of
+// queue description properties into the wire format.
+func (q QueueDescProps) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	for _, prop := range q {
+		_, err := prop.WriteTo(&buf)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return encoding.WriteTo(w, buf.Bytes())
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the set
+// of queue description properties from the wire format.
+func (q *QueueDescProps) ReadFrom(r io.Reader) (int64, error) {
+	rm := func(propType QueueDescPropType) (io.ReaderFrom, error) {
+		if rm, ok := queueDescPropTypeMap[propType]; ok {
+			rd, err := rm.MakeReader()
+			if err != nil {
+				return nil, err
+			}
+
+			prop, ok := rd.(QueueDescProp)
+			if !ok {
+				return nil, fmt.Errorf("ofp: invalid queue description "+
+					"property reader: %T", rd)
+			}
+
+			*q = append(*q, prop)
+			return rd, nil
+		}
+
+		if readerMode(r) == CheckStrict {
+			format := "ofp: unknown queue description property type: '%x'"
+			return nil, fmt.Errorf(format, propType)
+		}
+
+		// Preserve the unknown property, so the queue description
+		// could be encoded back without loss.
+		rd := &QueueDescPropRaw{}
+		*q = append(*q, rd)
+		return rd, nil
+	}
+
+	return encoding.ScanFrom(r, rm)
+}
+
+// queueDescPropLen defines the length of the queue description property
+// header.
+const queueDescPropLen = 4
+
+// queueDescProp is a common header of the queue description properties.
+type queueDescProp struct {
+	Type QueueDescPropType
+	Len  uint16
+}
+
+// queueDescPropRateLen defines the length of the minimum-rate and
+// maximum-rate queue description properties.
+const queueDescPropRateLen = queueDescPropLen + 4
+
+// readQueueDescPropRate deserializes the minimum-rate or maximum-rate
+// queue description property. The bytes exceeding the length of the
+// property are skipped.
+func readQueueDescPropRate(r io.Reader, rate *uint16) (int64, error) {
+	var header queueDescProp
+	n, err := encoding.ReadFrom(r, &header, rate, &defaultPad2)
+	if err != nil {
+		return n, err
+	}
+
+	if header.Len < queueDescPropRateLen {
+		return n, fmt.Errorf("ofp: invalid queue property length: %d",
+			header.Len)
+	}
+
+	nn, err := io.CopyN(ioutil.Discard, r, int64(header.Len-queueDescPropRateLen))
+	return n + nn, err
+}
+
+// QueueDescPropMinRate defines the minimum-rate queue description
+// property.
+type QueueDescPropMinRate struct {
+	// Rate in 1/10 of a percent. If value is more than 1000,
+	// the rate is disabled.
+	Rate uint16
+}
+
+// Type implements QueueDescProp interface. It returns the type of
+// minimum-rate queue description property.
+func (q *QueueDescPropMinRate) Type() QueueDescPropType {
+	return QueueDescPropTypeMinRate
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the queue
+// description property into the wire format.
+func (q *QueueDescPropMinRate) WriteTo(w io.Writer) (int64, error) {
+	header := queueDescProp{q.Type(), queueDescPropRateLen}
+	return encoding.WriteTo(w, header, q.Rate, pad2{})
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the queue
+// description property from the wire format.
+func (q *QueueDescPropMinRate) ReadFrom(r io.Reader) (int64, error) {
+	return readQueueDescPropRate(r, &q.Rate)
+}
+
+// QueueDescPropMaxRate defines the maximum-rate queue description
+// property.
+type QueueDescPropMaxRate struct {
+	// Rate in 1/10 of a percent. If value is more than 1000,
+	// the rate is disabled.
+	Rate uint16
+}
+
+// Type implements QueueDescProp interface. It returns the type of
+// maximum-rate queue description property.
+func (q *QueueDescPropMaxRate) Type() QueueDescPropType {
+	return QueueDescPropTypeMaxRate
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the queue
+// description property into the wire format.
+func (q *QueueDescPropMaxRate) WriteTo(w io.Writer) (int64, error) {
+	header := queueDescProp{q.Type(), queueDescPropRateLen}
+	return encoding.WriteTo(w, header, q.Rate, pad2{})
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the queue
+// description property from the wire format.
+func (q *QueueDescPropMaxRate) ReadFrom(r io.Reader) (int64, error) {
+	return readQueueDescPropRate(r, &q.Rate)
+}
+
+// queueDescPropExperimenterLen defines the length of the experimental
+// queue description property without the experimenter-defined data.
+const queueDescPropExperimenterLen = queueDescPropLen + 8
+
+// QueueDescPropExperimenter defines an experimental queue description
+// property.
+type QueueDescPropExperimenter struct {
+	// Experimenter identifier.
+	Experimenter uint32
+
+	// ExpType is an experimenter-defined type.
+	ExpType uint32
+
+	// Experimenter-defined data.
+	Data []byte
+}
+
+// Type implements QueueDescProp interface. It returns the type of the
+// experimental queue description property.
+func (q *QueueDescPropExperimenter) Type() QueueDescPropType {
+	return QueueDescPropTypeExperimenter
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the
+// experimental queue description property into the wire format. The
+// length of the property excludes the padding.
+func (q *QueueDescPropExperimenter) WriteTo(w io.Writer) (int64, error) {
+	length := queueDescPropExperimenterLen + len(q.Data)
+	header := queueDescProp{q.Type(), uint16(length)}
+
+	return encoding.WriteTo(w, header, q.Experimenter, q.ExpType,
+		q.Data, makePad(length))
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// experimental queue description property from the wire format.
+func (q *QueueDescPropExperimenter) ReadFrom(r io.Reader) (int64, error) {
+	var header queueDescProp
+	n, err := encoding.ReadFrom(r, &header, &q.Experimenter, &q.ExpType)
+	if err != nil {
+		return n, err
+	}
+
+	if header.Len < queueDescPropExperimenterLen {
+		return n, fmt.Errorf("ofp: invalid queue property length: %d",
+			header.Len)
+	}
+
+	limrd := encoding.LimitReader(r, int64(header.Len-queueDescPropExperimenterLen))
+	q.Data, err = ioutil.ReadAll(limrd)
+	if n += int64(len(q.Data)); err != nil {
+		return n, err
+	}
+
+	nn, err := encoding.ReadFrom(r, makePad(int(header.Len)))
+	return n + nn, err
+}
+
+// QueueDescPropRaw is a queue description property of the type unknown
+// to the library. In the lenient check mode the unknown properties are
+// decoded into the raw property, so the queue description could be
+// encoded back without loss.
+type QueueDescPropRaw struct {
+	// PropType is a type of the queue description property.
+	PropType QueueDescPropType
+
+	// Data is a body of the property following the header, without
+	// the trailing padding.
+	Data []byte
+}
+
+// Type implements QueueDescProp interface. It returns the type of the
+// queue description property.
+func (q *QueueDescPropRaw) Type() QueueDescPropType {
+	return q.PropType
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the raw queue
+// description property into the wire format.
+func (q *QueueDescPropRaw) WriteTo(w io.Writer) (int64, error) {
+	length := queueDescPropLen + len(q.Data)
+	header := queueDescProp{q.PropType, uint16(length)}
+	return encoding.WriteTo(w, header, q.Data, makePad(length))
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the raw
+// queue description property from the wire format.
+func (q *QueueDescPropRaw) ReadFrom(r io.Reader) (int64, error) {
+	var header queueDescProp
+	n, err := encoding.ReadFrom(r, &header)
+	if err != nil {
+		return n, err
+	}
+
+	if header.Len < queueDescPropLen {
+		return n, fmt.Errorf("ofp: invalid queue property length: %d",
+			header.Len)
+	}
+
+	q.PropType = header.Type
+	limrd := encoding.LimitReader(r, int64(header.Len-queueDescPropLen))
+	q.Data, err = ioutil.ReadAll(limrd)
+	if n += int64(len(q.Data)); err != nil {
+		return n, err
+	}
+
+	nn, err := encoding.ReadFrom(r, makePad(int(header.Len)))
+	return n + nn, err
+}
+
+// QueueDescRequest is a multipart request used to retrieve the
+// configuration of one or more queues (OpenFlow 1.4).
+//
+// For example, to retrieve description of all queues configured on
+// all ports, the following request can be created:
+//
+//	body := &ofp.QueueDescRequest{ofp.PortAny, ofp.QueueAll}
+//	req := of.NewRequest(of.TypeMultipartRequest,
+//		ofp.NewMultipartRequest(ofp.MultipartTypeQueueDescription, body))
+type QueueDescRequest struct {
+	// Port identifier or PortAny for all ports.
+	Port PortNo
+
+	// Queue identifier or QueueAll for all queues.
+	Queue Queue
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the queue
+// description request into the wire format.
+func (q *QueueDescRequest) WriteTo(w io.Writer) (int64, error) {
+	return encoding.WriteTo(w, q.Port, q.Queue)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// queue description request from the wire format.
+func (q *QueueDescRequest) ReadFrom(r io.Reader) (int64, error) {
+	return encoding.ReadFrom(r, &q.Port, &q.Queue)
+}
+
+// queueDescLen defines the length of the queue description header.
+const queueDescLen = 16
+
+// QueueDesc describes the configuration of the queue (OpenFlow 1.4).
+// The list of QueueDesc structures will be returned as a multipart
+// response on queue description request.
+type QueueDesc struct {
+	// Port this queue attached to.
+	Port PortNo
+
+	// Queue identifies the specified queue.
+	Queue Queue
+
+	// Properties is a list of queue description properties.
+	Properties QueueDescProps
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the queue
+// description into the wire format.
+func (q *QueueDesc) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	_, err := q.Properties.WriteTo(&buf)
+	if err != nil {
+		return 0, err
+	}
+
+	return encoding.WriteTo(w, q.Port, q.Queue,
+		uint16(buf.Len()+queueDescLen), pad6{}, buf.Bytes())
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// queue description from the wire format.
+func (q *QueueDesc) ReadFrom(r io.Reader) (int64, error) {
+	var length uint16
+	n, err := encoding.ReadFrom(r, &q.Port, &q.Queue,
+		&length, &defaultPad6)
+
+	if err != nil {
+		return n, err
+	}
+
+	if length < queueDescLen {
+		return n, fmt.Errorf("ofp: invalid queue description length: %d",
+			length)
+	}
+
+	limrd := encoding.LimitReader(r, int64(length-queueDescLen))
+	q.Properties = nil
+
+	nn, err := q.Properties.ReadFrom(limrd)
 	return n + nn, err
 }
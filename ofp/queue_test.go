@@ -1,9 +1,13 @@
 package ofp
 
 import (
+	"bytes"
 	"encoding/gob"
+	"io"
+	"reflect"
 	"testing"
 
+	"github.com/netrack/openflow/internal/encoding"
 	"github.com/netrack/openflow/internal/encodingtest"
 )
 
@@ -63,6 +67,104 @@ func TestQueuePropExperimenter(t *testing.T) {
 	encodingtest.RunMU(t, tests)
 }
 
+func TestQueuePropExperimenterTrailing(t *testing.T) {
+	tests := []encodingtest.MU{
+		{ReadWriter: &PacketQueue{
+			Queue: 1,
+			Port:  2,
+			Properties: QueueProps{
+				&QueuePropExperimenter{
+					Experimenter: 359,
+					Data:         []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+				},
+				&QueuePropMinRate{42},
+			},
+		}, Bytes: []byte{
+			0x00, 0x00, 0x00, 0x01, // Queue.
+			0x00, 0x00, 0x00, 0x02, // Port number.
+			0x00, 0x38, // Length.
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 6-byte padding.
+
+			0xff, 0xff, // Queue property experimenter.
+			0x00, 0x18, // Queue property length.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+			0x00, 0x00, 0x01, 0x67, // Experimenter.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+			0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, // Data.
+
+			0x00, 0x01, // Queue property min rate.
+			0x00, 0x10, // Queue property length.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+			0x00, 0x2a, // Rate.
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 6-byte padding.
+		}},
+	}
+
+	gob.Register(QueuePropExperimenter{})
+	gob.Register(QueuePropMinRate{})
+	encodingtest.RunMU(t, tests)
+}
+
+func TestQueuePropLength(t *testing.T) {
+	tests := []struct {
+		ReadWriter interface {
+			ReadFrom(r io.Reader) (int64, error)
+		}
+		Bytes []byte
+	}{
+		// Length of the experimenter property is shorter than the
+		// experimenter header.
+		{&QueuePropExperimenter{}, []byte{
+			0xff, 0xff, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x01, 0x67, 0x00, 0x00, 0x00, 0x00,
+		}},
+		// Data of the experimenter property is truncated.
+		{&QueuePropExperimenter{}, []byte{
+			0xff, 0xff, 0x00, 0x18, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x01, 0x67, 0x00, 0x00, 0x00, 0x00,
+			0x01, 0x02,
+		}},
+		{&QueuePropMinRate{}, []byte{
+			0x00, 0x01, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x2a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		}},
+		{&PacketQueue{}, []byte{
+			0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02,
+			0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		}},
+		{&QueueDescPropMaxRate{}, []byte{
+			0x00, 0x02, 0x00, 0x04, 0x00, 0x2a, 0x00, 0x00,
+		}},
+	}
+
+	for _, test := range tests {
+		_, err := test.ReadWriter.ReadFrom(bytes.NewReader(test.Bytes))
+		if err == nil {
+			t.Errorf("Error expected for %T: % x", test.ReadWriter, test.Bytes)
+		}
+	}
+
+	// The bytes exceeding the length of the rate property must be
+	// skipped, so the next property is decoded correctly.
+	var props QueueProps
+	_, err := props.ReadFrom(bytes.NewReader([]byte{
+		0x00, 0x01, 0x00, 0x18, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x2a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x02, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x2b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}))
+
+	if err != nil {
+		t.Fatalf("Failed to read queue properties: %s", err)
+	}
+
+	expected := QueueProps{&QueuePropMinRate{42}, &QueuePropMaxRate{43}}
+	if !reflect.DeepEqual(props, expected) {
+		t.Errorf("Invalid queue properties: %v", props)
+	}
+}
+
 func TestQueueStastsRequest(t *testing.T) {
 	tests := []encodingtest.MU{
 		{ReadWriter: &QueueStatsRequest{
@@ -176,3 +278,196 @@ func TestQueueGetConfigReply(t *testing.T) {
 
 	encodingtest.RunMU(t, tests)
 }
+
+func TestQueueDescRequest(t *testing.T) {
+	tests := []encodingtest.MU{
+		{ReadWriter: &QueueDescRequest{
+			Port:  PortAny,
+			Queue: Queue(7),
+		}, Bytes: []byte{
+			0xff, 0xff, 0xff, 0xff, // Port number.
+			0x00, 0x00, 0x00, 0x07, // Queue number.
+		}},
+	}
+
+	encodingtest.RunMU(t, tests)
+}
+
+func TestQueueDesc(t *testing.T) {
+	props := QueueDescProps{
+		&QueueDescPropMinRate{42},
+		&QueueDescPropMaxRate{43},
+	}
+
+	tests := []encodingtest.MU{
+		{ReadWriter: &QueueDesc{
+			Port:       PortNo(3),
+			Queue:      Queue(1),
+			Properties: props,
+		}, Bytes: []byte{
+			0x00, 0x00, 0x00, 0x03, // Port number.
+			0x00, 0x00, 0x00, 0x01, // Queue.
+			0x00, 0x20, // Length.
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 6-byte padding.
+
+			// Properties
+			0x00, 0x01, // Queue property min rate.
+			0x00, 0x08, // Queue property length.
+			0x00, 0x2a, // Rate.
+			0x00, 0x00, // 2-byte padding.
+
+			0x00, 0x02, // Queue property max rate.
+			0x00, 0x08, // Queue property length.
+			0x00, 0x2b, // Rate.
+			0x00, 0x00, // 2-byte padding.
+		}},
+	}
+
+	gob.Register(QueueDescPropMinRate{})
+	gob.Register(QueueDescPropMaxRate{})
+	encodingtest.RunMU(t, tests)
+}
+
+func TestQueueDescStrict(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x00, 0x03, // Port number.
+		0x00, 0x00, 0x00, 0x01, // Queue.
+		0x00, 0x18, // Length.
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 6-byte padding.
+
+		0x00, 0x10, // Queue property type.
+		0x00, 0x08, // Queue property length.
+		0x00, 0x00, 0x00, 0x00, // Property data.
+	}
+
+	var desc QueueDesc
+	if _, err := desc.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Failed to read queue description: %s", err)
+	}
+
+	// The check mode of the reader must reach the properties.
+	rd := NewCheckReader(bytes.NewReader(data), CheckStrict)
+	if _, err := desc.ReadFrom(rd); err == nil {
+		t.Fatalf("Unknown property must be rejected in strict mode")
+	}
+}
+
+func TestQueueDescPropsInvalidReader(t *testing.T) {
+	rm := queueDescPropTypeMap[QueueDescPropTypeMinRate]
+	defer func() { queueDescPropTypeMap[QueueDescPropTypeMinRate] = rm }()
+
+	queueDescPropTypeMap[QueueDescPropTypeMinRate] = encoding.ReaderMakerFunc(
+		func() (io.ReaderFrom, error) { return new(QueuePropMinRate), nil })
+
+	data := []byte{
+		0x00, 0x01, // Queue property type.
+		0x00, 0x08, // Queue property length.
+		0x00, 0x2a, // Rate.
+		0x00, 0x00, // 2-byte padding.
+	}
+
+	var props QueueDescProps
+	if _, err := props.ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Fatalf("Invalid property reader must be rejected")
+	}
+}
+
+func TestQueueDescPropExperimenter(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x02}
+
+	tests := []encodingtest.MU{
+		{ReadWriter: &QueueDescPropExperimenter{
+			Experimenter: 359,
+			ExpType:      2,
+			Data:         data,
+		}, Bytes: append(append([]byte{
+			0xff, 0xff, // Queue property type.
+			0x00, 0x0f, // Queue property length.
+			0x00, 0x00, 0x01, 0x67, // Experimenter.
+			0x00, 0x00, 0x00, 0x02, // Experimenter type.
+		}, data...), 0x00)}, // 1-byte padding.
+	}
+
+	encodingtest.RunMU(t, tests)
+}
+
+func TestQueuePropRaw(t *testing.T) {
+	tests := []encodingtest.MU{
+		{ReadWriter: &QueuePropRaw{
+			PropType: 0x0010,
+			Data:     []byte{0x00, 0x00, 0x00, 0x00, 0x11, 0x22, 0x33, 0x44},
+		}, Bytes: []byte{
+			0x00, 0x10, // Queue property type.
+			0x00, 0x0c, // Queue property length.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+			0x11, 0x22, 0x33, 0x44, // Property data.
+		}},
+	}
+
+	encodingtest.RunMU(t, tests)
+}
+
+func TestQueueDescPropRaw(t *testing.T) {
+	tests := []encodingtest.MU{
+		{ReadWriter: &QueueDescPropRaw{
+			PropType: 0x0010,
+			Data:     []byte{0x11, 0x22},
+		}, Bytes: []byte{
+			0x00, 0x10, // Queue property type.
+			0x00, 0x06, // Queue property length.
+			0x11, 0x22, // Property data.
+			0x00, 0x00, // 2-byte padding.
+		}},
+	}
+
+	encodingtest.RunMU(t, tests)
+}
+
+func TestQueuePropsUnknown(t *testing.T) {
+	data := []byte{
+		0x00, 0x10, // Queue property type.
+		0x00, 0x10, // Queue property length.
+		0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // Property data.
+
+		0x00, 0x01, // Queue property type.
+		0x00, 0x10, // Queue property length.
+		0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+		0x00, 0x2a, // Minimum rate.
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 6-byte padding.
+	}
+
+	var props QueueProps
+	if _, err := props.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Failed to read queue properties: %s", err)
+	}
+
+	expected := QueueProps{
+		&QueuePropRaw{PropType: 0x0010, Data: []byte{
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03,
+			0x04, 0x05, 0x06, 0x07,
+		}},
+		&QueuePropMinRate{Rate: 42},
+	}
+
+	if !reflect.DeepEqual(props, expected) {
+		t.Fatalf("Unknown property is not preserved: %v", props)
+	}
+
+	var buf bytes.Buffer
+	if _, err := props.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write queue properties: %s", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Queue properties are not encoded losslessly: %x", buf.Bytes())
+	}
+
+	defer SetCheckMode(CheckLenient)
+	SetCheckMode(CheckStrict)
+
+	props = nil
+	if _, err := props.ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Errorf("Unknown property must be rejected in strict mode")
+	}
+}
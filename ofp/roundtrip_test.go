@@ -0,0 +1,135 @@
+package ofp
+
+import (
+	"testing"
+
+	"github.com/netrack/openflow/internal/encodingtest"
+)
+
+// TestRoundTrip ensures, that the messages captured from the switches
+// are encoded back without loss, which is required by the proxies and
+// recorders of the OpenFlow channel.
+func TestRoundTrip(t *testing.T) {
+	name := make([]byte, maxTableNameLen)
+	copy(name, "classifier")
+
+	tableFeatures := append(append([]byte{
+		0x00, 0x90, // Length.
+		0x00,                         // Table identifier.
+		0x00, 0x00, 0x00, 0x00, 0x00, // 5-byte padding.
+	}, name...),
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, // Metadata match.
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, // Metadata write.
+		0x00, 0x00, 0x00, 0x03, // Table configuration.
+		0x00, 0x0f, 0x42, 0x40, // Maximum entries.
+
+		// Instructions property with experimenter instruction.
+		0x00, 0x00, // Property type.
+		0x00, 0x10, // Property length.
+		0x00, 0x01, 0x00, 0x04, // Goto table.
+		0xff, 0xff, 0x00, 0x08, // Experimenter instruction.
+		0x00, 0x00, 0x23, 0x20, // Experimenter identifier.
+
+		// Next tables property.
+		0x00, 0x02, // Property type.
+		0x00, 0x07, // Property length.
+		0x01, 0x02, 0x03, // Next tables.
+		0x00, // 1-byte padding.
+
+		// Apply actions property with experimenter action.
+		0x00, 0x06, // Property type.
+		0x00, 0x10, // Property length.
+		0x00, 0x00, 0x00, 0x04, // Output.
+		0xff, 0xff, 0x00, 0x08, // Experimenter action.
+		0x00, 0x00, 0x23, 0x20, // Experimenter identifier.
+
+		// Match property with the lengths of the fields.
+		0x00, 0x08, // Property type.
+		0x00, 0x10, // Property length.
+		0x80, 0x00, 0x00, 0x04, // In port.
+		0x80, 0x00, 0x07, 0x0c, // Ethernet destination with mask.
+		0xff, 0xff, 0x02, 0x08, // Experimenter field.
+
+		// Unknown property.
+		0x00, 0x20, // Property type.
+		0x00, 0x06, // Property length.
+		0xaa, 0xbb, // Property data.
+		0x00, 0x00, // 2-byte padding.
+
+		// Experimenter property.
+		0xff, 0xfe, // Property type.
+		0x00, 0x0e, // Property length.
+		0x00, 0x00, 0x23, 0x20, // Experimenter.
+		0x00, 0x00, 0x00, 0x01, // Experimenter type.
+		0x11, 0x22, // Experimenter data.
+		0x00, 0x00, // 2-byte padding.
+	)
+
+	tests := []encodingtest.R{
+		{ReadWriter: new(PacketIn), Bytes: []byte{
+			0xff, 0xff, 0xff, 0xff, // Buffer identifier.
+			0x00, 0x0e, // Total length.
+			0x00,                                           // Reason.
+			0x00,                                           // Table identifier.
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Cookie.
+
+			// Match.
+			0x00, 0x01, // Match type.
+			0x00, 0x0c, // Match length.
+			0x80, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, // In port.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+
+			0x00, 0x00, // 2-byte padding.
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // Destination.
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // Source.
+			0x08, 0x06, // Ethernet type.
+		}},
+		{ReadWriter: new(FlowMod), Bytes: []byte{
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a, // Cookie.
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Cookie mask.
+			0x01,       // Table identifier.
+			0x00,       // Command.
+			0x00, 0x0a, // Idle timeout.
+			0x00, 0x00, // Hard timeout.
+			0x80, 0x00, // Priority.
+			0xff, 0xff, 0xff, 0xff, // Buffer identifier.
+			0xff, 0xff, 0xff, 0xff, // Output port.
+			0xff, 0xff, 0xff, 0xff, // Output group.
+			0x00, 0x01, // Flags.
+			0x00, 0x00, // 2-byte padding.
+
+			// Match.
+			0x00, 0x01, // Match type.
+			0x00, 0x26, // Match length.
+			0x80, 0x00, 0x0a, 0x02, 0x08, 0x00, // Ethernet type.
+			0x80, 0x00, 0x07, 0x0c, // Ethernet destination with mask.
+			0x01, 0x00, 0x5e, 0x00, 0x00, 0x00,
+			0xff, 0xff, 0xff, 0x80, 0x00, 0x00,
+			0xff, 0xff, 0x02, 0x08, // Experimenter field.
+			0x00, 0x00, 0x23, 0x20, 0x00, 0x00, 0x00, 0x07,
+			0x00, 0x00, // 2-byte padding.
+
+			// Apply actions instruction.
+			0x00, 0x04, // Instruction type.
+			0x00, 0x18, // Instruction length.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+			0x00, 0x00, // Action type.
+			0x00, 0x10, // Action length.
+			0xff, 0xff, 0xff, 0xfd, // Port.
+			0xff, 0xff, // Maximum length.
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 6-byte padding.
+		}},
+		{ReadWriter: new(Match), Bytes: []byte{
+			0x00, 0x01, // Match type.
+			0x00, 0x04, // Match length.
+			0xde, 0xad, 0xbe, 0xef, // Non-zero padding.
+		}, Normalized: []byte{
+			0x00, 0x01, // Match type.
+			0x00, 0x04, // Match length.
+			0x00, 0x00, 0x00, 0x00, // 4-byte padding.
+		}},
+		{ReadWriter: new(TableFeatures), Bytes: tableFeatures},
+	}
+
+	encodingtest.RunR(t, tests)
+}
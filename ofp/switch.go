@@ -2,6 +2,7 @@ package ofp
 
 import (
 	"io"
+	"strings"
 
 	"github.com/netrack/openflow/internal/encoding"
 )
@@ -43,6 +44,35 @@ const (
 	CapabilityPortBlocked Capability = 1 << 8
 )
 
+var capabilityText = []struct {
+	mask Capability
+	text string
+}{
+	{CapabilityFlowStats, "flow stats"},
+	{CapabilityTableStats, "table stats"},
+	{CapabilityPortStats, "port stats"},
+	{CapabilityGroupStats, "group stats"},
+	{CapabilityIPReasm, "ip reasm"},
+	{CapabilityQueueStats, "queue stats"},
+	{CapabilityPortBlocked, "port blocked"},
+}
+
+// String returns the human-readable representation of the switch
+// capabilities bitmap.
+func (c Capability) String() string {
+	var capabilities []string
+
+	// Iterate though all of the capabilities and check if the
+	// respective bit is set.
+	for _, capability := range capabilityText {
+		if capability.mask&c != 0 {
+			capabilities = append(capabilities, capability.text)
+		}
+	}
+
+	return strings.Join(capabilities, " ")
+}
+
 // ConfigFlag represents a switch configuration flags.
 type ConfigFlag uint16
 
@@ -76,7 +106,7 @@ const (
 type SwitchFeatures struct {
 	// Datapath unique ID. The lower 48-bits are for a MAC address,
 	// while the upper 16-bits are implementer-defined.
-	DatapathID uint64
+	DatapathID DatapathID
 
 	// NumBuffers is a number of max packets buffered at once.
 	NumBuffers uint32
@@ -97,6 +127,10 @@ type SwitchFeatures struct {
 // WriteTo implements io.WriterTo interface. It serializes the switch
 // features into the wire format.
 func (s *SwitchFeatures) WriteTo(w io.Writer) (int64, error) {
+	if err := s.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
 	return encoding.WriteTo(w,
 		s.DatapathID, s.NumBuffers, s.NumTables, s.AuxiliaryID,
 		pad2{}, s.Capabilities, s.Reserved)
@@ -105,9 +139,20 @@ func (s *SwitchFeatures) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // switch features from the wire format.
 func (s *SwitchFeatures) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r,
+	n, err := encoding.ReadFrom(r,
 		&s.DatapathID, &s.NumBuffers, &s.NumTables, &s.AuxiliaryID,
 		&defaultPad2, &s.Capabilities, &s.Reserved)
+	if err != nil {
+		return n, err
+	}
+
+	return n, s.checkReserved(readerMode(r))
+}
+
+// checkReserved validates the reserved field of the switch features in strict mode.
+func (s *SwitchFeatures) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "switch features reserved",
+		s.Reserved == 0, s.Reserved)
 }
 
 // SwitchConfig is a message used as a response to the controller
@@ -6,6 +6,21 @@ import (
 	"github.com/netrack/openflow/internal/encodingtest"
 )
 
+func TestCapabilityString(t *testing.T) {
+	caps := map[Capability]string{
+		CapabilityFlowStats | CapabilityPortStats: "flow stats port stats",
+		CapabilityIPReasm | CapabilityPortBlocked: "ip reasm port blocked",
+		Capability(0): "",
+	}
+
+	for capability, text := range caps {
+		if capability.String() != text {
+			t.Errorf("Invalid capabilities, expected:\n"+
+				"`%s` got:\n`%s`", text, capability.String())
+		}
+	}
+}
+
 func TestSwitchFeatures(t *testing.T) {
 	caps := CapabilityFlowStats | CapabilityPortStats
 
@@ -2,6 +2,7 @@ package ofp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -56,13 +57,28 @@ type TableMod struct {
 // WriteTo implements io.WriterTo interface. It serializes the table
 // modification message into the wire format.
 func (t *TableMod) WriteTo(w io.Writer) (int64, error) {
+	if err := t.checkReserved(CurrentCheckMode()); err != nil {
+		return 0, err
+	}
+
 	return encoding.WriteTo(w, t.Table, pad3{}, t.Config)
 }
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // table modification message from the wire format.
 func (t *TableMod) ReadFrom(r io.Reader) (int64, error) {
-	return encoding.ReadFrom(r, &t.Table, &pad3{}, &t.Config)
+	n, err := encoding.ReadFrom(r, &t.Table, &pad3{}, &t.Config)
+	if err != nil {
+		return n, err
+	}
+
+	return n, t.checkReserved(readerMode(r))
+}
+
+// checkReserved validates the table configuration bits in strict mode.
+func (t *TableMod) checkReserved(mode CheckMode) error {
+	return checkReserved(mode, "table config",
+		t.Config&^TableConfigDeprecatedMask == 0, t.Config)
 }
 
 // TableStats defines a multipart request body used to query information
@@ -137,10 +153,14 @@ func (t *TableFeatures) WriteTo(w io.Writer) (int64, error) {
 	var buf bytes.Buffer
 
 	for _, prop := range t.Properties {
-		_, err := prop.WriteTo(&buf)
+		n, err := prop.WriteTo(&buf)
 		if err != nil {
 			return 0, err
 		}
+
+		if err = checkAligned(CurrentCheckMode(), prop.Type(), n); err != nil {
+			return 0, err
+		}
 	}
 
 	// Copy the table name into the fixed-length slice.
@@ -171,21 +191,26 @@ func (t *TableFeatures) ReadFrom(r io.Reader) (int64, error) {
 	t.Name = string(name[:])
 	t.Properties = nil
 
-	var tablePropType TablePropType
-
-	rm := func() (io.ReaderFrom, error) {
-		if rm, ok := tablePropMap[tablePropType]; ok {
+	rm := func(tablePropType TablePropType) (io.ReaderFrom, error) {
+		if rm, ok := tablePropTable.Lookup(tablePropType); ok {
 			rd, err := rm.MakeReader()
 			t.Properties = append(t.Properties, rd.(TableProp))
 			return rd, err
 		}
 
-		return nil, fmt.Errorf("ofp: unknown table property type: %s", tablePropType)
+		if readerMode(r) == CheckStrict {
+			return nil, fmt.Errorf("ofp: unknown table property type: %s", tablePropType)
+		}
+
+		// Preserve the unknown property, so the features could
+		// be encoded back without loss.
+		rd := &TablePropRaw{}
+		t.Properties = append(t.Properties, rd)
+		return rd, nil
 	}
 
-	limrd := io.LimitReader(r, int64(length)-n)
-	nn, err := encoding.ScanFrom(limrd, &tablePropType,
-		encoding.ReaderMakerFunc(rm))
+	limrd := encoding.LimitReader(r, int64(length)-n)
+	nn, err := encoding.ScanFrom(limrd, rm)
 
 	return n + nn, err
 }
@@ -236,10 +261,10 @@ const (
 	TablePropTypeMatch
 
 	// TablePropTypeWildcards indicates wildcards property.
-	TablePropTypeWildcards = 1 + iota
+	TablePropTypeWildcards TablePropType = 1 + iota
 
 	// TablePropTypeWriteSetField indicates write set-field property.
-	TablePropTypeWriteSetField = 2 + iota
+	TablePropTypeWriteSetField TablePropType = 2 + iota
 
 	// TablePropTypeWriteSetFieldMiss indicates write set-field property
 	// for table-miss.
@@ -280,22 +305,34 @@ var tablePropTypeText = map[TablePropType]string{
 }
 
 var tablePropMap = map[TablePropType]encoding.ReaderMaker{
-	TablePropTypeInstructions:      encoding.ReaderMakerOf(TablePropInstructions{}),
-	TablePropTypeInstructionsMiss:  encoding.ReaderMakerOf(TablePropInstructions{}),
-	TablePropTypeNextTables:        encoding.ReaderMakerOf(TablePropNextTables{}),
-	TablePropTypeNextTablesMiss:    encoding.ReaderMakerOf(TablePropNextTables{}),
-	TablePropTypeWriteActions:      encoding.ReaderMakerOf(TablePropWriteActions{}),
-	TablePropTypeWriteActionsMiss:  encoding.ReaderMakerOf(TablePropWriteActions{}),
-	TablePropTypeApplyActions:      encoding.ReaderMakerOf(TablePropApplyActions{}),
-	TablePropTypeApplyActionsMiss:  encoding.ReaderMakerOf(TablePropApplyActions{}),
-	TablePropTypeMatch:             encoding.ReaderMakerOf(TablePropMatch{}),
-	TablePropTypeWildcards:         encoding.ReaderMakerOf(TablePropWildcards{}),
-	TablePropTypeWriteSetField:     encoding.ReaderMakerOf(TablePropWriteSetField{}),
-	TablePropTypeWriteSetFieldMiss: encoding.ReaderMakerOf(TablePropWriteSetField{}),
-	TablePropTypeApplySetField:     encoding.ReaderMakerOf(TablePropApplySetField{}),
-	TablePropTypeApplySetFieldMiss: encoding.ReaderMakerOf(TablePropApplySetField{}),
-	TablePropTypeExperimenter:      encoding.ReaderMakerOf(TablePropExperimenter{}),
-	TablePropTypeExperimenterMiss:  encoding.ReaderMakerOf(TablePropExperimenter{}),
+	TablePropTypeInstructions:      encoding.ReaderMakerOf[TablePropInstructions](),
+	TablePropTypeInstructionsMiss:  encoding.ReaderMakerOf[TablePropInstructions](),
+	TablePropTypeNextTables:        encoding.ReaderMakerOf[TablePropNextTables](),
+	TablePropTypeNextTablesMiss:    encoding.ReaderMakerOf[TablePropNextTables](),
+	TablePropTypeWriteActions:      encoding.ReaderMakerOf[TablePropWriteActions](),
+	TablePropTypeWriteActionsMiss:  encoding.ReaderMakerOf[TablePropWriteActions](),
+	TablePropTypeApplyActions:      encoding.ReaderMakerOf[TablePropApplyActions](),
+	TablePropTypeApplyActionsMiss:  encoding.ReaderMakerOf[TablePropApplyActions](),
+	TablePropTypeMatch:             encoding.ReaderMakerOf[TablePropMatch](),
+	TablePropTypeWildcards:         encoding.ReaderMakerOf[TablePropWildcards](),
+	TablePropTypeWriteSetField:     encoding.ReaderMakerOf[TablePropWriteSetField](),
+	TablePropTypeWriteSetFieldMiss: encoding.ReaderMakerOf[TablePropWriteSetField](),
+	TablePropTypeApplySetField:     encoding.ReaderMakerOf[TablePropApplySetField](),
+	TablePropTypeApplySetFieldMiss: encoding.ReaderMakerOf[TablePropApplySetField](),
+	TablePropTypeExperimenter:      encoding.ReaderMakerOf[TablePropExperimenter](),
+	TablePropTypeExperimenterMiss:  encoding.ReaderMakerOf[TablePropExperimenter](),
+}
+
+// tablePropTable is a lookup table of the table property decoders.
+var tablePropTable = encoding.NewReaderMakerTable(tablePropMap)
+
+// RegisterTableProp registers the constructor of the table property of
+// the given type, used to decode the table features from the wire
+// format. The decoder of the property of the same type is replaced. It
+// is safe to register the properties concurrently with the decoding.
+func RegisterTableProp(t TablePropType, fn func() TableProp) {
+	tablePropTable.Register(t, encoding.ReaderMakerFunc(
+		func() (io.ReaderFrom, error) { return fn(), nil }))
 }
 
 // TableProp is an interface representing OpenFlow table property.
@@ -328,7 +365,7 @@ func yieldTableProp(r io.Reader, miss *bool) (
 	}
 
 	limrdlen := int64(header.Len - tablePropLen)
-	limrd := io.LimitReader(r, limrdlen)
+	limrd := encoding.LimitReader(r, limrdlen)
 	return header, limrd, n, nil
 }
 
@@ -356,12 +393,16 @@ func writeTablePropXM(w io.Writer, tp TableProp, xms []XM) (int64, error) {
 		return n, err
 	}
 
-	nn, err := encoding.WriteSliceTo(w, xms)
-	if n += nn; err != nil {
-		return n, err
+	// The property lists only the headers of the extensible
+	// matches, the payload of the match is never written.
+	for index := range xms {
+		nn, err := xms[index].writeHeader(w)
+		if n += nn; err != nil {
+			return n, err
+		}
 	}
 
-	nn, err = encoding.WriteTo(w, makePad(headerlen))
+	nn, err := encoding.WriteTo(w, makePad(headerlen))
 	return n + nn, err
 }
 
@@ -381,43 +422,84 @@ func readTablePropXM(r io.Reader, xms *[]XM, miss *bool) (int64, error) {
 	return n + nn, err
 }
 
-func writeTablePropActions(w io.Writer, p TableProp, a []ActionType) (int64, error) {
-	nacs := len(a)
+// tablePropID is a header of the action or instruction identifier
+// listed in the table property.
+type tablePropID[T ~uint16] struct {
+	Type T
+	Len  uint16
+}
+
+// tablePropIDExperimenter is a type of the experimenter action and
+// instruction identifiers, followed by the experimenter identifier.
+const tablePropIDExperimenter = 0xffff
 
-	proplen := tablePropLen + int(actionHeaderLen)*nacs
-	header := tableProp{p.Type(), uint16(proplen)}
+// writeTablePropIDs serializes the table property with the list of
+// action or instruction identifiers. Each experimenter identifier is
+// followed by the next experimenter from the given list.
+func writeTablePropIDs[T ~uint16](w io.Writer, p TableProp,
+	ids []T, experimenters []uint32) (int64, error) {
+
+	var buf bytes.Buffer
+	for _, id := range ids {
+		if id != tablePropIDExperimenter || len(experimenters) == 0 {
+			encoding.WriteTo(&buf, tablePropID[T]{id, 4})
+			continue
+		}
 
-	acs := make([]action, nacs)
-	for ii, actionType := range a {
-		acs[ii] = action{actionType, actionHeaderLen}
+		encoding.WriteTo(&buf, tablePropID[T]{id, 8}, experimenters[0])
+		experimenters = experimenters[1:]
 	}
 
-	return encoding.WriteTo(w, header, acs, makePad(proplen))
+	proplen := tablePropLen + buf.Len()
+	header := tableProp{p.Type(), uint16(proplen)}
+
+	return encoding.WriteTo(w, header, buf.Bytes(), makePad(proplen))
 }
 
-// readTablePropActions reads the list of actions from the given reader
-// and appends them into the specified list of action types. The list will
-// be truncated first.
-func readTablePropActions(r io.Reader, a *[]ActionType, m *bool) (int64, error) {
-	header, limrd, n, err := yieldTableProp(r, m)
+// readTablePropIDs reads the list of action or instruction identifiers
+// from the given reader. The list will be truncated first. The lengths
+// of the identifiers are respected, so the experimenter identifiers
+// are appended into the list of experimenters.
+func readTablePropIDs[T ~uint16](r io.Reader, ids *[]T,
+	experimenters *[]uint32, miss *bool) (int64, error) {
+
+	header, limrd, n, err := yieldTableProp(r, miss)
 	if err != nil {
 		return n, err
 	}
 
-	// Truncate any data specified within a list of action types,
+	body, err := ioutil.ReadAll(limrd)
+	if n += int64(len(body)); err != nil {
+		return n, err
+	}
+
+	// Truncate any data specified within a list of identifiers,
 	// in this way, list will always contain only decoded messages.
-	*a = (*a)[:0]
-	amaker := encoding.ReaderMakerOf(action{})
+	*ids, *experimenters = (*ids)[:0], nil
 
-	// Read a list of action headers and aggregate the types.
-	join := func(r io.ReaderFrom) { *a = append(*a, r.(*action).Type) }
-	nn, err := encoding.ReadFunc(limrd, amaker, join)
+	rd := bytes.NewReader(body)
+	for rd.Len() > 0 {
+		var id tablePropID[T]
+		if _, err = encoding.ReadFrom(rd, &id); err != nil {
+			return n, err
+		}
 
-	if n += nn; err != nil {
-		return n, err
+		if id.Len < 4 || int(id.Len-4) > rd.Len() {
+			return n, fmt.Errorf("ofp: invalid length of the %s "+
+				"identifier: %d", header.Type, id.Len)
+		}
+
+		data := make([]byte, id.Len-4)
+		rd.Read(data)
+
+		*ids = append(*ids, id.Type)
+		if id.Type == tablePropIDExperimenter && len(data) >= 4 {
+			*experimenters = append(*experimenters,
+				binary.BigEndian.Uint32(data))
+		}
 	}
 
-	nn, err = encoding.ReadFrom(r, makePad(int(header.Len)))
+	nn, err := encoding.ReadFrom(r, makePad(int(header.Len)))
 	return n + nn, err
 }
 
@@ -429,6 +511,10 @@ type TablePropInstructions struct {
 	// Instructions specifies a list of instructions supported by the
 	// table.
 	Instructions []InstructionType
+
+	// Experimenters is a list of the experimenter identifiers of the
+	// experimenter instructions in the order of the instructions.
+	Experimenters []uint32
 }
 
 // String returns a string representation of instructions table property.
@@ -448,51 +534,13 @@ func (t *TablePropInstructions) Type() TablePropType {
 // WriteTo implements io.WriterTo interface. It serializes the table
 // instruction property into the wire format.
 func (t *TablePropInstructions) WriteTo(w io.Writer) (int64, error) {
-	nit := len(t.Instructions)
-	proplen := tablePropLen + int(instructionHeaderLen)*nit
-	header := tableProp{t.Type(), uint16(proplen)}
-
-	its := make([]instruction, nit)
-	for ii, it := range t.Instructions {
-		its[ii] = instruction{it, instructionHeaderLen}
-	}
-
-	nn, err := encoding.WriteTo(w, header, its)
-	if err != nil {
-		return nn, err
-	}
-
-	n, err := encoding.WriteTo(w, makePad(int(header.Len)))
-	return n + nn, err
+	return writeTablePropIDs(w, t, t.Instructions, t.Experimenters)
 }
 
 // ReadFrom implements io.ReaderFrom interface. It serializes the table
 // instruction property from the wire format.
 func (t *TablePropInstructions) ReadFrom(r io.Reader) (int64, error) {
-	header, limrd, n, err := yieldTableProp(r, &t.Miss)
-	if err != nil {
-		return n, err
-	}
-
-	// Truncate the list of instructions, but leave the underlying
-	// allocated memory, thereby decoded instructions will be saved
-	// without memory allocation overhead.
-	t.Instructions = t.Instructions[:0]
-
-	maker := encoding.ReaderMakerOf(instruction{})
-	join := func(r io.ReaderFrom) {
-		t.Instructions = append(
-			t.Instructions, r.(*instruction).Type)
-	}
-
-	// Read slice of instructions and append them to the slice.
-	nn, err := encoding.ReadFunc(limrd, maker, join)
-	if n += nn; err != nil {
-		return n, err
-	}
-
-	nn, err = encoding.ReadFrom(r, makePad(int(header.Len)))
-	return n + nn, err
+	return readTablePropIDs(r, &t.Instructions, &t.Experimenters, &t.Miss)
 }
 
 // TablePropNextTables defines the table next table property.
@@ -552,6 +600,10 @@ type TablePropWriteActions struct {
 
 	// Actions is a list of actions for the feature.
 	Actions []ActionType
+
+	// Experimenters is a list of the experimenter identifiers of the
+	// experimenter actions in the order of the actions.
+	Experimenters []uint32
 }
 
 // String returns a string representation of write actions table property.
@@ -571,13 +623,13 @@ func (t *TablePropWriteActions) Type() TablePropType {
 // WriteTo implements io.WriterTo interface. It serializes the write
 // actions property into the wire format.
 func (t *TablePropWriteActions) WriteTo(w io.Writer) (int64, error) {
-	return writeTablePropActions(w, t, t.Actions)
+	return writeTablePropIDs(w, t, t.Actions, t.Experimenters)
 }
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // write actions property from the wire format.
 func (t *TablePropWriteActions) ReadFrom(r io.Reader) (int64, error) {
-	return readTablePropActions(r, &t.Actions, &t.Miss)
+	return readTablePropIDs(r, &t.Actions, &t.Experimenters, &t.Miss)
 }
 
 // TablePropApplyActions defines the apply actions property of the
@@ -588,6 +640,10 @@ type TablePropApplyActions struct {
 
 	// Actions is a list of actions for the feature.
 	Actions []ActionType
+
+	// Experimenters is a list of the experimenter identifiers of the
+	// experimenter actions in the order of the actions.
+	Experimenters []uint32
 }
 
 // String returns a string representation of apply actions table property.
@@ -607,13 +663,13 @@ func (t *TablePropApplyActions) Type() TablePropType {
 // WriteTo implements io.WriterTo interface. It serializes the
 // apply actions property into the wire format.
 func (t *TablePropApplyActions) WriteTo(w io.Writer) (int64, error) {
-	return writeTablePropActions(w, t, t.Actions)
+	return writeTablePropIDs(w, t, t.Actions, t.Experimenters)
 }
 
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // apply actions property from the wire format.
 func (t *TablePropApplyActions) ReadFrom(r io.Reader) (int64, error) {
-	return readTablePropActions(r, &t.Actions, &t.Miss)
+	return readTablePropIDs(r, &t.Actions, &t.Experimenters, &t.Miss)
 }
 
 // TablePropMatch  defines the match property of the table.
@@ -767,14 +823,67 @@ func (t *TablePropExperimenter) WriteTo(w io.Writer) (int64, error) {
 // ReadFrom implements io.ReaderFrom interface. It deserializes the
 // experimenter property from the wire format.
 func (t *TablePropExperimenter) ReadFrom(r io.Reader) (int64, error) {
-	var header tableProp
+	header, _, n, err := yieldTableProp(r, &t.Miss)
+	if err != nil {
+		return n, err
+	}
+
+	nn, err := encoding.ReadFrom(r, &t.Experimenter, &t.ExpType)
+	if n += nn; err != nil {
+		return n, err
+	}
+
+	limrd := encoding.LimitReader(r, int64(header.Len-tablePropLen-8))
+	t.Data, err = ioutil.ReadAll(limrd)
+	n += int64(len(t.Data))
+
+	if err != nil {
+		return n, err
+	}
+
+	padding := makePad(int(header.Len))
+	nn, err = encoding.ReadFrom(r, padding)
+
+	return n + nn, err
+}
+
+// TablePropRaw is a table property of the type unknown to the library.
+// In the lenient check mode the unknown properties are decoded into the
+// raw property, so the table features could be encoded back without loss,
+// for example by the proxies between the controller and the switch.
+type TablePropRaw struct {
+	// PropType is a type of the table property.
+	PropType TablePropType
+
+	// Data is a body of the property following the header, without
+	// the trailing padding.
+	Data []byte
+}
+
+// Type implements TableProp interface. It returns the type of the
+// table property.
+func (t *TablePropRaw) Type() TablePropType {
+	return t.PropType
+}
 
-	n, err := encoding.ReadFrom(r, &header, &t.Experimenter, &t.ExpType)
+// WriteTo implements io.WriterTo interface. It serializes the raw
+// table property into the wire format.
+func (t *TablePropRaw) WriteTo(w io.Writer) (int64, error) {
+	header := tableProp{t.PropType, uint16(tablePropLen + len(t.Data))}
+	padding := makePad(int(header.Len))
+
+	return encoding.WriteTo(w, header, t.Data, padding)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// raw table property from the wire format.
+func (t *TablePropRaw) ReadFrom(r io.Reader) (int64, error) {
+	header, limrd, n, err := yieldTableProp(r, nil)
 	if err != nil {
 		return n, err
 	}
 
-	limrd := io.LimitReader(r, int64(header.Len-tablePropLen-8))
+	t.PropType = header.Type
 	t.Data, err = ioutil.ReadAll(limrd)
 	n += int64(len(t.Data))
 
@@ -782,7 +891,7 @@ func (t *TablePropExperimenter) ReadFrom(r io.Reader) (int64, error) {
 		return n, err
 	}
 
-	padding := make([]byte, (header.Len+7)/8*8-header.Len)
+	padding := makePad(int(header.Len))
 	nn, err := encoding.ReadFrom(r, padding)
 
 	return n + nn, err
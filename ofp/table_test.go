@@ -1,7 +1,9 @@
 package ofp
 
 import (
+	"bytes"
 	"encoding/gob"
+	"reflect"
 	"testing"
 
 	"github.com/netrack/openflow/internal/encodingtest"
@@ -222,6 +224,98 @@ func TestTablePropExperimenter(t *testing.T) {
 	encodingtest.RunMU(t, tests)
 }
 
+func TestTablePropRaw(t *testing.T) {
+	tests := []encodingtest.MU{
+		{ReadWriter: &TablePropRaw{
+			PropType: 0x0020,
+			Data:     []byte{0x11, 0x22, 0x33},
+		}, Bytes: []byte{
+			0x00, 0x20, // Property type.
+			0x00, 0x07, // Property length.
+			0x11, 0x22, 0x33, // Property data.
+
+			// Alignment.
+			0x00,
+		}},
+	}
+
+	encodingtest.RunMU(t, tests)
+}
+
+func TestTableFeaturesUnknownProp(t *testing.T) {
+	features := TableFeatures{Table: 1, Properties: []TableProp{
+		&TablePropRaw{PropType: 0x0020, Data: []byte{0x11, 0x22}},
+		&TablePropNextTables{NextTables: []Table{2}},
+	}}
+
+	var buf bytes.Buffer
+	if _, err := features.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write table features: %s", err)
+	}
+
+	data := buf.Bytes()
+
+	var decoded TableFeatures
+	if _, err := decoded.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Failed to read table features: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded.Properties, features.Properties) {
+		t.Fatalf("Unknown property is not preserved: %v", decoded.Properties)
+	}
+
+	buf.Reset()
+	if _, err := decoded.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write decoded table features: %s", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Table features are not encoded losslessly: %x", buf.Bytes())
+	}
+
+	defer SetCheckMode(CheckLenient)
+	SetCheckMode(CheckStrict)
+
+	if _, err := decoded.ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Errorf("Unknown property must be rejected in strict mode")
+	}
+}
+
+func TestTablePropType(t *testing.T) {
+	for ptype, rm := range tablePropMap {
+		rd, err := rm.MakeReader()
+		if err != nil {
+			t.Fatalf("Failed to create property %s: %s", ptype, err)
+		}
+
+		prop := rd.(TableProp)
+
+		// Set the miss flag for properties of the table-miss type,
+		// properties without the flag have the single type.
+		if miss := reflect.ValueOf(prop).Elem().FieldByName("Miss"); miss.IsValid() {
+			miss.SetBool(ptype&1 == 1)
+		}
+
+		if prop.Type() != ptype {
+			t.Fatalf("Invalid type of property %s: %s", ptype, prop.Type())
+		}
+
+		var buf bytes.Buffer
+		if _, err = prop.WriteTo(&buf); err != nil {
+			t.Fatalf("Failed to write property %s: %s", ptype, err)
+		}
+
+		rd, _ = rm.MakeReader()
+		if _, err = rd.ReadFrom(&buf); err != nil {
+			t.Fatalf("Failed to read property %s: %s", ptype, err)
+		}
+
+		if decoded := rd.(TableProp).Type(); decoded != ptype {
+			t.Errorf("Decoded property %s has type %s", ptype, decoded)
+		}
+	}
+}
+
 func TestTableFeatures(t *testing.T) {
 	name := make([]byte, maxTableNameLen)
 	copy(name, []byte("table-1"))
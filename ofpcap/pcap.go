@@ -0,0 +1,241 @@
+package ofpcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/netrack/openflow/ofpconst"
+)
+
+var (
+	// ErrFormat is returned when the file is not in the pcap format.
+	ErrFormat = errors.New("ofpcap: invalid pcap file format")
+
+	// ErrLinkType is returned when the link type of the captured
+	// packets is not supported.
+	ErrLinkType = errors.New("ofpcap: unsupported link type")
+)
+
+const (
+	// Magic numbers of the pcap file with microsecond and nanosecond
+	// timestamp resolution.
+	magicMicroseconds = 0xa1b2c3d4
+	magicNanoseconds  = 0xa1b23c4d
+
+	// Supported link types of the captured packets.
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+
+	// maxCaplen is a maximum length of the captured packet data,
+	// it matches the default snapshot length of the tcpdump.
+	maxCaplen = 262144
+
+	// TCP flags used for stream reassembly.
+	tcpFlagFIN = 0x01
+	tcpFlagSYN = 0x02
+	tcpFlagRST = 0x04
+)
+
+// packet is a captured packet.
+type packet struct {
+	data []byte
+	time time.Time
+}
+
+// pcapReader reads the packets from the pcap file.
+type pcapReader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	nanos bool
+	link  uint32
+
+	// snaplen is a maximum length of the captured packet data
+	// defined in the global header of the file.
+	snaplen uint32
+}
+
+// newPcapReader reads the global header of the pcap file.
+func newPcapReader(r io.Reader) (*pcapReader, error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrFormat
+	}
+
+	pr := &pcapReader{r: r}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header) {
+		case magicMicroseconds:
+			pr.order = order
+		case magicNanoseconds:
+			pr.order, pr.nanos = order, true
+		}
+	}
+
+	if pr.order == nil {
+		return nil, ErrFormat
+	}
+
+	pr.snaplen = pr.order.Uint32(header[16:])
+	pr.link = pr.order.Uint32(header[20:])
+
+	switch pr.link {
+	case linkTypeNull, linkTypeEthernet, linkTypeRaw, linkTypeLinuxSLL:
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrLinkType, pr.link)
+	}
+
+	return pr, nil
+}
+
+// next reads the next captured packet from the file.
+func (pr *pcapReader) next() (*packet, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(pr.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = ErrFormat
+		}
+		return nil, err
+	}
+
+	sec := pr.order.Uint32(header)
+	frac := pr.order.Uint32(header[4:])
+	caplen := pr.order.Uint32(header[8:])
+
+	if !pr.nanos {
+		frac *= 1000
+	}
+
+	// The length of the captured data is controlled by the file,
+	// therefore it must be validated before the memory is allocated.
+	if caplen > pr.snaplen || caplen > maxCaplen {
+		return nil, ErrFormat
+	}
+
+	data := make([]byte, caplen)
+	if _, err := io.ReadFull(pr.r, data); err != nil {
+		return nil, ErrFormat
+	}
+
+	return &packet{data, time.Unix(int64(sec), int64(frac))}, nil
+}
+
+// segment is a TCP segment extracted from the captured packet.
+type segment struct {
+	src, dst net.TCPAddr
+	seq      uint32
+	flags    uint8
+	payload  []byte
+}
+
+// decode extracts the TCP segment from the packet of the given link
+// type. When the packet does not contain TCP segment, false is returned.
+func decode(link uint32, data []byte) (*segment, bool) {
+	var etherType uint16
+
+	switch link {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil, false
+		}
+
+		etherType, data = binary.BigEndian.Uint16(data[12:]), data[14:]
+
+		// Skip the VLAN tags of the frame.
+		for etherType == ofpconst.EtherTypeVLAN || etherType == ofpconst.EtherTypeQinQ {
+			if len(data) < 4 {
+				return nil, false
+			}
+			etherType, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+		}
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:]), data[16:]
+	case linkTypeNull:
+		if len(data) < 4 {
+			return nil, false
+		}
+
+		// The address family is in the host byte order, so
+		// detect the version using the IP header instead.
+		data = data[4:]
+		fallthrough
+	case linkTypeRaw:
+		if len(data) < 1 {
+			return nil, false
+		}
+
+		etherType = ofpconst.EtherTypeIPv4
+		if data[0]>>4 == 6 {
+			etherType = ofpconst.EtherTypeIPv6
+		}
+	}
+
+	var (
+		src, dst net.IP
+		proto    uint8
+	)
+
+	switch etherType {
+	case ofpconst.EtherTypeIPv4:
+		if len(data) < 20 {
+			return nil, false
+		}
+
+		ihl := int(data[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(data[2:]))
+		if ihl < 20 || total < ihl || len(data) < total {
+			return nil, false
+		}
+
+		// Fragmented packets are not supported.
+		if binary.BigEndian.Uint16(data[6:])&0x3fff != 0 {
+			return nil, false
+		}
+
+		proto = data[9]
+		src, dst = net.IP(data[12:16]), net.IP(data[16:20])
+		data = data[ihl:total]
+	case ofpconst.EtherTypeIPv6:
+		if len(data) < 40 {
+			return nil, false
+		}
+
+		total := 40 + int(binary.BigEndian.Uint16(data[4:]))
+		if len(data) < total {
+			return nil, false
+		}
+
+		// Extension headers are not supported.
+		proto = data[6]
+		src, dst = net.IP(data[8:24]), net.IP(data[24:40])
+		data = data[40:total]
+	default:
+		return nil, false
+	}
+
+	if proto != ofpconst.IPProtoTCP || len(data) < 20 {
+		return nil, false
+	}
+
+	offset := int(data[12]>>4) * 4
+	if offset < 20 || len(data) < offset {
+		return nil, false
+	}
+
+	return &segment{
+		src:     net.TCPAddr{IP: src, Port: int(binary.BigEndian.Uint16(data))},
+		dst:     net.TCPAddr{IP: dst, Port: int(binary.BigEndian.Uint16(data[2:]))},
+		seq:     binary.BigEndian.Uint32(data[4:]),
+		flags:   data[13],
+		payload: data[offset:],
+	}, true
+}
@@ -0,0 +1,261 @@
+// Package ofpcap implements offline decoding of the OpenFlow messages
+// captured into the pcap files.
+package ofpcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	of "github.com/netrack/openflow"
+)
+
+// Message is an OpenFlow message extracted from the captured TCP stream.
+type Message struct {
+	// Time is a capture time of the packet completing the message.
+	Time time.Time
+
+	// Src and Dst are the source and destination addresses of the
+	// TCP stream carrying the message.
+	Src, Dst *net.TCPAddr
+
+	// Request is a decoded OpenFlow message. The address of the
+	// request is set to the source address of the message.
+	Request *of.Request
+}
+
+const (
+	// maxPendingSegments and maxPendingBytes limit the segments kept
+	// out of order, when the segment filling the gap was not captured.
+	maxPendingSegments = 1024
+	maxPendingBytes    = 4 << 20
+)
+
+// stream is a unidirectional TCP stream being reassembled.
+type stream struct {
+	src, dst net.TCPAddr
+
+	// next is the sequence number of the next expected byte.
+	next   uint32
+	synced bool
+
+	// pending stores the segments received out of order, pendingBytes
+	// is the total length of their payloads.
+	pending      map[uint32][]byte
+	pendingBytes int
+
+	// buf contains the reassembled bytes of incomplete messages.
+	buf []byte
+}
+
+// push appends the payload of the segment to the stream, taking into
+// account retransmissions and out of order delivery.
+func (s *stream) push(seq uint32, payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+
+	if diff := int32(seq - s.next); diff <= 0 {
+		s.append(seq, payload)
+	} else {
+		s.pendingBytes += len(payload) - len(s.pending[seq])
+		s.pending[seq] = append([]byte(nil), payload...)
+
+		if len(s.pending) <= maxPendingSegments && s.pendingBytes <= maxPendingBytes {
+			return
+		}
+
+		// The gap is not going to be filled, so skip it.
+		s.resync()
+	}
+
+	// Append the segments that were received out of order and
+	// now continue the reassembled stream.
+	for progress := true; progress; {
+		progress = false
+
+		for seq, payload := range s.pending {
+			if int32(seq-s.next) <= 0 {
+				delete(s.pending, seq)
+				s.pendingBytes -= len(payload)
+				s.append(seq, payload)
+				progress = true
+			}
+		}
+	}
+}
+
+// resync skips the missing bytes of the stream up to the earliest
+// pending segment. The incomplete message is dropped, as the rest of
+// it is lost, and the stream continues as if the capture started in
+// the middle of it.
+func (s *stream) resync() {
+	first := true
+	for seq := range s.pending {
+		if first || int32(seq-s.next) < 0 {
+			s.next, first = seq, false
+		}
+	}
+
+	s.buf = nil
+}
+
+// append appends the payload starting at the given sequence number,
+// which is not after the next expected one.
+func (s *stream) append(seq uint32, payload []byte) {
+	// Skip the bytes that were already received.
+	overlap := int(s.next - seq)
+	if overlap >= len(payload) {
+		return
+	}
+
+	s.buf = append(s.buf, payload[overlap:]...)
+	s.next += uint32(len(payload) - overlap)
+}
+
+// messages extracts the complete OpenFlow messages from the stream.
+func (s *stream) messages() [][]byte {
+	var msgs [][]byte
+
+	for len(s.buf) >= of.HeaderLen {
+		length := int(binary.BigEndian.Uint16(s.buf[2:]))
+
+		// The stream is corrupted or the capture started in
+		// the middle of the message, so skip the data.
+		if length < of.HeaderLen {
+			s.buf = nil
+			break
+		}
+
+		if len(s.buf) < length {
+			break
+		}
+
+		msgs = append(msgs, s.buf[:length:length])
+		s.buf = s.buf[length:]
+	}
+
+	// Release the memory of the processed messages.
+	if len(s.buf) == 0 {
+		s.buf = nil
+	}
+
+	return msgs
+}
+
+// Reader extracts OpenFlow messages from the TCP streams captured into
+// the pcap file. Only the streams with the given control port (either
+// source or destination) are reassembled.
+//
+// For example, to print the types of all messages captured on the
+// default OpenFlow port, the following code could be used:
+//
+//	f, _ := os.Open("capture.pcap")
+//	r, err := ofpcap.NewReader(f, 6653)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	for {
+//		m, err := r.Next()
+//		if err != nil {
+//			break
+//		}
+//
+//		fmt.Println(m.Src, m.Dst, m.Request.Header.Type)
+//	}
+type Reader struct {
+	port    int
+	pr      *pcapReader
+	streams map[string]*stream
+	queue   []*Message
+}
+
+// NewReader creates a new reader of OpenFlow messages transmitted on
+// the given control port from the pcap file.
+func NewReader(r io.Reader, port int) (*Reader, error) {
+	pr, err := newPcapReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{
+		port:    port,
+		pr:      pr,
+		streams: make(map[string]*stream),
+	}, nil
+}
+
+// Next returns the next OpenFlow message from the capture. At the end
+// of the capture, Next returns io.EOF error.
+func (r *Reader) Next() (*Message, error) {
+	for len(r.queue) == 0 {
+		pkt, err := r.pr.next()
+		if err != nil {
+			return nil, err
+		}
+
+		r.process(pkt)
+	}
+
+	m := r.queue[0]
+	r.queue = r.queue[1:]
+	return m, nil
+}
+
+// process reassembles the TCP stream of the packet and queues decoded
+// OpenFlow messages.
+func (r *Reader) process(pkt *packet) {
+	seg, ok := decode(r.pr.link, pkt.data)
+	if !ok || (seg.src.Port != r.port && seg.dst.Port != r.port) {
+		return
+	}
+
+	key := seg.src.String() + ">" + seg.dst.String()
+	s, ok := r.streams[key]
+
+	if seg.flags&tcpFlagRST != 0 {
+		delete(r.streams, key)
+		return
+	}
+
+	if !ok {
+		s = &stream{
+			src: seg.src, dst: seg.dst,
+			pending: make(map[uint32][]byte),
+		}
+		r.streams[key] = s
+	}
+
+	switch {
+	case seg.flags&tcpFlagSYN != 0:
+		// Synchronization consumes a single sequence number.
+		s.next, s.synced, s.buf = seg.seq+1, true, nil
+		seg.seq++
+	case !s.synced:
+		// The capture started in the middle of the stream.
+		s.next, s.synced = seg.seq, true
+	}
+
+	s.push(seg.seq, seg.payload)
+
+	for _, msg := range s.messages() {
+		req := new(of.Request)
+		if _, err := req.ReadFrom(bytes.NewReader(msg)); err != nil {
+			continue
+		}
+
+		src, dst := s.src, s.dst
+		req.Addr = &src
+
+		r.queue = append(r.queue, &Message{
+			Time: pkt.time, Src: &src, Dst: &dst, Request: req,
+		})
+	}
+
+	if seg.flags&tcpFlagFIN != 0 {
+		delete(r.streams, key)
+	}
+}
@@ -0,0 +1,203 @@
+package ofpcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofpconst"
+)
+
+// capture is a builder of the pcap file with Ethernet frames.
+type capture struct {
+	bytes.Buffer
+}
+
+func newCapture() *capture {
+	c := new(capture)
+	binary.Write(c, binary.LittleEndian, []uint32{
+		magicMicroseconds, 0x00040002, 0, 0, 65535, linkTypeEthernet,
+	})
+	return c
+}
+
+// tcp appends the frame with the TCP segment between the given ports.
+func (c *capture) tcp(sport, dport uint16, seq uint32, flags uint8, payload []byte) {
+	var tcp bytes.Buffer
+	binary.Write(&tcp, binary.BigEndian, []uint16{sport, dport})
+	binary.Write(&tcp, binary.BigEndian, []uint32{seq, 0})
+	tcp.Write([]byte{0x50, flags, 0xff, 0xff, 0, 0, 0, 0})
+	tcp.Write(payload)
+
+	var ip bytes.Buffer
+	ip.Write([]byte{0x45, 0})
+	binary.Write(&ip, binary.BigEndian, uint16(20+tcp.Len()))
+	ip.Write([]byte{0, 0, 0x40, 0, 64, ofpconst.IPProtoTCP, 0, 0})
+	ip.Write(net.IPv4(10, 0, 0, 1).To4())
+	ip.Write(net.IPv4(10, 0, 0, 2).To4())
+	tcp.WriteTo(&ip)
+
+	var frame bytes.Buffer
+	frame.Write(make([]byte, 12))
+	binary.Write(&frame, binary.BigEndian, uint16(ofpconst.EtherTypeIPv4))
+	ip.WriteTo(&frame)
+
+	binary.Write(c, binary.LittleEndian, []uint32{
+		1, 0, uint32(frame.Len()), uint32(frame.Len()),
+	})
+	frame.WriteTo(c)
+}
+
+func message(t of.Type, body io.WriterTo) []byte {
+	var buf bytes.Buffer
+	of.NewRequest(t, body).WriteTo(&buf)
+	return buf.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	hello := message(of.TypeHello, nil)
+	echo := message(of.TypeEchoRequest, &ofp.EchoRequest{
+		Data: []byte{1, 2, 3, 4},
+	})
+
+	c := newCapture()
+	c.tcp(40000, 6653, 99, tcpFlagSYN, nil)
+	c.tcp(6653, 40000, 499, tcpFlagSYN, nil)
+
+	// The second part of the echo request is received first.
+	c.tcp(40000, 6653, 100+uint32(len(hello))+5, 0, echo[5:])
+	c.tcp(40000, 6653, 100, 0, hello)
+	c.tcp(6653, 40000, 500, 0, hello)
+
+	// Retransmission of the hello message and the first part of
+	// the echo request.
+	c.tcp(40000, 6653, 100, 0, append(hello, echo[:5]...))
+
+	// The traffic on the other ports must be ignored.
+	c.tcp(40000, 80, 100, 0, hello)
+
+	r, err := NewReader(c, 6653)
+	if err != nil {
+		t.Fatalf("Failed to create a reader: %s", err)
+	}
+
+	expected := []struct {
+		Type  of.Type
+		Port  int
+		Bytes []byte
+	}{
+		{of.TypeHello, 40000, hello},
+		{of.TypeHello, 6653, hello},
+		{of.TypeEchoRequest, 40000, echo},
+	}
+
+	for _, e := range expected {
+		m, err := r.Next()
+		if err != nil {
+			t.Fatalf("Failed to read the message: %s", err)
+		}
+
+		if m.Request.Header.Type != e.Type || m.Src.Port != e.Port {
+			t.Errorf("Invalid message %s from %s", m.Request.Header.Type, m.Src)
+		}
+
+		if !m.Src.IP.Equal(net.IPv4(10, 0, 0, 1)) {
+			t.Errorf("Invalid source address: %s", m.Src)
+		}
+
+		var buf bytes.Buffer
+		m.Request.WriteTo(&buf)
+
+		if !bytes.Equal(buf.Bytes(), e.Bytes) {
+			t.Errorf("Invalid message bytes:\n%x\n%x", buf.Bytes(), e.Bytes)
+		}
+	}
+
+	if _, err = r.Next(); err != io.EOF {
+		t.Fatalf("End of capture expected: %v", err)
+	}
+}
+
+func TestReaderPendingLimit(t *testing.T) {
+	hello := message(of.TypeHello, nil)
+	hlen := uint32(len(hello))
+
+	c := newCapture()
+	c.tcp(40000, 6653, 99, tcpFlagSYN, nil)
+
+	// The first part of the message is received, but the segment
+	// with the rest of it is lost, so the following segments are
+	// never appended to the stream until the limit is reached.
+	c.tcp(40000, 6653, 100, 0, hello[:4])
+
+	start := 100 + 2*hlen
+	for i := uint32(0); i <= maxPendingSegments; i++ {
+		c.tcp(40000, 6653, start+i*hlen, 0, hello)
+	}
+
+	r, err := NewReader(c, 6653)
+	if err != nil {
+		t.Fatalf("Failed to create a reader: %s", err)
+	}
+
+	var count int
+	for ; ; count++ {
+		m, err := r.Next()
+		if err != nil {
+			break
+		}
+
+		if m.Request.Header.Type != of.TypeHello {
+			t.Fatalf("Invalid message: %s", m.Request.Header.Type)
+		}
+	}
+
+	if count != maxPendingSegments+1 {
+		t.Errorf("Invalid count of messages after resync: %d", count)
+	}
+
+	for _, s := range r.streams {
+		if len(s.pending) != 0 || s.pendingBytes != 0 {
+			t.Errorf("Pending segments must be released: %d", len(s.pending))
+		}
+	}
+}
+
+func TestReaderFormat(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader(make([]byte, 24)), 6653); err != ErrFormat {
+		t.Fatalf("Invalid file format must be rejected: %v", err)
+	}
+}
+
+func TestReaderCaplen(t *testing.T) {
+	tests := []struct {
+		Snaplen uint32
+		Caplen  uint32
+	}{
+		// Captured length exceeds the snapshot length of the file.
+		{Snaplen: 65535, Caplen: 65536},
+		// Captured length exceeds the maximum length.
+		{Snaplen: 0xffffffff, Caplen: 0xfffffff0},
+	}
+
+	for _, test := range tests {
+		var c capture
+		binary.Write(&c, binary.LittleEndian, []uint32{
+			magicMicroseconds, 0x00040002, 0, 0, test.Snaplen, linkTypeEthernet,
+			1, 0, test.Caplen, test.Caplen,
+		})
+
+		r, err := NewReader(&c, 6653)
+		if err != nil {
+			t.Fatalf("Failed to create reader: %s", err)
+		}
+
+		if _, err = r.Next(); err != ErrFormat {
+			t.Errorf("Captured length %d must be rejected: %v", test.Caplen, err)
+		}
+	}
+}
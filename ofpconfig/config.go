@@ -0,0 +1,507 @@
+// Package ofpconfig loads the settings of the OpenFlow controller from
+// the JSON or YAML documents, so the deployments don't need to wire each
+// option of the server, listeners and connections in code.
+//
+// For example, the following YAML document configures the controller
+// listening on two addresses with TLS:
+//
+//	listen:
+//	  - ":6653"
+//	  - "10.0.0.1:6633"
+//	tls:
+//	  cert_file: /etc/openflow/controller.crt
+//	  key_file: /etc/openflow/controller.key
+//	  ca_file: /etc/openflow/switches.crt
+//	versions: ["1.3", "1.4"]
+//	echo_interval: 5s
+//	echo_timeout: 15s
+//	max_conns: 1024
+//
+// The package does not parse YAML itself, the application registers the
+// YAML decoder of its choice with YAMLUnmarshal.
+package ofpconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofputil"
+)
+
+var (
+	// ErrInvalidConfig is returned when the configuration does not
+	// pass the validation.
+	ErrInvalidConfig = errors.New("ofpconfig: invalid configuration")
+
+	// ErrNoYAML is returned on attempt to decode the YAML document,
+	// while YAMLUnmarshal is not set.
+	ErrNoYAML = errors.New("ofpconfig: YAML decoder is not configured")
+)
+
+// YAMLUnmarshal decodes the YAML documents. It is nil by default, so
+// the YAML documents are rejected with ErrNoYAML. The decoded document
+// is converted to JSON, so the mappings must be decoded into the
+// map[string]interface{} values, like gopkg.in/yaml.v3 does:
+//
+//	ofpconfig.YAMLUnmarshal = yaml.Unmarshal
+var YAMLUnmarshal func([]byte, interface{}) error
+
+// Format is a format of the configuration document.
+type Format int
+
+const (
+	// FormatJSON is a JSON document.
+	FormatJSON Format = iota
+
+	// FormatYAML is a YAML document.
+	FormatYAML
+)
+
+func (f Format) String() string {
+	text, ok := formatText[f]
+	if !ok {
+		return fmt.Sprintf("Format(%d)", f)
+	}
+	return text
+}
+
+var formatText = map[Format]string{
+	FormatJSON: "FormatJSON",
+	FormatYAML: "FormatYAML",
+}
+
+// FormatOf returns the format of the configuration file by the
+// extension of the file name.
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	}
+
+	return 0, fmt.Errorf("ofpconfig: unknown format of file %q", path)
+}
+
+// Duration is a time duration decoded from the string representation,
+// like "1m30s", or from the number of seconds.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("ofpconfig: invalid duration: %w", err)
+		}
+		*d = Duration(duration)
+	default:
+		return fmt.Errorf("ofpconfig: invalid duration: %s", b)
+	}
+
+	return nil
+}
+
+// Version is a version of the protocol, like "1.3". It is decoded from
+// the string or from the number, so the unquoted versions, like
+// `versions: [1.3]`, are accepted.
+type Version string
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (v *Version) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(b, []byte(`"`)) {
+		return json.Unmarshal(b, (*string)(v))
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("ofpconfig: invalid version: %s", b)
+	}
+
+	// The YAML decoders lose the fraction of the whole numbers,
+	// so the version 1.0 is decoded as 1.
+	*v = Version(n)
+	if !strings.Contains(string(n), ".") {
+		*v += ".0"
+	}
+
+	return nil
+}
+
+// TLSConfig is a configuration of the TLS listeners.
+type TLSConfig struct {
+	// CertFile is a path to the PEM encoded certificate of the
+	// controller.
+	CertFile string `json:"cert_file"`
+
+	// KeyFile is a path to the PEM encoded private key of the
+	// controller.
+	KeyFile string `json:"key_file"`
+
+	// CAFile is an optional path to the PEM encoded certificates used
+	// to verify the switches. When specified, the switches must present
+	// the certificates signed by one of them.
+	CAFile string `json:"ca_file,omitempty"`
+}
+
+// QueueConfig is a configuration of the send and receive queues of the
+// switch connections (see of.QueueConfig).
+type QueueConfig struct {
+	// MaxLen is a maximum number of requests pending to be sent.
+	MaxLen int `json:"max_len,omitempty"`
+
+	// MaxBytes is a maximum number of bytes pending to be sent.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+
+	// Policy is one of "block", "drop" or "error".
+	Policy string `json:"policy,omitempty"`
+
+	// ReceiveLen is a capacity of the receive queue.
+	ReceiveLen int `json:"receive_len,omitempty"`
+}
+
+// queuePolicies maps the names of the queue policies.
+var queuePolicies = map[string]of.QueuePolicy{
+	"":      of.QueueBlock,
+	"block": of.QueueBlock,
+	"drop":  of.QueueDrop,
+	"error": of.QueueError,
+}
+
+// versions maps the protocol versions to the wire versions.
+var versions = map[string]uint8{
+	"1.0": 1,
+	"1.1": 2,
+	"1.2": 3,
+	"1.3": 4,
+	"1.4": 5,
+	"1.5": 6,
+}
+
+// Config is a configuration of the controller.
+type Config struct {
+	// Listen is a list of the addresses to listen on.
+	Listen []string `json:"listen"`
+
+	// TLS enables TLS on all listeners, when specified.
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Versions is a list of the allowed protocol versions, like "1.3".
+	// All versions are allowed, when the list is empty.
+	Versions []Version `json:"versions,omitempty"`
+
+	// EchoInterval is a period of the liveness check of the switches.
+	// Zero disables the echo requests.
+	EchoInterval Duration `json:"echo_interval,omitempty"`
+
+	// EchoTimeout is a maximum time without messages from the switch,
+	// after which the connection is closed.
+	EchoTimeout Duration `json:"echo_timeout,omitempty"`
+
+	// ReadTimeout is a maximum duration of reading the request.
+	ReadTimeout Duration `json:"read_timeout,omitempty"`
+
+	// WriteTimeout is a maximum duration of writing the response.
+	WriteTimeout Duration `json:"write_timeout,omitempty"`
+
+	// MaxConns is a maximum number of the switch connections. Zero
+	// means no limit.
+	MaxConns int `json:"max_conns,omitempty"`
+
+	// Queue is a configuration of the connection queues.
+	Queue *QueueConfig `json:"queue,omitempty"`
+}
+
+// Load reads the configuration from the file. The format of the file
+// is selected by the extension.
+func Load(path string) (*Config, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(bytes.NewReader(b), format)
+}
+
+// Decode reads the configuration of the given format from the reader
+// and validates it. Unknown fields of the configuration and the values
+// of the invalid types are rejected with ErrInvalidConfig.
+func Decode(r io.Reader, format Format) (*Config, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatJSON:
+	case FormatYAML:
+		if YAMLUnmarshal == nil {
+			return nil, ErrNoYAML
+		}
+
+		// The YAML document is converted to JSON, so both formats
+		// are decoded with the same rules.
+		var v interface{}
+		if err = YAMLUnmarshal(b, &v); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+
+		if b, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	default:
+		return nil, fmt.Errorf("ofpconfig: unsupported format: %s", format)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	var c Config
+	if err = dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	if err = c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Validate checks the consistency of the configuration.
+func (c *Config) Validate() error {
+	newError := func(format string, v ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, v...))
+	}
+
+	if len(c.Listen) == 0 {
+		return newError("no listen addresses")
+	}
+
+	for _, addr := range c.Listen {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return newError("listen address %q: %v", addr, err)
+		}
+	}
+
+	if c.TLS != nil && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return newError("both TLS certificate and key files are required")
+	}
+
+	for _, version := range c.Versions {
+		if _, ok := versions[string(version)]; !ok {
+			return newError("unsupported protocol version %q", version)
+		}
+	}
+
+	durations := map[string]Duration{
+		"echo interval": c.EchoInterval,
+		"echo timeout":  c.EchoTimeout,
+		"read timeout":  c.ReadTimeout,
+		"write timeout": c.WriteTimeout,
+	}
+
+	for name, d := range durations {
+		if d < 0 {
+			return newError("negative %s: %s", name, time.Duration(d))
+		}
+	}
+
+	if c.EchoInterval != 0 && c.EchoTimeout < c.EchoInterval {
+		return newError("echo timeout %s is less than echo interval %s",
+			time.Duration(c.EchoTimeout), time.Duration(c.EchoInterval))
+	}
+
+	if c.MaxConns < 0 {
+		return newError("negative maximum number of connections")
+	}
+
+	if q := c.Queue; q != nil {
+		if _, ok := queuePolicies[q.Policy]; !ok {
+			return newError("unknown queue policy %q", q.Policy)
+		}
+
+		if q.MaxLen < 0 || q.MaxBytes < 0 || q.ReceiveLen < 0 {
+			return newError("negative queue limits")
+		}
+	}
+
+	return nil
+}
+
+// WireVersions returns the wire versions of the allowed protocol
+// versions. The empty list means all versions are allowed.
+func (c *Config) WireVersions() []uint8 {
+	var wire []uint8
+	for _, version := range c.Versions {
+		wire = append(wire, versions[string(version)])
+	}
+
+	return wire
+}
+
+// VersionMatcher returns a matcher of the requests of the allowed
+// protocol versions.
+func (c *Config) VersionMatcher() of.Matcher {
+	allowed := c.WireVersions()
+	return &of.MatcherFunc{Func: func(r *of.Request) bool {
+		if len(allowed) == 0 {
+			return true
+		}
+
+		for _, version := range allowed {
+			if r.Header.Version == version {
+				return true
+			}
+		}
+
+		return false
+	}}
+}
+
+// TLSConfig loads the certificates and returns the TLS configuration of
+// the listeners. It returns nil when TLS is not configured.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	if c.TLS == nil {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if c.TLS.CAFile == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(c.TLS.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: no certificates in %q",
+			ErrInvalidConfig, c.TLS.CAFile)
+	}
+
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
+
+// Listeners announces on all configured addresses. The listeners are
+// wrapped with TLS, when it is configured.
+func (c *Config) Listeners() ([]net.Listener, error) {
+	config, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var listeners []net.Listener
+	for _, addr := range c.Listen {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return nil, err
+		}
+
+		if config != nil {
+			ln = tls.NewListener(ln, config)
+		}
+
+		listeners = append(listeners, ln)
+	}
+
+	return listeners, nil
+}
+
+// Server returns a new server with the configured timeouts and limits.
+// The address of the server is the first listen address.
+func (c *Config) Server(h of.Handler) *of.Server {
+	srv := &of.Server{
+		Handler:      h,
+		ReadTimeout:  time.Duration(c.ReadTimeout),
+		WriteTimeout: time.Duration(c.WriteTimeout),
+		MaxConns:     c.MaxConns,
+	}
+
+	if len(c.Listen) > 0 {
+		srv.Addr = c.Listen[0]
+	}
+
+	return srv
+}
+
+// QueueConfig returns the configuration of the queued connections.
+func (c *Config) QueueConfig() of.QueueConfig {
+	if c.Queue == nil {
+		return of.QueueConfig{}
+	}
+
+	return of.QueueConfig{
+		MaxLen:     c.Queue.MaxLen,
+		MaxBytes:   c.Queue.MaxBytes,
+		Policy:     queuePolicies[c.Queue.Policy],
+		ReceiveLen: c.Queue.ReceiveLen,
+	}
+}
+
+// KeepaliveConn returns the connection probing the liveness of the
+// switch with the configured echo interval and timeout. The liveness
+// check runs until the context is canceled, the connection is closed
+// when the switch does not respond within the echo timeout. The
+// connection is returned as is, when the echo requests are disabled.
+//
+// The messages must be received from the returned connection, so the
+// received messages confirm the liveness of the switch.
+func (c *Config) KeepaliveConn(ctx context.Context, conn of.Conn) of.Conn {
+	if c.EchoInterval == 0 {
+		return conn
+	}
+
+	keepalive := ofputil.NewKeepaliveConn(conn,
+		time.Duration(c.EchoInterval), time.Duration(c.EchoTimeout))
+
+	go func() {
+		if err := keepalive.Run(ctx); err != nil && ctx.Err() == nil {
+			keepalive.Close()
+		}
+	}()
+
+	return keepalive
+}
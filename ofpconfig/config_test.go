@@ -0,0 +1,190 @@
+package ofpconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofputil"
+)
+
+const jsonConfig = `{
+	"listen": [":6653", "127.0.0.1:6633"],
+	"versions": ["1.3", 1.4],
+	"echo_interval": "5s",
+	"echo_timeout": 15,
+	"max_conns": 1024,
+	"queue": {"max_len": 64, "policy": "drop"}
+}`
+
+func TestDecode(t *testing.T) {
+	c, err := Decode(strings.NewReader(jsonConfig), FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to decode configuration: %s", err)
+	}
+
+	if !reflect.DeepEqual(c.Listen, []string{":6653", "127.0.0.1:6633"}) {
+		t.Errorf("Invalid listen addresses: %v", c.Listen)
+	}
+
+	if !reflect.DeepEqual(c.WireVersions(), []uint8{4, 5}) {
+		t.Errorf("Invalid wire versions: %v", c.WireVersions())
+	}
+
+	if c.EchoInterval != Duration(5*time.Second) ||
+		c.EchoTimeout != Duration(15*time.Second) {
+		t.Errorf("Invalid echo durations: %v, %v", c.EchoInterval, c.EchoTimeout)
+	}
+
+	if c.MaxConns != 1024 {
+		t.Errorf("Invalid maximum number of connections: %d", c.MaxConns)
+	}
+
+	queue := c.QueueConfig()
+	if queue.MaxLen != 64 || queue.Policy != of.QueueDrop {
+		t.Errorf("Invalid queue configuration: %v", queue)
+	}
+
+	srv := c.Server(nil)
+	if srv.Addr != ":6653" || srv.MaxConns != 1024 {
+		t.Errorf("Invalid server configuration: %v", srv)
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	if _, err := Decode(strings.NewReader(jsonConfig), FormatYAML); err != ErrNoYAML {
+		t.Fatalf("YAML must be rejected without decoder: %v", err)
+	}
+
+	// The JSON documents are valid YAML documents, so the JSON
+	// decoder stands for the YAML one.
+	var decoded []byte
+	YAMLUnmarshal = func(b []byte, v interface{}) error {
+		decoded = b
+		return json.Unmarshal(b, v)
+	}
+	defer func() { YAMLUnmarshal = nil }()
+
+	c, err := Decode(strings.NewReader(jsonConfig), FormatYAML)
+	if err != nil {
+		t.Fatalf("Failed to decode configuration: %s", err)
+	}
+
+	if string(decoded) != jsonConfig || c.MaxConns != 1024 {
+		t.Errorf("Configuration must be decoded with YAML decoder: %v", c)
+	}
+
+	_, err = Decode(strings.NewReader("listen: ["), FormatYAML)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Malformed document must be rejected: %v", err)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	const text = `{"listen": [":6653"], "tls": {"cert_file": "c.pem", "key_file": "k.pem"}}`
+
+	c, err := Decode(strings.NewReader(text), FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to decode configuration: %s", err)
+	}
+
+	if c.TLS == nil || c.TLS.CertFile != "c.pem" || c.TLS.KeyFile != "k.pem" {
+		t.Errorf("Invalid TLS configuration: %v", c.TLS)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		text   string
+		format Format
+	}{
+		{`{"listen": []}`, FormatJSON},
+		{`{"listen": ["localhost"]}`, FormatJSON},
+		{`{"listen": [":6653"], "versions": ["2.0"]}`, FormatJSON},
+		{`{"listen": [":6653"], "tls": {"cert_file": "c.pem"}}`, FormatJSON},
+		{`{"listen": [":6653"], "echo_interval": "10s", "echo_timeout": "5s"}`, FormatJSON},
+		{`{"listen": [":6653"], "queue": {"policy": "retry"}}`, FormatJSON},
+		{`{"listen": [":6653"], "versions": [2.0]}`, FormatJSON},
+		{`{"listen": [":6653"], "versions": [true]}`, FormatJSON},
+		{`{"listen": [":6653"], "max_conns": "many"}`, FormatJSON},
+		{`{"listen": [":6653"], "echo_interval": [5]}`, FormatJSON},
+	}
+
+	for _, tt := range tests {
+		_, err := Decode(strings.NewReader(tt.text), tt.format)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Expected invalid configuration error for %q: %v", tt.text, err)
+		}
+	}
+
+	// Unknown fields are rejected to catch the typos.
+	_, err := Decode(strings.NewReader(`{"listen": [":6653"], "max_con": 1}`), FormatJSON)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Unknown field must be rejected: %v", err)
+	}
+}
+
+func TestDecodeNumericVersions(t *testing.T) {
+	const text = `{"listen": [":6653"], "versions": [1, 1.3]}`
+
+	c, err := Decode(strings.NewReader(text), FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to decode configuration: %s", err)
+	}
+
+	if !reflect.DeepEqual(c.WireVersions(), []uint8{1, 4}) {
+		t.Errorf("Invalid wire versions: %v", c.WireVersions())
+	}
+}
+
+func TestKeepaliveConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sw := of.NewConn(server)
+	defer sw.Close()
+
+	c := &Config{
+		EchoInterval: Duration(10 * time.Millisecond),
+		EchoTimeout:  Duration(30 * time.Millisecond),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn := c.KeepaliveConn(ctx, of.NewConn(client))
+	if _, ok := conn.(*ofputil.KeepaliveConn); !ok {
+		t.Fatalf("Keepalive connection expected: %T", conn)
+	}
+
+	// The switch never replies to the echo requests, so the
+	// connection is closed after the echo timeout.
+	r, err := sw.Receive()
+	if err != nil || r.Header.Type != of.TypeEchoRequest {
+		t.Fatalf("Echo request expected: %v", err)
+	}
+
+	for err == nil {
+		_, err = sw.Receive()
+	}
+
+	if disabled := (&Config{}).KeepaliveConn(ctx, conn); disabled != conn {
+		t.Errorf("Connection must be returned as is: %T", disabled)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	if format, err := FormatOf("/etc/of.YML"); err != nil || format != FormatYAML {
+		t.Errorf("Invalid format of YAML file: %v, %v", format, err)
+	}
+
+	if _, err := FormatOf("/etc/of.ini"); err == nil {
+		t.Errorf("Unknown format must be rejected")
+	}
+}
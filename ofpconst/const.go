@@ -0,0 +1,57 @@
+// Package ofpconst defines the EtherType and IP protocol numbers, that
+// are commonly used in the OpenFlow matches and actions.
+//
+// The constants are untyped, so they could be used both in the action
+// fields and in the match helpers, for example:
+//
+//	ofputil.MatchEthType(ofpconst.EtherTypeIPv4)
+//	&ofp.ActionPushVLAN{EtherType: ofpconst.EtherTypeVLAN}
+package ofpconst
+
+// Ethernet types of the network layer protocols and tags.
+const (
+	// EtherTypeIPv4 is an Internet Protocol version 4.
+	EtherTypeIPv4 = 0x0800
+
+	// EtherTypeARP is an Address Resolution Protocol.
+	EtherTypeARP = 0x0806
+
+	// EtherTypeVLAN is an IEEE 802.1Q VLAN-tagged frame.
+	EtherTypeVLAN = 0x8100
+
+	// EtherTypeIPv6 is an Internet Protocol version 6.
+	EtherTypeIPv6 = 0x86dd
+
+	// EtherTypeMPLS is an MPLS unicast.
+	EtherTypeMPLS = 0x8847
+
+	// EtherTypeMPLSMulticast is an MPLS multicast.
+	EtherTypeMPLSMulticast = 0x8848
+
+	// EtherTypeLLDP is a Link Layer Discovery Protocol.
+	EtherTypeLLDP = 0x88cc
+
+	// EtherTypeQinQ is an IEEE 802.1ad service VLAN tag.
+	EtherTypeQinQ = 0x88a8
+
+	// EtherTypePBB is an IEEE 802.1ah provider backbone bridge tag.
+	EtherTypePBB = 0x88e7
+)
+
+// Protocol numbers of the IP payload.
+const (
+	// IPProtoICMP is an Internet Control Message Protocol.
+	IPProtoICMP = 1
+
+	// IPProtoTCP is a Transmission Control Protocol.
+	IPProtoTCP = 6
+
+	// IPProtoUDP is a User Datagram Protocol.
+	IPProtoUDP = 17
+
+	// IPProtoICMPv6 is an Internet Control Message Protocol for IPv6.
+	IPProtoICMPv6 = 58
+
+	// IPProtoSCTP is a Stream Control Transmission Protocol.
+	IPProtoSCTP = 132
+)
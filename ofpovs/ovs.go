@@ -0,0 +1,260 @@
+// Package ofpovs pairs the OpenFlow channel with the configuration of
+// the Open vSwitch performed through the OVSDB management protocol.
+//
+// The package does not implement the OVSDB protocol, instead it defines
+// the Client interface, that could be implemented on top of any OVSDB
+// library, so the bridges, controllers and queues could be configured
+// together with the OpenFlow connection.
+package ofpovs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+var (
+	// ErrNoClient is returned when the bridge is used without the OVSDB
+	// client.
+	ErrNoClient = errors.New("ofpovs: OVSDB client is not configured")
+
+	// ErrNoQoS is returned when the QoS configuration is not specified,
+	// the ClearQoS method is used to remove the configuration instead.
+	ErrNoQoS = errors.New("ofpovs: QoS is not specified")
+)
+
+// QoSType defines the type of the QoS configured on the port.
+type QoSType string
+
+const (
+	// QoSTypeLinuxHTB is a Linux "hierarchy token bucket" classifier.
+	QoSTypeLinuxHTB QoSType = "linux-htb"
+
+	// QoSTypeLinuxHFSC is a Linux "hierarchical fair service curve"
+	// classifier.
+	QoSTypeLinuxHFSC QoSType = "linux-hfsc"
+)
+
+// QueueConfig describes the configuration of the single queue attached
+// to the port.
+type QueueConfig struct {
+	// MinRate is a minimum guaranteed bandwidth in bit/s.
+	MinRate uint64
+
+	// MaxRate is a maximum allowed bandwidth in bit/s.
+	MaxRate uint64
+
+	// Priority of the queue, the queues with smaller numbers are
+	// served first.
+	Priority uint32
+}
+
+// QoS describes the quality of service configuration of the port.
+type QoS struct {
+	// Type of the QoS.
+	Type QoSType
+
+	// MaxRate is a maximum rate shared by all queues in bit/s.
+	MaxRate uint64
+
+	// Queues maps the OpenFlow queue identifiers to the configuration
+	// of the queues. The same identifiers are used in the OpenFlow
+	// set-queue actions.
+	Queues map[ofp.Queue]QueueConfig
+}
+
+// Client is an interface of the OVSDB client adapter used to manage the
+// configuration of the Open vSwitch.
+type Client interface {
+	// AddBridge creates a new bridge with the given name.
+	AddBridge(ctx context.Context, bridge string) error
+
+	// DelBridge removes the bridge with the given name.
+	DelBridge(ctx context.Context, bridge string) error
+
+	// SetController replaces the list of controllers of the bridge
+	// with the given targets, e.g. "tcp:10.0.0.1:6653".
+	SetController(ctx context.Context, bridge string, targets ...string) error
+
+	// SetQoS replaces the QoS configuration of the given port.
+	SetQoS(ctx context.Context, port string, qos *QoS) error
+
+	// ClearQoS removes the QoS configuration of the given port.
+	ClearQoS(ctx context.Context, port string) error
+}
+
+// Bridge coordinates the management and the OpenFlow planes of the
+// single Open vSwitch bridge.
+//
+// For example, to create a bridge and accept the OpenFlow connection
+// from it:
+//
+//	ln, _ := of.Listen("tcp", ":6653")
+//	br := &ofpovs.Bridge{Name: "br0", Client: client}
+//
+//	if err := br.Create(ctx); err != nil {
+//		// ...
+//	}
+//
+//	conn, err := br.Accept(ctx, ln, "tcp:10.0.0.1:6653")
+type Bridge struct {
+	// Name of the bridge.
+	Name string
+
+	// Client is an OVSDB client used to configure the bridge.
+	Client Client
+
+	mu sync.Mutex
+
+	// pending is the accept left in flight by the cancelled Accept.
+	pending *acceptCall
+}
+
+// client returns the configured OVSDB client or an error.
+func (b *Bridge) client() (Client, error) {
+	if b.Client == nil {
+		return nil, ErrNoClient
+	}
+
+	return b.Client, nil
+}
+
+// Create creates the bridge.
+func (b *Bridge) Create(ctx context.Context) error {
+	c, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	return c.AddBridge(ctx, b.Name)
+}
+
+// Delete removes the bridge.
+func (b *Bridge) Delete(ctx context.Context) error {
+	c, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	return c.DelBridge(ctx, b.Name)
+}
+
+// SetController points the bridge to the given OpenFlow controllers.
+func (b *Bridge) SetController(ctx context.Context, targets ...string) error {
+	c, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	return c.SetController(ctx, b.Name, targets...)
+}
+
+// acceptCall is an accept of the connection on the listener, that
+// could outlive the Accept call cancelled by the context.
+type acceptCall struct {
+	ln   of.Listener
+	done chan struct{}
+	conn of.Conn
+	err  error
+}
+
+// accept waits for the next connection on the listener.
+func (call *acceptCall) accept() {
+	call.conn, call.err = call.ln.Accept()
+	close(call.done)
+}
+
+// discard closes the connection accepted by the abandoned call, so it
+// does not leak.
+func (call *acceptCall) discard() {
+	<-call.done
+	if call.conn != nil {
+		call.conn.Close()
+	}
+}
+
+// Accept points the bridge to the given controller target and waits for
+// the OpenFlow connection on the listener. The listener must accept the
+// connections on the address referenced by the target.
+//
+// The listener is owned by the caller and must be dedicated to this
+// bridge, since the first connection accepted on it is returned without
+// checking the datapath of the switch. When the context is cancelled,
+// the listener is left open and the pending accept is kept by the
+// bridge, so the connection is returned by the next Accept call with
+// the same listener.
+func (b *Bridge) Accept(ctx context.Context, ln of.Listener, target string) (of.Conn, error) {
+	if err := b.SetController(ctx, target); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	call := b.pending
+	b.pending = nil
+
+	if call != nil && call.ln != ln {
+		go call.discard()
+		call = nil
+	}
+
+	if call == nil {
+		call = &acceptCall{ln: ln, done: make(chan struct{})}
+		go call.accept()
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.conn, call.err
+	case <-ctx.Done():
+		b.mu.Lock()
+		if b.pending != nil {
+			go b.pending.discard()
+		}
+		b.pending = call
+		b.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// SetQoS replaces the QoS configuration of the given port of the bridge.
+// Each queue must have the maximum rate not exceeding the maximum rate
+// of the QoS, when the last one is specified.
+func (b *Bridge) SetQoS(ctx context.Context, port string, qos *QoS) error {
+	c, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	if qos == nil {
+		return ErrNoQoS
+	}
+
+	for queue, config := range qos.Queues {
+		if qos.MaxRate != 0 && config.MaxRate > qos.MaxRate {
+			return fmt.Errorf("ofpovs: queue %d exceeds the QoS rate: %d",
+				queue, config.MaxRate)
+		}
+
+		if config.MaxRate != 0 && config.MinRate > config.MaxRate {
+			return fmt.Errorf("ofpovs: queue %d has invalid rates: %d > %d",
+				queue, config.MinRate, config.MaxRate)
+		}
+	}
+
+	return c.SetQoS(ctx, port, qos)
+}
+
+// ClearQoS removes the QoS configuration of the given port of the bridge.
+func (b *Bridge) ClearQoS(ctx context.Context, port string) error {
+	c, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	return c.ClearQoS(ctx, port)
+}
@@ -0,0 +1,169 @@
+package ofpovs
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+type fakeClient struct {
+	bridges     map[string][]string
+	qos         map[string]*QoS
+	onConnected func(targets ...string)
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		bridges: make(map[string][]string),
+		qos:     make(map[string]*QoS),
+	}
+}
+
+func (c *fakeClient) AddBridge(ctx context.Context, bridge string) error {
+	c.bridges[bridge] = nil
+	return nil
+}
+
+func (c *fakeClient) DelBridge(ctx context.Context, bridge string) error {
+	delete(c.bridges, bridge)
+	return nil
+}
+
+func (c *fakeClient) SetController(ctx context.Context, bridge string, targets ...string) error {
+	c.bridges[bridge] = targets
+	if c.onConnected != nil {
+		c.onConnected(targets...)
+	}
+	return nil
+}
+
+func (c *fakeClient) SetQoS(ctx context.Context, port string, qos *QoS) error {
+	c.qos[port] = qos
+	return nil
+}
+
+func (c *fakeClient) ClearQoS(ctx context.Context, port string) error {
+	delete(c.qos, port)
+	return nil
+}
+
+func TestBridgeAccept(t *testing.T) {
+	ln, err := of.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %s", err)
+	}
+
+	defer ln.Close()
+
+	client := newFakeClient()
+	client.onConnected = func(targets ...string) {
+		// Emulate the switch connecting to the controller.
+		go func() {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err == nil {
+				defer conn.Close()
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+	}
+
+	br := &Bridge{Name: "br0", Client: client}
+	if err = br.Create(context.Background()); err != nil {
+		t.Fatalf("Failed to create bridge: %s", err)
+	}
+
+	target := "tcp:" + ln.Addr().String()
+	conn, err := br.Accept(context.Background(), ln, target)
+	if err != nil {
+		t.Fatalf("Failed to accept connection: %s", err)
+	}
+
+	conn.Close()
+
+	targets := client.bridges["br0"]
+	if len(targets) != 1 || targets[0] != target {
+		t.Errorf("Invalid controller targets: %v", targets)
+	}
+}
+
+func TestBridgeAcceptCancel(t *testing.T) {
+	ln, err := of.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %s", err)
+	}
+
+	defer ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	br := &Bridge{Name: "br0", Client: newFakeClient()}
+	target := "tcp:" + ln.Addr().String()
+
+	if _, err = br.Accept(ctx, ln, target); err != context.Canceled {
+		t.Errorf("Context error expected: %v", err)
+	}
+
+	// The listener must stay open after the cancellation, and the
+	// next call must return the connection of the pending accept.
+	sw, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial the listener: %s", err)
+	}
+
+	defer sw.Close()
+
+	conn, err := br.Accept(context.Background(), ln, target)
+	if err != nil {
+		t.Fatalf("Failed to accept connection: %s", err)
+	}
+
+	defer conn.Close()
+
+	if conn.RemoteAddr().String() != sw.LocalAddr().String() {
+		t.Errorf("Invalid connection accepted: %s", conn.RemoteAddr())
+	}
+}
+
+func TestBridgeSetQoS(t *testing.T) {
+	client := newFakeClient()
+	br := &Bridge{Name: "br0", Client: client}
+
+	qos := &QoS{Type: QoSTypeLinuxHTB, MaxRate: 1000, Queues: map[ofp.Queue]QueueConfig{
+		1: {MinRate: 100, MaxRate: 500},
+	}}
+
+	if err := br.SetQoS(context.Background(), "eth0", qos); err != nil {
+		t.Fatalf("Failed to set QoS: %s", err)
+	}
+
+	if client.qos["eth0"] != qos {
+		t.Errorf("QoS is not configured: %v", client.qos)
+	}
+
+	if err := br.SetQoS(context.Background(), "eth1", nil); err != ErrNoQoS {
+		t.Errorf("Error expected for the unspecified QoS: %v", err)
+	}
+
+	qos.Queues[2] = QueueConfig{MaxRate: 2000}
+	if err := br.SetQoS(context.Background(), "eth1", qos); err == nil {
+		t.Errorf("Error expected for the queue exceeding the QoS rate")
+	}
+
+	if err := br.ClearQoS(context.Background(), "eth0"); err != nil {
+		t.Fatalf("Failed to clear QoS: %s", err)
+	}
+
+	if _, ok := client.qos["eth0"]; ok {
+		t.Errorf("QoS must be removed: %v", client.qos)
+	}
+
+	br = &Bridge{Name: "br0"}
+	if err := br.Create(context.Background()); err != ErrNoClient {
+		t.Errorf("Missing client error expected: %v", err)
+	}
+}
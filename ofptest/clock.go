@@ -0,0 +1,190 @@
+package ofptest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	of "github.com/netrack/openflow"
+)
+
+// Clock is a fake clock implementing of.Clock interface. The time of
+// the clock changes only when it is advanced by the test, so the
+// subsystems depending on timeouts could be tested without sleeping.
+//
+// For example, to test the flow collector without waiting for the
+// polling interval:
+//
+//	clock := ofptest.NewClock(time.Unix(0, 0))
+//	gc := &ofputil.FlowGC{Clock: clock}
+//	go gc.Run(ctx, conn, time.Minute)
+//
+//	// Wait until the ticker is created, then trigger the next poll.
+//	clock.BlockUntil(1)
+//	clock.Advance(time.Minute)
+type Clock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*clockTimer
+}
+
+// NewClock creates a new fake clock set to the given time.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// clockTimer is a timer or ticker scheduled by the fake clock.
+type clockTimer struct {
+	clock  *Clock
+	when   time.Time
+	period time.Duration
+	ch     chan time.Time
+	fn     func()
+}
+
+// C implements of.Ticker interface.
+func (t *clockTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop implements of.Timer interface.
+func (t *clockTimer) Stop() bool {
+	return t.clock.remove(t)
+}
+
+// clockTicker adapts the timer to of.Ticker interface.
+type clockTicker struct {
+	*clockTimer
+}
+
+// Stop implements of.Ticker interface.
+func (t clockTicker) Stop() {
+	t.clockTimer.Stop()
+}
+
+// Now implements of.Clock interface. It returns the current time of
+// the fake clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker implements of.Clock interface. The ticks are delivered when
+// the clock is advanced, the ticks are dropped for the slow receivers.
+func (c *Clock) NewTicker(d time.Duration) of.Ticker {
+	if d <= 0 {
+		panic("ofptest: non-positive interval for NewTicker")
+	}
+
+	t := &clockTimer{clock: c, period: d, ch: make(chan time.Time, 1)}
+	c.add(t, d)
+	return clockTicker{t}
+}
+
+// AfterFunc implements of.Clock interface. The function is called in
+// its own goroutine, the Advance waits for the function to return, so
+// its effects are observed once the clock is advanced.
+func (c *Clock) AfterFunc(d time.Duration, f func()) of.Timer {
+	t := &clockTimer{clock: c, fn: f}
+	c.add(t, d)
+	return t
+}
+
+// add schedules the timer after the given duration.
+func (c *Clock) add(t *clockTimer, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t.when = c.now.Add(d)
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+}
+
+// remove removes the timer, it returns false when the timer is not
+// scheduled.
+func (c *Clock) remove(t *clockTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+
+	return false
+}
+
+// Advance moves the time of the clock forward by the given duration.
+// The timers and tickers expiring within the duration fire in the
+// order of their expiration time.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	until := c.now.Add(d)
+
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].when.Before(c.timers[j].when)
+		})
+
+		if len(c.timers) == 0 || c.timers[0].when.After(until) {
+			break
+		}
+
+		t := c.timers[0]
+		if c.now.Before(t.when) {
+			c.now = t.when
+		}
+
+		if t.period > 0 {
+			t.when = t.when.Add(t.period)
+			select {
+			case t.ch <- c.now:
+			default:
+			}
+			continue
+		}
+
+		c.timers = c.timers[1:]
+		c.cond.Broadcast()
+
+		// Call the function without the lock, so it could use
+		// the clock to schedule the next timer.
+		c.mu.Unlock()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			t.fn()
+		}()
+		<-done
+		c.mu.Lock()
+	}
+
+	c.now = until
+	c.mu.Unlock()
+}
+
+// Timers returns the number of scheduled timers and tickers.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil blocks until the given number of timers and tickers is
+// scheduled. It is used to wait until the tested goroutine starts
+// waiting for the clock before advancing it.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.timers) != n {
+		c.cond.Wait()
+	}
+}
@@ -0,0 +1,49 @@
+package ofptest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Unix(100, 0)
+	clock := NewClock(start)
+
+	var fired []time.Time
+	clock.AfterFunc(3*time.Second, func() {
+		fired = append(fired, clock.Now())
+	})
+
+	timer := clock.AfterFunc(time.Second, func() {
+		t.Errorf("Stopped timer must not fire")
+	})
+
+	ticker := clock.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	if !timer.Stop() || timer.Stop() {
+		t.Fatalf("Timer must be stopped only once")
+	}
+
+	clock.Advance(2 * time.Second)
+	if tick := <-ticker.C(); !tick.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("Invalid time of the tick: %s", tick)
+	}
+
+	clock.Advance(2 * time.Second)
+	if len(fired) != 1 || !fired[0].Equal(start.Add(3*time.Second)) {
+		t.Fatalf("Timer must fire at the expiry time: %v", fired)
+	}
+
+	if !clock.Now().Equal(start.Add(4 * time.Second)) {
+		t.Fatalf("Invalid time of the clock: %s", clock.Now())
+	}
+
+	if tick := <-ticker.C(); !tick.Equal(start.Add(4 * time.Second)) {
+		t.Fatalf("Invalid time of the tick: %s", tick)
+	}
+
+	if clock.Timers() != 1 {
+		t.Fatalf("Only ticker must be scheduled: %d", clock.Timers())
+	}
+}
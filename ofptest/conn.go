@@ -0,0 +1,177 @@
+package ofptest
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrConnReset is returned by the FaultyConn when the connection was
+// reset due to the injected fault.
+var ErrConnReset = errors.New("ofptest: connection reset by peer")
+
+// Faults describes the degradation of the control channel injected
+// into the connection.
+type Faults struct {
+	// Latency is a delay before each write to the connection.
+	Latency time.Duration
+
+	// Jitter is a maximum random delay added to the latency.
+	Jitter time.Duration
+
+	// SegmentSize splits each write into the segments of the given
+	// size written to the connection one after another, so the peer
+	// receives messages partially. Zero means no segmentation.
+	SegmentSize int
+
+	// ResetRate is a probability in range [0, 1] of the connection
+	// reset on each read or write.
+	ResetRate float64
+
+	// Seed is used to initialize the source of random numbers, so the
+	// injected faults could be reproduced.
+	Seed int64
+}
+
+// FaultyConn is a net.Conn wrapper that injects configured faults into
+// the connection, so the controller applications could be tested
+// against degraded control channels.
+//
+// For example, to connect to the switch with unstable connection, the
+// following code could be used:
+//
+//	c, err := net.Dial("tcp", addr)
+//	// ...
+//
+//	conn := of.NewConn(ofptest.NewFaultyConn(c, ofptest.Faults{
+//		Latency:   10 * time.Millisecond,
+//		ResetRate: 0.01,
+//	}))
+type FaultyConn struct {
+	net.Conn
+
+	faults Faults
+	rand   *rand.Rand
+	reset  bool
+
+	// Mutex protects the random source and reset flag, since the
+	// connection is read and written concurrently.
+	mu sync.Mutex
+}
+
+// NewFaultyConn returns a new connection that injects the given faults
+// into the connection c.
+func NewFaultyConn(c net.Conn, faults Faults) *FaultyConn {
+	return &FaultyConn{
+		Conn:   c,
+		faults: faults,
+		rand:   rand.New(rand.NewSource(faults.Seed)),
+	}
+}
+
+// fail reports whether the connection has to be reset. Once reset,
+// connection is closed and all further calls fail.
+func (c *FaultyConn) fail() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.reset && c.rand.Float64() < c.faults.ResetRate {
+		c.reset = true
+		c.Conn.Close()
+	}
+
+	return c.reset
+}
+
+// delay returns the latency with a random jitter.
+func (c *FaultyConn) delay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d := c.faults.Latency
+	if c.faults.Jitter > 0 {
+		d += time.Duration(c.rand.Int63n(int64(c.faults.Jitter)))
+	}
+
+	return d
+}
+
+// Read implements io.Reader interface. It reads data from the wrapped
+// connection, unless connection was reset.
+func (c *FaultyConn) Read(b []byte) (int, error) {
+	if c.fail() {
+		return 0, ErrConnReset
+	}
+
+	return c.Conn.Read(b)
+}
+
+// Write implements io.Writer interface. It writes data to the wrapped
+// connection in segments, each delayed by the configured latency.
+func (c *FaultyConn) Write(b []byte) (int, error) {
+	var n int
+
+	for len(b) > 0 {
+		if c.fail() {
+			return n, ErrConnReset
+		}
+
+		segment := b
+		if size := c.faults.SegmentSize; size > 0 && size < len(b) {
+			segment = b[:size]
+		}
+
+		if d := c.delay(); d > 0 {
+			time.Sleep(d)
+		}
+
+		nn, err := c.Conn.Write(segment)
+		n += nn
+
+		if err != nil {
+			return n, err
+		}
+
+		b = b[nn:]
+	}
+
+	return n, nil
+}
+
+// FaultyListener is a net.Listener wrapper that injects configured
+// faults into all accepted connections. Each connection draws the faults
+// from its own source of random numbers, the n-th accepted connection
+// (starting from zero) is seeded with Seed+n, so the connections don't
+// fail in lockstep, while the faults are still reproducible. It could
+// be used to create a test server with degraded control channels:
+//
+//	ln := ofptest.NewFaultyListener(listener, faults)
+//	srv := ofptest.NewUnstartedServer(handler, ln)
+type FaultyListener struct {
+	net.Listener
+
+	faults   Faults
+	accepted int64
+}
+
+// NewFaultyListener returns a new listener that injects the given
+// faults into connections accepted by listener ln.
+func NewFaultyListener(ln net.Listener, faults Faults) *FaultyListener {
+	return &FaultyListener{Listener: ln, faults: faults}
+}
+
+// Accept implements net.Listener interface. It waits for the next
+// connection and wraps it with the faulty connection.
+func (ln *FaultyListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	faults := ln.faults
+	faults.Seed += atomic.AddInt64(&ln.accepted, 1) - 1
+	return NewFaultyConn(c, faults), nil
+}
@@ -0,0 +1,102 @@
+package ofptest
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFaultyConnSegments(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+
+	conn := NewFaultyConn(c1, Faults{
+		Latency:     time.Millisecond,
+		SegmentSize: 3,
+	})
+
+	defer conn.Close()
+
+	data := []byte("openflow")
+	go func() {
+		if _, err := conn.Write(data); err != nil {
+			t.Errorf("Failed to write data: %s", err)
+		}
+	}()
+
+	// Each read from the pipe returns a single segment.
+	b := make([]byte, len(data))
+	n, err := c2.Read(b)
+	if err != nil || n != 3 {
+		t.Fatalf("Expected segment of 3 bytes, got %d: %v", n, err)
+	}
+
+	_, err = io.ReadFull(c2, b[n:])
+	if err != nil {
+		t.Fatalf("Failed to read data: %s", err)
+	}
+
+	if !bytes.Equal(b, data) {
+		t.Fatalf("Invalid data received: %q", b)
+	}
+}
+
+func TestFaultyConnReset(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+
+	conn := NewFaultyConn(c1, Faults{ResetRate: 1})
+
+	_, err := conn.Write([]byte("openflow"))
+	if err != ErrConnReset {
+		t.Fatalf("Expected connection reset error, got: %v", err)
+	}
+
+	_, err = conn.Read(make([]byte, 1))
+	if err != ErrConnReset {
+		t.Fatalf("Expected connection reset error, got: %v", err)
+	}
+
+	// The underlying connection has to be closed.
+	_, err = c2.Read(make([]byte, 1))
+	if err != io.EOF {
+		t.Fatalf("Expected closed connection, got: %v", err)
+	}
+}
+
+// pipeListener is a listener returning the ends of the in-memory pipes.
+type pipeListener struct {
+	net.Listener
+}
+
+// Accept implements net.Listener interface.
+func (pipeListener) Accept() (net.Conn, error) {
+	c, _ := net.Pipe()
+	return c, nil
+}
+
+func TestFaultyListenerSeed(t *testing.T) {
+	faults := Faults{ResetRate: 0.5, Seed: 42}
+	ln := NewFaultyListener(pipeListener{}, faults)
+
+	for i := int64(0); i < 3; i++ {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("Failed to accept connection: %s", err)
+		}
+
+		defer c.Close()
+
+		// Each connection must reproduce the faults of its own seed.
+		expected := NewFaultyConn(nil, Faults{ResetRate: 0.5, Seed: 42 + i})
+		conn := c.(*FaultyConn)
+
+		for j := 0; j < 8; j++ {
+			if got, want := conn.rand.Int63(), expected.rand.Int63(); got != want {
+				t.Fatalf("Connection %d is not seeded with %d", i, 42+i)
+			}
+		}
+	}
+}
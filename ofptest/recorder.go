@@ -1,15 +1,26 @@
 package ofptest
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
 
 	of "github.com/netrack/openflow"
 )
 
-// ResponseRecorder is an implementation of http.ResponseWriter that
+// ResponseRecorder is an implementation of of.ResponseWriter that
 // records its mutations for later inspection in tests.
+//
+// The bodies of the recorded messages are serialized at the moment of
+// writing, so the handler could reuse the written structures.
 type ResponseRecorder struct {
-	reqs []*of.Request
+	mu     sync.Mutex
+	reqs   []*of.Request
+	bodies [][]byte
 }
 
 // NewRecorder returns an initialized ResponseRecorder.
@@ -19,16 +30,33 @@ func NewRecorder() *ResponseRecorder {
 
 // Write saves the given message into the list of requests.
 func (r *ResponseRecorder) Write(h *of.Header, w io.WriterTo) error {
-	req := of.NewRequest(h.Type, w)
-	req.Header = *h
+	var buf bytes.Buffer
+	if w != nil {
+		if _, err := w.WriteTo(&buf); err != nil {
+			return err
+		}
+	}
 
-	r.reqs = append(r.reqs, req)
+	r.record(*h, buf.Bytes())
 	return nil
 }
 
+// record saves the message with the given header and body.
+func (r *ResponseRecorder) record(h of.Header, body []byte) {
+	req := of.NewRequest(h.Type, bytes.NewReader(body))
+	req.Header = h
+	req.ContentLength = int64(len(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reqs = append(r.reqs, req)
+	r.bodies = append(r.bodies, body)
+}
+
 // notempty thows a panic if the response list is empty.
 func (r *ResponseRecorder) notempty() {
-	if r == nil {
+	if len(r.reqs) == 0 {
 		panic("ofptest: response list is empty")
 	}
 }
@@ -36,6 +64,9 @@ func (r *ResponseRecorder) notempty() {
 // First returns the first response message generated by
 // the handler.
 func (r *ResponseRecorder) First() *of.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.notempty()
 	return r.reqs[0]
 }
@@ -43,6 +74,9 @@ func (r *ResponseRecorder) First() *of.Request {
 // Last returns the last response message generated by
 // the handler.
 func (r *ResponseRecorder) Last() *of.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.notempty()
 	return r.reqs[len(r.reqs)-1]
 }
@@ -50,5 +84,203 @@ func (r *ResponseRecorder) Last() *of.Request {
 // All returns complete list of the messages generated
 // by the handler.
 func (r *ResponseRecorder) All() []*of.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	return r.reqs[:]
 }
+
+// Len returns the number of the messages generated by the handler.
+func (r *ResponseRecorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.reqs)
+}
+
+// Types returns the types of the messages generated by the handler in
+// the order they were written.
+func (r *ResponseRecorder) Types() []of.Type {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	types := make([]of.Type, 0, len(r.reqs))
+	for _, req := range r.reqs {
+		types = append(types, req.Header.Type)
+	}
+
+	return types
+}
+
+// Decode deserializes the body of the i-th message generated by the
+// handler into the given structure. The body could be decoded any
+// number of times, independently of the reads of the request body.
+//
+// For example, to decode the echo reply written by the handler:
+//
+//	var reply ofp.EchoReply
+//	if err := rec.Decode(0, &reply); err != nil {
+//		t.Fatalf("Failed to decode echo reply: %s", err)
+//	}
+func (r *ResponseRecorder) Decode(i int, v io.ReaderFrom) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i < 0 || i >= len(r.bodies) {
+		return fmt.Errorf("ofptest: message %d is not recorded, total %d",
+			i, len(r.bodies))
+	}
+
+	_, err := v.ReadFrom(bytes.NewReader(r.bodies[i]))
+	return err
+}
+
+// ExpectTypes returns an error when the types of the messages generated
+// by the handler differ from the given types, including their order.
+func (r *ResponseRecorder) ExpectTypes(types ...of.Type) error {
+	recorded := r.Types()
+	if len(recorded) != len(types) {
+		return fmt.Errorf("ofptest: %d messages recorded %v, expected %d %v",
+			len(recorded), recorded, len(types), types)
+	}
+
+	for i, t := range types {
+		if recorded[i] != t {
+			return fmt.Errorf("ofptest: message %d is %s, expected %s",
+				i, recorded[i], t)
+		}
+	}
+
+	return nil
+}
+
+// recorderAddr is a network address of the recorder connection.
+type recorderAddr struct{}
+
+// Network implements net.Addr interface.
+func (recorderAddr) Network() string { return "recorder" }
+
+// String implements net.Addr interface.
+func (recorderAddr) String() string { return "recorder" }
+
+// ConnRecorder is an implementation of of.Conn that records the sent
+// messages for later inspection in tests, and returns the preconfigured
+// messages on receive.
+//
+// For example, to test a function that sends requests to the switch:
+//
+//	conn := ofptest.NewConnRecorder(
+//		of.NewRequest(of.TypeEchoReply, nil))
+//
+//	doSomething(conn)
+//
+//	if err := conn.ExpectTypes(of.TypeEchoRequest); err != nil {
+//		t.Fatal(err)
+//	}
+type ConnRecorder struct {
+	ResponseRecorder
+
+	flushed  int
+	closed   bool
+	incoming []*of.Request
+}
+
+// NewConnRecorder returns an initialized ConnRecorder, that returns the
+// given requests on receive.
+func NewConnRecorder(reqs ...*of.Request) *ConnRecorder {
+	return &ConnRecorder{incoming: reqs}
+}
+
+// Push appends the given requests to the list of incoming messages.
+func (c *ConnRecorder) Push(reqs ...*of.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.incoming = append(c.incoming, reqs...)
+}
+
+// Receive returns the next incoming message, or io.EOF error when there
+// are no more messages.
+func (c *ConnRecorder) Receive() (*of.Request, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || len(c.incoming) == 0 {
+		return nil, io.EOF
+	}
+
+	req := c.incoming[0]
+	c.incoming = c.incoming[1:]
+	return req, nil
+}
+
+// Send saves the given message into the list of requests.
+func (c *ConnRecorder) Send(req *of.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+	}
+
+	c.record(req.Header, body)
+	return nil
+}
+
+// Flush implements of.Conn interface.
+func (c *ConnRecorder) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flushed++
+	return nil
+}
+
+// Flushed returns the number of calls to Flush method.
+func (c *ConnRecorder) Flushed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flushed
+}
+
+// Close implements of.Conn interface.
+func (c *ConnRecorder) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	return nil
+}
+
+// Closed reports whether the connection is closed.
+func (c *ConnRecorder) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// LocalAddr implements of.Conn interface.
+func (c *ConnRecorder) LocalAddr() net.Addr {
+	return recorderAddr{}
+}
+
+// RemoteAddr implements of.Conn interface.
+func (c *ConnRecorder) RemoteAddr() net.Addr {
+	return recorderAddr{}
+}
+
+// SetDeadline implements of.Conn interface.
+func (c *ConnRecorder) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline implements of.Conn interface.
+func (c *ConnRecorder) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline implements of.Conn interface.
+func (c *ConnRecorder) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package ofptest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	rec := NewRecorder()
+
+	echo := &ofp.EchoReply{Data: []byte("ping")}
+	rec.Write(&of.Header{Type: of.TypeEchoReply}, echo)
+
+	// The recorder must not depend on the later modifications
+	// of the written structures.
+	echo.Data = []byte("pong")
+	rec.Write(&of.Header{Type: of.TypeBarrierReply}, nil)
+
+	if err := rec.ExpectTypes(of.TypeEchoReply, of.TypeBarrierReply); err != nil {
+		t.Fatalf("Invalid messages recorded: %s", err)
+	}
+
+	if err := rec.ExpectTypes(of.TypeBarrierReply, of.TypeEchoReply); err == nil {
+		t.Errorf("Error expected for the messages in wrong order")
+	}
+
+	for i := 0; i < 2; i++ {
+		var reply ofp.EchoReply
+		if err := rec.Decode(0, &reply); err != nil {
+			t.Fatalf("Failed to decode echo reply: %s", err)
+		}
+
+		if !bytes.Equal(reply.Data, []byte("ping")) {
+			t.Errorf("Invalid data of echo reply: %s", reply.Data)
+		}
+	}
+
+	if err := rec.Decode(2, &ofp.EchoReply{}); err == nil {
+		t.Errorf("Error expected for the message out of range")
+	}
+}
+
+func TestConnRecorder(t *testing.T) {
+	var conn of.Conn = NewConnRecorder(
+		of.NewRequest(of.TypeEchoRequest, nil))
+
+	req, err := conn.Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive request: %s", err)
+	}
+
+	if req.Header.Type != of.TypeEchoRequest {
+		t.Errorf("Invalid type of the request: %s", req.Header.Type)
+	}
+
+	if _, err = conn.Receive(); err != io.EOF {
+		t.Errorf("End of file expected: %v", err)
+	}
+
+	echo := &ofp.EchoReply{Data: []byte("pong")}
+	if err = of.Send(conn, of.NewRequest(of.TypeEchoReply, echo)); err != nil {
+		t.Fatalf("Failed to send reply: %s", err)
+	}
+
+	rec := conn.(*ConnRecorder)
+	if err = rec.ExpectTypes(of.TypeEchoReply); err != nil {
+		t.Fatalf("Invalid messages recorded: %s", err)
+	}
+
+	var reply ofp.EchoReply
+	if err = rec.Decode(0, &reply); err != nil {
+		t.Fatalf("Failed to decode echo reply: %s", err)
+	}
+
+	if !bytes.Equal(reply.Data, echo.Data) {
+		t.Errorf("Invalid data of echo reply: %s", reply.Data)
+	}
+
+	if rec.Flushed() != 1 {
+		t.Errorf("Connection must be flushed once: %d", rec.Flushed())
+	}
+}
@@ -7,13 +7,13 @@ import (
 // ActionsApply returns a list of instructions with a single element used
 // to apply the set of specified actions.
 func ActionsApply(actions ...ofp.Action) ofp.Instructions {
-	return ofp.Instructions{&ofp.InstructionApplyActions{actions}}
+	return ofp.Instructions{&ofp.InstructionApplyActions{Actions: actions}}
 }
 
 // ActionsWrite returns a list of instructions with a single element used
 // to write the set of specified actions.
 func ActionsWrite(actions ...ofp.Action) ofp.Instructions {
-	return ofp.Instructions{&ofp.InstructionWriteActions{actions}}
+	return ofp.Instructions{&ofp.InstructionWriteActions{Actions: actions}}
 }
 
 // ActionsClear returns a list of instructions with a single element used
@@ -30,8 +30,8 @@ func TestActionsApply(t *testing.T) {
 }
 
 func TestActionsWrite(t *testing.T) {
-	ac1 := ofp.ActionSetNetworkTTL{128}
-	ac2 := ofp.ActionGroup{3}
+	ac1 := ofp.ActionSetNetworkTTL{TTL: 128}
+	ac2 := ofp.ActionGroup{Group: 3}
 
 	its := ActionsWrite(&ac1, &ac2)
 	if len(its) != 1 {
@@ -0,0 +1,50 @@
+package ofputil
+
+import (
+	"fmt"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// GetAsync returns a request used to retrieve the asynchronous
+// configuration of the switch for the current controller connection.
+// The switch replies with the TypeGetAsyncReply message, that could be
+// decoded using AsyncConfig function.
+func GetAsync() *of.Request {
+	return of.NewRequest(of.TypeGetAsyncRequest, nil)
+}
+
+// SetAsync returns a request used to configure the switch to send only
+// the specified asynchronous messages to the current controller
+// connection.
+func SetAsync(config *ofp.AsyncConfig) *of.Request {
+	return of.NewRequest(of.TypeSetAsync, config)
+}
+
+// AsyncConfig decodes the asynchronous configuration from the reply to
+// the GetAsync request.
+//
+// For example, to check that the switch sends the packet-in messages
+// caused by the table miss to the master controller:
+//
+//	config, err := ofputil.AsyncConfig(r)
+//	if err != nil {
+//		// ...
+//	}
+//
+//	config.IsPacketInEnabled(ofp.ControllerRoleMaster,
+//		ofp.PacketInReasonNoMatch)
+func AsyncConfig(r *of.Request) (*ofp.AsyncConfig, error) {
+	if r.Header.Type != of.TypeGetAsyncReply {
+		return nil, fmt.Errorf("ofputil: unexpected message type: %s",
+			r.Header.Type)
+	}
+
+	var config ofp.AsyncConfig
+	if _, err := config.ReadFrom(r.Body); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
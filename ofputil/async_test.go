@@ -0,0 +1,39 @@
+package ofputil
+
+import (
+	"bytes"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestAsyncConfig(t *testing.T) {
+	if r := GetAsync(); r.Header.Type != of.TypeGetAsyncRequest {
+		t.Fatalf("Invalid type of the request: %s", r.Header.Type)
+	}
+
+	config := &ofp.AsyncConfig{
+		PacketInMask: Bitmap64(PacketInReasonBitmap(
+			ofp.PacketInReasonNoMatch), 0),
+	}
+
+	var buf bytes.Buffer
+	config.WriteTo(&buf)
+
+	r := of.NewRequest(of.TypeGetAsyncReply, &buf)
+	reply, err := AsyncConfig(r)
+	if err != nil {
+		t.Fatalf("Failed to decode asynchronous configuration: %s", err)
+	}
+
+	if !reply.IsPacketInEnabled(ofp.ControllerRoleMaster,
+		ofp.PacketInReasonNoMatch) {
+		t.Errorf("Packet-in messages must be enabled: %v", reply)
+	}
+
+	r = SetAsync(config)
+	if _, err = AsyncConfig(r); err == nil {
+		t.Errorf("Error expected for the message of %s type", r.Header.Type)
+	}
+}
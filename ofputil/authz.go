@@ -0,0 +1,285 @@
+package ofputil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// authorizedTypes lists the types of the outgoing messages modifying
+// the state of the switch, that are subject to the authorization.
+var authorizedTypes = map[of.Type]bool{
+	of.TypePacketOut: true,
+	of.TypeFlowMod:   true,
+	of.TypeGroupMod:  true,
+	of.TypePortMod:   true,
+	of.TypeTableMod:  true,
+	of.TypeMeterMod:  true,
+	of.TypeSetConfig: true,
+	of.TypeSetAsync:  true,
+}
+
+// Authorization describes the message subject to the authorization.
+type Authorization struct {
+	// Conn is a connection the message is sent to or received from.
+	Conn of.Conn
+
+	// Inbound is set for the messages received from the connection.
+	Inbound bool
+
+	// Header is a header of the message.
+	Header of.Header
+
+	// Context is a context of the received request, it carries the
+	// values attached by the preceding handlers, like the connection
+	// metadata (see MetadataRegistry). For the replies written by the
+	// handler the context of the served request is used, for the rest
+	// of the outgoing messages the context of the sent request.
+	Context context.Context
+
+	// Message is a decoded body of the message, for example *ofp.FlowMod
+	// for the flow modification. It is nil for the messages without
+	// body and the types of messages unknown to the package.
+	Message io.ReaderFrom
+}
+
+// AuthorizeFunc decides whether the message is permitted. The non-nil
+// error denies the message.
+type AuthorizeFunc func(a *Authorization) error
+
+// AuthorizationError is returned when the outgoing message is denied by
+// the authorization function.
+type AuthorizationError struct {
+	// Type is a type of the denied message.
+	Type of.Type
+
+	// Transaction is a transaction identifier of the denied message.
+	Transaction uint32
+
+	// Err is the error returned by the authorization function.
+	Err error
+}
+
+// Error implements error interface.
+func (e *AuthorizationError) Error() string {
+	return fmt.Sprintf("ofputil: %s (xid=0x%x) is not authorized: %v",
+		e.Type, e.Transaction, e.Err)
+}
+
+// Unwrap returns the error returned by the authorization function.
+func (e *AuthorizationError) Unwrap() error {
+	return e.Err
+}
+
+// decodeBody decodes the body of the request into the type permitted
+// for the message type (see CheckBody). The body of the request is not
+// consumed.
+func decodeBody(r *of.Request) (io.ReaderFrom, error) {
+	raw, err := r.RawBody()
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+
+	if r.Header.Type == of.TypeError {
+		return ofp.ReadError(bytes.NewReader(raw))
+	}
+
+	for _, bodyType := range bodyTypes[r.Header.Type] {
+		if bodyType == noBody {
+			continue
+		}
+
+		body := reflect.New(bodyType.Elem()).Interface().(io.ReaderFrom)
+		if _, err = body.ReadFrom(bytes.NewReader(raw)); err != nil {
+			return nil, err
+		}
+
+		return body, nil
+	}
+
+	return nil, nil
+}
+
+// bundledRequest returns the message embedded into the bundle add
+// message, or nil when the request is not a bundle add message.
+func bundledRequest(r *of.Request) (*of.Request, error) {
+	if r.Header.Type != of.TypeExperiment {
+		return nil, nil
+	}
+
+	raw, err := r.RawBody()
+	if err != nil {
+		return nil, err
+	}
+
+	var header ofp.Experimenter
+	if _, err = header.ReadFrom(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+
+	if header.Experimenter != ofp.ExperimenterONF ||
+		header.ExpType != ofp.ONFExpTypeBundleAdd {
+		return nil, nil
+	}
+
+	var add ofp.BundleAdd
+	if _, err = add.ReadFrom(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+
+	var msg of.Request
+	if _, err = msg.ReadFrom(bytes.NewReader(add.Message)); err != nil {
+		return nil, err
+	}
+
+	return msg.WithContext(r.Context()), nil
+}
+
+// authorizedRequest returns the outgoing message subject to the
+// authorization: the request itself or the message embedded into the
+// bundle add message. Nil is returned when the request does not modify
+// the state of the switch.
+func authorizedRequest(r *of.Request) (*of.Request, error) {
+	if authorizedTypes[r.Header.Type] {
+		return r, nil
+	}
+
+	msg, err := bundledRequest(r)
+	if err != nil || msg == nil || !authorizedTypes[msg.Header.Type] {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// authorizeOutbound authorizes the outgoing request, the
+// *AuthorizationError is returned when the request is denied.
+func authorizeOutbound(fn AuthorizeFunc, conn of.Conn, r *of.Request) error {
+	msg, err := authorizedRequest(r)
+	if err == nil && msg == nil {
+		return nil
+	}
+
+	if err == nil {
+		err = authorize(fn, conn, msg, false)
+	}
+
+	if err != nil {
+		return &AuthorizationError{
+			Type:        r.Header.Type,
+			Transaction: r.Header.Transaction,
+			Err:         err,
+		}
+	}
+
+	return nil
+}
+
+// authorize decodes the request and passes it to the authorization
+// function.
+func authorize(fn AuthorizeFunc, conn of.Conn, r *of.Request, inbound bool) error {
+	body, err := decodeBody(r)
+	if err != nil {
+		return err
+	}
+
+	return fn(&Authorization{
+		Conn:    conn,
+		Inbound: inbound,
+		Header:  r.Header,
+		Context: r.Context(),
+		Message: body,
+	})
+}
+
+// AuthConn is a connection, that authorizes the outgoing messages
+// modifying the state of the switch (packet-out messages, flow, group,
+// port, table and meter modifications, switch and asynchronous
+// configurations) before sending them. Such messages added to the
+// bundle are authorized as well, the authorization function receives
+// the embedded message instead of the bundle add message.
+//
+// For example, to permit the module to modify only the tables 10-20,
+// the module could be given the following connection:
+//
+//	conn := ofputil.NewAuthConn(c, func(a *ofputil.Authorization) error {
+//		fmod, ok := a.Message.(*ofp.FlowMod)
+//		if ok && (fmod.Table < 10 || fmod.Table > 20) {
+//			return fmt.Errorf("table %d is not permitted", fmod.Table)
+//		}
+//		return nil
+//	})
+type AuthConn struct {
+	of.Conn
+
+	authorize AuthorizeFunc
+}
+
+// NewAuthConn creates a new connection authorizing the outgoing
+// messages with the given function.
+func NewAuthConn(c of.Conn, fn AuthorizeFunc) *AuthConn {
+	return &AuthConn{Conn: c, authorize: fn}
+}
+
+// Send sends the request to the underlying connection. When the request
+// modifies the state of the switch and is denied by the authorization
+// function, the *AuthorizationError is returned.
+func (c *AuthConn) Send(r *of.Request) error {
+	if err := authorizeOutbound(c.authorize, c.Conn, r); err != nil {
+		return err
+	}
+
+	return c.Conn.Send(r)
+}
+
+// authResponseWriter authorizes the messages written by the handler.
+type authResponseWriter struct {
+	of.ResponseWriter
+
+	authorize AuthorizeFunc
+	req       *of.Request
+}
+
+// Write authorizes the message and writes it to the underlying response
+// writer. When the message is denied, the *AuthorizationError is
+// returned.
+func (rw *authResponseWriter) Write(h *of.Header, body io.WriterTo) error {
+	r := of.NewRequest(h.Type, body).WithContext(rw.req.Context())
+	r.Header = *h
+
+	raw, err := r.RawBody()
+	if err != nil {
+		return err
+	}
+
+	if err = authorizeOutbound(rw.authorize, rw.req.Conn(), r); err != nil {
+		return err
+	}
+
+	return rw.ResponseWriter.Write(h, bytes.NewReader(raw))
+}
+
+// AuthHandler returns a handler, that authorizes the received requests
+// with the given function before calling the handler h. The denied
+// requests are logged and discarded.
+//
+// The messages written by the handler h to the response writer are
+// authorized in the same way as the messages sent to the AuthConn, so
+// the modules replying with the flow modifications to the packet-in
+// messages are restricted as well. The denied messages are not written,
+// the *AuthorizationError is returned to the handler instead.
+func AuthHandler(fn AuthorizeFunc, h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if err := authorize(fn, r.Conn(), r, true); err != nil {
+			Logf(r, "ofputil: %s is not authorized: %v", r.Header.Type, err)
+			return
+		}
+
+		h.Serve(&authResponseWriter{rw, fn, r}, r)
+	})
+}
@@ -0,0 +1,186 @@
+package ofputil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestAuthConn(t *testing.T) {
+	errTable := errors.New("table is not permitted")
+
+	var inspected []of.Type
+	conn := ofptest.NewConnRecorder()
+	authConn := NewAuthConn(conn, func(a *Authorization) error {
+		inspected = append(inspected, a.Header.Type)
+		if a.Inbound || a.Conn != conn {
+			t.Errorf("Invalid authorization: %v", a)
+		}
+
+		fmod, ok := a.Message.(*ofp.FlowMod)
+		if ok && (fmod.Table < 10 || fmod.Table > 20) {
+			return fmt.Errorf("%w: %d", errTable, fmod.Table)
+		}
+		return nil
+	})
+
+	newFlowMod := func(table ofp.Table) *of.Request {
+		return NewFlowModRequest(&ofp.FlowMod{
+			Table:   table,
+			Command: ofp.FlowAdd,
+			Buffer:  ofp.NoBuffer,
+			Match:   ofp.Match{Type: ofp.MatchTypeXM},
+		})
+	}
+
+	err := of.Send(authConn,
+		newFlowMod(10),
+		of.NewRequest(of.TypeEchoRequest, nil))
+	if err != nil {
+		t.Fatalf("Failed to send permitted messages: %s", err)
+	}
+
+	err = authConn.Send(newFlowMod(21))
+
+	var authErr *AuthorizationError
+	if !errors.As(err, &authErr) || authErr.Type != of.TypeFlowMod {
+		t.Fatalf("Authorization error expected: %v", err)
+	}
+
+	if !errors.Is(err, errTable) {
+		t.Errorf("Error of the authorization function expected: %v", err)
+	}
+
+	if err := conn.ExpectTypes(of.TypeFlowMod, of.TypeEchoRequest); err != nil {
+		t.Errorf("Invalid sent messages: %s", err)
+	}
+
+	var fmod ofp.FlowMod
+	if err := conn.Decode(0, &fmod); err != nil || fmod.Table != 10 {
+		t.Errorf("Body of the message must be sent: %v", err)
+	}
+
+	if len(inspected) != 2 {
+		t.Errorf("Only flow modifications must be authorized: %v", inspected)
+	}
+}
+
+func TestAuthHandler(t *testing.T) {
+	var served []of.Type
+	h := AuthHandler(func(a *Authorization) error {
+		if !a.Inbound {
+			t.Errorf("Inbound message expected")
+		}
+
+		if _, ok := a.Message.(*ofp.PacketIn); !ok {
+			return errors.New("only packet-in messages are permitted")
+		}
+		return nil
+	}, of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		served = append(served, r.Header.Type)
+	}))
+
+	rw := ofptest.NewRecorder()
+	h.Serve(rw, of.NewRequest(of.TypePacketIn, &ofp.PacketIn{
+		Match: ofp.Match{Type: ofp.MatchTypeXM},
+	}))
+	h.Serve(rw, of.NewRequest(of.TypeEchoRequest, nil))
+
+	if len(served) != 1 || served[0] != of.TypePacketIn {
+		t.Errorf("Only permitted messages must be served: %v", served)
+	}
+}
+
+func TestAuthHandlerReply(t *testing.T) {
+	fn := func(a *Authorization) error {
+		if a.Inbound {
+			return nil
+		}
+
+		fmod, ok := a.Message.(*ofp.FlowMod)
+		if ok && fmod.Table != 1 {
+			return fmt.Errorf("table %d is not permitted", fmod.Table)
+		}
+		return nil
+	}
+
+	var errs []error
+	h := AuthHandler(fn, of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		for _, table := range []ofp.Table{1, 2} {
+			fmod := ofp.NewFlowMod(ofp.FlowAdd, nil)
+			fmod.Table = table
+
+			header := r.Header.Copy()
+			header.Type = of.TypeFlowMod
+			errs = append(errs, rw.Write(header, fmod))
+		}
+	}))
+
+	rw := ofptest.NewRecorder()
+	h.Serve(rw, of.NewRequest(of.TypePacketIn, &ofp.PacketIn{
+		Match: ofp.Match{Type: ofp.MatchTypeXM},
+	}))
+
+	var authErr *AuthorizationError
+	if errs[0] != nil || !errors.As(errs[1], &authErr) {
+		t.Fatalf("Reply to the not permitted table must be denied: %v", errs)
+	}
+
+	if err := rw.ExpectTypes(of.TypeFlowMod); err != nil {
+		t.Fatal(err)
+	}
+
+	var fmod ofp.FlowMod
+	if err := rw.Decode(0, &fmod); err != nil || fmod.Table != 1 {
+		t.Errorf("Permitted reply must be written: %v", err)
+	}
+}
+
+func TestAuthConnBundle(t *testing.T) {
+	conn := ofptest.NewConnRecorder()
+	authConn := NewAuthConn(conn, func(a *Authorization) error {
+		fmod, ok := a.Message.(*ofp.FlowMod)
+		if !ok {
+			return fmt.Errorf("unexpected message: %s", a.Header.Type)
+		}
+
+		if fmod.Table != 1 {
+			return fmt.Errorf("table %d is not permitted", fmod.Table)
+		}
+		return nil
+	})
+
+	newBundleAdd := func(table ofp.Table) *of.Request {
+		fmod := ofp.NewFlowMod(ofp.FlowAdd, nil)
+		fmod.Table = table
+
+		req, err := BundleAdd(1, ofp.BundleAtomic, NewFlowModRequest(fmod))
+		if err != nil {
+			t.Fatalf("Failed to create bundle add: %s", err)
+		}
+		return req
+	}
+
+	if err := authConn.Send(newBundleAdd(1)); err != nil {
+		t.Fatalf("Failed to send permitted bundle add: %s", err)
+	}
+
+	var authErr *AuthorizationError
+	err := authConn.Send(newBundleAdd(2))
+	if !errors.As(err, &authErr) || authErr.Type != of.TypeExperiment {
+		t.Fatalf("Bundled flow modification must be denied: %v", err)
+	}
+
+	// The bundle control messages are not authorized.
+	if err = authConn.Send(BundleCommit(1, ofp.BundleAtomic)); err != nil {
+		t.Fatalf("Failed to send bundle commit: %s", err)
+	}
+
+	if err = conn.ExpectTypes(of.TypeExperiment, of.TypeExperiment); err != nil {
+		t.Error(err)
+	}
+}
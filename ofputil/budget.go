@@ -0,0 +1,209 @@
+package ofputil
+
+import (
+	"errors"
+	"sync"
+
+	of "github.com/netrack/openflow"
+)
+
+// ErrBudgetClosed is returned from Send of the budget connection, when
+// the connection was closed while waiting for the barrier reply.
+var ErrBudgetClosed = errors.New("ofputil: budget connection closed")
+
+// budgetTypes is a list of the state-modifying messages, limited by
+// the send budget.
+var budgetTypes = map[of.Type]bool{
+	of.TypeFlowMod:   true,
+	of.TypeGroupMod:  true,
+	of.TypePortMod:   true,
+	of.TypeTableMod:  true,
+	of.TypeMeterMod:  true,
+	of.TypeSetConfig: true,
+	of.TypeSetAsync:  true,
+}
+
+// budgetBarrier is a barrier request sent to the switch, that confirms
+// the consumption of the preceding messages.
+type budgetBarrier struct {
+	xid uint32
+
+	// n is a number of messages sent before the barrier.
+	n int
+
+	// auto is set for the barriers sent by the connection.
+	auto bool
+}
+
+// BudgetConn is a connection, that limits the number of state-modifying
+// messages (flow, group, meter modifications, etc.) in flight, that were
+// not confirmed by the barrier reply. It prevents the controller from
+// overrunning the slow hardware switches.
+//
+// When the budget is exhausted, the connection sends the barrier request
+// and blocks the Send call until the barrier reply is received. Therefore
+// the messages must be received from the connection in the separate
+// goroutine, for example:
+//
+//	conn := ofputil.NewBudgetConn(conn, 64)
+//	go func() {
+//		for {
+//			r, err := conn.Receive()
+//			// ...
+//		}
+//	}()
+//
+//	err := of.Send(conn, flowMods...)
+//
+// The barrier replies to the requests sent by the connection itself are
+// not returned from the Receive.
+type BudgetConn struct {
+	of.Conn
+
+	budget int
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// inflight is a number of messages not confirmed by the barrier
+	// reply, pending is a number of messages sent after the last
+	// barrier request.
+	inflight int
+	pending  int
+	barriers []budgetBarrier
+	closed   bool
+}
+
+// NewBudgetConn creates a new connection, that allows at most budget
+// state-modifying messages in flight. A non-positive budget is treated
+// as a budget of a single message.
+func NewBudgetConn(conn of.Conn, budget int) *BudgetConn {
+	if budget <= 0 {
+		budget = 1
+	}
+
+	c := &BudgetConn{Conn: conn, budget: budget}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// InFlight returns the number of state-modifying messages, that were
+// not confirmed by the barrier reply yet.
+func (c *BudgetConn) InFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inflight
+}
+
+// Send writes the message to the connection. When the message modifies
+// the state of the switch and the budget is exhausted, the call blocks
+// until the barrier reply confirms the consumption of earlier messages.
+func (c *BudgetConn) Send(r *of.Request) error {
+	if r.Header.Type == of.TypeBarrierRequest {
+		c.mu.Lock()
+		c.barrier(r.Header.Transaction, false)
+		c.mu.Unlock()
+
+		return c.Conn.Send(r)
+	}
+
+	if !budgetTypes[r.Header.Type] {
+		return c.Conn.Send(r)
+	}
+
+	c.mu.Lock()
+	for c.inflight >= c.budget && !c.closed {
+		if c.pending == 0 {
+			c.cond.Wait()
+			continue
+		}
+
+		// Confirm the pending messages with the barrier, otherwise
+		// the budget will never be restored.
+		barrier := of.NewRequest(of.TypeBarrierRequest, nil)
+		barrier.Header.Version = r.Header.Version
+		barrier.Header.Transaction = newXID()
+		c.barrier(barrier.Header.Transaction, true)
+		c.mu.Unlock()
+
+		if err := of.Send(c.Conn, barrier); err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+	}
+
+	if c.closed {
+		c.mu.Unlock()
+		return ErrBudgetClosed
+	}
+
+	c.inflight++
+	c.pending++
+	c.mu.Unlock()
+
+	return c.Conn.Send(r)
+}
+
+// barrier records the barrier request covering the pending messages.
+func (c *BudgetConn) barrier(xid uint32, auto bool) {
+	c.barriers = append(c.barriers, budgetBarrier{xid, c.pending, auto})
+	c.pending = 0
+}
+
+// Receive reads the message from the connection. The barrier replies
+// restore the budget of the messages sent before the barrier requests.
+func (c *BudgetConn) Receive() (*of.Request, error) {
+	for {
+		r, err := c.Conn.Receive()
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+
+		if r.Header.Type != of.TypeBarrierReply || !c.release(r) {
+			return r, nil
+		}
+	}
+}
+
+// release restores the budget of the messages confirmed by the barrier
+// reply. The switch processes the barriers in order, thus the earlier
+// barriers are confirmed as well. It returns true, when the barrier was
+// sent by the connection.
+func (c *BudgetConn) release(r *of.Request) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, barrier := range c.barriers {
+		if barrier.xid != r.Header.Transaction {
+			continue
+		}
+
+		for _, confirmed := range c.barriers[:i+1] {
+			c.inflight -= confirmed.n
+		}
+
+		c.barriers = c.barriers[i+1:]
+		c.cond.Broadcast()
+		return barrier.auto
+	}
+
+	return false
+}
+
+// close unblocks the Send calls waiting for the budget.
+func (c *BudgetConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	c.cond.Broadcast()
+}
+
+// Close closes the connection. The blocked Send calls return the
+// ErrBudgetClosed error.
+func (c *BudgetConn) Close() error {
+	c.close()
+	return c.Conn.Close()
+}
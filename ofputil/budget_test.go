@@ -0,0 +1,80 @@
+package ofputil
+
+import (
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestBudgetConn(t *testing.T) {
+	rec := ofptest.NewConnRecorder()
+	conn := NewBudgetConn(rec, 2)
+
+	err := of.Send(conn, NewFlowModRequest(&ofp.FlowMod{}),
+		NewFlowModRequest(&ofp.FlowMod{}), of.NewRequest(of.TypeEchoRequest, nil))
+	if err != nil {
+		t.Fatalf("Failed to send requests within budget: %s", err)
+	}
+
+	if n := conn.InFlight(); n != 2 {
+		t.Fatalf("Invalid number of messages in flight: %d", n)
+	}
+
+	done := make(chan error)
+	go func() { done <- of.Send(conn, NewFlowModRequest(&ofp.FlowMod{})) }()
+
+	// Wait for the barrier request sent by the connection.
+	for i := 0; rec.Len() < 4; i++ {
+		if i == 100 {
+			t.Fatalf("Barrier request expected: %d", rec.Len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("Send must block until the barrier reply: %v", err)
+	default:
+	}
+
+	barrier := rec.All()[3]
+	if barrier.Header.Type != of.TypeBarrierRequest {
+		t.Fatalf("Barrier request expected: %s", barrier.Header.Type)
+	}
+
+	reply := of.NewRequest(of.TypeBarrierReply, nil)
+	reply.Header.Transaction = barrier.Header.Transaction
+	rec.Push(reply, of.NewRequest(of.TypeEchoReply, nil))
+
+	r, err := conn.Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive message: %s", err)
+	}
+
+	if r.Header.Type != of.TypeEchoReply {
+		t.Errorf("Barrier reply must not be returned: %s", r.Header.Type)
+	}
+
+	if err = <-done; err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	if n := conn.InFlight(); n != 1 {
+		t.Errorf("Invalid number of messages in flight: %d", n)
+	}
+
+	// Blocked senders must be released on close.
+	conn = NewBudgetConn(ofptest.NewConnRecorder(), 1)
+	conn.Send(NewFlowModRequest(&ofp.FlowMod{}))
+	conn.Send(of.NewRequest(of.TypeBarrierRequest, nil))
+
+	go func() { done <- conn.Send(NewFlowModRequest(&ofp.FlowMod{})) }()
+	conn.Close()
+
+	if err = <-done; err != ErrBudgetClosed {
+		t.Errorf("Budget closed error expected: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package ofputil
+
+import (
+	"bytes"
+	"fmt"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// bundleControl returns a new bundle control request of the given type.
+func bundleControl(id uint32, t ofp.BundleControlType,
+	flags ofp.BundleFlag) *of.Request {
+
+	return of.NewRequest(of.TypeExperiment, &ofp.BundleControl{
+		Bundle: id, Type: t, Flags: flags,
+	})
+}
+
+// BundleOpen returns a request used to open a new bundle with the given
+// identifier and flags on the OpenFlow 1.3 switch implementing the ONF
+// extension 230.
+func BundleOpen(id uint32, flags ofp.BundleFlag) *of.Request {
+	return bundleControl(id, ofp.BundleOpenRequest, flags)
+}
+
+// BundleCommit returns a request used to apply the messages of the
+// bundle with the given identifier.
+func BundleCommit(id uint32, flags ofp.BundleFlag) *of.Request {
+	return bundleControl(id, ofp.BundleCommitRequest, flags)
+}
+
+// BundleDiscard returns a request used to discard the messages of the
+// bundle with the given identifier.
+func BundleDiscard(id uint32, flags ofp.BundleFlag) *of.Request {
+	return bundleControl(id, ofp.BundleDiscardRequest, flags)
+}
+
+// BundleAdd returns a request used to add the given message to the open
+// bundle. The body of the message is consumed. The bundle add request
+// uses the transaction identifier of the message, so the errors caused
+// by the message could be correlated with it.
+func BundleAdd(id uint32, flags ofp.BundleFlag, r *of.Request) (*of.Request, error) {
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+
+	req := of.NewRequest(of.TypeExperiment, &ofp.BundleAdd{
+		Bundle: id, Flags: flags, Message: buf.Bytes(),
+	})
+
+	req.Header.Version = r.Header.Version
+	req.Header.Transaction = r.Header.Transaction
+	return req, nil
+}
+
+// BundleRequests returns the sequence of requests, that opens a new
+// bundle, adds the given messages and commits the bundle. For example,
+// to install the flow entries atomically on the OpenFlow 1.3 switch:
+//
+//	reqs, err := ofputil.BundleRequests(1, ofp.BundleAtomic,
+//		ofputil.NewFlowModRequest(fmod1),
+//		ofputil.NewFlowModRequest(fmod2))
+//	if err != nil {
+//		// ...
+//	}
+//
+//	of.Send(conn, reqs...)
+//
+// The switch replies to the commit request with the bundle control
+// message of type ofp.BundleCommitReply or with the error.
+func BundleRequests(id uint32, flags ofp.BundleFlag,
+	msgs ...*of.Request) ([]*of.Request, error) {
+
+	reqs := []*of.Request{BundleOpen(id, flags)}
+	for _, msg := range msgs {
+		req, err := BundleAdd(id, flags, msg)
+		if err != nil {
+			return nil, err
+		}
+
+		reqs = append(reqs, req)
+	}
+
+	return append(reqs, BundleCommit(id, flags)), nil
+}
+
+// BundleReply decodes the bundle control message from the reply of the
+// switch to the bundle control request.
+func BundleReply(r *of.Request) (*ofp.BundleControl, error) {
+	if r.Header.Type != of.TypeExperiment {
+		return nil, fmt.Errorf("ofputil: unexpected message type: %s",
+			r.Header.Type)
+	}
+
+	var control ofp.BundleControl
+	if err := r.Decode(&control); err != nil {
+		return nil, err
+	}
+
+	return &control, nil
+}
@@ -0,0 +1,63 @@
+package ofputil
+
+import (
+	"bytes"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestBundleRequests(t *testing.T) {
+	fmod := NewFlowModRequest(&ofp.FlowMod{
+		Command: ofp.FlowAdd,
+		Buffer:  ofp.NoBuffer,
+		Match:   ofp.Match{Type: ofp.MatchTypeXM},
+	})
+	fmod.Header.Transaction = 0x2a
+
+	reqs, err := BundleRequests(1, ofp.BundleAtomic, fmod)
+	if err != nil {
+		t.Fatalf("Failed to create bundle requests: %s", err)
+	}
+
+	if len(reqs) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(reqs))
+	}
+
+	for i, typ := range []ofp.BundleControlType{
+		ofp.BundleOpenRequest, ofp.BundleCommitRequest} {
+
+		control, err := BundleReply(reqs[i*2])
+		if err != nil {
+			t.Fatalf("Failed to decode bundle control: %s", err)
+		}
+
+		if control.Type != typ || control.Bundle != 1 {
+			t.Errorf("Invalid bundle control: %v", control)
+		}
+	}
+
+	var add ofp.BundleAdd
+	if err = reqs[1].Decode(&add); err != nil {
+		t.Fatalf("Failed to decode bundle add: %s", err)
+	}
+
+	if reqs[1].Header.Transaction != 0x2a {
+		t.Errorf("Transaction of the message must be used: %d",
+			reqs[1].Header.Transaction)
+	}
+
+	var r of.Request
+	if _, err = r.ReadFrom(bytes.NewReader(add.Message)); err != nil {
+		t.Fatalf("Failed to read bundled message: %s", err)
+	}
+
+	if r.Header.Type != of.TypeFlowMod || r.Header.Transaction != 0x2a {
+		t.Errorf("Invalid bundled message: %v", r.Header)
+	}
+
+	if _, err = BundleReply(fmod); err == nil {
+		t.Errorf("Error expected for non-experimenter message")
+	}
+}
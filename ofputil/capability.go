@@ -0,0 +1,112 @@
+package ofputil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrNotSupported is returned when the switch lacks the capability
+// required by the helper, so the request is rejected before it is sent
+// to the switch.
+var ErrNotSupported = errors.New("ofputil: not supported by the switch")
+
+// multipartCapabilities maps the multipart statistics types to the
+// switch capabilities required to serve them.
+var multipartCapabilities = map[ofp.MultipartType]ofp.Capability{
+	ofp.MultipartTypeFlow:      ofp.CapabilityFlowStats,
+	ofp.MultipartTypeAggregate: ofp.CapabilityFlowStats,
+	ofp.MultipartTypeTable:     ofp.CapabilityTableStats,
+	ofp.MultipartTypePortStats: ofp.CapabilityPortStats,
+	ofp.MultipartTypeQueue:     ofp.CapabilityQueueStats,
+	ofp.MultipartTypeGroup:     ofp.CapabilityGroupStats,
+}
+
+// SwitchCapabilities are the capabilities of the switch probed by the
+// controller. The helpers consult the capabilities and fail early with
+// ErrNotSupported, instead of waiting for the error from the switch.
+//
+// The capabilities which were not probed are left nil, thus the
+// respective requests are not validated. The nil SwitchCapabilities
+// permits all requests. For example, to gate the statistics requests:
+//
+//	features, err := handshake.Run(conn)
+//	if err != nil {
+//		// ...
+//	}
+//
+//	demux := ofputil.NewMultipartDemux()
+//	demux.Capabilities = &ofputil.SwitchCapabilities{Features: features}
+type SwitchCapabilities struct {
+	// Features is the features reply of the switch.
+	Features *ofp.SwitchFeatures
+
+	// Groups are the group features of the switch.
+	Groups *ofp.GroupFeatures
+
+	// Meters are the meter features of the switch.
+	Meters *ofp.MeterFeatures
+
+	// Quirks are the workarounds of the switch firmware (see
+	// QuirkRegistry). The multipart requests listed as unsupported
+	// are rejected.
+	Quirks *Quirks
+}
+
+// CheckMultipart returns ErrNotSupported, when the switch lacks the
+// capability to serve the multipart request of the given type.
+func (c *SwitchCapabilities) CheckMultipart(t ofp.MultipartType) error {
+	if c == nil {
+		return nil
+	}
+
+	if err := c.Quirks.CheckMultipart(t); err != nil {
+		return err
+	}
+
+	switch t {
+	case ofp.MultipartTypeMeter, ofp.MultipartTypeMeterConfig:
+		return c.CheckMeters()
+	}
+
+	capability, ok := multipartCapabilities[t]
+	if !ok || c.Features == nil {
+		return nil
+	}
+
+	if c.Features.Capabilities&capability == 0 {
+		return fmt.Errorf("%w: %s", ErrNotSupported, capability)
+	}
+
+	return nil
+}
+
+// CheckGroup returns ErrNotSupported, when the type of the group added
+// or modified by the given message is not supported by the switch.
+func (c *SwitchCapabilities) CheckGroup(mod *ofp.GroupMod) error {
+	if c == nil || c.Groups == nil || mod.Command == ofp.GroupDelete {
+		return nil
+	}
+
+	if c.Groups.Types&(1<<mod.Type) == 0 {
+		return fmt.Errorf("%w: group type %d of group %d",
+			ErrNotSupported, mod.Type, mod.Group)
+	}
+
+	return nil
+}
+
+// CheckMeters returns ErrNotSupported, when the switch does not support
+// the meters.
+func (c *SwitchCapabilities) CheckMeters() error {
+	if c == nil || c.Meters == nil {
+		return nil
+	}
+
+	if c.Meters.MaxMeter == 0 {
+		return fmt.Errorf("%w: meters", ErrNotSupported)
+	}
+
+	return nil
+}
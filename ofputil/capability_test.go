@@ -0,0 +1,76 @@
+package ofputil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestSwitchCapabilities(t *testing.T) {
+	caps := &SwitchCapabilities{
+		Features: &ofp.SwitchFeatures{
+			Capabilities: ofp.CapabilityFlowStats | ofp.CapabilityPortStats,
+		},
+		Groups: &ofp.GroupFeatures{Types: 1 << ofp.GroupTypeAll},
+		Meters: &ofp.MeterFeatures{},
+	}
+
+	tests := []struct {
+		Type ofp.MultipartType
+		Err  error
+	}{
+		{ofp.MultipartTypeFlow, nil},
+		{ofp.MultipartTypePortStats, nil},
+		{ofp.MultipartTypeDescription, nil},
+		{ofp.MultipartTypeQueue, ErrNotSupported},
+		{ofp.MultipartTypeGroup, ErrNotSupported},
+		{ofp.MultipartTypeMeter, ErrNotSupported},
+	}
+
+	for _, test := range tests {
+		if err := caps.CheckMultipart(test.Type); !errors.Is(err, test.Err) {
+			t.Errorf("Invalid error for %s request: %v", test.Type, err)
+		}
+	}
+
+	var nilCaps *SwitchCapabilities
+	if err := nilCaps.CheckMultipart(ofp.MultipartTypeQueue); err != nil {
+		t.Errorf("Nil capabilities must permit requests: %s", err)
+	}
+
+	table := NewGroupTable()
+	table.Capabilities = caps
+
+	_, err := table.Install(&ofp.GroupMod{
+		Command: ofp.GroupAdd, Type: ofp.GroupTypeSelect, Group: 1})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Select group must not be supported: %v", err)
+	}
+
+	_, err = table.Install(&ofp.GroupMod{
+		Command: ofp.GroupAdd, Type: ofp.GroupTypeAll, Group: 1})
+	if err != nil {
+		t.Errorf("Failed to install group: %s", err)
+	}
+
+	demux := NewMultipartDemux()
+	demux.Capabilities = caps
+
+	conn := ofptest.NewConnRecorder()
+	_, err = demux.Do(context.Background(), conn, ofp.MultipartTypeQueue)
+	if !errors.Is(err, ErrNotSupported) || conn.Len() != 0 {
+		t.Errorf("Queue statistics must not be requested: %v", err)
+	}
+
+	policy := &QoSPolicy{
+		MeterFeatures: caps.Meters,
+		Rules:         []QoSRule{{Port: 1, Meter: 1, Rate: 1}},
+	}
+
+	if _, err = policy.Requests(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Meters must not be supported: %v", err)
+	}
+}
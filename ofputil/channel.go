@@ -0,0 +1,208 @@
+package ofputil
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ChannelStat is a snapshot of the message counters of a single channel
+// (main or auxiliary connection) of the switch.
+type ChannelStat struct {
+	// DatapathID is a datapath identifier of the switch. It is zero
+	// until the features reply is received from the channel.
+	DatapathID ofp.DatapathID
+
+	// AuxiliaryID identifies the auxiliary channel, zero is used for
+	// the main channel.
+	AuxiliaryID uint8
+
+	// Received is a number of received messages by type.
+	Received map[of.Type]uint64
+
+	// Sent is a number of sent messages by type.
+	Sent map[of.Type]uint64
+}
+
+// channelState is a state of the single channel.
+type channelState struct {
+	datapathID  ofp.DatapathID
+	auxiliaryID uint8
+	received    map[of.Type]uint64
+	sent        map[of.Type]uint64
+}
+
+// ChannelStats counts the messages of each type flowing over the main
+// and auxiliary channels of the switches. The channel is identified by
+// the features reply received from the connection, so it could be used
+// to validate that the packet-in and packet-out messages are offloaded
+// to the auxiliary channels.
+//
+// For example, to count the messages received and replied by the
+// server, and to remove the counters of the closed connections:
+//
+//	stats := ofputil.NewChannelStats()
+//	srv := &of.Server{
+//		Addr:      ":6633",
+//		Handler:   stats.Handler(mux),
+//		ConnState: stats.ConnState,
+//	}
+//
+// The server accepts a single ConnState function, so when the states of
+// the connections are tracked by other components too, the hooks are
+// combined with of.ConnStateHooks. Once the hook is installed, only the
+// messages of the connections reported as new by the server are
+// counted, so the handlers completed after the connection is closed
+// don't create the counters of the closed connection again.
+type ChannelStats struct {
+	mu       sync.Mutex
+	channels map[of.Conn]*channelState
+	conns    connSet
+}
+
+// NewChannelStats creates a new empty channel statistics.
+func NewChannelStats() *ChannelStats {
+	return &ChannelStats{channels: make(map[of.Conn]*channelState)}
+}
+
+// channel returns the state of the channel of the given connection,
+// nil is returned for the closed connections. The caller must hold the
+// lock.
+func (s *ChannelStats) channel(conn of.Conn) *channelState {
+	ch, ok := s.channels[conn]
+	if !ok && s.conns.isOpen(conn) {
+		ch = &channelState{
+			received: make(map[of.Type]uint64),
+			sent:     make(map[of.Type]uint64),
+		}
+		s.channels[conn] = ch
+	}
+
+	return ch
+}
+
+// Receive counts the message received from the connection. The features
+// reply assigns the datapath and auxiliary identifiers to the channel,
+// the body of the request is not consumed.
+func (s *ChannelStats) Receive(conn of.Conn, r *of.Request) {
+	var features *ofp.SwitchFeatures
+	if r.Header.Type == of.TypeFeaturesReply {
+		features = new(ofp.SwitchFeatures)
+		if r.Decode(features) != nil {
+			features = nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := s.channel(conn)
+	if ch == nil {
+		return
+	}
+
+	ch.received[r.Header.Type]++
+	if features != nil {
+		ch.datapathID = features.DatapathID
+		ch.auxiliaryID = features.AuxiliaryID
+	}
+}
+
+// Send counts the message sent to the connection.
+func (s *ChannelStats) Send(conn of.Conn, r *of.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ch := s.channel(conn); ch != nil {
+		ch.sent[r.Header.Type]++
+	}
+}
+
+// Remove removes the counters of the closed connection.
+func (s *ChannelStats) Remove(conn of.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.channels, conn)
+}
+
+// ConnState tracks the connections accepted by the server and removes
+// the counters of the closed ones. Without the hook the counters of the
+// closed connections are kept until removed with Remove.
+func (s *ChannelStats) ConnState(conn of.Conn, state of.ConnState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conns.update(conn, state)
+	if state == of.StateClosed {
+		delete(s.channels, conn)
+	}
+}
+
+// channelResponseWriter counts the messages written by the handler.
+type channelResponseWriter struct {
+	of.ResponseWriter
+
+	stats *ChannelStats
+	conn  of.Conn
+}
+
+// Write writes the message to the underlying response writer and counts
+// it as sent, when the write succeeds.
+func (rw *channelResponseWriter) Write(h *of.Header, body io.WriterTo) error {
+	if err := rw.ResponseWriter.Write(h, body); err != nil {
+		return err
+	}
+
+	rw.stats.Send(rw.conn, &of.Request{Header: *h})
+	return nil
+}
+
+// Handler returns a handler, that counts the requests received from the
+// connections and then calls the given handler. The messages written by
+// the handler h to the response writer are counted as sent.
+func (s *ChannelStats) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		s.Receive(r.Conn(), r)
+		h.Serve(&channelResponseWriter{rw, s, r.Conn()}, r)
+	})
+}
+
+// Stats returns the snapshot of the counters of all channels ordered by
+// the datapath and auxiliary identifiers.
+func (s *ChannelStats) Stats() []ChannelStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]ChannelStat, 0, len(s.channels))
+	for _, ch := range s.channels {
+		stat := ChannelStat{
+			DatapathID:  ch.datapathID,
+			AuxiliaryID: ch.auxiliaryID,
+			Received:    make(map[of.Type]uint64, len(ch.received)),
+			Sent:        make(map[of.Type]uint64, len(ch.sent)),
+		}
+
+		for t, n := range ch.received {
+			stat.Received[t] = n
+		}
+
+		for t, n := range ch.sent {
+			stat.Sent[t] = n
+		}
+
+		stats = append(stats, stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].DatapathID != stats[j].DatapathID {
+			return stats[i].DatapathID < stats[j].DatapathID
+		}
+		return stats[i].AuxiliaryID < stats[j].AuxiliaryID
+	})
+
+	return stats
+}
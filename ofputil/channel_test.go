@@ -0,0 +1,148 @@
+package ofputil
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestChannelStats(t *testing.T) {
+	main, aux := ofptest.NewConnRecorder(), ofptest.NewConnRecorder()
+	stats := NewChannelStats()
+
+	features := func(auxID uint8) *of.Request {
+		var buf bytes.Buffer
+		f := ofp.SwitchFeatures{DatapathID: 1, AuxiliaryID: auxID}
+		f.WriteTo(&buf)
+		return of.NewRequest(of.TypeFeaturesReply, &buf)
+	}
+
+	stats.Receive(aux, features(1))
+	stats.Receive(main, features(0))
+	stats.Receive(aux, of.NewRequest(of.TypePacketIn, nil))
+	stats.Send(aux, of.NewRequest(of.TypePacketOut, nil))
+
+	r := features(0)
+	stats.Receive(main, r)
+	var f ofp.SwitchFeatures
+	if _, err := f.ReadFrom(r.Body); err != nil || f.DatapathID != 1 {
+		t.Errorf("Body of the request must not be consumed: %v", err)
+	}
+
+	channels := stats.Stats()
+	if len(channels) != 2 {
+		t.Fatalf("Expected 2 channels, got %d", len(channels))
+	}
+
+	if channels[0].AuxiliaryID != 0 || channels[1].AuxiliaryID != 1 {
+		t.Fatalf("Channels must be ordered: %v", channels)
+	}
+
+	if n := channels[1].Received[of.TypePacketIn]; n != 1 {
+		t.Errorf("Packet-in must be received on auxiliary channel: %d", n)
+	}
+
+	if n := channels[1].Sent[of.TypePacketOut]; n != 1 {
+		t.Errorf("Packet-out must be sent on auxiliary channel: %d", n)
+	}
+
+	if n := channels[0].Received[of.TypeFeaturesReply]; n != 2 {
+		t.Errorf("Invalid number of features replies: %d", n)
+	}
+
+	stats.Remove(aux)
+	if len(stats.Stats()) != 1 {
+		t.Errorf("Channel must be removed")
+	}
+}
+
+func TestChannelStatsHandler(t *testing.T) {
+	stats := NewChannelStats()
+	h := stats.Handler(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		rw.Write(&of.Header{Type: of.TypePacketOut}, nil)
+	}))
+
+	h.Serve(ofptest.NewRecorder(), of.NewRequest(of.TypePacketIn, nil))
+
+	channels := stats.Stats()
+	if len(channels) != 1 {
+		t.Fatalf("Expected 1 channel, got %d", len(channels))
+	}
+
+	if n := channels[0].Received[of.TypePacketIn]; n != 1 {
+		t.Errorf("Packet-in must be received: %d", n)
+	}
+
+	if n := channels[0].Sent[of.TypePacketOut]; n != 1 {
+		t.Errorf("Reply of the handler must be sent: %d", n)
+	}
+
+	stats.ConnState(nil, of.StateActive)
+	if len(stats.Stats()) != 1 {
+		t.Errorf("Channel of the active connection must be kept")
+	}
+
+	stats.ConnState(nil, of.StateClosed)
+	if len(stats.Stats()) != 0 {
+		t.Errorf("Channel of the closed connection must be removed")
+	}
+}
+
+// serveAfterClose sends the request to the server and serves it with
+// the given handler after the connection is closed and reported to the
+// hook, like the handler scheduled late by the server runner.
+func serveAfterClose(t *testing.T, req *of.Request, h of.Handler,
+	hook func(of.Conn, of.ConnState)) {
+
+	closed := make(chan struct{})
+	done := make(chan struct{})
+
+	srv := ofptest.NewUnstartedServer(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		defer close(done)
+		<-closed
+		h.Serve(rw, r)
+	}), nil)
+
+	srv.Config.ConnState = of.ConnStateHooks(hook,
+		func(conn of.Conn, state of.ConnState) {
+			if state == of.StateClosed {
+				close(closed)
+			}
+		})
+
+	srv.Start()
+	defer srv.Close()
+
+	conn, err := of.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial server: %s", err)
+	}
+
+	if err = of.Send(conn, req); err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	conn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Handler was not called")
+	}
+}
+
+func TestChannelStatsClosed(t *testing.T) {
+	stats := NewChannelStats()
+	h := stats.Handler(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		stats.Send(r.Conn(), of.NewRequest(of.TypePacketOut, nil))
+	}))
+
+	serveAfterClose(t, of.NewRequest(of.TypePacketIn, nil), h, stats.ConnState)
+	if channels := stats.Stats(); len(channels) != 0 {
+		t.Errorf("Channel of the closed connection must not be created: %v", channels)
+	}
+}
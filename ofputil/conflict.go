@@ -0,0 +1,271 @@
+package ofputil
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+// FlowConflictType defines a type of the conflict between two flows.
+type FlowConflictType int
+
+const (
+	// FlowConflictOverlap means that a single packet may match both
+	// flows of the same priority. Such flows are rejected by the switch
+	// when the ofp.FlowFlagCheckOverlap flag is set, and otherwise the
+	// order of matching is undefined.
+	FlowConflictOverlap FlowConflictType = iota
+
+	// FlowConflictShadow means that the flow of the higher priority
+	// fully covers the flow of the lower priority, so the latter never
+	// matches any packet.
+	FlowConflictShadow
+
+	// FlowConflictOrder means that the flow of the higher priority is
+	// installed after the overlapping flow of the lower priority within
+	// the same batch. Until the former is installed, the packets it is
+	// intended for are processed by the latter, for example dropped.
+	FlowConflictOrder
+
+	// FlowConflictOrderSplit is the ordering conflict of the flows
+	// separated by a command of other type, for example modification
+	// or deletion. The FlowOrder function does not move the flows
+	// across such commands, so the batch must be reordered manually.
+	FlowConflictOrderSplit
+)
+
+func (t FlowConflictType) String() string {
+	text, ok := flowConflictTypeText[t]
+	if !ok {
+		return fmt.Sprintf("FlowConflictType(%d)", t)
+	}
+	return text
+}
+
+var flowConflictTypeText = map[FlowConflictType]string{
+	FlowConflictOverlap:    "FlowConflictOverlap",
+	FlowConflictShadow:     "FlowConflictShadow",
+	FlowConflictOrder:      "FlowConflictOrder",
+	FlowConflictOrderSplit: "FlowConflictOrderSplit",
+}
+
+// FlowConflict describes a conflict between two flows of the same table.
+type FlowConflict struct {
+	// Type is a type of the conflict.
+	Type FlowConflictType
+
+	// Flow is a flow that conflicts with another flow. For shadowing
+	// conflicts it is the flow that never matches.
+	Flow *ofp.FlowMod
+
+	// Other is a conflicting flow. For shadowing conflicts it is the
+	// flow of the higher priority covering the Flow. For ordering
+	// conflicts it is the flow of the lower priority installed first.
+	Other *ofp.FlowMod
+}
+
+// FlowConflicts returns a list of conflicts between the given flows.
+// Only flows of the same table are compared with each other. The match
+// fields are compared as is, prerequisites are not taken into account.
+//
+// For example, to find the flows that never match, the following code
+// could be used:
+//
+//	for _, c := range ofputil.FlowConflicts(flows) {
+//		if c.Type == ofputil.FlowConflictShadow {
+//			log.Printf("flow %v is shadowed by %v", c.Flow, c.Other)
+//		}
+//	}
+func FlowConflicts(flows []*ofp.FlowMod) []FlowConflict {
+	var conflicts []FlowConflict
+
+	for i, flow := range flows {
+		for _, other := range flows[i+1:] {
+			if flow.Table != other.Table {
+				continue
+			}
+
+			if flow.Priority == other.Priority {
+				if MatchOverlaps(flow.Match, other.Match) {
+					conflicts = append(conflicts, FlowConflict{
+						FlowConflictOverlap, flow, other})
+				}
+				continue
+			}
+
+			// Order the flows by priority to check if the flow
+			// with a lower priority is covered by another one.
+			high, low := flow, other
+			if high.Priority < low.Priority {
+				high, low = low, high
+			}
+
+			if MatchCovers(high.Match, low.Match) {
+				conflicts = append(conflicts, FlowConflict{
+					FlowConflictShadow, low, high})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// FlowOrderConflicts returns a list of ordering conflicts in the batch
+// of flow modifications, sent to the switch in the given order. Only
+// flow additions of the same table are compared with each other.
+//
+// The conflict is reported, when the flow of the higher priority is
+// installed after the overlapping flow of the lower priority, so the
+// traffic could be transiently misforwarded by the latter. Conflicts
+// of the consecutive flow additions are reported as FlowConflictOrder,
+// such batch could be fixed with FlowOrder function. Conflicts of the
+// flows separated by other commands are reported as
+// FlowConflictOrderSplit.
+func FlowOrderConflicts(flows []*ofp.FlowMod) []FlowConflict {
+	var conflicts []FlowConflict
+
+	// The first flow addition after the last command of other type.
+	start := 0
+
+	for i, flow := range flows {
+		if flow.Command != ofp.FlowAdd {
+			start = i + 1
+			continue
+		}
+
+		for j, other := range flows[:i] {
+			if other.Command != ofp.FlowAdd || flow.Table != other.Table {
+				continue
+			}
+
+			if flow.Priority <= other.Priority || !MatchOverlaps(flow.Match, other.Match) {
+				continue
+			}
+
+			conflictType := FlowConflictOrder
+			if j < start {
+				conflictType = FlowConflictOrderSplit
+			}
+
+			conflicts = append(conflicts, FlowConflict{
+				conflictType, flow, other})
+		}
+	}
+
+	return conflicts
+}
+
+// FlowOrder returns a copy of the batch of flow modifications, where
+// the consecutive flow additions are ordered by descending priority,
+// so the specific flows are installed before the broad ones. The flow
+// additions of the same priority keep the original order, and other
+// commands are not moved, so the result of the batch is preserved.
+//
+// For example, to install the batch without transient misforwarding:
+//
+//	if len(ofputil.FlowOrderConflicts(flows)) != 0 {
+//		flows = ofputil.FlowOrder(flows)
+//	}
+func FlowOrder(flows []*ofp.FlowMod) []*ofp.FlowMod {
+	ordered := make([]*ofp.FlowMod, len(flows))
+	copy(ordered, flows)
+
+	for i := 0; i < len(ordered); {
+		j := i
+		for j < len(ordered) && ordered[j].Command == ofp.FlowAdd {
+			j++
+		}
+
+		adds := ordered[i:j]
+		sort.SliceStable(adds, func(a, b int) bool {
+			return adds[a].Priority > adds[b].Priority
+		})
+
+		i = j + 1
+	}
+
+	return ordered
+}
+
+// xmKey uniquely identifies the match field.
+type xmKey struct {
+	Class ofp.XMClass
+	Type  ofp.XMType
+}
+
+// matchFields returns the fields of the match indexed by the class and
+// type of the field.
+func matchFields(m ofp.Match) map[xmKey]ofp.XM {
+	fields := make(map[xmKey]ofp.XM, len(m.Fields))
+	for _, xm := range m.Fields {
+		fields[xmKey{xm.Class, xm.Type}] = xm
+	}
+
+	return fields
+}
+
+// xmMask returns the mask of the match field. When the mask is not
+// specified, the field is matched exactly.
+func xmMask(xm ofp.XM) []byte {
+	if len(xm.Mask) == len(xm.Value) {
+		return xm.Mask
+	}
+
+	mask := make([]byte, len(xm.Value))
+	for i := range mask {
+		mask[i] = 0xff
+	}
+
+	return mask
+}
+
+// MatchOverlaps reports whether a single packet may match both matches.
+func MatchOverlaps(a, b ofp.Match) bool {
+	fields := matchFields(b)
+
+	for _, xa := range a.Fields {
+		xb, ok := fields[xmKey{xa.Class, xa.Type}]
+		if !ok {
+			continue
+		}
+
+		if len(xa.Value) != len(xb.Value) {
+			return false
+		}
+
+		ma, mb := xmMask(xa), xmMask(xb)
+		for i := range xa.Value {
+			// Values must be equal in bits matched by both fields.
+			if (xa.Value[i]^xb.Value[i])&ma[i]&mb[i] != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// MatchCovers reports whether each packet matching the match b also
+// matches the match a.
+func MatchCovers(a, b ofp.Match) bool {
+	fields := matchFields(b)
+
+	for _, xa := range a.Fields {
+		xb, ok := fields[xmKey{xa.Class, xa.Type}]
+		if !ok || len(xa.Value) != len(xb.Value) {
+			return false
+		}
+
+		ma, mb := xmMask(xa), xmMask(xb)
+		for i := range xa.Value {
+			// All bits matched by the field a must be matched by
+			// the field b with the same value.
+			if ma[i]&^mb[i] != 0 || (xa.Value[i]^xb.Value[i])&ma[i] != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,133 @@
+package ofputil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestFlowConflicts(t *testing.T) {
+	// Matches all IPv6 packets received on the first port.
+	f1 := &ofp.FlowMod{Priority: 10, Match: ExtendedMatch(
+		MatchInPort(1), MatchEthType(0x86dd),
+	)}
+
+	// Never matches, since the first flow covers it.
+	f2 := &ofp.FlowMod{Priority: 5, Match: ExtendedMatch(
+		MatchEthType(0x86dd), MatchInPort(1), MatchIPProto(58),
+	)}
+
+	// Overlaps with the first flow for IPv6 packets.
+	f3 := &ofp.FlowMod{Priority: 10, Match: ExtendedMatch(
+		MatchEthType(0x86dd),
+	)}
+
+	// Does not conflict with any flow, it is in another table.
+	f4 := &ofp.FlowMod{Table: 1, Priority: 10, Match: ExtendedMatch(
+		MatchEthType(0x86dd),
+	)}
+
+	// Does not intersect with the first flow.
+	f5 := &ofp.FlowMod{Priority: 10, Match: ExtendedMatch(
+		MatchInPort(2),
+	)}
+
+	conflicts := FlowConflicts([]*ofp.FlowMod{f1, f2, f3, f4, f5})
+	expected := []FlowConflict{
+		{FlowConflictShadow, f2, f1},
+		{FlowConflictOverlap, f1, f3},
+		{FlowConflictShadow, f2, f3},
+		{FlowConflictOverlap, f3, f5},
+	}
+
+	if len(conflicts) != len(expected) {
+		t.Fatalf("Expected %d conflicts, got %d: %v",
+			len(expected), len(conflicts), conflicts)
+	}
+
+	for i, conflict := range conflicts {
+		if conflict != expected[i] {
+			t.Errorf("Invalid conflict %d: %v, expected %v",
+				i, conflict, expected[i])
+		}
+	}
+}
+
+func TestMatchCovers(t *testing.T) {
+	masked := ofp.XM{
+		Class: ofp.XMClassOpenflowBasic,
+		Type:  ofp.XMTypeMetadata,
+		Value: ofp.XMValue{0x10, 0x00},
+		Mask:  ofp.XMValue{0xf0, 0x00},
+	}
+
+	exact := ofp.XM{
+		Class: ofp.XMClassOpenflowBasic,
+		Type:  ofp.XMTypeMetadata,
+		Value: ofp.XMValue{0x1f, 0x01},
+	}
+
+	if !MatchCovers(ExtendedMatch(masked), ExtendedMatch(exact)) {
+		t.Errorf("Masked match is expected to cover exact match")
+	}
+
+	if MatchCovers(ExtendedMatch(exact), ExtendedMatch(masked)) {
+		t.Errorf("Exact match is not expected to cover masked match")
+	}
+
+	if !MatchOverlaps(ExtendedMatch(exact), ExtendedMatch(masked)) {
+		t.Errorf("Exact match is expected to overlap masked match")
+	}
+}
+
+func TestFlowOrderConflicts(t *testing.T) {
+	// Drops all packets received on the first port.
+	drop := &ofp.FlowMod{Priority: 1, Match: ExtendedMatch(MatchInPort(1))}
+
+	// Forwards IPv6 packets received on the first port.
+	f1 := &ofp.FlowMod{Priority: 10, Match: ExtendedMatch(
+		MatchInPort(1), MatchEthType(0x86dd),
+	)}
+
+	// Does not overlap with the drop flow.
+	f2 := &ofp.FlowMod{Priority: 10, Match: ExtendedMatch(MatchInPort(2))}
+
+	// Splits the batch, flow additions are not moved across it.
+	del := &ofp.FlowMod{Command: ofp.FlowDelete, Match: ExtendedMatch()}
+
+	f3 := &ofp.FlowMod{Priority: 20, Match: ExtendedMatch(MatchInPort(1))}
+
+	flows := []*ofp.FlowMod{drop, f1, f2, del, f3}
+
+	conflicts := FlowOrderConflicts(flows)
+	expected := []FlowConflict{
+		{FlowConflictOrder, f1, drop},
+		{FlowConflictOrderSplit, f3, drop},
+		{FlowConflictOrderSplit, f3, f1},
+	}
+
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Fatalf("Invalid ordering conflicts: %v", conflicts)
+	}
+
+	ordered := FlowOrder(flows)
+	if !reflect.DeepEqual(ordered, []*ofp.FlowMod{f1, f2, drop, del, f3}) {
+		t.Fatalf("Invalid order of flows: %v", ordered)
+	}
+
+	if flows[0] != drop {
+		t.Errorf("Original batch must not be changed")
+	}
+
+	if conflicts = FlowOrderConflicts(ordered[:3]); len(conflicts) != 0 {
+		t.Errorf("Ordered flows must not conflict: %v", conflicts)
+	}
+
+	// The flows separated by other commands are not reordered.
+	for _, conflict := range FlowOrderConflicts(ordered) {
+		if conflict.Type != FlowConflictOrderSplit {
+			t.Errorf("Conflict must not be fixed by ordering: %v", conflict)
+		}
+	}
+}
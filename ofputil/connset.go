@@ -0,0 +1,47 @@
+package ofputil
+
+import (
+	of "github.com/netrack/openflow"
+)
+
+// connSet is a set of the open connections reported by the ConnState
+// hook of the server. The handlers run concurrently with the receive
+// loop, so the handler could complete after the connection is closed
+// and its state is removed by the hook. The registries create the
+// state of the connections on demand only for the open connections,
+// so the late handlers don't re-create the state, that would never be
+// removed.
+//
+// Until the hook reports the first new connection, all connections are
+// considered open, so the registries could be used without the server.
+// The access to the set must be synchronized by the caller.
+type connSet struct {
+	hooked bool
+	open   map[of.Conn]struct{}
+}
+
+// update tracks the state change of the connection reported by the
+// ConnState hook.
+func (s *connSet) update(conn of.Conn, state of.ConnState) {
+	switch state {
+	case of.StateNew:
+		if s.open == nil {
+			s.open = make(map[of.Conn]struct{})
+		}
+
+		s.hooked = true
+		s.open[conn] = struct{}{}
+	case of.StateClosed:
+		delete(s.open, conn)
+	}
+}
+
+// isOpen reports whether the state of the connection could be created.
+func (s *connSet) isOpen(conn of.Conn) bool {
+	if !s.hooked {
+		return true
+	}
+
+	_, ok := s.open[conn]
+	return ok
+}
@@ -0,0 +1,266 @@
+package ofputil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// FlowDedupConn suppresses the flow add commands identical to the flow
+// entries already known to be installed on the switch, so the periodic
+// reconciliation does not churn the flow tables and reset the counters
+// of the flow entries.
+//
+// The connection caches the hashes of the sent flow entries indexed
+// by the table, priority and canonical match. The flow entries with
+// timeouts are never suppressed, since they could expire silently,
+// and the flow entries referring to the packets buffered at the switch
+// are neither suppressed nor cached, so the packets are released.
+// The modify and delete commands invalidate the affected entries, the
+// flow removed messages received from the switch invalidate the
+// removed entries, and the error messages invalidate the whole cache,
+// since the failed request could not be identified. When the messages
+// are served by the of.Server, they must be passed through the handler
+// returned by the Handler method.
+//
+// The cache is only a hint of the switch state. The flow entries
+// removed without notification, for example by another controller or
+// by the delete command without the ofp.FlowFlagSendFlowRem flag, stay
+// in the cache until it is rebuilt from the flow dump with Rebuild or
+// forgotten with Reset.
+//
+// For example, to reinstall the desired flows each minute:
+//
+//	conn := ofputil.NewFlowDedupConn(c)
+//	for range time.Tick(time.Minute) {
+//		var dump []*ofp.FlowStats
+//		err := ofputil.DumpFlows(ctx, c, req, func(f *ofp.FlowStats) error {
+//			dump = append(dump, f)
+//			return nil
+//		})
+//
+//		if err == nil {
+//			conn.Rebuild(dump...)
+//		}
+//
+//		of.Send(conn, flows...)
+//	}
+type FlowDedupConn struct {
+	of.Conn
+
+	mu         sync.Mutex
+	flows      map[flowKey]uint64
+	suppressed int
+
+	// generation is incremented each time the cache is invalidated,
+	// so the flow entries sent concurrently with the invalidation are
+	// not cached.
+	generation uint64
+}
+
+// NewFlowDedupConn creates a new connection suppressing the duplicate
+// flow add commands.
+func NewFlowDedupConn(c of.Conn) *FlowDedupConn {
+	return &FlowDedupConn{Conn: c, flows: make(map[flowKey]uint64)}
+}
+
+// flowHash returns the hash of the flow entry installed with the
+// given flow modification.
+func flowHash(key flowKey, fmod *ofp.FlowMod) uint64 {
+	var buf bytes.Buffer
+	fmod.Instructions.WriteTo(&buf)
+
+	h := fnv.New64a()
+	binary.Write(h, binary.BigEndian, struct {
+		Table    ofp.Table
+		Priority uint16
+		Cookie   uint64
+		Flags    ofp.FlowModFlag
+	}{key.Table, key.Priority, fmod.Cookie, fmod.Flags})
+
+	h.Write([]byte(key.Match))
+	h.Write(buf.Bytes())
+	return h.Sum64()
+}
+
+// Send sends the request to the underlying connection. The flow add
+// commands identical to the installed flow entries are discarded.
+func (c *FlowDedupConn) Send(r *of.Request) error {
+	if r.Header.Type != of.TypeFlowMod {
+		return c.Conn.Send(r)
+	}
+
+	var fmod ofp.FlowMod
+	if r.Decode(&fmod) != nil {
+		return c.Conn.Send(r)
+	}
+
+	key, err := newFlowKey(fmod.Table, fmod.Priority, &fmod.Match)
+	if err != nil {
+		return c.Conn.Send(r)
+	}
+
+	if fmod.Command != ofp.FlowAdd {
+		c.mu.Lock()
+		c.invalidate(&fmod, key)
+		c.mu.Unlock()
+
+		return c.Conn.Send(r)
+	}
+
+	hash := flowHash(key, &fmod)
+	buffered := fmod.Buffer != ofp.NoBuffer
+
+	// The lock is not held while the request is sent, so the slow
+	// connection does not block the receive of the messages.
+	c.mu.Lock()
+	if known, ok := c.flows[key]; ok && known == hash && !buffered {
+		c.suppressed++
+		c.mu.Unlock()
+		return nil
+	}
+
+	// The flow entry is replaced, so the previous hash is not valid
+	// even when the request fails.
+	delete(c.flows, key)
+	generation := c.generation
+	c.mu.Unlock()
+
+	if err = c.Conn.Send(r); err != nil {
+		return err
+	}
+
+	if buffered || fmod.IdleTimeout != 0 || fmod.HardTimeout != 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation == generation {
+		c.flows[key] = hash
+	}
+
+	return nil
+}
+
+// invalidate removes the flow entries affected by the modify or delete
+// command from the cache. The lock must be held.
+func (c *FlowDedupConn) invalidate(fmod *ofp.FlowMod, key flowKey) {
+	c.generation++
+
+	switch fmod.Command {
+	case ofp.FlowModifyStrict, ofp.FlowDeleteStrict:
+		delete(c.flows, key)
+		return
+	}
+
+	// The non-strict commands could affect any flow entry of the
+	// table, so all of them are forgotten.
+	for k := range c.flows {
+		if fmod.Table == ofp.TableAll || k.Table == fmod.Table {
+			delete(c.flows, k)
+		}
+	}
+}
+
+// Receive receives the next request from the underlying connection.
+// The flow removed and error messages invalidate the cache.
+func (c *FlowDedupConn) Receive() (*of.Request, error) {
+	r, err := c.Conn.Receive()
+	if err != nil {
+		return r, err
+	}
+
+	c.observe(r)
+	return r, nil
+}
+
+// Handler returns a handler, that invalidates the cache on the flow
+// removed and error messages of the underlying connection and then
+// calls the given handler. The messages of other connections are
+// passed through unchanged.
+func (c *FlowDedupConn) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if r.Conn() == c.Conn {
+			c.observe(r)
+		}
+
+		h.Serve(rw, r)
+	})
+}
+
+// observe invalidates the cache on the flow removed and error messages
+// received from the switch.
+func (c *FlowDedupConn) observe(r *of.Request) {
+	switch r.Header.Type {
+	case of.TypeFlowRemoved:
+		var removed ofp.FlowRemoved
+		if r.Decode(&removed) != nil {
+			c.Reset()
+			break
+		}
+
+		key, err := newFlowKey(removed.Table, removed.Priority, &removed.Match)
+		if err != nil {
+			c.Reset()
+			break
+		}
+
+		c.mu.Lock()
+		c.generation++
+		delete(c.flows, key)
+		c.mu.Unlock()
+	case of.TypeError:
+		c.Reset()
+	}
+}
+
+// Rebuild replaces the cache with the flow entries of the given flow
+// statistics, for example retrieved with DumpFlows, so the flow entries
+// removed out of band are sent again.
+func (c *FlowDedupConn) Rebuild(flows ...*ofp.FlowStats) {
+	cache := make(map[flowKey]uint64, len(flows))
+
+	for _, flow := range flows {
+		if flow.IdleTimeout != 0 || flow.HardTimeout != 0 {
+			continue
+		}
+
+		fmod := flow.FlowMod()
+		key, err := newFlowKey(fmod.Table, fmod.Priority, &fmod.Match)
+		if err != nil {
+			continue
+		}
+
+		cache[key] = flowHash(key, fmod)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.flows = cache
+}
+
+// Reset forgets all flow entries, so the following flow add commands
+// are sent to the switch.
+func (c *FlowDedupConn) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.flows = make(map[flowKey]uint64)
+}
+
+// Suppressed returns the number of the discarded flow add commands.
+func (c *FlowDedupConn) Suppressed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.suppressed
+}
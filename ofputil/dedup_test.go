@@ -0,0 +1,239 @@
+package ofputil
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestFlowDedupConn(t *testing.T) {
+	rec := ofptest.NewConnRecorder()
+	conn := NewFlowDedupConn(rec)
+
+	newFlow := func(cmd ofp.FlowModCommand, port ofp.PortNo) *ofp.FlowMod {
+		fmod := ofp.NewFlowMod(cmd, nil)
+		fmod.Table = 1
+		fmod.Priority = 10
+		fmod.Match = ExtendedMatch(MatchInPort(1))
+		fmod.Instructions = ofp.Instructions{&ofp.InstructionApplyActions{
+			Actions: ofp.Actions{&ofp.ActionOutput{Port: port}},
+		}}
+		return fmod
+	}
+
+	send := func(fmod *ofp.FlowMod) {
+		if err := conn.Send(of.NewRequest(of.TypeFlowMod, fmod)); err != nil {
+			t.Fatalf("Failed to send flow mod: %s", err)
+		}
+	}
+
+	send(newFlow(ofp.FlowAdd, 2))
+	send(newFlow(ofp.FlowAdd, 2))
+
+	// The instructions are changed, so the flow must be sent.
+	send(newFlow(ofp.FlowAdd, 3))
+	send(newFlow(ofp.FlowAdd, 3))
+
+	if rec.Len() != 2 || conn.Suppressed() != 2 {
+		t.Fatalf("Duplicate flows must be suppressed: %d, %d",
+			rec.Len(), conn.Suppressed())
+	}
+
+	// The delete command invalidates the flow entry.
+	send(newFlow(ofp.FlowDeleteStrict, 3))
+	send(newFlow(ofp.FlowAdd, 3))
+
+	// The flow entries with timeouts are never suppressed.
+	timed := newFlow(ofp.FlowAdd, 4)
+	timed.IdleTimeout = 10
+	send(timed)
+	send(timed)
+
+	if rec.Len() != 6 {
+		t.Fatalf("Invalid number of sent flow mods: %d", rec.Len())
+	}
+
+	// The removal of the flow entry invalidates the cache.
+	removed := &ofp.FlowRemoved{
+		Table:    1,
+		Priority: 10,
+		Match:    ExtendedMatch(MatchInPort(1)),
+	}
+
+	rec.Push(of.NewRequest(of.TypeFlowRemoved, removed))
+	if _, err := conn.Receive(); err != nil {
+		t.Fatalf("Failed to receive flow removed: %s", err)
+	}
+
+	send(newFlow(ofp.FlowAdd, 3))
+	send(newFlow(ofp.FlowAdd, 3))
+
+	// The error invalidates the whole cache.
+	rec.Push(of.NewRequest(of.TypeError, nil))
+	if _, err := conn.Receive(); err != nil {
+		t.Fatalf("Failed to receive error: %s", err)
+	}
+
+	send(newFlow(ofp.FlowAdd, 3))
+	if rec.Len() != 8 {
+		t.Fatalf("Invalid number of sent flow mods: %d", rec.Len())
+	}
+}
+
+func TestFlowDedupConnBuffered(t *testing.T) {
+	rec := ofptest.NewConnRecorder()
+	conn := NewFlowDedupConn(rec)
+
+	fmod := ofp.NewFlowMod(ofp.FlowAdd, nil)
+	fmod.Match = ExtendedMatch(MatchInPort(1))
+
+	buffered := ofp.NewFlowMod(ofp.FlowAdd, nil)
+	buffered.Match = fmod.Match
+	buffered.Buffer = 42
+
+	// The flow mod carrying the buffered packet must be sent even
+	// when the identical flow entry is installed.
+	for _, fmod := range []*ofp.FlowMod{fmod, buffered, buffered} {
+		if err := conn.Send(of.NewRequest(of.TypeFlowMod, fmod)); err != nil {
+			t.Fatalf("Failed to send flow mod: %s", err)
+		}
+	}
+
+	if rec.Len() != 3 || conn.Suppressed() != 0 {
+		t.Fatalf("Buffered flow mods must not be suppressed: %d, %d",
+			rec.Len(), conn.Suppressed())
+	}
+
+	// The buffered flow mod must not be cached either.
+	conn.Send(of.NewRequest(of.TypeFlowMod, fmod))
+	if rec.Len() != 4 {
+		t.Fatalf("Buffered flow mod must not be cached: %d", rec.Len())
+	}
+}
+
+// blockingConn blocks the sent requests until released.
+type blockingConn struct {
+	*ofptest.ConnRecorder
+	sending chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingConn) Send(r *of.Request) error {
+	c.sending <- struct{}{}
+	<-c.release
+	return c.ConnRecorder.Send(r)
+}
+
+func TestFlowDedupConnConcurrentReset(t *testing.T) {
+	rec := &blockingConn{
+		ConnRecorder: ofptest.NewConnRecorder(),
+		sending:      make(chan struct{}),
+		release:      make(chan struct{}),
+	}
+
+	conn := NewFlowDedupConn(rec)
+	fmod := ofp.NewFlowMod(ofp.FlowAdd, nil)
+	fmod.Match = ExtendedMatch(MatchInPort(1))
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- conn.Send(of.NewRequest(of.TypeFlowMod, fmod))
+	}()
+
+	<-rec.sending
+
+	// The cache must not be locked while the request is sent.
+	reset := make(chan struct{})
+	go func() {
+		conn.Reset()
+		close(reset)
+	}()
+
+	select {
+	case <-reset:
+	case <-time.After(time.Second):
+		t.Fatalf("Reset must not be blocked by the sent request")
+	}
+
+	close(rec.release)
+	if err := <-errs; err != nil {
+		t.Fatalf("Failed to send flow mod: %s", err)
+	}
+
+	// The flow sent concurrently with the reset must not be cached.
+	go func() { <-rec.sending }()
+	if err := conn.Send(of.NewRequest(of.TypeFlowMod, fmod)); err != nil {
+		t.Fatalf("Failed to send flow mod: %s", err)
+	}
+
+	if rec.Len() != 2 || conn.Suppressed() != 0 {
+		t.Errorf("Flow must be sent again after the reset: %d", rec.Len())
+	}
+}
+
+func TestFlowDedupConnHandler(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sw := of.NewConn(server)
+	defer sw.Close()
+
+	go func() {
+		for {
+			if _, err := sw.Receive(); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn := of.NewConn(client)
+	dedup := NewFlowDedupConn(conn)
+
+	fmod := ofp.NewFlowMod(ofp.FlowAdd, nil)
+	fmod.Priority = 10
+	fmod.Match = ExtendedMatch(MatchInPort(1))
+
+	send := func(suppressed int) {
+		if err := of.Send(dedup, of.NewRequest(of.TypeFlowMod, fmod)); err != nil {
+			t.Fatalf("Failed to send flow mod: %s", err)
+		}
+
+		if n := dedup.Suppressed(); n != suppressed {
+			t.Fatalf("Expected %d suppressed flows, got %d", suppressed, n)
+		}
+	}
+
+	send(0)
+	send(1)
+
+	// The flow removed message served by the handler must
+	// invalidate the cache.
+	go of.Send(sw, of.NewRequest(of.TypeFlowRemoved, &ofp.FlowRemoved{
+		Priority: 10, Match: ExtendedMatch(MatchInPort(1)),
+	}))
+
+	r, err := conn.Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive flow removed: %s", err)
+	}
+
+	handler := dedup.Handler(of.HandlerFunc(func(of.ResponseWriter, *of.Request) {}))
+	handler.Serve(nil, r)
+
+	send(1)
+	send(2)
+
+	// The flow is not in the dump, so it must be sent again.
+	dedup.Rebuild()
+	send(2)
+
+	// The flow is installed according to the dump.
+	dedup.Rebuild(&ofp.FlowStats{
+		Priority: 10, Flags: fmod.Flags, Match: ExtendedMatch(MatchInPort(1)),
+	})
+	send(3)
+}
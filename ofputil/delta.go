@@ -0,0 +1,174 @@
+package ofputil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrDuplicateFlow is returned when the set of flows contains multiple
+// flows with the same table, priority and match.
+var ErrDuplicateFlow = errors.New("ofputil: duplicate flow")
+
+// FlowDelta is a set of the flow modifications transforming one set of
+// flow entries into another.
+type FlowDelta struct {
+	// Add lists the flow entries to install. The entries with the
+	// changed cookie, timeouts or flags are installed again, since
+	// the modification commands do not update these fields.
+	Add []*ofp.FlowMod
+
+	// Modify lists the flow entries, which instructions are changed.
+	// The commands of the flow modifications are FlowModifyStrict.
+	Modify []*ofp.FlowMod
+
+	// Delete lists the flow entries to remove. The commands of the
+	// flow modifications are FlowDeleteStrict.
+	Delete []*ofp.FlowMod
+}
+
+// Len returns the total number of flow modifications in the delta.
+func (d *FlowDelta) Len() int {
+	return len(d.Add) + len(d.Modify) + len(d.Delete)
+}
+
+// Requests returns the flow modification requests of the delta. The
+// new flow entries are installed before the stale ones are removed,
+// so the traffic is not dropped by the switch in between.
+func (d *FlowDelta) Requests() []*of.Request {
+	reqs := make([]*of.Request, 0, d.Len())
+	for _, fmods := range [][]*ofp.FlowMod{d.Add, d.Modify, d.Delete} {
+		for _, fmod := range fmods {
+			reqs = append(reqs, of.NewRequest(of.TypeFlowMod, fmod))
+		}
+	}
+
+	return reqs
+}
+
+// flowKey identifies the flow entry in the flow table.
+type flowKey struct {
+	Table    ofp.Table
+	Priority uint16
+	Match    string
+}
+
+// newFlowKey returns the key of the flow entry with the canonical form
+// of the given match.
+func newFlowKey(table ofp.Table, priority uint16, m *ofp.Match) (flowKey, error) {
+	match := m.Clone()
+	if err := match.Canonicalize(); err != nil {
+		return flowKey{}, err
+	}
+
+	return flowKey{table, priority, match.Key()}, nil
+}
+
+// flowIndex indexes the flows by the table, priority and canonical
+// match, preserving the order of the flows.
+func flowIndex(flows []*ofp.FlowMod) ([]flowKey, map[flowKey]*ofp.FlowMod, error) {
+	keys := make([]flowKey, 0, len(flows))
+	index := make(map[flowKey]*ofp.FlowMod, len(flows))
+
+	for _, flow := range flows {
+		key, err := newFlowKey(flow.Table, flow.Priority, &flow.Match)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if _, ok := index[key]; ok {
+			return nil, nil, fmt.Errorf("%w: table %d, priority %d, match %v",
+				ErrDuplicateFlow, flow.Table, flow.Priority, flow.Match.Fields)
+		}
+
+		keys = append(keys, key)
+		index[key] = flow
+	}
+
+	return keys, index, nil
+}
+
+// instructionsEqual reports whether the instructions have the same
+// wire representation.
+func instructionsEqual(a, b ofp.Instructions) bool {
+	var abuf, bbuf bytes.Buffer
+	a.WriteTo(&abuf)
+	b.WriteTo(&bbuf)
+	return bytes.Equal(abuf.Bytes(), bbuf.Bytes())
+}
+
+// ComputeFlowDelta returns the flow modifications transforming the old
+// set of the flow entries into the new one. The flow entries are
+// identified by the table, priority and match, the matches are compared
+// in the canonical form. The commands of the given flow modifications
+// are ignored.
+//
+// It could be used to reconcile the flow tables of the switch with the
+// desired state, or to preview the changes of the policy update:
+//
+//	delta, err := ofputil.ComputeFlowDelta(current, desired)
+//	if err != nil {
+//		return err
+//	}
+//
+//	for _, fmod := range delta.Delete {
+//		log.Printf("remove %v", fmod.Match)
+//	}
+func ComputeFlowDelta(old, new []*ofp.FlowMod) (FlowDelta, error) {
+	var delta FlowDelta
+
+	oldKeys, oldIndex, err := flowIndex(old)
+	if err != nil {
+		return delta, err
+	}
+
+	newKeys, newIndex, err := flowIndex(new)
+	if err != nil {
+		return delta, err
+	}
+
+	for _, key := range newKeys {
+		flow := newIndex[key]
+		prev, ok := oldIndex[key]
+
+		switch {
+		case !ok || prev.Cookie != flow.Cookie ||
+			prev.IdleTimeout != flow.IdleTimeout ||
+			prev.HardTimeout != flow.HardTimeout ||
+			prev.Flags != flow.Flags:
+
+			fmod := flow.Clone()
+			fmod.Command = ofp.FlowAdd
+			fmod.CookieMask = 0
+			delta.Add = append(delta.Add, fmod)
+
+		case !instructionsEqual(prev.Instructions, flow.Instructions):
+			fmod := flow.Clone()
+			fmod.Command = ofp.FlowModifyStrict
+			fmod.CookieMask = 0
+			delta.Modify = append(delta.Modify, fmod)
+		}
+	}
+
+	for _, key := range oldKeys {
+		if _, ok := newIndex[key]; ok {
+			continue
+		}
+
+		flow := oldIndex[key]
+		delta.Delete = append(delta.Delete, &ofp.FlowMod{
+			Table:    flow.Table,
+			Command:  ofp.FlowDeleteStrict,
+			Priority: flow.Priority,
+			Buffer:   ofp.NoBuffer,
+			OutPort:  ofp.PortAny,
+			OutGroup: ofp.GroupAny,
+			Match:    flow.Match.Clone(),
+		})
+	}
+
+	return delta, nil
+}
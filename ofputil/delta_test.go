@@ -0,0 +1,92 @@
+package ofputil
+
+import (
+	"errors"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestComputeFlowDelta(t *testing.T) {
+	output := func(port ofp.PortNo) ofp.Instructions {
+		return ActionsApply(&ofp.ActionOutput{Port: port})
+	}
+
+	newFlow := func(priority uint16, port ofp.PortNo, fields ...ofp.XM) *ofp.FlowMod {
+		return &ofp.FlowMod{
+			Priority:     priority,
+			Match:        ExtendedMatch(fields...),
+			Instructions: output(port),
+		}
+	}
+
+	old := []*ofp.FlowMod{
+		// Left untouched, the order of the fields does not matter.
+		newFlow(10, 2, MatchInPort(1), MatchEthType(0x0800)),
+		// Instructions are modified.
+		newFlow(10, 3, MatchInPort(2)),
+		// Removed.
+		newFlow(10, 4, MatchInPort(3)),
+		// Cookie is updated.
+		newFlow(20, 4, MatchInPort(4)),
+	}
+
+	updated := newFlow(20, 4, MatchInPort(4))
+	updated.Cookie = 0xab
+
+	new := []*ofp.FlowMod{
+		newFlow(10, 2, MatchEthType(0x0800), MatchInPort(1)),
+		newFlow(10, 4, MatchInPort(2)),
+		updated,
+		// Added, the priority is different.
+		newFlow(5, 4, MatchInPort(3)),
+	}
+
+	delta, err := ComputeFlowDelta(old, new)
+	if err != nil {
+		t.Fatalf("Failed to compute flow delta: %s", err)
+	}
+
+	if len(delta.Add) != 2 || len(delta.Modify) != 1 || len(delta.Delete) != 1 {
+		t.Fatalf("Invalid flow delta: %+v", delta)
+	}
+
+	if delta.Add[0].Cookie != 0xab || delta.Add[1].Priority != 5 ||
+		delta.Add[1].Command != ofp.FlowAdd {
+		t.Errorf("Invalid flows to add: %v", delta.Add)
+	}
+
+	fmod := delta.Modify[0]
+	if fmod.Command != ofp.FlowModifyStrict || !instructionsEqual(fmod.Instructions, output(4)) {
+		t.Errorf("Invalid flow to modify: %v", fmod)
+	}
+
+	fmod = delta.Delete[0]
+	if fmod.Command != ofp.FlowDeleteStrict || fmod.Priority != 10 ||
+		fmod.OutPort != ofp.PortAny || len(fmod.Instructions) != 0 {
+		t.Errorf("Invalid flow to delete: %v", fmod)
+	}
+
+	reqs := delta.Requests()
+	if len(reqs) != delta.Len() || reqs[0].Header.Type != of.TypeFlowMod {
+		t.Errorf("Invalid flow delta requests: %v", reqs)
+	}
+
+	// Modifications of the originals must not leak into the delta.
+	if new[2].Command != 0 || new[1].Command != 0 {
+		t.Errorf("Original flows must not be changed")
+	}
+}
+
+func TestComputeFlowDeltaDuplicate(t *testing.T) {
+	flows := []*ofp.FlowMod{
+		{Match: ExtendedMatch(MatchInPort(1))},
+		{Match: ExtendedMatch(MatchInPort(1))},
+	}
+
+	_, err := ComputeFlowDelta(nil, flows)
+	if !errors.Is(err, ErrDuplicateFlow) {
+		t.Errorf("Duplicate flow error expected: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package ofputil
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// multipartCall is a multipart request waiting for the reply.
+type multipartCall struct {
+	typ     ofp.MultipartType
+	replies []*of.Request
+	done    chan error
+}
+
+// MultipartDemux issues several multipart requests concurrently over a
+// single connection and demultiplexes the interleaved parts of the
+// replies by the transaction identifier and the multipart type. Each
+// request is assigned a transaction identifier unique among the pending
+// requests.
+//
+// MultipartDemux implements both of.Matcher and of.Handler interfaces,
+// the replies received from the connection should be routed to it, for
+// example:
+//
+//	demux := ofputil.NewMultipartDemux()
+//	mux.Handle(demux, demux)
+//
+//	go func() {
+//		ports, err := demux.Do(ctx, conn, ofp.MultipartTypePortStats,
+//			&ofp.PortStatsRequest{PortNo: ofp.PortAny})
+//		// ...
+//	}()
+//
+//	tables, err := demux.Do(ctx, conn, ofp.MultipartTypeTable)
+//
+// The parts of a single reply are collected in the order they are
+// served, therefore the server must handle the requests of the
+// connection sequentially (see of.SequentialRunner).
+type MultipartDemux struct {
+	// Capabilities are the probed capabilities of the switch. When
+	// defined, the requests the switch is not capable to serve are
+	// rejected with ErrNotSupported without being sent.
+	Capabilities *SwitchCapabilities
+
+	mu    sync.Mutex
+	calls map[uint32]*multipartCall
+}
+
+// NewMultipartDemux creates a new demultiplexer of multipart replies.
+func NewMultipartDemux() *MultipartDemux {
+	return &MultipartDemux{calls: make(map[uint32]*multipartCall)}
+}
+
+// register assigns the unused transaction identifier to the call.
+func (d *MultipartDemux) register(call *multipartCall) uint32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		xid := newXID()
+		if _, ok := d.calls[xid]; !ok {
+			d.calls[xid] = call
+			return xid
+		}
+	}
+}
+
+// forget removes the call of the given transaction.
+func (d *MultipartDemux) forget(xid uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.calls, xid)
+}
+
+// Do sends the multipart request of the given type with the entries to
+// the connection and waits for all parts of the reply. The request is
+// split into several messages, when the entries do not fit into a
+// single one (see MultipartRequests).
+//
+// The parts of the reply are returned in the order they are received,
+// the body of each part starts with the multipart reply header. The
+// error reply to the request is returned as an error (see
+// MultipartError).
+func (d *MultipartDemux) Do(ctx context.Context, conn of.Conn,
+	t ofp.MultipartType, entries ...io.WriterTo) ([]*of.Request, error) {
+
+	if err := d.Capabilities.CheckMultipart(t); err != nil {
+		return nil, err
+	}
+
+	reqs, err := MultipartRequests(t, 0, entries...)
+	if err != nil {
+		return nil, err
+	}
+
+	call := &multipartCall{typ: t, done: make(chan error, 1)}
+	xid := d.register(call)
+	defer d.forget(xid)
+
+	for _, req := range reqs {
+		req.Header.Transaction = xid
+	}
+
+	if err = of.Send(conn, reqs...); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err = <-call.done:
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return call.replies, nil
+}
+
+// Pending returns the number of multipart requests waiting for the
+// reply.
+func (d *MultipartDemux) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.calls)
+}
+
+// Match implements of.Matcher interface. It matches the multipart and
+// error replies to the pending requests.
+func (d *MultipartDemux) Match(r *of.Request) bool {
+	if r.Header.Type != of.TypeMultipartReply &&
+		r.Header.Type != of.TypeError {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.calls[r.Header.Transaction]
+	return ok
+}
+
+// Serve implements of.Handler interface. It appends the part of the
+// reply to the pending request and completes the request after the
+// last part is received. The parts of the multipart type different
+// from the type of the request are discarded.
+func (d *MultipartDemux) Serve(rw of.ResponseWriter, r *of.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	xid := r.Header.Transaction
+	call, ok := d.calls[xid]
+	if !ok {
+		return
+	}
+
+	if r.Header.Type == of.TypeError {
+		delete(d.calls, xid)
+		call.done <- MultipartError(r)
+		return
+	}
+
+	var reply ofp.MultipartReply
+	if err := r.Decode(&reply); err != nil {
+		delete(d.calls, xid)
+		call.done <- err
+		return
+	}
+
+	if reply.Type != call.typ {
+		Logf(r, "ofputil: unexpected %s reply to the %s request",
+			reply.Type, call.typ)
+		return
+	}
+
+	call.replies = append(call.replies, r)
+	if reply.Flags&ofp.MultipartReplyMode == 0 {
+		delete(d.calls, xid)
+		call.done <- nil
+	}
+}
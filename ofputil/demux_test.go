@@ -0,0 +1,136 @@
+package ofputil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestMultipartDemux(t *testing.T) {
+	conn := ofptest.NewConnRecorder()
+	demux := NewMultipartDemux()
+
+	type result struct {
+		replies []*of.Request
+		err     error
+	}
+
+	types := []ofp.MultipartType{
+		ofp.MultipartTypeTable,
+		ofp.MultipartTypePortStats,
+		ofp.MultipartTypeGroupDescription,
+	}
+
+	results := make(map[ofp.MultipartType]chan result)
+	for _, typ := range types {
+		ch := make(chan result, 1)
+		results[typ] = ch
+
+		go func(typ ofp.MultipartType) {
+			replies, err := demux.Do(context.Background(), conn, typ)
+			ch <- result{replies, err}
+		}(typ)
+	}
+
+	for conn.Len() != len(types) {
+		runtime.Gosched()
+	}
+
+	xids := make(map[ofp.MultipartType]uint32)
+	for i, r := range conn.All() {
+		var req ofp.MultipartRequest
+		if err := conn.Decode(i, &req); err != nil {
+			t.Fatalf("Failed to decode multipart request: %s", err)
+		}
+		xids[req.Type] = r.Header.Transaction
+	}
+
+	if len(xids) != len(types) || xids[types[0]] == xids[types[1]] ||
+		xids[types[1]] == xids[types[2]] {
+		t.Fatalf("Requests must have distinct transactions: %v", xids)
+	}
+
+	newReply := func(typ, replyType ofp.MultipartType,
+		flags ofp.MultipartReplyFlag) *of.Request {
+
+		var buf bytes.Buffer
+		reply := ofp.MultipartReply{Type: replyType, Flags: flags}
+		reply.WriteTo(&buf)
+
+		r := of.NewRequest(of.TypeMultipartReply, &buf)
+		r.Header.Transaction = xids[typ]
+		return r
+	}
+
+	errReply := of.NewRequest(of.TypeError, &ofp.Error{
+		Type: ofp.ErrTypeBadRequest,
+		Code: ofp.ErrCodeBadRequestBadMultipart,
+	})
+	errReply.Header.Transaction = xids[ofp.MultipartTypeGroupDescription]
+
+	// Interleave the parts of the replies to the different requests,
+	// the part of the unexpected type must be discarded.
+	table, ports := ofp.MultipartTypeTable, ofp.MultipartTypePortStats
+	replies := []*of.Request{
+		newReply(table, table, ofp.MultipartReplyMode),
+		newReply(ports, ports, ofp.MultipartReplyMode),
+		newReply(table, ports, 0),
+		errReply,
+		newReply(ports, ports, ofp.MultipartReplyMode),
+		newReply(table, table, 0),
+		newReply(ports, ports, 0),
+	}
+
+	rw := ofptest.NewRecorder()
+	for _, r := range replies {
+		if !demux.Match(r) {
+			t.Fatalf("Reply must match the pending request: %v", r.Header)
+		}
+		demux.Serve(rw, r)
+	}
+
+	if res := <-results[table]; res.err != nil || len(res.replies) != 2 {
+		t.Errorf("Invalid reply to the table request: %d, %v",
+			len(res.replies), res.err)
+	}
+
+	if res := <-results[ports]; res.err != nil || len(res.replies) != 3 {
+		t.Errorf("Invalid reply to the port request: %d, %v",
+			len(res.replies), res.err)
+	}
+
+	var oe *ofp.Error
+	res := <-results[ofp.MultipartTypeGroupDescription]
+	if !errors.As(res.err, &oe) || oe.Code != ofp.ErrCodeBadRequestBadMultipart {
+		t.Errorf("Error reply expected: %v", res.err)
+	}
+
+	if n := demux.Pending(); n != 0 {
+		t.Errorf("Requests must be completed: %d", n)
+	}
+
+	if demux.Match(replies[0]) {
+		t.Errorf("Reply to the completed request must not match")
+	}
+}
+
+func TestMultipartDemuxCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	demux := NewMultipartDemux()
+	_, err := demux.Do(ctx, ofptest.NewConnRecorder(), ofp.MultipartTypeDescription)
+	if err != context.Canceled {
+		t.Fatalf("Context cancellation error expected: %v", err)
+	}
+
+	if n := demux.Pending(); n != 0 {
+		t.Errorf("Canceled request must be removed: %d", n)
+	}
+}
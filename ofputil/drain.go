@@ -0,0 +1,130 @@
+package ofputil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// DefaultDrainTimeout is a time the DrainConn waits for the switch to
+// process the pending requests on close, when the timeout is not set.
+const DefaultDrainTimeout = 5 * time.Second
+
+// Drain writes the buffered requests to the connection, sends the
+// barrier request and waits for the barrier reply, so all requests sent
+// before are processed by the switch, and their replies are received.
+//
+// The echo requests received while waiting are replied, the rest of the
+// messages are discarded. When the switch replied with an error to any
+// of the preceding requests, the first error is returned after the
+// barrier reply is received.
+//
+// For example, to make sure the flow modifications are installed before
+// the command line tool exits:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+//	defer cancel()
+//
+//	if err := ofputil.Drain(ctx, conn); err != nil {
+//		log.Fatal(err)
+//	}
+func Drain(ctx context.Context, conn of.Conn) error {
+	barrier := of.NewRequest(of.TypeBarrierRequest, nil)
+	barrier.Header.Transaction = newXID()
+
+	if err := of.Send(conn, barrier); err != nil {
+		return err
+	}
+
+	// Unblock the receive of the message, when the context is
+	// canceled or its deadline is exceeded.
+	defer watchContext(ctx, conn)()
+
+	var failure error
+	for {
+		r, err := conn.Receive()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch r.Header.Type {
+		case of.TypeEchoRequest:
+			data, err := r.RawBody()
+			if err != nil {
+				return err
+			}
+
+			reply := r.NewReply(of.TypeEchoReply, bytes.NewReader(data))
+			if err = of.Send(conn, reply); err != nil {
+				return err
+			}
+		case of.TypeError:
+			e, err := ofp.ReadError(r.Body)
+			if err != nil {
+				return err
+			}
+
+			if r.Header.Transaction == barrier.Header.Transaction {
+				return e
+			}
+
+			if failure == nil {
+				failure = fmt.Errorf("ofputil: request (xid=0x%x) failed: %w",
+					r.Header.Transaction, e)
+			}
+		case of.TypeBarrierReply:
+			if r.Header.Transaction == barrier.Header.Transaction {
+				return failure
+			}
+		}
+	}
+}
+
+// DrainConn is a connection, that drains the pending requests before
+// closing the underlying connection. It could be used by the short-lived
+// tools to ensure the last requests are not lost, when the connection
+// is closed right after sending them.
+//
+// The Close of the connection must not be called concurrently with the
+// Receive, since the replies are received by the Close.
+type DrainConn struct {
+	of.Conn
+
+	// Timeout is a maximum time to wait for the switch to process the
+	// pending requests. When zero, DefaultDrainTimeout is used.
+	Timeout time.Duration
+}
+
+// NewDrainConn creates a new connection draining the pending requests
+// on close within the given timeout.
+func NewDrainConn(c of.Conn, timeout time.Duration) *DrainConn {
+	return &DrainConn{Conn: c, Timeout: timeout}
+}
+
+// Close drains the pending requests and closes the underlying
+// connection. The connection is closed even if the draining failed,
+// the error of the draining is returned in that case.
+func (c *DrainConn) Close() error {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := Drain(ctx, c.Conn)
+	if cerr := c.Conn.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
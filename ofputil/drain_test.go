@@ -0,0 +1,98 @@
+package ofputil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// serveBarrier replies to the flow modifications with the error and
+// to the barrier requests with the barrier replies. When reply is false
+// the requests are read, but left without replies.
+func serveBarrier(conn net.Conn, reply bool) {
+	c := of.NewConn(conn)
+	defer c.Close()
+
+	// Write the replies from the separate goroutine, otherwise the
+	// synchronous pipe blocks both sides.
+	replies := make(chan *of.Request, 8)
+	go func() {
+		for r := range replies {
+			if of.Send(c, r) != nil {
+				return
+			}
+		}
+	}()
+
+	defer close(replies)
+
+	for {
+		r, err := c.Receive()
+		if err != nil {
+			return
+		}
+
+		if !reply {
+			continue
+		}
+
+		switch r.Header.Type {
+		case of.TypeFlowMod:
+			replies <- r.NewReply(of.TypeError, &ofp.Error{
+				Type: ofp.ErrTypeFlowModFailed,
+				Code: ofp.ErrCodeFlowModFailedTableFull,
+			})
+		case of.TypeBarrierRequest:
+			replies <- of.NewRequest(of.TypeEchoRequest, nil)
+			replies <- r.NewReply(of.TypeBarrierReply, nil)
+		}
+	}
+}
+
+func TestDrainConn(t *testing.T) {
+	client, server := net.Pipe()
+	go serveBarrier(server, true)
+
+	conn := NewDrainConn(of.NewConn(client), time.Second)
+
+	fmod := of.NewRequest(of.TypeFlowMod, ofp.NewFlowMod(ofp.FlowAdd, nil))
+	fmod.Header.Transaction = 5
+
+	if err := of.Send(conn, fmod); err != nil {
+		t.Fatalf("Failed to send flow mod: %s", err)
+	}
+
+	err := conn.Close()
+
+	var e *ofp.Error
+	if !errors.As(err, &e) || e.Code != ofp.ErrCodeFlowModFailedTableFull {
+		t.Fatalf("Error of the flow mod expected: %v", err)
+	}
+
+	if err = of.Send(conn, fmod); err == nil {
+		t.Errorf("Connection must be closed")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go serveBarrier(server, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	conn := of.NewConn(client)
+	err := Drain(ctx, conn)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Deadline exceeded error expected: %v", err)
+	}
+
+	expectNoDeadline(t, conn)
+}
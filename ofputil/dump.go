@@ -0,0 +1,161 @@
+package ofputil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrStopDump is used as a return value from the DumpFlows callback to
+// indicate that the rest of the flow entries must be skipped. It is not
+// returned as an error by the DumpFlows.
+var ErrStopDump = errors.New("ofputil: stop flow dump")
+
+// DumpFlows sends the flow statistics request to the connection and
+// calls fn for each flow entry of the reply as the parts of the reply
+// are received, so the flow tables of any size are processed with the
+// constant memory. The entry passed to the callback is not retained by
+// the function.
+//
+// When the callback returns an error, the dump is terminated and the
+// error is returned, unless it is ErrStopDump. The remaining parts of
+// the reply are not read from the connection in that case.
+//
+// The echo requests received during the dump are replied, the rest of
+// the messages not related to the dump are discarded. The error reply
+// to the request is returned as an error. For example, to print the
+// flow entries of the first table:
+//
+//	req := &ofp.FlowStatsRequest{
+//		Table:    0,
+//		OutPort:  ofp.PortAny,
+//		OutGroup: ofp.GroupAny,
+//		Match:    ofp.Match{Type: ofp.MatchTypeXM},
+//	}
+//
+//	err := ofputil.DumpFlows(ctx, conn, req, func(f *ofp.FlowStats) error {
+//		fmt.Println(f.Priority, f.Match)
+//		return nil
+//	})
+func DumpFlows(ctx context.Context, conn of.Conn, req *ofp.FlowStatsRequest,
+	fn func(*ofp.FlowStats) error) error {
+
+	dump := of.NewRequest(of.TypeMultipartRequest,
+		ofp.NewMultipartRequest(ofp.MultipartTypeFlow, req))
+	dump.Header.Transaction = newXID()
+
+	if err := of.Send(conn, dump); err != nil {
+		return err
+	}
+
+	// Unblock the receive of the message, when the context is
+	// canceled or its deadline is exceeded.
+	defer watchContext(ctx, conn)()
+
+	for {
+		r, err := conn.Receive()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if r.Header.Type == of.TypeEchoRequest {
+			data, err := r.RawBody()
+			if err != nil {
+				return err
+			}
+
+			reply := r.NewReply(of.TypeEchoReply, bytes.NewReader(data))
+			if err = of.Send(conn, reply); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if r.Header.Transaction != dump.Header.Transaction {
+			continue
+		}
+
+		switch r.Header.Type {
+		case of.TypeError:
+			e, err := ofp.ReadError(r.Body)
+			if err != nil {
+				return err
+			}
+			return e
+		case of.TypeMultipartReply:
+			more, err := dumpFlows(r, fn)
+			if err == ErrStopDump {
+				return nil
+			}
+
+			if err != nil || !more {
+				return err
+			}
+		}
+	}
+}
+
+// dumpFlows calls fn for each flow entry of the multipart reply. It
+// returns true, when more parts of the reply are expected.
+func dumpFlows(r *of.Request, fn func(*ofp.FlowStats) error) (bool, error) {
+	var reply ofp.MultipartReply
+	if _, err := reply.ReadFrom(r.Body); err != nil {
+		return false, err
+	}
+
+	for {
+		var flow ofp.FlowStats
+		_, err := flow.ReadFrom(r.Body)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if err = fn(&flow); err != nil {
+			return false, err
+		}
+	}
+
+	return reply.Flags&ofp.MultipartReplyMode != 0, nil
+}
+
+// watchContext unblocks the receive of the message from the connection,
+// when the context is canceled or its deadline is exceeded. The read
+// deadline is not set to the deadline of the context, otherwise the
+// receive could time out before the context reports an error.
+//
+// The returned function stops watching the context and resets the read
+// deadline of the connection.
+func watchContext(ctx context.Context, conn of.Conn) (stop func()) {
+	done, stopped := make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	return func() {
+		// Wait for the goroutine to exit before the deadline is
+		// reset, so the deadline is not set after the reset.
+		close(done)
+		<-stopped
+		conn.SetReadDeadline(time.Time{})
+	}
+}
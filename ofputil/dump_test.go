@@ -0,0 +1,155 @@
+package ofputil
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// serveFlowDump replies to the flow dump request with the given parts
+// of the reply, each part contains the flows of the given priorities.
+func serveFlowDump(conn net.Conn, parts ...[]uint16) {
+	c := of.NewConn(conn)
+	defer c.Close()
+
+	req, err := c.Receive()
+	if err != nil {
+		return
+	}
+
+	// Drain the echo replies until the client closes the connection,
+	// otherwise the synchronous pipe blocks both sides.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, err := c.Receive(); err != nil {
+				return
+			}
+		}
+	}()
+
+	defer func() { <-closed }()
+
+	// Unrelated messages must be skipped.
+	of.Send(c, of.NewRequest(of.TypeEchoRequest, nil),
+		of.NewRequest(of.TypeBarrierReply, nil))
+
+	for i, priorities := range parts {
+		var flags ofp.MultipartReplyFlag
+		if i < len(parts)-1 {
+			flags = ofp.MultipartReplyMode
+		}
+
+		var buf bytes.Buffer
+		reply := ofp.MultipartReply{Type: ofp.MultipartTypeFlow, Flags: flags}
+		reply.WriteTo(&buf)
+
+		for _, priority := range priorities {
+			flow := ofp.FlowStats{
+				Priority: priority,
+				Match:    ofp.Match{Type: ofp.MatchTypeXM},
+			}
+			flow.WriteTo(&buf)
+		}
+
+		if of.Send(c, req.NewReply(of.TypeMultipartReply, &buf)) != nil {
+			return
+		}
+	}
+}
+
+func TestDumpFlows(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go serveFlowDump(server, []uint16{1, 2}, []uint16{3})
+
+	req := &ofp.FlowStatsRequest{
+		Table:    ofp.TableAll,
+		OutPort:  ofp.PortAny,
+		OutGroup: ofp.GroupAny,
+		Match:    ofp.Match{Type: ofp.MatchTypeXM},
+	}
+
+	var priorities []uint16
+	err := DumpFlows(context.Background(), of.NewConn(client), req,
+		func(f *ofp.FlowStats) error {
+			priorities = append(priorities, f.Priority)
+			return nil
+		})
+
+	if err != nil {
+		t.Fatalf("Failed to dump flows: %s", err)
+	}
+
+	if len(priorities) != 3 || priorities[2] != 3 {
+		t.Errorf("Invalid dumped flows: %v", priorities)
+	}
+}
+
+func TestDumpFlowsStop(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go serveFlowDump(server, []uint16{1, 2}, []uint16{3})
+
+	var count int
+	err := DumpFlows(context.Background(), of.NewConn(client),
+		&ofp.FlowStatsRequest{}, func(f *ofp.FlowStats) error {
+			if count++; count == 2 {
+				return ErrStopDump
+			}
+			return nil
+		})
+
+	if err != nil {
+		t.Fatalf("Stopped flow dump must not fail: %s", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Flow dump must be stopped after two entries: %d", count)
+	}
+}
+
+func TestDumpFlowsCancel(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	// Switch never replies to the request.
+	go serveFlowDump(server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	conn := of.NewConn(client)
+	err := DumpFlows(ctx, conn, &ofp.FlowStatsRequest{},
+		func(f *ofp.FlowStats) error { return nil })
+
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Deadline error expected: %v", err)
+	}
+
+	expectNoDeadline(t, conn)
+}
+
+// expectNoDeadline checks that the read deadline of the connection was
+// reset, so the receive blocks until the connection is closed.
+func expectNoDeadline(t *testing.T, conn of.Conn) {
+	errs := make(chan error, 1)
+	go func() {
+		_, err := conn.Receive()
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		t.Fatalf("Read deadline must be reset: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
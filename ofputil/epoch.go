@@ -0,0 +1,298 @@
+package ofputil
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	of "github.com/netrack/openflow"
+)
+
+// ErrEpochAborted is returned when waiting for the confirmation of the
+// epoch, which is not confirmed before the connection is removed, and
+// when sending the state changes to the closed connection.
+var ErrEpochAborted = errors.New("ofputil: epoch is not confirmed")
+
+// epochState is a sequence of epochs of the single connection.
+type epochState struct {
+	// current is an epoch of the state changes sent now.
+	current uint64
+
+	// confirmed is the latest epoch confirmed by the barrier reply.
+	confirmed uint64
+
+	// barriers maps the transaction identifiers of the barrier
+	// requests to the epochs closed by them.
+	barriers map[uint32]uint64
+
+	// changed is closed and replaced when the confirmed epoch is
+	// changed or the connection is removed.
+	changed chan struct{}
+	removed bool
+
+	// sendMu serializes the Send calls of the connection, so the
+	// barriers are written in the order of the epochs.
+	sendMu sync.Mutex
+}
+
+// Epochs is a sequence of the barrier epochs of the datapaths. The
+// modules tag the state changes (flow, group, meter modifications)
+// with the current epoch of the connection they are sent to, the
+// barrier request closes the epoch and starts the next one. Since the
+// switch processes the barrier after all preceding messages, the epoch
+// is confirmed when the barrier reply is received.
+//
+// The epochs are tracked per connection, since the barrier orders only
+// the messages of its own connection. The epochs start from 1, the zero
+// epoch is always confirmed.
+//
+// The epochs of the connection are kept until the connection is
+// removed, the epochs of the closed connections are removed by the
+// ConnState hook of the server. With the hook installed, the epochs are
+// started only for the connections reported as new by the server, so
+// the modules sending the state changes after the connection is closed
+// get ErrEpochAborted instead of starting the epochs, that are never
+// removed. Several hooks are installed with of.ConnStateHooks.
+//
+// The state changes must be sent with the Send method, when the
+// connection is shared by multiple modules. Otherwise the barrier of
+// another module could be sent between the state changes and their
+// barrier, and confirm the epoch before the switch processed them.
+//
+// For example, to wait until the flow is installed:
+//
+//	epochs := ofputil.NewEpochs()
+//	srv := &of.Server{
+//		Addr:      ":6633",
+//		Handler:   epochs.Handler(mux),
+//		ConnState: epochs.ConnState,
+//	}
+//
+//	epoch, err := epochs.Send(conn, flowMod)
+//	if err != nil {
+//		// ...
+//	}
+//
+//	if err := epochs.Wait(ctx, conn, epoch); err != nil {
+//		// ...
+//	}
+type Epochs struct {
+	mu    sync.Mutex
+	conns map[of.Conn]*epochState
+	open  connSet
+}
+
+// NewEpochs creates a new empty sequence of the barrier epochs.
+func NewEpochs() *Epochs {
+	return &Epochs{conns: make(map[of.Conn]*epochState)}
+}
+
+// state returns the epochs of the connection, nil is returned for the
+// closed connections. The lock must be held.
+func (e *Epochs) state(conn of.Conn) *epochState {
+	s, ok := e.conns[conn]
+	if !ok && e.open.isOpen(conn) {
+		s = &epochState{
+			current:  1,
+			barriers: make(map[uint32]uint64),
+			changed:  make(chan struct{}),
+		}
+		e.conns[conn] = s
+	}
+
+	return s
+}
+
+// Current returns the epoch of the state changes sent to the connection
+// now, it is closed by the next barrier request. The epoch could be
+// closed by a concurrent Barrier or Send call before the state changes
+// are sent, use Send when the connection is shared. Zero is returned
+// for the closed connections.
+func (e *Epochs) Current(conn of.Conn) uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if s := e.state(conn); s != nil {
+		return s.current
+	}
+	return 0
+}
+
+// Barrier returns a new barrier request closing the current epoch of
+// the connection and starts the next epoch. The request must be sent
+// to the connection after the state changes of the closed epoch, and
+// before the barriers of the following epochs. The barrier of the closed
+// connection closes no epoch.
+func (e *Epochs) Barrier(conn of.Conn) *of.Request {
+	req := of.NewRequest(of.TypeBarrierRequest, nil)
+	req.Header.Transaction = newXID()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if s := e.state(conn); s != nil {
+		s.barriers[req.Header.Transaction] = s.current
+		s.current++
+	}
+
+	return req
+}
+
+// Send sends the requests followed by the barrier request closing their
+// epoch to the connection, and returns the epoch. The epoch is assigned
+// and the messages are written under the lock of the connection, so
+// the concurrent Send calls can't confirm the epoch before the requests
+// are written. The requests are not sent to the closed connection and
+// ErrEpochAborted is returned.
+func (e *Epochs) Send(conn of.Conn, reqs ...*of.Request) (uint64, error) {
+	e.mu.Lock()
+	s := e.state(conn)
+	e.mu.Unlock()
+
+	if s == nil {
+		return 0, ErrEpochAborted
+	}
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	barrier := of.NewRequest(of.TypeBarrierRequest, nil)
+	barrier.Header.Transaction = newXID()
+
+	e.mu.Lock()
+	epoch := s.current
+	s.barriers[barrier.Header.Transaction] = epoch
+	s.current++
+	e.mu.Unlock()
+
+	reqs = append(reqs[:len(reqs):len(reqs)], barrier)
+	return epoch, of.Send(conn, reqs...)
+}
+
+// Confirmed returns the latest epoch of the connection confirmed by
+// the barrier reply.
+func (e *Epochs) Confirmed(conn of.Conn) uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if s, ok := e.conns[conn]; ok {
+		return s.confirmed
+	}
+	return 0
+}
+
+// IsConfirmed reports whether the given epoch of the connection was
+// confirmed by the barrier reply.
+func (e *Epochs) IsConfirmed(conn of.Conn, epoch uint64) bool {
+	return epoch <= e.Confirmed(conn)
+}
+
+// Wait blocks until the given epoch of the connection is confirmed or
+// the context is canceled. ErrEpochAborted is returned, when the
+// connection is removed before the epoch is confirmed or the epochs of
+// the connection are not tracked.
+func (e *Epochs) Wait(ctx context.Context, conn of.Conn, epoch uint64) error {
+	e.mu.Lock()
+	s, ok := e.conns[conn]
+	e.mu.Unlock()
+
+	// The epochs of the unknown connection are never confirmed, the
+	// connection was either removed or its epochs were never used.
+	if !ok {
+		if epoch == 0 {
+			return nil
+		}
+		return ErrEpochAborted
+	}
+
+	for {
+		e.mu.Lock()
+		confirmed, removed, changed := s.confirmed, s.removed, s.changed
+		e.mu.Unlock()
+
+		switch {
+		case epoch <= confirmed:
+			return nil
+		case removed:
+			return ErrEpochAborted
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Confirm confirms the epoch closed by the barrier request with the
+// given transaction identifier. It returns false when the barrier was
+// not issued by the Barrier call. The epochs of the connections, that
+// never requested the barrier, are not tracked.
+func (e *Epochs) Confirm(conn of.Conn, xid uint32) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s, ok := e.conns[conn]
+	if !ok {
+		return false
+	}
+
+	epoch, ok := s.barriers[xid]
+	if !ok {
+		return false
+	}
+
+	// The barriers are processed in order, so the earlier barriers
+	// are confirmed as well, even when their replies were lost.
+	for xid, closed := range s.barriers {
+		if closed <= epoch {
+			delete(s.barriers, xid)
+		}
+	}
+
+	if epoch > s.confirmed {
+		s.confirmed = epoch
+		close(s.changed)
+		s.changed = make(chan struct{})
+	}
+
+	return true
+}
+
+// Remove stops tracking the epochs of the given connection. The pending
+// Wait calls return ErrEpochAborted.
+func (e *Epochs) Remove(conn of.Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if s, ok := e.conns[conn]; ok {
+		s.removed = true
+		close(s.changed)
+		delete(e.conns, conn)
+	}
+}
+
+// ConnState removes the epochs of the connection closed by the server,
+// the pending Wait calls of the connection are aborted.
+func (e *Epochs) ConnState(conn of.Conn, state of.ConnState) {
+	e.mu.Lock()
+	e.open.update(conn, state)
+	e.mu.Unlock()
+
+	if state == of.StateClosed {
+		e.Remove(conn)
+	}
+}
+
+// Handler returns a handler, that confirms the epochs on the barrier
+// replies and then calls the given handler.
+func (e *Epochs) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if r.Header.Type == of.TypeBarrierReply {
+			e.Confirm(r.Conn(), r.Header.Transaction)
+		}
+
+		h.Serve(rw, r)
+	})
+}
@@ -0,0 +1,162 @@
+package ofputil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestEpochs(t *testing.T) {
+	conn := ofptest.NewConnRecorder()
+	epochs := NewEpochs()
+
+	if epochs.Current(conn) != 1 || !epochs.IsConfirmed(conn, 0) {
+		t.Fatalf("Epochs must start from 1")
+	}
+
+	first := epochs.Barrier(conn)
+	second := epochs.Barrier(conn)
+
+	if epochs.Current(conn) != 3 || epochs.IsConfirmed(conn, 1) {
+		t.Fatalf("Invalid state of epochs: %d", epochs.Current(conn))
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- epochs.Wait(context.Background(), conn, 2)
+	}()
+
+	// The reply of the second barrier confirms the first epoch too.
+	epochs.Confirm(conn, second.Header.Transaction)
+	if err := <-done; err != nil {
+		t.Fatalf("Failed to wait for epoch: %s", err)
+	}
+
+	if !epochs.IsConfirmed(conn, 1) || epochs.Confirmed(conn) != 2 {
+		t.Fatalf("Epochs must be confirmed: %d", epochs.Confirmed(conn))
+	}
+
+	if epochs.Confirm(conn, first.Header.Transaction) {
+		t.Fatalf("Barrier of confirmed epoch must be forgotten")
+	}
+
+	// The handler must confirm the epochs and pass the barrier
+	// replies through. The requests created for tests have no
+	// connection, so the nil connection is used.
+	var served int
+	handler := epochs.Handler(of.HandlerFunc(
+		func(rw of.ResponseWriter, r *of.Request) { served++ }))
+
+	barrier := epochs.Barrier(nil)
+	handler.Serve(nil, barrier.NewReply(of.TypeBarrierReply, nil))
+
+	if served != 1 || epochs.Confirmed(nil) != 1 {
+		t.Fatalf("Barrier reply must be served and confirm epoch")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if err := epochs.Wait(ctx, conn, 10); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Deadline error expected: %v", err)
+	}
+
+	go func() {
+		done <- epochs.Wait(context.Background(), conn, 10)
+	}()
+
+	epochs.ConnState(conn, of.StateClosed)
+
+	if err := <-done; !errors.Is(err, ErrEpochAborted) {
+		t.Fatalf("Aborted error expected: %v", err)
+	}
+
+	// Replies of the closed connections must not be tracked again.
+	if epochs.Confirm(conn, barrier.Header.Transaction) || epochs.Confirmed(conn) != 0 {
+		t.Errorf("Barrier of the removed connection must not be confirmed")
+	}
+
+	if _, ok := epochs.conns[conn]; ok {
+		t.Errorf("Epochs of the removed connection must not be created")
+	}
+}
+
+func TestEpochsSend(t *testing.T) {
+	conn := ofptest.NewConnRecorder()
+	epochs := NewEpochs()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sent = make(map[uint32]uint64)
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 16; j++ {
+				req := of.NewRequest(of.TypeFlowMod, nil)
+				req.Header.Transaction = uint32(i<<8 | j)
+
+				epoch, err := epochs.Send(conn, req)
+				if err != nil {
+					t.Errorf("Failed to send request: %s", err)
+					return
+				}
+
+				mu.Lock()
+				sent[req.Header.Transaction] = epoch
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	// The request of the epoch must be written after the barrier
+	// of the previous epoch and before the barrier of its own.
+	var barriers uint64
+	for _, r := range conn.All() {
+		if r.Header.Type == of.TypeBarrierRequest {
+			barriers++
+			continue
+		}
+
+		if epoch := sent[r.Header.Transaction]; epoch != barriers+1 {
+			t.Fatalf("Request of epoch %d written after %d barriers",
+				epoch, barriers)
+		}
+	}
+
+	if barriers != 8*16 {
+		t.Errorf("Invalid number of barriers: %d", barriers)
+	}
+}
+
+func TestEpochsClosed(t *testing.T) {
+	epochs := NewEpochs()
+
+	var err error
+	h := of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		_, err = epochs.Send(r.Conn(), of.NewRequest(of.TypeFlowMod, nil))
+	})
+
+	serveAfterClose(t, of.NewRequest(of.TypePacketIn, nil), h, epochs.ConnState)
+	if err != ErrEpochAborted {
+		t.Errorf("Send to the closed connection must be aborted: %v", err)
+	}
+
+	epochs.mu.Lock()
+	defer epochs.mu.Unlock()
+
+	if len(epochs.conns) != 0 {
+		t.Errorf("Epochs of the closed connection must not be created")
+	}
+}
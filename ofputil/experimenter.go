@@ -0,0 +1,214 @@
+package ofputil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ExperimenterMatcher matches the experimenter messages by the
+// experimenter identifier and the experimenter type.
+type ExperimenterMatcher ofp.Experimenter
+
+// Match implements of.Matcher interface.
+func (m ExperimenterMatcher) Match(r *of.Request) bool {
+	if r.Header.Type != of.TypeExperiment {
+		return false
+	}
+
+	var exp ofp.Experimenter
+	return r.Decode(&exp) == nil && ofp.Experimenter(m) == exp
+}
+
+// RawExperimenter is an experimenter message with the payload kept in
+// the wire format, so the vendor protocols not modeled by the package
+// could be exchanged with the switches.
+type RawExperimenter struct {
+	ofp.Experimenter
+
+	// Payload is a body of the message following the experimenter
+	// header.
+	Payload []byte
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the
+// experimenter header followed by the payload into the wire format.
+func (e *RawExperimenter) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	if _, err := e.Experimenter.WriteTo(&buf); err != nil {
+		return 0, err
+	}
+
+	buf.Write(e.Payload)
+	return buf.WriteTo(w)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// experimenter header, the rest of the message is read as the payload.
+func (e *RawExperimenter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := e.Experimenter.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+
+	e.Payload, err = ioutil.ReadAll(r)
+	return n + int64(len(e.Payload)), err
+}
+
+// NewExperimenterRequest returns a new experimenter request with the
+// given raw payload.
+func NewExperimenterRequest(experimenter, expType uint32, payload []byte) *of.Request {
+	return of.NewRequest(of.TypeExperiment, &RawExperimenter{
+		Experimenter: ofp.Experimenter{
+			Experimenter: experimenter,
+			ExpType:      expType,
+		},
+		Payload: payload,
+	})
+}
+
+// SendExperimenter sends the experimenter message with the given raw
+// payload to the connection and flushes it.
+func SendExperimenter(conn of.Conn, experimenter, expType uint32, payload []byte) error {
+	if err := conn.Send(NewExperimenterRequest(experimenter, expType, payload)); err != nil {
+		return err
+	}
+
+	return conn.Flush()
+}
+
+// RawExperimenterFunc is a function handling the experimenter message
+// with the raw payload.
+type RawExperimenterFunc func(rw of.ResponseWriter, r *of.Request, msg *RawExperimenter)
+
+// RawExperimenterHandler returns a handler, that decodes the header of
+// the experimenter message and passes the raw payload to the function.
+// The messages of other types and malformed messages are discarded.
+func RawExperimenterHandler(fn RawExperimenterFunc) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if r.Header.Type != of.TypeExperiment {
+			return
+		}
+
+		var msg RawExperimenter
+		if err := r.Decode(&msg); err != nil {
+			Logf(r, "ofputil: failed to decode experimenter message: %v", err)
+			return
+		}
+
+		fn(rw, r, &msg)
+	})
+}
+
+// ExperimenterMux is a multiplexer of the experimenter messages, sent
+// by the switches. It routes the messages to the handlers registered
+// for the experimenter identifier and the experimenter type, so the
+// vendor notifications could be processed by the respective modules.
+//
+// The handlers registered for the experimenter type take precedence
+// over the handlers registered for all types of the experimenter. The
+// messages without a matching handler are served with the Fallback
+// handler, or discarded when it is not specified.
+//
+// For example, to route the Open vSwitch notifications:
+//
+//	exp := ofputil.NewExperimenterMux()
+//	exp.Handle(0x00002320, 1, ovsHandler)
+//
+//	mux := of.NewTypeMux()
+//	mux.Handle(of.TypeExperiment, exp)
+type ExperimenterMux struct {
+	// Fallback is a handler used for the messages that don't have
+	// a registered handler.
+	Fallback of.Handler
+
+	mu      sync.RWMutex
+	types   map[ofp.Experimenter]of.Handler
+	vendors map[uint32]of.Handler
+}
+
+// NewExperimenterMux creates a new empty multiplexer of the
+// experimenter messages.
+func NewExperimenterMux() *ExperimenterMux {
+	return &ExperimenterMux{
+		types:   make(map[ofp.Experimenter]of.Handler),
+		vendors: make(map[uint32]of.Handler),
+	}
+}
+
+// Handle registers the handler for the messages of the given
+// experimenter identifier and experimenter type.
+func (mux *ExperimenterMux) Handle(experimenter, expType uint32, h of.Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	key := ofp.Experimenter{Experimenter: experimenter, ExpType: expType}
+	if _, dup := mux.types[key]; dup {
+		text := "ofputil: multiple registrations for experimenter 0x%x type %d"
+		panic(fmt.Errorf(text, experimenter, expType))
+	}
+
+	mux.types[key] = h
+}
+
+// HandleFunc registers the handler function for the messages of the
+// given experimenter identifier and experimenter type.
+func (mux *ExperimenterMux) HandleFunc(experimenter, expType uint32, f of.HandlerFunc) {
+	mux.Handle(experimenter, expType, f)
+}
+
+// HandleRaw registers the function receiving the raw payload of the
+// messages of the given experimenter identifier and experimenter type.
+func (mux *ExperimenterMux) HandleRaw(experimenter, expType uint32, fn RawExperimenterFunc) {
+	mux.Handle(experimenter, expType, RawExperimenterHandler(fn))
+}
+
+// HandleExperimenter registers the handler for the messages of all
+// types of the given experimenter identifier.
+func (mux *ExperimenterMux) HandleExperimenter(experimenter uint32, h of.Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if _, dup := mux.vendors[experimenter]; dup {
+		text := "ofputil: multiple registrations for experimenter 0x%x"
+		panic(fmt.Errorf(text, experimenter))
+	}
+
+	mux.vendors[experimenter] = h
+}
+
+// Handler returns the handler of the given experimenter message. The
+// header of the experimenter message is decoded without consuming the
+// body of the request.
+func (mux *ExperimenterMux) Handler(r *of.Request) of.Handler {
+	var exp ofp.Experimenter
+	if r.Header.Type == of.TypeExperiment && r.Decode(&exp) == nil {
+		mux.mu.RLock()
+		h, ok := mux.types[exp]
+		if !ok {
+			h, ok = mux.vendors[exp.Experimenter]
+		}
+		mux.mu.RUnlock()
+
+		if ok {
+			return h
+		}
+	}
+
+	if mux.Fallback != nil {
+		return mux.Fallback
+	}
+
+	return of.DiscardHandler
+}
+
+// Serve implements of.Handler interface. It routes the experimenter
+// message to the registered handler.
+func (mux *ExperimenterMux) Serve(rw of.ResponseWriter, r *of.Request) {
+	mux.Handler(r).Serve(rw, r)
+}
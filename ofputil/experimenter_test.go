@@ -0,0 +1,110 @@
+package ofputil
+
+import (
+	"bytes"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestExperimenterMux(t *testing.T) {
+	var served []string
+	handler := func(name string) of.HandlerFunc {
+		return func(rw of.ResponseWriter, r *of.Request) {
+			// The body must be preserved for the handler.
+			var exp ofp.Experimenter
+			if _, err := exp.ReadFrom(r.Body); err != nil {
+				t.Errorf("Failed to decode experimenter header: %s", err)
+			}
+
+			served = append(served, name)
+		}
+	}
+
+	mux := NewExperimenterMux()
+	mux.Handle(0x2320, 1, handler("ovs-1"))
+	mux.HandleExperimenter(0x2320, handler("ovs"))
+	mux.HandleFunc(0x4f4e4600, 2300, handler("onf"))
+
+	newRequest := func(experimenter, expType uint32) *of.Request {
+		return of.NewRequest(of.TypeExperiment, &ofp.Experimenter{
+			Experimenter: experimenter, ExpType: expType,
+		})
+	}
+
+	mux.Serve(nil, newRequest(0x2320, 1))
+	mux.Serve(nil, newRequest(0x2320, 2))
+	mux.Serve(nil, newRequest(0x4f4e4600, 2300))
+
+	// Without the fallback handler the message is discarded.
+	mux.Serve(nil, newRequest(0x4f4e4600, 2301))
+
+	mux.Fallback = handler("fallback")
+	mux.Serve(nil, newRequest(0x4f4e4600, 2301))
+
+	expected := []string{"ovs-1", "ovs", "onf", "fallback"}
+	if len(served) != len(expected) {
+		t.Fatalf("Invalid handlers served: %v", served)
+	}
+
+	for i := range expected {
+		if served[i] != expected[i] {
+			t.Fatalf("Invalid handlers served: %v", served)
+		}
+	}
+
+	m := ExperimenterMatcher{Experimenter: 0x2320, ExpType: 1}
+	if !m.Match(newRequest(0x2320, 1)) || m.Match(newRequest(0x2320, 2)) {
+		t.Errorf("Experimenter matcher must match by identifier and type")
+	}
+
+	if m.Match(of.NewRequest(of.TypeHello, nil)) {
+		t.Errorf("Experimenter matcher must not match other types")
+	}
+}
+
+func TestExperimenterMuxDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Duplicate registration must panic")
+		}
+	}()
+
+	mux := NewExperimenterMux()
+	mux.Handle(0x2320, 1, of.DiscardHandler)
+	mux.Handle(0x2320, 1, of.DiscardHandler)
+}
+
+func TestRawExperimenter(t *testing.T) {
+	rec := ofptest.NewConnRecorder()
+	payload := []byte{0x01, 0x02, 0x03}
+
+	if err := SendExperimenter(rec, 0x2320, 7, payload); err != nil {
+		t.Fatalf("Failed to send experimenter message: %s", err)
+	}
+
+	if err := rec.ExpectTypes(of.TypeExperiment); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Flushed() != 1 {
+		t.Errorf("Experimenter message must be flushed")
+	}
+
+	var received *RawExperimenter
+	mux := NewExperimenterMux()
+	mux.HandleRaw(0x2320, 7, func(rw of.ResponseWriter, r *of.Request, msg *RawExperimenter) {
+		received = msg
+	})
+
+	mux.Serve(nil, rec.First())
+	if received == nil {
+		t.Fatalf("Raw experimenter handler must be called")
+	}
+
+	if received.ExpType != 7 || !bytes.Equal(received.Payload, payload) {
+		t.Errorf("Invalid raw experimenter message: %v", received)
+	}
+}
@@ -0,0 +1,101 @@
+package ofputil
+
+import (
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// clockOrSystem returns the given clock, or the system clock when the
+// clock is not specified.
+func clockOrSystem(c of.Clock) of.Clock {
+	if c == nil {
+		return of.SystemClock
+	}
+	return c
+}
+
+// FlowExpiry describes the wall-clock times when the flow entry is
+// removed from the flow table by the switch. The zero time of the Idle
+// or Hard field means the respective timeout is not configured.
+type FlowExpiry struct {
+	// Idle is a time the flow entry expires, unless it matches any
+	// packet after the reference time used to compute the expiry.
+	Idle time.Time
+
+	// Hard is a time the flow entry expires regardless of the
+	// matched traffic.
+	Hard time.Time
+}
+
+// FlowModExpiry returns the expiry times of the flow entry installed by
+// the given flow modification at the specified time.
+func FlowModExpiry(fmod *ofp.FlowMod, installed time.Time) FlowExpiry {
+	return flowExpiry(installed, installed, fmod.IdleTimeout, fmod.HardTimeout)
+}
+
+// FlowStatsExpiry returns the expiry times of the flow entry described
+// by the given flow statistics received at the specified time.
+//
+// The install time of the entry is derived from the duration reported
+// by the switch. The time of the last packet matched by the entry is not
+// reported, so the idle expiry is computed from the given time, as the
+// entry is not known to be idle before it.
+func FlowStatsExpiry(flow *ofp.FlowStats, received time.Time) FlowExpiry {
+	duration := time.Duration(flow.DurationSec)*time.Second +
+		time.Duration(flow.DurationNSec)
+
+	installed := received.Add(-duration)
+	return flowExpiry(installed, received, flow.IdleTimeout, flow.HardTimeout)
+}
+
+func flowExpiry(installed, seen time.Time, idle, hard uint16) (e FlowExpiry) {
+	if idle != 0 {
+		e.Idle = seen.Add(time.Duration(idle) * time.Second)
+	}
+	if hard != 0 {
+		e.Hard = installed.Add(time.Duration(hard) * time.Second)
+	}
+	return e
+}
+
+// Permanent returns true when neither idle nor hard timeout is
+// configured, so the flow entry is never removed by the switch.
+func (e FlowExpiry) Permanent() bool {
+	return e.Idle.IsZero() && e.Hard.IsZero()
+}
+
+// Time returns the earliest of the idle and hard expiry times. The
+// second value is false when the flow entry is permanent.
+func (e FlowExpiry) Time() (time.Time, bool) {
+	switch {
+	case e.Permanent():
+		return time.Time{}, false
+	case e.Idle.IsZero():
+		return e.Hard, true
+	case e.Hard.IsZero() || e.Idle.Before(e.Hard):
+		return e.Idle, true
+	}
+	return e.Hard, true
+}
+
+// Expired returns true when the flow entry is expected to be removed
+// at the given time.
+func (e FlowExpiry) Expired(now time.Time) bool {
+	t, ok := e.Time()
+	return ok && !now.Before(t)
+}
+
+// AfterFunc calls the function in its own goroutine, when the flow
+// entry expires according to the given clock. The system clock is used,
+// when the clock is nil. It returns nil for the permanent flow entries.
+func (e FlowExpiry) AfterFunc(clock of.Clock, f func()) of.Timer {
+	t, ok := e.Time()
+	if !ok {
+		return nil
+	}
+
+	clock = clockOrSystem(clock)
+	return clock.AfterFunc(t.Sub(clock.Now()), f)
+}
@@ -0,0 +1,93 @@
+package ofputil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestFlowModExpiry(t *testing.T) {
+	installed := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	e := FlowModExpiry(&ofp.FlowMod{}, installed)
+	if !e.Permanent() {
+		t.Fatalf("Flow without timeouts must be permanent: %v", e)
+	}
+
+	if _, ok := e.Time(); ok || e.Expired(installed.Add(time.Hour)) {
+		t.Errorf("Permanent flow must not expire")
+	}
+
+	e = FlowModExpiry(&ofp.FlowMod{IdleTimeout: 10, HardTimeout: 60}, installed)
+	if !e.Idle.Equal(installed.Add(10*time.Second)) ||
+		!e.Hard.Equal(installed.Add(time.Minute)) {
+		t.Fatalf("Invalid expiry times: %v", e)
+	}
+
+	if tm, ok := e.Time(); !ok || !tm.Equal(e.Idle) {
+		t.Errorf("Idle expiry expected first: %v", tm)
+	}
+
+	if e.Expired(installed.Add(9*time.Second)) ||
+		!e.Expired(installed.Add(10*time.Second)) {
+		t.Errorf("Flow must expire after idle timeout")
+	}
+
+	e = FlowModExpiry(&ofp.FlowMod{HardTimeout: 60}, installed)
+	if tm, ok := e.Time(); !ok || !tm.Equal(installed.Add(time.Minute)) {
+		t.Errorf("Hard expiry expected: %v", tm)
+	}
+}
+
+func TestFlowStatsExpiry(t *testing.T) {
+	received := time.Date(2020, 1, 1, 0, 1, 0, 0, time.UTC)
+
+	flow := &ofp.FlowStats{
+		DurationSec:  30,
+		DurationNSec: 500,
+		IdleTimeout:  10,
+		HardTimeout:  60,
+	}
+
+	e := FlowStatsExpiry(flow, received)
+
+	hard := received.Add(30*time.Second - 500*time.Nanosecond)
+	if !e.Hard.Equal(hard) {
+		t.Errorf("Invalid hard expiry time: %s", e.Hard)
+	}
+
+	if !e.Idle.Equal(received.Add(10 * time.Second)) {
+		t.Errorf("Invalid idle expiry time: %s", e.Idle)
+	}
+
+	e = FlowStatsExpiry(&ofp.FlowStats{DurationSec: 30}, received)
+	if !e.Permanent() {
+		t.Errorf("Flow without timeouts must be permanent: %v", e)
+	}
+}
+
+func TestFlowExpiryAfterFunc(t *testing.T) {
+	clock := ofptest.NewClock(time.Unix(0, 0))
+	fmod := &ofp.FlowMod{IdleTimeout: 10, HardTimeout: 30}
+
+	var expired bool
+	FlowModExpiry(fmod, clock.Now()).AfterFunc(clock, func() {
+		expired = true
+	})
+
+	clock.Advance(9 * time.Second)
+	if expired {
+		t.Fatalf("Flow must not expire before idle timeout")
+	}
+
+	clock.Advance(time.Second)
+	if !expired {
+		t.Fatalf("Flow must expire after idle timeout")
+	}
+
+	if FlowModExpiry(&ofp.FlowMod{}, clock.Now()).AfterFunc(clock, nil) != nil {
+		t.Fatalf("Permanent flow must not schedule a timer")
+	}
+}
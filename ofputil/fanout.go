@@ -0,0 +1,289 @@
+package ofputil
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrUnknownController is returned when the connection was not added
+// to the set of controllers.
+var ErrUnknownController = errors.New("ofputil: unknown controller connection")
+
+// DefaultAsyncConfig returns the asynchronous configuration used by the
+// switch for a newly connected controller. The controllers in master or
+// equal role receive all asynchronous messages except the packet-in
+// messages caused by the invalid TTL, the controllers in slave role
+// receive only port status messages.
+func DefaultAsyncConfig() ofp.AsyncConfig {
+	return ofp.AsyncConfig{
+		PacketInMask: Bitmap64(PacketInReasonBitmap(
+			ofp.PacketInReasonNoMatch, ofp.PacketInReasonAction), 0),
+
+		PortStatusMask: Bitmap64(
+			PortReasonBitmap(ofp.PortReasonAdd, ofp.PortReasonDelete,
+				ofp.PortReasonModify),
+			PortReasonBitmap(ofp.PortReasonAdd, ofp.PortReasonDelete,
+				ofp.PortReasonModify),
+		),
+
+		FlowRemovedMask: Bitmap64(FlowReasonBitmap(
+			ofp.FlowReasonIdleTimeout, ofp.FlowReasonHardTimeout,
+			ofp.FlowReasonDelete, ofp.FlowReasonGroupDelete), 0),
+	}
+}
+
+// controllerState is a role and asynchronous configuration of the
+// single controller connection.
+type controllerState struct {
+	role  ofp.ControllerRole
+	async ofp.AsyncConfig
+}
+
+// Controllers maintains the connections of the datapath to multiple
+// controllers, together with the role and asynchronous configuration of
+// each of them, and routes the asynchronous messages according to them.
+//
+// Controllers is used in the switch or proxy role. For example, to
+// serve the role and asynchronous configuration requests and send the
+// packet-in message to all interested controllers:
+//
+//	ctrls := ofputil.NewControllers()
+//	ctrls.Add(conn)
+//
+//	// For each request received from the connection.
+//	if handled, err := ctrls.Serve(conn, r); handled {
+//		// ...
+//	}
+//
+//	err := ctrls.PacketIn(&ofp.PacketIn{Reason: ofp.PacketInReasonNoMatch})
+type Controllers struct {
+	mu    sync.RWMutex
+	conns map[of.Conn]*controllerState
+
+	// generation is the largest generation identifier seen in the
+	// role requests changing the role to master or slave.
+	generation    uint64
+	hasGeneration bool
+}
+
+// NewControllers creates a new empty set of controllers.
+func NewControllers() *Controllers {
+	return &Controllers{conns: make(map[of.Conn]*controllerState)}
+}
+
+// Add adds the connection to the set of controllers. The controller
+// assumes the equal role and the default asynchronous configuration.
+func (c *Controllers) Add(conn of.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conns[conn] = &controllerState{
+		role:  ofp.ControllerRoleEqual,
+		async: DefaultAsyncConfig(),
+	}
+}
+
+// Remove removes the connection from the set of controllers.
+func (c *Controllers) Remove(conn of.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.conns, conn)
+}
+
+// Role returns the current role of the controller.
+func (c *Controllers) Role(conn of.Conn) (ofp.ControllerRole, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state, ok := c.conns[conn]
+	if !ok {
+		return ofp.ControllerRoleNoChange, ErrUnknownController
+	}
+
+	return state.role, nil
+}
+
+// SetRole changes the role of the controller according to the role
+// request and returns the resulting role.
+//
+// The generation identifier of the requests changing the role to master
+// or slave is validated against the largest one seen before, the stale
+// requests are rejected with ofp.Error of ErrCodeRoleRequestFailedStale
+// code. When the controller becomes master, all other masters are
+// changed to slaves.
+func (c *Controllers) SetRole(conn of.Conn, req *ofp.RoleRequest) (ofp.ControllerRole, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.conns[conn]
+	if !ok {
+		return ofp.ControllerRoleNoChange, ErrUnknownController
+	}
+
+	switch req.Role {
+	case ofp.ControllerRoleNoChange:
+		return state.role, nil
+	case ofp.ControllerRoleEqual:
+		state.role = req.Role
+		return state.role, nil
+	case ofp.ControllerRoleMaster, ofp.ControllerRoleSlave:
+	default:
+		return state.role, ofp.Error{
+			Type: ofp.ErrTypeRoleRequestFailed,
+			Code: ofp.ErrCodeRoleRequestFailedBadRole,
+		}
+	}
+
+	// The generation identifiers are compared using the
+	// serial number arithmetic to handle the wrap around.
+	if c.hasGeneration && int64(req.GenerationID-c.generation) < 0 {
+		return state.role, ofp.Error{
+			Type: ofp.ErrTypeRoleRequestFailed,
+			Code: ofp.ErrCodeRoleRequestFailedStale,
+		}
+	}
+
+	c.generation, c.hasGeneration = req.GenerationID, true
+
+	if req.Role == ofp.ControllerRoleMaster {
+		for _, other := range c.conns {
+			if other.role == ofp.ControllerRoleMaster {
+				other.role = ofp.ControllerRoleSlave
+			}
+		}
+	}
+
+	state.role = req.Role
+	return state.role, nil
+}
+
+// Async returns the asynchronous configuration of the controller.
+func (c *Controllers) Async(conn of.Conn) (ofp.AsyncConfig, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state, ok := c.conns[conn]
+	if !ok {
+		return ofp.AsyncConfig{}, ErrUnknownController
+	}
+
+	return state.async, nil
+}
+
+// SetAsync replaces the asynchronous configuration of the controller.
+func (c *Controllers) SetAsync(conn of.Conn, config *ofp.AsyncConfig) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.conns[conn]
+	if !ok {
+		return ErrUnknownController
+	}
+
+	state.async = *config
+	return nil
+}
+
+// Serve handles the role request and the asynchronous configuration
+// messages received from the controller connection and sends the
+// replies to it. It reports whether the request was handled.
+//
+// The failed role requests are replied with the error message.
+func (c *Controllers) Serve(conn of.Conn, r *of.Request) (bool, error) {
+	var reply *of.Request
+
+	switch r.Header.Type {
+	case of.TypeRoleRequest:
+		var req ofp.RoleRequest
+		if err := r.Decode(&req); err != nil {
+			return true, err
+		}
+
+		role, err := c.SetRole(conn, &req)
+		if e, ok := NewErrorReply(r, err); ok {
+			reply = e
+		} else if err != nil {
+			return true, err
+		} else {
+			reply = r.NewReply(of.TypeRoleReply, &ofp.RoleRequest{
+				Role: role, GenerationID: req.GenerationID,
+			})
+		}
+	case of.TypeSetAsync:
+		var config ofp.AsyncConfig
+		if _, err := config.ReadFrom(r.Body); err != nil {
+			return true, err
+		}
+
+		return true, c.SetAsync(conn, &config)
+	case of.TypeGetAsyncRequest:
+		config, err := c.Async(conn)
+		if err != nil {
+			return true, err
+		}
+
+		reply = r.NewReply(of.TypeGetAsyncReply, &config)
+	default:
+		return false, nil
+	}
+
+	return true, of.Send(conn, reply)
+}
+
+// publish sends the message of the given type to each controller, for
+// which the enabled function returns true. The first error is returned
+// after the message is sent to all controllers.
+func (c *Controllers) publish(t of.Type, body io.WriterTo,
+	enabled func(*controllerState) bool) error {
+
+	c.mu.RLock()
+	var conns []of.Conn
+	for conn, state := range c.conns {
+		if enabled(state) {
+			conns = append(conns, conn)
+		}
+	}
+	c.mu.RUnlock()
+
+	var rerr error
+	for _, conn := range conns {
+		err := of.Send(conn, of.NewRequest(t, body))
+		if err != nil && rerr == nil {
+			rerr = err
+		}
+	}
+
+	return rerr
+}
+
+// PacketIn sends the packet-in message to the controllers, which
+// asynchronous configuration enables the reason of the message for
+// their role.
+func (c *Controllers) PacketIn(p *ofp.PacketIn) error {
+	return c.publish(of.TypePacketIn, p, func(s *controllerState) bool {
+		return s.async.IsPacketInEnabled(s.role, p.Reason)
+	})
+}
+
+// PortStatus sends the port status message to the controllers, which
+// asynchronous configuration enables the reason of the message for
+// their role.
+func (c *Controllers) PortStatus(p *ofp.PortStatus) error {
+	return c.publish(of.TypePortStatus, p, func(s *controllerState) bool {
+		return s.async.IsPortStatusEnabled(s.role, p.Reason)
+	})
+}
+
+// FlowRemoved sends the flow removed message to the controllers, which
+// asynchronous configuration enables the reason of the message for
+// their role.
+func (c *Controllers) FlowRemoved(f *ofp.FlowRemoved) error {
+	return c.publish(of.TypeFlowRemoved, f, func(s *controllerState) bool {
+		return s.async.IsFlowRemovedEnabled(s.role, f.Reason)
+	})
+}
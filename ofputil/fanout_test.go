@@ -0,0 +1,120 @@
+package ofputil
+
+import (
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestControllersSetRole(t *testing.T) {
+	c1, c2 := ofptest.NewConnRecorder(), ofptest.NewConnRecorder()
+
+	ctrls := NewControllers()
+	ctrls.Add(c1)
+	ctrls.Add(c2)
+
+	master := &ofp.RoleRequest{Role: ofp.ControllerRoleMaster, GenerationID: 2}
+	if _, err := ctrls.SetRole(c1, master); err != nil {
+		t.Fatalf("Failed to change role: %s", err)
+	}
+
+	if _, err := ctrls.SetRole(c2, master); err != nil {
+		t.Fatalf("Failed to change role: %s", err)
+	}
+
+	// The previous master must be changed to slave.
+	if role, _ := ctrls.Role(c1); role != ofp.ControllerRoleSlave {
+		t.Errorf("Controller must become slave: %d", role)
+	}
+
+	stale := &ofp.RoleRequest{Role: ofp.ControllerRoleMaster, GenerationID: 1}
+	_, err := ctrls.SetRole(c1, stale)
+
+	e, ok := err.(ofp.Error)
+	if !ok || e.Code != ofp.ErrCodeRoleRequestFailedStale {
+		t.Errorf("Stale role request error expected: %v", err)
+	}
+
+	if _, err = ctrls.Role(ofptest.NewConnRecorder()); err != ErrUnknownController {
+		t.Errorf("Unknown controller error expected: %v", err)
+	}
+}
+
+func TestControllersServe(t *testing.T) {
+	conn := ofptest.NewConnRecorder()
+
+	ctrls := NewControllers()
+	ctrls.Add(conn)
+
+	req := of.NewRequest(of.TypeRoleRequest, &ofp.RoleRequest{
+		Role: ofp.ControllerRoleSlave,
+	})
+	req.Header.Transaction = 42
+
+	if handled, err := ctrls.Serve(conn, req); !handled || err != nil {
+		t.Fatalf("Role request must be handled: %v, %v", handled, err)
+	}
+
+	if err := conn.ExpectTypes(of.TypeRoleReply); err != nil {
+		t.Fatalf("Invalid replies: %s", err)
+	}
+
+	if xid := conn.Last().Header.Transaction; xid != 42 {
+		t.Errorf("Invalid transaction of the reply: %d", xid)
+	}
+
+	var reply ofp.RoleRequest
+	if err := conn.Decode(0, &reply); err != nil {
+		t.Fatalf("Failed to decode role reply: %s", err)
+	}
+
+	if reply.Role != ofp.ControllerRoleSlave {
+		t.Errorf("Invalid role in reply: %d", reply.Role)
+	}
+
+	echo := of.NewRequest(of.TypeEchoRequest, nil)
+	if handled, _ := ctrls.Serve(conn, echo); handled {
+		t.Errorf("Echo request must not be handled")
+	}
+}
+
+func TestControllersPublish(t *testing.T) {
+	master, slave := ofptest.NewConnRecorder(), ofptest.NewConnRecorder()
+
+	ctrls := NewControllers()
+	ctrls.Add(master)
+	ctrls.Add(slave)
+
+	ctrls.SetRole(master, &ofp.RoleRequest{Role: ofp.ControllerRoleMaster})
+	ctrls.SetRole(slave, &ofp.RoleRequest{Role: ofp.ControllerRoleSlave})
+
+	err := ctrls.PacketIn(&ofp.PacketIn{Reason: ofp.PacketInReasonNoMatch})
+	if err != nil {
+		t.Fatalf("Failed to send packet-in: %s", err)
+	}
+
+	err = ctrls.PortStatus(&ofp.PortStatus{Reason: ofp.PortReasonAdd})
+	if err != nil {
+		t.Fatalf("Failed to send port status: %s", err)
+	}
+
+	if err = master.ExpectTypes(of.TypePacketIn, of.TypePortStatus); err != nil {
+		t.Errorf("Invalid messages sent to master: %s", err)
+	}
+
+	if err = slave.ExpectTypes(of.TypePortStatus); err != nil {
+		t.Errorf("Invalid messages sent to slave: %s", err)
+	}
+
+	// Enable the packet-in messages for the slave controller.
+	config := DefaultAsyncConfig()
+	config.PacketInMask[1] = PacketInReasonBitmap(ofp.PacketInReasonNoMatch)
+	ctrls.SetAsync(slave, &config)
+
+	ctrls.PacketIn(&ofp.PacketIn{Reason: ofp.PacketInReasonNoMatch})
+	if slave.Len() != 2 {
+		t.Errorf("Packet-in must be sent to slave: %v", slave.Types())
+	}
+}
@@ -12,7 +12,7 @@ func TableFlush(table ofp.Table) *of.Request {
 		Buffer:   ofp.NoBuffer,
 		OutPort:  ofp.PortAny,
 		OutGroup: ofp.GroupAny,
-		Match:    ofp.Match{ofp.MatchTypeXM, nil},
+		Match:    ofp.Match{Type: ofp.MatchTypeXM},
 	})
 }
 
@@ -32,6 +32,57 @@ func FlowDrop(table ofp.Table) *of.Request {
 		Table:   table,
 		Command: ofp.FlowAdd,
 		Buffer:  ofp.NoBuffer,
-		Match:   ofp.Match{ofp.MatchTypeXM, nil},
+		Match:   ofp.Match{Type: ofp.MatchTypeXM},
 	})
 }
+
+// TableMissController returns the requests that install the table-miss
+// flow entry sending the packets to the controller and configure the
+// switch with the matching miss_send_len. The packets are truncated to
+// maxLen bytes, ofp.ContentLenNoBuffer is used to send the complete
+// packets without buffering them on the switch.
+//
+// The set-config message replaces the whole switch configuration, so
+// the fragment handling flags are sent as given, the flags returned in
+// the get-config reply could be passed to keep them unchanged.
+//
+// Keeping both values consistent prevents the packet-in messages from
+// being truncated unexpectedly. For example, to send the first 128 bytes
+// of the packets missing the first table:
+//
+//	reqs := ofputil.TableMissController(0, 128, ofp.ConfigFlagFragNormal)
+//	err := of.Send(conn, reqs...)
+func TableMissController(table ofp.Table, maxLen uint16, flags ofp.ConfigFlag) []*of.Request {
+	fmod := of.NewRequest(of.TypeFlowMod, &ofp.FlowMod{
+		Table:    table,
+		Command:  ofp.FlowAdd,
+		Buffer:   ofp.NoBuffer,
+		OutPort:  ofp.PortAny,
+		OutGroup: ofp.GroupAny,
+		Match:    ofp.Match{Type: ofp.MatchTypeXM},
+		Instructions: ActionsApply(&ofp.ActionOutput{
+			Port:   ofp.PortController,
+			MaxLen: maxLen,
+		}),
+	})
+
+	config := of.NewRequest(of.TypeSetConfig, &ofp.SwitchConfig{
+		Flags:          flags,
+		MissSendLength: maxLen,
+	})
+
+	return []*of.Request{fmod, config}
+}
+
+// FlowRestore returns the flow modification requests, that add the flow
+// entries described by the given flow statistics. It could be used to
+// restore the snapshot of the flow tables retrieved with the flow
+// statistics request, or to clone the flow entries between switches.
+func FlowRestore(flows ...*ofp.FlowStats) []*of.Request {
+	reqs := make([]*of.Request, 0, len(flows))
+	for _, flow := range flows {
+		reqs = append(reqs, of.NewRequest(of.TypeFlowMod, flow.FlowMod()))
+	}
+
+	return reqs
+}
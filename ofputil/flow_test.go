@@ -0,0 +1,80 @@
+package ofputil
+
+import (
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestTableMissController(t *testing.T) {
+	reqs := TableMissController(2, 128, ofp.ConfigFlagFragReasm)
+	if len(reqs) != 2 {
+		t.Fatalf("Expected two requests, got %d", len(reqs))
+	}
+
+	if reqs[0].Header.Type != of.TypeFlowMod {
+		t.Fatalf("Invalid type of the first request: %s", reqs[0].Header.Type)
+	}
+
+	var fmod ofp.FlowMod
+	if _, err := fmod.ReadFrom(reqs[0].Body); err != nil {
+		t.Fatalf("Failed to decode flow mod: %s", err)
+	}
+
+	if fmod.Table != 2 || fmod.Priority != 0 || len(fmod.Match.Fields) != 0 {
+		t.Errorf("Flow mod must install table-miss entry: %v", fmod)
+	}
+
+	apply, ok := fmod.Instructions[0].(*ofp.InstructionApplyActions)
+	if !ok || len(apply.Actions) != 1 {
+		t.Fatalf("Invalid instructions: %v", fmod.Instructions)
+	}
+
+	output, ok := apply.Actions[0].(*ofp.ActionOutput)
+	if !ok || output.Port != ofp.PortController || output.MaxLen != 128 {
+		t.Errorf("Invalid output action: %v", apply.Actions[0])
+	}
+
+	if reqs[1].Header.Type != of.TypeSetConfig {
+		t.Fatalf("Invalid type of the second request: %s", reqs[1].Header.Type)
+	}
+
+	var config ofp.SwitchConfig
+	if _, err := config.ReadFrom(reqs[1].Body); err != nil {
+		t.Fatalf("Failed to decode switch config: %s", err)
+	}
+
+	if config.Flags != ofp.ConfigFlagFragReasm {
+		t.Errorf("Invalid switch config flags: %v", config.Flags)
+	}
+
+	if config.MissSendLength != output.MaxLen {
+		t.Errorf("Miss send length %d does not match output max length",
+			config.MissSendLength)
+	}
+}
+
+func TestFlowRestore(t *testing.T) {
+	flows := []*ofp.FlowStats{
+		{Table: 0, Priority: 10, Match: ofp.Match{Type: ofp.MatchTypeXM}},
+		{Table: 1, Priority: 20, Match: ofp.Match{Type: ofp.MatchTypeXM}},
+	}
+
+	reqs := FlowRestore(flows...)
+	if len(reqs) != len(flows) {
+		t.Fatalf("Expected %d requests, got %d", len(flows), len(reqs))
+	}
+
+	for i, req := range reqs {
+		var fmod ofp.FlowMod
+		if _, err := fmod.ReadFrom(req.Body); err != nil {
+			t.Fatalf("Failed to decode flow mod: %s", err)
+		}
+
+		if fmod.Command != ofp.FlowAdd || fmod.Table != flows[i].Table ||
+			fmod.Priority != flows[i].Priority {
+			t.Errorf("Invalid flow mod: %v", fmod)
+		}
+	}
+}
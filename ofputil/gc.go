@@ -0,0 +1,279 @@
+package ofputil
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// FlowGC removes the flow entries installed by the controller, that are
+// not present in the desired state anymore. The ownership of the flow
+// entries is defined by the cookie namespace: the bits of the cookie
+// selected by the CookieMask must be equal to the Cookie.
+//
+// The collector periodically dumps the flows of the namespace and sends
+// the strict delete requests for the stale entries, so the rules are not
+// leaked after the controller crashes or loses the connection. FlowGC
+// implements both of.Matcher and of.Handler interfaces, the replies and
+// the errors of the flow dumps should be routed to it, for example:
+//
+//	gc := &ofputil.FlowGC{
+//		Cookie:     0xab00000000000000,
+//		CookieMask: 0xff00000000000000,
+//		Desired:    cache.Contains,
+//	}
+//
+//	mux.Handle(gc, gc)
+//	go gc.Run(ctx, conn, time.Minute)
+type FlowGC struct {
+	// Cookie is a namespace of the controller flow entries.
+	Cookie uint64
+
+	// CookieMask selects the bits of the cookie used as a namespace.
+	// A zero mask makes the collector own all flow entries.
+	CookieMask uint64
+
+	// Desired reports whether the flow entry is present in the desired
+	// state. When not defined, all flow entries of the namespace are
+	// considered stale.
+	Desired func(*ofp.FlowStats) bool
+
+	// Clock is used to schedule the flow dumps. When not defined,
+	// the system clock is used.
+	Clock of.Clock
+
+	mu sync.Mutex
+
+	// pending is a list of the flow dumps in progress, indexed by the
+	// transaction identifiers.
+	pending map[uint32]*flowDump
+}
+
+// flowDump is a flow dump in progress.
+type flowDump struct {
+	// flows are the flow entries received in the replies.
+	flows []*ofp.FlowStats
+
+	// sent is a time, when the flow dump was requested.
+	sent time.Time
+}
+
+// Owns reports whether the flow entry with the given cookie belongs to
+// the namespace of the collector.
+func (gc *FlowGC) Owns(cookie uint64) bool {
+	return cookie&gc.CookieMask == gc.Cookie&gc.CookieMask
+}
+
+// Request returns a new flow statistics request, that dumps the flows
+// of the namespace from all tables. The transaction identifier of the
+// request is remembered, so the replies could be matched by the Match.
+func (gc *FlowGC) Request() *of.Request {
+	req := of.NewRequest(of.TypeMultipartRequest, ofp.NewMultipartRequest(
+		ofp.MultipartTypeFlow, &ofp.FlowStatsRequest{
+			Table:      ofp.TableAll,
+			OutPort:    ofp.PortAny,
+			OutGroup:   ofp.GroupAny,
+			Cookie:     gc.Cookie & gc.CookieMask,
+			CookieMask: gc.CookieMask,
+			Match:      ofp.Match{Type: ofp.MatchTypeXM},
+		}))
+
+	req.Header.Transaction = newXID()
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if gc.pending == nil {
+		gc.pending = make(map[uint32]*flowDump)
+	}
+
+	now := clockOrSystem(gc.Clock).Now()
+	gc.pending[req.Header.Transaction] = &flowDump{sent: now}
+	return req
+}
+
+// Collect returns the strict delete requests for the given flow entries,
+// that belong to the namespace, but are not present in the desired state.
+func (gc *FlowGC) Collect(flows ...*ofp.FlowStats) []*of.Request {
+	var reqs []*of.Request
+	for _, mod := range gc.collect(flows) {
+		reqs = append(reqs, of.NewRequest(of.TypeFlowMod, mod))
+	}
+
+	return reqs
+}
+
+// collect returns the strict delete flow modifications of the stale
+// flow entries.
+func (gc *FlowGC) collect(flows []*ofp.FlowStats) []*ofp.FlowMod {
+	var mods []*ofp.FlowMod
+
+	for _, flow := range flows {
+		if !gc.Owns(flow.Cookie) {
+			continue
+		}
+
+		if gc.Desired != nil && gc.Desired(flow) {
+			continue
+		}
+
+		mods = append(mods, &ofp.FlowMod{
+			Cookie:     flow.Cookie,
+			CookieMask: ^uint64(0),
+			Table:      flow.Table,
+			Command:    ofp.FlowDeleteStrict,
+			Priority:   flow.Priority,
+			Buffer:     ofp.NoBuffer,
+			OutPort:    ofp.PortAny,
+			OutGroup:   ofp.GroupAny,
+			Match:      flow.Match,
+		})
+	}
+
+	return mods
+}
+
+// Match implements of.Matcher interface. It matches the multipart
+// replies and the errors to the flow dumps requested by the collector.
+func (gc *FlowGC) Match(r *of.Request) bool {
+	if r.Header.Type != of.TypeMultipartReply && r.Header.Type != of.TypeError {
+		return false
+	}
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	_, ok := gc.pending[r.Header.Transaction]
+	return ok
+}
+
+// Serve implements of.Handler interface. It accumulates the flow entries
+// from the replies to the flow dump and after the last reply is received
+// sends the delete requests for the stale flow entries. The flow dump
+// failed with the error is removed.
+func (gc *FlowGC) Serve(rw of.ResponseWriter, r *of.Request) {
+	if r.Header.Type == of.TypeError {
+		gc.forget(r.Header.Transaction)
+
+		e, err := ofp.ReadError(r.Body)
+		if err != nil {
+			Logf(r, "ofputil: failed to read the message: %v", err)
+			return
+		}
+
+		Logf(r, "ofputil: flow dump failed: %v", e)
+		return
+	}
+
+	var reply ofp.MultipartReply
+	if _, err := reply.ReadFrom(r.Body); err != nil {
+		Logf(r, "ofputil: failed to read the message: %v", err)
+		return
+	}
+
+	var flows []*ofp.FlowStats
+	for {
+		var flow ofp.FlowStats
+		_, err := flow.ReadFrom(r.Body)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			Logf(r, "ofputil: failed to read the flow entry: %v", err)
+			gc.forget(r.Header.Transaction)
+			return
+		}
+
+		flows = append(flows, &flow)
+	}
+
+	flows, done := gc.append(r.Header.Transaction, flows,
+		reply.Flags&ofp.MultipartReplyMode == 0)
+
+	// Wait for the remaining parts of the flow dump.
+	if !done {
+		return
+	}
+
+	for _, mod := range gc.collect(flows) {
+		header := &of.Header{Version: r.Header.Version, Type: of.TypeFlowMod}
+		if err := rw.Write(header, mod); err != nil {
+			Logf(r, "ofputil: failed to delete the flow entry: %v", err)
+			return
+		}
+	}
+}
+
+// append appends the flow entries to the flow dump of the given
+// transaction. When the last part is received, the flow dump is
+// removed and returned with true.
+func (gc *FlowGC) append(xid uint32, flows []*ofp.FlowStats,
+	last bool) ([]*ofp.FlowStats, bool) {
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	dump, ok := gc.pending[xid]
+	if !ok {
+		return nil, false
+	}
+
+	dump.flows = append(dump.flows, flows...)
+	if !last {
+		return nil, false
+	}
+
+	flows = dump.flows
+	delete(gc.pending, xid)
+	return flows, true
+}
+
+// forget removes the flow dump of the given transaction.
+func (gc *FlowGC) forget(xid uint32) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	delete(gc.pending, xid)
+}
+
+// expire removes the flow dumps requested earlier than the given
+// duration ago, so the dumps never completed by the switch do not
+// accumulate.
+func (gc *FlowGC) expire(d time.Duration) {
+	deadline := clockOrSystem(gc.Clock).Now().Add(-d)
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	for xid, dump := range gc.pending {
+		if dump.sent.Before(deadline) {
+			delete(gc.pending, xid)
+		}
+	}
+}
+
+// Run sends the flow dump requests to the connection with the specified
+// interval until the context is canceled or the request fails. The first
+// request is sent immediately. The flow dumps not completed within the
+// interval are discarded.
+func (gc *FlowGC) Run(ctx context.Context, conn of.Conn, interval time.Duration) error {
+	ticker := clockOrSystem(gc.Clock).NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		gc.expire(interval)
+		if err := of.Send(conn, gc.Request()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package ofputil
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestFlowGC(t *testing.T) {
+	gc := &FlowGC{
+		Cookie:     0xab00000000000000,
+		CookieMask: 0xff00000000000000,
+		Desired: func(f *ofp.FlowStats) bool {
+			return f.Cookie == 0xab00000000000001
+		},
+	}
+
+	req := gc.Request()
+
+	newReply := func(flags ofp.MultipartReplyFlag, cookies ...uint64) *of.Request {
+		var buf bytes.Buffer
+		reply := ofp.MultipartReply{Type: ofp.MultipartTypeFlow, Flags: flags}
+		reply.WriteTo(&buf)
+
+		for _, cookie := range cookies {
+			flow := ofp.FlowStats{
+				Cookie: cookie,
+				Match:  ofp.Match{Type: ofp.MatchTypeXM},
+			}
+			flow.WriteTo(&buf)
+		}
+
+		r := of.NewRequest(of.TypeMultipartReply, &buf)
+		r.Header.Transaction = req.Header.Transaction
+		return r
+	}
+
+	// Other flow entries and the desired flow entries must be left.
+	r1 := newReply(ofp.MultipartReplyMode, 0xab00000000000001, 0xcd00000000000002)
+	r2 := newReply(0, 0xab00000000000003)
+
+	if !gc.Match(r1) {
+		t.Fatalf("Reply to the flow dump must match")
+	}
+
+	rw := ofptest.NewRecorder()
+	gc.Serve(rw, r1)
+
+	if rw.Len() != 0 {
+		t.Fatalf("Flows must be deleted after the last reply: %d", rw.Len())
+	}
+
+	gc.Serve(rw, r2)
+	if err := rw.ExpectTypes(of.TypeFlowMod); err != nil {
+		t.Fatalf("Failed to delete stale flow: %s", err)
+	}
+
+	var fmod ofp.FlowMod
+	if err := rw.Decode(0, &fmod); err != nil {
+		t.Fatalf("Failed to decode flow modification: %s", err)
+	}
+
+	if fmod.Command != ofp.FlowDeleteStrict || fmod.Cookie != 0xab00000000000003 {
+		t.Errorf("Invalid flow modification: %v", fmod)
+	}
+
+	if gc.Match(r2) {
+		t.Errorf("Finished flow dump must not match")
+	}
+}
+
+func TestFlowGCError(t *testing.T) {
+	gc := &FlowGC{}
+	req := gc.Request()
+
+	r := req.NewReply(of.TypeError, &ofp.Error{
+		Type: ofp.ErrTypeBadRequest,
+		Code: ofp.ErrCodeBadRequestBadMultipart,
+	})
+
+	if !gc.Match(r) {
+		t.Fatalf("Error of the flow dump must match")
+	}
+
+	rw := ofptest.NewRecorder()
+	gc.Serve(rw, r)
+
+	if gc.Match(r) || rw.Len() != 0 {
+		t.Errorf("Failed flow dump must be removed")
+	}
+}
+
+func TestFlowGCExpire(t *testing.T) {
+	clock := ofptest.NewClock(time.Now())
+	gc := &FlowGC{Clock: clock}
+
+	stale := gc.Request()
+	clock.Advance(time.Minute)
+	fresh := gc.Request()
+
+	clock.Advance(30 * time.Second)
+	gc.expire(time.Minute)
+
+	if gc.Match(stale.NewReply(of.TypeMultipartReply, nil)) {
+		t.Errorf("Stale flow dump must be expired")
+	}
+
+	if !gc.Match(fresh.NewReply(of.TypeMultipartReply, nil)) {
+		t.Errorf("Recent flow dump must not be expired")
+	}
+}
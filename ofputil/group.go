@@ -0,0 +1,321 @@
+package ofputil
+
+import (
+	"sort"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// GroupTable is a local cache of the groups installed into the switch.
+// It is used to order the group modification messages, so the groups
+// chained by other groups are installed first and removed last.
+//
+// For example, to install an indirect group used by the select group,
+// the following requests could be sent:
+//
+//	table := ofputil.NewGroupTable()
+//	reqs, err := table.Install(selectGroup, indirectGroup)
+//	if err != nil {
+//		// The groups were not installed.
+//	}
+//
+//	of.Send(conn, reqs...)
+type GroupTable struct {
+	// Capabilities are the probed capabilities of the switch. When
+	// defined, the groups of the types not supported by the switch
+	// are rejected with ErrNotSupported.
+	Capabilities *SwitchCapabilities
+
+	mu     sync.Mutex
+	groups map[ofp.Group]*ofp.GroupMod
+}
+
+// NewGroupTable creates a new empty group table.
+func NewGroupTable() *GroupTable {
+	return &GroupTable{groups: make(map[ofp.Group]*ofp.GroupMod)}
+}
+
+// Group returns the cached group modification message of the given
+// group identifier.
+func (t *GroupTable) Group(group ofp.Group) (*ofp.GroupMod, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	mod, ok := t.groups[group]
+	return mod, ok
+}
+
+// Install validates the given group modification messages against the
+// local cache and returns the list of requests ordered by dependencies
+// between groups. Each dependency level is followed by the barrier
+// request, thus flows referencing the installed groups could be sent
+// right after the returned requests.
+//
+// Only GroupAdd and GroupModify commands are accepted. The cache is
+// updated only when all groups are valid.
+func (t *GroupTable) Install(mods ...*ofp.GroupMod) ([]*of.Request, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := make(map[ofp.Group]*ofp.GroupMod, len(mods))
+
+	for _, mod := range mods {
+		if _, ok := pending[mod.Group]; ok {
+			return nil, groupModError(ofp.ErrCodeGroupModFailedGroupExists)
+		}
+
+		if err := t.Capabilities.CheckGroup(mod); err != nil {
+			return nil, err
+		}
+
+		_, exists := t.groups[mod.Group]
+
+		switch mod.Command {
+		case ofp.GroupAdd:
+			if exists {
+				return nil, groupModError(ofp.ErrCodeGroupModFailedGroupExists)
+			}
+		case ofp.GroupModify:
+			if !exists {
+				return nil, groupModError(ofp.ErrCodeGroupModFailedUnknownGroup)
+			}
+		default:
+			return nil, groupModError(ofp.ErrCodeGroupModBadCommand)
+		}
+
+		pending[mod.Group] = mod
+	}
+
+	// Build the view of the group table as it will look like after
+	// the installation of the pending groups.
+	view := make(map[ofp.Group]*ofp.GroupMod, len(t.groups)+len(pending))
+	for group, mod := range t.groups {
+		view[group] = mod
+	}
+	for group, mod := range pending {
+		view[group] = mod
+	}
+
+	for _, mod := range pending {
+		for _, ref := range groupRefs(mod) {
+			if _, ok := view[ref]; !ok {
+				return nil, groupModError(ofp.ErrCodeGroupModFailedUnknownGroup)
+			}
+		}
+	}
+
+	if groupLoop(view) {
+		return nil, groupModError(ofp.ErrCodeGroupModFailedLoop)
+	}
+
+	// Only the newly added groups have to be installed before the
+	// groups referencing them, modified groups already exist.
+	levels := groupLevels(pending, func(mod *ofp.GroupMod) bool {
+		return mod.Command == ofp.GroupAdd
+	})
+
+	for group, mod := range pending {
+		t.groups[group] = mod
+	}
+
+	return groupRequests(levels, func(mod *ofp.GroupMod) *ofp.GroupMod {
+		return mod
+	}), nil
+}
+
+// Remove returns the list of group delete requests ordered in reverse
+// to the dependencies between groups, so the group is deleted only after
+// all groups forwarding to it. Each dependency level is followed by the
+// barrier request.
+//
+// When the group is chained by the group that is not deleted, an error
+// with ErrCodeGroupModFailedChainedGroup code is returned, and the cache
+// is left untouched. Groups missing in the cache are ignored. GroupAll
+// removes all cached groups.
+func (t *GroupTable) Remove(groups ...ofp.Group) ([]*of.Request, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := make(map[ofp.Group]*ofp.GroupMod, len(groups))
+
+	for _, group := range groups {
+		if group == ofp.GroupAll {
+			for group, mod := range t.groups {
+				pending[group] = mod
+			}
+			continue
+		}
+
+		if mod, ok := t.groups[group]; ok {
+			pending[group] = mod
+		}
+	}
+
+	// Ensure that none of the remaining groups forwards
+	// packets to the removing groups.
+	for group, mod := range t.groups {
+		if _, ok := pending[group]; ok {
+			continue
+		}
+
+		for _, ref := range groupRefs(mod) {
+			if _, ok := pending[ref]; ok {
+				return nil, groupModError(ofp.ErrCodeGroupModFailedChainedGroup)
+			}
+		}
+	}
+
+	levels := groupLevels(pending, func(*ofp.GroupMod) bool {
+		return true
+	})
+
+	// Groups that reference others are removed first.
+	for i, j := 0, len(levels)-1; i < j; i, j = i+1, j-1 {
+		levels[i], levels[j] = levels[j], levels[i]
+	}
+
+	for group := range pending {
+		delete(t.groups, group)
+	}
+
+	return groupRequests(levels, func(mod *ofp.GroupMod) *ofp.GroupMod {
+		return &ofp.GroupMod{
+			Command: ofp.GroupDelete,
+			Type:    mod.Type,
+			Group:   mod.Group,
+		}
+	}), nil
+}
+
+// groupModError returns a group modification error with the given code.
+func groupModError(code ofp.ErrCode) error {
+	return ofp.Error{Type: ofp.ErrTypeGroupModFailed, Code: code}
+}
+
+// groupRefs returns a list of groups the given group forwards packets to.
+func groupRefs(mod *ofp.GroupMod) []ofp.Group {
+	var refs []ofp.Group
+
+	for _, bucket := range mod.Buckets {
+		for _, action := range bucket.Actions {
+			if action, ok := action.(*ofp.ActionGroup); ok {
+				refs = append(refs, action.Group)
+			}
+		}
+	}
+
+	return refs
+}
+
+// groupLoop reports whether the given group table contains a loop.
+func groupLoop(groups map[ofp.Group]*ofp.GroupMod) bool {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	state := make(map[ofp.Group]int, len(groups))
+
+	var visit func(group ofp.Group) bool
+	visit = func(group ofp.Group) bool {
+		switch state[group] {
+		case visiting:
+			return true
+		case visited:
+			return false
+		}
+
+		state[group] = visiting
+
+		if mod, ok := groups[group]; ok {
+			for _, ref := range groupRefs(mod) {
+				if visit(ref) {
+					return true
+				}
+			}
+		}
+
+		state[group] = visited
+		return false
+	}
+
+	for group := range groups {
+		if visit(group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// groupLevels splits the given groups into dependency levels: groups of
+// the first level do not depend on any other given group, groups of the
+// next level depend only on groups of the previous levels. The ordered
+// function tells whether the group should be ordered before the groups
+// referencing it. The groups must not contain loops.
+func groupLevels(groups map[ofp.Group]*ofp.GroupMod,
+	ordered func(*ofp.GroupMod) bool) [][]*ofp.GroupMod {
+
+	depth := make(map[ofp.Group]int, len(groups))
+
+	var level func(group ofp.Group) int
+	level = func(group ofp.Group) int {
+		if d, ok := depth[group]; ok {
+			return d
+		}
+
+		var d int
+		for _, ref := range groupRefs(groups[group]) {
+			mod, ok := groups[ref]
+			if !ok || !ordered(mod) {
+				continue
+			}
+
+			if l := level(ref) + 1; l > d {
+				d = l
+			}
+		}
+
+		depth[group] = d
+		return d
+	}
+
+	var levels [][]*ofp.GroupMod
+	for group, mod := range groups {
+		d := level(group)
+		for len(levels) <= d {
+			levels = append(levels, nil)
+		}
+
+		levels[d] = append(levels[d], mod)
+	}
+
+	// Keep the order of requests within the level stable.
+	for _, level := range levels {
+		sort.Slice(level, func(i, j int) bool {
+			return level[i].Group < level[j].Group
+		})
+	}
+
+	return levels
+}
+
+// groupRequests creates a list of requests from the given dependency
+// levels, each level is followed by the barrier request.
+func groupRequests(levels [][]*ofp.GroupMod,
+	fn func(*ofp.GroupMod) *ofp.GroupMod) []*of.Request {
+
+	var reqs []*of.Request
+
+	for _, level := range levels {
+		for _, mod := range level {
+			reqs = append(reqs, of.NewRequest(of.TypeGroupMod, fn(mod)))
+		}
+
+		reqs = append(reqs, of.NewRequest(of.TypeBarrierRequest, nil))
+	}
+
+	return reqs
+}
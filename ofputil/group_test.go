@@ -0,0 +1,139 @@
+package ofputil
+
+import (
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// chainedGroup returns a new group modification message with buckets
+// forwarding packets to the specified groups.
+func chainedGroup(group ofp.Group, refs ...ofp.Group) *ofp.GroupMod {
+	mod := &ofp.GroupMod{
+		Command: ofp.GroupAdd,
+		Type:    ofp.GroupTypeAll,
+		Group:   group,
+	}
+
+	for _, ref := range refs {
+		mod.Buckets = append(mod.Buckets, ofp.Bucket{
+			Actions: ofp.Actions{&ofp.ActionGroup{Group: ref}},
+		})
+	}
+
+	return mod
+}
+
+// groupOrder returns the list of group identifiers and barriers (as
+// ofp.GroupAny) of the given requests.
+func groupOrder(t *testing.T, reqs []*of.Request) []ofp.Group {
+	var groups []ofp.Group
+
+	for _, req := range reqs {
+		switch req.Header.Type {
+		case of.TypeBarrierRequest:
+			groups = append(groups, ofp.GroupAny)
+		case of.TypeGroupMod:
+			var mod ofp.GroupMod
+			if _, err := mod.ReadFrom(req.Body); err != nil {
+				t.Fatalf("Failed to read group modification: %s", err)
+			}
+			groups = append(groups, mod.Group)
+		default:
+			t.Fatalf("Unexpected request type: %s", req.Header.Type)
+		}
+	}
+
+	return groups
+}
+
+func assertGroupOrder(t *testing.T, reqs []*of.Request, groups ...ofp.Group) {
+	order := groupOrder(t, reqs)
+	if len(order) != len(groups) {
+		t.Fatalf("Invalid order of groups: %v, expected %v", order, groups)
+	}
+
+	for i := range order {
+		if order[i] != groups[i] {
+			t.Fatalf("Invalid order of groups: %v, expected %v", order, groups)
+		}
+	}
+}
+
+func assertGroupModError(t *testing.T, err error, code ofp.ErrCode) {
+	e, ok := err.(ofp.Error)
+	if !ok || e.Type != ofp.ErrTypeGroupModFailed || e.Code != code {
+		t.Fatalf("Expected group modification error %d, got: %v", code, err)
+	}
+}
+
+func TestGroupTableInstall(t *testing.T) {
+	table := NewGroupTable()
+
+	reqs, err := table.Install(
+		chainedGroup(1, 2, 3),
+		chainedGroup(3, 2),
+		chainedGroup(2),
+	)
+
+	if err != nil {
+		t.Fatalf("Failed to install groups: %s", err)
+	}
+
+	barrier := ofp.GroupAny
+	assertGroupOrder(t, reqs, 2, barrier, 3, barrier, 1, barrier)
+
+	if _, ok := table.Group(3); !ok {
+		t.Fatalf("Group is expected to be cached")
+	}
+
+	_, err = table.Install(chainedGroup(4, 5))
+	assertGroupModError(t, err, ofp.ErrCodeGroupModFailedUnknownGroup)
+
+	_, err = table.Install(chainedGroup(2))
+	assertGroupModError(t, err, ofp.ErrCodeGroupModFailedGroupExists)
+
+	mod := chainedGroup(2, 1)
+	mod.Command = ofp.GroupModify
+
+	_, err = table.Install(mod)
+	assertGroupModError(t, err, ofp.ErrCodeGroupModFailedLoop)
+
+	if _, ok := table.Group(4); ok {
+		t.Fatalf("Failed installation must not modify the cache")
+	}
+}
+
+func TestGroupTableRemove(t *testing.T) {
+	table := NewGroupTable()
+
+	_, err := table.Install(
+		chainedGroup(1, 2),
+		chainedGroup(2, 3),
+		chainedGroup(3),
+	)
+
+	if err != nil {
+		t.Fatalf("Failed to install groups: %s", err)
+	}
+
+	_, err = table.Remove(2)
+	assertGroupModError(t, err, ofp.ErrCodeGroupModFailedChainedGroup)
+
+	if _, ok := table.Group(2); !ok {
+		t.Fatalf("Failed removal must not modify the cache")
+	}
+
+	reqs, err := table.Remove(ofp.GroupAll)
+	if err != nil {
+		t.Fatalf("Failed to remove groups: %s", err)
+	}
+
+	barrier := ofp.GroupAny
+	assertGroupOrder(t, reqs, 1, barrier, 2, barrier, 3, barrier)
+
+	if _, ok := table.Group(3); ok {
+		t.Fatalf("Removed group must not be cached")
+	}
+}
@@ -1,7 +1,8 @@
 package ofputil
 
 import (
-	"log"
+	"bytes"
+	"io"
 
 	of "github.com/netrack/openflow"
 	"github.com/netrack/openflow/ofp"
@@ -22,15 +23,12 @@ func EchoHandler(h of.Handler) of.Handler {
 		_, err := req.ReadFrom(r.Body)
 		if err != nil {
 			text := "ofputil: failed to read the message: %v"
-			log.Printf(text, err)
+			Logf(r, text, err)
 			return
 		}
 
-		header := r.Header.Copy()
-		header.Type = of.TypeEchoReply
-
 		// Send a reply with the same data in body.
-		rw.Write(header, &ofp.EchoReply{req.Data})
+		r.Reply(rw, &ofp.EchoReply{Data: req.Data}, of.TypeEchoReply)
 
 		// Execute optional handler.
 		if h != nil {
@@ -48,14 +46,14 @@ func EchoHandler(h of.Handler) of.Handler {
 // in case of successful message submission.
 func HelloHandler(version uint8, h of.Handler) of.Handler {
 	fn := func(rw of.ResponseWriter, r *of.Request) {
-		// Copy the header of retrieved message,
+		// Correlate the reply with retrieved message,
 		// including the trasnaction identifier.
-		header := r.Header.Copy()
-		header.Version = version
+		reply := r.NewReply(of.TypeHello, nil)
+		reply.Header.Version = version
 
 		// Send a response to the called with a version
 		// supported version in the header.
-		rw.Write(header, nil)
+		rw.Write(&reply.Header, nil)
 
 		if h != nil {
 			h.Serve(rw, r)
@@ -64,3 +62,85 @@ func HelloHandler(version uint8, h of.Handler) of.Handler {
 
 	return of.HandlerFunc(fn)
 }
+
+// CheckModeHandler returns a request handler that attaches the check
+// mode to the request context and then calls the given handler, so
+// the requests decoded with the Decode method are validated in the
+// given mode instead of the default one set by ofp.SetCheckMode.
+//
+// For example, to certify a single switch without affecting the rest
+// of the connections:
+//
+//	handler = ofputil.CheckModeHandler(ofp.CheckStrict, handler)
+func CheckModeHandler(mode ofp.CheckMode, h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		h.Serve(rw, r.WithContext(ofp.NewCheckModeContext(r.Context(), mode)))
+	})
+}
+
+// ErrorHandler returns a request handler that decodes each error
+// message and passes it to the given function. The experimenter errors
+// are passed as *ofp.ErrorExperimenter, the rest as *ofp.Error.
+func ErrorHandler(fn func(of.ResponseWriter, *of.Request, ofp.ErrorMessage)) of.Handler {
+	h := func(rw of.ResponseWriter, r *of.Request) {
+		e, err := ofp.ReadError(r.Body)
+		if err != nil {
+			text := "ofputil: failed to read the message: %v"
+			Logf(r, text, err)
+			return
+		}
+
+		fn(rw, r, e)
+	}
+
+	return of.HandlerFunc(h)
+}
+
+// FlowUpdateHandler returns a request handler that decodes the flow
+// monitor updates from the multipart replies and passes each of them to
+// the given function. The flow updates are passed as *ofp.FlowUpdateFull,
+// *ofp.FlowUpdateAbbrev or *ofp.FlowUpdatePaused.
+//
+// The multipart replies of other types are passed to the optional
+// handler h, for example:
+//
+//	mux.Handle(of.TypeMultipartReply, ofputil.FlowUpdateHandler(
+//		func(rw of.ResponseWriter, r *of.Request, u ofp.FlowUpdate) {
+//			log.Println(u.Event())
+//		}, nil))
+func FlowUpdateHandler(fn func(of.ResponseWriter, *of.Request, ofp.FlowUpdate), h of.Handler) of.Handler {
+	f := func(rw of.ResponseWriter, r *of.Request) {
+		var buf bytes.Buffer
+		var reply ofp.MultipartReply
+
+		// Preserve the header of the multipart reply, so it could
+		// be passed to the next handler.
+		_, err := reply.ReadFrom(io.TeeReader(r.Body, &buf))
+		if err != nil {
+			text := "ofputil: failed to read the message: %v"
+			Logf(r, text, err)
+			return
+		}
+
+		if reply.Type != ofp.MultipartTypeFlowMonitor {
+			if h != nil {
+				r.Body = io.MultiReader(&buf, r.Body)
+				h.Serve(rw, r)
+			}
+			return
+		}
+
+		var updates ofp.FlowUpdates
+		if _, err = updates.ReadFrom(r.Body); err != nil {
+			text := "ofputil: failed to read the message: %v"
+			Logf(r, text, err)
+			return
+		}
+
+		for _, update := range updates {
+			fn(rw, r, update)
+		}
+	}
+
+	return of.HandlerFunc(f)
+}
@@ -1,6 +1,10 @@
 package ofputil
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
 	"testing"
 
 	of "github.com/netrack/openflow"
@@ -58,3 +62,104 @@ func TestHelloHandler(t *testing.T) {
 		t.Errorf(text, resp.Header.Transaction)
 	}
 }
+
+func TestCheckModeHandler(t *testing.T) {
+	body := []byte{
+		0x01,             // Table identifier.
+		0x00, 0x01, 0x00, // 3-byte padding.
+		0x00, 0x00, 0x00, 0x00, // Configuration.
+	}
+
+	var errs []error
+	h := of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		errs = append(errs, r.Decode(&ofp.TableMod{}))
+	})
+
+	req := of.NewRequest(of.TypeTableMod, bytes.NewBuffer(body))
+	h.Serve(nil, req)
+	CheckModeHandler(ofp.CheckStrict, h).Serve(nil, req)
+
+	if errs[0] != nil {
+		t.Errorf("Default mode must ignore padding: %v", errs[0])
+	}
+
+	if !errors.Is(errs[1], ofp.ErrPaddingNotZero) {
+		t.Errorf("Strict mode must reject padding: %v", errs[1])
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		Body io.WriterTo
+		Text string
+	}{
+		{&ofp.Error{
+			Type: ofp.ErrTypeBadMatch,
+			Code: ofp.ErrCodeBadMatchBadPrereq,
+		}, "ErrCodeBadMatchBadPrereq"},
+		{&ofp.ErrorExperimenter{
+			ExpType:      4,
+			Experimenter: 0x2320,
+		}, "ErrTypeExperimenter(0x00002320)ExpType(4)"},
+	}
+
+	for _, test := range tests {
+		var e ofp.ErrorMessage
+		h := ErrorHandler(func(rw of.ResponseWriter, r *of.Request,
+			err ofp.ErrorMessage) {
+			e = err
+		})
+
+		h.Serve(ofptest.NewRecorder(), of.NewRequest(of.TypeError, test.Body))
+
+		if reflect.TypeOf(e) != reflect.TypeOf(test.Body) {
+			t.Errorf("Invalid type of the error: %T", e)
+		}
+
+		if e.Error() != test.Text {
+			t.Errorf("Invalid error text: %s", e)
+		}
+	}
+}
+
+func TestFlowUpdateHandler(t *testing.T) {
+	var body bytes.Buffer
+
+	reply := ofp.MultipartReply{Type: ofp.MultipartTypeFlowMonitor}
+	reply.WriteTo(&body)
+
+	updates := ofp.FlowUpdates{
+		&ofp.FlowUpdateAbbrev{Transaction: 42},
+		&ofp.FlowUpdatePaused{EventType: ofp.FlowUpdateEventPaused},
+	}
+	updates.WriteTo(&body)
+
+	var events []ofp.FlowUpdateEvent
+	h := FlowUpdateHandler(func(rw of.ResponseWriter, r *of.Request,
+		u ofp.FlowUpdate) {
+		events = append(events, u.Event())
+	}, nil)
+
+	h.Serve(ofptest.NewRecorder(), of.NewRequest(of.TypeMultipartReply, &body))
+
+	if len(events) != 2 || events[0] != ofp.FlowUpdateEventAbbrev ||
+		events[1] != ofp.FlowUpdateEventPaused {
+		t.Fatalf("Invalid flow updates received: %v", events)
+	}
+
+	// Replies of other types must be passed to the next handler.
+	var next ofp.MultipartReply
+	h = FlowUpdateHandler(nil, of.HandlerFunc(func(rw of.ResponseWriter,
+		r *of.Request) {
+		next.ReadFrom(r.Body)
+	}))
+
+	body.Reset()
+	reply = ofp.MultipartReply{Type: ofp.MultipartTypePortDescription}
+	reply.WriteTo(&body)
+
+	h.Serve(ofptest.NewRecorder(), of.NewRequest(of.TypeMultipartReply, &body))
+	if next.Type != ofp.MultipartTypePortDescription {
+		t.Fatalf("Reply was not passed to the next handler: %v", next.Type)
+	}
+}
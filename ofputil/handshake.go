@@ -0,0 +1,223 @@
+package ofputil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrHandshakeTimeout is returned when the switch does not reply within
+// the timeout of the handshake phase.
+var ErrHandshakeTimeout = errors.New("ofputil: handshake timed out")
+
+// HandshakePhase is a phase of the connection handshake.
+type HandshakePhase int
+
+const (
+	// HandshakePhaseHello is a phase of waiting for the hello message.
+	HandshakePhaseHello HandshakePhase = iota
+
+	// HandshakePhaseFeatures is a phase of waiting for the features
+	// reply.
+	HandshakePhaseFeatures
+)
+
+func (p HandshakePhase) String() string {
+	text, ok := handshakePhaseText[p]
+	if !ok {
+		return fmt.Sprintf("HandshakePhase(%d)", p)
+	}
+	return text
+}
+
+var handshakePhaseText = map[HandshakePhase]string{
+	HandshakePhaseHello:    "HandshakePhaseHello",
+	HandshakePhaseFeatures: "HandshakePhaseFeatures",
+}
+
+// HandshakeAction is an action taken when the handshake phase times out.
+type HandshakeAction int
+
+const (
+	// HandshakeClose closes the connection and fails the handshake.
+	HandshakeClose HandshakeAction = iota
+
+	// HandshakeRetry sends the request of the phase again and waits
+	// for the reply within the timeout of the phase.
+	HandshakeRetry
+
+	// HandshakeLog logs the timeout and waits for the reply within the
+	// timeout of the phase again.
+	HandshakeLog
+)
+
+// Handshake performs the controller side of the connection handshake:
+// exchanges the hello messages and requests the switch features. Each
+// phase of the handshake is limited with the timeout, so the half-open
+// connections do not occupy the controller resources forever.
+//
+// For example, to retry the features request once before closing the
+// connection:
+//
+//	h := &ofputil.Handshake{
+//		HelloTimeout:    5 * time.Second,
+//		FeaturesTimeout: 5 * time.Second,
+//		Policy: func(phase ofputil.HandshakePhase, attempt int) ofputil.HandshakeAction {
+//			if phase == ofputil.HandshakePhaseFeatures && attempt == 1 {
+//				return ofputil.HandshakeRetry
+//			}
+//			return ofputil.HandshakeClose
+//		},
+//	}
+//
+//	features, err := h.Run(conn)
+type Handshake struct {
+	// Version is a version of the protocol sent in the hello message.
+	// When zero, the version of the of.NewRequest is used.
+	Version uint8
+
+	// HelloTimeout is a maximum duration of waiting for the hello
+	// message. Zero means no timeout.
+	HelloTimeout time.Duration
+
+	// FeaturesTimeout is a maximum duration of waiting for the features
+	// reply. Zero means no timeout.
+	FeaturesTimeout time.Duration
+
+	// Policy is called when the phase times out with the number of
+	// the timed out attempts of the phase, starting from one. When not
+	// defined, the connection is closed on the first timeout.
+	Policy func(phase HandshakePhase, attempt int) HandshakeAction
+}
+
+// Run performs the handshake over the given connection and returns the
+// features of the switch. The echo requests received during handshake
+// are replied, the error messages fail the handshake, the rest of the
+// messages are discarded.
+//
+// When the handshake fails because of the timeout, the connection is
+// closed and the error wrapping ErrHandshakeTimeout is returned.
+func (h *Handshake) Run(conn of.Conn) (*ofp.SwitchFeatures, error) {
+	hello := of.NewRequest(of.TypeHello, nil)
+	if h.Version != 0 {
+		hello.Header.Version = h.Version
+	}
+
+	_, err := h.phase(conn, HandshakePhaseHello, hello, h.HelloTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	req := of.NewRequest(of.TypeFeaturesRequest, nil)
+	req.Header.Version = hello.Header.Version
+
+	r, err := h.phase(conn, HandshakePhaseFeatures, req, h.FeaturesTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var features ofp.SwitchFeatures
+	if _, err = features.ReadFrom(r.Body); err != nil {
+		return nil, err
+	}
+
+	return &features, nil
+}
+
+// phase sends the request and waits for the reply of the phase.
+func (h *Handshake) phase(conn of.Conn, phase HandshakePhase,
+	req *of.Request, timeout time.Duration) (*of.Request, error) {
+
+	expected := of.TypeHello
+	if phase == HandshakePhaseFeatures {
+		expected = of.TypeFeaturesReply
+	}
+
+	if err := of.Send(conn, req); err != nil {
+		return nil, err
+	}
+
+	// Reset the deadline, so it does not affect the connection
+	// after the handshake.
+	defer conn.SetReadDeadline(time.Time{})
+
+	for attempt := 1; ; {
+		if timeout != 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		r, err := h.receive(conn, expected)
+		if err == nil {
+			return r, nil
+		}
+
+		// The message is read partially, so the stream of messages
+		// is desynchronized and the phase can't be retried.
+		if errors.Is(err, of.ErrPartialMessage) {
+			conn.Close()
+			return nil, err
+		}
+
+		if e, ok := err.(net.Error); !ok || !e.Timeout() {
+			return nil, err
+		}
+
+		action := HandshakeClose
+		if h.Policy != nil {
+			action = h.Policy(phase, attempt)
+		}
+
+		switch action {
+		case HandshakeRetry:
+			if err = of.Send(conn, req); err != nil {
+				return nil, err
+			}
+		case HandshakeLog:
+			log.Printf("ofputil: %s of %s timed out, attempt %d",
+				phase, conn.RemoteAddr(), attempt)
+		default:
+			conn.Close()
+			return nil, fmt.Errorf("%w: %s", ErrHandshakeTimeout, phase)
+		}
+
+		attempt++
+	}
+}
+
+// receive reads the messages from the connection until the message of
+// the expected type is received.
+func (h *Handshake) receive(conn of.Conn, t of.Type) (*of.Request, error) {
+	for {
+		r, err := conn.Receive()
+		if err != nil {
+			return nil, err
+		}
+
+		switch r.Header.Type {
+		case t:
+			return r, nil
+		case of.TypeEchoRequest:
+			data, err := r.RawBody()
+			if err != nil {
+				return nil, err
+			}
+
+			reply := r.NewReply(of.TypeEchoReply, bytes.NewReader(data))
+			if err = of.Send(conn, reply); err != nil {
+				return nil, err
+			}
+		case of.TypeError:
+			e, err := ofp.ReadError(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			return nil, e
+		}
+	}
+}
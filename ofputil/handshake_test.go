@@ -0,0 +1,110 @@
+package ofputil
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestHandshake(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	var phases []HandshakePhase
+	h := &Handshake{
+		HelloTimeout:    time.Second,
+		FeaturesTimeout: 50 * time.Millisecond,
+		Policy: func(phase HandshakePhase, attempt int) HandshakeAction {
+			phases = append(phases, phase)
+			return HandshakeRetry
+		},
+	}
+
+	// Switch replies only to the second features request.
+	go func() {
+		conn := of.NewConn(server)
+		defer conn.Close()
+
+		var features int
+		for {
+			r, err := conn.Receive()
+			if err != nil {
+				return
+			}
+
+			switch r.Header.Type {
+			case of.TypeHello:
+				of.Send(conn, r.NewReply(of.TypeHello, nil),
+					of.NewRequest(of.TypeEchoRequest, nil))
+			case of.TypeFeaturesRequest:
+				if features++; features == 2 {
+					of.Send(conn, r.NewReply(of.TypeFeaturesReply,
+						&ofp.SwitchFeatures{DatapathID: 42}))
+				}
+			}
+		}
+	}()
+
+	features, err := h.Run(of.NewConn(client))
+	if err != nil {
+		t.Fatalf("Failed to perform handshake: %s", err)
+	}
+
+	if features.DatapathID != 42 {
+		t.Errorf("Invalid datapath identifier: %s", features.DatapathID)
+	}
+
+	if len(phases) != 1 || phases[0] != HandshakePhaseFeatures {
+		t.Errorf("Features phase must time out once: %v", phases)
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	// Drain the hello message, but never reply.
+	go of.NewConn(server).Receive()
+
+	h := &Handshake{HelloTimeout: 10 * time.Millisecond}
+	_, err := h.Run(of.NewConn(client))
+
+	if !errors.Is(err, ErrHandshakeTimeout) {
+		t.Fatalf("Handshake timeout error expected: %v", err)
+	}
+
+	if _, err = client.Write([]byte{0}); err == nil {
+		t.Errorf("Connection must be closed")
+	}
+}
+
+func TestHandshakePartialMessage(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	// Write only the part of the hello message header.
+	go func() {
+		of.NewConn(server).Receive()
+		server.Write([]byte{4, byte(of.TypeHello)})
+	}()
+
+	h := &Handshake{
+		HelloTimeout: 50 * time.Millisecond,
+		Policy: func(HandshakePhase, int) HandshakeAction {
+			return HandshakeLog
+		},
+	}
+
+	_, err := h.Run(of.NewConn(client))
+	if !errors.Is(err, of.ErrPartialMessage) {
+		t.Fatalf("Partial message error expected: %v", err)
+	}
+
+	if _, err = client.Write([]byte{0}); err == nil {
+		t.Errorf("Connection must be closed")
+	}
+}
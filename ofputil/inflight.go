@@ -0,0 +1,311 @@
+package ofputil
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// replyTypes maps the types of the requests to the types of the replies
+// sent by the switch in response.
+var replyTypes = map[of.Type]of.Type{
+	of.TypeEchoRequest:           of.TypeEchoReply,
+	of.TypeFeaturesRequest:       of.TypeFeaturesReply,
+	of.TypeGetConfigRequest:      of.TypeGetConfigReply,
+	of.TypeMultipartRequest:      of.TypeMultipartReply,
+	of.TypeBarrierRequest:        of.TypeBarrierReply,
+	of.TypeQueueGetConfigRequest: of.TypeQueueGetConfigReply,
+	of.TypeRoleRequest:           of.TypeRoleReply,
+	of.TypeGetAsyncRequest:       of.TypeGetAsyncReply,
+}
+
+// InflightRequest describes the request sent to the switch, that is
+// waiting for the reply.
+type InflightRequest struct {
+	// Conn is a connection the request was sent to.
+	Conn of.Conn
+
+	// Transaction is a transaction identifier of the request.
+	Transaction uint32
+
+	// Type is a type of the request.
+	Type of.Type
+
+	// Sent is a time the request was sent.
+	Sent time.Time
+
+	// Age is a time elapsed since the request was sent.
+	Age time.Duration
+
+	// Awaiting is a matcher of the reply the request is waiting for.
+	Awaiting of.Matcher
+}
+
+// inflightEntry is a request waiting for the reply.
+type inflightEntry struct {
+	t        of.Type
+	sent     time.Time
+	awaiting of.Matcher
+}
+
+// Inflight tracks the requests waiting for the replies from switches,
+// indexed by the connection and transaction identifier. It could be used
+// to diagnose the controller waiting for the reply, that never arrives.
+//
+// The requests sent through the InflightConn are tracked automatically,
+// the replies are untracked by the handler returned from Handler method.
+// The error replies complete the request of the same transaction. The
+// requests of the closed connections are removed by the ConnState hook
+// of the server. Once the hook is installed, only the requests sent to
+// the connections accepted by the server are tracked, so the requests
+// sent by the handlers completed after the connection is closed are
+// not kept forever.
+//
+// For example, to publish the in-flight requests on the "/debug/vars"
+// HTTP endpoint:
+//
+//	inflight := ofputil.NewInflight()
+//	expvar.Publish("inflight", inflight.Var())
+//
+//	srv := &of.Server{
+//		Addr:      ":6633",
+//		Handler:   inflight.Handler(mux),
+//		ConnState: of.ConnStateHooks(inflight.ConnState, epochs.ConnState),
+//	}
+type Inflight struct {
+	// Clock is used to measure the age of the requests. When not
+	// defined, the system clock is used.
+	Clock of.Clock
+
+	mu    sync.Mutex
+	conns map[of.Conn]map[uint32]*inflightEntry
+	open  connSet
+}
+
+// NewInflight creates a new empty tracker of in-flight requests.
+func NewInflight() *Inflight {
+	return &Inflight{conns: make(map[of.Conn]map[uint32]*inflightEntry)}
+}
+
+// Track starts tracking the request sent to the connection, that is
+// waiting for the reply matching the given matcher. The requests of the
+// closed connections are not tracked.
+func (f *Inflight) Track(conn of.Conn, r *of.Request, awaiting of.Matcher) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.open.isOpen(conn) {
+		return
+	}
+
+	entries, ok := f.conns[conn]
+	if !ok {
+		entries = make(map[uint32]*inflightEntry)
+		f.conns[conn] = entries
+	}
+
+	entries[r.Header.Transaction] = &inflightEntry{
+		t:        r.Header.Type,
+		sent:     clockOrSystem(f.Clock).Now(),
+		awaiting: awaiting,
+	}
+}
+
+// Done stops tracking the request of the given transaction identifier.
+// Returns false when the request is not tracked.
+func (f *Inflight) Done(conn of.Conn, xid uint32) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries := f.conns[conn]
+	if _, ok := entries[xid]; !ok {
+		return false
+	}
+
+	delete(entries, xid)
+	if len(entries) == 0 {
+		delete(f.conns, conn)
+	}
+
+	return true
+}
+
+// Remove stops tracking the requests of the closed connection.
+func (f *Inflight) Remove(conn of.Conn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.conns, conn)
+}
+
+// ConnState drops the requests of the connections closed by the server,
+// since their replies never arrive.
+func (f *Inflight) ConnState(conn of.Conn, state of.ConnState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.open.update(conn, state)
+	if state == of.StateClosed {
+		delete(f.conns, conn)
+	}
+}
+
+// complete reports whether the reply completes the in-flight request.
+// The multipart replies complete the request with the last reply.
+func (f *Inflight) complete(r *of.Request) bool {
+	f.mu.Lock()
+	entry, ok := f.conns[r.Conn()][r.Header.Transaction]
+	f.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	switch {
+	case r.Header.Type == of.TypeError:
+		return true
+	case entry.awaiting != nil && !entry.awaiting.Match(r):
+		return false
+	case r.Header.Type != of.TypeMultipartReply:
+		return true
+	}
+
+	var reply ofp.MultipartReply
+	return r.Decode(&reply) != nil || reply.Flags&ofp.MultipartReplyMode == 0
+}
+
+// Handler returns a handler, that stops tracking the requests completed
+// by the replies received from the connections, and then calls the
+// given handler.
+func (f *Inflight) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if f.complete(r) {
+			f.Done(r.Conn(), r.Header.Transaction)
+		}
+
+		h.Serve(rw, r)
+	})
+}
+
+// Requests returns the snapshot of the in-flight requests of all
+// connections ordered from the oldest to the newest.
+func (f *Inflight) Requests() []InflightRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := clockOrSystem(f.Clock).Now()
+
+	var reqs []InflightRequest
+	for conn, entries := range f.conns {
+		for xid, entry := range entries {
+			reqs = append(reqs, InflightRequest{
+				Conn:        conn,
+				Transaction: xid,
+				Type:        entry.t,
+				Sent:        entry.sent,
+				Age:         now.Sub(entry.sent),
+				Awaiting:    entry.awaiting,
+			})
+		}
+	}
+
+	sort.Slice(reqs, func(i, j int) bool {
+		if !reqs[i].Sent.Equal(reqs[j].Sent) {
+			return reqs[i].Sent.Before(reqs[j].Sent)
+		}
+		return reqs[i].Transaction < reqs[j].Transaction
+	})
+
+	return reqs
+}
+
+// matcherString returns the human-readable description of the matcher.
+func matcherString(m of.Matcher) string {
+	switch m := m.(type) {
+	case nil:
+		return ""
+	case of.TypeMatcher:
+		return of.Type(m).String()
+	case fmt.Stringer:
+		return m.String()
+	}
+	return fmt.Sprintf("%T", m)
+}
+
+// Var returns the variable exposing the in-flight requests through the
+// expvar package. The requests are grouped by the remote address of
+// the connection.
+func (f *Inflight) Var() expvar.Var {
+	return expvar.Func(func() interface{} {
+		conns := make(map[string][]map[string]interface{})
+
+		for _, r := range f.Requests() {
+			var addr string
+			if remote := r.Conn.RemoteAddr(); remote != nil {
+				addr = remote.String()
+			}
+
+			conns[addr] = append(conns[addr], map[string]interface{}{
+				"xid":      fmt.Sprintf("0x%x", r.Transaction),
+				"type":     r.Type.String(),
+				"age":      r.Age.String(),
+				"awaiting": matcherString(r.Awaiting),
+			})
+		}
+
+		return conns
+	})
+}
+
+// InflightConn is a connection tracking the sent requests, that expect
+// the reply from the switch, like echo, barrier and multipart requests.
+type InflightConn struct {
+	of.Conn
+
+	// Key is a connection the requests are tracked for. It must be
+	// the connection the replies are received from, as returned by
+	// the Conn method of the request served by the handler. When nil,
+	// the wrapped connection is used, so the Key must be set when the
+	// wrapped connection is a wrapper itself, like RoleConn.
+	Key of.Conn
+
+	inflight *Inflight
+}
+
+// NewInflightConn creates a new connection tracking the requests with
+// the given tracker.
+func NewInflightConn(c of.Conn, inflight *Inflight) *InflightConn {
+	return &InflightConn{Conn: c, inflight: inflight}
+}
+
+// key returns the connection the requests are tracked for.
+func (c *InflightConn) key() of.Conn {
+	if c.Key != nil {
+		return c.Key
+	}
+	return c.Conn
+}
+
+// Send sends the request to the underlying connection. The request is
+// tracked until the reply is received, when the type of the request
+// expects the reply.
+func (c *InflightConn) Send(r *of.Request) error {
+	reply, ok := replyTypes[r.Header.Type]
+	if !ok {
+		return c.Conn.Send(r)
+	}
+
+	c.inflight.Track(c.key(), r, of.TypeMatcher(reply))
+
+	err := c.Conn.Send(r)
+	if err != nil {
+		c.inflight.Done(c.key(), r.Header.Transaction)
+	}
+
+	return err
+}
@@ -0,0 +1,146 @@
+package ofputil
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestInflight(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sw := of.NewConn(server)
+	defer sw.Close()
+
+	go func() {
+		for {
+			if _, err := sw.Receive(); err != nil {
+				return
+			}
+		}
+	}()
+
+	inflight := NewInflight()
+	conn := of.NewConn(client)
+	ic := NewInflightConn(conn, inflight)
+
+	newRequest := func(t of.Type, xid uint32, body io.WriterTo) *of.Request {
+		r := of.NewRequest(t, body)
+		r.Header.Transaction = xid
+		return r
+	}
+
+	reqs := []*of.Request{
+		newRequest(of.TypeEchoRequest, 1, nil),
+		newRequest(of.TypeMultipartRequest, 2, &ofp.MultipartRequest{
+			Type: ofp.MultipartTypePortDescription,
+		}),
+		newRequest(of.TypeFlowMod, 3, nil),
+		newRequest(of.TypeBarrierRequest, 4, nil),
+	}
+
+	for _, r := range reqs {
+		if err := of.Send(ic, r); err != nil {
+			t.Fatalf("Failed to send request: %s", err)
+		}
+	}
+
+	pending := inflight.Requests()
+	if len(pending) != 3 {
+		t.Fatalf("Three requests expected in flight: %v", pending)
+	}
+
+	if pending[0].Transaction != 1 || pending[0].Type != of.TypeEchoRequest ||
+		pending[0].Awaiting != of.TypeMatcher(of.TypeEchoReply) {
+		t.Errorf("Invalid in-flight request: %+v", pending[0])
+	}
+
+	if s := inflight.Var().String(); !strings.Contains(s, "TypeMultipartReply") {
+		t.Errorf("Awaited reply type expected: %s", s)
+	}
+
+	replies := []*of.Request{
+		newRequest(of.TypeMultipartReply, 2, &ofp.MultipartReply{
+			Type:  ofp.MultipartTypePortDescription,
+			Flags: ofp.MultipartReplyMode,
+		}),
+		newRequest(of.TypeEchoReply, 1, nil),
+		// The reply of unexpected type must be ignored.
+		newRequest(of.TypeEchoReply, 4, nil),
+	}
+
+	handler := inflight.Handler(of.HandlerFunc(func(of.ResponseWriter, *of.Request) {}))
+
+	serve := func(reply *of.Request) {
+		go of.Send(sw, reply)
+
+		r, err := conn.Receive()
+		if err != nil {
+			t.Fatalf("Failed to receive reply: %s", err)
+		}
+
+		handler.Serve(nil, r)
+	}
+
+	for _, reply := range replies {
+		serve(reply)
+	}
+
+	pending = inflight.Requests()
+	if len(pending) != 2 || pending[0].Transaction != 2 || pending[1].Transaction != 4 {
+		t.Fatalf("Invalid in-flight requests: %v", pending)
+	}
+
+	serve(newRequest(of.TypeMultipartReply, 2, &ofp.MultipartReply{
+		Type: ofp.MultipartTypePortDescription,
+	}))
+	serve(newRequest(of.TypeError, 4, nil))
+
+	if pending = inflight.Requests(); len(pending) != 0 {
+		t.Fatalf("Requests must be completed: %v", pending)
+	}
+}
+
+func TestInflightConnKey(t *testing.T) {
+	rec := ofptest.NewConnRecorder()
+	inflight := NewInflight()
+
+	// The requests are sent through the wrapper of the connection,
+	// but tracked for the connection the replies are received from.
+	ic := NewInflightConn(struct{ of.Conn }{rec}, inflight)
+	ic.Key = rec
+
+	if err := of.Send(ic, of.NewRequest(of.TypeEchoRequest, nil)); err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	pending := inflight.Requests()
+	if len(pending) != 1 || pending[0].Conn != rec {
+		t.Fatalf("Request must be tracked for the key: %v", pending)
+	}
+
+	inflight.ConnState(rec, of.StateClosed)
+	if pending = inflight.Requests(); len(pending) != 0 {
+		t.Fatalf("Requests of closed connection must be removed: %v", pending)
+	}
+}
+
+func TestInflightClosed(t *testing.T) {
+	inflight := NewInflight()
+	h := of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		conn := &InflightConn{Conn: ofptest.NewConnRecorder(), Key: r.Conn()}
+		conn.inflight = inflight
+		conn.Send(of.NewRequest(of.TypeBarrierRequest, nil))
+	})
+
+	serveAfterClose(t, of.NewRequest(of.TypePacketIn, nil), h, inflight.ConnState)
+	if reqs := inflight.Requests(); len(reqs) != 0 {
+		t.Errorf("Requests of the closed connection must not be tracked: %v", reqs)
+	}
+}
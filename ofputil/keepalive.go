@@ -0,0 +1,138 @@
+package ofputil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrKeepaliveTimeout is returned by the keepalive connection, when no
+// messages were received from the switch within the timeout.
+var ErrKeepaliveTimeout = errors.New("ofputil: keepalive timeout")
+
+// KeepaliveConn is a connection, that probes the liveness of the switch
+// with the echo requests. Any message received from the connection
+// confirms the liveness, so the echo requests are sent only to probe
+// the idle connections.
+//
+// The messages must be received from the connection in the separate
+// goroutine, for example:
+//
+//	conn := ofputil.NewKeepaliveConn(conn, 5*time.Second, 15*time.Second)
+//	go func() {
+//		if err := conn.Run(ctx); err != nil {
+//			conn.Close()
+//		}
+//	}()
+//
+//	for {
+//		r, err := conn.Receive()
+//		// ...
+//	}
+//
+// The liveness is recorded only by the Receive of the keepalive
+// connection. When the messages are received by the of.Server, that
+// reads the underlying connection, the liveness is recorded by the
+// handler of the keepalive connection:
+//
+//	srv := &of.Server{Handler: conn.Handler(mux)}
+type KeepaliveConn struct {
+	of.Conn
+
+	// Interval is a period of the liveness check.
+	Interval time.Duration
+
+	// Timeout is a maximum time without the messages from the switch,
+	// after which the connection is considered dead.
+	Timeout time.Duration
+
+	// Clock is used to schedule the echo requests. When not defined,
+	// the system clock is used.
+	Clock of.Clock
+
+	mu   sync.Mutex
+	seen time.Time
+}
+
+// NewKeepaliveConn creates a new keepalive connection with the given
+// interval and timeout.
+func NewKeepaliveConn(c of.Conn, interval, timeout time.Duration) *KeepaliveConn {
+	return &KeepaliveConn{Conn: c, Interval: interval, Timeout: timeout}
+}
+
+// Receive receives the next request from the underlying connection
+// and records the liveness of the switch.
+func (c *KeepaliveConn) Receive() (*of.Request, error) {
+	r, err := c.Conn.Receive()
+	if err == nil {
+		c.touch()
+	}
+
+	return r, err
+}
+
+// Handler returns a handler, that records the liveness of the switch
+// on each request received from the connection and then calls the given
+// handler. The requests of the other connections are passed as is.
+func (c *KeepaliveConn) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if conn := r.Conn(); conn == c.Conn || conn == of.Conn(c) {
+			c.touch()
+		}
+
+		h.Serve(rw, r)
+	})
+}
+
+// touch records the time of the last received message.
+func (c *KeepaliveConn) touch() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen = clockOrSystem(c.Clock).Now()
+}
+
+// Seen returns the time the last message was received.
+func (c *KeepaliveConn) Seen() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seen
+}
+
+// Run checks the liveness of the switch each interval until the context
+// is canceled. The echo request is sent, when no messages were received
+// during the last interval. ErrKeepaliveTimeout is returned, when no
+// messages were received within the timeout.
+func (c *KeepaliveConn) Run(ctx context.Context) error {
+	clock := clockOrSystem(c.Clock)
+	ticker := clock.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	// The liveness is measured from the start of the check.
+	c.touch()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+
+		idle := clock.Now().Sub(c.Seen())
+		if idle >= c.Timeout {
+			return ErrKeepaliveTimeout
+		}
+
+		if idle < c.Interval {
+			continue
+		}
+
+		echo := of.NewRequest(of.TypeEchoRequest, &ofp.EchoRequest{})
+		if err := of.Send(c.Conn, echo); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ofputil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestKeepaliveConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sw := of.NewConn(server)
+	defer sw.Close()
+
+	// Receive the messages of the controller in the background,
+	// since the pipe blocks the writes until they are read.
+	received := make(chan of.Type, 8)
+	go func() {
+		for {
+			r, err := sw.Receive()
+			if err != nil {
+				return
+			}
+			received <- r.Header.Type
+		}
+	}()
+
+	clock := ofptest.NewClock(time.Unix(0, 0))
+	conn := NewKeepaliveConn(of.NewConn(client), 5*time.Second, 15*time.Second)
+	conn.Clock = clock
+
+	errc := make(chan error, 1)
+	go func() { errc <- conn.Run(context.Background()) }()
+
+	expectEcho := func() {
+		if typ := <-received; typ != of.TypeEchoRequest {
+			t.Fatalf("Echo request expected: %s", typ)
+		}
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Second)
+	expectEcho()
+
+	go of.Send(sw, of.NewRequest(of.TypeEchoReply, &ofp.EchoReply{}))
+	if _, err := conn.Receive(); err != nil {
+		t.Fatalf("Failed to receive echo reply: %s", err)
+	}
+
+	if !conn.Seen().Equal(time.Unix(5, 0)) {
+		t.Fatalf("Invalid time of the last message: %s", conn.Seen())
+	}
+
+	// The switch does not reply to the echo requests anymore.
+	clock.Advance(5 * time.Second)
+	expectEcho()
+
+	clock.Advance(5 * time.Second)
+	expectEcho()
+
+	clock.Advance(5 * time.Second)
+	if err := <-errc; !errors.Is(err, ErrKeepaliveTimeout) {
+		t.Fatalf("Keepalive timeout expected: %v", err)
+	}
+}
+
+func TestKeepaliveConnHandler(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sw := of.NewConn(server)
+	defer sw.Close()
+
+	clock := ofptest.NewClock(time.Unix(0, 0))
+	conn := NewKeepaliveConn(of.NewConn(client), 5*time.Second, 15*time.Second)
+	conn.Clock = clock
+
+	var served int
+	h := conn.Handler(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		served++
+	}))
+
+	// The server reads the underlying connection, so the liveness is
+	// recorded only by the handler.
+	go of.Send(sw, of.NewRequest(of.TypeEchoReply, &ofp.EchoReply{}))
+	r, err := conn.Conn.Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive echo reply: %s", err)
+	}
+
+	clock.Advance(5 * time.Second)
+	h.Serve(ofptest.NewRecorder(), r)
+
+	if served != 1 || !conn.Seen().Equal(time.Unix(5, 0)) {
+		t.Fatalf("Liveness must be recorded by the handler: %s", conn.Seen())
+	}
+
+	// The requests of the other connections do not confirm liveness.
+	clock.Advance(5 * time.Second)
+	h.Serve(ofptest.NewRecorder(), of.NewRequest(of.TypeEchoReply, nil))
+
+	if served != 2 || !conn.Seen().Equal(time.Unix(5, 0)) {
+		t.Errorf("Liveness must not be recorded for other connections")
+	}
+}
@@ -0,0 +1,344 @@
+package ofputil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrLinkMode is returned when the link settings could not be expressed
+// with the port features or are not supported by the port.
+var ErrLinkMode = errors.New("ofputil: unsupported link mode")
+
+// LinkSpeed is a bitrate of the link in Kbps, the same units are used
+// for the current and maximum speed of the port.
+type LinkSpeed uint32
+
+// Speeds of the links defined by the port features.
+const (
+	LinkSpeed10Mb  LinkSpeed = 10 * 1000
+	LinkSpeed100Mb LinkSpeed = 100 * 1000
+	LinkSpeed1Gb   LinkSpeed = 1000 * 1000
+	LinkSpeed10Gb  LinkSpeed = 10 * 1000 * 1000
+	LinkSpeed40Gb  LinkSpeed = 40 * 1000 * 1000
+	LinkSpeed100Gb LinkSpeed = 100 * 1000 * 1000
+	LinkSpeed1Tb   LinkSpeed = 1000 * 1000 * 1000
+)
+
+// Duplex is a duplex mode of the link.
+type Duplex int
+
+const (
+	// DuplexFull is a full-duplex mode.
+	DuplexFull Duplex = iota
+
+	// DuplexHalf is a half-duplex mode.
+	DuplexHalf
+)
+
+func (d Duplex) String() string {
+	text, ok := duplexText[d]
+	if !ok {
+		return fmt.Sprintf("Duplex(%d)", d)
+	}
+	return text
+}
+
+var duplexText = map[Duplex]string{
+	DuplexFull: "DuplexFull",
+	DuplexHalf: "DuplexHalf",
+}
+
+// Medium is a physical medium of the link.
+type Medium int
+
+const (
+	// MediumAny does not restrict the medium of the link.
+	MediumAny Medium = iota
+
+	// MediumCopper is a copper medium.
+	MediumCopper
+
+	// MediumFiber is a fiber medium.
+	MediumFiber
+)
+
+func (m Medium) String() string {
+	text, ok := mediumText[m]
+	if !ok {
+		return fmt.Sprintf("Medium(%d)", m)
+	}
+	return text
+}
+
+var mediumText = map[Medium]string{
+	MediumAny:    "MediumAny",
+	MediumCopper: "MediumCopper",
+	MediumFiber:  "MediumFiber",
+}
+
+// mediumFeatures maps the media to the port features.
+var mediumFeatures = map[Medium]ofp.PortFeature{
+	MediumCopper: ofp.PortFeatureCopper,
+	MediumFiber:  ofp.PortFeatureFiber,
+}
+
+// LinkMode is a speed and a duplex mode of the link.
+type LinkMode struct {
+	Speed  LinkSpeed
+	Duplex Duplex
+}
+
+// String returns a human-readable representation of the link mode.
+func (m LinkMode) String() string {
+	return fmt.Sprintf("%d Kbps %s", m.Speed, m.Duplex)
+}
+
+// linkModes maps the link modes to the port features, the modes are
+// ordered by the speed and then by the duplex mode.
+var linkModes = []struct {
+	mode    LinkMode
+	feature ofp.PortFeature
+}{
+	{LinkMode{LinkSpeed10Mb, DuplexHalf}, ofp.PortFeature10MbitHalfDuplex},
+	{LinkMode{LinkSpeed10Mb, DuplexFull}, ofp.PortFeature10MbitFullDuplex},
+	{LinkMode{LinkSpeed100Mb, DuplexHalf}, ofp.PortFeature100MbitHalfDuplex},
+	{LinkMode{LinkSpeed100Mb, DuplexFull}, ofp.PortFeature100MbitFullDuplex},
+	{LinkMode{LinkSpeed1Gb, DuplexHalf}, ofp.PortFeature1GbitHalfDuplex},
+	{LinkMode{LinkSpeed1Gb, DuplexFull}, ofp.PortFeature1GbitFullDuplex},
+	{LinkMode{LinkSpeed10Gb, DuplexFull}, ofp.PortFeature10GbitFullDuplex},
+	{LinkMode{LinkSpeed40Gb, DuplexFull}, ofp.PortFeature40GbitFullDuplex},
+	{LinkMode{LinkSpeed100Gb, DuplexFull}, ofp.PortFeature100GbitFullDuplex},
+	{LinkMode{LinkSpeed1Tb, DuplexFull}, ofp.PortFeature1TbitFullDuplex},
+}
+
+// linkModeFeature returns the port feature of the link mode.
+func linkModeFeature(mode LinkMode) (ofp.PortFeature, bool) {
+	for _, m := range linkModes {
+		if m.mode == mode {
+			return m.feature, true
+		}
+	}
+
+	return 0, false
+}
+
+// LinkSettings are the desired settings of the link advertised by the
+// port to the peer.
+//
+// For example, to limit the port to 1 Gbps full-duplex over copper
+// with the autonegotiation:
+//
+//	settings := ofputil.LinkSettings{
+//		Modes:   []ofputil.LinkMode{{ofputil.LinkSpeed1Gb, ofputil.DuplexFull}},
+//		Medium:  ofputil.MediumCopper,
+//		Autoneg: true,
+//	}
+//
+//	pmod, err := ofputil.PortAdvertise(port, settings)
+type LinkSettings struct {
+	// Modes are the advertised speeds and duplex modes.
+	Modes []LinkMode
+
+	// Medium is the advertised medium of the link.
+	Medium Medium
+
+	// Autoneg enables the autonegotiation.
+	Autoneg bool
+
+	// Pause enables the symmetric pause frames.
+	Pause bool
+
+	// PauseAsym enables the asymmetric pause frames.
+	PauseAsym bool
+}
+
+// Advertise returns the bitmap of the port features, that advertises
+// the link settings. ErrLinkMode is returned when the link mode is not
+// defined by the port features, like 10 Gbps half-duplex.
+func (s *LinkSettings) Advertise() (ofp.PortFeature, error) {
+	var features ofp.PortFeature
+
+	for _, mode := range s.Modes {
+		feature, ok := linkModeFeature(mode)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrLinkMode, mode)
+		}
+
+		features |= feature
+	}
+
+	medium, ok := mediumFeatures[s.Medium]
+	if !ok && s.Medium != MediumAny {
+		return 0, fmt.Errorf("%w: %s", ErrLinkMode, s.Medium)
+	}
+
+	features |= medium
+
+	flags := []struct {
+		enabled bool
+		feature ofp.PortFeature
+	}{
+		{s.Autoneg, ofp.PortFeatureAutoneg},
+		{s.Pause, ofp.PortFeaturePause},
+		{s.PauseAsym, ofp.PortFeaturePauseAsym},
+	}
+
+	for _, flag := range flags {
+		if flag.enabled {
+			features |= flag.feature
+		}
+	}
+
+	return features, nil
+}
+
+// PortAdvertise returns the port modification, that changes the features
+// advertised by the port to the link settings. The configuration of the
+// port is left unchanged. ErrLinkMode is returned when the settings are
+// not supported by the port.
+func PortAdvertise(port *ofp.Port, s LinkSettings) (*ofp.PortMod, error) {
+	advertise, err := s.Advertise()
+	if err != nil {
+		return nil, err
+	}
+
+	// Zero advertise bitmap prevents any action taking place.
+	if advertise == 0 {
+		return nil, fmt.Errorf("%w: no features to advertise", ErrLinkMode)
+	}
+
+	// The settings are validated only when the port reports the
+	// supported features.
+	if port.Supported != 0 && advertise&^port.Supported != 0 {
+		return nil, fmt.Errorf("%w: %s on port %d", ErrLinkMode,
+			advertise&^port.Supported, port.PortNo)
+	}
+
+	return &ofp.PortMod{
+		PortNo:    port.PortNo,
+		HWAddr:    port.HWAddr,
+		Advertise: advertise,
+	}, nil
+}
+
+// LinkCapabilities are the link settings described by the bitmap of
+// the port features.
+type LinkCapabilities struct {
+	// Modes are the speeds and duplex modes ordered by the speed and
+	// then by the duplex mode, the half-duplex goes first.
+	Modes []LinkMode
+
+	// Other is set when the rate is not listed in the port features.
+	Other bool
+
+	// Copper is set for the copper medium.
+	Copper bool
+
+	// Fiber is set for the fiber medium.
+	Fiber bool
+
+	// Autoneg is set when the autonegotiation is supported.
+	Autoneg bool
+
+	// Pause is set when the symmetric pause frames are supported.
+	Pause bool
+
+	// PauseAsym is set when the asymmetric pause frames are supported.
+	PauseAsym bool
+}
+
+// ParseLinkCapabilities interprets the bitmap of the port features.
+func ParseLinkCapabilities(features ofp.PortFeature) LinkCapabilities {
+	c := LinkCapabilities{
+		Other:     features&ofp.PortFeatureOther != 0,
+		Copper:    features&ofp.PortFeatureCopper != 0,
+		Fiber:     features&ofp.PortFeatureFiber != 0,
+		Autoneg:   features&ofp.PortFeatureAutoneg != 0,
+		Pause:     features&ofp.PortFeaturePause != 0,
+		PauseAsym: features&ofp.PortFeaturePauseAsym != 0,
+	}
+
+	for _, m := range linkModes {
+		if features&m.feature != 0 {
+			c.Modes = append(c.Modes, m.mode)
+		}
+	}
+
+	return c
+}
+
+// Supports returns true when the link mode is listed.
+func (c *LinkCapabilities) Supports(mode LinkMode) bool {
+	for _, m := range c.Modes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Best returns the fastest link mode, the full-duplex is preferred. The
+// second value is false when no modes are listed.
+func (c *LinkCapabilities) Best() (LinkMode, bool) {
+	if len(c.Modes) == 0 {
+		return LinkMode{}, false
+	}
+
+	return c.Modes[len(c.Modes)-1], true
+}
+
+// PortLink describes the link of the port.
+type PortLink struct {
+	// Current are the current features of the port.
+	Current LinkCapabilities
+
+	// Advertised are the features advertised by the port.
+	Advertised LinkCapabilities
+
+	// Supported are the features supported by the port.
+	Supported LinkCapabilities
+
+	// Peer are the features advertised by the peer.
+	Peer LinkCapabilities
+
+	// CurrSpeed is a current bitrate of the port.
+	CurrSpeed LinkSpeed
+
+	// MaxSpeed is a maximum bitrate of the port.
+	MaxSpeed LinkSpeed
+}
+
+// NewPortLink interprets the features of the port description.
+func NewPortLink(port *ofp.Port) *PortLink {
+	return &PortLink{
+		Current:    ParseLinkCapabilities(port.Curr),
+		Advertised: ParseLinkCapabilities(port.Advertised),
+		Supported:  ParseLinkCapabilities(port.Supported),
+		Peer:       ParseLinkCapabilities(port.Peer),
+		CurrSpeed:  LinkSpeed(port.CurrSpeed),
+		MaxSpeed:   LinkSpeed(port.MaxSpeed),
+	}
+}
+
+// Negotiated returns the fastest link mode advertised by both the port
+// and the peer, the mode expected to be negotiated by the
+// autonegotiation. The second value is false when there is no common
+// mode.
+func (l *PortLink) Negotiated() (LinkMode, bool) {
+	var common []LinkMode
+	for _, mode := range l.Advertised.Modes {
+		if l.Peer.Supports(mode) {
+			common = append(common, mode)
+		}
+	}
+
+	// The modes are ordered, so the last common mode is the fastest.
+	if len(common) == 0 {
+		return LinkMode{}, false
+	}
+
+	return common[len(common)-1], true
+}
@@ -0,0 +1,105 @@
+package ofputil
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestLinkSettingsAdvertise(t *testing.T) {
+	settings := LinkSettings{
+		Modes: []LinkMode{
+			{LinkSpeed1Gb, DuplexFull},
+			{LinkSpeed100Mb, DuplexHalf},
+		},
+		Medium:  MediumCopper,
+		Autoneg: true,
+	}
+
+	features, err := settings.Advertise()
+	if err != nil {
+		t.Fatalf("Failed to convert link settings: %s", err)
+	}
+
+	want := ofp.PortFeature1GbitFullDuplex | ofp.PortFeature100MbitHalfDuplex |
+		ofp.PortFeatureCopper | ofp.PortFeatureAutoneg
+
+	if features != want {
+		t.Errorf("Invalid advertised features: %s", features)
+	}
+
+	// The half-duplex is not defined for the 10 Gbps links.
+	settings.Modes = []LinkMode{{LinkSpeed10Gb, DuplexHalf}}
+	if _, err = settings.Advertise(); !errors.Is(err, ErrLinkMode) {
+		t.Errorf("Undefined link mode must be rejected: %v", err)
+	}
+}
+
+func TestPortAdvertise(t *testing.T) {
+	port := &ofp.Port{
+		PortNo:    3,
+		HWAddr:    net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		Supported: ofp.PortFeature1GbitFullDuplex | ofp.PortFeature10GbitFullDuplex | ofp.PortFeatureFiber,
+	}
+
+	settings := LinkSettings{
+		Modes:  []LinkMode{{LinkSpeed10Gb, DuplexFull}},
+		Medium: MediumFiber,
+	}
+
+	pmod, err := PortAdvertise(port, settings)
+	if err != nil {
+		t.Fatalf("Failed to create port modification: %s", err)
+	}
+
+	if pmod.PortNo != 3 || pmod.Mask != 0 || !reflect.DeepEqual(pmod.HWAddr, port.HWAddr) {
+		t.Errorf("Invalid port modification: %v", pmod)
+	}
+
+	if pmod.Advertise != ofp.PortFeature10GbitFullDuplex|ofp.PortFeatureFiber {
+		t.Errorf("Invalid advertised features: %s", pmod.Advertise)
+	}
+
+	settings.Autoneg = true
+	if _, err = PortAdvertise(port, settings); !errors.Is(err, ErrLinkMode) {
+		t.Errorf("Unsupported feature must be rejected: %v", err)
+	}
+
+	if _, err = PortAdvertise(port, LinkSettings{}); !errors.Is(err, ErrLinkMode) {
+		t.Errorf("Empty settings must be rejected: %v", err)
+	}
+}
+
+func TestPortLink(t *testing.T) {
+	port := &ofp.Port{
+		Curr:       ofp.PortFeature1GbitFullDuplex | ofp.PortFeatureCopper,
+		Advertised: ofp.PortFeature100MbitFullDuplex | ofp.PortFeature1GbitHalfDuplex | ofp.PortFeature1GbitFullDuplex,
+		Peer:       ofp.PortFeature100MbitFullDuplex | ofp.PortFeature1GbitHalfDuplex | ofp.PortFeaturePause,
+		CurrSpeed:  1000000,
+	}
+
+	link := NewPortLink(port)
+	if !link.Current.Copper || link.Current.Fiber || link.CurrSpeed != LinkSpeed1Gb {
+		t.Errorf("Invalid current link: %v", link.Current)
+	}
+
+	if best, ok := link.Advertised.Best(); !ok || best != (LinkMode{LinkSpeed1Gb, DuplexFull}) {
+		t.Errorf("Invalid best advertised mode: %v", best)
+	}
+
+	mode, ok := link.Negotiated()
+	if !ok || mode != (LinkMode{LinkSpeed1Gb, DuplexHalf}) {
+		t.Errorf("Invalid negotiated mode: %v", mode)
+	}
+
+	if !link.Peer.Pause || link.Peer.Supports(LinkMode{LinkSpeed1Gb, DuplexFull}) {
+		t.Errorf("Invalid peer capabilities: %v", link.Peer)
+	}
+
+	if _, ok = NewPortLink(&ofp.Port{}).Negotiated(); ok {
+		t.Errorf("No mode must be negotiated without the features")
+	}
+}
@@ -0,0 +1,156 @@
+package ofputil
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// Correlation identifies the request/response exchange in the logs, so
+// the log lines of the same exchange could be correlated.
+type Correlation struct {
+	// DatapathID is a datapath identifier of the switch. It is zero
+	// until the features reply is received from the connection.
+	DatapathID ofp.DatapathID
+
+	// Transaction is a transaction identifier of the request.
+	Transaction uint32
+}
+
+// String returns the correlation identifiers in the key=value format,
+// the unknown datapath identifier is omitted.
+func (c Correlation) String() string {
+	if c.DatapathID == 0 {
+		return fmt.Sprintf("xid=0x%x", c.Transaction)
+	}
+	return fmt.Sprintf("dpid=%s xid=0x%x", c.DatapathID, c.Transaction)
+}
+
+// correlationKey is a key of the correlation in the request context.
+type correlationKey struct{}
+
+// NewCorrelationContext returns a copy of the parent context with the
+// given correlation attached.
+func NewCorrelationContext(ctx context.Context, c Correlation) context.Context {
+	return context.WithValue(ctx, correlationKey{}, c)
+}
+
+// CorrelationFromContext returns the correlation attached to the
+// context, the false is returned when the context carries none.
+func CorrelationFromContext(ctx context.Context) (Correlation, bool) {
+	c, ok := ctx.Value(correlationKey{}).(Correlation)
+	return c, ok
+}
+
+// RequestCorrelation returns the correlation of the request. When the
+// request context carries no correlation, only the transaction
+// identifier from the request header is returned.
+func RequestCorrelation(r *of.Request) Correlation {
+	if c, ok := CorrelationFromContext(r.Context()); ok {
+		return c
+	}
+	return Correlation{Transaction: r.Header.Transaction}
+}
+
+// Logf logs the message with the standard logger followed by the
+// correlation identifiers of the request, for example:
+//
+//	ofputil.Logf(r, "failed to install flow: %v", err)
+//	// failed to install flow: table is full (dpid=00:00:00:00:00:00:00:01 xid=0x2a)
+//
+// The datapath identifier is logged only for the requests served by the
+// handler wrapped with the Correlator, for the rest of the requests only
+// the transaction identifier is logged.
+func Logf(r *of.Request, format string, v ...interface{}) {
+	log.Printf("%s (%s)", fmt.Sprintf(format, v...), RequestCorrelation(r))
+}
+
+// Correlator attaches the correlation identifiers to the contexts of
+// the requests. The datapath identifiers are learned from the features
+// replies received from the connections.
+//
+// For example, to attach the identifiers to all requests and forget the
+// datapath identifiers of the closed connections:
+//
+//	corr := ofputil.NewCorrelator()
+//	srv := &of.Server{
+//		Addr:      ":6633",
+//		Handler:   corr.Handler(mux),
+//		ConnState: of.ConnStateHooks(corr.ConnState, stats.ConnState),
+//	}
+//
+// When the ConnState hook is installed, the datapath identifiers are
+// learned only from the connections still open, the features replies
+// handled after the connection is closed are ignored.
+//
+// And then use them in the handlers:
+//
+//	c, _ := ofputil.CorrelationFromContext(r.Context())
+//	logger.Info("packet-in", "dpid", c.DatapathID, "xid", c.Transaction)
+type Correlator struct {
+	mu        sync.RWMutex
+	datapaths map[of.Conn]ofp.DatapathID
+	conns     connSet
+}
+
+// NewCorrelator creates a new correlator.
+func NewCorrelator() *Correlator {
+	return &Correlator{datapaths: make(map[of.Conn]ofp.DatapathID)}
+}
+
+// DatapathID returns the datapath identifier learned from the
+// connection.
+func (c *Correlator) DatapathID(conn of.Conn) (ofp.DatapathID, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	id, ok := c.datapaths[conn]
+	return id, ok
+}
+
+// Remove removes the datapath identifier of the closed connection.
+func (c *Correlator) Remove(conn of.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.datapaths, conn)
+}
+
+// ConnState forgets the datapath identifier of the connection, once the
+// server reports it closed.
+func (c *Correlator) ConnState(conn of.Conn, state of.ConnState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conns.update(conn, state)
+	if state == of.StateClosed {
+		delete(c.datapaths, conn)
+	}
+}
+
+// Handler returns a handler, that attaches the correlation identifiers
+// to the request context and then calls the given handler. The body of
+// the features reply is not consumed.
+func (c *Correlator) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if r.Header.Type == of.TypeFeaturesReply {
+			var features ofp.SwitchFeatures
+			if r.Decode(&features) == nil {
+				c.mu.Lock()
+				if c.conns.isOpen(r.Conn()) {
+					c.datapaths[r.Conn()] = features.DatapathID
+				}
+				c.mu.Unlock()
+			}
+		}
+
+		corr := Correlation{Transaction: r.Header.Transaction}
+		corr.DatapathID, _ = c.DatapathID(r.Conn())
+
+		h.Serve(rw, r.WithContext(NewCorrelationContext(r.Context(), corr)))
+	})
+}
@@ -0,0 +1,101 @@
+package ofputil
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestCorrelator(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	features := of.NewRequest(of.TypeFeaturesReply,
+		&ofp.SwitchFeatures{DatapathID: 0x2a})
+	features.Header.Transaction = 1
+
+	packet := of.NewRequest(of.TypePacketIn, nil)
+	packet.Header.Transaction = 2
+
+	go of.Send(of.NewConn(c2), features, packet)
+
+	conn := of.NewConn(c1)
+	corr := NewCorrelator()
+
+	var correlations []Correlation
+	h := corr.Handler(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		c, ok := CorrelationFromContext(r.Context())
+		if !ok {
+			t.Fatalf("Correlation is not attached to the request")
+		}
+		correlations = append(correlations, c)
+	}))
+
+	for i := 0; i < 2; i++ {
+		r, err := conn.Receive()
+		if err != nil {
+			t.Fatalf("Failed to receive request: %s", err)
+		}
+		h.Serve(ofptest.NewRecorder(), r)
+	}
+
+	expected := []Correlation{{0x2a, 1}, {0x2a, 2}}
+	for i, c := range correlations {
+		if c != expected[i] {
+			t.Errorf("Invalid correlation of request %d: %v", i, c)
+		}
+	}
+
+	corr.ConnState(conn, of.StateClosed)
+	if _, ok := corr.DatapathID(conn); ok {
+		t.Errorf("Datapath identifier must be removed")
+	}
+}
+
+func TestCorrelatorClosed(t *testing.T) {
+	corr := NewCorrelator()
+
+	var conn of.Conn
+	h := corr.Handler(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		conn = r.Conn()
+	}))
+
+	features := of.NewRequest(of.TypeFeaturesReply,
+		&ofp.SwitchFeatures{DatapathID: 0x2a})
+
+	serveAfterClose(t, features, h, corr.ConnState)
+	if _, ok := corr.DatapathID(conn); ok {
+		t.Errorf("Datapath of the closed connection must not be learned")
+	}
+}
+
+func TestLogf(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	r := of.NewRequest(of.TypeEchoRequest, nil)
+	r.Header.Transaction = 0x2a
+
+	Logf(r, "failed: %d", 1)
+	if !strings.HasSuffix(buf.String(), "failed: 1 (xid=0x2a)\n") {
+		t.Errorf("Invalid log line: %q", buf.String())
+	}
+
+	buf.Reset()
+	r = r.WithContext(NewCorrelationContext(r.Context(),
+		Correlation{DatapathID: 1, Transaction: 0x2b}))
+
+	Logf(r, "failed")
+	if !strings.HasSuffix(buf.String(), "failed (dpid=00:00:00:00:00:00:00:01 xid=0x2b)\n") {
+		t.Errorf("Invalid log line: %q", buf.String())
+	}
+}
@@ -2,7 +2,9 @@ package ofputil
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net"
 
 	"github.com/netrack/openflow/internal/encoding"
 	"github.com/netrack/openflow/ofp"
@@ -21,7 +23,7 @@ func bytesOf(v interface{}) []byte {
 }
 
 func ExtendedMatch(xms ...ofp.XM) ofp.Match {
-	return ofp.Match{ofp.MatchTypeXM, xms}
+	return ofp.Match{Type: ofp.MatchTypeXM, Fields: xms}
 }
 
 // basic creates an Openflow basic extensible match of the given type.
@@ -60,3 +62,374 @@ func MatchICMPv6Type(icmpt uint8) ofp.XM {
 func MatchIPv6ExtHeader(header uint16) ofp.XM {
 	return basic(ofp.XMTypeIPv6ExtHeader, bytesOf(header), nil)
 }
+
+// MatchIPv6ExtHeaderFlags creates an Openflow basic extensible match of
+// IPv6 extension header pseudo-field with exactly the given headers
+// present, for example:
+//
+//	MatchIPv6ExtHeaderFlags(ofp.IPv6ExtensionHeaderHop | ofp.IPv6ExtensionHeaderFrag)
+func MatchIPv6ExtHeaderFlags(headers ofp.IPv6ExtensionHeader) ofp.XM {
+	headers &= ofp.IPv6ExtensionHeaderAll
+	return basic(ofp.XMTypeIPv6ExtHeader, bytesOf(headers), nil)
+}
+
+// MatchIPv6ExtHeaderMasked creates an Openflow basic extensible match of
+// IPv6 extension header pseudo-field, where only the bits set in the
+// mask are compared. For example, to match the fragmented packets
+// regardless of the other extension headers:
+//
+//	MatchIPv6ExtHeaderMasked(ofp.IPv6ExtensionHeaderFrag, ofp.IPv6ExtensionHeaderFrag)
+func MatchIPv6ExtHeaderMasked(headers, mask ofp.IPv6ExtensionHeader) ofp.XM {
+	mask &= ofp.IPv6ExtensionHeaderAll
+	return basic(ofp.XMTypeIPv6ExtHeader,
+		bytesOf(headers&mask), bytesOf(mask))
+}
+
+var (
+	// ErrDSCPRange is returned when the DSCP value does not fit into
+	// the 6 bits of the IP header.
+	ErrDSCPRange = errors.New("ofputil: DSCP value exceeds 6 bits")
+
+	// ErrECNRange is returned when the ECN value does not fit into the
+	// 2 bits of the IP header.
+	ErrECNRange = errors.New("ofputil: ECN value exceeds 2 bits")
+)
+
+const (
+	// maxDSCP is a maximum value of the differentiated services code
+	// point.
+	maxDSCP = 1<<6 - 1
+
+	// maxECN is a maximum value of the explicit congestion notification.
+	maxECN = 1<<2 - 1
+)
+
+// MatchIPDSCP creates an Openflow basic extensible match of the IP
+// differentiated services code point. The value is the 6 upper bits of
+// the IPv4 ToS or IPv6 traffic class field, ErrDSCPRange is returned
+// when it does not fit into 6 bits.
+func MatchIPDSCP(dscp uint8) (ofp.XM, error) {
+	if dscp > maxDSCP {
+		return ofp.XM{}, ErrDSCPRange
+	}
+
+	return basic(ofp.XMTypeIPDSCP, bytesOf(dscp), nil), nil
+}
+
+// MatchIPECN creates an Openflow basic extensible match of the IP
+// explicit congestion notification. ErrECNRange is returned when the
+// value does not fit into 2 bits.
+func MatchIPECN(ecn uint8) (ofp.XM, error) {
+	if ecn > maxECN {
+		return ofp.XM{}, ErrECNRange
+	}
+
+	return basic(ofp.XMTypeIPECN, bytesOf(ecn), nil), nil
+}
+
+// MatchTunnelID creates an Openflow basic extensible match of the
+// metadata associated with a logical port, e.g. VXLAN network
+// identifier.
+func MatchTunnelID(id uint64) ofp.XM {
+	return basic(ofp.XMTypeTunnelID, bytesOf(id), nil)
+}
+
+// MatchTunnelIDMasked creates an Openflow basic extensible match of the
+// logical port metadata with the given bitmask.
+func MatchTunnelIDMasked(id, mask uint64) ofp.XM {
+	return basic(ofp.XMTypeTunnelID, bytesOf(id), bytesOf(mask))
+}
+
+// SetField returns a set-field action rewriting the header field with
+// the value of the given match. The match must not be masked.
+func SetField(xm ofp.XM) *ofp.ActionSetField {
+	return &ofp.ActionSetField{Field: xm}
+}
+
+// SetIPDSCP returns a set-field action rewriting the IP differentiated
+// services code point. ErrDSCPRange is returned when the value does not
+// fit into 6 bits.
+func SetIPDSCP(dscp uint8) (*ofp.ActionSetField, error) {
+	xm, err := MatchIPDSCP(dscp)
+	if err != nil {
+		return nil, err
+	}
+
+	return SetField(xm), nil
+}
+
+// SetIPECN returns a set-field action rewriting the IP explicit
+// congestion notification. ErrECNRange is returned when the value does
+// not fit into 2 bits.
+func SetIPECN(ecn uint8) (*ofp.ActionSetField, error) {
+	xm, err := MatchIPECN(ecn)
+	if err != nil {
+		return nil, err
+	}
+
+	return SetField(xm), nil
+}
+
+// SetTunnelID returns a set-field action setting the metadata of the
+// logical port, used by the encapsulating ports, e.g. to select VXLAN
+// network identifier.
+func SetTunnelID(id uint64) *ofp.ActionSetField {
+	return SetField(MatchTunnelID(id))
+}
+
+var (
+	// ErrIPv4Addr is returned when the address is not an IPv4 address.
+	ErrIPv4Addr = errors.New("ofputil: not an IPv4 address")
+
+	// ErrIPv6Addr is returned when the address is not an IPv6 address.
+	ErrIPv6Addr = errors.New("ofputil: not an IPv6 address")
+
+	// ErrHardwareAddr is returned when the hardware address is not an
+	// 48-bit Ethernet address.
+	ErrHardwareAddr = errors.New("ofputil: not an Ethernet address")
+
+	// ErrVlanIDRange is returned when the VLAN identifier does not fit
+	// into the 12 bits of the VLAN tag.
+	ErrVlanIDRange = errors.New("ofputil: VLAN identifier exceeds 12 bits")
+
+	// ErrVlanPCPRange is returned when the VLAN priority does not fit
+	// into the 3 bits of the VLAN tag.
+	ErrVlanPCPRange = errors.New("ofputil: VLAN priority exceeds 3 bits")
+
+	// ErrMPLSLabelRange is returned when the MPLS label does not fit
+	// into the 20 bits of the MPLS shim header.
+	ErrMPLSLabelRange = errors.New("ofputil: MPLS label exceeds 20 bits")
+)
+
+const (
+	// maxVlanID is a maximum value of the VLAN identifier.
+	maxVlanID = 1<<12 - 1
+
+	// maxVlanPCP is a maximum value of the VLAN priority.
+	maxVlanPCP = 1<<3 - 1
+
+	// maxMPLSLabel is a maximum value of the MPLS label.
+	maxMPLSLabel = 1<<20 - 1
+)
+
+// ipv4 returns the 4-byte representation of the IPv4 address.
+func ipv4(ip net.IP) (ofp.XMValue, error) {
+	if ip = ip.To4(); ip == nil {
+		return nil, ErrIPv4Addr
+	}
+
+	return ofp.XMValue(ip), nil
+}
+
+// ipv6 returns the 16-byte representation of the IPv6 address, the IPv4
+// addresses are rejected.
+func ipv6(ip net.IP) (ofp.XMValue, error) {
+	if len(ip) != net.IPv6len || ip.To4() != nil {
+		return nil, ErrIPv6Addr
+	}
+
+	return ofp.XMValue(ip), nil
+}
+
+// matchIPv4 creates a match of the IPv4 address of the given type.
+func matchIPv4(t ofp.XMType, ip net.IP) (ofp.XM, error) {
+	value, err := ipv4(ip)
+	if err != nil {
+		return ofp.XM{}, err
+	}
+
+	return basic(t, value, nil), nil
+}
+
+// matchIPv4Net creates a match of the IPv4 network of the given type.
+// The mask is omitted for the networks of a single address.
+func matchIPv4Net(t ofp.XMType, n *net.IPNet) (ofp.XM, error) {
+	value, err := ipv4(n.IP.Mask(n.Mask))
+	if err != nil {
+		return ofp.XM{}, err
+	}
+
+	ones, bits := n.Mask.Size()
+	if bits != net.IPv4len*8 {
+		return ofp.XM{}, ErrIPv4Addr
+	}
+
+	if ones == bits {
+		return basic(t, value, nil), nil
+	}
+
+	return basic(t, value, ofp.XMValue(n.Mask)), nil
+}
+
+// matchIPv6 creates a match of the IPv6 address of the given type.
+func matchIPv6(t ofp.XMType, ip net.IP) (ofp.XM, error) {
+	value, err := ipv6(ip)
+	if err != nil {
+		return ofp.XM{}, err
+	}
+
+	return basic(t, value, nil), nil
+}
+
+// matchEth creates a match of the Ethernet address of the given type.
+func matchEth(t ofp.XMType, mac net.HardwareAddr) (ofp.XM, error) {
+	if len(mac) != 6 {
+		return ofp.XM{}, ErrHardwareAddr
+	}
+
+	return basic(t, ofp.XMValue(mac), nil), nil
+}
+
+// MatchIPv4Src creates an Openflow basic extensible match of the IPv4
+// source address. ErrIPv4Addr is returned for non-IPv4 addresses.
+func MatchIPv4Src(ip net.IP) (ofp.XM, error) {
+	return matchIPv4(ofp.XMTypeIPv4Src, ip)
+}
+
+// MatchIPv4Dst creates an Openflow basic extensible match of the IPv4
+// destination address. ErrIPv4Addr is returned for non-IPv4 addresses.
+func MatchIPv4Dst(ip net.IP) (ofp.XM, error) {
+	return matchIPv4(ofp.XMTypeIPv4Dst, ip)
+}
+
+// MatchIPv4SrcNet creates an Openflow basic extensible match of the
+// IPv4 source network, for example:
+//
+//	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+//	xm, err := ofputil.MatchIPv4SrcNet(n)
+func MatchIPv4SrcNet(n *net.IPNet) (ofp.XM, error) {
+	return matchIPv4Net(ofp.XMTypeIPv4Src, n)
+}
+
+// MatchIPv4DstNet creates an Openflow basic extensible match of the
+// IPv4 destination network.
+func MatchIPv4DstNet(n *net.IPNet) (ofp.XM, error) {
+	return matchIPv4Net(ofp.XMTypeIPv4Dst, n)
+}
+
+// MatchIPv6Src creates an Openflow basic extensible match of the IPv6
+// source address. ErrIPv6Addr is returned for non-IPv6 addresses.
+func MatchIPv6Src(ip net.IP) (ofp.XM, error) {
+	return matchIPv6(ofp.XMTypeIPv6Src, ip)
+}
+
+// MatchIPv6Dst creates an Openflow basic extensible match of the IPv6
+// destination address. ErrIPv6Addr is returned for non-IPv6 addresses.
+func MatchIPv6Dst(ip net.IP) (ofp.XM, error) {
+	return matchIPv6(ofp.XMTypeIPv6Dst, ip)
+}
+
+// MatchEthSrc creates an Openflow basic extensible match of the Ethernet
+// source address. ErrHardwareAddr is returned for non-Ethernet addresses.
+func MatchEthSrc(mac net.HardwareAddr) (ofp.XM, error) {
+	return matchEth(ofp.XMTypeEthSrc, mac)
+}
+
+// MatchEthDst creates an Openflow basic extensible match of the Ethernet
+// destination address. ErrHardwareAddr is returned for non-Ethernet
+// addresses.
+func MatchEthDst(mac net.HardwareAddr) (ofp.XM, error) {
+	return matchEth(ofp.XMTypeEthDst, mac)
+}
+
+// MatchVlanVID creates an Openflow basic extensible match of the packets
+// tagged with the given VLAN identifier. ErrVlanIDRange is returned when
+// the identifier does not fit into 12 bits.
+func MatchVlanVID(vid uint16) (ofp.XM, error) {
+	if vid > maxVlanID {
+		return ofp.XM{}, ErrVlanIDRange
+	}
+
+	value := ofp.VlanPresent | ofp.VlanID(vid)
+	return basic(ofp.XMTypeVlanID, bytesOf(value), nil), nil
+}
+
+// MatchVlanPCP creates an Openflow basic extensible match of the VLAN
+// priority code point. ErrVlanPCPRange is returned when the priority
+// does not fit into 3 bits.
+func MatchVlanPCP(pcp uint8) (ofp.XM, error) {
+	if pcp > maxVlanPCP {
+		return ofp.XM{}, ErrVlanPCPRange
+	}
+
+	return basic(ofp.XMTypeVlanPCP, bytesOf(pcp), nil), nil
+}
+
+// MatchMPLSLabel creates an Openflow basic extensible match of the MPLS
+// label. ErrMPLSLabelRange is returned when the label does not fit into
+// 20 bits.
+func MatchMPLSLabel(label uint32) (ofp.XM, error) {
+	if label > maxMPLSLabel {
+		return ofp.XM{}, ErrMPLSLabelRange
+	}
+
+	return basic(ofp.XMTypeMPLSLabel, bytesOf(label), nil), nil
+}
+
+// setField returns a set-field action of the given match, or the error
+// when the match cannot be created.
+func setField(xm ofp.XM, err error) (*ofp.ActionSetField, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return SetField(xm), nil
+}
+
+// SetIPv4Src returns a set-field action rewriting the IPv4 source
+// address. ErrIPv4Addr is returned for non-IPv4 addresses.
+func SetIPv4Src(ip net.IP) (*ofp.ActionSetField, error) {
+	return setField(MatchIPv4Src(ip))
+}
+
+// SetIPv4Dst returns a set-field action rewriting the IPv4 destination
+// address. ErrIPv4Addr is returned for non-IPv4 addresses.
+func SetIPv4Dst(ip net.IP) (*ofp.ActionSetField, error) {
+	return setField(MatchIPv4Dst(ip))
+}
+
+// SetIPv6Src returns a set-field action rewriting the IPv6 source
+// address. ErrIPv6Addr is returned for non-IPv6 addresses.
+func SetIPv6Src(ip net.IP) (*ofp.ActionSetField, error) {
+	return setField(MatchIPv6Src(ip))
+}
+
+// SetIPv6Dst returns a set-field action rewriting the IPv6 destination
+// address. ErrIPv6Addr is returned for non-IPv6 addresses.
+func SetIPv6Dst(ip net.IP) (*ofp.ActionSetField, error) {
+	return setField(MatchIPv6Dst(ip))
+}
+
+// SetEthSrc returns a set-field action rewriting the Ethernet source
+// address. ErrHardwareAddr is returned for non-Ethernet addresses.
+func SetEthSrc(mac net.HardwareAddr) (*ofp.ActionSetField, error) {
+	return setField(MatchEthSrc(mac))
+}
+
+// SetEthDst returns a set-field action rewriting the Ethernet
+// destination address. ErrHardwareAddr is returned for non-Ethernet
+// addresses.
+func SetEthDst(mac net.HardwareAddr) (*ofp.ActionSetField, error) {
+	return setField(MatchEthDst(mac))
+}
+
+// SetVlanVID returns a set-field action rewriting the VLAN identifier
+// of the outermost tag. ErrVlanIDRange is returned when the identifier
+// does not fit into 12 bits.
+func SetVlanVID(vid uint16) (*ofp.ActionSetField, error) {
+	return setField(MatchVlanVID(vid))
+}
+
+// SetVlanPCP returns a set-field action rewriting the priority code point
+// of the outermost VLAN tag. ErrVlanPCPRange is returned when the priority
+// does not fit into 3 bits.
+func SetVlanPCP(pcp uint8) (*ofp.ActionSetField, error) {
+	return setField(MatchVlanPCP(pcp))
+}
+
+// SetMPLSLabel returns a set-field action rewriting the label of the
+// outermost MPLS shim header. ErrMPLSLabelRange is returned when the
+// label does not fit into 20 bits.
+func SetMPLSLabel(label uint32) (*ofp.ActionSetField, error) {
+	return setField(MatchMPLSLabel(label))
+}
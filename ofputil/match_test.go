@@ -0,0 +1,149 @@
+package ofputil
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestMatchIPDSCP(t *testing.T) {
+	xm, err := MatchIPDSCP(46)
+	if err != nil {
+		t.Fatalf("Failed to create DSCP match: %s", err)
+	}
+
+	if xm.Type != ofp.XMTypeIPDSCP || !bytes.Equal(xm.Value, []byte{46}) {
+		t.Errorf("Invalid DSCP match: %v", xm)
+	}
+
+	if _, err = MatchIPDSCP(64); err != ErrDSCPRange {
+		t.Errorf("DSCP range error expected: %v", err)
+	}
+
+	if _, err = SetIPDSCP(64); err != ErrDSCPRange {
+		t.Errorf("DSCP range error expected: %v", err)
+	}
+}
+
+func TestMatchIPECN(t *testing.T) {
+	action, err := SetIPECN(3)
+	if err != nil {
+		t.Fatalf("Failed to create ECN action: %s", err)
+	}
+
+	if action.Field.Type != ofp.XMTypeIPECN {
+		t.Errorf("Invalid type of the field: %s", action.Field.Type)
+	}
+
+	if _, err = MatchIPECN(4); err != ErrECNRange {
+		t.Errorf("ECN range error expected: %v", err)
+	}
+}
+
+func TestMatchTunnelID(t *testing.T) {
+	xm := MatchTunnelIDMasked(0x1234, 0xffff)
+	if !bytes.Equal(xm.Mask, []byte{0, 0, 0, 0, 0, 0, 0xff, 0xff}) {
+		t.Errorf("Invalid mask of the tunnel match: %v", xm.Mask)
+	}
+
+	action := SetTunnelID(0x1234)
+	if !bytes.Equal(action.Field.Value, []byte{0, 0, 0, 0, 0, 0, 0x12, 0x34}) {
+		t.Errorf("Invalid value of the tunnel field: %v", action.Field.Value)
+	}
+}
+
+func TestMatchIPv6ExtHeader(t *testing.T) {
+	xm := MatchIPv6ExtHeaderMasked(ofp.IPv6ExtensionHeaderFrag|0x8000,
+		ofp.IPv6ExtensionHeaderFrag|ofp.IPv6ExtensionHeaderHop|0x8000)
+
+	if !bytes.Equal(xm.Value, []byte{0x00, 0x10}) {
+		t.Errorf("Invalid value of the extension header: %v", xm.Value)
+	}
+
+	if !bytes.Equal(xm.Mask, []byte{0x00, 0x50}) {
+		t.Errorf("Invalid mask of the extension header: %v", xm.Mask)
+	}
+
+	xm = MatchIPv6ExtHeaderFlags(ofp.IPv6ExtensionHeaderNoNext)
+	if xm.Mask != nil || !bytes.Equal(xm.Value, []byte{0x00, 0x01}) {
+		t.Errorf("Invalid extension header match: %v", xm)
+	}
+}
+
+func TestSetFieldBuilders(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("10.0.0.0/8")
+	hostnet := &net.IPNet{IP: net.IPv4(10, 0, 0, 1), Mask: net.CIDRMask(32, 32)}
+	mac := net.HardwareAddr{0, 1, 2, 3, 4, 5}
+
+	tests := []struct {
+		Build func() (ofp.XM, error)
+		Type  ofp.XMType
+		Value ofp.XMValue
+		Mask  ofp.XMValue
+	}{
+		{func() (ofp.XM, error) { return MatchIPv4Src(net.IPv4(10, 0, 0, 1)) },
+			ofp.XMTypeIPv4Src, ofp.XMValue{10, 0, 0, 1}, nil},
+		{func() (ofp.XM, error) { return MatchIPv4DstNet(ipnet) },
+			ofp.XMTypeIPv4Dst, ofp.XMValue{10, 0, 0, 0},
+			ofp.XMValue{0xff, 0, 0, 0}},
+		{func() (ofp.XM, error) { return MatchIPv4SrcNet(hostnet) },
+			ofp.XMTypeIPv4Src, ofp.XMValue{10, 0, 0, 1}, nil},
+		{func() (ofp.XM, error) { return MatchIPv6Dst(net.ParseIP("fe80::1")) },
+			ofp.XMTypeIPv6Dst, ofp.XMValue(net.ParseIP("fe80::1")), nil},
+		{func() (ofp.XM, error) { return MatchEthDst(mac) },
+			ofp.XMTypeEthDst, ofp.XMValue(mac), nil},
+		{func() (ofp.XM, error) { return MatchVlanVID(10) },
+			ofp.XMTypeVlanID, ofp.XMValue{0x10, 0x0a}, nil},
+		{func() (ofp.XM, error) { return MatchVlanPCP(5) },
+			ofp.XMTypeVlanPCP, ofp.XMValue{0x05}, nil},
+		{func() (ofp.XM, error) { return MatchMPLSLabel(0xfffff) },
+			ofp.XMTypeMPLSLabel, ofp.XMValue{0x00, 0x0f, 0xff, 0xff}, nil},
+	}
+
+	for _, test := range tests {
+		xm, err := test.Build()
+		if err != nil {
+			t.Fatalf("Failed to create %s match: %s", test.Type, err)
+		}
+
+		if xm.Type != test.Type || !bytes.Equal(xm.Value, test.Value) ||
+			!bytes.Equal(xm.Mask, test.Mask) {
+			t.Errorf("Invalid %s match: %v", test.Type, xm)
+		}
+	}
+
+	action, err := SetIPv4Src(net.ParseIP("192.168.0.1"))
+	if err != nil {
+		t.Fatalf("Failed to create set-field action: %s", err)
+	}
+
+	if !bytes.Equal(action.Field.Value, []byte{192, 168, 0, 1}) {
+		t.Errorf("Invalid IPv4 address: %v", action.Field.Value)
+	}
+
+	if _, err = SetIPv4Dst(net.ParseIP("::1")); err != ErrIPv4Addr {
+		t.Errorf("IPv4 address error expected: %v", err)
+	}
+
+	if _, err = SetIPv6Src(net.IPv4(10, 0, 0, 1)); err != ErrIPv6Addr {
+		t.Errorf("IPv6 address error expected: %v", err)
+	}
+
+	if _, err = SetEthSrc(mac[:4]); err != ErrHardwareAddr {
+		t.Errorf("Hardware address error expected: %v", err)
+	}
+
+	if _, err = SetVlanVID(4096); err != ErrVlanIDRange {
+		t.Errorf("VLAN identifier range error expected: %v", err)
+	}
+
+	if _, err = SetVlanPCP(8); err != ErrVlanPCPRange {
+		t.Errorf("VLAN priority range error expected: %v", err)
+	}
+
+	if _, err = SetMPLSLabel(1 << 20); err != ErrMPLSLabelRange {
+		t.Errorf("MPLS label range error expected: %v", err)
+	}
+}
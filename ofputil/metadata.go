@@ -0,0 +1,154 @@
+package ofputil
+
+import (
+	"context"
+	"sync"
+
+	of "github.com/netrack/openflow"
+)
+
+// Metadata is a set of user-defined key/value attributes of the
+// connection, like the site, rack or owner of the switch.
+type Metadata map[string]string
+
+// Get returns the value of the given key, an empty string is returned
+// when the key is not present.
+func (m Metadata) Get(key string) string {
+	return m[key]
+}
+
+// clone returns a copy of the metadata.
+func (m Metadata) clone() Metadata {
+	md := make(Metadata, len(m))
+	for k, v := range m {
+		md[k] = v
+	}
+	return md
+}
+
+// metadataKey is a key of the metadata in the request context.
+type metadataKey struct{}
+
+// NewMetadataContext returns a copy of the parent context with the
+// given metadata attached.
+func NewMetadataContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext returns the metadata attached to the context,
+// the false is returned when the context carries no metadata.
+func MetadataFromContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(metadataKey{}).(Metadata)
+	return md, ok
+}
+
+// MetadataRegistry stores the metadata of the connections, so the
+// multi-tenant controllers could make decisions based on the attributes
+// of the switch without maintaining the side tables.
+//
+// The metadata is usually assigned once the switch is identified, for
+// example, on the features reply:
+//
+//	registry := ofputil.NewMetadataRegistry()
+//	mux.HandleFunc(of.TypeMatcher(of.TypeFeaturesReply),
+//		func(rw of.ResponseWriter, r *of.Request) {
+//			// Lookup the inventory by the datapath identifier.
+//			registry.Set(r.Conn(), "site", "ams1")
+//		})
+//
+// And then accessed by the handlers from the request context, the
+// metadata of the closed connections is removed by the ConnState hook:
+//
+//	srv := &of.Server{
+//		Addr:      ":6633",
+//		Handler:   registry.Handler(mux),
+//		ConnState: registry.ConnState,
+//	}
+//
+// Since the handler assigning the metadata could complete after the
+// connection is closed, the hook also stops the assignments to the
+// closed connections. The hooks of other registries are installed
+// along with it using of.ConnStateHooks.
+type MetadataRegistry struct {
+	mu    sync.RWMutex
+	conns map[of.Conn]Metadata
+	open  connSet
+}
+
+// NewMetadataRegistry creates a new empty metadata registry.
+func NewMetadataRegistry() *MetadataRegistry {
+	return &MetadataRegistry{conns: make(map[of.Conn]Metadata)}
+}
+
+// Set assigns the value of the key in the metadata of the connection.
+// The metadata of the connections reported closed by the ConnState hook
+// is not assigned.
+func (reg *MetadataRegistry) Set(conn of.Conn, key, value string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if !reg.open.isOpen(conn) {
+		return
+	}
+
+	// The metadata is copied on write, so the instances attached
+	// to the contexts of the requests are never modified.
+	md := reg.conns[conn].clone()
+	md[key] = value
+	reg.conns[conn] = md
+}
+
+// Delete removes the key from the metadata of the connection.
+func (reg *MetadataRegistry) Delete(conn of.Conn, key string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if _, ok := reg.conns[conn][key]; !ok {
+		return
+	}
+
+	md := reg.conns[conn].clone()
+	delete(md, key)
+	reg.conns[conn] = md
+}
+
+// Metadata returns the metadata of the connection. The returned value
+// must not be modified.
+func (reg *MetadataRegistry) Metadata(conn of.Conn) (Metadata, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	md, ok := reg.conns[conn]
+	return md, ok
+}
+
+// Remove removes the metadata of the closed connection.
+func (reg *MetadataRegistry) Remove(conn of.Conn) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	delete(reg.conns, conn)
+}
+
+// ConnState drops the metadata of the connections closed by the server.
+func (reg *MetadataRegistry) ConnState(conn of.Conn, state of.ConnState) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.open.update(conn, state)
+	if state == of.StateClosed {
+		delete(reg.conns, conn)
+	}
+}
+
+// Handler returns a handler, that attaches the metadata of the request
+// connection to the request context and then calls the given handler.
+// The requests of the connections without metadata are passed as is.
+func (reg *MetadataRegistry) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if md, ok := reg.Metadata(r.Conn()); ok {
+			r = r.WithContext(NewMetadataContext(r.Context(), md))
+		}
+		h.Serve(rw, r)
+	})
+}
@@ -0,0 +1,80 @@
+package ofputil
+
+import (
+	"net"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestMetadataRegistry(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	go of.Send(of.NewConn(c2), of.NewRequest(of.TypePacketIn, nil))
+
+	conn := of.NewConn(c1)
+	r, err := conn.Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive request: %s", err)
+	}
+
+	reg := NewMetadataRegistry()
+	reg.Set(conn, "site", "ams1")
+	reg.Set(conn, "rack", "r42")
+
+	var md Metadata
+	h := reg.Handler(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		md, _ = MetadataFromContext(r.Context())
+	}))
+
+	h.Serve(ofptest.NewRecorder(), r)
+	if md.Get("site") != "ams1" || md.Get("rack") != "r42" {
+		t.Fatalf("Invalid metadata of the request: %v", md)
+	}
+
+	// Attached metadata must not be affected by the updates.
+	reg.Delete(conn, "rack")
+	reg.Set(conn, "owner", "tenant1")
+
+	if _, ok := md["owner"]; ok || md.Get("rack") != "r42" {
+		t.Errorf("Attached metadata must not change: %v", md)
+	}
+
+	if md, _ := reg.Metadata(conn); len(md) != 2 || md.Get("rack") != "" {
+		t.Errorf("Invalid metadata of the connection: %v", md)
+	}
+
+	reg.ConnState(conn, of.StateActive)
+	if _, ok := reg.Metadata(conn); !ok {
+		t.Errorf("Metadata of the active connection must be kept")
+	}
+
+	reg.ConnState(conn, of.StateClosed)
+	if _, ok := reg.Metadata(conn); ok {
+		t.Errorf("Metadata of the closed connection must be removed")
+	}
+
+	md = nil
+	h.Serve(ofptest.NewRecorder(), r)
+	if md != nil {
+		t.Errorf("Request without metadata expected: %v", md)
+	}
+}
+
+func TestMetadataRegistryClosed(t *testing.T) {
+	reg := NewMetadataRegistry()
+
+	var conn of.Conn
+	h := of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		conn = r.Conn()
+		reg.Set(conn, "site", "ams1")
+	})
+
+	serveAfterClose(t, of.NewRequest(of.TypeFeaturesReply, nil), h, reg.ConnState)
+	if md, ok := reg.Metadata(conn); ok {
+		t.Errorf("Metadata of the closed connection must not be set: %v", md)
+	}
+}
@@ -0,0 +1,165 @@
+package ofputil
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// Monitor reads the messages from the connection and writes one-line
+// summaries of the asynchronous messages (packet-in, flow removed, port
+// status, error messages and flow monitor updates) to the given
+// writer. The echo requests are replied to keep the connection alive,
+// the rest of the messages are ignored.
+//
+// Monitor returns when the connection fails, for example, to print the
+// events of the connected switch to the standard output:
+//
+//	err := ofputil.Monitor(conn, os.Stdout)
+//	// ...
+func Monitor(conn of.Conn, w io.Writer) error {
+	for {
+		r, err := conn.Receive()
+		if err != nil {
+			return err
+		}
+
+		if r.Header.Type == of.TypeEchoRequest {
+			var echo ofp.EchoRequest
+			if _, err = echo.ReadFrom(r.Body); err != nil {
+				return err
+			}
+
+			reply := r.NewReply(of.TypeEchoReply,
+				&ofp.EchoReply{Data: echo.Data})
+
+			if err = of.Send(conn, reply); err != nil {
+				return err
+			}
+			continue
+		}
+
+		text, ok := Summary(r)
+		if !ok {
+			continue
+		}
+
+		if _, err = fmt.Fprintln(w, text); err != nil {
+			return err
+		}
+	}
+}
+
+// Summary returns a one-line decoded summary of the asynchronous
+// message. When the message is not asynchronous, false is returned.
+func Summary(r *of.Request) (string, bool) {
+	var text string
+
+	switch r.Header.Type {
+	case of.TypePacketIn:
+		var p ofp.PacketIn
+		if _, err := p.ReadFrom(r.Body); err != nil {
+			return summaryErr(r, err), true
+		}
+
+		text = fmt.Sprintf("reason=%s table=%d cookie=0x%x total_len=%d",
+			p.Reason, p.Table, p.Cookie, p.Length)
+		text = joinFields(text, p.Match)
+	case of.TypeFlowRemoved:
+		var f ofp.FlowRemoved
+		if _, err := f.ReadFrom(r.Body); err != nil {
+			return summaryErr(r, err), true
+		}
+
+		text = fmt.Sprintf("reason=%s table=%d cookie=0x%x priority=%d "+
+			"duration=%d.%03ds n_packets=%d n_bytes=%d", f.Reason, f.Table,
+			f.Cookie, f.Priority, f.DurationSec, f.DurationNSec/1e6,
+			f.PacketCount, f.ByteCount)
+		text = joinFields(text, f.Match)
+	case of.TypePortStatus:
+		var p ofp.PortStatus
+		if _, err := p.ReadFrom(r.Body); err != nil {
+			return summaryErr(r, err), true
+		}
+
+		text = fmt.Sprintf("reason=%s port=%d name=%s addr=%s "+
+			"config=[%s] state=[%s]", p.Reason, p.Port.PortNo,
+			strings.TrimRight(p.Port.Name, "\x00"), p.Port.HWAddr,
+			p.Port.Config, p.Port.State)
+	case of.TypeError:
+		e, err := ofp.ReadError(r.Body)
+		if err != nil {
+			return summaryErr(r, err), true
+		}
+
+		text = e.Error()
+	case of.TypeMultipartReply:
+		var reply ofp.MultipartReply
+		if _, err := reply.ReadFrom(r.Body); err != nil {
+			return summaryErr(r, err), true
+		}
+
+		// Only the flow monitor updates are sent asynchronously.
+		if reply.Type != ofp.MultipartTypeFlowMonitor {
+			return "", false
+		}
+
+		var updates ofp.FlowUpdates
+		if _, err := updates.ReadFrom(r.Body); err != nil {
+			return summaryErr(r, err), true
+		}
+
+		text = summaryUpdates(updates)
+	default:
+		return "", false
+	}
+
+	return fmt.Sprintf("%s (xid=0x%x): %s", r.Header.Type,
+		r.Header.Transaction, text), true
+}
+
+// summaryErr returns a summary of the message that cannot be decoded.
+func summaryErr(r *of.Request, err error) string {
+	return fmt.Sprintf("%s (xid=0x%x): decode error: %s", r.Header.Type,
+		r.Header.Transaction, err)
+}
+
+// summaryUpdates returns a summary of the flow monitor updates.
+func summaryUpdates(updates ofp.FlowUpdates) string {
+	texts := make([]string, 0, len(updates))
+
+	for _, update := range updates {
+		text := fmt.Sprintf("event=%s", update.Event())
+
+		switch update := update.(type) {
+		case *ofp.FlowUpdateFull:
+			text += fmt.Sprintf(" table=%d cookie=0x%x priority=%d",
+				update.Table, update.Cookie, update.Priority)
+
+			if update.EventType == ofp.FlowUpdateEventRemoved {
+				text += fmt.Sprintf(" reason=%s", update.Reason)
+			}
+
+			text = joinFields(text, update.Match)
+		case *ofp.FlowUpdateAbbrev:
+			text += fmt.Sprintf(" xid=0x%x", update.Transaction)
+		}
+
+		texts = append(texts, text)
+	}
+
+	return strings.Join(texts, "; ")
+}
+
+// joinFields appends the string representation of the match fields
+// to the given text.
+func joinFields(text string, m ofp.Match) string {
+	for _, xm := range m.Fields {
+		text += " " + xm.String()
+	}
+
+	return text
+}
@@ -0,0 +1,116 @@
+package ofputil
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestMonitor(t *testing.T) {
+	client, server := net.Pipe()
+	sw := of.NewConn(server)
+
+	replies := make(chan *of.Request, 1)
+	go func() {
+		// Close the connection after the echo reply, so the
+		// monitor stops after processing all messages.
+		defer sw.Close()
+
+		r, _ := sw.Receive()
+		replies <- r
+	}()
+
+	go func() {
+		packetIn := &ofp.PacketIn{
+			Buffer: ofp.NoBuffer,
+			Length: 60,
+			Reason: ofp.PacketInReasonAction,
+			Match:  ExtendedMatch(MatchInPort(3)),
+		}
+
+		portStatus := &ofp.PortStatus{
+			Reason: ofp.PortReasonDelete,
+			Port: ofp.Port{
+				PortNo: 2,
+				HWAddr: net.HardwareAddr{0, 0, 0, 0, 0, 2},
+				Name:   "eth2",
+			},
+		}
+
+		flowRemoved := &ofp.FlowRemoved{
+			Reason:   ofp.FlowReasonIdleTimeout,
+			Priority: 10,
+			Match:    ExtendedMatch(MatchEthType(0x86dd)),
+		}
+
+		errorMsg := &ofp.Error{
+			Type: ofp.ErrTypeBadRequest,
+			Code: ofp.ErrCodeBadRequestBadType,
+		}
+
+		var updates bytes.Buffer
+		reply := ofp.MultipartReply{Type: ofp.MultipartTypeFlowMonitor}
+		reply.WriteTo(&updates)
+
+		ofp.FlowUpdates{
+			&ofp.FlowUpdateFull{
+				EventType: ofp.FlowUpdateEventRemoved,
+				Table:     2,
+				Reason:    ofp.FlowReasonDelete,
+				Priority:  5,
+				Match:     ExtendedMatch(MatchInPort(1)),
+			},
+			&ofp.FlowUpdateAbbrev{Transaction: 7},
+		}.WriteTo(&updates)
+
+		of.Send(sw,
+			of.NewRequest(of.TypePacketIn, packetIn),
+			of.NewRequest(of.TypeEchoRequest, &ofp.EchoRequest{}),
+			of.NewRequest(of.TypeBarrierReply, nil),
+			of.NewRequest(of.TypePortStatus, portStatus),
+			of.NewRequest(of.TypeFlowRemoved, flowRemoved),
+			of.NewRequest(of.TypeError, errorMsg),
+			of.NewRequest(of.TypeMultipartReply, &updates),
+		)
+	}()
+
+	var buf bytes.Buffer
+	if err := Monitor(of.NewConn(client), &buf); err != io.EOF {
+		t.Fatalf("Monitor must stop on closed connection: %v", err)
+	}
+
+	if r := <-replies; r == nil || r.Header.Type != of.TypeEchoReply {
+		t.Errorf("Echo request must be replied")
+	}
+
+	expected := []string{
+		"TypePacketIn (xid=0x0): reason=PacketInReasonAction table=0 " +
+			"cookie=0x0 total_len=60 in_port=3",
+		"TypePortStatus (xid=0x0): reason=PortReasonDelete port=2 " +
+			"name=eth2 addr=00:00:00:00:00:02 config=[up] state=[link up]",
+		"TypeFlowRemoved (xid=0x0): reason=FlowReasonIdleTimeout table=0 " +
+			"cookie=0x0 priority=10 duration=0.000s n_packets=0 " +
+			"n_bytes=0 eth_type=0x86dd",
+		"TypeError (xid=0x0): ErrCodeBadRequestBadType",
+		"TypeMultipartReply (xid=0x0): event=FlowUpdateEventRemoved " +
+			"table=2 cookie=0x0 priority=5 reason=FlowReasonDelete " +
+			"in_port=1; event=FlowUpdateEventAbbrev xid=0x7",
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got:\n%s", len(expected), buf.String())
+	}
+
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("Invalid summary, expected:\n%s got:\n%s",
+				expected[i], line)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package ofputil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+var (
+	// ErrMultipartEntryTooLong is returned when a single entry of the
+	// multipart reply does not fit into the message of the maximum
+	// length.
+	ErrMultipartEntryTooLong = errors.New(
+		"ofputil: multipart entry does not fit into the message")
+
+	// ErrMultipartClosed is returned on attempt to write entries to the
+	// closed multipart writer.
+	ErrMultipartClosed = errors.New(
+		"ofputil: write to the closed multipart writer")
+)
+
+// MultipartWriter streams a large list of entries (flow statistics,
+// table features, port descriptions, etc.) into a sequence of multipart
+// reply messages. Each message, except the last one, is sent with the
+// ofp.MultipartReplyMode flag set.
+//
+// Only the entries of a single message are kept in memory, the message
+// is sent to the response writer as soon as the next entry does not fit
+// into it.
+//
+// For example, to reply on the flow statistics request from within the
+// handler, the following code could be used:
+//
+//	mw := ofputil.NewMultipartWriter(rw, r, ofp.MultipartTypeFlow)
+//	for _, stats := range flowStats {
+//		if err := mw.Write(stats); err != nil {
+//			return
+//		}
+//	}
+//	mw.Close()
+type MultipartWriter struct {
+	// MaxLen is the maximum length of a single message including the
+	// OpenFlow header. When zero, of.MaxMessageLen is used.
+	MaxLen int
+
+	rw     of.ResponseWriter
+	header of.Header
+	typ    ofp.MultipartType
+
+	buf    *bytes.Buffer
+	entry  bytes.Buffer
+	closed bool
+}
+
+// NewMultipartWriter creates a new writer of multipart replies of the
+// given type. The replies use the version and transaction identifier of
+// the given multipart request.
+func NewMultipartWriter(rw of.ResponseWriter, r *of.Request,
+	t ofp.MultipartType) *MultipartWriter {
+
+	header := r.NewReply(of.TypeMultipartReply, nil).Header
+	return &MultipartWriter{rw: rw, header: header, typ: t}
+}
+
+// maxLen returns the maximum length of the message body excluding the
+// OpenFlow and multipart headers.
+func (mw *MultipartWriter) maxLen() int {
+	return MultipartBodyLen(mw.MaxLen)
+}
+
+// Write serializes the given entries into the current multipart reply.
+// When the entry does not fit into the current reply, the reply is sent
+// with the ofp.MultipartReplyMode flag and the entry is written into a
+// new one.
+func (mw *MultipartWriter) Write(entries ...io.WriterTo) error {
+	if mw.closed {
+		return ErrMultipartClosed
+	}
+
+	for _, e := range entries {
+		mw.entry.Reset()
+		if _, err := e.WriteTo(&mw.entry); err != nil {
+			return err
+		}
+
+		if mw.entry.Len() > mw.maxLen() {
+			return ErrMultipartEntryTooLong
+		}
+
+		if mw.buf != nil && mw.buf.Len()+mw.entry.Len() > mw.maxLen() {
+			if err := mw.flush(ofp.MultipartReplyMode); err != nil {
+				return err
+			}
+		}
+
+		if mw.buf == nil {
+			mw.buf = new(bytes.Buffer)
+		}
+
+		mw.buf.Write(mw.entry.Bytes())
+	}
+
+	return nil
+}
+
+// flush sends the buffered entries as a single multipart reply.
+func (mw *MultipartWriter) flush(flags ofp.MultipartReplyFlag) error {
+	var body bytes.Buffer
+
+	reply := ofp.MultipartReply{Type: mw.typ, Flags: flags}
+	if _, err := reply.WriteTo(&body); err != nil {
+		return err
+	}
+
+	if mw.buf != nil {
+		body.Write(mw.buf.Bytes())
+	}
+
+	// A new buffer is allocated for each message, since the response
+	// writer may retain the body after the call.
+	mw.buf = nil
+
+	header := mw.header
+	return mw.rw.Write(&header, &body)
+}
+
+// Close sends the last multipart reply without ofp.MultipartReplyMode
+// flag. When no entries were written, an empty reply is sent.
+func (mw *MultipartWriter) Close() error {
+	if mw.closed {
+		return ErrMultipartClosed
+	}
+
+	mw.closed = true
+	return mw.flush(0)
+}
+
+// MultipartBodyLen returns the maximum length of the entries that fit
+// into a single multipart message of the given maximum length, which
+// includes the OpenFlow and multipart headers. When the maximum length
+// is not positive or exceeds of.MaxMessageLen, the of.MaxMessageLen is
+// used.
+func MultipartBodyLen(maxLen int) int {
+	if maxLen <= 0 || maxLen > of.MaxMessageLen {
+		maxLen = of.MaxMessageLen
+	}
+
+	return maxLen - of.HeaderLen - ofp.MultipartHeaderLen
+}
+
+// EntriesPerMessage returns the number of entries of the same length
+// that fit into the body of the given capacity. For example, to compute
+// the number of port statistics entries in the single multipart reply:
+//
+//	n := ofputil.EntriesPerMessage(
+//		ofputil.MultipartBodyLen(0), portStatsLen)
+func EntriesPerMessage(capacity, entryLen int) int {
+	if capacity <= 0 || entryLen <= 0 {
+		return 0
+	}
+
+	return capacity / entryLen
+}
+
+// SplitEntries splits the entries into the batches, so the serialized
+// entries of each batch fit into the body of the given capacity. The
+// order of the entries is preserved.
+//
+// When a single entry does not fit into the capacity, the
+// ErrMultipartEntryTooLong error is returned. For example, to split the
+// flow statistics into multipart replies:
+//
+//	batches, err := ofputil.SplitEntries(
+//		ofputil.MultipartBodyLen(0), entries...)
+func SplitEntries(capacity int, entries ...io.WriterTo) ([][]io.WriterTo, error) {
+	var (
+		batches [][]io.WriterTo
+		batch   []io.WriterTo
+		size    int
+	)
+
+	for _, e := range entries {
+		n, err := e.WriteTo(ioutil.Discard)
+		if err != nil {
+			return nil, err
+		}
+
+		if int(n) > capacity {
+			return nil, ErrMultipartEntryTooLong
+		}
+
+		if batch != nil && size+int(n) > capacity {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+
+		batch = append(batch, e)
+		size += int(n)
+	}
+
+	if batch != nil {
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
+
+// MultipartOverflowError is returned when the switch is not able to
+// buffer the multipart request spanning multiple messages. Such request
+// should be split into several independent requests, each of them fits
+// into a smaller number of messages.
+type MultipartOverflowError struct {
+	// Type is a type of the failed multipart request, when it could
+	// be decoded from the data of the error message.
+	Type ofp.MultipartType
+
+	// Err is the error message returned by the switch.
+	Err *ofp.Error
+}
+
+// Error implements error interface.
+func (e *MultipartOverflowError) Error() string {
+	return fmt.Sprintf("ofputil: %s request overflows the buffer of the "+
+		"switch, split it into smaller requests", e.Type)
+}
+
+// Unwrap returns the error message returned by the switch.
+func (e *MultipartOverflowError) Unwrap() error {
+	return e.Err
+}
+
+// MultipartError decodes the error message received in response to the
+// multipart request. The buffer overflow errors are returned as
+// *MultipartOverflowError, the rest are returned as ofp.ErrorMessage.
+func MultipartError(r *of.Request) error {
+	if r.Header.Type != of.TypeError {
+		return fmt.Errorf("ofputil: unexpected message type: %s",
+			r.Header.Type)
+	}
+
+	e, err := ofp.ReadError(r.Body)
+	if err != nil {
+		return err
+	}
+
+	oe, ok := e.(*ofp.Error)
+	if !ok || oe.Type != ofp.ErrTypeBadRequest ||
+		oe.Code != ofp.ErrCodeBadRequestMultipartBufferOverflow {
+		return e
+	}
+
+	overflow := &MultipartOverflowError{Err: oe}
+
+	// The data of the error message contains the beginning of the
+	// failed request: the OpenFlow header and the multipart type.
+	if len(oe.Data) >= of.HeaderLen+2 {
+		overflow.Type = ofp.MultipartType(
+			binary.BigEndian.Uint16(oe.Data[of.HeaderLen:]))
+	}
+
+	return overflow
+}
+
+// MultipartRequests returns a sequence of multipart requests of the
+// given type, that carry the entries split into the messages of the
+// given maximum length. Each request, except the last one, is sent with
+// the ofp.MultipartRequestMode flag set. All requests share the same
+// transaction identifier.
+func MultipartRequests(t ofp.MultipartType, maxLen int,
+	entries ...io.WriterTo) ([]*of.Request, error) {
+
+	batches, err := SplitEntries(MultipartBodyLen(maxLen), entries...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Even the request without entries is sent as a single message.
+	if len(batches) == 0 {
+		batches = append(batches, nil)
+	}
+
+	xid := newXID()
+	reqs := make([]*of.Request, 0, len(batches))
+
+	for i, batch := range batches {
+		body := ofp.NewMultipartRequest(t, batch...)
+		if i < len(batches)-1 {
+			body.Flags = ofp.MultipartRequestMode
+		}
+
+		req := of.NewRequest(of.TypeMultipartRequest, body)
+		req.Header.Transaction = xid
+		reqs = append(reqs, req)
+	}
+
+	return reqs, nil
+}
+
+// MultipartRetry sends the entries as the multipart request of the given
+// type with the send function. When the send function returns the
+// *MultipartOverflowError, the entries are split in halves, and each half
+// is sent as an independent multipart request. The splitting continues
+// until the request with a single entry fails.
+//
+// The function should only be used for the requests, which entries are
+// independent from each other, like the flow monitor requests. The send
+// function must wait for the reply, for example:
+//
+//	err := ofputil.MultipartRetry(ofp.MultipartTypeFlowMonitor,
+//		func(reqs []*of.Request) error {
+//			of.Send(conn, reqs...)
+//			return waitReply(reqs[0].Header.Transaction)
+//		}, monitors...)
+func MultipartRetry(t ofp.MultipartType, send func([]*of.Request) error,
+	entries ...io.WriterTo) error {
+
+	reqs, err := MultipartRequests(t, 0, entries...)
+	if err != nil {
+		return err
+	}
+
+	err = send(reqs)
+
+	var overflow *MultipartOverflowError
+	if len(entries) < 2 || !errors.As(err, &overflow) {
+		return err
+	}
+
+	half := len(entries) / 2
+	if err = MultipartRetry(t, send, entries[:half]...); err != nil {
+		return err
+	}
+
+	return MultipartRetry(t, send, entries[half:]...)
+}
@@ -0,0 +1,264 @@
+package ofputil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestMultipartWriter(t *testing.T) {
+	rw := ofptest.NewRecorder()
+
+	req := of.NewRequest(of.TypeMultipartRequest, nil)
+	req.Header.Transaction = 42
+
+	// Each port description is 64 bytes long, so only three ports
+	// fit into a single message.
+	mw := NewMultipartWriter(rw, req, ofp.MultipartTypePortDescription)
+	mw.MaxLen = of.HeaderLen + ofp.MultipartHeaderLen + 64*3 + 63
+
+	for i := 0; i < 10; i++ {
+		port := &ofp.Port{
+			PortNo: ofp.PortNo(i + 1),
+			HWAddr: net.HardwareAddr{0, 0, 0, 0, 0, byte(i)},
+		}
+
+		err := mw.Write(port)
+		if err != nil {
+			t.Fatalf("Failed to write port description: %s", err)
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		t.Fatalf("Failed to close multipart writer: %s", err)
+	}
+
+	if err := mw.Write(&ofp.Port{}); err != ErrMultipartClosed {
+		t.Fatalf("Write to closed writer must fail: %v", err)
+	}
+
+	reqs := rw.All()
+	if len(reqs) != 4 {
+		t.Fatalf("Expected 4 replies, got %d", len(reqs))
+	}
+
+	var ports int
+	for i, r := range reqs {
+		if r.Header.Type != of.TypeMultipartReply {
+			t.Errorf("Multipart reply expected: %s", r.Header.Type)
+		}
+
+		if r.Header.Transaction != req.Header.Transaction {
+			t.Errorf("Transaction identifier changed: %d",
+				r.Header.Transaction)
+		}
+
+		var reply ofp.MultipartReply
+		if _, err := reply.ReadFrom(r.Body); err != nil {
+			t.Fatalf("Failed to read multipart reply: %s", err)
+		}
+
+		more := reply.Flags&ofp.MultipartReplyMode != 0
+		if more != (i < len(reqs)-1) {
+			t.Errorf("Invalid flags of reply %d: %d", i, reply.Flags)
+		}
+
+		for {
+			var port ofp.Port
+			_, err := port.ReadFrom(r.Body)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Failed to read port description: %s", err)
+			}
+
+			ports++
+			if port.PortNo != ofp.PortNo(ports) {
+				t.Errorf("Invalid port number: %d", port.PortNo)
+			}
+		}
+	}
+
+	if ports != 10 {
+		t.Fatalf("Expected 10 port descriptions, got %d", ports)
+	}
+}
+
+func TestMultipartWriterEmpty(t *testing.T) {
+	rw := ofptest.NewRecorder()
+
+	req := of.NewRequest(of.TypeMultipartRequest, nil)
+	mw := NewMultipartWriter(rw, req, ofp.MultipartTypeFlow)
+
+	if err := mw.Close(); err != nil {
+		t.Fatalf("Failed to close multipart writer: %s", err)
+	}
+
+	body, _ := ioutil.ReadAll(rw.First().Body)
+	if len(body) != ofp.MultipartHeaderLen {
+		t.Fatalf("Empty reply expected, got %x", body)
+	}
+}
+
+func TestMultipartBodyLen(t *testing.T) {
+	tests := []struct {
+		MaxLen  int
+		BodyLen int
+	}{
+		{0, of.MaxMessageLen - 16},
+		{-1, of.MaxMessageLen - 16},
+		{of.MaxMessageLen + 1, of.MaxMessageLen - 16},
+		{1024, 1008},
+	}
+
+	for _, test := range tests {
+		if n := MultipartBodyLen(test.MaxLen); n != test.BodyLen {
+			t.Errorf("Invalid body length for %d: %d", test.MaxLen, n)
+		}
+	}
+
+	if n := EntriesPerMessage(1008, 112); n != 9 {
+		t.Errorf("Invalid number of entries per message: %d", n)
+	}
+
+	if n := EntriesPerMessage(1008, 0); n != 0 {
+		t.Errorf("Zero-length entries must not fit: %d", n)
+	}
+}
+
+func TestSplitEntries(t *testing.T) {
+	var entries []io.WriterTo
+	for i := 0; i < 5; i++ {
+		entries = append(entries, &ofp.PortStats{PortNo: ofp.PortNo(i)})
+	}
+
+	// Port statistics entry takes 112 bytes.
+	batches, err := SplitEntries(250, entries...)
+	if err != nil {
+		t.Fatalf("Failed to split entries: %s", err)
+	}
+
+	lens := []int{2, 2, 1}
+	if len(batches) != len(lens) {
+		t.Fatalf("Invalid number of batches: %d", len(batches))
+	}
+
+	for i, batch := range batches {
+		if len(batch) != lens[i] {
+			t.Errorf("Invalid length of batch %d: %d", i, len(batch))
+		}
+	}
+
+	if batches[2][0] != entries[4] {
+		t.Errorf("Order of entries must be preserved")
+	}
+
+	_, err = SplitEntries(100, entries...)
+	if err != ErrMultipartEntryTooLong {
+		t.Fatalf("Entry must not fit into the message: %v", err)
+	}
+}
+
+func TestMultipartRequests(t *testing.T) {
+	var entries []io.WriterTo
+	for i := 0; i < 5; i++ {
+		entries = append(entries, &ofp.PortStats{PortNo: ofp.PortNo(i)})
+	}
+
+	// Two port statistics entries fit into a single message.
+	maxLen := of.HeaderLen + ofp.MultipartHeaderLen + 250
+	reqs, err := MultipartRequests(ofp.MultipartTypePortStats, maxLen, entries...)
+	if err != nil {
+		t.Fatalf("Failed to create multipart requests: %s", err)
+	}
+
+	if len(reqs) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(reqs))
+	}
+
+	for i, r := range reqs {
+		if r.Header.Transaction != reqs[0].Header.Transaction {
+			t.Errorf("Transaction identifier changed: %d",
+				r.Header.Transaction)
+		}
+
+		var req ofp.MultipartRequest
+		if _, err := req.ReadFrom(r.Body); err != nil {
+			t.Fatalf("Failed to read multipart request: %s", err)
+		}
+
+		more := req.Flags&ofp.MultipartRequestMode != 0
+		if more != (i < len(reqs)-1) {
+			t.Errorf("Invalid flags of request %d: %d", i, req.Flags)
+		}
+	}
+}
+
+func TestMultipartError(t *testing.T) {
+	var buf bytes.Buffer
+	of.NewRequest(of.TypeMultipartRequest, ofp.NewMultipartRequest(
+		ofp.MultipartTypeTableFeatures)).WriteTo(&buf)
+
+	r := of.NewRequest(of.TypeError, &ofp.Error{
+		Type: ofp.ErrTypeBadRequest,
+		Code: ofp.ErrCodeBadRequestMultipartBufferOverflow,
+		Data: buf.Bytes(),
+	})
+
+	var overflow *MultipartOverflowError
+	if err := MultipartError(r); !errors.As(err, &overflow) {
+		t.Fatalf("Overflow error expected: %v", err)
+	}
+
+	if overflow.Type != ofp.MultipartTypeTableFeatures {
+		t.Errorf("Invalid type of the multipart request: %s", overflow.Type)
+	}
+
+	r = of.NewRequest(of.TypeError, &ofp.Error{
+		Type: ofp.ErrTypeBadRequest,
+		Code: ofp.ErrCodeBadRequestBadMultipart,
+	})
+
+	if err := MultipartError(r); errors.As(err, &overflow) {
+		t.Errorf("Overflow error is not expected: %v", err)
+	}
+}
+
+func TestMultipartRetry(t *testing.T) {
+	var entries []io.WriterTo
+	for i := 0; i < 5; i++ {
+		entries = append(entries, &ofp.PortStats{PortNo: ofp.PortNo(i)})
+	}
+
+	var sent []int
+	send := func(reqs []*of.Request) error {
+		body, _ := ioutil.ReadAll(reqs[0].Body)
+		n := (len(body) - ofp.MultipartHeaderLen) / 112
+
+		// Pretend the switch buffers only two entries.
+		if n > 2 {
+			return &MultipartOverflowError{Err: &ofp.Error{}}
+		}
+
+		sent = append(sent, n)
+		return nil
+	}
+
+	err := MultipartRetry(ofp.MultipartTypePortStats, send, entries...)
+	if err != nil {
+		t.Fatalf("Failed to send multipart request: %s", err)
+	}
+
+	if fmt.Sprint(sent) != "[2 1 2]" {
+		t.Errorf("Invalid split of the entries: %v", sent)
+	}
+}
@@ -0,0 +1,335 @@
+package ofputil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+const (
+	// ExperimenterNicira is an experimenter identifier of the Open
+	// vSwitch (Nicira) extensions.
+	ExperimenterNicira uint32 = 0x00002320
+
+	// NXTPacketIn2 is an experimenter type of the Open vSwitch packet-in
+	// message with the properties (NXT_PACKET_IN2).
+	NXTPacketIn2 uint32 = 30
+)
+
+// Properties of the NXT_PACKET_IN2 message.
+const (
+	nxpintPacket       uint16 = 0
+	nxpintFullLen      uint16 = 1
+	nxpintBufferID     uint16 = 2
+	nxpintTableID      uint16 = 3
+	nxpintCookie       uint16 = 4
+	nxpintReason       uint16 = 5
+	nxpintMetadata     uint16 = 6
+	nxpintUserdata     uint16 = 7
+	nxpintContinuation uint16 = 8
+)
+
+// PacketInProp is a property of the vendor-specific packet-in message,
+// that is not recognized by the decoder.
+type PacketInProp struct {
+	// Type is a type of the property.
+	Type uint16
+
+	// Value is a value of the property without the padding.
+	Value []byte
+}
+
+// PacketIn is a packet-in message with the vendor-specific metadata.
+// The standard packet-in messages are represented with the zero
+// Experimenter header and empty metadata.
+type PacketIn struct {
+	ofp.PacketIn
+
+	// Experimenter is a header of the vendor-specific message, the
+	// packet-in was decoded from.
+	Experimenter ofp.Experimenter
+
+	// Userdata is an opaque data attached to the packet by the
+	// controller action, that sent the packet to the controller.
+	Userdata []byte
+
+	// Continuation is an opaque state of the paused pipeline, that
+	// could be sent back to the switch to resume the processing.
+	Continuation []byte
+
+	// Props is a list of the properties unknown to the decoder.
+	Props []PacketInProp
+}
+
+// A PacketInHandler responds to the packet-in messages, both standard
+// and vendor-specific.
+type PacketInHandler interface {
+	ServePacketIn(rw of.ResponseWriter, r *of.Request, p *PacketIn)
+}
+
+// The PacketInHandlerFunc is an adapter to allow use of ordinary
+// functions as packet-in handlers.
+type PacketInHandlerFunc func(of.ResponseWriter, *of.Request, *PacketIn)
+
+// ServePacketIn implements PacketInHandler interface and calls f(rw, r, p).
+func (f PacketInHandlerFunc) ServePacketIn(rw of.ResponseWriter, r *of.Request, p *PacketIn) {
+	f(rw, r, p)
+}
+
+// A PacketInDecoder decodes the body of the vendor-specific packet-in
+// message following the experimenter header.
+type PacketInDecoder interface {
+	DecodePacketIn(r io.Reader, p *PacketIn) error
+}
+
+// The PacketInDecoderFunc is an adapter to allow use of ordinary
+// functions as packet-in decoders.
+type PacketInDecoderFunc func(io.Reader, *PacketIn) error
+
+// DecodePacketIn implements PacketInDecoder interface and calls f(r, p).
+func (f PacketInDecoderFunc) DecodePacketIn(r io.Reader, p *PacketIn) error {
+	return f(r, p)
+}
+
+// NXPacketIn2Decoder decodes the Open vSwitch NXT_PACKET_IN2 message.
+var NXPacketIn2Decoder PacketInDecoder = PacketInDecoderFunc(decodeNXPacketIn2)
+
+// decodeNXPacketIn2 decodes the list of the NXT_PACKET_IN2 properties.
+func decodeNXPacketIn2(r io.Reader, p *PacketIn) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	p.Match = ofp.Match{Type: ofp.MatchTypeXM}
+	p.Buffer = ofp.NoBuffer
+
+	var fullLen bool
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return fmt.Errorf("ofputil: truncated packet-in property: %w",
+				io.ErrUnexpectedEOF)
+		}
+
+		ptype := binary.BigEndian.Uint16(b)
+		plen := int(binary.BigEndian.Uint16(b[2:]))
+
+		if plen < 4 || plen > len(b) {
+			return fmt.Errorf("ofputil: invalid length %d of packet-in "+
+				"property %d", plen, ptype)
+		}
+
+		// The length of the property does not include the padding
+		// to the multiple of 8 bytes, the padding of the last
+		// property could be omitted.
+		value := b[4:plen:plen]
+		if padded := (plen + 7) / 8 * 8; padded < len(b) {
+			b = b[padded:]
+		} else {
+			b = nil
+		}
+
+		switch ptype {
+		case nxpintPacket:
+			p.Data = value
+		case nxpintFullLen:
+			fullLen = true
+			err = decodeProp(ptype, value, func(v []byte) {
+				p.Length = uint16(binary.BigEndian.Uint32(v))
+			}, 4)
+		case nxpintBufferID:
+			err = decodeProp(ptype, value, func(v []byte) {
+				p.Buffer = binary.BigEndian.Uint32(v)
+			}, 4)
+		case nxpintTableID:
+			err = decodeProp(ptype, value, func(v []byte) {
+				p.Table = ofp.Table(v[0])
+			}, 1)
+		case nxpintCookie:
+			err = decodeProp(ptype, value, func(v []byte) {
+				p.Cookie = binary.BigEndian.Uint64(v)
+			}, 8)
+		case nxpintReason:
+			err = decodeProp(ptype, value, func(v []byte) {
+				p.Reason = ofp.PacketInReason(v[0])
+			}, 1)
+		case nxpintMetadata:
+			err = decodeMetadata(value, &p.Match)
+		case nxpintUserdata:
+			p.Userdata = value
+		case nxpintContinuation:
+			p.Continuation = value
+		default:
+			p.Props = append(p.Props, PacketInProp{ptype, value})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if !fullLen {
+		p.Length = uint16(len(p.Data))
+	}
+
+	return nil
+}
+
+// decodeProp calls the decode function with the value of the fixed
+// size property. The values are aligned to their size, so the leading
+// padding of the value is skipped.
+func decodeProp(ptype uint16, value []byte, decode func([]byte), size int) error {
+	if len(value) < size {
+		return fmt.Errorf("ofputil: invalid length %d of packet-in "+
+			"property %d", len(value), ptype)
+	}
+
+	decode(value[len(value)-size:])
+	return nil
+}
+
+// decodeMetadata decodes the list of the extensible match fields of
+// the pipeline metadata property.
+func decodeMetadata(value []byte, m *ofp.Match) error {
+	r := bytes.NewReader(value)
+	for r.Len() > 0 {
+		var xm ofp.XM
+		if _, err := xm.ReadFrom(r); err != nil {
+			return fmt.Errorf("ofputil: invalid packet-in metadata: %w", err)
+		}
+
+		m.Fields = append(m.Fields, xm)
+	}
+
+	return nil
+}
+
+// PacketInServer serves the standard and vendor-specific packet-in
+// messages with the same packet-in handler. The vendor-specific
+// messages are decoded with the decoders registered for the
+// experimenter identifier and experimenter type.
+//
+// For example, to process the packets sent to the controller by the
+// Open vSwitch controller action with the userdata:
+//
+//	pins := ofputil.NewPacketInServer(handler)
+//
+//	exp := ofputil.NewExperimenterMux()
+//	pins.Route(exp)
+//
+//	mux := of.NewTypeMux()
+//	mux.Handle(of.TypePacketIn, pins)
+//	mux.Handle(of.TypeExperiment, exp)
+type PacketInServer struct {
+	// Handler is a handler of the decoded packet-in messages.
+	Handler PacketInHandler
+
+	mu       sync.RWMutex
+	decoders map[ofp.Experimenter]PacketInDecoder
+}
+
+// NewPacketInServer creates a new packet-in server with the given
+// handler. The decoder of the NXT_PACKET_IN2 message is registered
+// by default.
+func NewPacketInServer(h PacketInHandler) *PacketInServer {
+	s := &PacketInServer{
+		Handler:  h,
+		decoders: make(map[ofp.Experimenter]PacketInDecoder),
+	}
+
+	s.HandleDecoder(ExperimenterNicira, NXTPacketIn2, NXPacketIn2Decoder)
+	return s
+}
+
+// HandleDecoder registers the decoder of the packet-in messages of the
+// given experimenter identifier and experimenter type.
+func (s *PacketInServer) HandleDecoder(experimenter, expType uint32, d PacketInDecoder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ofp.Experimenter{Experimenter: experimenter, ExpType: expType}
+	if _, dup := s.decoders[key]; dup {
+		text := "ofputil: multiple packet-in decoders for experimenter 0x%x type %d"
+		panic(fmt.Errorf(text, experimenter, expType))
+	}
+
+	s.decoders[key] = d
+}
+
+// Route registers the server in the experimenter multiplexer for the
+// messages of all registered decoders. The decoders registered after
+// the call are not routed.
+func (s *PacketInServer) Route(mux *ExperimenterMux) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key := range s.decoders {
+		mux.Handle(key.Experimenter, key.ExpType, s)
+	}
+}
+
+// Decode decodes the packet-in message from the request. The
+// experimenter messages are decoded with the registered decoders.
+func (s *PacketInServer) Decode(r *of.Request) (*PacketIn, error) {
+	var p PacketIn
+
+	switch r.Header.Type {
+	case of.TypePacketIn:
+		if err := r.Decode(&p.PacketIn); err != nil {
+			return nil, err
+		}
+
+		return &p, nil
+	case of.TypeExperiment:
+	default:
+		return nil, fmt.Errorf("ofputil: unexpected message type: %s",
+			r.Header.Type)
+	}
+
+	body, err := r.RawBody()
+	if err != nil {
+		return nil, err
+	}
+
+	rd := bytes.NewReader(body)
+	if _, err = p.Experimenter.ReadFrom(rd); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	d, ok := s.decoders[p.Experimenter]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("ofputil: no packet-in decoder for "+
+			"experimenter 0x%x type %d", p.Experimenter.Experimenter,
+			p.Experimenter.ExpType)
+	}
+
+	if err = d.DecodePacketIn(rd, &p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Serve implements of.Handler interface. It decodes the packet-in
+// message and calls the packet-in handler. The messages that could
+// not be decoded are logged and discarded.
+func (s *PacketInServer) Serve(rw of.ResponseWriter, r *of.Request) {
+	p, err := s.Decode(r)
+	if err != nil {
+		Logf(r, "ofputil: failed to decode packet-in: %v", err)
+		return
+	}
+
+	if s.Handler != nil {
+		s.Handler.ServePacketIn(rw, r, p)
+	}
+}
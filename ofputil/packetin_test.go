@@ -0,0 +1,92 @@
+package ofputil
+
+import (
+	"bytes"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestPacketInServer(t *testing.T) {
+	body := []byte{
+		0x00, 0x00, 0x23, 0x20, 0x00, 0x00, 0x00, 0x1e,
+		// Packet.
+		0x00, 0x00, 0x00, 0x0a, 'a', 'b', 'c', 'd', 'e', 'f', 0, 0, 0, 0, 0, 0,
+		// Full length.
+		0x00, 0x01, 0x00, 0x08, 0x00, 0x00, 0x00, 0x64,
+		// Buffer identifier.
+		0x00, 0x02, 0x00, 0x08, 0x00, 0x00, 0x00, 0x07,
+		// Table identifier.
+		0x00, 0x03, 0x00, 0x05, 0x02, 0, 0, 0,
+		// Cookie.
+		0x00, 0x04, 0x00, 0x10, 0, 0, 0, 0,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a,
+		// Reason.
+		0x00, 0x05, 0x00, 0x05, 0x01, 0, 0, 0,
+		// Metadata with the in_port match field.
+		0x00, 0x06, 0x00, 0x0c, 0x80, 0x00, 0x00, 0x04,
+		0x00, 0x00, 0x00, 0x03, 0, 0, 0, 0,
+		// Userdata.
+		0x00, 0x07, 0x00, 0x07, 'u', 's', 'r', 0,
+		// Unknown property without the trailing padding.
+		0x00, 0x2a, 0x00, 0x06, 'x', 'y',
+	}
+
+	var served []*PacketIn
+	s := NewPacketInServer(PacketInHandlerFunc(
+		func(rw of.ResponseWriter, r *of.Request, p *PacketIn) {
+			served = append(served, p)
+		}))
+
+	exp := NewExperimenterMux()
+	s.Route(exp)
+
+	exp.Serve(nil, of.NewRequest(of.TypeExperiment, bytes.NewBuffer(body)))
+
+	pin := &ofp.PacketIn{Buffer: ofp.NoBuffer, Length: 2, Data: []byte{1, 2}}
+	s.Serve(nil, of.NewRequest(of.TypePacketIn, pin))
+
+	// The message without the registered decoder is discarded.
+	s.Serve(nil, of.NewRequest(of.TypeExperiment, &ofp.Experimenter{
+		Experimenter: ExperimenterNicira, ExpType: 1,
+	}))
+
+	if len(served) != 2 {
+		t.Fatalf("Two packet-in messages expected: %d", len(served))
+	}
+
+	p := served[0]
+	if p.Experimenter.ExpType != NXTPacketIn2 || string(p.Data) != "abcdef" {
+		t.Errorf("Invalid packet-in decoded: %v", p)
+	}
+
+	if p.Length != 100 || p.Buffer != 7 || p.Table != 2 ||
+		p.Cookie != 42 || p.Reason != ofp.PacketInReasonAction {
+		t.Errorf("Invalid packet-in properties decoded: %v", p.PacketIn)
+	}
+
+	if port, ok := p.InPort(); !ok || port != 3 {
+		t.Errorf("Invalid packet-in metadata decoded: %v", p.Match)
+	}
+
+	if string(p.Userdata) != "usr" {
+		t.Errorf("Invalid userdata decoded: %q", p.Userdata)
+	}
+
+	if len(p.Props) != 1 || p.Props[0].Type != 42 ||
+		string(p.Props[0].Value) != "xy" {
+		t.Errorf("Invalid unknown properties: %v", p.Props)
+	}
+
+	p = served[1]
+	if p.Experimenter != (ofp.Experimenter{}) || !bytes.Equal(p.Data, pin.Data) {
+		t.Errorf("Invalid standard packet-in decoded: %v", p)
+	}
+
+	// The property length exceeds the message.
+	r := of.NewRequest(of.TypeExperiment, bytes.NewBuffer(body[:60]))
+	if _, err := s.Decode(r); err == nil {
+		t.Errorf("Error expected for truncated property")
+	}
+}
@@ -0,0 +1,151 @@
+package ofputil
+
+import (
+	"sync"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+const (
+	// DefaultPacketOutDelay is a default time the packet-out messages
+	// wait in the batch before the batch is flushed.
+	DefaultPacketOutDelay = time.Millisecond
+
+	// DefaultPacketOutBatch is a default maximum number of packet-out
+	// messages in the batch.
+	DefaultPacketOutBatch = 64
+)
+
+// PacketOutBatcher sends the packet-out messages to the switch in
+// batches, so the reactive applications sending thousands of small
+// packet-out messages per second don't pay for the write to the
+// connection of each message.
+//
+// The messages are written into the buffer of the connection and
+// flushed after the delay or when the batch reaches the maximum
+// size, whatever comes first. The full batch is flushed in the Send
+// call, so when the switch does not keep up, the sender is blocked
+// instead of accumulating the messages in memory.
+//
+// One batcher should be used per switch connection, for example:
+//
+//	batcher := ofputil.NewPacketOutBatcher(conn, 0, 0)
+//	defer batcher.Flush()
+//
+//	mux.HandleFunc(of.TypePacketIn, func(rw of.ResponseWriter, r *of.Request) {
+//		// ...
+//		batcher.Send(&ofp.PacketOut{...})
+//	})
+type PacketOutBatcher struct {
+	// Clock is used to schedule the flush of the batch. When not
+	// defined, the system clock is used.
+	Clock of.Clock
+
+	conn  of.Conn
+	delay time.Duration
+	max   int
+
+	mu      sync.Mutex
+	pending int
+	timer   of.Timer
+
+	// err is an error of the flush performed after the delay, it
+	// is returned from the next call to Send or Flush.
+	err error
+}
+
+// NewPacketOutBatcher creates a new batcher of the packet-out messages
+// sent to the given connection. When the delay or the maximum size of
+// the batch are zero, the defaults are used.
+func NewPacketOutBatcher(c of.Conn, delay time.Duration, max int) *PacketOutBatcher {
+	if delay <= 0 {
+		delay = DefaultPacketOutDelay
+	}
+
+	if max <= 0 {
+		max = DefaultPacketOutBatch
+	}
+
+	return &PacketOutBatcher{conn: c, delay: delay, max: max}
+}
+
+// Send appends the packet-out message to the batch. The batch is
+// flushed, when it reaches the maximum size.
+func (b *PacketOutBatcher) Send(p *ofp.PacketOut) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+
+	if err := b.conn.Send(of.NewRequest(of.TypePacketOut, p)); err != nil {
+		return err
+	}
+
+	if b.pending++; b.pending >= b.max {
+		return b.flush()
+	}
+
+	if b.timer == nil {
+		b.timer = clockOrSystem(b.Clock).AfterFunc(b.delay, b.expire)
+	}
+
+	return nil
+}
+
+// Flush writes the pending packet-out messages to the connection.
+func (b *PacketOutBatcher) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+
+	return b.flush()
+}
+
+// Pending returns the number of the packet-out messages waiting in
+// the batch.
+func (b *PacketOutBatcher) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.pending
+}
+
+// expire flushes the batch after the delay.
+func (b *PacketOutBatcher) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.timer = nil
+	if b.pending == 0 {
+		return
+	}
+
+	if err := b.flush(); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+// flush writes the batch to the connection, the lock must be held.
+func (b *PacketOutBatcher) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	b.pending = 0
+	return b.conn.Flush()
+}
+
+// takeErr returns and resets the error of the delayed flush.
+func (b *PacketOutBatcher) takeErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
@@ -0,0 +1,58 @@
+package ofputil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestPacketOutBatcher(t *testing.T) {
+	rec := ofptest.NewConnRecorder()
+	batcher := NewPacketOutBatcher(rec, time.Hour, 3)
+
+	packet := &ofp.PacketOut{Buffer: ofp.NoBuffer, InPort: ofp.PortController}
+	for i := 0; i < 4; i++ {
+		if err := batcher.Send(packet); err != nil {
+			t.Fatalf("Failed to send packet-out: %s", err)
+		}
+	}
+
+	// The full batch must be flushed immediately.
+	if rec.Len() != 4 || rec.Flushed() != 1 || batcher.Pending() != 1 {
+		t.Fatalf("Invalid batch state: %d sent, %d flushed, %d pending",
+			rec.Len(), rec.Flushed(), batcher.Pending())
+	}
+
+	if err := batcher.Flush(); err != nil {
+		t.Fatalf("Failed to flush batch: %s", err)
+	}
+
+	if rec.Flushed() != 2 || batcher.Pending() != 0 {
+		t.Fatalf("Pending packet-outs must be flushed: %d", rec.Flushed())
+	}
+
+	// The incomplete batch is flushed after the delay.
+	clock := ofptest.NewClock(time.Unix(0, 0))
+	batcher = NewPacketOutBatcher(rec, time.Millisecond, 0)
+	batcher.Clock = clock
+
+	if err := batcher.Send(packet); err != nil {
+		t.Fatalf("Failed to send packet-out: %s", err)
+	}
+
+	clock.Advance(time.Millisecond / 2)
+	if batcher.Pending() != 1 {
+		t.Fatalf("Batch must not be flushed before delay")
+	}
+
+	clock.Advance(time.Millisecond / 2)
+	if batcher.Pending() != 0 || rec.Flushed() != 3 {
+		t.Fatalf("Batch must be flushed after delay: %d", rec.Flushed())
+	}
+
+	if clock.Timers() != 0 {
+		t.Fatalf("Timer must not be scheduled for empty batch")
+	}
+}
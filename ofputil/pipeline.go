@@ -0,0 +1,256 @@
+package ofputil
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+// Pipeline is a graph of the flow tables of the switch built from the
+// table features. Tables are the nodes of the graph, and the tables
+// reachable using the goto-table instruction are the edges.
+//
+// Pipeline could be used to place the flow entries automatically in
+// the multi-table pipelines, for example, to find the first table that
+// is able to match the flow:
+//
+//	pipeline := ofputil.NewPipeline(features)
+//
+//	table, ok := pipeline.FirstTableSupporting(match.Fields...)
+//	if !ok {
+//		// ...
+//	}
+type Pipeline struct {
+	tables   []ofp.Table
+	features map[ofp.Table]*ofp.TableFeatures
+	next     map[ofp.Table][]ofp.Table
+}
+
+// NewPipeline creates a new pipeline from the list of table features
+// returned within the table features multipart reply.
+func NewPipeline(features []ofp.TableFeatures) *Pipeline {
+	p := &Pipeline{
+		features: make(map[ofp.Table]*ofp.TableFeatures),
+		next:     make(map[ofp.Table][]ofp.Table),
+	}
+
+	for i := range features {
+		table := features[i].Table
+		if _, ok := p.features[table]; !ok {
+			p.tables = append(p.tables, table)
+		}
+
+		p.features[table] = &features[i]
+
+		// Both, regular and table-miss flow entries can
+		// continue the processing in the next tables.
+		seen := make(map[ofp.Table]bool)
+		for _, prop := range features[i].Properties {
+			prop, ok := prop.(*ofp.TablePropNextTables)
+			if !ok {
+				continue
+			}
+
+			for _, next := range prop.NextTables {
+				if !seen[next] {
+					seen[next] = true
+					p.next[table] = append(p.next[table], next)
+				}
+			}
+		}
+	}
+
+	sort.Slice(p.tables, func(i, j int) bool {
+		return p.tables[i] < p.tables[j]
+	})
+
+	return p
+}
+
+// Tables returns the identifiers of the tables in ascending order.
+func (p *Pipeline) Tables() []ofp.Table {
+	return append([]ofp.Table(nil), p.tables...)
+}
+
+// Features returns the features of the specified table. When the table
+// does not exist, false is returned.
+func (p *Pipeline) Features(table ofp.Table) (*ofp.TableFeatures, bool) {
+	features, ok := p.features[table]
+	return features, ok
+}
+
+// NextTables returns the list of tables that can be directly reached
+// from the specified table using the goto-table instruction.
+func (p *Pipeline) NextTables(table ofp.Table) []ofp.Table {
+	return append([]ofp.Table(nil), p.next[table]...)
+}
+
+// PathExists reports whether the packet can be passed from one table
+// to another using one or more goto-table instructions. Each existing
+// table is reachable from itself.
+func (p *Pipeline) PathExists(from, to ofp.Table) bool {
+	if _, ok := p.features[from]; !ok {
+		return false
+	}
+
+	visited := map[ofp.Table]bool{from: true}
+	queue := []ofp.Table{from}
+
+	for len(queue) > 0 {
+		table := queue[0]
+		queue = queue[1:]
+
+		if table == to {
+			return true
+		}
+
+		for _, next := range p.next[table] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return false
+}
+
+// Supports reports whether the specified table is able to match all
+// given fields. Only the class and type of the fields are compared.
+func (p *Pipeline) Supports(table ofp.Table, fields ...ofp.XM) bool {
+	features, ok := p.features[table]
+	if !ok {
+		return false
+	}
+
+	type key struct {
+		class ofp.XMClass
+		typ   ofp.XMType
+	}
+
+	supported := make(map[key]bool)
+	for _, prop := range features.Properties {
+		if prop, ok := prop.(*ofp.TablePropMatch); ok {
+			for _, xm := range prop.Fields {
+				supported[key{xm.Class, xm.Type}] = true
+			}
+		}
+	}
+
+	for _, xm := range fields {
+		if !supported[key{xm.Class, xm.Type}] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FirstTableSupporting returns the table with the lowest identifier
+// that is able to match all given fields and is reachable from the
+// first table of the pipeline. When there is no such table, false is
+// returned.
+func (p *Pipeline) FirstTableSupporting(fields ...ofp.XM) (ofp.Table, bool) {
+	if len(p.tables) == 0 {
+		return 0, false
+	}
+
+	first := p.tables[0]
+	for _, table := range p.tables {
+		if p.Supports(table, fields...) && p.PathExists(first, table) {
+			return table, true
+		}
+	}
+
+	return 0, false
+}
+
+// GotoTableError is returned when the goto-table instruction of the flow
+// targets a table with the same or lower identifier, which could result
+// in the processing loops and is forbidden by the specification, or the
+// table not listed in the next tables of the pipeline.
+type GotoTableError struct {
+	// Flow is the flow modification with invalid instruction.
+	Flow *ofp.FlowMod
+
+	// Table is a target table of the goto-table instruction.
+	Table ofp.Table
+
+	// Unreachable is set when the table is not listed in the next
+	// tables of the flow table.
+	Unreachable bool
+}
+
+// Error implements error interface.
+func (e *GotoTableError) Error() string {
+	if e.Unreachable {
+		return fmt.Sprintf("ofputil: table %d is not reachable from "+
+			"table %d", e.Table, e.Flow.Table)
+	}
+
+	return fmt.Sprintf("ofputil: goto-table from table %d to table %d "+
+		"is not forward", e.Flow.Table, e.Table)
+}
+
+// Unwrap returns the error the switch replies with to the same flow.
+func (e *GotoTableError) Unwrap() error {
+	return ofp.Error{
+		Type: ofp.ErrTypeBadInstruction,
+		Code: ofp.ErrCodeBadInstructionTableID,
+	}
+}
+
+// CheckGotoTables validates the goto-table instructions of the given
+// flows before installation. Since each instruction must target a table
+// with the higher identifier, the loops in the chains of the tables are
+// not possible when the validation succeeds. The first invalid flow is
+// returned as *GotoTableError.
+func CheckGotoTables(flows ...*ofp.FlowMod) error {
+	return checkGotoTables(flows, nil)
+}
+
+// CheckGotoTables validates the goto-table instructions of the given
+// flows like CheckGotoTables function, additionally the target tables
+// must be listed in the next tables of the flow tables.
+func (p *Pipeline) CheckGotoTables(flows ...*ofp.FlowMod) error {
+	return checkGotoTables(flows, func(from, to ofp.Table) bool {
+		if _, ok := p.features[from]; !ok {
+			return true
+		}
+
+		for _, next := range p.next[from] {
+			if next == to {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// checkGotoTables validates the goto-table instructions of the flows,
+// reachable reports whether the target table is reachable.
+func checkGotoTables(flows []*ofp.FlowMod,
+	reachable func(from, to ofp.Table) bool) error {
+
+	for _, flow := range flows {
+		for _, inst := range flow.Instructions {
+			inst, ok := inst.(*ofp.InstructionGotoTable)
+			if !ok {
+				continue
+			}
+
+			if inst.Table <= flow.Table {
+				return &GotoTableError{Flow: flow, Table: inst.Table}
+			}
+
+			if reachable != nil && !reachable(flow.Table, inst.Table) {
+				return &GotoTableError{Flow: flow, Table: inst.Table,
+					Unreachable: true}
+			}
+		}
+	}
+
+	return nil
+}
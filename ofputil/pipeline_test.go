@@ -0,0 +1,138 @@
+package ofputil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestPipeline(t *testing.T) {
+	inPort := ofp.XM{Class: ofp.XMClassOpenflowBasic, Type: ofp.XMTypeInPort}
+	ethType := ofp.XM{Class: ofp.XMClassOpenflowBasic, Type: ofp.XMTypeEthType}
+	ipv4Dst := ofp.XM{Class: ofp.XMClassOpenflowBasic, Type: ofp.XMTypeIPv4Dst}
+
+	features := []ofp.TableFeatures{
+		{Table: 0, Properties: []ofp.TableProp{
+			&ofp.TablePropMatch{Fields: []ofp.XM{inPort, ethType}},
+			&ofp.TablePropNextTables{NextTables: []ofp.Table{1}},
+			&ofp.TablePropNextTables{Miss: true, NextTables: []ofp.Table{1, 2}},
+		}},
+		{Table: 2, Properties: []ofp.TableProp{
+			&ofp.TablePropMatch{Fields: []ofp.XM{ethType, ipv4Dst}},
+		}},
+		{Table: 1, Properties: []ofp.TableProp{
+			&ofp.TablePropMatch{Fields: []ofp.XM{ethType}},
+			&ofp.TablePropNextTables{NextTables: []ofp.Table{2}},
+		}},
+		{Table: 3, Properties: []ofp.TableProp{
+			&ofp.TablePropMatch{Fields: []ofp.XM{inPort, ipv4Dst}},
+		}},
+	}
+
+	p := NewPipeline(features)
+
+	tables := []ofp.Table{0, 1, 2, 3}
+	if !reflect.DeepEqual(p.Tables(), tables) {
+		t.Fatalf("Invalid list of tables: %v", p.Tables())
+	}
+
+	if next := p.NextTables(0); !reflect.DeepEqual(next, []ofp.Table{1, 2}) {
+		t.Fatalf("Invalid list of next tables: %v", next)
+	}
+
+	paths := []struct {
+		From, To ofp.Table
+		Exists   bool
+	}{
+		{0, 0, true},
+		{0, 2, true},
+		{1, 2, true},
+		{2, 1, false},
+		{0, 3, false},
+		{4, 4, false},
+	}
+
+	for _, path := range paths {
+		if p.PathExists(path.From, path.To) != path.Exists {
+			t.Errorf("Invalid path existence from %d to %d, expected %v",
+				path.From, path.To, path.Exists)
+		}
+	}
+
+	placements := []struct {
+		Fields []ofp.XM
+		Table  ofp.Table
+		Found  bool
+	}{
+		{[]ofp.XM{ethType}, 0, true},
+		{[]ofp.XM{ipv4Dst}, 2, true},
+		{[]ofp.XM{ethType, ipv4Dst}, 2, true},
+		// Table 3 is not reachable from the first table.
+		{[]ofp.XM{inPort, ipv4Dst}, 0, false},
+	}
+
+	for _, placement := range placements {
+		table, ok := p.FirstTableSupporting(placement.Fields...)
+		if ok != placement.Found || table != placement.Table {
+			t.Errorf("Invalid table found for %v: %d, %v",
+				placement.Fields, table, ok)
+		}
+	}
+}
+
+func TestCheckGotoTables(t *testing.T) {
+	gotoTable := func(from, to ofp.Table) *ofp.FlowMod {
+		return &ofp.FlowMod{Table: from, Instructions: ofp.Instructions{
+			&ofp.InstructionGotoTable{Table: to},
+		}}
+	}
+
+	tests := []struct {
+		Flow        *ofp.FlowMod
+		Valid       bool
+		Unreachable bool
+	}{
+		{gotoTable(0, 1), true, false},
+		{&ofp.FlowMod{Table: 3}, true, false},
+		{gotoTable(1, 1), false, false},
+		{gotoTable(2, 0), false, false},
+		{gotoTable(1, 3), false, true},
+		// Tables without features are not checked for reachability.
+		{gotoTable(5, 6), true, false},
+	}
+
+	p := NewPipeline([]ofp.TableFeatures{
+		{Table: 0, Properties: []ofp.TableProp{
+			&ofp.TablePropNextTables{NextTables: []ofp.Table{1}},
+		}},
+		{Table: 1, Properties: []ofp.TableProp{
+			&ofp.TablePropNextTables{NextTables: []ofp.Table{2}},
+		}},
+	})
+
+	for i, test := range tests {
+		err := p.CheckGotoTables(test.Flow)
+		if test.Valid {
+			if err != nil {
+				t.Errorf("Flow %d must be valid: %s", i, err)
+			}
+			continue
+		}
+
+		gerr, ok := err.(*GotoTableError)
+		if !ok || gerr.Unreachable != test.Unreachable {
+			t.Errorf("Goto table error expected for flow %d: %v", i, err)
+		}
+
+		var oerr ofp.Error
+		if !errors.As(err, &oerr) || oerr.Code != ofp.ErrCodeBadInstructionTableID {
+			t.Errorf("Bad table error expected for flow %d: %v", i, err)
+		}
+	}
+
+	if err := CheckGotoTables(gotoTable(1, 3), gotoTable(3, 2)); err == nil {
+		t.Errorf("Backward jump must be rejected")
+	}
+}
@@ -0,0 +1,222 @@
+package ofputil
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+var (
+	// ErrMetadataRange is returned when the value does not fit into
+	// the bits of the metadata field.
+	ErrMetadataRange = errors.New("ofputil: value exceeds metadata field")
+
+	// ErrMetadataConflict is returned when the metadata bits are set
+	// twice to the different values.
+	ErrMetadataConflict = errors.New("ofputil: conflicting metadata bits")
+
+	// ErrMetadataExhausted is returned when there are no free bits
+	// left in the metadata to allocate the field.
+	ErrMetadataExhausted = errors.New("ofputil: metadata bits exhausted")
+
+	// ErrMetadataField is returned on attempt to allocate the metadata
+	// field with the name of already allocated field.
+	ErrMetadataField = errors.New("ofputil: metadata field already allocated")
+)
+
+// MatchMetadata creates an Openflow basic extensible match of the
+// metadata passed between the tables of the pipeline.
+func MatchMetadata(metadata uint64) ofp.XM {
+	return basic(ofp.XMTypeMetadata, bytesOf(metadata), nil)
+}
+
+// MatchMetadataMasked creates an Openflow basic extensible match of the
+// pipeline metadata with the given bitmask.
+func MatchMetadataMasked(metadata, mask uint64) ofp.XM {
+	return basic(ofp.XMTypeMetadata, bytesOf(metadata), bytesOf(mask))
+}
+
+// WriteMetadata returns an instruction writing the masked bits of the
+// pipeline metadata passed to the next table.
+func WriteMetadata(metadata, mask uint64) *ofp.InstructionWriteMetadata {
+	return &ofp.InstructionWriteMetadata{Metadata: metadata, MetadataMask: mask}
+}
+
+// MetadataBits is a set of the pipeline metadata bits, the value bits
+// not covered by the mask are ignored.
+//
+// For example, to write the tenant and the zone identifiers within a
+// single instruction:
+//
+//	var bits ofputil.MetadataBits
+//	bits.Set(tenant<<8, 0xff00)
+//	bits.Set(zone, 0x00ff)
+//
+//	fmod.Instructions = ofp.Instructions{bits.Instruction()}
+type MetadataBits struct {
+	Value uint64
+	Mask  uint64
+}
+
+// Set sets the masked bits of the value. ErrMetadataConflict is
+// returned when some of the bits were already set to another value,
+// in that case the bits are left unchanged.
+func (b *MetadataBits) Set(value, mask uint64) error {
+	value &= mask
+
+	overlap := b.Mask & mask
+	if b.Value&overlap != value&overlap {
+		return fmt.Errorf("%w: 0x%x/0x%x", ErrMetadataConflict, value, mask)
+	}
+
+	b.Value |= value
+	b.Mask |= mask
+	return nil
+}
+
+// Match returns the extensible match of the metadata bits. When all
+// bits are set, the match is not masked.
+func (b MetadataBits) Match() ofp.XM {
+	if b.Mask == ^uint64(0) {
+		return MatchMetadata(b.Value)
+	}
+	return MatchMetadataMasked(b.Value, b.Mask)
+}
+
+// Instruction returns the instruction writing the metadata bits.
+func (b MetadataBits) Instruction() *ofp.InstructionWriteMetadata {
+	return WriteMetadata(b.Value, b.Mask)
+}
+
+// MetadataField is a contiguous range of the pipeline metadata bits.
+type MetadataField struct {
+	// Name is a name of the field, it identifies the module, that
+	// uses the bits of the field.
+	Name string
+
+	// Offset is a position of the least significant bit of the field.
+	Offset uint
+
+	// Width is a number of bits of the field.
+	Width uint
+}
+
+// Mask returns the mask of the metadata bits of the field.
+func (f MetadataField) Mask() uint64 {
+	if f.Width >= 64 {
+		return ^uint64(0)
+	}
+	return (uint64(1)<<f.Width - 1) << f.Offset
+}
+
+// Bits returns the metadata bits of the field set to the given value.
+// ErrMetadataRange is returned when the value does not fit into the
+// field.
+func (f MetadataField) Bits(value uint64) (MetadataBits, error) {
+	if f.Width < 64 && value>>f.Width != 0 {
+		return MetadataBits{}, fmt.Errorf("%w: %s=%d", ErrMetadataRange, f.Name, value)
+	}
+
+	return MetadataBits{Value: value << f.Offset, Mask: f.Mask()}, nil
+}
+
+// Value extracts the value of the field from the metadata.
+func (f MetadataField) Value(metadata uint64) uint64 {
+	return (metadata & f.Mask()) >> f.Offset
+}
+
+// MetadataAllocator allocates the bits of the pipeline metadata to the
+// fields of the modules of the controller, so the modules programming
+// the different pipeline stages do not overwrite the bits of each other.
+//
+// For example, to pass the tenant identifier between the tables:
+//
+//	tenant, err := allocator.Allocate("tenant", 16)
+//	if err != nil {
+//		return err
+//	}
+//
+//	bits, err := tenant.Bits(42)
+//	...
+//	match := ofputil.ExtendedMatch(bits.Match())
+type MetadataAllocator struct {
+	mu     sync.Mutex
+	used   uint64
+	fields map[string]MetadataField
+}
+
+// NewMetadataAllocator creates a new allocator of the metadata bits.
+// The bits of the reserved mask are never allocated, for example they
+// could be used by the switch or a third-party application.
+func NewMetadataAllocator(reserved uint64) *MetadataAllocator {
+	return &MetadataAllocator{
+		used:   reserved,
+		fields: make(map[string]MetadataField),
+	}
+}
+
+// Allocate allocates the field of the given width from the lowest free
+// contiguous range of the metadata bits.
+func (a *MetadataAllocator) Allocate(name string, width uint) (MetadataField, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.fields[name]; ok {
+		return MetadataField{}, fmt.Errorf("%w: %s", ErrMetadataField, name)
+	}
+
+	for offset := uint(0); width > 0 && offset+width <= 64; offset++ {
+		f := MetadataField{Name: name, Offset: offset, Width: width}
+		if a.used&f.Mask() != 0 {
+			continue
+		}
+
+		a.used |= f.Mask()
+		a.fields[name] = f
+		return f, nil
+	}
+
+	return MetadataField{}, fmt.Errorf("%w: %s requires %d bits",
+		ErrMetadataExhausted, name, width)
+}
+
+// Release returns the bits of the field with the given name back to
+// the allocator.
+func (a *MetadataAllocator) Release(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if f, ok := a.fields[name]; ok {
+		a.used &^= f.Mask()
+		delete(a.fields, name)
+	}
+}
+
+// Field returns the allocated field with the given name.
+func (a *MetadataAllocator) Field(name string) (MetadataField, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, ok := a.fields[name]
+	return f, ok
+}
+
+// Fields returns the allocated fields ordered by the offset.
+func (a *MetadataAllocator) Fields() []MetadataField {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	fields := make([]MetadataField, 0, len(a.fields))
+	for _, f := range a.fields {
+		fields = append(fields, f)
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Offset < fields[j].Offset
+	})
+
+	return fields
+}
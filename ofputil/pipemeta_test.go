@@ -0,0 +1,104 @@
+package ofputil
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestMetadataBits(t *testing.T) {
+	var bits MetadataBits
+	if err := bits.Set(0x1234, 0xff00); err != nil {
+		t.Fatalf("Failed to set metadata bits: %s", err)
+	}
+
+	if err := bits.Set(0x0056, 0x00ff); err != nil {
+		t.Fatalf("Failed to set metadata bits: %s", err)
+	}
+
+	if bits.Value != 0x1256 || bits.Mask != 0xffff {
+		t.Fatalf("Invalid metadata bits: %+v", bits)
+	}
+
+	if err := bits.Set(0x0100, 0x0f00); !errors.Is(err, ErrMetadataConflict) {
+		t.Fatalf("Metadata conflict error expected: %v", err)
+	}
+
+	if err := bits.Set(0x1200, 0x0f00); err != nil {
+		t.Fatalf("Equal bits must not conflict: %s", err)
+	}
+
+	xm := bits.Match()
+	if xm.Type != ofp.XMTypeMetadata ||
+		!bytes.Equal(xm.Value, []byte{0, 0, 0, 0, 0, 0, 0x12, 0x56}) ||
+		!bytes.Equal(xm.Mask, []byte{0, 0, 0, 0, 0, 0, 0xff, 0xff}) {
+		t.Errorf("Invalid metadata match: %v", xm)
+	}
+
+	instr := bits.Instruction()
+	if instr.Metadata != 0x1256 || instr.MetadataMask != 0xffff {
+		t.Errorf("Invalid write metadata instruction: %+v", instr)
+	}
+
+	full := MetadataBits{Value: 1, Mask: ^uint64(0)}
+	if xm = full.Match(); xm.Mask != nil {
+		t.Errorf("Match of all bits must not be masked: %v", xm)
+	}
+}
+
+func TestMetadataAllocator(t *testing.T) {
+	a := NewMetadataAllocator(0xff)
+
+	tenant, err := a.Allocate("tenant", 16)
+	if err != nil {
+		t.Fatalf("Failed to allocate field: %s", err)
+	}
+
+	if tenant.Offset != 8 || tenant.Mask() != 0xffff00 {
+		t.Fatalf("Invalid allocated field: %+v", tenant)
+	}
+
+	if _, err = a.Allocate("tenant", 4); !errors.Is(err, ErrMetadataField) {
+		t.Fatalf("Duplicate field error expected: %v", err)
+	}
+
+	zone, err := a.Allocate("zone", 4)
+	if err != nil || zone.Offset != 24 {
+		t.Fatalf("Failed to allocate field: %v, %+v", err, zone)
+	}
+
+	if _, err = a.Allocate("large", 40); !errors.Is(err, ErrMetadataExhausted) {
+		t.Fatalf("Exhausted error expected: %v", err)
+	}
+
+	a.Release("tenant")
+	if _, ok := a.Field("tenant"); ok {
+		t.Fatalf("Released field must be removed")
+	}
+
+	small, _ := a.Allocate("small", 8)
+	if small.Offset != 8 {
+		t.Errorf("Released bits must be reused: %+v", small)
+	}
+
+	expected := []MetadataField{small, zone}
+	if fields := a.Fields(); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Invalid allocated fields: %v", fields)
+	}
+
+	bits, err := zone.Bits(5)
+	if err != nil || bits.Value != 5<<24 || bits.Mask != 0xf<<24 {
+		t.Fatalf("Invalid field bits: %v, %+v", err, bits)
+	}
+
+	if zone.Value(bits.Value|0xff) != 5 {
+		t.Errorf("Invalid field value: %d", zone.Value(bits.Value))
+	}
+
+	if _, err = zone.Bits(16); !errors.Is(err, ErrMetadataRange) {
+		t.Errorf("Range error expected: %v", err)
+	}
+}
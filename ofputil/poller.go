@@ -0,0 +1,59 @@
+package ofputil
+
+import (
+	"context"
+	"time"
+
+	of "github.com/netrack/openflow"
+)
+
+// StatsPoller sends the statistics request to the switch each interval.
+// The replies are received as any other message of the connection, so
+// they are processed by the handler of the server, for example to
+// compute the rates of the ports with PortStatsRate:
+//
+//	poller := &ofputil.StatsPoller{
+//		Conn:     conn,
+//		Interval: 10 * time.Second,
+//		Request: func() *of.Request {
+//			body := ofp.NewMultipartRequest(ofp.MultipartTypePortStats,
+//				&ofp.PortStatsRequest{PortNo: ofp.PortAny})
+//			return of.NewRequest(of.TypeMultipartRequest, body)
+//		},
+//	}
+//
+//	go poller.Run(ctx)
+type StatsPoller struct {
+	// Conn is a connection to the switch.
+	Conn of.Conn
+
+	// Interval is a period of the statistics requests.
+	Interval time.Duration
+
+	// Request returns a new statistics request on each poll, since the
+	// body of the request is consumed, when the request is sent.
+	Request func() *of.Request
+
+	// Clock is used to schedule the statistics requests. When not
+	// defined, the system clock is used.
+	Clock of.Clock
+}
+
+// Run sends the statistics request each interval until the context is
+// canceled or the request could not be sent.
+func (p *StatsPoller) Run(ctx context.Context) error {
+	ticker := clockOrSystem(p.Clock).NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+
+		if err := of.Send(p.Conn, p.Request()); err != nil {
+			return err
+		}
+	}
+}
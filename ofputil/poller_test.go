@@ -0,0 +1,80 @@
+package ofputil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestStatsPoller(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sw := of.NewConn(server)
+	defer sw.Close()
+
+	received := make(chan *of.Request, 8)
+	go func() {
+		for {
+			r, err := sw.Receive()
+			if err != nil {
+				return
+			}
+			received <- r
+		}
+	}()
+
+	clock := ofptest.NewClock(time.Unix(0, 0))
+	poller := &StatsPoller{
+		Conn:     of.NewConn(client),
+		Interval: 10 * time.Second,
+		Clock:    clock,
+		Request: func() *of.Request {
+			body := ofp.NewMultipartRequest(ofp.MultipartTypePortStats,
+				&ofp.PortStatsRequest{PortNo: ofp.PortAny})
+			return of.NewRequest(of.TypeMultipartRequest, body)
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- poller.Run(ctx) }()
+
+	clock.BlockUntil(1)
+	for i := 0; i < 3; i++ {
+		// No requests are sent until the interval elapses.
+		clock.Advance(5 * time.Second)
+		select {
+		case r := <-received:
+			t.Fatalf("Unexpected request before the interval: %s", r.Header.Type)
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		clock.Advance(5 * time.Second)
+
+		r := <-received
+		if r.Header.Type != of.TypeMultipartRequest {
+			t.Fatalf("Multipart request expected: %s", r.Header.Type)
+		}
+
+		var req ofp.MultipartRequest
+		if _, err := req.ReadFrom(r.Body); err != nil {
+			t.Fatalf("Failed to decode multipart request: %s", err)
+		}
+
+		if req.Type != ofp.MultipartTypePortStats {
+			t.Errorf("Port statistics request expected: %v", req.Type)
+		}
+	}
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Context error expected: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package ofputil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+// CounterUnsupported is a value of the statistics counter, that is not
+// supported by the switch. The specification requires the switches to
+// set all bits of the unavailable counters.
+const CounterUnsupported = ^uint64(0)
+
+var (
+	// ErrPortMismatch is returned when the rates are computed from
+	// the statistics of the different ports.
+	ErrPortMismatch = errors.New("ofputil: statistics of different ports")
+
+	// ErrNoInterval is returned when the interval between the port
+	// statistics samples could not be determined.
+	ErrNoInterval = errors.New("ofputil: unknown interval between samples")
+)
+
+// CounterDelta returns the increment of the counter between the two
+// samples. The wrap of the 64-bit counter is handled by the modular
+// arithmetic. The second value is false when any of the samples is the
+// unsupported counter.
+func CounterDelta(prev, cur uint64) (uint64, bool) {
+	if prev == CounterUnsupported || cur == CounterUnsupported {
+		return 0, false
+	}
+
+	return cur - prev, true
+}
+
+// PortRate is a rate of the port statistics counters per second. The
+// rates of the counters not supported by the switch are NaN.
+type PortRate struct {
+	// PortNo is a number of the port.
+	PortNo ofp.PortNo
+
+	// Interval is a time between the statistics samples.
+	Interval time.Duration
+
+	// Reset is true, when the counters of the port were reset between
+	// the samples, so the rates are computed from the latest sample.
+	Reset bool
+
+	RxPackets float64
+	TxPackets float64
+	RxBytes   float64
+	TxBytes   float64
+	RxDropped float64
+	TxDropped float64
+	RxErrors  float64
+	TxErrors  float64
+}
+
+// portDuration returns the time the port has been alive.
+func portDuration(s *ofp.PortStats) time.Duration {
+	return time.Duration(s.DurationSec)*time.Second +
+		time.Duration(s.DurationNSec)
+}
+
+// PortStatsRate computes the rates of the port counters from the two
+// successive statistics samples of the same port.
+//
+// The interval between the samples is computed from the durations of
+// the port reported by the switch. When the duration does not advance,
+// for example the switch does not report it, the given interval is used
+// instead. When the duration of the port decreases, the port was
+// re-created and the counters are treated as reset.
+//
+// For example, to compute the receive bandwidth of the port polled each
+// ten seconds:
+//
+//	rate, err := ofputil.PortStatsRate(prev, cur, 10*time.Second)
+//	if err != nil {
+//		return err
+//	}
+//
+//	log.Printf("port %d: %.0f bit/s", rate.PortNo, rate.RxBytes*8)
+func PortStatsRate(prev, cur *ofp.PortStats, interval time.Duration) (PortRate, error) {
+	rate := PortRate{PortNo: cur.PortNo}
+	if prev.PortNo != cur.PortNo {
+		return rate, fmt.Errorf("%w: %d and %d",
+			ErrPortMismatch, prev.PortNo, cur.PortNo)
+	}
+
+	from, to := portDuration(prev), portDuration(cur)
+	base := *prev
+
+	switch {
+	case to < from:
+		// The port was re-created, the counters start from zero.
+		rate.Reset = true
+		base = ofp.PortStats{PortNo: cur.PortNo}
+		rate.Interval = to
+	case to > from:
+		rate.Interval = to - from
+	default:
+		rate.Interval = interval
+	}
+
+	if rate.Interval <= 0 {
+		return rate, ErrNoInterval
+	}
+
+	seconds := rate.Interval.Seconds()
+	perSecond := func(prev, cur uint64) float64 {
+		delta, ok := CounterDelta(prev, cur)
+		if !ok {
+			return math.NaN()
+		}
+		return float64(delta) / seconds
+	}
+
+	rate.RxPackets = perSecond(base.RxPackets, cur.RxPackets)
+	rate.TxPackets = perSecond(base.TxPackets, cur.TxPackets)
+	rate.RxBytes = perSecond(base.RxBytes, cur.RxBytes)
+	rate.TxBytes = perSecond(base.TxBytes, cur.TxBytes)
+	rate.RxDropped = perSecond(base.RxDropped, cur.RxDropped)
+	rate.TxDropped = perSecond(base.TxDropped, cur.TxDropped)
+	rate.RxErrors = perSecond(base.RxErrors, cur.RxErrors)
+	rate.TxErrors = perSecond(base.TxErrors, cur.TxErrors)
+
+	return rate, nil
+}
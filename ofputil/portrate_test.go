@@ -0,0 +1,87 @@
+package ofputil
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestCounterDelta(t *testing.T) {
+	if delta, ok := CounterDelta(10, 25); !ok || delta != 15 {
+		t.Errorf("Invalid counter delta: %d", delta)
+	}
+
+	// The counter wrapped around the 64-bit boundary.
+	if delta, ok := CounterDelta(math.MaxUint64-5, 10); !ok || delta != 16 {
+		t.Errorf("Invalid delta of wrapped counter: %d", delta)
+	}
+
+	if _, ok := CounterDelta(10, CounterUnsupported); ok {
+		t.Errorf("Unsupported counter must be reported")
+	}
+}
+
+func TestPortStatsRate(t *testing.T) {
+	prev := &ofp.PortStats{
+		PortNo:      1,
+		RxBytes:     1000,
+		TxBytes:     math.MaxUint64 - 99,
+		RxErrors:    CounterUnsupported,
+		DurationSec: 10,
+	}
+
+	cur := &ofp.PortStats{
+		PortNo:       1,
+		RxBytes:      3000,
+		TxBytes:      100,
+		RxErrors:     CounterUnsupported,
+		DurationSec:  11,
+		DurationNSec: uint32(time.Second / 2),
+	}
+
+	rate, err := PortStatsRate(prev, cur, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to compute port rate: %s", err)
+	}
+
+	if rate.Interval != 1500*time.Millisecond || rate.Reset {
+		t.Fatalf("Invalid interval of the samples: %v", rate)
+	}
+
+	if rate.RxBytes != 2000/1.5 || rate.TxBytes != 200/1.5 {
+		t.Errorf("Invalid byte rates: %v, %v", rate.RxBytes, rate.TxBytes)
+	}
+
+	if !math.IsNaN(rate.RxErrors) {
+		t.Errorf("Rate of unsupported counter must be NaN: %v", rate.RxErrors)
+	}
+
+	// The port is re-created, the counters start from zero.
+	reset := &ofp.PortStats{PortNo: 1, RxBytes: 400, DurationSec: 2}
+	if rate, err = PortStatsRate(cur, reset, time.Minute); err != nil {
+		t.Fatalf("Failed to compute port rate: %s", err)
+	}
+
+	if !rate.Reset || rate.RxBytes != 200 {
+		t.Errorf("Counters must be reset: %v", rate)
+	}
+
+	// The durations are not reported, the interval is used.
+	nodur := &ofp.PortStats{PortNo: 1, RxBytes: 1000}
+	rate, err = PortStatsRate(&ofp.PortStats{PortNo: 1}, nodur, 10*time.Second)
+	if err != nil || rate.RxBytes != 100 {
+		t.Errorf("Invalid port rate: %v, %v", err, rate)
+	}
+
+	if _, err = PortStatsRate(nodur, nodur, 0); !errors.Is(err, ErrNoInterval) {
+		t.Errorf("Unknown interval error expected: %v", err)
+	}
+
+	other := &ofp.PortStats{PortNo: 2}
+	if _, err = PortStatsRate(nodur, other, time.Second); !errors.Is(err, ErrPortMismatch) {
+		t.Errorf("Port mismatch error expected: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package ofputil
+
+import (
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// SwitchProfile describes the switch emulated with the library. It
+// replies to the features and configuration requests of the controller
+// on behalf of the switch, so the emulators do not have to implement the
+// handshake and configuration boilerplate.
+//
+// For example, to emulate the passive switch with a single table:
+//
+//	profile := &ofputil.SwitchProfile{
+//		DatapathID: 1,
+//		NumTables:  1,
+//		Config:     ofp.SwitchConfig{MissSendLength: 128},
+//	}
+//
+//	of.ListenAndServe(":6634", profile.Handler(mux))
+type SwitchProfile struct {
+	// DatapathID is a datapath identifier of the switch.
+	DatapathID ofp.DatapathID
+
+	// NumBuffers is a maximum number of packets buffered at once.
+	NumBuffers uint32
+
+	// NumTables is a number of tables supported by the switch.
+	NumTables uint8
+
+	// Capabilities is a bitmap of the supported capabilities.
+	Capabilities ofp.Capability
+
+	// Config is an initial configuration of the switch. It is replaced
+	// by the set configuration messages of the controller.
+	Config ofp.SwitchConfig
+
+	mu sync.Mutex
+
+	// config is a current configuration of the switch, it is nil
+	// until the first set configuration message is received.
+	config *ofp.SwitchConfig
+}
+
+// Features returns the features of the switch, that are sent in the
+// features reply.
+func (p *SwitchProfile) Features() *ofp.SwitchFeatures {
+	return &ofp.SwitchFeatures{
+		DatapathID:   p.DatapathID,
+		NumBuffers:   p.NumBuffers,
+		NumTables:    p.NumTables,
+		Capabilities: p.Capabilities,
+	}
+}
+
+// CurrentConfig returns the current configuration of the switch.
+func (p *SwitchProfile) CurrentConfig() ofp.SwitchConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.config == nil {
+		return p.Config
+	}
+
+	return *p.config
+}
+
+// Handler returns a handler, that replies to the features and get
+// configuration requests and applies the set configuration messages.
+// The rest of the messages are passed to the optional handler h.
+func (p *SwitchProfile) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		switch r.Header.Type {
+		case of.TypeFeaturesRequest:
+			r.Reply(rw, p.Features(), of.TypeFeaturesReply)
+		case of.TypeGetConfigRequest:
+			config := p.CurrentConfig()
+			r.Reply(rw, &config, of.TypeGetConfigReply)
+		case of.TypeSetConfig:
+			var config ofp.SwitchConfig
+			if _, err := config.ReadFrom(r.Body); err != nil {
+				text := "ofputil: failed to read the message: %v"
+				Logf(r, text, err)
+				return
+			}
+
+			p.mu.Lock()
+			p.config = &config
+			p.mu.Unlock()
+		default:
+			if h != nil {
+				h.Serve(rw, r)
+			}
+		}
+	})
+}
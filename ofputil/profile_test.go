@@ -0,0 +1,66 @@
+package ofputil
+
+import (
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestSwitchProfile(t *testing.T) {
+	profile := &SwitchProfile{
+		DatapathID:   42,
+		NumBuffers:   256,
+		NumTables:    4,
+		Capabilities: ofp.CapabilityFlowStats,
+		Config:       ofp.SwitchConfig{MissSendLength: 128},
+	}
+
+	var served []of.Type
+	h := profile.Handler(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		served = append(served, r.Header.Type)
+	}))
+
+	rw := ofptest.NewRecorder()
+
+	req := of.NewRequest(of.TypeFeaturesRequest, nil)
+	req.Header.Transaction = 7
+	h.Serve(rw, req)
+
+	h.Serve(rw, of.NewRequest(of.TypeSetConfig,
+		&ofp.SwitchConfig{Flags: ofp.ConfigFlagFragDrop, MissSendLength: 64}))
+	h.Serve(rw, of.NewRequest(of.TypeGetConfigRequest, nil))
+	h.Serve(rw, of.NewRequest(of.TypeBarrierRequest, nil))
+
+	err := rw.ExpectTypes(of.TypeFeaturesReply, of.TypeGetConfigReply)
+	if err != nil {
+		t.Fatalf("Failed to reply to the requests: %s", err)
+	}
+
+	if xid := rw.First().Header.Transaction; xid != 7 {
+		t.Errorf("Transaction identifier changed: %d", xid)
+	}
+
+	var features ofp.SwitchFeatures
+	if err = rw.Decode(0, &features); err != nil {
+		t.Fatalf("Failed to decode features reply: %s", err)
+	}
+
+	if features.DatapathID != 42 || features.NumTables != 4 {
+		t.Errorf("Invalid features reply: %v", features)
+	}
+
+	var config ofp.SwitchConfig
+	if err = rw.Decode(1, &config); err != nil {
+		t.Fatalf("Failed to decode configuration reply: %s", err)
+	}
+
+	if config.MissSendLength != 64 || config.Flags != ofp.ConfigFlagFragDrop {
+		t.Errorf("Configuration must be updated: %v", config)
+	}
+
+	if len(served) != 1 || served[0] != of.TypeBarrierRequest {
+		t.Errorf("Unhandled messages must be passed to handler: %v", served)
+	}
+}
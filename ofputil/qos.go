@@ -0,0 +1,302 @@
+package ofputil
+
+import (
+	"errors"
+	"fmt"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+var (
+	// ErrQueueNotFound is returned when the queue referenced by the QoS
+	// rule is not configured on the output port.
+	ErrQueueNotFound = errors.New("ofputil: queue is not configured")
+
+	// ErrMeterUnsupported is returned when the meter of the QoS rule
+	// is not supported by the meter features of the switch.
+	ErrMeterUnsupported = errors.New("ofputil: meter is not supported")
+)
+
+// QoSRule is an intent to forward the matching packets through the
+// queue of the output port, optionally remarking the VLAN priority and
+// DSCP of the packets and limiting the rate of the traffic with meter.
+type QoSRule struct {
+	// Table is a table of the flow entry.
+	Table ofp.Table
+
+	// Priority is a priority of the flow entry.
+	Priority uint16
+
+	// Match selects the packets of the rule.
+	Match ofp.Match
+
+	// Port is an output port of the packets.
+	Port ofp.PortNo
+
+	// Queue is a queue of the output port, the queue must be configured
+	// on the port out of the OpenFlow channel, e.g. through OVSDB.
+	Queue ofp.Queue
+
+	// RemarkPCP rewrites the VLAN priority of the packets with PCP.
+	RemarkPCP bool
+	PCP       uint8
+
+	// RemarkDSCP rewrites the IP DSCP of the packets with DSCP.
+	RemarkDSCP bool
+	DSCP       uint8
+
+	// Meter is a meter limiting the rate of the packets. The meter is
+	// installed, when the Rate is not zero.
+	Meter ofp.Meter
+
+	// Rate is a rate of the meter in kilo-bits per second, the packets
+	// exceeding the rate are dropped or their drop precedence is
+	// increased by the PrecLevel.
+	Rate      uint32
+	BurstSize uint32
+	PrecLevel uint8
+}
+
+// band returns the meter band of the rule.
+func (r *QoSRule) band() ofp.MeterBand {
+	if r.PrecLevel == 0 {
+		return &ofp.MeterBandDrop{Rate: r.Rate, BurstSize: r.BurstSize}
+	}
+
+	return &ofp.MeterBandDSCPRemark{
+		Rate:      r.Rate,
+		BurstSize: r.BurstSize,
+		PrecLevel: r.PrecLevel,
+	}
+}
+
+// meterMod returns the meter modification of the rule.
+func (r *QoSRule) meterMod() *ofp.MeterMod {
+	flags := ofp.MeterFlagKBitPerSec
+	if r.BurstSize != 0 {
+		flags |= ofp.MeterFlagBurst
+	}
+
+	return &ofp.MeterMod{
+		Command: ofp.MeterAdd,
+		Flags:   flags,
+		Meter:   r.Meter,
+		Bands:   ofp.MeterBands{r.band()},
+	}
+}
+
+// flowMod returns the flow modification of the rule.
+func (r *QoSRule) flowMod() (*ofp.FlowMod, error) {
+	var actions ofp.Actions
+
+	if r.RemarkPCP {
+		action, err := SetVlanPCP(r.PCP)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	if r.RemarkDSCP {
+		action, err := SetIPDSCP(r.DSCP)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	actions = append(actions,
+		&ofp.ActionSetQueue{QueueID: r.Queue},
+		&ofp.ActionOutput{Port: r.Port, MaxLen: ofp.ContentLenNoBuffer})
+
+	var instructions ofp.Instructions
+	if r.Rate != 0 {
+		instructions = append(instructions, &ofp.InstructionMeter{Meter: r.Meter})
+	}
+
+	instructions = append(instructions, ActionsApply(actions...)...)
+
+	return &ofp.FlowMod{
+		Table:        r.Table,
+		Command:      ofp.FlowAdd,
+		Priority:     r.Priority,
+		Buffer:       ofp.NoBuffer,
+		OutPort:      ofp.PortAny,
+		OutGroup:     ofp.GroupAny,
+		Match:        r.Match,
+		Instructions: instructions,
+	}, nil
+}
+
+// QoSPolicy translates the QoS rules into the meter and flow
+// modifications. The rules are validated against the meter features
+// and the queues of the switch, when they are defined.
+//
+// For example, to forward the voice traffic through the first queue of
+// the second port and remark it with the expedited forwarding DSCP:
+//
+//	policy := &ofputil.QoSPolicy{
+//		MeterFeatures: features,
+//		Queues:        queues,
+//		Rules: []ofputil.QoSRule{{
+//			Priority:   100,
+//			Match:      voice,
+//			Port:       2,
+//			Queue:      1,
+//			RemarkDSCP: true,
+//			DSCP:       46,
+//		}},
+//	}
+//
+//	reqs, err := policy.Requests()
+type QoSPolicy struct {
+	// MeterFeatures are the meter features of the switch. When nil,
+	// the meters are not validated. The rules with meters are rejected
+	// with ErrNotSupported, when the switch does not support meters.
+	MeterFeatures *ofp.MeterFeatures
+
+	// Queues is a list of the queues configured on the ports of the
+	// switch (see QueueDescs). When nil, the queues are not validated.
+	Queues []ofp.QueueDesc
+
+	// Rules is a list of QoS rules.
+	Rules []QoSRule
+}
+
+// Requests returns the meter modifications followed by the flow
+// modifications implementing the QoS rules of the policy.
+func (p *QoSPolicy) Requests() ([]*of.Request, error) {
+	var meters, flows []*of.Request
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+
+		if err := p.checkQueue(rule); err != nil {
+			return nil, err
+		}
+
+		if rule.Rate != 0 {
+			if err := p.checkMeter(rule); err != nil {
+				return nil, err
+			}
+
+			meters = append(meters, of.NewRequest(
+				of.TypeMeterMod, rule.meterMod()))
+		}
+
+		mod, err := rule.flowMod()
+		if err != nil {
+			return nil, err
+		}
+
+		flows = append(flows, of.NewRequest(of.TypeFlowMod, mod))
+	}
+
+	return append(meters, flows...), nil
+}
+
+// checkQueue validates that the queue of the rule is configured on the
+// output port.
+func (p *QoSPolicy) checkQueue(rule *QoSRule) error {
+	if p.Queues == nil {
+		return nil
+	}
+
+	for _, queue := range p.Queues {
+		if queue.Port == rule.Port && queue.Queue == rule.Queue {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: queue %d on port %d",
+		ErrQueueNotFound, rule.Queue, rule.Port)
+}
+
+// checkMeter validates that the meter of the rule is supported by the
+// meter features of the switch.
+func (p *QoSPolicy) checkMeter(rule *QoSRule) error {
+	features := p.MeterFeatures
+
+	newError := func(format string, v ...interface{}) error {
+		return fmt.Errorf("%w: meter %d: %s", ErrMeterUnsupported,
+			rule.Meter, fmt.Sprintf(format, v...))
+	}
+
+	if rule.Meter == 0 || rule.Meter > ofp.MeterMax {
+		return newError("invalid meter identifier")
+	}
+
+	if features == nil {
+		return nil
+	}
+
+	caps := SwitchCapabilities{Meters: features}
+	if err := caps.CheckMeters(); err != nil {
+		return err
+	}
+
+	mod := rule.meterMod()
+	band := mod.Bands[0].Type()
+
+	switch {
+	case uint32(rule.Meter) > features.MaxMeter:
+		return newError("exceeds %d meters", features.MaxMeter)
+	case features.MaxBands == 0:
+		return newError("bands are not supported")
+	case features.BandTypes&(1<<band) == 0:
+		return newError("band type %d is not supported", band)
+	case features.Capabilities&uint32(mod.Flags) != uint32(mod.Flags):
+		return newError("flags 0x%x are not supported", mod.Flags)
+	}
+
+	return nil
+}
+
+// QoSRuleOf interprets the flow entry as a QoS rule. It returns false,
+// when the flow entry does not forward the packets through the queue.
+// The rate of the meter is not defined in the flow entry and is left
+// zero.
+func QoSRuleOf(flow *ofp.FlowStats) (QoSRule, bool) {
+	rule := QoSRule{
+		Table:    flow.Table,
+		Priority: flow.Priority,
+		Match:    flow.Match,
+	}
+
+	var queued bool
+	for _, instruction := range flow.Instructions {
+		switch instruction := instruction.(type) {
+		case *ofp.InstructionMeter:
+			rule.Meter = instruction.Meter
+		case *ofp.InstructionApplyActions:
+			for _, action := range instruction.Actions {
+				switch action := action.(type) {
+				case *ofp.ActionSetQueue:
+					rule.Queue, queued = action.QueueID, true
+				case *ofp.ActionOutput:
+					rule.Port = action.Port
+				case *ofp.ActionSetField:
+					qosRemark(&rule, action.Field)
+				}
+			}
+		}
+	}
+
+	return rule, queued
+}
+
+// qosRemark sets the remarked field of the rule from the set-field
+// action.
+func qosRemark(rule *QoSRule, xm ofp.XM) {
+	if xm.Class != ofp.XMClassOpenflowBasic || len(xm.Value) != 1 {
+		return
+	}
+
+	switch xm.Type {
+	case ofp.XMTypeVlanPCP:
+		rule.RemarkPCP, rule.PCP = true, xm.Value.UInt8()
+	case ofp.XMTypeIPDSCP:
+		rule.RemarkDSCP, rule.DSCP = true, xm.Value.UInt8()
+	}
+}
@@ -0,0 +1,105 @@
+package ofputil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestQoSPolicy(t *testing.T) {
+	policy := &QoSPolicy{
+		MeterFeatures: &ofp.MeterFeatures{
+			MaxMeter:     16,
+			BandTypes:    1 << ofp.MeterBandTypeDrop,
+			Capabilities: uint32(ofp.MeterFlagKBitPerSec),
+			MaxBands:     1,
+		},
+		Queues: []ofp.QueueDesc{{Port: 2, Queue: 1}},
+		Rules: []QoSRule{{
+			Priority:   100,
+			Match:      ExtendedMatch(MatchEthType(0x0800)),
+			Port:       2,
+			Queue:      1,
+			RemarkPCP:  true,
+			PCP:        5,
+			RemarkDSCP: true,
+			DSCP:       46,
+			Meter:      3,
+			Rate:       1000,
+		}},
+	}
+
+	reqs, err := policy.Requests()
+	if err != nil {
+		t.Fatalf("Failed to create QoS requests: %s", err)
+	}
+
+	if len(reqs) != 2 || reqs[0].Header.Type != of.TypeMeterMod ||
+		reqs[1].Header.Type != of.TypeFlowMod {
+		t.Fatalf("Meter and flow modifications expected: %v", reqs)
+	}
+
+	var fmod ofp.FlowMod
+	if err = reqs[1].Decode(&fmod); err != nil {
+		t.Fatalf("Failed to decode flow modification: %s", err)
+	}
+
+	// The rule must be restored from the installed flow entry.
+	rule, ok := QoSRuleOf(&ofp.FlowStats{
+		Priority:     fmod.Priority,
+		Match:        fmod.Match,
+		Instructions: fmod.Instructions,
+	})
+
+	if !ok {
+		t.Fatalf("Flow entry must be interpreted as QoS rule")
+	}
+
+	expected := policy.Rules[0]
+	expected.Rate = 0
+
+	if !reflect.DeepEqual(rule, expected) {
+		t.Errorf("Invalid QoS rule: %v", rule)
+	}
+}
+
+func TestQoSPolicyValidate(t *testing.T) {
+	features := &ofp.MeterFeatures{
+		MaxMeter:     4,
+		BandTypes:    1 << ofp.MeterBandTypeDrop,
+		Capabilities: uint32(ofp.MeterFlagKBitPerSec),
+		MaxBands:     1,
+	}
+
+	queues := []ofp.QueueDesc{{Port: 1, Queue: 1}}
+
+	tests := []struct {
+		Rule QoSRule
+		Err  error
+	}{
+		{QoSRule{Port: 1, Queue: 2}, ErrQueueNotFound},
+		{QoSRule{Port: 2, Queue: 1}, ErrQueueNotFound},
+		{QoSRule{Port: 1, Queue: 1, Meter: 5, Rate: 1}, ErrMeterUnsupported},
+		{QoSRule{Port: 1, Queue: 1, Rate: 1}, ErrMeterUnsupported},
+		{QoSRule{Port: 1, Queue: 1, Meter: 1, Rate: 1, PrecLevel: 1}, ErrMeterUnsupported},
+		{QoSRule{Port: 1, Queue: 1, Meter: 1, Rate: 1, BurstSize: 1}, ErrMeterUnsupported},
+		{QoSRule{Port: 1, Queue: 1, RemarkPCP: true, PCP: 8}, ErrVlanPCPRange},
+		{QoSRule{Port: 1, Queue: 1, Meter: 1, Rate: 1}, nil},
+	}
+
+	for _, test := range tests {
+		policy := &QoSPolicy{
+			MeterFeatures: features,
+			Queues:        queues,
+			Rules:         []QoSRule{test.Rule},
+		}
+
+		_, err := policy.Requests()
+		if !errors.Is(err, test.Err) || (test.Err == nil) != (err == nil) {
+			t.Errorf("Invalid error returned for %v: %v", test.Rule, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ofputil
+
+import (
+	"fmt"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/internal/encoding"
+	"github.com/netrack/openflow/ofp"
+)
+
+// QueueDescRequest returns a request used to retrieve the configuration
+// of the queues attached to the given port. The type of the request is
+// selected according to the negotiated version of the protocol: the
+// queue description multipart request is used starting from OpenFlow
+// 1.4, and the queue configuration request is used otherwise.
+//
+// The reply on the request could be decoded using QueueDescs function.
+func QueueDescRequest(version uint8, port ofp.PortNo) *of.Request {
+	if version < ofp.Version14 {
+		req := of.NewRequest(of.TypeQueueGetConfigRequest,
+			&ofp.QueueGetConfigRequest{Port: port})
+
+		req.Header.Version = version
+		return req
+	}
+
+	body := &ofp.QueueDescRequest{Port: port, Queue: ofp.QueueAll}
+	req := of.NewRequest(of.TypeMultipartRequest, ofp.NewMultipartRequest(
+		ofp.MultipartTypeQueueDescription, body))
+
+	req.Header.Version = version
+	return req
+}
+
+// QueueDescs decodes the list of queue descriptions from the reply to
+// the QueueDescRequest. The properties of the queues retrieved from the
+// queue configuration reply are converted into the queue description
+// properties, so the caller does not depend on the negotiated version.
+func QueueDescs(r *of.Request) ([]ofp.QueueDesc, error) {
+	switch r.Header.Type {
+	case of.TypeQueueGetConfigReply:
+		var reply ofp.QueueGetConfigReply
+		if _, err := reply.ReadFrom(r.Body); err != nil {
+			return nil, err
+		}
+
+		return queueDescsOf(reply.Queues), nil
+	case of.TypeMultipartReply:
+		var reply ofp.MultipartReply
+		if _, err := reply.ReadFrom(r.Body); err != nil {
+			return nil, err
+		}
+
+		if reply.Type != ofp.MultipartTypeQueueDescription {
+			return nil, fmt.Errorf("ofputil: unexpected multipart type: %s",
+				reply.Type)
+		}
+
+		var queues []ofp.QueueDesc
+		if _, err := encoding.ReadSliceFrom(r.Body, &queues); err != nil {
+			return nil, err
+		}
+
+		return queues, nil
+	}
+
+	return nil, fmt.Errorf("ofputil: unexpected message type: %s",
+		r.Header.Type)
+}
+
+// queueDescsOf converts the packet queues of the OpenFlow 1.3 into the
+// queue descriptions.
+func queueDescsOf(queues []ofp.PacketQueue) []ofp.QueueDesc {
+	descs := make([]ofp.QueueDesc, 0, len(queues))
+
+	for _, queue := range queues {
+		desc := ofp.QueueDesc{Port: queue.Port, Queue: queue.Queue}
+
+		for _, prop := range queue.Properties {
+			switch prop := prop.(type) {
+			case *ofp.QueuePropMinRate:
+				desc.Properties = append(desc.Properties,
+					&ofp.QueueDescPropMinRate{Rate: prop.Rate})
+			case *ofp.QueuePropMaxRate:
+				desc.Properties = append(desc.Properties,
+					&ofp.QueueDescPropMaxRate{Rate: prop.Rate})
+			case *ofp.QueuePropExperimenter:
+				desc.Properties = append(desc.Properties,
+					&ofp.QueueDescPropExperimenter{
+						Experimenter: prop.Experimenter,
+						Data:         prop.Data,
+					})
+			}
+		}
+
+		descs = append(descs, desc)
+	}
+
+	return descs
+}
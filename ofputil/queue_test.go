@@ -0,0 +1,88 @@
+package ofputil
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestQueueDescRequest(t *testing.T) {
+	r := QueueDescRequest(4, ofp.PortNo(1))
+	if r.Header.Type != of.TypeQueueGetConfigRequest {
+		t.Errorf("Invalid type of the request: %s", r.Header.Type)
+	}
+
+	r = QueueDescRequest(5, ofp.PortNo(1))
+	if r.Header.Type != of.TypeMultipartRequest {
+		t.Fatalf("Invalid type of the request: %s", r.Header.Type)
+	}
+
+	if r.Header.Version != 5 {
+		t.Errorf("Invalid version of the request: %d", r.Header.Version)
+	}
+
+	var req ofp.MultipartRequest
+	if _, err := req.ReadFrom(r.Body); err != nil {
+		t.Fatalf("Failed to decode multipart request: %s", err)
+	}
+
+	if req.Type != ofp.MultipartTypeQueueDescription {
+		t.Errorf("Invalid type of the multipart request: %s", req.Type)
+	}
+}
+
+func TestQueueDescs(t *testing.T) {
+	want := []ofp.QueueDesc{{
+		Port:  ofp.PortNo(2),
+		Queue: ofp.Queue(1),
+		Properties: ofp.QueueDescProps{
+			&ofp.QueueDescPropMinRate{Rate: 100},
+			&ofp.QueueDescPropMaxRate{Rate: 500},
+		},
+	}}
+
+	var buf bytes.Buffer
+	config := &ofp.QueueGetConfigReply{
+		Port: ofp.PortNo(2),
+		Queues: []ofp.PacketQueue{{
+			Queue: ofp.Queue(1),
+			Port:  ofp.PortNo(2),
+			Properties: ofp.QueueProps{
+				&ofp.QueuePropMinRate{Rate: 100},
+				&ofp.QueuePropMaxRate{Rate: 500},
+			},
+		}},
+	}
+
+	config.WriteTo(&buf)
+	queues, err := QueueDescs(of.NewRequest(of.TypeQueueGetConfigReply, &buf))
+	if err != nil {
+		t.Fatalf("Failed to decode queue configuration: %s", err)
+	}
+
+	if !reflect.DeepEqual(queues, want) {
+		t.Errorf("Invalid queues returned: %v, expected %v", queues, want)
+	}
+
+	buf.Reset()
+	reply := &ofp.MultipartReply{Type: ofp.MultipartTypeQueueDescription}
+	reply.WriteTo(&buf)
+	want[0].WriteTo(&buf)
+
+	queues, err = QueueDescs(of.NewRequest(of.TypeMultipartReply, &buf))
+	if err != nil {
+		t.Fatalf("Failed to decode queue description: %s", err)
+	}
+
+	if !reflect.DeepEqual(queues, want) {
+		t.Errorf("Invalid queues returned: %v, expected %v", queues, want)
+	}
+
+	r := of.NewRequest(of.TypeEchoReply, nil)
+	if _, err = QueueDescs(r); err == nil {
+		t.Errorf("Error expected for the message of %s type", r.Header.Type)
+	}
+}
@@ -0,0 +1,311 @@
+package ofputil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// Quirks are the workarounds of the defects of the switch firmwares,
+// that deviate from the specification. The zero Quirks disables all
+// workarounds.
+type Quirks struct {
+	// ReorderInstructions sorts the instructions of the flow
+	// modifications in the order of their execution defined by the
+	// specification, for the switches rejecting the instructions
+	// listed in any other order.
+	ReorderInstructions bool
+
+	// UnsupportedMultiparts are the types of the multipart requests,
+	// that the switch fails to serve or disconnects on. Such requests
+	// are rejected with ErrNotSupported without being sent.
+	UnsupportedMultiparts []ofp.MultipartType
+
+	// NonZeroPadding marks the switches filling the paddings of the
+	// messages with non-zero bytes. The messages received from such
+	// switches are decoded in ofp.CheckLenient mode, even when strict
+	// mode is the default one.
+	NonZeroPadding bool
+}
+
+// merge enables the workarounds of the given quirks.
+func (q *Quirks) merge(other *Quirks) {
+	q.ReorderInstructions = q.ReorderInstructions || other.ReorderInstructions
+	q.NonZeroPadding = q.NonZeroPadding || other.NonZeroPadding
+
+	for _, t := range other.UnsupportedMultiparts {
+		if !q.unsupported(t) {
+			q.UnsupportedMultiparts = append(q.UnsupportedMultiparts, t)
+		}
+	}
+}
+
+// unsupported returns true when the multipart type is unsupported.
+func (q *Quirks) unsupported(t ofp.MultipartType) bool {
+	for _, unsupported := range q.UnsupportedMultiparts {
+		if unsupported == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckMultipart returns ErrNotSupported, when the multipart request of
+// the given type is listed as unsupported.
+func (q *Quirks) CheckMultipart(t ofp.MultipartType) error {
+	if q == nil || !q.unsupported(t) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrNotSupported, t)
+}
+
+// instructionOrder is the order of the execution of the instructions.
+// The instructions of other types are placed after the listed ones.
+var instructionOrder = map[ofp.InstructionType]int{
+	ofp.InstructionTypeMeter:         0,
+	ofp.InstructionTypeApplyActions:  1,
+	ofp.InstructionTypeClearActions:  2,
+	ofp.InstructionTypeWriteActions:  3,
+	ofp.InstructionTypeWriteMetadata: 4,
+	ofp.InstructionTypeGotoTable:     5,
+}
+
+// SortInstructions returns a copy of the instructions sorted in the
+// order of their execution: meter, apply-actions, clear-actions,
+// write-actions, write-metadata and goto-table. The experimenter
+// instructions are placed last in the original order.
+func SortInstructions(instructions ofp.Instructions) ofp.Instructions {
+	rank := func(inst ofp.Instruction) int {
+		if order, ok := instructionOrder[inst.Type()]; ok {
+			return order
+		}
+		return len(instructionOrder)
+	}
+
+	sorted := append(ofp.Instructions(nil), instructions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+
+	return sorted
+}
+
+// QuirkRule enables the quirks for the switches with the matching
+// description. The empty fields of the rule match any description,
+// the rest are matched as the prefixes of the respective description
+// fields.
+type QuirkRule struct {
+	// Manufacturer is a prefix of the manufacturer description.
+	Manufacturer string
+
+	// Hardware is a prefix of the hardware description.
+	Hardware string
+
+	// Software is a prefix of the software description, usually the
+	// version of the firmware.
+	Software string
+
+	// Quirks are the workarounds enabled for the matching switches.
+	Quirks Quirks
+}
+
+// Match returns true when the description matches the rule.
+func (rule *QuirkRule) Match(desc *ofp.Description) bool {
+	return strings.HasPrefix(desc.Manufacturer, rule.Manufacturer) &&
+		strings.HasPrefix(desc.Hardware, rule.Hardware) &&
+		strings.HasPrefix(desc.Software, rule.Software)
+}
+
+// QuirkRegistry keeps the quirks of the connected datapaths, detected
+// by the descriptions of the switches.
+//
+// For example, to avoid the table features requests to the specific
+// firmware:
+//
+//	quirks := ofputil.NewQuirkRegistry(ofputil.QuirkRule{
+//		Manufacturer: "Acme",
+//		Software:     "1.2.",
+//		Quirks: ofputil.Quirks{
+//			UnsupportedMultiparts: []ofp.MultipartType{
+//				ofp.MultipartTypeTableFeatures,
+//			},
+//		},
+//	})
+//
+// After the description of the switch is received, the quirks of the
+// datapath are applied to the connection:
+//
+//	conn = ofputil.NewQuirksConn(conn, quirks.Bind(features.DatapathID, desc))
+type QuirkRegistry struct {
+	mu        sync.RWMutex
+	rules     []QuirkRule
+	datapaths map[ofp.DatapathID]*Quirks
+}
+
+// NewQuirkRegistry creates a new registry with the given rules.
+func NewQuirkRegistry(rules ...QuirkRule) *QuirkRegistry {
+	return &QuirkRegistry{
+		rules:     append([]QuirkRule(nil), rules...),
+		datapaths: make(map[ofp.DatapathID]*Quirks),
+	}
+}
+
+// Register adds the rule to the registry. The datapaths bound before
+// the registration are not affected.
+func (reg *QuirkRegistry) Register(rule QuirkRule) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.rules = append(reg.rules, rule)
+}
+
+// Match returns the quirks of all rules matching the description, or
+// nil when none of the rules match.
+func (reg *QuirkRegistry) Match(desc *ofp.Description) *Quirks {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	var quirks *Quirks
+	for i := range reg.rules {
+		if !reg.rules[i].Match(desc) {
+			continue
+		}
+
+		if quirks == nil {
+			quirks = new(Quirks)
+		}
+
+		quirks.merge(&reg.rules[i].Quirks)
+	}
+
+	return quirks
+}
+
+// Bind matches the description of the datapath and remembers the quirks
+// of the datapath. The matched quirks are returned.
+func (reg *QuirkRegistry) Bind(dpid ofp.DatapathID, desc *ofp.Description) *Quirks {
+	quirks := reg.Match(desc)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if quirks == nil {
+		delete(reg.datapaths, dpid)
+	} else {
+		reg.datapaths[dpid] = quirks
+	}
+
+	return quirks
+}
+
+// Datapath returns the quirks of the bound datapath, or nil when the
+// datapath has no quirks.
+func (reg *QuirkRegistry) Datapath(dpid ofp.DatapathID) *Quirks {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	return reg.datapaths[dpid]
+}
+
+// Unbind forgets the quirks of the datapath, for example when the switch
+// disconnects.
+func (reg *QuirkRegistry) Unbind(dpid ofp.DatapathID) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	delete(reg.datapaths, dpid)
+}
+
+// QuirksConn is a connection, that applies the workarounds of the
+// switch to the outgoing messages, so the modules keep using the
+// messages as defined by the specification. The received messages
+// are decoded in the check mode tolerating the defects of the switch.
+type QuirksConn struct {
+	of.Conn
+
+	quirks *Quirks
+}
+
+// NewQuirksConn creates a new connection applying the given quirks. The
+// nil quirks pass the messages unchanged.
+func NewQuirksConn(c of.Conn, quirks *Quirks) *QuirksConn {
+	return &QuirksConn{Conn: c, quirks: quirks}
+}
+
+// Receive receives the request from the underlying connection and
+// attaches the check mode of the switch to the request context.
+func (c *QuirksConn) Receive() (*of.Request, error) {
+	r, err := c.Conn.Receive()
+	if err != nil {
+		return r, err
+	}
+
+	return c.withCheckMode(r), nil
+}
+
+// Handler returns a handler, that attaches the check mode of the switch
+// to the context of each request received from the connection and then
+// calls the given handler. The requests of the other connections are
+// passed as is.
+func (c *QuirksConn) Handler(h of.Handler) of.Handler {
+	return of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		if conn := r.Conn(); conn == c.Conn || conn == of.Conn(c) {
+			r = c.withCheckMode(r)
+		}
+
+		h.Serve(rw, r)
+	})
+}
+
+// withCheckMode returns the request decoded in lenient mode, when the
+// switch fills the paddings with non-zero bytes.
+func (c *QuirksConn) withCheckMode(r *of.Request) *of.Request {
+	if c.quirks == nil || !c.quirks.NonZeroPadding {
+		return r
+	}
+
+	ctx := ofp.NewCheckModeContext(r.Context(), ofp.CheckLenient)
+	return r.WithContext(ctx)
+}
+
+// Send applies the quirks to the request and sends it to the underlying
+// connection. The multipart requests unsupported by the switch are
+// rejected with ErrNotSupported.
+func (c *QuirksConn) Send(r *of.Request) error {
+	if c.quirks == nil {
+		return c.Conn.Send(r)
+	}
+
+	switch r.Header.Type {
+	case of.TypeMultipartRequest:
+		var mr ofp.MultipartRequest
+		if r.Decode(&mr) == nil {
+			if err := c.quirks.CheckMultipart(mr.Type); err != nil {
+				return err
+			}
+		}
+	case of.TypeFlowMod:
+		if !c.quirks.ReorderInstructions {
+			break
+		}
+
+		var fmod ofp.FlowMod
+		if r.Decode(&fmod) != nil {
+			break
+		}
+
+		fmod.Instructions = SortInstructions(fmod.Instructions)
+
+		reordered := of.NewRequest(r.Header.Type, &fmod)
+		reordered.Header = r.Header
+		r = reordered.WithContext(r.Context())
+	}
+
+	return c.Conn.Send(r)
+}
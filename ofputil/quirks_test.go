@@ -0,0 +1,193 @@
+package ofputil
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofptest"
+)
+
+func TestSortInstructions(t *testing.T) {
+	instructions := ofp.Instructions{
+		&ofp.InstructionGotoTable{Table: 2},
+		&ofp.InstructionWriteMetadata{Metadata: 1, MetadataMask: 1},
+		&ofp.InstructionApplyActions{},
+		&ofp.InstructionMeter{Meter: 1},
+	}
+
+	sorted := SortInstructions(instructions)
+	types := make([]ofp.InstructionType, len(sorted))
+	for i, inst := range sorted {
+		types[i] = inst.Type()
+	}
+
+	want := []ofp.InstructionType{
+		ofp.InstructionTypeMeter,
+		ofp.InstructionTypeApplyActions,
+		ofp.InstructionTypeWriteMetadata,
+		ofp.InstructionTypeGotoTable,
+	}
+
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("Invalid order of instructions: %v", types)
+	}
+
+	if instructions[0].Type() != ofp.InstructionTypeGotoTable {
+		t.Errorf("Original instructions must not be modified")
+	}
+}
+
+func TestQuirkRegistry(t *testing.T) {
+	reg := NewQuirkRegistry(
+		QuirkRule{
+			Manufacturer: "Acme",
+			Quirks:       Quirks{ReorderInstructions: true},
+		},
+		QuirkRule{
+			Manufacturer: "Acme",
+			Software:     "1.2.",
+			Quirks: Quirks{UnsupportedMultiparts: []ofp.MultipartType{
+				ofp.MultipartTypeTableFeatures,
+			}},
+		},
+	)
+
+	old := &ofp.Description{Manufacturer: "Acme Inc.", Software: "1.2.7"}
+	quirks := reg.Bind(1, old)
+	if quirks == nil || !quirks.ReorderInstructions {
+		t.Fatalf("Quirks of all matching rules must be merged: %v", quirks)
+	}
+
+	err := quirks.CheckMultipart(ofp.MultipartTypeTableFeatures)
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Unsupported multipart must be rejected: %v", err)
+	}
+
+	fixed := &ofp.Description{Manufacturer: "Acme Inc.", Software: "2.0"}
+	if quirks = reg.Match(fixed); quirks.CheckMultipart(ofp.MultipartTypeTableFeatures) != nil {
+		t.Errorf("Multipart must be permitted for fixed firmware")
+	}
+
+	if reg.Match(&ofp.Description{Manufacturer: "Other"}) != nil {
+		t.Errorf("Quirks must not be returned for unmatched description")
+	}
+
+	if reg.Datapath(1) == nil {
+		t.Errorf("Quirks of the datapath must be bound")
+	}
+
+	reg.Unbind(1)
+	if reg.Datapath(1) != nil {
+		t.Errorf("Quirks of the datapath must be unbound")
+	}
+}
+
+func TestQuirksConn(t *testing.T) {
+	rec := ofptest.NewConnRecorder()
+	conn := NewQuirksConn(rec, &Quirks{
+		ReorderInstructions:   true,
+		UnsupportedMultiparts: []ofp.MultipartType{ofp.MultipartTypeTableFeatures},
+	})
+
+	fmod := ofp.NewFlowMod(ofp.FlowAdd, nil)
+	fmod.Instructions = ofp.Instructions{
+		&ofp.InstructionGotoTable{Table: 2},
+		&ofp.InstructionApplyActions{},
+	}
+
+	req := of.NewRequest(of.TypeFlowMod, fmod)
+	req.Header.Transaction = 42
+
+	if err := conn.Send(req); err != nil {
+		t.Fatalf("Failed to send flow mod: %s", err)
+	}
+
+	var sent ofp.FlowMod
+	if err := rec.Decode(0, &sent); err != nil {
+		t.Fatalf("Failed to decode flow mod: %s", err)
+	}
+
+	if rec.First().Header.Transaction != 42 {
+		t.Errorf("Transaction must be preserved: %d", rec.First().Header.Transaction)
+	}
+
+	if sent.Instructions[0].Type() != ofp.InstructionTypeApplyActions {
+		t.Errorf("Instructions must be reordered: %v", sent.Instructions)
+	}
+
+	mr := ofp.NewMultipartRequest(ofp.MultipartTypeTableFeatures)
+	err := conn.Send(of.NewRequest(of.TypeMultipartRequest, mr))
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Unsupported multipart must be rejected: %v", err)
+	}
+
+	if rec.Len() != 1 {
+		t.Errorf("Rejected request must not be sent: %d", rec.Len())
+	}
+}
+
+func TestSwitchCapabilitiesQuirks(t *testing.T) {
+	caps := &SwitchCapabilities{Quirks: &Quirks{
+		UnsupportedMultiparts: []ofp.MultipartType{ofp.MultipartTypeDescription},
+	}}
+
+	err := caps.CheckMultipart(ofp.MultipartTypeDescription)
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Unsupported multipart must be rejected: %v", err)
+	}
+}
+
+func TestQuirksConnNonZeroPadding(t *testing.T) {
+	ofp.SetCheckMode(ofp.CheckStrict)
+	defer ofp.SetCheckMode(ofp.CheckLenient)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sw := of.NewConn(server)
+	defer sw.Close()
+
+	body := []byte{
+		0x01,             // Table identifier.
+		0x00, 0x01, 0x00, // 3-byte padding.
+		0x00, 0x00, 0x00, 0x00, // Configuration.
+	}
+
+	send := func() {
+		of.Send(sw, of.NewRequest(of.TypeTableMod, bytes.NewBuffer(body)))
+	}
+
+	conn := NewQuirksConn(of.NewConn(client), &Quirks{NonZeroPadding: true})
+
+	go send()
+	r, err := conn.Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive table mod: %s", err)
+	}
+
+	if err = r.Decode(&ofp.TableMod{}); err != nil {
+		t.Errorf("Padding of the quirky switch must be tolerated: %s", err)
+	}
+
+	go send()
+	if r, err = conn.Conn.Receive(); err != nil {
+		t.Fatalf("Failed to receive table mod: %s", err)
+	}
+
+	if err = r.Decode(&ofp.TableMod{}); !errors.Is(err, ofp.ErrPaddingNotZero) {
+		t.Errorf("Default mode must be used without quirks: %v", err)
+	}
+
+	conn.Handler(of.HandlerFunc(func(rw of.ResponseWriter, r *of.Request) {
+		err = r.Decode(&ofp.TableMod{})
+	})).Serve(ofptest.NewRecorder(), r)
+
+	if err != nil {
+		t.Errorf("Handler must tolerate padding of the quirky switch: %s", err)
+	}
+}
@@ -0,0 +1,167 @@
+package ofputil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrBodyMismatch is returned when the body of the message does not
+// correspond to the type of the message.
+var ErrBodyMismatch = errors.New("ofputil: body does not match the message type")
+
+// noBody is used to mark message types, that could be sent without body.
+var noBody reflect.Type
+
+// bodyTypes maps the message types to the permitted types of the
+// message bodies. The message types missing in the mapping are not
+// validated.
+var bodyTypes = map[of.Type][]reflect.Type{
+	of.TypeHello:                 {noBody, reflect.TypeOf(&ofp.Hello{})},
+	of.TypeError:                 {reflect.TypeOf(&ofp.Error{}), reflect.TypeOf(&ofp.ErrorExperimenter{})},
+	of.TypeEchoRequest:           {noBody, reflect.TypeOf(&ofp.EchoRequest{})},
+	of.TypeEchoReply:             {noBody, reflect.TypeOf(&ofp.EchoReply{})},
+	of.TypeFeaturesRequest:       {noBody},
+	of.TypeFeaturesReply:         {reflect.TypeOf(&ofp.SwitchFeatures{})},
+	of.TypeGetConfigRequest:      {noBody},
+	of.TypeGetConfigReply:        {reflect.TypeOf(&ofp.SwitchConfig{})},
+	of.TypeSetConfig:             {reflect.TypeOf(&ofp.SwitchConfig{})},
+	of.TypePacketIn:              {reflect.TypeOf(&ofp.PacketIn{})},
+	of.TypeFlowRemoved:           {reflect.TypeOf(&ofp.FlowRemoved{})},
+	of.TypePortStatus:            {reflect.TypeOf(&ofp.PortStatus{})},
+	of.TypePacketOut:             {reflect.TypeOf(&ofp.PacketOut{})},
+	of.TypeFlowMod:               {reflect.TypeOf(&ofp.FlowMod{})},
+	of.TypeGroupMod:              {reflect.TypeOf(&ofp.GroupMod{})},
+	of.TypePortMod:               {reflect.TypeOf(&ofp.PortMod{})},
+	of.TypeTableMod:              {reflect.TypeOf(&ofp.TableMod{})},
+	of.TypeMultipartRequest:      {reflect.TypeOf(&ofp.MultipartRequest{})},
+	of.TypeBarrierRequest:        {noBody},
+	of.TypeBarrierReply:          {noBody},
+	of.TypeQueueGetConfigRequest: {reflect.TypeOf(&ofp.QueueGetConfigRequest{})},
+	of.TypeQueueGetConfigReply:   {reflect.TypeOf(&ofp.QueueGetConfigReply{})},
+	of.TypeRoleRequest:           {reflect.TypeOf(&ofp.RoleRequest{})},
+	of.TypeRoleReply:             {reflect.TypeOf(&ofp.RoleRequest{})},
+	of.TypeGetAsyncRequest:       {noBody},
+	of.TypeGetAsyncReply:         {reflect.TypeOf(&ofp.AsyncConfig{})},
+	of.TypeSetAsync:              {reflect.TypeOf(&ofp.AsyncConfig{})},
+	of.TypeMeterMod:              {reflect.TypeOf(&ofp.MeterMod{})},
+}
+
+// CheckBody ensures the body corresponds to the type of the message.
+// The bodies in the wire format (*bytes.Buffer and *bytes.Reader) and
+// the bodies of the message types unknown to the package are accepted
+// without validation.
+func CheckBody(t of.Type, body io.WriterTo) error {
+	switch body.(type) {
+	case *bytes.Buffer, *bytes.Reader:
+		return nil
+	}
+
+	permitted, ok := bodyTypes[t]
+	if !ok {
+		return nil
+	}
+
+	bodyType := reflect.TypeOf(body)
+	for _, permittedType := range permitted {
+		if bodyType == permittedType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %T for %s", ErrBodyMismatch, body, t)
+}
+
+// NewRequest returns a new request of the given type, and ensures the
+// body corresponds to the type of the message, so the body of one
+// message could not be sent by mistake under the type of another one.
+//
+// For example, the following request is rejected:
+//
+//	// The error is returned, since flow modification
+//	// could not be sent as a packet-out message.
+//	_, err := ofputil.NewRequest(of.TypePacketOut, &ofp.FlowMod{})
+func NewRequest(t of.Type, body io.WriterTo) (*of.Request, error) {
+	if err := CheckBody(t, body); err != nil {
+		return nil, err
+	}
+
+	return of.NewRequest(t, body), nil
+}
+
+// NewFlowModRequest returns a new flow modification request.
+func NewFlowModRequest(fmod *ofp.FlowMod) *of.Request {
+	return of.NewRequest(of.TypeFlowMod, fmod)
+}
+
+// NewGroupModRequest returns a new group modification request.
+func NewGroupModRequest(gmod *ofp.GroupMod) *of.Request {
+	return of.NewRequest(of.TypeGroupMod, gmod)
+}
+
+// NewMeterModRequest returns a new meter modification request.
+func NewMeterModRequest(mmod *ofp.MeterMod) *of.Request {
+	return of.NewRequest(of.TypeMeterMod, mmod)
+}
+
+// NewPortModRequest returns a new port modification request.
+func NewPortModRequest(pmod *ofp.PortMod) *of.Request {
+	return of.NewRequest(of.TypePortMod, pmod)
+}
+
+// NewTableModRequest returns a new table modification request.
+func NewTableModRequest(tmod *ofp.TableMod) *of.Request {
+	return of.NewRequest(of.TypeTableMod, tmod)
+}
+
+// NewPacketOutRequest returns a new packet-out request.
+func NewPacketOutRequest(pout *ofp.PacketOut) *of.Request {
+	return of.NewRequest(of.TypePacketOut, pout)
+}
+
+// NewMultipartRequest returns a new multipart request of the given type
+// with the optional body.
+func NewMultipartRequest(t ofp.MultipartType, body ...io.WriterTo) *of.Request {
+	return of.NewRequest(of.TypeMultipartRequest,
+		ofp.NewMultipartRequest(t, body...))
+}
+
+// NewErrorReply returns the error message replying to the failed request.
+// The error of the validation must wrap ofp.Error, which defines the type
+// and code of the error message. Unless the data of the error is defined,
+// it is set to the first 64 bytes of the request in the wire format. The
+// second value is false when the error does not wrap ofp.Error.
+//
+// For example, to reject the flow modification with the invalid match:
+//
+//	if err := fmod.Match.Canonicalize(); err != nil {
+//		if reply, ok := ofputil.NewErrorReply(r, err); ok {
+//			of.Send(r.Conn(), reply)
+//		}
+//	}
+func NewErrorReply(r *of.Request, err error) (*of.Request, bool) {
+	e, ok := ofp.AsError(err)
+	if !ok {
+		return nil, false
+	}
+
+	if e.Data == nil {
+		raw, _ := r.RawBody()
+
+		header := r.Header
+		header.Length = uint16(of.HeaderLen + len(raw))
+
+		var buf bytes.Buffer
+		header.WriteTo(&buf)
+		buf.Write(raw)
+
+		e.Data = ofp.ErrorData(buf.Bytes())
+	}
+
+	return r.NewReply(of.TypeError, &e), true
+}
@@ -0,0 +1,93 @@
+package ofputil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestNewRequest(t *testing.T) {
+	tests := []struct {
+		Type  of.Type
+		Body  io.WriterTo
+		Valid bool
+	}{
+		{of.TypeFlowMod, &ofp.FlowMod{}, true},
+		{of.TypeBarrierRequest, nil, true},
+		{of.TypeEchoRequest, &ofp.EchoRequest{}, true},
+		{of.TypePacketOut, &bytes.Buffer{}, true},
+		{of.TypeExperiment, &ofp.FlowMod{}, true},
+		{of.TypePacketOut, &ofp.FlowMod{}, false},
+		{of.TypeFlowMod, nil, false},
+		{of.TypeBarrierRequest, &ofp.EchoRequest{}, false},
+	}
+
+	for _, test := range tests {
+		req, err := NewRequest(test.Type, test.Body)
+		if !test.Valid {
+			if !errors.Is(err, ErrBodyMismatch) {
+				t.Errorf("Body mismatch error expected for %s: %v",
+					test.Type, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Failed to create %s request: %s", test.Type, err)
+			continue
+		}
+
+		if req.Header.Type != test.Type {
+			t.Errorf("Invalid type of the request: %s", req.Header.Type)
+		}
+	}
+
+	if req := NewFlowModRequest(&ofp.FlowMod{}); req.Header.Type != of.TypeFlowMod {
+		t.Errorf("Invalid type of the request: %s", req.Header.Type)
+	}
+}
+
+func TestNewErrorReply(t *testing.T) {
+	fmod := ofp.NewFlowMod(ofp.FlowAdd, nil)
+	fmod.Match = ExtendedMatch(MatchInPort(1), MatchInPort(2))
+	fmod.Instructions = ActionsApply(&ofp.ActionOutput{Port: 3})
+
+	r := of.NewRequest(of.TypeFlowMod, fmod)
+	r.Header.Transaction = 42
+
+	var wire bytes.Buffer
+	if _, err := r.WriteTo(&wire); err != nil {
+		t.Fatalf("Failed to marshal request: %s", err)
+	}
+
+	err := fmod.Match.Canonicalize()
+	reply, ok := NewErrorReply(r, err)
+	if !ok {
+		t.Fatalf("Error reply expected for validation error: %v", err)
+	}
+
+	if reply.Header.Type != of.TypeError || reply.Header.Transaction != 42 {
+		t.Fatalf("Invalid header of error reply: %v", reply.Header)
+	}
+
+	var e ofp.Error
+	if err = reply.Decode(&e); err != nil {
+		t.Fatalf("Failed to decode error reply: %s", err)
+	}
+
+	if e.Type != ofp.ErrTypeBadMatch || e.Code != ofp.ErrCodeBadMatchDupField {
+		t.Errorf("Invalid type and code of error: %s", e)
+	}
+
+	if !bytes.Equal(e.Data, wire.Bytes()[:ofp.ErrorDataLen]) {
+		t.Errorf("Data must contain first 64 bytes of request: %x", e.Data)
+	}
+
+	if _, ok = NewErrorReply(r, errors.New("failure")); ok {
+		t.Errorf("Error reply must not be created for unknown error")
+	}
+}
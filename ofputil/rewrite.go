@@ -0,0 +1,178 @@
+package ofputil
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofpconst"
+)
+
+// The action sequences below are intended for the apply-actions
+// instruction, where the actions are executed in the order of the list.
+// The switches reject the set-field actions applied to the header,
+// which is not present yet, with ErrCodeBadActionUnsupportedOrder, so
+// the headers are always pushed before their fields are set.
+
+// PushVLAN returns the actions pushing a new 802.1Q tag with the given
+// VLAN identifier. ErrVlanIDRange is returned when the identifier does
+// not fit into 12 bits.
+func PushVLAN(vid uint16) (ofp.Actions, error) {
+	setVID, err := SetVlanVID(vid)
+	if err != nil {
+		return nil, err
+	}
+
+	return ofp.Actions{
+		&ofp.ActionPushVLAN{EtherType: ofpconst.EtherTypeVLAN},
+		setVID,
+	}, nil
+}
+
+// SwapVLAN returns the actions replacing the outermost VLAN tag with a
+// new 802.1Q tag with the given VLAN identifier. The priority of the
+// popped tag is not preserved.
+func SwapVLAN(vid uint16) (ofp.Actions, error) {
+	push, err := PushVLAN(vid)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ofp.Actions{&ofp.ActionPopVLAN{}}, push...), nil
+}
+
+// PushMPLS returns the actions pushing a new MPLS unicast shim header
+// with the given label. The TTL of the IP header is copied into the
+// pushed header. ErrMPLSLabelRange is returned when the label does not
+// fit into 20 bits.
+func PushMPLS(label uint32) (ofp.Actions, error) {
+	setLabel, err := SetMPLSLabel(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return ofp.Actions{
+		&ofp.ActionPushMPLS{EtherType: ofpconst.EtherTypeMPLS},
+		&ofp.ActionCopyTTLOut{},
+		setLabel,
+	}, nil
+}
+
+// SwapMPLS returns the actions replacing the label of the outermost
+// MPLS shim header and decrementing its TTL, as done by the label
+// switching router.
+func SwapMPLS(label uint32) (ofp.Actions, error) {
+	setLabel, err := SetMPLSLabel(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return ofp.Actions{setLabel, &ofp.ActionDecMPLSTTL{}}, nil
+}
+
+// PopMPLS returns the actions removing the last MPLS shim header, the
+// TTL of the header is copied into the IP header of the payload with the
+// given EtherType.
+func PopMPLS(etherType uint16) ofp.Actions {
+	return ofp.Actions{
+		&ofp.ActionCopyTTLIn{},
+		&ofp.ActionPopMPLS{EtherType: etherType},
+	}
+}
+
+// portTypes maps the IP protocol numbers to the types of the source and
+// destination transport ports.
+var portTypes = map[uint8][2]ofp.XMType{
+	ofpconst.IPProtoTCP:  {ofp.XMTypeTCPSrc, ofp.XMTypeTCPDst},
+	ofpconst.IPProtoUDP:  {ofp.XMTypeUDPSrc, ofp.XMTypeUDPDst},
+	ofpconst.IPProtoSCTP: {ofp.XMTypeSCTPSrc, ofp.XMTypeSCTPDst},
+}
+
+// NAT is a network address translation rewrite of the IP packets. The
+// zero fields are left untouched.
+//
+// For example, to translate the destination of the TCP connections:
+//
+//	nat := ofputil.NAT{
+//		Dst:     net.ParseIP("10.0.0.2"),
+//		DstPort: 8080,
+//		Proto:   ofpconst.IPProtoTCP,
+//	}
+//
+//	actions, err := nat.Actions()
+type NAT struct {
+	// Src is a new source address.
+	Src net.IP
+
+	// Dst is a new destination address.
+	Dst net.IP
+
+	// SrcPort is a new source transport port.
+	SrcPort uint16
+
+	// DstPort is a new destination transport port.
+	DstPort uint16
+
+	// Proto is an IP protocol number, it is required to rewrite the
+	// transport ports of the TCP, UDP or SCTP packets.
+	Proto uint8
+}
+
+// Actions returns the set-field actions rewriting the addresses and
+// then the transport ports of the packet. The IPv4 and IPv6 addresses
+// could not be mixed in a single rewrite.
+func (n *NAT) Actions() (ofp.Actions, error) {
+	var actions ofp.Actions
+
+	// The address family is selected by the first defined address,
+	// the rest must belong to the same family.
+	ip4 := n.Dst != nil && n.Dst.To4() != nil
+	if n.Src != nil {
+		ip4 = n.Src.To4() != nil
+	}
+
+	addrs := []struct {
+		ip         net.IP
+		set4, set6 func(net.IP) (*ofp.ActionSetField, error)
+	}{
+		{n.Src, SetIPv4Src, SetIPv6Src},
+		{n.Dst, SetIPv4Dst, SetIPv6Dst},
+	}
+
+	for _, addr := range addrs {
+		if addr.ip == nil {
+			continue
+		}
+
+		set := addr.set6
+		if ip4 {
+			set = addr.set4
+		}
+
+		action, err := set(addr.ip)
+		if err != nil {
+			return nil, err
+		}
+
+		actions = append(actions, action)
+	}
+
+	if n.SrcPort == 0 && n.DstPort == 0 {
+		return actions, nil
+	}
+
+	types, ok := portTypes[n.Proto]
+	if !ok {
+		return nil, fmt.Errorf("ofputil: ports of IP protocol %d "+
+			"could not be rewritten", n.Proto)
+	}
+
+	for i, port := range []uint16{n.SrcPort, n.DstPort} {
+		if port != 0 {
+			xm := basic(types[i], bytesOf(port), nil)
+			actions = append(actions, SetField(xm))
+		}
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,116 @@
+package ofputil
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+	"github.com/netrack/openflow/ofpconst"
+)
+
+// actionTypes returns the types of the actions.
+func actionTypes(actions ofp.Actions) []ofp.ActionType {
+	var types []ofp.ActionType
+	for _, action := range actions {
+		types = append(types, action.Type())
+	}
+	return types
+}
+
+func TestRewriteActions(t *testing.T) {
+	build := func(fn func() (ofp.Actions, error)) ofp.Actions {
+		actions, err := fn()
+		if err != nil {
+			t.Fatalf("Failed to create actions: %s", err)
+		}
+		return actions
+	}
+
+	tests := []struct {
+		Actions ofp.Actions
+		Types   []ofp.ActionType
+	}{
+		{build(func() (ofp.Actions, error) { return PushVLAN(10) }),
+			[]ofp.ActionType{ofp.ActionTypePushVLAN, ofp.ActionTypeSetField}},
+		{build(func() (ofp.Actions, error) { return SwapVLAN(10) }),
+			[]ofp.ActionType{ofp.ActionTypePopVLAN, ofp.ActionTypePushVLAN,
+				ofp.ActionTypeSetField}},
+		{build(func() (ofp.Actions, error) { return PushMPLS(16) }),
+			[]ofp.ActionType{ofp.ActionTypePushMPLS, ofp.ActionTypeCopyTTLOut,
+				ofp.ActionTypeSetField}},
+		{build(func() (ofp.Actions, error) { return SwapMPLS(16) }),
+			[]ofp.ActionType{ofp.ActionTypeSetField, ofp.ActionTypeDecMPLSTTL}},
+		{PopMPLS(ofpconst.EtherTypeIPv4),
+			[]ofp.ActionType{ofp.ActionTypeCopyTTLIn, ofp.ActionTypePopMPLS}},
+	}
+
+	for _, test := range tests {
+		types := actionTypes(test.Actions)
+		if len(types) != len(test.Types) {
+			t.Errorf("Invalid actions: %v, expected %v", types, test.Types)
+			continue
+		}
+
+		for i := range types {
+			if types[i] != test.Types[i] {
+				t.Errorf("Invalid order of actions: %v, expected %v",
+					types, test.Types)
+				break
+			}
+		}
+	}
+
+	if _, err := SwapVLAN(4096); err != ErrVlanIDRange {
+		t.Errorf("VLAN identifier range error expected: %v", err)
+	}
+
+	if _, err := SwapMPLS(1 << 20); err != ErrMPLSLabelRange {
+		t.Errorf("MPLS label range error expected: %v", err)
+	}
+}
+
+func TestNAT(t *testing.T) {
+	nat := NAT{
+		Src:     net.ParseIP("192.168.0.1"),
+		Dst:     net.ParseIP("10.0.0.2"),
+		DstPort: 8080,
+		Proto:   ofpconst.IPProtoTCP,
+	}
+
+	actions, err := nat.Actions()
+	if err != nil {
+		t.Fatalf("Failed to create NAT actions: %s", err)
+	}
+
+	expected := []struct {
+		Type  ofp.XMType
+		Value ofp.XMValue
+	}{
+		{ofp.XMTypeIPv4Src, ofp.XMValue{192, 168, 0, 1}},
+		{ofp.XMTypeIPv4Dst, ofp.XMValue{10, 0, 0, 2}},
+		{ofp.XMTypeTCPDst, ofp.XMValue{0x1f, 0x90}},
+	}
+
+	if len(actions) != len(expected) {
+		t.Fatalf("Invalid NAT actions: %v", actions)
+	}
+
+	for i, action := range actions {
+		field := action.(*ofp.ActionSetField).Field
+		if field.Type != expected[i].Type ||
+			!bytes.Equal(field.Value, expected[i].Value) {
+			t.Errorf("Invalid set-field action %d: %v", i, field)
+		}
+	}
+
+	nat = NAT{Src: net.ParseIP("fe80::1"), Dst: net.ParseIP("10.0.0.2")}
+	if _, err = nat.Actions(); err != ErrIPv6Addr {
+		t.Errorf("IPv6 address error expected: %v", err)
+	}
+
+	nat = NAT{SrcPort: 53, Proto: ofpconst.IPProtoICMP}
+	if _, err = nat.Actions(); err == nil {
+		t.Errorf("Error expected for ports of ICMP packets")
+	}
+}
@@ -0,0 +1,188 @@
+package ofputil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+// ErrSlaveRole is returned by the role-aware connection on attempt to
+// send a state-modifying message, while the controller has the slave
+// role. The switch would reject such message with the BadRequest error
+// of ErrCodeBadRequestIsSlave code.
+var ErrSlaveRole = errors.New("ofputil: message is not permitted in slave role")
+
+// slaveDenied lists the types of the messages modifying the state of
+// the switch, that are rejected by the switch in the slave role.
+var slaveDenied = map[of.Type]bool{
+	of.TypePacketOut: true,
+	of.TypeFlowMod:   true,
+	of.TypeGroupMod:  true,
+	of.TypePortMod:   true,
+	of.TypeTableMod:  true,
+	of.TypeMeterMod:  true,
+}
+
+// Discarder is implemented by the connections buffering the requests
+// before sending them to the switch, like of.QueuedConn. Discard removes
+// the buffered requests, which headers satisfy the given function, and
+// returns the number of the removed requests.
+type Discarder interface {
+	Discard(fn func(*of.Header) bool) int
+}
+
+// RoleChangeFunc is called by the role-aware connection, when the role
+// of the controller is changed from prev to role. The discarded is the
+// number of the pending messages drained on the change to slave role.
+type RoleChangeFunc func(prev, role ofp.ControllerRole, discarded int)
+
+// RoleConn is a connection enforcing the controller role semantics on
+// the client side. The role of the controller is updated from the role
+// replies received from the switch, or explicitly using SetRole method.
+//
+// When the controller has the slave role, the state-modifying messages
+// (flow, group, port, table and meter modifications and packet-out
+// messages) are rejected with ErrSlaveRole error without sending them
+// to the switch.
+//
+// For example, to log such messages instead of rejecting them, the
+// following connection could be used:
+//
+//	conn := ofputil.NewRoleConn(c)
+//	conn.Warn = true
+//
+// When the role changes to slave, the connection could drain the
+// state-modifying messages buffered by the underlying connection, since
+// the switch would reject them anyway. The applications are notified
+// about the role changes with the functions registered by Notify:
+//
+//	conn := ofputil.NewRoleConn(of.NewQueuedConn(c, of.QueueConfig{}))
+//	conn.Drain = true
+//	conn.Notify(func(prev, role ofp.ControllerRole, discarded int) {
+//		if role == ofp.ControllerRoleSlave {
+//			// Stop issuing flow modifications and reissue
+//			// the discarded ones on the change to master.
+//		}
+//	})
+type RoleConn struct {
+	of.Conn
+
+	// Warn instructs the connection to log the state-modifying
+	// messages sent in the slave role instead of rejecting them.
+	Warn bool
+
+	// Drain instructs the connection to discard the state-modifying
+	// messages pending to be sent, when the role of the controller
+	// changes to slave. The underlying connection must implement
+	// Discarder interface, otherwise the option has no effect.
+	Drain bool
+
+	mu     sync.RWMutex
+	role   ofp.ControllerRole
+	notify []RoleChangeFunc
+}
+
+// NewRoleConn creates a new role-aware connection from the given one.
+// The initial role of the controller is ControllerRoleEqual.
+func NewRoleConn(c of.Conn) *RoleConn {
+	return &RoleConn{Conn: c, role: ofp.ControllerRoleEqual}
+}
+
+// Role returns the current role of the controller.
+func (c *RoleConn) Role() ofp.ControllerRole {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.role
+}
+
+// Notify registers the function called on each change of the role of
+// the controller. The functions are called synchronously in the order
+// of registration, after the pending messages are drained.
+func (c *RoleConn) Notify(fn RoleChangeFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = append(c.notify, fn)
+}
+
+// SetRole sets the current role of the controller. The value
+// ControllerRoleNoChange is ignored.
+func (c *RoleConn) SetRole(role ofp.ControllerRole) {
+	if role == ofp.ControllerRoleNoChange {
+		return
+	}
+
+	c.mu.Lock()
+	prev := c.role
+	c.role = role
+	notify := c.notify
+	c.mu.Unlock()
+
+	if prev == role {
+		return
+	}
+
+	var discarded int
+	if role == ofp.ControllerRoleSlave && c.Drain {
+		discarded = c.drain()
+	}
+
+	for _, fn := range notify {
+		fn(prev, role, discarded)
+	}
+}
+
+// drain discards the state-modifying messages pending to be sent by
+// the underlying connection and returns the number of the discarded
+// messages.
+func (c *RoleConn) drain() int {
+	d, ok := c.Conn.(Discarder)
+	if !ok {
+		return 0
+	}
+
+	return d.Discard(func(h *of.Header) bool {
+		return slaveDenied[h.Type]
+	})
+}
+
+// Send sends the request to the underlying connection. When the
+// controller has the slave role and the request modifies the state of
+// the switch, the ErrSlaveRole error is returned.
+func (c *RoleConn) Send(r *of.Request) error {
+	if c.Role() == ofp.ControllerRoleSlave && slaveDenied[r.Header.Type] {
+		if !c.Warn {
+			return fmt.Errorf("%w: %s (xid=0x%x)", ErrSlaveRole,
+				r.Header.Type, r.Header.Transaction)
+		}
+
+		Logf(r, "ofputil: sending %s in slave role", r.Header.Type)
+	}
+
+	return c.Conn.Send(r)
+}
+
+// Receive receives the next request from the underlying connection.
+// The role replies are used to update the role of the controller.
+func (c *RoleConn) Receive() (*of.Request, error) {
+	r, err := c.Conn.Receive()
+	if err != nil || r.Header.Type != of.TypeRoleReply {
+		return r, err
+	}
+
+	// Preserve the body of the role reply, so it could be
+	// processed by the caller.
+	var buf bytes.Buffer
+	var reply ofp.RoleRequest
+
+	if _, err = reply.ReadFrom(io.TeeReader(r.Body, &buf)); err == nil {
+		c.SetRole(reply.Role)
+	}
+
+	r.Body = io.MultiReader(&buf, r.Body)
+	return r, nil
+}
@@ -0,0 +1,123 @@
+package ofputil
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	of "github.com/netrack/openflow"
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestRoleConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sw := of.NewConn(server)
+	defer sw.Close()
+
+	conn := NewRoleConn(of.NewConn(client))
+
+	go func() {
+		reply := of.NewRequest(of.TypeRoleReply, &ofp.RoleRequest{
+			Role: ofp.ControllerRoleSlave,
+		})
+		of.Send(sw, reply)
+	}()
+
+	r, err := conn.Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive role reply: %s", err)
+	}
+
+	if conn.Role() != ofp.ControllerRoleSlave {
+		t.Fatalf("Role must be updated from reply: %d", conn.Role())
+	}
+
+	// The body of the reply must be preserved for the caller.
+	var reply ofp.RoleRequest
+	if _, err = reply.ReadFrom(r.Body); err != nil {
+		t.Fatalf("Failed to decode role reply: %s", err)
+	}
+
+	if reply.Role != ofp.ControllerRoleSlave {
+		t.Fatalf("Invalid role in reply: %d", reply.Role)
+	}
+
+	fmod := of.NewRequest(of.TypeFlowMod, ofp.NewFlowMod(ofp.FlowAdd, nil))
+	if err = conn.Send(fmod); !errors.Is(err, ErrSlaveRole) {
+		t.Fatalf("Flow mod must be rejected in slave role: %v", err)
+	}
+
+	conn.SetRole(ofp.ControllerRoleNoChange)
+	if conn.Role() != ofp.ControllerRoleSlave {
+		t.Fatalf("Role must not be changed: %d", conn.Role())
+	}
+
+	conn.SetRole(ofp.ControllerRoleMaster)
+
+	go func() {
+		sw.Receive()
+	}()
+
+	if err = conn.Send(fmod); err != nil {
+		t.Fatalf("Flow mod must be sent in master role: %s", err)
+	}
+}
+
+// discardConn is a connection recording the discarded messages.
+type discardConn struct {
+	of.Conn
+	discarded []of.Type
+}
+
+func (c *discardConn) Discard(fn func(*of.Header) bool) int {
+	var n int
+	for _, t := range []of.Type{of.TypeHello, of.TypeFlowMod, of.TypePacketOut} {
+		if fn(&of.Header{Type: t}) {
+			c.discarded = append(c.discarded, t)
+			n++
+		}
+	}
+	return n
+}
+
+func TestRoleConnDrain(t *testing.T) {
+	dc := &discardConn{}
+	conn := NewRoleConn(dc)
+	conn.Drain = true
+
+	var changes [][2]ofp.ControllerRole
+	var discarded []int
+	conn.Notify(func(prev, role ofp.ControllerRole, n int) {
+		changes = append(changes, [2]ofp.ControllerRole{prev, role})
+		discarded = append(discarded, n)
+	})
+
+	conn.SetRole(ofp.ControllerRoleMaster)
+	if len(dc.discarded) != 0 {
+		t.Fatalf("Messages must not be discarded in master role: %v", dc.discarded)
+	}
+
+	conn.SetRole(ofp.ControllerRoleSlave)
+	conn.SetRole(ofp.ControllerRoleSlave)
+
+	if len(dc.discarded) != 2 || dc.discarded[0] != of.TypeFlowMod ||
+		dc.discarded[1] != of.TypePacketOut {
+		t.Fatalf("State-modifying messages must be discarded: %v", dc.discarded)
+	}
+
+	expected := [][2]ofp.ControllerRole{
+		{ofp.ControllerRoleEqual, ofp.ControllerRoleMaster},
+		{ofp.ControllerRoleMaster, ofp.ControllerRoleSlave},
+	}
+
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Invalid role changes: %v", changes)
+	}
+
+	if !reflect.DeepEqual(discarded, []int{0, 2}) {
+		t.Errorf("Invalid number of discarded messages: %v", discarded)
+	}
+}
@@ -0,0 +1,165 @@
+package ofputil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/netrack/openflow/internal/encoding"
+	"github.com/netrack/openflow/ofp"
+)
+
+// sessionStateVersion is a version of the session state format.
+const sessionStateVersion uint8 = 1
+
+// ErrSessionStateVersion is returned when the session state was
+// serialized in unsupported format.
+var ErrSessionStateVersion = errors.New(
+	"ofputil: unsupported session state version")
+
+// SessionState is a per-connection controller state. It could be
+// persisted before the controller restart and then used to hydrate
+// in-memory registries before reconciling with the live switches.
+//
+// Ports and tables are serialized using OpenFlow wire format.
+//
+// For example, to persist the state of the sessions, the following
+// code could be used:
+//
+//	states := ofputil.SessionStates{{
+//		Version:    4,
+//		DatapathID: features.DatapathID,
+//		Ports:      ports,
+//	}}
+//
+//	_, err := states.WriteTo(file)
+type SessionState struct {
+	// Version is a negotiated version of the protocol.
+	Version uint8
+
+	// DatapathID is a datapath unique identifier of the switch.
+	DatapathID ofp.DatapathID
+
+	// Role is a role of the controller in the session.
+	Role ofp.ControllerRole
+
+	// GenerationID is a master election generation identifier.
+	GenerationID uint64
+
+	// Ports is a list of cached port descriptions.
+	Ports []ofp.Port
+
+	// Tables is a list of cached table features.
+	Tables []ofp.TableFeatures
+}
+
+// WriteTo implements io.WriterTo interface. It serializes the session
+// state into the writer.
+func (s *SessionState) WriteTo(w io.Writer) (int64, error) {
+	// The numbers of ports and tables are serialized as 16-bit
+	// integers, so the longer lists can't be encoded.
+	if len(s.Ports) > math.MaxUint16 || len(s.Tables) > math.MaxUint16 {
+		return 0, fmt.Errorf("ofputil: %d ports and %d tables exceed %d",
+			len(s.Ports), len(s.Tables), math.MaxUint16)
+	}
+
+	n, err := encoding.WriteTo(w, sessionStateVersion, s.Version,
+		uint16(len(s.Ports)), s.Role, s.DatapathID, s.GenerationID,
+		uint16(len(s.Tables)))
+
+	if err != nil {
+		return n, err
+	}
+
+	nn, err := encoding.WriteSliceTo(w, s.Ports)
+	n += nn
+
+	if err != nil {
+		return n, err
+	}
+
+	nn, err = encoding.WriteSliceTo(w, s.Tables)
+	return n + nn, err
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// session state from the reader.
+func (s *SessionState) ReadFrom(r io.Reader) (int64, error) {
+	var (
+		version   uint8
+		numPorts  uint16
+		numTables uint16
+	)
+
+	n, err := encoding.ReadFrom(r, &version, &s.Version, &numPorts,
+		&s.Role, &s.DatapathID, &s.GenerationID, &numTables)
+
+	if err != nil {
+		return n, err
+	}
+
+	if version != sessionStateVersion {
+		return n, ErrSessionStateVersion
+	}
+
+	s.Ports = make([]ofp.Port, numPorts)
+	for i := range s.Ports {
+		nn, err := s.Ports[i].ReadFrom(r)
+		n += nn
+
+		if err != nil {
+			return n, noEOF(err)
+		}
+	}
+
+	s.Tables = make([]ofp.TableFeatures, numTables)
+	for i := range s.Tables {
+		nn, err := s.Tables[i].ReadFrom(r)
+		n += nn
+
+		if err != nil {
+			return n, noEOF(err)
+		}
+	}
+
+	return n, nil
+}
+
+// noEOF converts io.EOF into io.ErrUnexpectedEOF, since the truncated
+// session state is not valid.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// SessionStates groups the set of session states, usually one per
+// each connected switch.
+type SessionStates []SessionState
+
+// WriteTo implements io.WriterTo interface. It serializes the list of
+// session states into the writer.
+func (s SessionStates) WriteTo(w io.Writer) (int64, error) {
+	return encoding.WriteSliceTo(w, s)
+}
+
+// ReadFrom implements io.ReaderFrom interface. It deserializes the
+// list of session states from the reader until the end of file.
+func (s *SessionStates) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+
+	for {
+		var state SessionState
+		nn, err := state.ReadFrom(r)
+		n += nn
+
+		if err != nil {
+			return n, encoding.SkipEOF(err)
+		}
+
+		*s = append(*s, state)
+	}
+}
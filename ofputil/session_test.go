@@ -0,0 +1,91 @@
+package ofputil
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/netrack/openflow/ofp"
+)
+
+func TestSessionStates(t *testing.T) {
+	hwaddr, _ := net.ParseMAC("01:23:45:67:89:ab")
+
+	// Names are padded with zeros in the wire format, so use
+	// names of the maximum length to compare states as is.
+	states := SessionStates{{
+		Version:      4,
+		DatapathID:   0x0000f2e0b3b6a34c,
+		Role:         ofp.ControllerRoleMaster,
+		GenerationID: 42,
+		Ports: []ofp.Port{{
+			PortNo: 1,
+			HWAddr: hwaddr,
+			Name:   strings.Repeat("p", 16),
+		}},
+		Tables: []ofp.TableFeatures{{
+			Table:      1,
+			Name:       strings.Repeat("t", 32),
+			MaxEntries: 1024,
+			Properties: []ofp.TableProp{
+				&ofp.TablePropNextTables{NextTables: []ofp.Table{2, 3}},
+			},
+		}},
+	}, {
+		Version:    1,
+		DatapathID: 1,
+		Ports:      []ofp.Port{},
+		Tables:     []ofp.TableFeatures{},
+	}}
+
+	var buf bytes.Buffer
+	if _, err := states.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write session states: %s", err)
+	}
+
+	var restored SessionStates
+	if _, err := restored.ReadFrom(&buf); err != nil {
+		t.Fatalf("Failed to read session states: %s", err)
+	}
+
+	if !reflect.DeepEqual(states, restored) {
+		t.Fatalf("Restored states are not equal:\n%v\n%v", states, restored)
+	}
+}
+
+func TestSessionStateVersion(t *testing.T) {
+	var state SessionState
+
+	b := make([]byte, 26)
+	b[0] = sessionStateVersion + 1
+
+	_, err := state.ReadFrom(bytes.NewReader(b))
+	if err != ErrSessionStateVersion {
+		t.Fatalf("Expected unsupported version error, got: %v", err)
+	}
+
+	// The state without ports and tables must be truncated.
+	b[0] = sessionStateVersion
+	b[3] = 1
+
+	_, err = state.ReadFrom(bytes.NewReader(b))
+	if err == nil {
+		t.Fatalf("Expected error on truncated session state")
+	}
+}
+
+func TestSessionStateLimits(t *testing.T) {
+	state := SessionState{Ports: make([]ofp.Port, math.MaxUint16+1)}
+	if _, err := state.WriteTo(io.Discard); err == nil {
+		t.Errorf("Error expected for %d ports", len(state.Ports))
+	}
+
+	state = SessionState{Tables: make([]ofp.TableFeatures, math.MaxUint16+1)}
+	if _, err := state.WriteTo(io.Discard); err == nil {
+		t.Errorf("Error expected for %d tables", len(state.Tables))
+	}
+}
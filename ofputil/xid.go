@@ -0,0 +1,24 @@
+package ofputil
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// lastXID is the last transaction identifier allocated for the requests
+// sent by the package. It starts at the random value, so the identifiers
+// are unlikely to clash with the ones chosen by the application.
+var lastXID = rand.Uint32()
+
+// newXID returns a new transaction identifier. The identifiers are
+// allocated sequentially from the counter shared by all helpers of the
+// package, so the replies of the helpers running concurrently on the
+// same connection are never confused. Zero is skipped, since it is
+// treated as the unset identifier.
+func newXID() uint32 {
+	for {
+		if xid := atomic.AddUint32(&lastXID, 1); xid != 0 {
+			return xid
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ofputil
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewXID(t *testing.T) {
+	const n = 100
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		seen = make(map[uint32]bool)
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			xid := newXID()
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if seen[xid] {
+				t.Errorf("Transaction identifier %d allocated twice", xid)
+			}
+			seen[xid] = true
+		}()
+	}
+
+	wg.Wait()
+
+	// The counter wraps without allocating the zero identifier.
+	atomic.StoreUint32(&lastXID, ^uint32(0))
+	if xid := newXID(); xid != 1 {
+		t.Errorf("Zero identifier must be skipped: %d", xid)
+	}
+}
@@ -0,0 +1,384 @@
+package openflow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// ErrQueueFull is returned by the queued connection with QueueError
+// policy, when the send queue is full.
+var ErrQueueFull = errors.New("openflow: Send queue is full")
+
+// QueuePolicy defines the behavior of the queued connection when the
+// send queue is full, because the peer reads slowly.
+type QueuePolicy int
+
+const (
+	// QueueBlock blocks the Send call until the queue has a space for
+	// the request.
+	QueueBlock QueuePolicy = iota
+
+	// QueueDrop silently drops the request. The number of dropped
+	// requests is reported in the queue statistics.
+	QueueDrop
+
+	// QueueError returns ErrQueueFull error from the Send call.
+	QueueError
+)
+
+func (p QueuePolicy) String() string {
+	text, ok := queuePolicyText[p]
+	if !ok {
+		return fmt.Sprintf("QueuePolicy(%d)", p)
+	}
+	return text
+}
+
+var queuePolicyText = map[QueuePolicy]string{
+	QueueBlock: "QueueBlock",
+	QueueDrop:  "QueueDrop",
+	QueueError: "QueueError",
+}
+
+// defaultQueueLen is a default capacity of the send and receive queues.
+const defaultQueueLen = 64
+
+// QueueConfig is a configuration of the queued connection.
+type QueueConfig struct {
+	// MaxLen is a maximum number of requests pending to be sent,
+	// including the one being written. When zero, the default of
+	// 64 requests is used.
+	MaxLen int
+
+	// MaxBytes is a maximum number of bytes pending to be sent. Zero
+	// means the number of bytes is not limited. A single request
+	// larger than the limit is accepted into the empty queue.
+	MaxBytes int64
+
+	// Policy defines the behavior when the send queue is full.
+	Policy QueuePolicy
+
+	// ReceiveLen is a capacity of the receive queue. When zero, the
+	// default of 64 requests is used.
+	ReceiveLen int
+}
+
+// QueueStats is a snapshot of the queued connection statistics.
+type QueueStats struct {
+	// SendLen is a number of requests pending to be sent.
+	SendLen int
+
+	// SendBytes is a number of bytes pending to be sent.
+	SendBytes int64
+
+	// Dropped is a number of requests dropped with QueueDrop policy.
+	Dropped uint64
+
+	// ReceiveLen is a number of received requests waiting to be
+	// returned from the Receive call.
+	ReceiveLen int
+}
+
+// queued is a serialized request waiting in the send queue.
+type queued struct {
+	header Header
+	body   []byte
+}
+
+// QueuedConn is a connection with the bounded send and receive queues.
+// The requests are sent and received by the background goroutines, so
+// the application can observe the depth of the queues and shed the load
+// when the control channel is congested.
+//
+// The Flush call blocks until all queued requests are written to the
+// underlying connection.
+//
+// For example, to drop the packet-out messages instead of blocking the
+// application when the switch does not keep up, the following connection
+// could be used:
+//
+//	qc := of.NewQueuedConn(conn, of.QueueConfig{
+//		MaxBytes: 1 << 20,
+//		Policy:   of.QueueDrop,
+//	})
+//
+//	// ...
+//	log.Printf("dropped %d requests", qc.Stats().Dropped)
+type QueuedConn struct {
+	Conn
+
+	config QueueConfig
+
+	// sendq is a queue of the requests to send, pending is a number
+	// of requests pending to be sent, including the one being written.
+	sendq   []queued
+	pending int
+	bytes   int64
+	dropped uint64
+	closed  bool
+	err     error
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	recv    chan *Request
+	recvErr error
+
+	// done is closed when the connection is closed, so the receive
+	// goroutine does not block on the full receive queue.
+	done chan struct{}
+}
+
+// NewQueuedConn creates a new queued connection from the given one and
+// starts the background goroutines serving the queues.
+func NewQueuedConn(c Conn, config QueueConfig) *QueuedConn {
+	if config.MaxLen <= 0 {
+		config.MaxLen = defaultQueueLen
+	}
+
+	if config.ReceiveLen <= 0 {
+		config.ReceiveLen = defaultQueueLen
+	}
+
+	qc := &QueuedConn{
+		Conn:   c,
+		config: config,
+		recv:   make(chan *Request, config.ReceiveLen),
+		done:   make(chan struct{}),
+	}
+
+	qc.cond = sync.NewCond(&qc.mu)
+
+	go qc.send()
+	go qc.receive()
+	return qc
+}
+
+// full reports whether the request of the given size exceeds the
+// limits of the send queue.
+func (c *QueuedConn) full(size int64) bool {
+	if c.pending >= c.config.MaxLen {
+		return true
+	}
+
+	maxBytes := c.config.MaxBytes
+	return maxBytes > 0 && c.pending > 0 && c.bytes+size > maxBytes
+}
+
+// Send puts the request into the send queue. When the queue is full,
+// the request is handled according to the queue policy. The error of
+// the previous write to the underlying connection is returned, if any.
+func (c *QueuedConn) Send(r *Request) error {
+	var body []byte
+
+	if r.Body != nil {
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, r.Body); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	size := int64(headerlen + len(body))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		switch {
+		case c.closed:
+			return net.ErrClosed
+		case c.err != nil:
+			return c.err
+		case !c.full(size):
+			c.sendq = append(c.sendq, queued{r.Header, body})
+			c.pending++
+			c.bytes += size
+
+			c.cond.Broadcast()
+			return nil
+		}
+
+		switch c.config.Policy {
+		case QueueDrop:
+			c.dropped++
+			return nil
+		case QueueError:
+			return ErrQueueFull
+		}
+
+		c.cond.Wait()
+	}
+}
+
+// send writes the queued requests to the underlying connection.
+func (c *QueuedConn) send() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		for len(c.sendq) == 0 && !c.closed {
+			c.cond.Wait()
+		}
+
+		if c.closed {
+			return
+		}
+
+		q := c.sendq[0]
+		c.sendq = c.sendq[1:]
+		last := len(c.sendq) == 0
+
+		c.mu.Unlock()
+
+		req := &Request{Header: q.header, Body: bytes.NewReader(q.body)}
+		err := c.Conn.Send(req)
+
+		// Flush the buffered requests, when the queue is drained.
+		if err == nil && last {
+			err = c.Conn.Flush()
+		}
+
+		c.mu.Lock()
+
+		c.pending--
+		c.bytes -= int64(headerlen + len(q.body))
+
+		if err != nil && c.err == nil {
+			c.err = err
+		}
+
+		c.cond.Broadcast()
+	}
+}
+
+// Flush blocks until all queued requests are written to the underlying
+// connection.
+func (c *QueuedConn) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.pending > 0 && c.err == nil && !c.closed {
+		c.cond.Wait()
+	}
+
+	return c.err
+}
+
+// Discard removes the requests, which headers satisfy the given
+// function, from the send queue. The request being written to the
+// underlying connection is not affected. Returns the number of the
+// discarded requests.
+func (c *QueuedConn) Discard(fn func(*Header) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	sendq := c.sendq[:0]
+
+	for _, q := range c.sendq {
+		if !fn(&q.header) {
+			sendq = append(sendq, q)
+			continue
+		}
+
+		c.pending--
+		c.bytes -= int64(headerlen + len(q.body))
+		n++
+	}
+
+	// Release the references to the discarded requests.
+	for i := len(sendq); i < len(c.sendq); i++ {
+		c.sendq[i] = queued{}
+	}
+
+	c.sendq = sendq
+	if n > 0 {
+		c.cond.Broadcast()
+	}
+
+	return n
+}
+
+// receive reads the requests from the underlying connection into the
+// receive queue.
+func (c *QueuedConn) receive() {
+	defer close(c.recv)
+
+	for {
+		r, err := c.Conn.Receive()
+
+		// Check the close before queueing the request: once the
+		// receive queue has a space, the select below could pick
+		// the send even though the connection is closed.
+		if c.isDone() {
+			c.recvErr = net.ErrClosed
+			return
+		}
+
+		if err != nil {
+			c.recvErr = err
+			return
+		}
+
+		select {
+		case c.recv <- r:
+		case <-c.done:
+			c.recvErr = net.ErrClosed
+			return
+		}
+	}
+}
+
+// isDone returns true when the connection is closed.
+func (c *QueuedConn) isDone() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Receive returns the next request from the receive queue. Once the
+// underlying connection failed, the error is returned.
+func (c *QueuedConn) Receive() (*Request, error) {
+	r, ok := <-c.recv
+	if !ok {
+		return nil, c.recvErr
+	}
+
+	return r, nil
+}
+
+// Stats returns the statistics of the connection queues.
+func (c *QueuedConn) Stats() QueueStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return QueueStats{
+		SendLen:    c.pending,
+		SendBytes:  c.bytes,
+		Dropped:    c.dropped,
+		ReceiveLen: len(c.recv),
+	}
+}
+
+// Close closes the underlying connection. The requests remaining in
+// the send queue are discarded, the requests already in the receive
+// queue could still be received.
+func (c *QueuedConn) Close() error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	return c.Conn.Close()
+}
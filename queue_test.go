@@ -0,0 +1,223 @@
+package openflow
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockConn is a connection blocking the Send calls until released.
+type blockConn struct {
+	Conn
+
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []Type
+	recv    chan *Request
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newBlockConn() *blockConn {
+	return &blockConn{
+		release: make(chan struct{}),
+		recv:    make(chan *Request),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *blockConn) Send(r *Request) error {
+	<-c.release
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent = append(c.sent, r.Header.Type)
+	return nil
+}
+
+func (c *blockConn) Receive() (*Request, error) {
+	select {
+	case r, ok := <-c.recv:
+		if !ok {
+			return nil, io.EOF
+		}
+		return r, nil
+	case <-c.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (c *blockConn) Flush() error { return nil }
+
+func (c *blockConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func TestQueuedConnPolicy(t *testing.T) {
+	tests := []struct {
+		Policy  QueuePolicy
+		Err     error
+		Dropped uint64
+	}{
+		{QueueError, ErrQueueFull, 0},
+		{QueueDrop, nil, 1},
+	}
+
+	for _, test := range tests {
+		bc := newBlockConn()
+		qc := NewQueuedConn(bc, QueueConfig{MaxLen: 2, Policy: test.Policy})
+
+		for i := 0; i < 2; i++ {
+			if err := qc.Send(NewRequest(TypeEchoRequest, nil)); err != nil {
+				t.Fatalf("Failed to queue request: %s", err)
+			}
+		}
+
+		err := qc.Send(NewRequest(TypeEchoRequest, nil))
+		if err != test.Err {
+			t.Errorf("%s: expected %v error, got %v", test.Policy, test.Err, err)
+		}
+
+		stats := qc.Stats()
+		if stats.SendLen != 2 || stats.SendBytes != 2*headerlen {
+			t.Errorf("%s: invalid queue statistics: %+v", test.Policy, stats)
+		}
+
+		if stats.Dropped != test.Dropped {
+			t.Errorf("%s: invalid number of dropped requests: %d",
+				test.Policy, stats.Dropped)
+		}
+
+		close(bc.release)
+		if err = qc.Flush(); err != nil {
+			t.Fatalf("Failed to flush the queue: %s", err)
+		}
+
+		if stats = qc.Stats(); stats.SendLen != 0 || stats.SendBytes != 0 {
+			t.Errorf("%s: queue must be drained: %+v", test.Policy, stats)
+		}
+
+		qc.Close()
+	}
+}
+
+func TestQueuedConnBlock(t *testing.T) {
+	bc := newBlockConn()
+	qc := NewQueuedConn(bc, QueueConfig{MaxLen: 1})
+	defer qc.Close()
+
+	qc.Send(NewRequest(TypeHello, nil))
+
+	done := make(chan error)
+	go func() {
+		done <- qc.Send(NewRequest(TypeEchoRequest, nil))
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("Send must block on the full queue")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(bc.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	qc.Flush()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if len(bc.sent) != 2 || bc.sent[0] != TypeHello || bc.sent[1] != TypeEchoRequest {
+		t.Fatalf("Invalid requests sent: %v", bc.sent)
+	}
+}
+
+func TestQueuedConnReceive(t *testing.T) {
+	bc := newBlockConn()
+	qc := NewQueuedConn(bc, QueueConfig{})
+
+	bc.recv <- NewRequest(TypeHello, nil)
+	close(bc.recv)
+
+	r, err := qc.Receive()
+	if err != nil || r.Header.Type != TypeHello {
+		t.Fatalf("Failed to receive request: %v", err)
+	}
+
+	if _, err = qc.Receive(); err != io.EOF {
+		t.Fatalf("Error of the connection expected: %v", err)
+	}
+
+	qc.Close()
+	if err = qc.Send(NewRequest(TypeHello, nil)); err != net.ErrClosed {
+		t.Fatalf("Send to closed connection must fail: %v", err)
+	}
+}
+
+func TestQueuedConnReceiveClose(t *testing.T) {
+	bc := newBlockConn()
+	qc := NewQueuedConn(bc, QueueConfig{ReceiveLen: 1})
+
+	// The second request blocks the receive goroutine on the full
+	// receive queue, it must be released by closing the connection.
+	bc.recv <- NewRequest(TypeHello, nil)
+	bc.recv <- NewRequest(TypeEchoRequest, nil)
+	qc.Close()
+
+	done := make(chan error)
+	go func() {
+		for {
+			if _, err := qc.Receive(); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-done:
+		if err != net.ErrClosed {
+			t.Fatalf("Closed connection error expected: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Receive goroutine is blocked after close")
+	}
+}
+
+func TestQueuedConnDiscard(t *testing.T) {
+	bc := newBlockConn()
+	qc := NewQueuedConn(bc, QueueConfig{})
+	defer qc.Close()
+
+	qc.Send(NewRequest(TypeHello, nil))
+	qc.Send(NewRequest(TypeFlowMod, nil))
+	qc.Send(NewRequest(TypeEchoRequest, nil))
+
+	n := qc.Discard(func(h *Header) bool {
+		return h.Type == TypeFlowMod
+	})
+
+	if n != 1 {
+		t.Fatalf("Single request must be discarded: %d", n)
+	}
+
+	if stats := qc.Stats(); stats.SendLen != 2 || stats.SendBytes != 2*headerlen {
+		t.Errorf("Invalid queue statistics: %+v", stats)
+	}
+
+	close(bc.release)
+	qc.Flush()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if len(bc.sent) != 2 || bc.sent[0] != TypeHello || bc.sent[1] != TypeEchoRequest {
+		t.Fatalf("Invalid requests sent: %v", bc.sent)
+	}
+}
@@ -2,12 +2,16 @@ package openflow
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"net"
 	"sync"
+
+	"github.com/netrack/openflow/internal/encoding"
 )
 
 var (
@@ -18,11 +22,31 @@ var (
 	// ErrCorruptedHeader is returned when request body does not match
 	// the length specified in a request header.
 	ErrCorruptedHeader = errors.New("openflow: Corrupted header")
+
+	// ErrNoConn is returned when the reply is sent to the request
+	// that was not received from the connection.
+	ErrNoConn = errors.New("openflow: Request has no connection")
+
+	// ErrPartialMessage is matched by the errors of the reads failed
+	// after a part of the message was consumed, for example when the
+	// read deadline is reached in the middle of the message. The stream
+	// of messages is desynchronized, so the connection must be closed.
+	// The errors unwrap to the original read error.
+	ErrPartialMessage = errors.New("openflow: Message is read partially")
 )
 
 // headerlen defines a length of the OpenFlow header.
 const headerlen = 8
 
+const (
+	// HeaderLen is a length of the OpenFlow message header.
+	HeaderLen = headerlen
+
+	// MaxMessageLen is a maximum length of the OpenFlow message
+	// including the header.
+	MaxMessageLen = math.MaxUint16
+)
+
 // copyReader is a wrapper of io.WriterTo interface to implement
 // io.Reader interface.
 type copyReader struct {
@@ -98,6 +122,14 @@ type Request struct {
 
 	// Connection instance.
 	conn Conn
+
+	// ctx is the context of the request, it is modified only by
+	// copying the whole request using WithContext.
+	ctx context.Context
+
+	// raw is the body of the request in the wire format. It is set
+	// for the received requests and shares the memory with Body.
+	raw []byte
 }
 
 // NewRequest returns a new Request given a type, address, and optional
@@ -122,11 +154,131 @@ func (r *Request) ProtoAtLeast(major, minor int) bool {
 		r.ProtoMajor == major && r.ProtoMinor >= minor
 }
 
+// RawBody returns the body of the request in the wire format without
+// consuming the Body, so the handlers forwarding or filtering the
+// messages do not pay for decoding and encoding of each of them.
+//
+// The returned slice shares the memory with the request body and must
+// not be modified. For the requests created with NewRequest the body is
+// serialized on the first call.
+func (r *Request) RawBody() ([]byte, error) {
+	if r.raw != nil || r.Body == nil {
+		return r.raw, nil
+	}
+
+	raw, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.raw = raw
+	r.Body = bytes.NewBuffer(raw)
+	return raw, nil
+}
+
+// Decode deserializes the body of the request into the given value.
+// Unlike reading from the Body, decoding does not consume it, thus the
+// body could be decoded multiple times, for example:
+//
+//	var packet ofp.PacketIn
+//	if err := r.Decode(&packet); err != nil {
+//		// ...
+//	}
+//
+// The context of the request is passed to the decoder, so the options
+// of decoding, like the check mode, could be set per connection.
+func (r *Request) Decode(v io.ReaderFrom) error {
+	raw, err := r.RawBody()
+	if err != nil {
+		return err
+	}
+
+	var rd io.Reader = bytes.NewReader(raw)
+	if r.ctx != nil {
+		rd = encoding.NewContextReader(rd, r.ctx)
+	}
+
+	_, err = v.ReadFrom(rd)
+	return err
+}
+
+// NewReply returns a new Request of the given type and body, correlated
+// with the request: the version and transaction identifier are copied
+// from the request header.
+func (r *Request) NewReply(t Type, body io.WriterTo) *Request {
+	reply := NewRequest(t, body)
+	reply.Header.Version = r.Header.Version
+	reply.Header.Transaction = r.Header.Transaction
+
+	if r.Header.Version != 0 {
+		reply.setProto()
+	}
+
+	return reply
+}
+
+// Reply writes the reply of the given type, correlated with the request
+// as by NewReply, to the response writer. For example, to respond to the
+// echo request:
+//
+//	var echo ofp.EchoRequest
+//	if err := r.Decode(&echo); err != nil {
+//		// ...
+//	}
+//
+//	err := r.Reply(rw, &ofp.EchoReply{Data: echo.Data}, of.TypeEchoReply)
+//
+// The reply passes through the handlers wrapping the response writer,
+// like authorization and statistics of the channels.
+//
+// When rw is nil, the reply is sent directly to the connection the
+// request was received from and flushed, it bypasses all wrappers of
+// the response writer. ErrNoConn is returned for the requests created
+// with NewRequest.
+func (r *Request) Reply(rw ResponseWriter, body io.WriterTo, t Type) error {
+	reply := r.NewReply(t, body)
+	if rw != nil {
+		return rw.Write(&reply.Header, body)
+	}
+
+	if r.conn == nil {
+		return ErrNoConn
+	}
+
+	return Send(r.conn, reply)
+}
+
 // Conn returns the instance of the OpenFlow protocol connection.
 func (r *Request) Conn() Conn {
 	return r.conn
 }
 
+// Context returns the context of the request. The returned context is
+// always non-nil, it defaults to the background context.
+//
+// The handlers could use the context to pass the values attributed
+// to the connection, like the metadata of the switch, down to the
+// nested handlers.
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
+// WithContext returns a shallow copy of the request with its context
+// changed to ctx. The provided ctx must be non-nil.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	if ctx == nil {
+		panic("openflow: nil context")
+	}
+
+	r2 := new(Request)
+	*r2 = *r
+	r2.ctx = ctx
+	return r2
+}
+
 // WriteTo implements WriterTo interface. Writes the request in wire format
 // to w until there's no more data to write or when an error occurs.
 func (r *Request) WriteTo(w io.Writer) (n int64, err error) {
@@ -150,7 +302,7 @@ func (r *Request) WriteTo(w io.Writer) (n int64, err error) {
 
 	// For sure we need to double check that body length fits into
 	// the header length.
-	if n+headerlen > math.MaxUint16 {
+	if n+headerlen > MaxMessageLen {
 		return 0, ErrBodyTooLong
 	}
 
@@ -173,6 +325,27 @@ func (r *Request) WriteTo(w io.Writer) (n int64, err error) {
 	return wbuf.WriteTo(w)
 }
 
+// protoText is a list of the protocol names indexed by the minor
+// number, it is used to not format the name for each request.
+var protoText = [...]string{
+	"OFP/1.0", "OFP/1.1", "OFP/1.2", "OFP/1.3",
+	"OFP/1.4", "OFP/1.5", "OFP/1.6",
+}
+
+// setProto decodes the protocol version major and minor number from
+// the header to make the request interface more or less friendly.
+func (r *Request) setProto() {
+	r.ProtoMajor = 1
+	r.ProtoMinor = int(r.Header.Version - 1)
+
+	// FIXME: wrong for version 2
+	if r.ProtoMinor >= 0 && r.ProtoMinor < len(protoText) {
+		r.Proto = protoText[r.ProtoMinor]
+	} else {
+		r.Proto = fmt.Sprintf("OFP/1.%d", r.ProtoMinor)
+	}
+}
+
 // ReadFrom implements ReaderFrom interface. Reads the request in wire
 // format from the r to the Request structure.
 func (r *Request) ReadFrom(rd io.Reader) (n int64, err error) {
@@ -183,13 +356,7 @@ func (r *Request) ReadFrom(rd io.Reader) (n int64, err error) {
 		return
 	}
 
-	// Decode the protocol version major and minor number to
-	// make the request interface more or less friendly.
-	r.ProtoMajor = 1
-	r.ProtoMinor = int(r.Header.Version - 1)
-
-	// FIXME: wrong for version 2
-	r.Proto = fmt.Sprintf("OFP/1.%d", r.ProtoMinor)
+	r.setProto()
 
 	contentlen := int64(r.Header.Len() - headerlen)
 	if contentlen < 0 {
@@ -212,5 +379,6 @@ func (r *Request) ReadFrom(rd io.Reader) (n int64, err error) {
 
 	r.Body = bytes.NewBuffer(buf)
 	r.ContentLength = contentlen
+	r.raw = buf
 	return n + headerlen, nil
 }
@@ -2,6 +2,10 @@ package openflow
 
 import (
 	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
 	"testing"
 )
 
@@ -55,3 +59,140 @@ func TestReadRequest(t *testing.T) {
 		t.Fatal("Wrong header version:", req.Header.Version)
 	}
 }
+
+// byteCounter reads all the data from the reader and records the
+// number of bytes read.
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(ioutil.Discard, r)
+	c.n += n
+	return n, err
+}
+
+func TestRequestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	NewRequest(TypeEchoRequest, bytes.NewReader([]byte{1, 2, 3})).WriteTo(&buf)
+
+	var r Request
+	if _, err := r.ReadFrom(&buf); err != nil {
+		t.Fatalf("Failed to read request: %s", err)
+	}
+
+	// Decoding must not consume the body of the request.
+	for i := 0; i < 2; i++ {
+		var c byteCounter
+		if err := r.Decode(&c); err != nil {
+			t.Fatalf("Failed to decode request: %s", err)
+		}
+
+		if c.n != 3 {
+			t.Errorf("Expected 3 bytes decoded, got %d", c.n)
+		}
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if !bytes.Equal(body, []byte{1, 2, 3}) {
+		t.Errorf("Body was consumed by decoding: %v", body)
+	}
+
+	req := NewRequest(TypeEchoReply, bytes.NewReader([]byte{4, 5}))
+	raw, err := req.RawBody()
+	if err != nil || !bytes.Equal(raw, []byte{4, 5}) {
+		t.Fatalf("Invalid raw body of the request: %v, %v", raw, err)
+	}
+
+	buf.Reset()
+	req.WriteTo(&buf)
+
+	if buf.Len() != headerlen+2 {
+		t.Errorf("Raw body must not consume the body: %v", buf.Bytes())
+	}
+}
+
+func TestRequestReply(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	req := NewRequest(TypeEchoRequest, nil)
+	req.Header.Version = 5
+	req.Header.Transaction = 42
+	go Send(NewConn(client), req)
+
+	r, err := NewConn(server).Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive request: %s", err)
+	}
+
+	go r.Reply(nil, bytes.NewReader([]byte{1, 2}), TypeEchoReply)
+
+	reply, err := NewConn(client).Receive()
+	if err != nil {
+		t.Fatalf("Failed to receive reply: %s", err)
+	}
+
+	if reply.Header.Type != TypeEchoReply {
+		t.Errorf("Invalid type of the reply: %s", reply.Header.Type)
+	}
+
+	if reply.Header.Version != 5 || reply.Header.Transaction != 42 {
+		t.Errorf("Reply is not correlated with request: %v", reply.Header)
+	}
+
+	if reply.ContentLength != 2 {
+		t.Errorf("Invalid length of the reply: %d", reply.ContentLength)
+	}
+
+	err = NewRequest(TypeEchoRequest, nil).Reply(nil, nil, TypeEchoReply)
+	if err != ErrNoConn {
+		t.Errorf("Reply without connection must fail: %v", err)
+	}
+}
+
+func TestRequestReplyWriter(t *testing.T) {
+	req := NewRequest(TypeEchoRequest, nil)
+	req.Header.Transaction = 42
+
+	// The reply must be written through the response writer, even
+	// when the request has no connection.
+	var rw dummyResponse
+	if err := req.Reply(&rw, nil, TypeEchoReply); err != nil {
+		t.Fatalf("Failed to write reply: %s", err)
+	}
+
+	if len(rw.headers) != 1 {
+		t.Fatalf("Reply must be written to the response writer")
+	}
+
+	h := rw.headers[0]
+	if h.Type != TypeEchoReply || h.Version != 4 || h.Transaction != 42 {
+		t.Errorf("Reply is not correlated with request: %v", h)
+	}
+}
+
+func TestRequestWithContext(t *testing.T) {
+	type key struct{}
+
+	r := NewRequest(TypeEchoRequest, nil)
+	if r.Context() != context.Background() {
+		t.Fatalf("Background context expected")
+	}
+
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	r2 := r.WithContext(ctx)
+
+	if r2.Context().Value(key{}) != "value" {
+		t.Errorf("Context of the request is not changed")
+	}
+
+	if r.Context() != context.Background() {
+		t.Errorf("Context of the original request must not change")
+	}
+
+	if r2.Header != r.Header || r2.Body != r.Body {
+		t.Errorf("Request fields must be copied: %v", r2)
+	}
+}
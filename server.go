@@ -42,7 +42,7 @@ var DiscardHandler = HandlerFunc(func(rw ResponseWriter, r *Request) {})
 // response from an OpenFlow request.
 type response struct {
 	// conn is an OpenFlow connection instance.
-	conn *conn
+	conn Conn
 
 	// Request header, that could be used to configure a few of
 	// response attributes.
@@ -76,6 +76,14 @@ func (r *response) Write(header *Header, w io.WriterTo) (err error) {
 	}
 
 	header.Length = headerlen + uint16(buf.Len())
+
+	// The connections other than the default one, like the framed
+	// connections, write the message as a request and count it.
+	c, ok := r.conn.(*conn)
+	if !ok {
+		return Send(r.conn, &Request{Header: *header, Body: &buf})
+	}
+
 	_, err = header.WriteTo(&r.buf)
 	if err != nil {
 		return
@@ -86,7 +94,11 @@ func (r *response) Write(header *Header, w io.WriterTo) (err error) {
 		return
 	}
 
-	return r.conn.forceWrite(r.buf.Bytes())
+	if err = c.forceWrite(r.buf.Bytes()); err == nil {
+		countSent(header.Type)
+	}
+
+	return err
 }
 
 // The reqwrap defines a placeholder for request and error returned
@@ -100,7 +112,7 @@ type reqwrap struct {
 // a channel of Requests by continuously fetching data from connection.
 type receiver struct {
 	// Conn is a client connection.
-	Conn *conn
+	Conn Conn
 
 	once sync.Once
 	ch   chan reqwrap
@@ -181,7 +193,8 @@ type Server struct {
 	ConnRunner Runner
 
 	// ConnState specifies an optional callback function that is called
-	// when a client connection changes state.
+	// when a client connection changes state. Use ConnStateHooks to
+	// call several functions.
 	ConnState func(Conn, ConnState)
 
 	// MaxConns defines the maximum number of client connections server
@@ -199,6 +212,23 @@ type Server struct {
 	once sync.Once
 }
 
+// ConnStateHooks returns the Server.ConnState function calling each of
+// the given functions in order, so the state changes of the connections
+// could be tracked by several components, for example:
+//
+//	srv := &of.Server{
+//		Addr:      ":6633",
+//		Handler:   mux,
+//		ConnState: of.ConnStateHooks(stats.ConnState, epochs.ConnState),
+//	}
+func ConnStateHooks(hooks ...func(Conn, ConnState)) func(Conn, ConnState) {
+	return func(conn Conn, state ConnState) {
+		for _, hook := range hooks {
+			hook(conn, state)
+		}
+	}
+}
+
 func (srv *Server) setState(conn Conn, state ConnState) {
 	if cb := srv.ConnState; cb != nil {
 		cb(conn, state)
@@ -219,6 +249,15 @@ func (srv *Server) ListenAndServe() error {
 // Serve accepts incoming connections on the Listener l, creating a
 // new service goroutine for each.
 func (srv *Server) Serve(l net.Listener) error {
+	return srv.ServeListener(&listener{l})
+}
+
+// ServeListener accepts incoming OpenFlow connections on the Listener
+// l, like Serve. It is used to serve the switches connected over the
+// custom transports, for example, when the Accept method returns the
+// connections created with NewFramedConn. The ReadTimeout and
+// WriteTimeout are applied only to the connections created by NewConn.
+func (srv *Server) ServeListener(l Listener) error {
 	defer l.Close()
 
 	// When the handler is not specified, the default dispatcher
@@ -252,15 +291,16 @@ func (srv *Server) Serve(l net.Listener) error {
 // The accept block until a new connection will be extracted from the
 // queue. It keeps track of count of incoming connections and closes all
 // that exceed the MaxConns threshold.
-func (srv *Server) accept(l net.Listener, cr, hr Runner, h Handler) error {
-	rwc, err := l.Accept()
+func (srv *Server) accept(l Listener, cr, hr Runner, h Handler) error {
+	c, err := l.Accept()
 	if err != nil {
 		return err
 	}
 
-	c := newConn(rwc)
-	c.ReadTimeout = srv.ReadTimeout
-	c.WriteTimeout = srv.WriteTimeout
+	if c, ok := c.(*conn); ok {
+		c.ReadTimeout = srv.ReadTimeout
+		c.WriteTimeout = srv.WriteTimeout
+	}
 
 	srv.setState(c, StateNew)
 
@@ -281,7 +321,7 @@ func (srv *Server) accept(l net.Listener, cr, hr Runner, h Handler) error {
 	return nil
 }
 
-func (srv *Server) serve(c *conn, r Runner, h Handler) {
+func (srv *Server) serve(c Conn, r Runner, h Handler) {
 	// Define a deferred call to close the connection.
 	defer c.Close()
 	defer srv.setState(c, StateClosed)
@@ -312,7 +352,7 @@ func (srv *Server) serve(c *conn, r Runner, h Handler) {
 
 // The serveReq serves a single request from the given connection using
 // specified handler.
-func (srv *Server) serveReq(c *conn, req *Request, h Handler) {
+func (srv *Server) serveReq(c Conn, req *Request, h Handler) {
 	state := StateActive
 	if req.Header.Type == TypeHello {
 		state = StateHandshake
@@ -3,6 +3,7 @@ package openflow
 import (
 	"io"
 	"net"
+	"reflect"
 	"sync"
 	"testing"
 )
@@ -125,3 +126,21 @@ func TestServerServe(t *testing.T) {
 		t.Errorf("Connection did not transition closed state")
 	}
 }
+
+func TestConnStateHooks(t *testing.T) {
+	var calls []string
+	hook := func(name string) func(Conn, ConnState) {
+		return func(c Conn, s ConnState) {
+			calls = append(calls, name+":"+s.String())
+		}
+	}
+
+	cb := ConnStateHooks(hook("a"), hook("b"))
+	cb(nil, StateNew)
+	cb(nil, StateClosed)
+
+	want := []string{"a:StateNew", "b:StateNew", "a:StateClosed", "b:StateClosed"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Invalid order of the hook calls: %v", calls)
+	}
+}
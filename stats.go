@@ -0,0 +1,198 @@
+package openflow
+
+import (
+	"container/list"
+	"encoding/binary"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats is a snapshot of the process-wide message counters of all
+// OpenFlow connections.
+type Stats struct {
+	// Received is a number of received messages by type.
+	Received map[Type]uint64
+
+	// Sent is a number of sent messages by type.
+	Sent map[Type]uint64
+
+	// DecodeErrors is a number of messages failed to be decoded
+	// due to the corrupted header or truncated body.
+	DecodeErrors uint64
+
+	// Conns is a number of established connections.
+	Conns uint64
+
+	// Reconnects is a number of connections of the channels, that
+	// were already connected before. The channel is identified by
+	// the datapath and auxiliary identifiers of the first features
+	// reply received from the connection, so the connections never
+	// receiving the features reply are not counted. Only the last
+	// 4096 connected channels are remembered.
+	Reconnects uint64
+}
+
+// counters are the process-wide message counters.
+var counters struct {
+	received     [256]uint64
+	sent         [256]uint64
+	decodeErrors uint64
+	conns        uint64
+	reconnects   uint64
+
+	// channels is a set of the channels connected at least
+	// once, it is used to count the reconnects.
+	channels channelSet
+}
+
+// maxStatsChannels is a maximum number of the channels remembered to
+// count the reconnects.
+const maxStatsChannels = 4096
+
+// channelSet is a set of the channels bounded by maxStatsChannels. When
+// the set is full, the least recently connected channel is forgotten,
+// so its next connection is not counted as a reconnect.
+type channelSet struct {
+	mu       sync.Mutex
+	channels map[string]*list.Element
+	order    *list.List
+}
+
+// add puts the channel into the set. Returns true, when the channel is
+// already in the set.
+func (s *channelSet) add(channel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.channels == nil {
+		s.channels = make(map[string]*list.Element)
+		s.order = list.New()
+	}
+
+	if e, ok := s.channels[channel]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+
+	s.channels[channel] = s.order.PushFront(channel)
+	if s.order.Len() > maxStatsChannels {
+		e := s.order.Back()
+		s.order.Remove(e)
+		delete(s.channels, e.Value.(string))
+	}
+
+	return false
+}
+
+// reset forgets all channels of the set.
+func (s *channelSet) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.channels, s.order = nil, nil
+}
+
+// countReceived increments the counter of the received messages.
+func countReceived(t Type) {
+	atomic.AddUint64(&counters.received[t], 1)
+}
+
+// countSent increments the counter of the sent messages.
+func countSent(t Type) {
+	atomic.AddUint64(&counters.sent[t], 1)
+}
+
+// countDecodeError increments the counter of the decoding errors.
+func countDecodeError() {
+	atomic.AddUint64(&counters.decodeErrors, 1)
+}
+
+// countConn increments the counter of the established connections.
+func countConn() {
+	atomic.AddUint64(&counters.conns, 1)
+}
+
+// featuresLen is a length of the features reply body up to the
+// auxiliary identifier inclusive.
+const featuresLen = 14
+
+// countFeatures increments the counter of the reconnects, when the
+// features reply is the first one received from the connection and
+// the channel identified by it was connected before. The identified
+// flag of the connection is set on the first features reply.
+func countFeatures(identified *int32, r *Request) {
+	if r.Header.Type != TypeFeaturesReply || len(r.raw) < featuresLen {
+		return
+	}
+
+	if !atomic.CompareAndSwapInt32(identified, 0, 1) {
+		return
+	}
+
+	// The auxiliary identifier is a padding before the version 1.3
+	// of the protocol, so all channels of the earlier versions are
+	// considered main.
+	datapath := binary.BigEndian.Uint64(r.raw)
+	channel := fmt.Sprintf("%016x/%d", datapath, r.raw[featuresLen-1])
+
+	if counters.channels.add(channel) {
+		atomic.AddUint64(&counters.reconnects, 1)
+	}
+}
+
+// ReadStats returns the snapshot of the message counters. Only the
+// message types with non-zero counters are present in the snapshot.
+func ReadStats() Stats {
+	stats := Stats{
+		Received:     make(map[Type]uint64),
+		Sent:         make(map[Type]uint64),
+		DecodeErrors: atomic.LoadUint64(&counters.decodeErrors),
+		Conns:        atomic.LoadUint64(&counters.conns),
+		Reconnects:   atomic.LoadUint64(&counters.reconnects),
+	}
+
+	for t := range counters.received {
+		if n := atomic.LoadUint64(&counters.received[t]); n != 0 {
+			stats.Received[Type(t)] = n
+		}
+
+		if n := atomic.LoadUint64(&counters.sent[t]); n != 0 {
+			stats.Sent[Type(t)] = n
+		}
+	}
+
+	return stats
+}
+
+// StatsVar returns the variable exposing the message counters through
+// the expvar package. Message types are reported by their names.
+//
+// For example, to publish the counters with the "openflow" name on the
+// "/debug/vars" HTTP endpoint:
+//
+//	expvar.Publish("openflow", of.StatsVar())
+func StatsVar() expvar.Var {
+	return expvar.Func(func() interface{} {
+		stats := ReadStats()
+
+		received := make(map[string]uint64, len(stats.Received))
+		for t, n := range stats.Received {
+			received[t.String()] = n
+		}
+
+		sent := make(map[string]uint64, len(stats.Sent))
+		for t, n := range stats.Sent {
+			sent[t.String()] = n
+		}
+
+		return map[string]interface{}{
+			"received":      received,
+			"sent":          sent,
+			"decode_errors": stats.DecodeErrors,
+			"conns":         stats.Conns,
+			"reconnects":    stats.Reconnects,
+		}
+	})
+}
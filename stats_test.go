@@ -0,0 +1,132 @@
+package openflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+)
+
+// tcpConn is a dummy connection with the TCP remote address.
+type tcpConn struct {
+	dummyConn
+	addr *net.TCPAddr
+}
+
+func (c *tcpConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func TestReadStats(t *testing.T) {
+	counters.channels.reset()
+	before := ReadStats()
+
+	dc := &tcpConn{addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 6653}}
+	c := NewConn(dc)
+
+	if err := c.Send(NewRequest(TypeEchoRequest, nil)); err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+
+	c.Flush()
+	dc.r.Write(dc.w.Bytes())
+
+	if _, err := c.Receive(); err != nil {
+		t.Fatalf("Failed to receive request: %s", err)
+	}
+
+	// Write the header with the length less than the header length.
+	dc.r.Write([]byte{4, byte(TypeHello), 0, 4, 0, 0, 0, 0})
+	if _, err := c.Receive(); err != ErrCorruptedHeader {
+		t.Fatalf("Corrupted header error expected: %v", err)
+	}
+
+	// The features replies of the datapath identify the channels.
+	features := func(datapath, aux byte) []byte {
+		return []byte{
+			4, byte(TypeFeaturesReply), 0, 32, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, datapath, // Datapath identifier.
+			0, 0, 0, 0, 0, aux, // Buffers, tables, auxiliary identifier.
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // Padding, capabilities, reserved.
+		}
+	}
+
+	receive := func(dc *tcpConn, c Conn, b []byte) {
+		dc.r.Write(b)
+		if _, err := c.Receive(); err != nil {
+			t.Fatalf("Failed to receive features reply: %s", err)
+		}
+	}
+
+	// The repeated features reply of the connection is not counted.
+	receive(dc, c, features(1, 0))
+	receive(dc, c, features(1, 0))
+
+	// Another datapath and the auxiliary connection are connected
+	// from the same host, but only the main channel reconnects.
+	for _, b := range [][]byte{features(2, 0), features(1, 1), features(1, 0)} {
+		dc := &tcpConn{addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 6654}}
+		receive(dc, NewConn(dc), b)
+	}
+
+	after := ReadStats()
+
+	if n := after.Sent[TypeEchoRequest] - before.Sent[TypeEchoRequest]; n != 1 {
+		t.Errorf("Invalid number of sent messages: %d", n)
+	}
+
+	if n := after.Received[TypeEchoRequest] - before.Received[TypeEchoRequest]; n != 1 {
+		t.Errorf("Invalid number of received messages: %d", n)
+	}
+
+	if n := after.DecodeErrors - before.DecodeErrors; n != 1 {
+		t.Errorf("Invalid number of decode errors: %d", n)
+	}
+
+	if n := after.Conns - before.Conns; n != 4 {
+		t.Errorf("Invalid number of connections: %d", n)
+	}
+
+	if n := after.Reconnects - before.Reconnects; n != 1 {
+		t.Errorf("Reconnect expected: %d", n)
+	}
+}
+
+func TestChannelSet(t *testing.T) {
+	var s channelSet
+	for i := 0; i <= maxStatsChannels; i++ {
+		if s.add(fmt.Sprintf("%016x/0", i)) {
+			t.Fatalf("Channel %d must not be in the set", i)
+		}
+	}
+
+	// The least recently connected channel is forgotten.
+	if s.add(fmt.Sprintf("%016x/0", 0)) {
+		t.Errorf("Channel must be forgotten when the set is full")
+	}
+
+	if !s.add(fmt.Sprintf("%016x/0", maxStatsChannels)) {
+		t.Errorf("Recently connected channel must be remembered")
+	}
+
+	s.reset()
+	if s.add(fmt.Sprintf("%016x/0", 1)) {
+		t.Errorf("Channel must be forgotten after reset")
+	}
+}
+
+func TestStatsVar(t *testing.T) {
+	countReceived(TypeBarrierReply)
+
+	var stats struct {
+		Received map[string]uint64 `json:"received"`
+	}
+
+	if err := json.Unmarshal([]byte(StatsVar().String()), &stats); err != nil {
+		t.Fatalf("Failed to decode statistics: %s", err)
+	}
+
+	if stats.Received[TypeBarrierReply.String()] == 0 {
+		t.Errorf("Barrier replies must be counted: %v", stats.Received)
+	}
+}